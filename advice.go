@@ -0,0 +1,94 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+)
+
+// adviser - Implemented by file management that can advise the operating system of how its files are accessed
+type adviser interface {
+	Advise(advice int) (err error)
+}
+
+// advise - Advises the operating system of how the files of fm are accessed, which is a no-op if fm doesn't take advice
+//   - fm is the file management
+//   - advice is one of the storage.Advice constants
+//
+// It returns:
+//   - err is a standard error, if the advice was rejected
+func advise(fm FileManagement, advice int) (err error) {
+	if a, ok := fm.(adviser); ok {
+		err = a.Advise(advice)
+	}
+
+	return
+}
+
+// EnableAccessHints - Turns on access pattern advice to the operating system (posix_fadvise on Linux, ignored on
+// other platforms). The map file, and the overflow file if any, is advised for random access which stops the kernel
+// from reading ahead on every Get and Set. Whole file scans made by Stat, Export, Iterator, ForEach and ReorgFiles
+// (see ReorgConf.AccessHints) always advise sequential access while scanning, and with access hints enabled they then
+// drop the scanned pages from the page cache before returning to random access, so a scan doesn't push the hot set out
+// of the cache.
+//
+// It returns:
+//   - err is a standard error, if the advice was rejected
+func (F *FileHashMap) EnableAccessHints() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.enableAccessHints()
+
+	return
+}
+
+// enableAccessHints - Is the implementation of EnableAccessHints, to be called with the lock held
+func (F *FileHashMap) enableAccessHints() (err error) {
+	err = advise(F.fileManagement, storage.AdviceRandom)
+	if err != nil {
+		err = fmt.Errorf("error while advising random access: %s", err)
+		return
+	}
+
+	F.accessHints = true
+
+	return
+}
+
+// DisableAccessHints - Turns off access pattern advice and returns the files to the default access pattern.
+//
+// It returns:
+//   - err is a standard error, if the advice was rejected
+func (F *FileHashMap) DisableAccessHints() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.accessHints = false
+
+	err = advise(F.fileManagement, storage.AdviceNormal)
+	if err != nil {
+		err = fmt.Errorf("error while advising normal access: %s", err)
+	}
+
+	return
+}
+
+// beginScan - Enters the sequential scan mode used by whole file scans, which advises sequential access so that the
+// kernel reads ahead where supported (posix_fadvise on Linux), while buckets are read in chunks (see bucketScanner).
+// Advice is only a hint, so any error is ignored. To be called with the lock held.
+func (F *FileHashMap) beginScan() {
+	_ = advise(F.fileManagement, storage.AdviceSequential)
+}
+
+// endScan - Leaves the sequential scan mode, returning to normal access. If access hints are enabled the scanned pages
+// are instead dropped from the page cache before returning to random access. Advice is only a hint, so any error is
+// ignored. To be called with the lock held.
+func (F *FileHashMap) endScan() {
+	if F.accessHints {
+		_ = advise(F.fileManagement, storage.AdviceDontNeed)
+		_ = advise(F.fileManagement, storage.AdviceRandom)
+		return
+	}
+
+	_ = advise(F.fileManagement, storage.AdviceNormal)
+}
@@ -0,0 +1,281 @@
+package filehashmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+)
+
+// autoGrow - Holds the settings and state for automatic growing, see EnableAutoGrow
+//   - maxLoadFactor is the load factor that triggers a grow when exceeded
+//   - records is the number of records stored, counted when enabled and when growing and kept up to date in between
+type autoGrow struct {
+	maxLoadFactor float64
+	records       int64
+}
+
+// EnableAutoGrow - Turns on automatic growing, where Set and SetBatch transparently double the number of buckets
+// before a new record would make the load factor (records stored divided by the number of records the map file can
+// hold) exceed maxLoadFactor, and also whenever the map file is full. Hence, crt.MapFileFull is no longer returned.
+// Growing rehashes all records into new files under a temporary name which then replace the original files, so it
+// takes a time proportional to the size of the hash map while the lock is held. If growing is interrupted, for
+// instance by a crash, the original files may have been replaced only partially, so use the WAL (see EnableWAL) or
+// other means of recovery if that is a concern.
+// The records stored are counted when enabled, which requires a walk through all buckets, and each Set then
+// makes an extra lookup to find out whether the key is new. Access counts are not preserved by a grow.
+// Automatic growing is not available for Linear Hashing since it grows by itself.
+//   - maxLoadFactor is the load factor above which to grow, it has to be above 0 and not above 1
+//
+// It returns:
+//   - err is a standard error, if maxLoadFactor is out of range, the collision resolution technique doesn't support
+//     growing or the records could not be counted
+func (F *FileHashMap) EnableAutoGrow(maxLoadFactor float64) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.checkAutoGrow(maxLoadFactor)
+	if err != nil {
+		return
+	}
+
+	err = F.enableAutoGrow(maxLoadFactor)
+
+	return
+}
+
+// checkAutoGrow - Checks that automatic growing can be enabled with maxLoadFactor, to be called with the lock held
+func (F *FileHashMap) checkAutoGrow(maxLoadFactor float64) (err error) {
+	err = F.requireFiles("EnableAutoGrow")
+	if err != nil {
+		return
+	}
+
+	if maxLoadFactor <= 0 || maxLoadFactor > 1 {
+		err = fmt.Errorf("max load factor must be above 0 and not above 1")
+		return
+	}
+
+	if F.fileManagement.GetStorageParameters().CollisionResolutionTechnique == crt.LinearHashing {
+		err = fmt.Errorf("auto grow is not supported for linear hashing")
+	}
+
+	return
+}
+
+// enableAutoGrow - Is the implementation of EnableAutoGrow once checked by checkAutoGrow, to be called with the lock
+// held
+func (F *FileHashMap) enableAutoGrow(maxLoadFactor float64) (err error) {
+	hms, err := F.stat(false, nil)
+	if err != nil {
+		err = fmt.Errorf("error while counting records: %s", err)
+		return
+	}
+
+	F.autoGrow = &autoGrow{maxLoadFactor: maxLoadFactor, records: int64(hms.Records)}
+
+	return
+}
+
+// DisableAutoGrow - Turns off automatic growing
+func (F *FileHashMap) DisableAutoGrow() {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.autoGrow = nil
+}
+
+// setGrowing - Sets a record in the files, growing first if the record is new and would make the load factor exceed
+// the max load factor, or if the map file is full
+func (F *FileHashMap) setGrowing(key, value []byte) (err error) {
+	_, err = F.fileManagement.Get(model.Record{Key: key})
+	isNew := errors.Is(err, crt.NoRecordFound{})
+	if err != nil && !isNew {
+		return
+	}
+
+	if isNew && F.exceedsLoadFactor(F.autoGrow.records+1) {
+		err = F.grow()
+		if err != nil {
+			return
+		}
+	}
+
+	err = F.fileManagement.Set(model.Record{Key: key, Value: value})
+	if errors.Is(err, crt.MapFileFull{}) {
+		err = F.grow()
+		if err != nil {
+			return
+		}
+		err = F.fileManagement.Set(model.Record{Key: key, Value: value})
+	}
+	if err != nil {
+		return
+	}
+
+	if isNew {
+		F.autoGrow.records++
+	}
+
+	return
+}
+
+// exceedsLoadFactor - Returns true if storing the given number of records would exceed the max load factor
+func (F *FileHashMap) exceedsLoadFactor(records int64) bool {
+	sp := F.fileManagement.GetStorageParameters()
+	capacity := sp.NumberOfBucketsAvailable * sp.RecordsPerBucket
+
+	return float64(records) > F.autoGrow.maxLoadFactor*float64(capacity)
+}
+
+// grow - Doubles the number of buckets by rehashing all records into new files with a -grow inserted in the name(s),
+// that then replace the original files. The sequence number, TTL and checksum support is kept, to be called with the
+// lock held.
+func (F *FileHashMap) grow() (err error) {
+	err = F.flushAccessCounts()
+	if err != nil {
+		return
+	}
+
+	sp := F.fileManagement.GetStorageParameters()
+	growName := fmt.Sprintf("%s-grow", F.name)
+	bucketsNeeded := int(sp.NumberOfBucketsAvailable * 2)
+	valueLength := F.userValueLength()
+
+	// A custom hash algorithm is shared with the current files until they are replaced, while the grown files get an
+	// internal one built on the same hash function as the current files
+	hashAlgorithm := F.hashAlgorithm
+	growAlgorithm := hashAlgorithm
+	if sp.InternalAlgorithm {
+		hashAlgorithm = nil
+		growAlgorithm = internalHashAlgorithm(sp)
+	}
+	var tableSize int64
+	if hashAlgorithm != nil {
+		tableSize = hashAlgorithm.GetTableSize()
+	}
+
+	o := F.options
+	o.features = F.features()
+	to, _, err := createFileHashMap(growName, sp.CollisionResolutionTechnique, bucketsNeeded, int(sp.RecordsPerBucket), int(sp.KeyLength), valueLength, growAlgorithm, o)
+	if err != nil {
+		err = fmt.Errorf("error while creating grown files: %s", err)
+		return
+	}
+
+	hms, err := F.copyToGrown(to, sp.NumberOfBucketsAvailable)
+	if err != nil {
+		_ = to.RemoveFiles()
+		if hashAlgorithm != nil {
+			hashAlgorithm.SetTableSize(tableSize)
+		}
+		err = fmt.Errorf("error while copying records to grown files: %s", err)
+		return
+	}
+
+	// Replace the original files with the grown ones
+	// Records are copied as stored, so the original key file is kept for variable length keys
+	to.CloseFiles()
+	_ = removeLockFile(growName, F.options)
+	F.fileManagement.CloseFiles()
+	if to.keyFile != nil {
+		_ = F.fileSystem().Remove(storage.GetKeyFileName(growName))
+	}
+	for _, fileName := range []func(string) string{storage.GetMapFileName, storage.GetOvflFileName} {
+		if _, statErr := F.fileSystem().Stat(fileName(growName)); statErr != nil {
+			continue
+		}
+		err = F.fileSystem().Rename(fileName(growName), fileName(F.name))
+		if err != nil {
+			err = fmt.Errorf("error while replacing files with grown files: %s", err)
+			return
+		}
+	}
+	err = syncDirOf(storage.GetMapFileName(F.name), F.options)
+	if err != nil {
+		return
+	}
+
+	fm, err := openFileManagement(F.name, sp.CollisionResolutionTechnique, hashAlgorithm, F.fileSystem())
+	if err != nil {
+		err = fmt.Errorf("error while opening grown files: %s", err)
+		return
+	}
+
+	F.fileManagement = fm
+	if checker, ok := fm.(expiryChecker); ok && F.ttl != nil {
+		checker.SetExpiryCheck(isExpiredValue)
+	}
+	if F.accessHints {
+		_ = advise(fm, storage.AdviceRandom)
+	}
+	if F.watchdog != nil {
+		setProgress(fm, F.watchdog.progress)
+	}
+	if metrics := F.storageMetrics(); metrics != nil {
+		setStorageMetrics(fm, metrics)
+	}
+	if mapper, ok := fm.(memoryMapper); ok && F.memoryMapped {
+		err = mapper.MemoryMap(true)
+		if err != nil {
+			err = fmt.Errorf("error while memory mapping grown files: %s", err)
+			return
+		}
+	}
+	if cacher, ok := fm.(bucketCacher); ok && F.bucketCacheBudget > 0 {
+		_ = cacher.SetBucketCache(F.bucketCacheBudget)
+	}
+	if capper, ok := fm.(probeCapper); ok && F.probeCap > 0 {
+		capper.SetProbeCap(F.probeCap)
+	}
+	if inserter, ok := fm.(brentInserter); ok && F.brentInsertion {
+		_ = inserter.SetBrentInsertion(true)
+	}
+	if bufferer, ok := fm.(writeBufferer); ok && F.writeBuffer != nil {
+		err = bufferer.SetWriteBuffer(F.writeBuffer.maxBytes, F.writeBuffer.interval)
+		if err != nil {
+			err = fmt.Errorf("error while enabling write buffer for grown files: %s", err)
+			return
+		}
+	}
+	if F.evictionHand != nil {
+		*F.evictionHand = evictionHand{}
+	}
+	F.autoGrow.records = int64(hms.Records)
+
+	return
+}
+
+// copyToGrown - Copies all records that have not expired, as they are stored, to the grown file hash map, keeping the
+// sequence number, and returns its statistics
+func (F *FileHashMap) copyToGrown(to *FileHashMap, nBuckets int64) (hms *HashMapStat, err error) {
+	var records []model.Record
+
+	getBucket := F.bucketScanner()
+	for i := int64(0); i < nBuckets; i++ {
+		records, err = F.readBucketLocked(getBucket, i)
+		if err != nil {
+			return
+		}
+
+		for _, r := range records {
+			if r.State != model.RecordOccupied || F.hasExpired(r) {
+				continue
+			}
+			err = to.fileManagement.Set(model.Record{Key: r.Key, Value: r.Value})
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	err = setMutationSeq(to.fileManagement, F.lastSeq())
+	if err != nil {
+		return
+	}
+
+	hms, err = to.stat(false, nil)
+
+	return
+}
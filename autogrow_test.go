@@ -0,0 +1,106 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestAutoGrow(t *testing.T) {
+	t.Run("grows instead of filling up for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "RobinHood", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("grows for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, hashMapInfo, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 300)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+
+				// Execute
+				errEnable := fhm.EnableAutoGrow(0.5)
+				for _, record := range records[:200] {
+					err = fhm.Set(record.Key, record.Value)
+					assert.NoError(t, err, "sets record")
+				}
+				errBatch := fhm.SetBatch(records[200:])
+				_, errPop := fhm.Pop(records[0].Key)
+				stat, errStat := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, errEnable, "enables auto grow")
+				assert.NoError(t, errBatch, "sets records in batch")
+				assert.NoError(t, errPop, "pops record")
+				assert.NoError(t, errStat, "gets stat")
+				assert.Equal(t, len(records)-1, stat.Records, "all records kept")
+				assert.Equal(t, int64(len(records)+1), stat.LastSeq, "sequence number kept")
+
+				sp := fhm.fileManagement.GetStorageParameters()
+				assert.Greater(t, int(sp.NumberOfBucketsAvailable), hashMapInfo.NumberOfBucketsAvailable, "number of buckets grown")
+				assert.LessOrEqual(t, float64(stat.Records), 0.5*float64(sp.NumberOfBucketsAvailable*sp.RecordsPerBucket), "load factor kept")
+
+				for _, record := range records[1:] {
+					value, err := fhm.Get(record.Key)
+					assert.NoError(t, err, "gets record")
+					assert.Equal(t, record.Value, value, "correct value")
+				}
+
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "opens grown files")
+				value, err := fhm.Get(records[1].Key)
+				assert.NoError(t, err, "gets record from grown files")
+				assert.Equal(t, records[1].Value, value, "correct value")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearHashing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errLinear := fhm.EnableAutoGrow(0.5)
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		fhm, _, err = NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		errLow := fhm.EnableAutoGrow(0)
+		errHigh := fhm.EnableAutoGrow(1.5)
+
+		// Check
+		assert.Error(t, errLinear, "linear hashing rejected")
+		assert.Error(t, errLow, "too low load factor rejected")
+		assert.Error(t, errHigh, "too high load factor rejected")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,270 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// backupMagic - Is written first in a backup, telling the format and its version
+var backupMagic = [8]byte{'F', 'H', 'M', 'B', 'A', 'K', 0, 1}
+
+// backupEnd - Is written as file kind after the last file of a backup
+const backupEnd byte = 0xff
+
+// snapshotCount - Makes names of snapshots taken by concurrent backups unique within the process
+var snapshotCount atomic.Int64
+
+// Backup - Writes a consistent snapshot of the file hash map to w, while the file hash map stays open and usable.
+// All files of the file hash map are included, i.e. map, overflow, key, Bloom filter and WAL files, but not the
+// operation log. The snapshot is taken by copying the files next to the originals, with a -backup-<n> inserted in the
+// name(s), while operations are held back, after which operations continue while the copies are streamed to w and
+// then removed. On file systems supporting it (reflink on Linux, e.g. Btrfs and XFS) the copies are clones that are
+// taken instantly, otherwise operations are held back for as long as the kernel takes to copy the files. Each file is
+// followed by a checksum in the backup, which is verified by Restore.
+//   - w is the writer to write the backup to, e.g. a file or a network connection
+//
+// It returns:
+//   - err is a standard error, if the hash map is held in memory or something went wrong
+func (F *FileHashMap) Backup(w io.Writer) (err error) {
+	snapshotName, err := F.snapshot()
+	if err != nil {
+		return
+	}
+	fs := F.options.fileSystem()
+	defer func() { _ = removeNamedFiles(snapshotName, fs) }()
+
+	_, err = w.Write(backupMagic[:])
+	if err != nil {
+		err = fmt.Errorf("error while writing backup: %s", err)
+		return
+	}
+
+	for kind, fileName := range hashMapFileNames {
+		err = writeBackupFile(w, byte(kind), fileName(snapshotName), fs)
+		if err != nil {
+			err = fmt.Errorf("error while writing backup: %s", err)
+			return
+		}
+	}
+
+	_, err = w.Write([]byte{backupEnd})
+	if err != nil {
+		err = fmt.Errorf("error while writing backup: %s", err)
+	}
+
+	return
+}
+
+// snapshot - Copies the files of the file hash map, with operations held back, and returns the name of the copy
+func (F *FileHashMap) snapshot() (snapshotName string, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.name == "" {
+		err = fmt.Errorf("Backup is not supported for a hash map held in memory")
+		return
+	}
+
+	// Everything kept in memory is written to the files first, unless they are opened read-only
+	if !F.readOnly {
+		if F.groupCommit != nil {
+			err = F.commitSeq()
+			if err != nil {
+				return
+			}
+		}
+		err = F.flushAccessCounts()
+		if err != nil {
+			return
+		}
+		err = F.saveBloomFilter()
+		if err != nil {
+			return
+		}
+		err = F.fileManagement.Sync()
+		if err != nil {
+			return
+		}
+	}
+
+	snapshotName = fmt.Sprintf("%s-backup-%d", F.name, snapshotCount.Add(1))
+	fs := F.options.fileSystem()
+	err = copyNamedFiles(F.name, snapshotName, fs)
+	if err != nil {
+		_ = removeNamedFiles(snapshotName, fs)
+	}
+
+	return
+}
+
+// writeBackupFile - Writes the file to w as its kind, length, contents and CRC-32 checksum of the contents, or nothing
+// if the file doesn't exist in fs
+func writeBackupFile(w io.Writer, kind byte, fileName string, fs vfs.FileSystem) (err error) {
+	file, err := fs.OpenFile(fileName, os.O_RDONLY, 0644)
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	header := make([]byte, 9)
+	header[0] = kind
+	binary.BigEndian.PutUint64(header[1:], uint64(info.Size()))
+	_, err = w.Write(header)
+	if err != nil {
+		return
+	}
+
+	checksum := crc32.NewIEEE()
+	_, err = io.Copy(io.MultiWriter(w, checksum), io.NewSectionReader(file, 0, info.Size()))
+	if err != nil {
+		return
+	}
+
+	_, err = w.Write(checksum.Sum(nil))
+
+	return
+}
+
+// Restore - Creates a file hash map from a backup written by Backup, which can then be opened with
+// NewFromExistingFiles. The checksum of each file is verified, and if anything is wrong with the backup no files of
+// the file hash map are left behind.
+//   - r is the reader to read the backup from
+//   - name is the name of the file hash map to create (including correct path), it must not already exist
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - err is a standard error, if the file hash map already exists, the backup is damaged or something went wrong
+func Restore(r io.Reader, name string, options ...Option) (err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	fs := o.fileSystem()
+
+	if _, statErr := fs.Stat(storage.GetMapFileName(name)); statErr == nil {
+		err = fmt.Errorf("file hash map %s already exists", name)
+		return
+	}
+
+	lock, err := lockFiles(name, false, o)
+	if err != nil {
+		return
+	}
+	if lock != nil {
+		defer func() { _ = lock.Unlock() }()
+	}
+
+	err = restoreFiles(r, name, fs)
+	if err != nil {
+		_ = removeNamedFiles(name, fs)
+		_ = removeLockFile(name, o)
+		err = fmt.Errorf("error while restoring backup: %s", err)
+		return
+	}
+
+	err = syncDirOf(storage.GetMapFileName(name), o)
+
+	return
+}
+
+// restoreFiles - Reads the files of a backup from r and writes them as the files of the file hash map name in fs
+func restoreFiles(r io.Reader, name string, fs vfs.FileSystem) (err error) {
+	var magic [8]byte
+	_, err = io.ReadFull(r, magic[:])
+	if err != nil {
+		return
+	}
+	if magic != backupMagic {
+		err = fmt.Errorf("not a backup of a file hash map")
+		return
+	}
+
+	header := make([]byte, 9)
+	for {
+		_, err = io.ReadFull(r, header[:1])
+		if err != nil {
+			return
+		}
+		if header[0] == backupEnd {
+			break
+		}
+		if int(header[0]) >= len(hashMapFileNames) {
+			err = fmt.Errorf("unknown file kind %d", header[0])
+			return
+		}
+
+		_, err = io.ReadFull(r, header[1:])
+		if err != nil {
+			return
+		}
+
+		err = restoreFile(r, hashMapFileNames[header[0]](name), int64(binary.BigEndian.Uint64(header[1:])), fs)
+		if err != nil {
+			return
+		}
+	}
+
+	if _, statErr := fs.Stat(storage.GetMapFileName(name)); statErr != nil {
+		err = fmt.Errorf("backup holds no map file")
+	}
+
+	return
+}
+
+// restoreFile - Reads size bytes and their checksum from r, writing them to a new file named fileName in fs
+func restoreFile(r io.Reader, fileName string, size int64, fs vfs.FileSystem) (err error) {
+	file, err := fs.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	checksum := crc32.NewIEEE()
+	_, err = io.CopyN(io.MultiWriter(&fileWriter{file: file}, checksum), r, size)
+	if err != nil {
+		return
+	}
+
+	stored := make([]byte, crc32.Size)
+	_, err = io.ReadFull(r, stored)
+	if err != nil {
+		return
+	}
+	if binary.BigEndian.Uint32(stored) != checksum.Sum32() {
+		err = fmt.Errorf("checksum mismatch for file %s", fileName)
+		return
+	}
+
+	err = file.Sync()
+
+	return
+}
+
+// fileWriter - Is an io.Writer writing to a vfs.File from its start and onwards
+type fileWriter struct {
+	file   vfs.File
+	offset int64
+}
+
+// Write - Writes p at the current offset and moves the offset past it
+func (W *fileWriter) Write(p []byte) (n int, err error) {
+	n, err = W.file.WriteAt(p, W.offset)
+	W.offset += int64(n)
+
+	return
+}
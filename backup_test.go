@@ -0,0 +1,124 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	restoreName := fmt.Sprintf("%s-restored", testHashMap)
+
+	t.Run("backs up open map and restores it", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 5, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 20; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		var backup bytes.Buffer
+
+		// Execute
+		err = fhm.Backup(&backup)
+
+		// Check
+		assert.NoError(t, err, "backup open map")
+		err = fhm.Set([]byte{0, 0, 1, 0}, []byte{2, 2, 2, 2})
+		assert.NoError(t, err, "map usable after backup")
+		_, err = os.Stat(fmt.Sprintf("%s-backup-1-map.bin", testHashMap))
+		assert.True(t, os.IsNotExist(err), "snapshot removed")
+
+		err = Restore(&backup, restoreName)
+		assert.NoError(t, err, "restore backup")
+		restored, _, err := NewFromExistingFiles(restoreName, nil)
+		assert.NoError(t, err, "open restored map")
+		count, err := restored.Count()
+		assert.NoError(t, err, "count restored records")
+		assert.Equal(t, int64(20), count, "records at time of backup restored")
+		for i := 0; i < 20; i++ {
+			value, err := restored.Get([]byte{0, 0, 0, byte(i)})
+			assert.NoErrorf(t, err, "get restored record #%d", i)
+			assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of restored record #%d", i)
+		}
+		_, err = restored.Get([]byte{0, 0, 1, 0})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "record set after backup not restored")
+
+		// Clean up
+		err = restored.RemoveFiles()
+		assert.NoError(t, err, "restored files can be removed")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("backs up key file of variable length keys", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 0, 4, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte("a key of some length"), []byte{1, 2, 3, 4})
+		assert.NoError(t, err, "sets record")
+		var backup bytes.Buffer
+
+		// Execute
+		err = fhm.Backup(&backup)
+
+		// Check
+		assert.NoError(t, err, "backup open map")
+		err = Restore(&backup, restoreName)
+		assert.NoError(t, err, "restore backup")
+		restored, _, err := NewFromExistingFiles(restoreName, nil)
+		assert.NoError(t, err, "open restored map")
+		value, err := restored.Get([]byte("a key of some length"))
+		assert.NoError(t, err, "get restored record")
+		assert.Equal(t, []byte{1, 2, 3, 4}, value, "value of restored record")
+
+		// Clean up
+		err = restored.RemoveFiles()
+		assert.NoError(t, err, "restored files can be removed")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("rejects damaged backup", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		var backup bytes.Buffer
+		err = fhm.Backup(&backup)
+		assert.NoError(t, err, "backup open map")
+		damaged := backup.Bytes()
+		damaged[len(damaged)/2] ^= 0xff
+
+		// Execute
+		err = Restore(bytes.NewReader(damaged), restoreName)
+
+		// Check
+		assert.Error(t, err, "damaged backup")
+		_, err = os.Stat(fmt.Sprintf("%s-map.bin", restoreName))
+		assert.True(t, os.IsNotExist(err), "no files left behind")
+
+		err = Restore(bytes.NewReader([]byte("not a backup")), restoreName)
+		assert.Error(t, err, "not a backup")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("fails for map held in memory", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewMemoryHashMap(crt.LinearProbing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create hash map in memory")
+
+		// Execute
+		err = fhm.Backup(&bytes.Buffer{})
+
+		// Check
+		assert.Error(t, err, "no files to back up")
+	})
+}
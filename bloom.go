@@ -0,0 +1,213 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/bloom"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"os"
+)
+
+// bloomSystemValueID - Is the id of the system value in the header that marks a file hash map as having a Bloom filter
+const bloomSystemValueID uint8 = 4
+
+// defaultFalsePositiveRate - Is the false positive rate of a Bloom filter rebuilt when its file could not be read
+const defaultFalsePositiveRate float64 = 0.01
+
+// EnableBloomFilter - Adds a Bloom filter that is consulted before the map file when looking up keys, so lookups of
+// keys that don't exist return crt.NoRecordFound right away instead of probing through buckets. This pays off for
+// workloads where most lookups are misses. The filter is built from the records currently stored, is kept in a file
+// next to the map file (see Physical files created) and is loaded again when the files are opened. It is saved when
+// the files are closed, and if the process ends without closing the files it is rebuilt when they are opened again.
+// Keys are added to the filter when records are set, but since a Bloom filter can't forget keys, popped records
+// still count towards the false positive rate until the files are reorganized (see ReorgFiles), which rebuilds it.
+// The filter is not supported for a hash map held in memory.
+//   - expectedKeys is the number of keys the filter is sized for, a higher number costs more memory and disk space
+//   - falsePositiveRate is the rate of lookups of missing keys that still have to probe the map file when
+//     expectedKeys keys are stored, it has to be above 0 and below 1, e.g. 0.01
+//
+// It returns:
+//   - err is a standard error, if the arguments are not valid or the filter could not be built or saved
+func (F *FileHashMap) EnableBloomFilter(expectedKeys int, falsePositiveRate float64) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("EnableBloomFilter")
+	if err != nil {
+		return
+	}
+
+	if expectedKeys < 1 {
+		err = fmt.Errorf("expected keys must be at least 1")
+		return
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		err = fmt.Errorf("false positive rate must be above 0 and below 1")
+		return
+	}
+
+	err = F.enableBloomFilter(int64(expectedKeys), falsePositiveRate)
+
+	return
+}
+
+// enableBloomFilter - Builds, saves and starts using a new Bloom filter, see EnableBloomFilter
+func (F *FileHashMap) enableBloomFilter(expectedKeys int64, falsePositiveRate float64) (err error) {
+	filter := bloom.New(expectedKeys, falsePositiveRate)
+	err = F.fillBloomFilter(filter)
+	if err != nil {
+		err = fmt.Errorf("error while building Bloom filter: %s", err)
+		return
+	}
+
+	err = F.useBloomFilter(filter)
+	if err != nil {
+		return
+	}
+
+	err = F.fileManagement.SetSystemValue(bloomSystemValueID, []byte{1})
+	if err != nil {
+		F.bloom = nil
+		err = fmt.Errorf("error while marking file hash map as having a Bloom filter: %s", err)
+	}
+
+	return
+}
+
+// DisableBloomFilter - Stops using the Bloom filter added by EnableBloomFilter and removes its file
+//
+// It returns:
+//   - err is a standard error, if the file hash map could not be marked as not having a Bloom filter
+func (F *FileHashMap) DisableBloomFilter() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.bloom == nil {
+		return
+	}
+
+	err = F.fileManagement.SetSystemValue(bloomSystemValueID, []byte{0})
+	if err != nil {
+		err = fmt.Errorf("error while marking file hash map as not having a Bloom filter: %s", err)
+		return
+	}
+
+	F.bloom = nil
+	err = F.fileSystem().Remove(storage.GetBloomFileName(F.name))
+	if err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("error while removing Bloom filter file: %s", err)
+		return
+	}
+
+	err = nil
+
+	return
+}
+
+// openBloomFilter - Loads the Bloom filter of a file hash map opened from existing files if it has one, rebuilding it
+// from the records stored if its file is missing, damaged or was not saved after it was last changed
+func (F *FileHashMap) openBloomFilter() (err error) {
+	if sv, svErr := F.fileManagement.GetSystemValue(bloomSystemValueID); svErr != nil || len(sv) == 0 || sv[0] != 1 {
+		return
+	}
+
+	filter, dirty, loadErr := bloom.Load(F.fileSystem(), storage.GetBloomFileName(F.name))
+	if loadErr != nil || dirty {
+		if loadErr != nil {
+			sp := F.fileManagement.GetStorageParameters()
+			filter = bloom.New(sp.NumberOfBucketsAvailable*sp.RecordsPerBucket, defaultFalsePositiveRate)
+		} else {
+			filter = bloom.New(filter.ExpectedKeys(), filter.FalsePositiveRate())
+		}
+		err = F.fillBloomFilter(filter)
+		if err != nil {
+			err = fmt.Errorf("error while rebuilding Bloom filter: %s", err)
+			return
+		}
+	}
+
+	if F.readOnly {
+		F.bloom = filter
+		return
+	}
+
+	if loadErr != nil || dirty {
+		err = F.useBloomFilter(filter)
+		return
+	}
+
+	err = bloom.MarkDirty(F.fileSystem(), storage.GetBloomFileName(F.name))
+	if err != nil {
+		return
+	}
+
+	F.bloom = filter
+
+	return
+}
+
+// useBloomFilter - Saves filter to file, marks the file as in use and starts using filter
+func (F *FileHashMap) useBloomFilter(filter *bloom.Filter) (err error) {
+	fileName := storage.GetBloomFileName(F.name)
+
+	err = filter.Save(F.fileSystem(), fileName)
+	if err == nil {
+		err = syncDirOf(fileName, F.options)
+	}
+	if err == nil {
+		err = bloom.MarkDirty(F.fileSystem(), fileName)
+	}
+	if err != nil {
+		return
+	}
+
+	F.bloom = filter
+
+	return
+}
+
+// saveBloomFilter - Saves the Bloom filter, if any, to file with the file marked as not in use
+func (F *FileHashMap) saveBloomFilter() (err error) {
+	if F.bloom == nil || F.readOnly {
+		return
+	}
+
+	err = F.bloom.Save(F.fileSystem(), storage.GetBloomFileName(F.name))
+
+	return
+}
+
+// fillBloomFilter - Adds the stored key of every record that is stored and has not expired to filter
+func (F *FileHashMap) fillBloomFilter(filter *bloom.Filter) (err error) {
+	var records []model.Record
+
+	nBuckets := F.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+	getBucket := F.bucketScanner()
+	for i := int64(0); i < nBuckets; i++ {
+		records, err = F.readBucketLocked(getBucket, i)
+		if err != nil {
+			return
+		}
+
+		for _, r := range records {
+			if r.State == model.RecordOccupied && !F.hasExpired(r) {
+				filter.Add(r.Key)
+			}
+		}
+	}
+
+	return
+}
+
+// addToBloomFilter - Adds a stored key to the Bloom filter, if any, which is done before the record is set so the
+// filter never misses a key that is stored
+func (F *FileHashMap) addToBloomFilter(storedKey []byte) {
+	if F.bloom != nil {
+		F.bloom.Add(storedKey)
+	}
+}
+
+// definitelyMissing - Returns true if the Bloom filter, if any, tells that no record with the stored key is stored
+func (F *FileHashMap) definitelyMissing(storedKey []byte) bool {
+	return F.bloom != nil && !F.bloom.MayContain(storedKey)
+}
@@ -0,0 +1,162 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestEnableBloomFilter(t *testing.T) {
+	testCases := []TestCaseOperations{
+		{crtName: "SeparateChaining", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: nil},
+		{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: nil},
+		{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood, hFunc: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("finds stored keys and rejects missing keys for %s", tc.crtName), func(t *testing.T) {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, tc.crt, tc.buckets, tc.rpb, tc.keyLength, tc.valueLength, tc.hFunc)
+			assert.NoError(t, err, "create new file hash map struct")
+			for i := 0; i < 50; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				err = fhm.Set(key, make([]byte, tc.valueLength))
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+
+			// Execute
+			err = fhm.EnableBloomFilter(200, 0.01)
+			assert.NoError(t, err, "enables Bloom filter")
+			for i := 50; i < 100; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				err = fhm.Set(key, make([]byte, tc.valueLength))
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			fhm.CloseFiles()
+			fhm, _, err = NewFromExistingFiles(testHashMap, tc.hFunc)
+			assert.NoError(t, err, "reopens file hash map")
+
+			// Check
+			assert.NotNil(t, fhm.bloom, "Bloom filter loaded")
+			for i := 0; i < 100; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				_, err = fhm.Get(key)
+				assert.NoErrorf(t, err, "gets record #%d", i)
+			}
+			missing := 0
+			for i := 100; i < 200; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				if !fhm.bloom.MayContain(key) {
+					missing++
+				}
+				_, err = fhm.Get(key)
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "record #%d not found", i)
+			}
+			assert.Greater(t, missing, 90, "most missing keys rejected by Bloom filter")
+
+			key := make([]byte, tc.keyLength)
+			key[0] = 1
+			missingKey := make([]byte, tc.keyLength)
+			missingKey[0] = 200
+			values, errs, err := fhm.GetBatch([][]byte{missingKey, key})
+			if assert.NoError(t, err, "gets batch") && assert.Len(t, errs, 2, "error per key") {
+				assert.ErrorIs(t, errs[0], crt.NoRecordFound{}, "missing key not found in batch")
+				assert.NoError(t, errs[1], "gets stored key in batch")
+				assert.NotNil(t, values[1], "value of stored key in batch")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+			_, err = os.Stat(storage.GetBloomFileName(testHashMap))
+			assert.True(t, os.IsNotExist(err), "Bloom filter file removed")
+		})
+	}
+
+	t.Run("rebuilds a filter that was not saved", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableBloomFilter(100, 0.01)
+		assert.NoError(t, err, "enables Bloom filter")
+		key := make([]byte, 16)
+		key[0] = 42
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+
+		// Execute, opens the files without closing the first instance, as after a crash
+		other, _, err := NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens file hash map again")
+		_, errGet := other.Get(key)
+
+		// Check
+		assert.NoError(t, errGet, "record set after the filter was saved is found")
+
+		// Clean up
+		other.CloseFiles()
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("rebuilds the filter when reorganizing and stops using it when disabled", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableBloomFilter(100, 0.01)
+		assert.NoError(t, err, "enables Bloom filter")
+		key := make([]byte, 16)
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{NumberOfBucketsNeeded: 200, RecordsPerBucket: 2}, false)
+		assert.NoError(t, err, "reorganizes files")
+		reorg, _, errReorg := NewFromExistingFiles(testHashMap+"-reorg", nil)
+
+		// Check
+		assert.NoError(t, errReorg, "opens reorganized files")
+		assert.NotNil(t, reorg.bloom, "reorganized files have Bloom filter")
+		_, err = reorg.Get(key)
+		assert.NoError(t, err, "gets record from reorganized files")
+		err = reorg.DisableBloomFilter()
+		assert.NoError(t, err, "disables Bloom filter")
+		_, err = os.Stat(storage.GetBloomFileName(testHashMap + "-reorg"))
+		assert.True(t, os.IsNotExist(err), "Bloom filter file removed")
+
+		// Clean up
+		err = reorg.RemoveFiles()
+		assert.NoError(t, err, "removes reorganized files")
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes original files")
+	})
+
+	t.Run("rejects invalid arguments", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errKeys := fhm.EnableBloomFilter(0, 0.01)
+		errRate := fhm.EnableBloomFilter(10, 1)
+
+		// Check
+		assert.Error(t, errKeys, "expected keys rejected")
+		assert.Error(t, errRate, "false positive rate rejected")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,51 @@
+package filehashmap
+
+import "fmt"
+
+// brentInserter - Implemented by file management that supports Brent's variation of insertion
+type brentInserter interface {
+	SetBrentInsertion(enabled bool) (err error)
+}
+
+// EnableBrentInsertion - Turns on Brent's variation of insertion for the DoubleHashing CRT. When a new key is added
+// and its probe sequence is long, a record already stored along the way may be moved further along its own probe
+// sequence to a free record, if that makes the probes needed to reach both records fewer in total, and the new key
+// takes its place. This makes adding new keys slower, since more buckets are read, in exchange for shorter probe
+// sequences and faster gets, which suits read heavy file hash maps that are filled to a high load factor. Records
+// that exist are updated in place as usual, and SetBatch doesn't move records. It is not persisted, so it has to be
+// enabled each time the FileHashMap is opened, but it is kept when the map grows automatically (see EnableAutoGrow).
+//
+// It returns:
+//   - err is a standard error, if the CRT is not DoubleHashing
+func (F *FileHashMap) EnableBrentInsertion() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	inserter, ok := F.fileManagement.(brentInserter)
+	if !ok {
+		err = fmt.Errorf("brent's variation is only supported by double hashing")
+		return
+	}
+
+	err = inserter.SetBrentInsertion(true)
+	if err != nil {
+		err = fmt.Errorf("error while enabling brent's variation: %s", err)
+		return
+	}
+
+	F.brentInsertion = true
+
+	return
+}
+
+// DisableBrentInsertion - Turns off Brent's variation of insertion turned on by EnableBrentInsertion, records already
+// moved stay where they are
+func (F *FileHashMap) DisableBrentInsertion() {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.brentInsertion = false
+	if inserter, ok := F.fileManagement.(brentInserter); ok {
+		_ = inserter.SetBrentInsertion(false)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnableBrentInsertion(t *testing.T) {
+	t.Run("sets, updates and pops records with brent's variation", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.DoubleHashing, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.EnableBrentInsertion()
+		assert.NoError(t, err, "enable brent insertion")
+
+		// Execute
+		for i := 0; i < 90; i++ {
+			err = fhm.Set([]byte{0, 0, byte(i), byte(i)}, []byte{1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		err = fhm.Set([]byte{0, 0, 5, 5}, []byte{2, 2, 2, 2})
+		assert.NoError(t, err, "updates record")
+		_, err = fhm.Pop([]byte{0, 0, 6, 6})
+		assert.NoError(t, err, "pops record")
+
+		// Check
+		for i := 0; i < 90; i++ {
+			value, err := fhm.Get([]byte{0, 0, byte(i), byte(i)})
+			switch i {
+			case 5:
+				assert.NoError(t, err, "gets updated record")
+				assert.Equal(t, []byte{2, 2, 2, 2}, value, "updated value")
+			case 6:
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record is gone")
+			default:
+				assert.NoErrorf(t, err, "gets record #%d", i)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+			}
+		}
+		count, err := fhm.Count()
+		assert.NoError(t, err, "count records")
+		assert.Equal(t, int64(89), count, "records after pop")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for other than double hashing", func(t *testing.T) {
+		for _, crtType := range []int{crt.SeparateChaining, crt.LinearProbing, crt.QuadraticProbing} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crtType, 10, 1, 4, 4, nil)
+			assert.NoErrorf(t, err, "create file hash map with crt %d", crtType)
+
+			// Execute
+			err = fhm.EnableBrentInsertion()
+
+			// Check
+			assert.Errorf(t, err, "brent insertion with crt %d", crtType)
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "remove files")
+		}
+	})
+}
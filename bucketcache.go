@@ -0,0 +1,87 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+)
+
+// bucketCacher - Implemented by file management that can keep recently read buckets in memory
+type bucketCacher interface {
+	SetBucketCache(budget int64) (err error)
+}
+
+// EnableBucketCache - Keeps recently read buckets in memory, so buckets visited again while probing, or by repeated
+// gets of the same keys, are not read from disk again. Buckets are cached in a least recently used manner within a
+// budget of bytes and are invalidated whenever they are written, e.g. by Set or Pop. It is only supported for the
+// open addressing CRTs (LinearProbing, QuadraticProbing and DoubleHashing), other CRTs return an error. The cache is
+// not persisted, so it has to be enabled each time the FileHashMap is opened, but it is kept when the map grows
+// automatically (see EnableAutoGrow). Changes made to the files by other processes are not seen through the cache.
+//   - budget is the maximum number of bytes of buckets to cache, which has to be above zero
+//
+// It returns:
+//   - err is a standard error, if the CRT doesn't support caching or budget is not above zero
+func (F *FileHashMap) EnableBucketCache(budget int) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.checkBucketCache(budget)
+	if err != nil {
+		return
+	}
+
+	err = F.enableBucketCache(budget)
+
+	return
+}
+
+// checkBucketCache - Checks that the bucket cache can be enabled with budget, to be called with the lock held
+func (F *FileHashMap) checkBucketCache(budget int) (err error) {
+	if budget <= 0 {
+		err = fmt.Errorf("bucket cache budget must be above zero")
+		return
+	}
+
+	if !F.supportsBucketCache() {
+		err = fmt.Errorf("bucket cache is not supported by the collision resolution technique")
+	}
+
+	return
+}
+
+// supportsBucketCache - Returns true if the collision resolution technique can cache buckets, to be called with the
+// lock held
+func (F *FileHashMap) supportsBucketCache() bool {
+	switch F.fileManagement.GetStorageParameters().CollisionResolutionTechnique {
+	case crt.SeparateChaining, crt.Hybrid, crt.LinearHashing:
+		return false
+	}
+	_, ok := F.fileManagement.(bucketCacher)
+
+	return ok
+}
+
+// enableBucketCache - Is the implementation of EnableBucketCache once checked by checkBucketCache, to be called with
+// the lock held
+func (F *FileHashMap) enableBucketCache(budget int) (err error) {
+	err = F.fileManagement.(bucketCacher).SetBucketCache(int64(budget))
+	if err != nil {
+		err = fmt.Errorf("error while enabling bucket cache: %s", err)
+		return
+	}
+
+	F.bucketCacheBudget = int64(budget)
+
+	return
+}
+
+// DisableBucketCache - Releases any cache of buckets made by EnableBucketCache, after which every bucket is read
+// from disk again.
+func (F *FileHashMap) DisableBucketCache() {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.bucketCacheBudget = 0
+	if cacher, ok := F.fileManagement.(bucketCacher); ok {
+		_ = cacher.SetBucketCache(0)
+	}
+}
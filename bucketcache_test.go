@@ -0,0 +1,87 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestEnableBucketCache(t *testing.T) {
+	t.Run("keeps records consistent through the cache for open addressing", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("keeps records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				err = fhm.EnableBucketCache(1024)
+				assert.NoError(t, err, "enables bucket cache")
+
+				keys := make([][]byte, 50)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					value := make([]byte, test.valueLength)
+					copy(value, keys[i])
+					err = fhm.Set(keys[i], value)
+					assert.NoErrorf(t, err, "sets record #%d", i)
+					_, err = fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+				}
+
+				// Execute
+				_, err = fhm.Pop(keys[0])
+				assert.NoError(t, err, "pops record")
+				err = fhm.Set(keys[1], make([]byte, test.valueLength))
+				assert.NoError(t, err, "updates record")
+
+				// Check
+				_, err = fhm.Get(keys[0])
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record is gone")
+				value, err := fhm.Get(keys[1])
+				assert.NoError(t, err, "gets updated record")
+				assert.Equal(t, make([]byte, test.valueLength), value, "updated value")
+				for i := 2; i < len(keys); i++ {
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(keys[i][:test.valueLength], value), "value of record #%d", i)
+				}
+
+				// Clean up
+				fhm.DisableBucketCache()
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("fails for other CRTs and invalid budgets", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errCRT := fhm.EnableBucketCache(1024)
+		errBudget := fhm.EnableBucketCache(0)
+
+		// Check
+		assert.Error(t, errCRT, "bucket cache not supported")
+		assert.Error(t, errBudget, "budget must be above zero")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,52 @@
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+)
+
+// scanChunkBytes - Is the largest number of bytes of buckets read at once when scanning all buckets
+const scanChunkBytes int64 = 1 << 20
+
+// bucketsReader - Implemented by file management that reads several buckets in a single read, see bucketScanner
+type bucketsReader interface {
+	GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error)
+}
+
+// bucketGetter - Gets a bucket and an iterator over its overflow records, as GetBucket of the file management does
+type bucketGetter func(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error)
+
+// bucketScanner - Returns a bucketGetter for scanning buckets in increasing order, which reads buckets in chunks of
+// scanChunkBytes and keeps the last chunk in memory if the file management supports it. Any other bucket than those in
+// the chunk is read starting a new chunk. The chunk is read again if the file hash map was changed since it was read,
+// i.e. if the mutation sequence number or the file management differs, so the lock may be released between buckets
+// as Iterator and Export do. The bucketGetter has to be called with the lock held.
+func (F *FileHashMap) bucketScanner() (getBucket bucketGetter) {
+	var buckets []model.Bucket
+	var overflowIterators []*overflow.Records
+	var first, seq int64
+	var fm FileManagement
+
+	getBucket = func(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error) {
+		reader, ok := F.fileManagement.(bucketsReader)
+		if !ok {
+			bucket, overflowIterator, err = F.fileManagement.GetBucket(bucketNo)
+			return
+		}
+
+		if fm != F.fileManagement || seq != F.lastSeq() || bucketNo < first || bucketNo >= first+int64(len(buckets)) {
+			buckets, overflowIterators, err = reader.GetBuckets(bucketNo, scanChunkBytes)
+			if err != nil {
+				buckets = nil
+				return
+			}
+			first, seq, fm = bucketNo, F.lastSeq(), F.fileManagement
+		}
+
+		bucket, overflowIterator = buckets[bucketNo-first], overflowIterators[bucketNo-first]
+
+		return
+	}
+
+	return
+}
@@ -0,0 +1,129 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// randomReadThreshold - Median latency of random bucket reads at or above which reads are considered to be served
+// by the storage medium rather than by the page cache, and read-ahead is considered a waste
+const randomReadThreshold = 50 * time.Microsecond
+
+// maxFlushThreshold - Upper limit of the access count flush threshold set by Calibrate
+const maxFlushThreshold = 4096
+
+// Calibration - Result of a call to Calibrate, with measured latencies and the settings that were applied
+//   - RandomReadLatency is the median latency of reading a bucket at a random position
+//   - SequentialReadLatency is the median latency of reading a bucket following the previous one
+//   - SyncLatency is the latency of committing the files to stable storage
+//   - AccessHints is true if access hints were enabled (see EnableAccessHints)
+//   - FlushThreshold is the access count flush threshold that was set, zero if access counting is not enabled
+type Calibration struct {
+	RandomReadLatency     time.Duration
+	SequentialReadLatency time.Duration
+	SyncLatency           time.Duration
+	AccessHints           bool
+	FlushThreshold        int
+}
+
+// Calibrate - Makes a quick measurement of the storage medium by reading buckets of the opened files, at random
+// positions as well as in sequence, and by syncing the files. Nothing is written apart from the sync. The measurements
+// are then used to tune the settings that depend on the storage medium:
+//   - If random reads are slow enough to not be served from the page cache, access hints are enabled to stop the
+//     kernel from reading ahead on every lookup (see EnableAccessHints).
+//   - If access counting is enabled, the flush threshold is set to the number of random reads that take as long as a
+//     sync, so that writing pending counts behind costs about as much as a sync (see EnableAccessCounting).
+//
+// Calibration is optional and meant to be called once directly after the files are opened, since it takes a while
+// on slow media and holds the lock while measuring. The probe limits of the collision resolution techniques are part
+// of the file format and are not affected.
+//   - samples is the number of reads to make of each kind, values below 1 are set to 1
+//
+// It returns:
+//   - calibration is a Calibration struct with measurements and applied settings
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) Calibrate(samples int) (calibration Calibration, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if samples < 1 {
+		samples = 1
+	}
+
+	nBuckets := F.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+
+	// Random reads
+	latencies := make([]time.Duration, samples)
+	for i := range latencies {
+		latencies[i], err = F.timeBucketRead(rand.Int63n(nBuckets))
+		if err != nil {
+			return
+		}
+	}
+	calibration.RandomReadLatency = median(latencies)
+
+	// Sequential reads
+	bucketNo := rand.Int63n(nBuckets)
+	for i := range latencies {
+		latencies[i], err = F.timeBucketRead((bucketNo + int64(i)) % nBuckets)
+		if err != nil {
+			return
+		}
+	}
+	calibration.SequentialReadLatency = median(latencies)
+
+	// Sync
+	start := time.Now()
+	err = F.fileManagement.Sync()
+	if err != nil {
+		return
+	}
+	calibration.SyncLatency = time.Since(start)
+
+	// Apply settings
+	if calibration.RandomReadLatency >= randomReadThreshold {
+		err = advise(F.fileManagement, storage.AdviceRandom)
+		if err != nil {
+			err = fmt.Errorf("error while advising random access: %s", err)
+			return
+		}
+		F.accessHints = true
+	}
+	calibration.AccessHints = F.accessHints
+
+	if F.accessCounter != nil {
+		threshold := maxFlushThreshold
+		if calibration.RandomReadLatency > 0 {
+			threshold = int(calibration.SyncLatency / calibration.RandomReadLatency)
+		}
+		if threshold < 1 {
+			threshold = 1
+		}
+		if threshold > maxFlushThreshold {
+			threshold = maxFlushThreshold
+		}
+		F.accessCounter.flushThreshold = threshold
+		calibration.FlushThreshold = threshold
+	}
+
+	return
+}
+
+// timeBucketRead - Returns the time it takes to read a bucket, to be called with the lock held
+func (F *FileHashMap) timeBucketRead(bucketNo int64) (latency time.Duration, err error) {
+	start := time.Now()
+	_, _, err = F.fileManagement.GetBucket(bucketNo)
+	latency = time.Since(start)
+
+	return
+}
+
+// median - Returns the median of the given durations, which are sorted in place
+func median(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	return durations[len(durations)/2]
+}
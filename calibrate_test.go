@@ -0,0 +1,36 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+	t.Run("measures latencies and tunes settings", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 1000, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		fhm.EnableAccessCounting(100)
+
+		// Execute
+		calibration, err := fhm.Calibrate(50)
+
+		// Check
+		assert.NoError(t, err, "calibrates")
+		assert.Greater(t, calibration.RandomReadLatency, time.Duration(0), "random read latency measured")
+		assert.Greater(t, calibration.SequentialReadLatency, time.Duration(0), "sequential read latency measured")
+		assert.Greater(t, calibration.SyncLatency, time.Duration(0), "sync latency measured")
+		assert.Equal(t, calibration.AccessHints, fhm.accessHints, "access hints reported")
+		assert.Equal(t, calibration.FlushThreshold, fhm.accessCounter.flushThreshold, "flush threshold applied")
+		assert.GreaterOrEqual(t, calibration.FlushThreshold, 1, "flush threshold within lower limit")
+		assert.LessOrEqual(t, calibration.FlushThreshold, maxFlushThreshold, "flush threshold within upper limit")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
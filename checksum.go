@@ -0,0 +1,80 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"hash/crc32"
+)
+
+// checksumSystemValueID - Is the id of the system value in the header that marks a file hash map as storing a
+// checksum with each record
+const checksumSystemValueID uint8 = 3
+
+// checksumLength - Is the number of bytes at the end of each stored value holding the checksum
+const checksumLength int = 4
+
+// withChecksum - Returns the stored value with the checksum of key and stored value appended, if checksums are stored
+func (F *FileHashMap) withChecksum(key, stored []byte) (checked []byte) {
+	if !F.checksums {
+		checked = stored
+		return
+	}
+
+	checked = make([]byte, len(stored), len(stored)+checksumLength)
+	copy(checked, stored)
+	checked = binary.LittleEndian.AppendUint32(checked, recordChecksum(key, stored))
+
+	return
+}
+
+// withoutChecksum - Returns a value in the form it is stored in files without any checksum at the end of it
+func (F *FileHashMap) withoutChecksum(stored []byte) (value []byte) {
+	if !F.checksums || len(stored) < checksumLength {
+		return stored
+	}
+
+	return stored[:len(stored)-checksumLength]
+}
+
+// checkRecord - Returns an error of type crt.CorruptRecord if checksums are stored and the checksum of record doesn't
+// match its key and value
+func (F *FileHashMap) checkRecord(record model.Record) (err error) {
+	if !F.checksums {
+		return
+	}
+
+	n := len(record.Value) - checksumLength
+	if n < 0 || binary.LittleEndian.Uint32(record.Value[n:]) != recordChecksum(record.Key, record.Value[:n]) {
+		err = crt.CorruptRecord{Address: record.RecordAddress, IsOverflow: record.IsOverflow}
+	}
+
+	return
+}
+
+// recordChecksum - Returns the CRC32 checksum of key followed by the stored value
+func recordChecksum(key, stored []byte) uint32 {
+	checksum := crc32.ChecksumIEEE(key)
+
+	return crc32.Update(checksum, crc32.IEEETable, stored)
+}
+
+// userValueLength - Returns the length of values as given by the caller, which is the stored value length less any
+// expiry time, key address and checksum
+func (F *FileHashMap) userValueLength() (valueLength int) {
+	valueLength = int(F.fileManagement.GetStorageParameters().ValueLength)
+	if F.ttl != nil {
+		valueLength -= ttlLength
+	}
+	if F.keyFile != nil {
+		valueLength -= keyAddressLength
+	}
+	if F.checksums {
+		valueLength -= checksumLength
+	}
+	if F.timestamps {
+		valueLength -= timestampsLength
+	}
+
+	return
+}
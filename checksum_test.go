@@ -0,0 +1,119 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	t.Run("detects corrupt records for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("detects corrupt records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc, FeatureChecksums)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 50)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				for _, record := range records[:25] {
+					err = fhm.Set(record.Key, record.Value)
+					assert.NoError(t, err, "sets record")
+				}
+				err = fhm.SetBatch(records[25:])
+				assert.NoError(t, err, "sets records in batch")
+
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "reopens file hash map")
+
+				// Corrupt the value of the first record below the checksum layer
+				stored, err := fhm.fileManagement.Get(model.Record{Key: records[0].Key})
+				assert.NoError(t, err, "gets stored record")
+				stored.Value[0] ^= 0xff
+				err = fhm.fileManagement.Set(model.Record{Key: stored.Key, Value: stored.Value})
+				assert.NoError(t, err, "corrupts stored record")
+
+				// Execute
+				_, errGet := fhm.Get(records[0].Key)
+				_, errPop := fhm.Pop(records[0].Key)
+				values, errs, errBatch := fhm.GetBatch([][]byte{records[0].Key, records[1].Key})
+
+				// Check
+				assert.ErrorIs(t, errGet, crt.CorruptRecord{}, "get detects corrupt record")
+				assert.ErrorIs(t, errPop, crt.CorruptRecord{}, "pop detects corrupt record")
+				assert.NoError(t, errBatch, "gets batch")
+				assert.ErrorIs(t, errs[0], crt.CorruptRecord{}, "get batch detects corrupt record")
+				assert.NoError(t, errs[1], "get batch gets intact record")
+				assert.True(t, utils.IsEqual(records[1].Value, values[1]), "value of intact record in batch")
+
+				for i, record := range records[1:] {
+					value, err := fhm.Get(record.Key)
+					assert.NoErrorf(t, err, "gets intact record #%d", i+1)
+					assert.Truef(t, utils.IsEqual(record.Value, value), "value of intact record #%d", i+1)
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("keeps checksums when reorganizing", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 5, 10, nil, FeatureChecksums)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{1, 2, 3, 4, 5}
+		value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record")
+		err = fhm.Set(key[:4], value)
+		assert.ErrorIs(t, err, crt.WrongLength{}, "rejects wrong key length")
+		err = fhm.Set(key, value[:9])
+		assert.ErrorIs(t, err, crt.WrongLength{}, "rejects wrong value length")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{NumberOfBucketsNeeded: 100, RecordsPerBucket: 2, ValueExtension: 2}, false)
+
+		// Check
+		assert.NoError(t, err, "reorganizes files")
+
+		fhm, _, err = NewFromExistingFiles(fmt.Sprintf("%s-reorg", testHashMap), nil)
+		assert.NoError(t, err, "opens reorganized files")
+		assert.True(t, fhm.checksums, "checksums kept")
+		got, err := fhm.Get(key)
+		assert.NoError(t, err, "gets record")
+		assert.True(t, utils.IsEqual(append(value, 0, 0), got), "value extended")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes reorganized files")
+		err = os.Remove(fmt.Sprintf("%s-map.bin", testHashMap))
+		assert.NoError(t, err, "removes original map file")
+	})
+}
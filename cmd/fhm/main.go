@@ -0,0 +1,344 @@
+// Command fhm performs operational tasks on file hash maps from the command line, built on the public API of the
+// filehashmap package. File hash maps created with a custom hash algorithm can not be handled.
+//
+// Usage:
+//
+//	fhm inspect <name>
+//	fhm dump [-format hex|ndjson|binary] <name>
+//	fhm stat [-distribution] <name>
+//	fhm verify <name>
+//	fhm reorg [-crt <technique>] [-buckets <n>] [-records-per-bucket <n>] [-key-extension <n>] [-value-extension <n>]
+//	          [-target <name>] [-replace] [-keep-backup] [-verify-samples <n>] [-force] <name>
+//
+// The name is the name of the file hash map including path, i.e. without the -map.bin suffix of its map file.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gostonefire/filehashmap"
+	"github.com/gostonefire/filehashmap/crt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// crtTypes - Maps the names accepted by the -crt flag to collision resolution techniques
+var crtTypes = map[string]int{
+	"SeparateChaining": crt.SeparateChaining,
+	"LinearProbing":    crt.LinearProbing,
+	"QuadraticProbing": crt.QuadraticProbing,
+	"DoubleHashing":    crt.DoubleHashing,
+	"Hybrid":           crt.Hybrid,
+	"RobinHood":        crt.RobinHood,
+	"CuckooHashing":    crt.CuckooHashing,
+	"Hopscotch":        crt.Hopscotch,
+	"LinearHashing":    crt.LinearHashing,
+}
+
+// hashFunctionNames - Maps the hash functions of the internal hash algorithm to the names printed by inspect
+var hashFunctionNames = map[filehashmap.HashFunction]string{
+	filehashmap.HashCRC32:   "CRC32",
+	filehashmap.HashSipHash: "SipHash",
+	filehashmap.HashXXHash:  "XXHash",
+	filehashmap.HashFNV1a:   "FNV-1a",
+	filehashmap.HashMurmur3: "Murmur3",
+}
+
+// errProblemsFound - Is returned by verify if the files have problems, which gives exit code 1 without usage
+var errProblemsFound = errors.New("problems found")
+
+const usage = `usage: fhm <command> [flags] <name>
+
+commands:
+  inspect  print what the file headers tell
+  dump     write all records to stdout
+  stat     print statistics on usage and distribution over buckets
+  verify   check the integrity of the files
+  reorg    reorganize the files
+
+run fhm <command> -h for the flags of a command
+`
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errProblemsFound):
+		os.Exit(1)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "fhm: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run - Runs the command given in args, writing its output to out
+//   - args is the command line arguments, without the name of the program
+//   - out is the writer to write output to
+//
+// It returns:
+//   - err is a standard error, if the arguments were wrong or the command failed
+func run(args []string, out io.Writer) (err error) {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		err = fmt.Errorf("no command given")
+		return
+	}
+
+	commands := map[string]func(args []string, out io.Writer) error{
+		"inspect": inspect,
+		"dump":    dump,
+		"stat":    stat,
+		"verify":  verify,
+		"reorg":   reorg,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", args[0])
+		return
+	}
+
+	err = command(args[1:], out)
+
+	return
+}
+
+// parseArgs - Parses flags of a command from args and returns the name of the file hash map that has to follow them
+func parseArgs(flags *flag.FlagSet, args []string) (name string, err error) {
+	err = flags.Parse(args)
+	if err != nil {
+		return
+	}
+	if flags.NArg() != 1 {
+		err = fmt.Errorf("%s takes exactly one file hash map name", flags.Name())
+		return
+	}
+
+	name = flags.Arg(0)
+
+	return
+}
+
+// inspect - Prints what the headers of the files of the file hash map tell, see filehashmap.InspectFiles
+func inspect(args []string, out io.Writer) (err error) {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	name, err := parseArgs(flags, args)
+	if err != nil {
+		return
+	}
+
+	header, err := filehashmap.InspectFiles(name)
+	if err != nil {
+		return
+	}
+
+	crtName := fmt.Sprintf("unknown (%d)", header.CollisionResolutionTechnique)
+	for n, crtType := range crtTypes {
+		if crtType == header.CollisionResolutionTechnique {
+			crtName = n
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "Name:                %s\n", name)
+	_, _ = fmt.Fprintf(out, "Technique:           %s\n", crtName)
+	_, _ = fmt.Fprintf(out, "Key length:          %d\n", header.KeyLength)
+	_, _ = fmt.Fprintf(out, "Value length:        %d\n", header.ValueLength)
+	_, _ = fmt.Fprintf(out, "Buckets needed:      %d\n", header.NumberOfBucketsNeeded)
+	_, _ = fmt.Fprintf(out, "Buckets available:   %d\n", header.NumberOfBucketsAvailable)
+	_, _ = fmt.Fprintf(out, "Records per bucket:  %d\n", header.RecordsPerBucket)
+	_, _ = fmt.Fprintf(out, "Internal hash:       %t\n", header.InternalHash)
+	if header.InternalHash {
+		hashName, ok := hashFunctionNames[header.HashFunction]
+		if !ok {
+			hashName = fmt.Sprintf("unknown (%d)", header.HashFunction)
+		}
+		_, _ = fmt.Fprintf(out, "Hash function:       %s\n", hashName)
+	}
+	_, _ = fmt.Fprintf(out, "Format version:      %d\n", header.FormatVersion)
+	_, _ = fmt.Fprintf(out, "Last sequence:       %d\n", header.LastSeq)
+	if header.CountsKnown {
+		_, _ = fmt.Fprintf(out, "Records:             %d\n", header.OccupiedRecords)
+		_, _ = fmt.Fprintf(out, "Deleted records:     %d\n", header.DeletedRecords)
+	} else {
+		_, _ = fmt.Fprintln(out, "Records:             unknown, use stat to count them")
+	}
+	if header.ClosedAt.IsZero() {
+		_, _ = fmt.Fprintln(out, "Closed at:           never")
+	} else {
+		_, _ = fmt.Fprintf(out, "Closed at:           %s\n", header.ClosedAt.Format(time.RFC3339))
+	}
+	_, _ = fmt.Fprintf(out, "Map file size:       %d\n", header.MapFileSize)
+	_, _ = fmt.Fprintf(out, "Overflow file size:  %d\n", header.OverflowFileSize)
+
+	return
+}
+
+// dump - Writes all records of the file hash map to out
+func dump(args []string, out io.Writer) (err error) {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := flags.String("format", "hex", "output format, hex for one record per line as hex key and value, or ndjson or binary as written by ExportRecords")
+	name, err := parseArgs(flags, args)
+	if err != nil {
+		return
+	}
+
+	fhm, _, err := filehashmap.NewFromExistingFilesReadOnly(name, nil)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	switch *format {
+	case "hex":
+		err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+			_, err = fmt.Fprintf(out, "%s %s\n", hex.EncodeToString(key), hex.EncodeToString(value))
+			return
+		})
+	case "ndjson":
+		_, err = fhm.ExportRecords(out, filehashmap.StreamNDJSON)
+	case "binary":
+		_, err = fhm.ExportRecords(out, filehashmap.StreamBinary)
+	default:
+		err = fmt.Errorf("format has to be one of hex, ndjson or binary")
+	}
+
+	return
+}
+
+// stat - Prints statistics on usage and distribution over buckets of the file hash map
+func stat(args []string, out io.Writer) (err error) {
+	flags := flag.NewFlagSet("stat", flag.ContinueOnError)
+	distribution := flags.Bool("distribution", false, "also print the number of records in each bucket")
+	name, err := parseArgs(flags, args)
+	if err != nil {
+		return
+	}
+
+	fhm, _, err := filehashmap.NewFromExistingFilesReadOnly(name, nil)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	s, err := fhm.Stat(*distribution)
+	if err != nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "Records:                  %d\n", s.Records)
+	_, _ = fmt.Fprintf(out, "Map file records:         %d\n", s.MapFileRecords)
+	_, _ = fmt.Fprintf(out, "Overflow records:         %d\n", s.OverflowRecords)
+	_, _ = fmt.Fprintf(out, "Overflow deleted records: %d\n", s.OverflowDeletedRecords)
+	_, _ = fmt.Fprintf(out, "Load factor:              %.4f\n", s.LoadFactor)
+	_, _ = fmt.Fprintf(out, "Map file bytes:           %d\n", s.MapFileBytes)
+	_, _ = fmt.Fprintf(out, "Overflow file bytes:      %d\n", s.OverflowFileBytes)
+	_, _ = fmt.Fprintf(out, "Max probe length:         %d\n", s.MaxProbeLength)
+	_, _ = fmt.Fprintf(out, "Mean probe length:        %.4f\n", s.MeanProbeLength)
+	_, _ = fmt.Fprintf(out, "Max chain length:         %d\n", s.MaxChainLength)
+	_, _ = fmt.Fprintf(out, "Mean chain length:        %.4f\n", s.MeanChainLength)
+	_, _ = fmt.Fprintf(out, "Last sequence:            %d\n", s.LastSeq)
+	if *distribution {
+		_, _ = fmt.Fprintln(out, "Bucket distribution:")
+		for bucketNo, records := range s.BucketDistribution {
+			_, _ = fmt.Fprintf(out, "  %d %d\n", bucketNo, records)
+		}
+	}
+
+	return
+}
+
+// verify - Checks the integrity of the files of the file hash map, printing any problems found
+func verify(args []string, out io.Writer) (err error) {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	name, err := parseArgs(flags, args)
+	if err != nil {
+		return
+	}
+
+	fhm, _, err := filehashmap.NewFromExistingFilesReadOnly(name, nil)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	report, err := fhm.Verify()
+	if err != nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "Buckets checked:          %d\n", report.Buckets)
+	_, _ = fmt.Fprintf(out, "Records checked:          %d\n", report.Records)
+	_, _ = fmt.Fprintf(out, "Overflow records checked: %d\n", report.OverflowRecords)
+	if report.OK() {
+		_, _ = fmt.Fprintln(out, "OK")
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "%d problem(s) found:\n", len(report.Problems))
+	for _, problem := range report.Problems {
+		file := "map"
+		if problem.IsOverflow {
+			file = "overflow"
+		}
+		_, _ = fmt.Fprintf(out, "  bucket %d, %s file address %d: %s\n", problem.BucketNo, file, problem.Address, problem.Description)
+	}
+	err = errProblemsFound
+
+	return
+}
+
+// reorg - Reorganizes the files of the file hash map, see filehashmap.ReorgFiles
+func reorg(args []string, out io.Writer) (err error) {
+	var reorgConf filehashmap.ReorgConf
+
+	names := make([]string, 0, len(crtTypes))
+	for crtName := range crtTypes {
+		names = append(names, crtName)
+	}
+	sort.Strings(names)
+
+	flags := flag.NewFlagSet("reorg", flag.ContinueOnError)
+	crtName := flags.String("crt", "", "new collision resolution technique, one of "+strings.Join(names, ", "))
+	flags.IntVar(&reorgConf.NumberOfBucketsNeeded, "buckets", 0, "new number of buckets needed")
+	flags.IntVar(&reorgConf.RecordsPerBucket, "records-per-bucket", 0, "new number of records per bucket")
+	flags.IntVar(&reorgConf.KeyExtension, "key-extension", 0, "number of bytes to extend keys with")
+	flags.IntVar(&reorgConf.ValueExtension, "value-extension", 0, "number of bytes to extend values with")
+	flags.StringVar(&reorgConf.TargetName, "target", "", "name of the new files, default is the name with -reorg inserted")
+	flags.BoolVar(&reorgConf.ReplaceInPlace, "replace", false, "swap the new files into the name of the original files")
+	flags.BoolVar(&reorgConf.KeepBackup, "keep-backup", false, "keep the original files with -original inserted when replacing")
+	flags.IntVar(&reorgConf.VerifySamples, "verify-samples", 0, "number of migrated records to look up in the new files")
+	force := flags.Bool("force", false, "reorganize even if nothing is changed")
+	name, err := parseArgs(flags, args)
+	if err != nil {
+		return
+	}
+
+	if *crtName != "" {
+		var ok bool
+		reorgConf.CollisionResolutionTechnique, ok = crtTypes[*crtName]
+		if !ok {
+			err = fmt.Errorf("crt has to be one of %s", strings.Join(names, ", "))
+			return
+		}
+	}
+
+	from, to, err := filehashmap.ReorgFiles(name, reorgConf, *force)
+	if err != nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "Buckets available: %d -> %d\n", from.NumberOfBucketsAvailable, to.NumberOfBucketsAvailable)
+	_, _ = fmt.Fprintf(out, "Map file records:  %d -> %d\n", from.TotalRecords, to.TotalRecords)
+	_, _ = fmt.Fprintf(out, "Map file size:     %d -> %d\n", from.FileSize, to.FileSize)
+	if to.Verification != nil {
+		_, _ = fmt.Fprintf(out, "Verification:      %d of %d samples matched\n", to.Verification.Matched, to.Verification.Samples)
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"github.com/gostonefire/filehashmap"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testHashMap = "test"
+
+func TestRun(t *testing.T) {
+	// Prepare
+	fhm, _, err := filehashmap.NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 2, 2, nil)
+	assert.NoError(t, err, "create file hash map")
+	for i := 0; i < 5; i++ {
+		err = fhm.Set([]byte{0, byte(i)}, []byte{1, byte(i)})
+		assert.NoErrorf(t, err, "sets record #%d", i)
+	}
+	fhm.CloseFiles()
+
+	t.Run("inspects", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		err := run([]string{"inspect", testHashMap}, out)
+
+		assert.NoError(t, err, "inspect")
+		assert.Contains(t, out.String(), "Records:             5\n", "records")
+	})
+
+	t.Run("dumps", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		err := run([]string{"dump", testHashMap}, out)
+
+		assert.NoError(t, err, "dump")
+		assert.Contains(t, out.String(), "0003 0103\n", "record")
+		assert.Equal(t, 5, bytes.Count(out.Bytes(), []byte{'\n'}), "one line per record")
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		err := run([]string{"stat", "-distribution", testHashMap}, out)
+
+		assert.NoError(t, err, "stat")
+		assert.Contains(t, out.String(), "Records:                  5\n", "records")
+		assert.Contains(t, out.String(), "Bucket distribution:\n", "distribution")
+	})
+
+	t.Run("verifies", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		err := run([]string{"verify", testHashMap}, out)
+
+		assert.NoError(t, err, "verify")
+		assert.Contains(t, out.String(), "OK\n", "no problems")
+	})
+
+	t.Run("reorganizes", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		err := run([]string{"reorg", "-crt", "LinearProbing", "-buckets", "20", "-replace", testHashMap}, out)
+
+		assert.NoError(t, err, "reorg")
+		assert.Contains(t, out.String(), "Buckets available:", "report")
+		fhm, _, err := filehashmap.NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open reorganized")
+		value, err := fhm.Get([]byte{0, 3})
+		assert.NoError(t, err, "get record")
+		assert.Equal(t, []byte{1, 3}, value, "value")
+		fhm.CloseFiles()
+	})
+
+	t.Run("fails for bad arguments", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		assert.Error(t, run(nil, out), "no command")
+		assert.Error(t, run([]string{"unknown", testHashMap}, out), "unknown command")
+		assert.Error(t, run([]string{"stat"}, out), "missing name")
+		assert.Error(t, run([]string{"dump", "-format", "xml", testHashMap}, out), "unknown format")
+		assert.Error(t, run([]string{"reorg", "-crt", "Unknown", testHashMap}, out), "unknown crt")
+	})
+
+	// Clean up
+	fhm, _, err = filehashmap.NewFromExistingFiles(testHashMap, nil)
+	assert.NoError(t, err, "open file hash map")
+	err = fhm.RemoveFiles()
+	assert.NoError(t, err, "remove files")
+}
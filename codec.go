@@ -0,0 +1,173 @@
+package filehashmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec - Encodes Go values into record values and decodes them back, see SetCodec, SetObject and GetObject. Any
+// encoding can be plugged in by implementing Codec, e.g. protobuf through proto.Marshal and proto.Unmarshal. There is
+// no built in protobuf codec, since it would make the google.golang.org/protobuf module a dependency of every user of
+// the package.
+type Codec interface {
+	// Marshal - Returns the encoding of v
+	Marshal(v any) (data []byte, err error)
+	// Unmarshal - Decodes data into the value pointed to by v
+	Unmarshal(data []byte, v any) (err error)
+}
+
+var (
+	// JSONCodec - Encodes values as JSON using the encoding/json package, which is the default codec
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec - Encodes values using the encoding/gob package. Each value is encoded with its type information, which
+	// makes the encoding larger than with a gob.Encoder reused over a stream.
+	GobCodec Codec = gobCodec{}
+	// BinaryCodec - Encodes values implementing encoding.BinaryMarshaler and decodes into values implementing
+	// encoding.BinaryUnmarshaler
+	BinaryCodec Codec = binaryCodec{}
+)
+
+// jsonCodec - Is the Codec behind JSONCodec
+type jsonCodec struct{}
+
+// Marshal - Returns the JSON encoding of v
+func (jsonCodec) Marshal(v any) (data []byte, err error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal - Decodes JSON data into the value pointed to by v
+func (jsonCodec) Unmarshal(data []byte, v any) (err error) {
+	return json.Unmarshal(data, v)
+}
+
+// gobCodec - Is the Codec behind GobCodec
+type gobCodec struct{}
+
+// Marshal - Returns the gob encoding of v
+func (gobCodec) Marshal(v any) (data []byte, err error) {
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(v)
+	data = buf.Bytes()
+
+	return
+}
+
+// Unmarshal - Decodes gob data into the value pointed to by v
+func (gobCodec) Unmarshal(data []byte, v any) (err error) {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// binaryCodec - Is the Codec behind BinaryCodec
+type binaryCodec struct{}
+
+// Marshal - Returns the binary encoding of v, which must implement encoding.BinaryMarshaler
+func (binaryCodec) Marshal(v any) (data []byte, err error) {
+	m, ok := v.(encoding.BinaryMarshaler)
+	if !ok {
+		err = fmt.Errorf("value of type %T does not implement encoding.BinaryMarshaler", v)
+		return
+	}
+
+	return m.MarshalBinary()
+}
+
+// Unmarshal - Decodes binary data into v, which must implement encoding.BinaryUnmarshaler
+func (binaryCodec) Unmarshal(data []byte, v any) (err error) {
+	u, ok := v.(encoding.BinaryUnmarshaler)
+	if !ok {
+		err = fmt.Errorf("value of type %T does not implement encoding.BinaryUnmarshaler", v)
+		return
+	}
+
+	return u.UnmarshalBinary(data)
+}
+
+// SetCodec - Sets the codec used by SetObject and GetObject, which is JSONCodec until another one is set. Records
+// set with one codec have to be read with the same codec.
+// The codec is not persisted, so it has to be set each time the FileHashMap is opened.
+//   - codec is the codec to use, nil sets JSONCodec
+func (F *FileHashMap) SetCodec(codec Codec) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.codec = codec
+}
+
+// SetObject - Works as Set but takes a Go value that is encoded using the codec set by SetCodec. The encoding is
+// stored in the value of the record preceded by its length, as a varint, and padded with zero bytes, so it has to fit
+// in the value length given when the file hash map was created, less the one to ten bytes taken by the length.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - v is the value to encode
+//
+// It returns:
+//   - err is a standard error, if v could not be encoded, if its encoding does not fit or if something went wrong
+func (F *FileHashMap) SetObject(key []byte, v any) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	data, err := F.objectCodec().Marshal(v)
+	if err != nil {
+		err = fmt.Errorf("error while encoding value: %s", err)
+		return
+	}
+
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(len(data)))
+
+	value := make([]byte, F.userValueLength())
+	if n+len(data) > len(value) {
+		err = fmt.Errorf("encoded value of %d bytes does not fit in value length %d with its length of %d bytes", len(data), len(value), n)
+		return
+	}
+	copy(value, length[:n])
+	copy(value[n:], data)
+
+	err = F.set(key, value, 0)
+
+	return
+}
+
+// GetObject - Works as Get but decodes the value of the record, as set by SetObject, into the Go value pointed to by v
+// using the codec set by SetCodec
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - v is a pointer to the value to decode into
+//
+// It returns:
+//   - err is either of type crt.NoRecordFound or a standard error, if the value could not be decoded or if something
+//     went wrong
+func (F *FileHashMap) GetObject(key []byte, v any) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	value, err := F.get(key)
+	if err != nil {
+		return
+	}
+
+	length, n := binary.Uvarint(value)
+	if n <= 0 || length > uint64(len(value)-n) {
+		err = fmt.Errorf("value is not an encoding set by SetObject")
+		return
+	}
+
+	err = F.objectCodec().Unmarshal(value[n:n+int(length)], v)
+	if err != nil {
+		err = fmt.Errorf("error while decoding value: %s", err)
+	}
+
+	return
+}
+
+// objectCodec - Returns the codec set by SetCodec, or JSONCodec if none is set
+func (F *FileHashMap) objectCodec() (codec Codec) {
+	codec = F.codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type codecTestObject struct {
+	Name  string
+	Count int
+}
+
+func TestSetGetObject(t *testing.T) {
+	t.Run("encodes and decodes objects with built-in codecs", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 4, 100, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		object := codecTestObject{Name: "gopher", Count: 42}
+		moment := time.Date(2023, 4, 5, 6, 7, 8, 9, time.UTC)
+
+		// Execute
+		err = fhm.SetObject([]byte("json"), object)
+		assert.NoError(t, err, "sets object with default codec")
+		var fromJSON codecTestObject
+		errJSON := fhm.GetObject([]byte("json"), &fromJSON)
+
+		fhm.SetCodec(GobCodec)
+		err = fhm.SetObject([]byte("gob "), object)
+		assert.NoError(t, err, "sets object with gob codec")
+		var fromGob codecTestObject
+		errGob := fhm.GetObject([]byte("gob "), &fromGob)
+
+		fhm.SetCodec(BinaryCodec)
+		err = fhm.SetObject([]byte("bin "), moment)
+		assert.NoError(t, err, "sets object with binary codec")
+		var fromBinary time.Time
+		errBinary := fhm.GetObject([]byte("bin "), &fromBinary)
+		errNotBinary := fhm.SetObject([]byte("bin "), object)
+
+		fhm.SetCodec(nil)
+		errTooLarge := fhm.SetObject([]byte("big "), codecTestObject{Name: string(make([]byte, 100))})
+		errMissing := fhm.GetObject([]byte("none"), &fromJSON)
+
+		// Check
+		assert.NoError(t, errJSON, "gets object with default codec")
+		assert.Equal(t, object, fromJSON, "object from JSON")
+		assert.NoError(t, errGob, "gets object with gob codec")
+		assert.Equal(t, object, fromGob, "object from gob")
+		assert.NoError(t, errBinary, "gets object with binary codec")
+		assert.True(t, moment.Equal(fromBinary), "object from binary")
+		assert.Error(t, errNotBinary, "object not implementing encoding.BinaryMarshaler rejected")
+		assert.Error(t, errTooLarge, "too large encoding rejected")
+		assert.ErrorIs(t, errMissing, crt.NoRecordFound{}, "missing record")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,83 @@
+package filehashmap
+
+import "fmt"
+
+// overflowCompactor - Implemented by file management with an overflow file that can be compacted
+type overflowCompactor interface {
+	CompactOverflow() (reclaimed int64, err error)
+}
+
+// overflowScavenger - Implemented by file management with an overflow file that can find and reclaim unreachable
+// overflow records
+type overflowScavenger interface {
+	UnreachableOverflow() (addresses []int64, err error)
+	ScavengeOverflow() (leaked int64, err error)
+}
+
+// CompactOverflow - Compacts the overflow file of an existing file hash map using Separate Chaining (or Hybrid or
+// Linear Hashing) in place. Deleted records are dropped from the overflow chains, remaining overflow records are moved
+// towards the beginning of the file, chains and bucket overflow addresses are relinked to them, and the overflow file
+// is truncated. This is a much cheaper alternative to ReorgFiles with the force flag when only the overflow file has
+// grown large, since the map file is not rewritten. The compaction is safe to interrupt, in which case the files are
+// left valid but may hold unreachable overflow records, which a later compaction drops.
+//   - name is the name of an existing file hash map (including correct path)
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - reclaimed is the number of overflow records removed from the overflow file
+//   - err is a standard error, if the file hash map does not use Separate Chaining or something went wrong
+func CompactOverflow(name string, options ...Option) (reclaimed int64, err error) {
+	// Open existing (we won't use get/set/pop so whatever bucket algorithm is used in the files is not important)
+	fhm, _, err := NewFromExistingFiles(name, nil, options...)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	compactor, ok := fhm.fileManagement.(overflowCompactor)
+	if !ok {
+		err = fmt.Errorf("compaction of the overflow file is only supported by separate chaining, hybrid and linear hashing")
+		return
+	}
+
+	reclaimed, err = compactor.CompactOverflow()
+	if err != nil {
+		err = fmt.Errorf("error while compacting overflow file: %s", err)
+	}
+
+	return
+}
+
+// ScavengeOverflow - Puts overflow records of an existing file hash map using Separate Chaining (or Hybrid or Linear
+// Hashing) that are neither reachable from any bucket nor in the list of free overflow records on that list, so their
+// space is reused. Such records are left behind if a crash occurs after a record is appended to the overflow file but
+// before it is linked into an overflow chain, or while a record is being freed, and are reported by Verify. Records in
+// overflow chains are not touched, which makes this much cheaper than CompactOverflow, but the overflow file is not
+// truncated.
+//   - name is the name of an existing file hash map (including correct path)
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - leaked is the number of unreachable records put on the list of free overflow records
+//   - err is a standard error, if the file hash map does not use Separate Chaining or something went wrong
+func ScavengeOverflow(name string, options ...Option) (leaked int64, err error) {
+	// Open existing (we won't use get/set/pop so whatever bucket algorithm is used in the files is not important)
+	fhm, _, err := NewFromExistingFiles(name, nil, options...)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	scavenger, ok := fhm.fileManagement.(overflowScavenger)
+	if !ok {
+		err = fmt.Errorf("scavenging of the overflow file is only supported by separate chaining, hybrid and linear hashing")
+		return
+	}
+
+	leaked, err = scavenger.ScavengeOverflow()
+	if err != nil {
+		err = fmt.Errorf("error while scavenging overflow file: %s", err)
+	}
+
+	return
+}
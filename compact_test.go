@@ -0,0 +1,100 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestCompactOverflow(t *testing.T) {
+	t.Run("compacts overflow file for separate chaining CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "Hybrid", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("compacts overflow file for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 300)
+				values := make([][]byte, 300)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					values[i] = make([]byte, test.valueLength)
+					rand.Read(values[i])
+					err = fhm.Set(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+				for i := 0; i < len(keys); i += 2 {
+					_, err = fhm.Pop(keys[i])
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+				fhm.CloseFiles()
+
+				before, err := os.Stat(storage.GetOvflFileName(testHashMap))
+				assert.NoError(t, err, "gets overflow file size before compaction")
+
+				// Execute
+				reclaimed, err := CompactOverflow(testHashMap)
+
+				// Check
+				assert.NoError(t, err, "compacts overflow file")
+				assert.Greater(t, reclaimed, int64(0), "reclaims overflow records")
+
+				after, err := os.Stat(storage.GetOvflFileName(testHashMap))
+				assert.NoError(t, err, "gets overflow file size after compaction")
+				assert.Less(t, after.Size(), before.Size(), "overflow file is truncated")
+
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "opens compacted files")
+				for i := range keys {
+					value, err := fhm.Get(keys[i])
+					if i%2 == 0 {
+						assert.ErrorIsf(t, err, crt.NoRecordFound{}, "popped record #%d is not found", i)
+						continue
+					}
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Equalf(t, values[i], value, "value of record #%d", i)
+				}
+				count, err := fhm.Count()
+				assert.NoError(t, err, "counts records")
+				assert.Equal(t, int64(150), count, "number of records")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("fails for CRTs without overflow file", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		fhm.CloseFiles()
+
+		// Execute
+		_, err = CompactOverflow(testHashMap)
+
+		// Check
+		assert.Error(t, err, "compaction is not supported")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,153 @@
+package filehashmap
+
+import (
+	"context"
+)
+
+// GetCtx - Works as Get but stops looking for the record once ctx is done, in which case the error of ctx is returned.
+// The context is checked before each bucket is read, and with Separate Chaining, Hybrid and Linear Hashing also before
+// each overflow record is read, so that long probe sequences can be cancelled.
+//   - ctx is the context that cancels the lookup
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound, the error of ctx or a standard error, if something went wrong
+func (F *FileHashMap) GetCtx(ctx context.Context, key []byte) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	defer F.interruptBy(ctx)(&err)
+	value, err = F.get(key)
+
+	return
+}
+
+// SetCtx - Works as Set but stops looking for where to set the record once ctx is done, in which case the error of ctx
+// is returned and nothing has been changed. Once the record is being written it is not interrupted, so a set that is
+// cancelled either happened in full or not at all. With write-ahead logging enabled (see EnableWAL) the set can not be
+// cancelled once it has been logged.
+//   - ctx is the context that cancels the set
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - err is the error of ctx or a standard error, if something went wrong
+func (F *FileHashMap) SetCtx(ctx context.Context, key []byte, value []byte) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	defer F.interruptBy(ctx)(&err)
+	err = F.set(key, value, 0)
+
+	return
+}
+
+// PopCtx - Works as Pop but stops looking for the record once ctx is done, in which case the error of ctx is returned
+// and nothing has been removed. Once the record is found it is removed without interruption.
+//   - ctx is the context that cancels the lookup
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound, the error of ctx or a standard error, if something went wrong
+func (F *FileHashMap) PopCtx(ctx context.Context, key []byte) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	defer F.interruptBy(ctx)(&err)
+	value, err = F.pop(key)
+
+	return
+}
+
+// StatCtx - Works as Stat but stops walking through the buckets once ctx is done, in which case the error of ctx is
+// returned. The context is checked after each bucket, including its overflow chain, has been read.
+//   - ctx is the context that cancels the walk
+//   - includeDistribution set to true will include a slice of length numberOfBuckets with number of records per bucket, false will set HashMapStat.BucketDistribution to nil.
+//
+// It returns:
+//   - hashMapStat is a pointer to a HashMapStat struct, nil if ctx was done before the walk was completed
+//   - err is the error of ctx or a standard error, if something went wrong
+func (F *FileHashMap) StatCtx(ctx context.Context, includeDistribution bool) (hashMapStat *HashMapStat, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	defer F.watch("Stat")()
+	defer F.interruptBy(ctx)(&err)
+	hashMapStat, err = F.stat(includeDistribution, func(int64, int) error {
+		return ctx.Err()
+	})
+
+	return
+}
+
+// ReorgFilesCtx - Works as ReorgFiles but stops reorganizing once ctx is done, in which case the error of ctx is
+// returned. The context is checked before each bucket of the original files is read. The original files are never
+// changed, and the incomplete new files are removed. See ReorgConf.Progress for following the progress.
+//   - ctx is the context that cancels the reorganization
+//   - name is the name of an existing file hash map (including correct path)
+//   - reorgConfig is an instance of the ReorgConf struct.
+//   - force set to true forces a reorganization regardless of what is changed from the ReorgConf struct
+//   - options is any optional settings, such as WithFileSystem, which apply to both the original and the new files
+func ReorgFilesCtx(ctx context.Context, name string, reorgConf ReorgConf, force bool, options ...Option) (fromHashMapInfo, toHashMapInfo HashMapInfo, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	fromHashMapInfo, toHashMapInfo, err = reorgFiles(ctx, name, reorgConf, force, o)
+
+	return
+}
+
+// interrupter - Implemented by file management that can stop lookups between bucket reads
+type interrupter interface {
+	SetInterrupt(interrupt func() error)
+}
+
+// setInterrupt - Sets the function fm calls between bucket reads of lookups, which stops the lookup with its error if
+// it returns one, nil turns it off. It is a no-op if fm can't be interrupted.
+func setInterrupt(fm FileManagement, interrupt func() error) {
+	if i, ok := fm.(interrupter); ok {
+		i.SetInterrupt(interrupt)
+	}
+}
+
+// interruptBy - Makes lookups stop between bucket reads once ctx is done, to be called with the lock held. The returned
+// function turns this off again and replaces any error with the error of ctx if ctx is done, and is typically deferred.
+func (F *FileHashMap) interruptBy(ctx context.Context) (done func(err *error)) {
+	setInterrupt(F.fileManagement, ctx.Err)
+
+	return func(err *error) {
+		setInterrupt(F.fileManagement, nil)
+		if *err != nil && ctx.Err() != nil {
+			*err = ctx.Err()
+		}
+	}
+}
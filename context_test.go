@@ -0,0 +1,157 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// countdownContext - Is a context that is done after Err has been called a number of times
+type countdownContext struct {
+	context.Context
+	calls int
+}
+
+func (c *countdownContext) Err() error {
+	if c.calls <= 0 {
+		return context.Canceled
+	}
+	c.calls--
+	return nil
+}
+
+func TestGetSetPopCtx(t *testing.T) {
+	t.Run("context tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("cancels lookups for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 50)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					err = fhm.SetCtx(context.Background(), keys[i], make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+				newKey := make([]byte, test.keyLength)
+				rand.Read(newKey)
+
+				cancelled, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				// Execute
+				_, errGet := fhm.GetCtx(context.Background(), keys[0])
+				_, errCancelledGet := fhm.GetCtx(cancelled, keys[0])
+				_, errInterruptedGet := fhm.GetCtx(&countdownContext{Context: context.Background(), calls: 1}, keys[0])
+				errInterruptedSet := fhm.SetCtx(&countdownContext{Context: context.Background(), calls: 1}, newKey, make([]byte, test.valueLength))
+				_, errInterruptedPop := fhm.PopCtx(&countdownContext{Context: context.Background(), calls: 1}, keys[1])
+				_, errPop := fhm.PopCtx(context.Background(), keys[2])
+
+				// Check
+				assert.NoError(t, errGet, "gets record")
+				assert.ErrorIs(t, errCancelledGet, context.Canceled, "cancelled before get")
+				assert.ErrorIs(t, errInterruptedGet, context.Canceled, "get interrupted between bucket reads")
+				assert.ErrorIs(t, errInterruptedSet, context.Canceled, "set interrupted between bucket reads")
+				assert.ErrorIs(t, errInterruptedPop, context.Canceled, "pop interrupted between bucket reads")
+				assert.NoError(t, errPop, "pops record")
+
+				_, err = fhm.Get(newKey)
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "interrupted set did not set record")
+				_, err = fhm.Get(keys[1])
+				assert.NoError(t, err, "interrupted pop did not remove record")
+				count, err := fhm.Count()
+				assert.NoError(t, err, "counts records")
+				assert.Equal(t, int64(49), count, "records left")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestStatCtx(t *testing.T) {
+	t.Run("stops walking buckets when cancelled", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		for i := 0; i < 20; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		// Execute
+		hms, errStat := fhm.StatCtx(context.Background(), true)
+		interruptedHms, errInterrupted := fhm.StatCtx(&countdownContext{Context: context.Background(), calls: 3}, true)
+
+		// Check
+		assert.NoError(t, errStat, "walks all buckets")
+		assert.Equal(t, 20, hms.Records, "records found")
+		assert.ErrorIs(t, errInterrupted, context.Canceled, "walk interrupted")
+		assert.Nil(t, interruptedHms, "no stat from interrupted walk")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestReorgFilesCtx(t *testing.T) {
+	t.Run("stops reorganizing when cancelled", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		for i := 0; i < 50; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = ReorgFilesCtx(&countdownContext{Context: context.Background(), calls: 10}, testHashMap, ReorgConf{}, true)
+
+		// Check
+		assert.ErrorIs(t, err, context.Canceled, "reorganization interrupted")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens original files")
+		count, err := fhm.Count()
+		assert.NoError(t, err, "counts records in original files")
+		assert.Equal(t, int64(50), count, "original files unchanged")
+		_, err = os.Stat(fmt.Sprintf("%s-map.bin", newName))
+		assert.ErrorIs(t, err, os.ErrNotExist, "incomplete new files removed")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes original files")
+	})
+}
@@ -0,0 +1,93 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// CopyFiles - Duplicates the files of a file hash map into a new name, giving an independent file hash map that can
+// be opened right away with NewFromExistingFiles. All files of it are copied, i.e. map, overflow, key, Bloom filter
+// and WAL files, as well as the files of the operation log (see EnableOperationLog). Files of the operating system are
+// cloned where the file system supports it (reflink on Linux, e.g. Btrfs and XFS), which is instant and shares data
+// blocks until either copy changes, or else copied by the kernel. The source must not be changed while copied, so it
+// has to be closed, and with file locking turned on (see WithFileLocking) a shared lock is held on it during the copy.
+//   - srcName is the name of an existing file hash map (including correct path)
+//   - dstName is the name of the copy (including correct path), it must not already exist
+//   - options is any optional settings, such as WithFileSystem, which apply to both the source and the copy
+//
+// It returns:
+//   - err is a standard error, if something went wrong, in which case no files of the copy are left behind
+func CopyFiles(srcName, dstName string, options ...Option) (err error) {
+	if srcName == dstName {
+		err = fmt.Errorf("dstName must differ from srcName")
+		return
+	}
+
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	fs := o.fileSystem()
+
+	if _, statErr := fs.Stat(storage.GetMapFileName(srcName)); statErr != nil {
+		err = fmt.Errorf("error while looking for source files: %s", statErr)
+		return
+	}
+	if _, statErr := fs.Stat(storage.GetMapFileName(dstName)); statErr == nil {
+		err = fmt.Errorf("file hash map %s already exists", dstName)
+		return
+	}
+
+	srcLock, err := lockFiles(srcName, true, o)
+	if err != nil {
+		return
+	}
+	if srcLock != nil {
+		defer func() { _ = srcLock.Unlock() }()
+	}
+	dstLock, err := lockFiles(dstName, false, o)
+	if err != nil {
+		return
+	}
+	if dstLock != nil {
+		defer func() { _ = dstLock.Unlock() }()
+	}
+
+	for _, names := range [][2]string{{srcName, dstName}, {srcName + "-oplog", dstName + "-oplog"}} {
+		err = copyNamedFiles(names[0], names[1], fs)
+		if err != nil {
+			_ = removeNamedFiles(dstName, fs)
+			_ = removeNamedFiles(dstName+"-oplog", fs)
+			_ = removeLockFile(dstName, o)
+			return
+		}
+	}
+
+	err = syncDirOf(storage.GetMapFileName(dstName), o)
+
+	return
+}
+
+// copyNamedFiles - Copies the existing files of the file hash map from to the file hash map to
+//   - from is the name of the file hash map to copy
+//   - to is the name of the copy
+//   - fs is the file system holding the files
+//
+// It returns:
+//   - err is a standard error, if a copy failed
+func copyNamedFiles(from, to string, fs vfs.FileSystem) (err error) {
+	for _, fileName := range hashMapFileNames {
+		if _, statErr := fs.Stat(fileName(from)); statErr != nil {
+			continue
+		}
+
+		err = storage.CopyFile(fs, fileName(from), fileName(to))
+		if err != nil {
+			err = fmt.Errorf("error while copying file %s: %s", fileName(from), err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCopyFiles(t *testing.T) {
+	tests := []struct {
+		crtName string
+		crt     int
+	}{
+		{crtName: "SeparateChaining", crt: crt.SeparateChaining},
+		{crtName: "LinearProbing", crt: crt.LinearProbing},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("copies files for %s", test.crtName), func(t *testing.T) {
+			// Prepare
+			copyName := fmt.Sprintf("%s-copy", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, test.crt, 10, 1, 4, 4, nil)
+			assert.NoError(t, err, "create file hash map")
+			for i := 0; i < 10; i++ {
+				err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			err = fhm.EnableOperationLog(10)
+			assert.NoError(t, err, "enable operation log")
+			fhm.CloseFiles()
+
+			// Execute
+			err = CopyFiles(testHashMap, copyName)
+
+			// Check
+			assert.NoError(t, err, "copy files")
+
+			cp, _, err := NewFromExistingFiles(copyName, nil)
+			assert.NoError(t, err, "open copy")
+			for i := 0; i < 10; i++ {
+				value, err := cp.Get([]byte{0, 0, 0, byte(i)})
+				assert.NoErrorf(t, err, "get record #%d from copy", i)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d in copy", i)
+			}
+			err = cp.Set([]byte{0, 0, 1, 0}, []byte{2, 2, 2, 2})
+			assert.NoError(t, err, "set record in copy")
+			err = cp.EnableOperationLog(10)
+			assert.NoError(t, err, "open copied operation log")
+
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open original")
+			_, err = fhm.Get([]byte{0, 0, 1, 0})
+			assert.ErrorIs(t, err, crt.NoRecordFound{}, "original independent of copy")
+
+			err = CopyFiles(testHashMap, copyName)
+			assert.Error(t, err, "copy already exists")
+
+			// Clean up
+			err = cp.RemoveFiles()
+			assert.NoError(t, err, "copy can be removed")
+			err = fhm.EnableOperationLog(10)
+			assert.NoError(t, err, "open operation log")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "original can be removed")
+		})
+	}
+
+	t.Run("fails for missing source", func(t *testing.T) {
+		// Execute
+		err := CopyFiles("missing", fmt.Sprintf("%s-copy", testHashMap))
+
+		// Check
+		assert.Error(t, err, "missing source")
+	})
+}
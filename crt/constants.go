@@ -34,4 +34,46 @@ const (
 	//It uses the hash value generated by the first hash function as the starting point. In case of a collision,
 	// the second hash function, which is independent of the original function, determines the final location of the next value.
 	DoubleHashing int = 4
+
+	// Hybrid - Represents the collision resolution technique where open addressing is used up to a bounded number of
+	// probes, and records that exceed it spill into a linked list in the overflow file.
+	//
+	// A record is first placed in its home bucket, and if that is full, the following buckets are probed linearly up to
+	// a limit. Only records for which all probed buckets are full end up in the overflow file, in a linked list belonging
+	// to the home bucket. This combines fast lookups in the typical case with graceful handling of pathological clustering.
+	Hybrid int = 5
+
+	// RobinHood - Represents the collision resolution technique where linear probing is used, but records are moved
+	// along on insert to keep the variance of probe lengths low.
+	//
+	// Each record keeps track of its displacement, i.e. how many buckets away from its home bucket it is stored. When a new
+	// record is probing for a free slot and finds a record with a lower displacement than its own, they swap places and the
+	// displaced record continues probing. This "takes from the rich and gives to the poor", and makes it possible to stop
+	// probing for a missing key as soon as a record with a lower displacement than the current probe length is found.
+	RobinHood int = 6
+
+	// CuckooHashing - Represents the collision resolution technique where each key has two candidate buckets, given by
+	// two independent hash functions, and a record is always stored in one of them.
+	//
+	// If both candidate buckets are full on insert, a record in one of them is kicked out to its own alternative bucket,
+	// which may in turn kick out another record, and so on up to a bounded number of times. Hence, a Get never reads more
+	// than two buckets, which makes it well suited for read heavy workloads, at the cost of more work on insert.
+	CuckooHashing int = 7
+
+	// Hopscotch - Represents the collision resolution technique where each record is kept within a bounded neighborhood
+	// of consecutive buckets starting with its home bucket.
+	//
+	// Each bucket keeps a bitmap of which buckets in its neighborhood hold records belonging to it, so a Get only reads
+	// the home bucket and the flagged buckets. A free slot is found by linear probing, and if it is too far away, records
+	// are moved towards it within their own neighborhoods until it is close enough. This bounds the worst case probe
+	// distance, which gives predictable lookup latency.
+	Hopscotch int = 8
+
+	// LinearHashing - Represents the collision resolution technique where the hash table grows one bucket at a time.
+	//
+	// Records are addressed using the low bits of the hash value, and buckets are split in order, one each time a record
+	// has to be put in overflow. When a bucket is split, its records that are addressed to the new bucket using one more
+	// bit of the hash value are moved there. Hence, the map file grows gradually as the load rises, and never becomes
+	// full, without any need to reorganize the files.
+	LinearHashing int = 9
 )
@@ -1,5 +1,9 @@
 package crt
 
+import (
+	"fmt"
+)
+
 // NoRecordFound - Custom error to inform that no record was found
 type NoRecordFound struct {
 	msg string
@@ -26,15 +30,231 @@ func (E MapFileFull) Error() string {
 	return E.msg
 }
 
-// ProbingAlgorithm - Custom error to inform that something went wrong concerning a probing algorithm
+// InsertCycle - Custom error to inform that a record could not be added by Cuckoo Hashing since kicking records out to
+// their alternative buckets went around in a cycle, although the map file still has free slots. The files have to be
+// rehashed into a bigger table, either by ReorgFiles or by automatic growing which does so by itself. It matches
+// MapFileFull as well, since the record can't be added to the files as they are.
+//   - Displacements is the number of records kicked out before giving up
+type InsertCycle struct {
+	Displacements int
+}
+
+// Error - Used to notify that displacing records went around in a cycle
+func (I InsertCycle) Error() string {
+	return fmt.Sprintf("insert cycle, no free slot found after kicking out %d records although the map file is not full", I.Displacements)
+}
+
+// Is - Returns true if target is an InsertCycle, regardless of the number of displacements, or a MapFileFull
+func (I InsertCycle) Is(target error) bool {
+	switch target.(type) {
+	case InsertCycle, MapFileFull:
+		return true
+	}
+	return false
+}
+
+// ProbingAlgorithm - Custom error to inform that something went wrong concerning a probing algorithm, i.e. that the
+// probe sequence of a key ended before reaching either the key, an empty slot or all buckets
+//   - HashValue1 is the value of HashFunc1 for the key
+//   - HashValue2 is the value of HashFunc2 for the key
+//   - Probes is the number of probe iterations attempted, which is the probing cap
+//   - Visited is the number of distinct buckets within the table that the probe iterations reached
+//   - Buckets is the number of buckets in the table
 type ProbingAlgorithm struct {
-	msg string
+	msg        string
+	HashValue1 int64
+	HashValue2 int64
+	Probes     int64
+	Visited    int64
+	Buckets    int64
 }
 
-// Error - Used to notify that map file is full
+// Error - Used to notify that the probing algorithm was exhausted, with what is known about the probe sequence
 func (P ProbingAlgorithm) Error() string {
-	if P.msg == "" {
+	if P.msg != "" {
+		return P.msg
+	}
+	if P.Probes == 0 {
 		return "probing algorithm exhausted"
 	}
-	return P.msg
+	return fmt.Sprintf("probing algorithm exhausted after %d probe iterations visiting %d of %d buckets, for a key with hash values %d and %d",
+		P.Probes, P.Visited, P.Buckets, P.HashValue1, P.HashValue2)
+}
+
+// Is - Returns true if target is a ProbingAlgorithm, regardless of the probe sequence
+func (P ProbingAlgorithm) Is(target error) bool {
+	_, ok := target.(ProbingAlgorithm)
+	return ok
+}
+
+// InvalidValue - Custom error to inform that a value was rejected by the value validator, Err holds the validator error
+type InvalidValue struct {
+	Err error
+}
+
+// Error - Used to notify that a value was rejected by the value validator
+func (I InvalidValue) Error() string {
+	if I.Err == nil {
+		return "invalid value"
+	}
+	return "invalid value: " + I.Err.Error()
+}
+
+// Unwrap - Returns the error from the value validator
+func (I InvalidValue) Unwrap() error {
+	return I.Err
+}
+
+// Is - Returns true if target is an InvalidValue, regardless of the validator error
+func (I InvalidValue) Is(target error) bool {
+	_, ok := target.(InvalidValue)
+	return ok
+}
+
+// WrongLength - Custom error to inform that a key or value doesn't have the length given when the file hash map was
+// created. An empty key or value gives a message of its own, since it usually means that the input was never set
+// rather than that it was built the wrong way.
+//   - Field is either "key" or "value"
+//   - Operation is the name of the operation that rejected the key or value, e.g. "Get" or "Set"
+//   - Expected is the length the file hash map was created with
+//   - Actual is the length that was given
+type WrongLength struct {
+	Field     string
+	Operation string
+	Expected  int
+	Actual    int
+}
+
+// Error - Used to notify that a key or value has the wrong length, including a hint on how to remedy it
+func (W WrongLength) Error() string {
+	if W.Actual == 0 {
+		return fmt.Sprintf("empty %s in %s, should be %d bytes long", W.Field, W.Operation, W.Expected)
+	}
+	return fmt.Sprintf("wrong length of %s in %s, got %d bytes but should be %d (see filehashmap.PadOrTrim to normalize)",
+		W.Field, W.Operation, W.Actual, W.Expected)
+}
+
+// Is - Returns true if target is a WrongLength, regardless of field, operation and lengths
+func (W WrongLength) Is(target error) bool {
+	_, ok := target.(WrongLength)
+	return ok
+}
+
+// CorruptRecord - Custom error to inform that a record failed checksum validation, i.e. its key or value has been
+// changed on disk since it was written
+//   - Address is the address of the record in the file
+//   - IsOverflow is true if the record is stored in the overflow file
+type CorruptRecord struct {
+	Address    int64
+	IsOverflow bool
+}
+
+// Error - Used to notify that a record is corrupt, including where it is stored
+func (C CorruptRecord) Error() string {
+	file := "map"
+	if C.IsOverflow {
+		file = "overflow"
+	}
+	return fmt.Sprintf("corrupt record at address %d in %s file, checksum mismatch", C.Address, file)
+}
+
+// Is - Returns true if target is a CorruptRecord, regardless of address
+func (C CorruptRecord) Is(target error) bool {
+	_, ok := target.(CorruptRecord)
+	return ok
+}
+
+// FileLocked - Custom error to inform that the files of a file hash map are locked by another process, see
+// filehashmap.WithFileLocking
+//   - Name is the name of the file hash map
+type FileLocked struct {
+	Name string
+}
+
+// Error - Used to notify that the files are locked by another process
+func (F FileLocked) Error() string {
+	return fmt.Sprintf("file hash map %s is locked by another process", F.Name)
+}
+
+// Is - Returns true if target is a FileLocked, regardless of name
+func (F FileLocked) Is(target error) bool {
+	_, ok := target.(FileLocked)
+	return ok
+}
+
+// ValueConflict - Custom error to inform that a compare-and-swap failed since the stored value differs from the
+// expected value, see filehashmap.CAS
+//   - Actual is the value currently stored
+type ValueConflict struct {
+	Actual []byte
+}
+
+// Error - Used to notify that the stored value differs from the expected value
+func (V ValueConflict) Error() string {
+	return "value conflict, stored value differs from expected value"
+}
+
+// Is - Returns true if target is a ValueConflict, regardless of the stored value
+func (V ValueConflict) Is(target error) bool {
+	_, ok := target.(ValueConflict)
+	return ok
+}
+
+// UnknownFormat - Custom error to inform that a file is not a map file of a file hash map, or that it is of a format
+// version newer than this release can read
+//   - FileName is the name of the file, empty if not known
+//   - FormatVersion is the format version found in the file, zero if it is not recognized as a map file at all
+//   - SupportedVersion is the newest format version this release can read
+type UnknownFormat struct {
+	FileName         string
+	FormatVersion    int64
+	SupportedVersion int64
+}
+
+// Error - Used to notify that a file is not a map file or of a format version that can not be read
+func (U UnknownFormat) Error() string {
+	file := "file"
+	if U.FileName != "" {
+		file = "file " + U.FileName
+	}
+	if U.FormatVersion > 0 {
+		return fmt.Sprintf("%s is of format version %d, but format version %d is the newest supported", file, U.FormatVersion, U.SupportedVersion)
+	}
+	return fmt.Sprintf("%s is not a map file of a file hash map", file)
+}
+
+// Is - Returns true if target is an UnknownFormat, regardless of file name and versions
+func (U UnknownFormat) Is(target error) bool {
+	_, ok := target.(UnknownFormat)
+	return ok
+}
+
+// HashAlgorithmMismatch - Custom error to inform that a file hash map was opened with another hash algorithm than it
+// was created with, see hashfunc.Identity
+//   - ExpectedID is the identifier of the hash algorithm the file hash map was created with
+//   - ExpectedSeed is the seed of the hash algorithm the file hash map was created with
+//   - ActualID is the identifier of the hash algorithm given, empty if it doesn't implement hashfunc.Identity
+//   - ActualSeed is the seed of the hash algorithm given
+type HashAlgorithmMismatch struct {
+	ExpectedID   string
+	ExpectedSeed int64
+	ActualID     string
+	ActualSeed   int64
+}
+
+// Error - Used to notify that the hash algorithm given differs from the one the file hash map was created with
+func (H HashAlgorithmMismatch) Error() string {
+	if H.ActualID == "" {
+		return fmt.Sprintf("hash algorithm mismatch, created with %q but the given hash algorithm has no identity", H.ExpectedID)
+	}
+	if H.ActualID != H.ExpectedID {
+		return fmt.Sprintf("hash algorithm mismatch, created with %q but opened with %q", H.ExpectedID, H.ActualID)
+	}
+	return fmt.Sprintf("hash algorithm mismatch, %q created with seed %d but opened with seed %d", H.ExpectedID, H.ExpectedSeed, H.ActualSeed)
+}
+
+// Is - Returns true if target is a HashAlgorithmMismatch, regardless of identifiers and seeds
+func (H HashAlgorithmMismatch) Is(target error) bool {
+	_, ok := target.(HashAlgorithmMismatch)
+	return ok
 }
@@ -0,0 +1,200 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"math"
+	"sync"
+)
+
+// MinExternalCRT - Is the lowest id a collision resolution technique registered by RegisterCRT can have, lower ids are
+// reserved for the built-in ones
+const MinExternalCRT int = 100
+
+// MaxExternalCRT - Is the highest id a collision resolution technique registered by RegisterCRT can have, which is the
+// highest the header of the map file holds
+const MaxExternalCRT int = math.MaxUint16
+
+// MapFileHeaderLength - Is the length of the header at the start of the map file, which files of a registered
+// collision resolution technique must keep as well, see WriteFileHeader
+const MapFileHeaderLength int64 = storage.MapFileHeaderLength
+
+// StorageRecord - A record as handled by file management, see FileManagement
+type StorageRecord = model.Record
+
+// StorageBucket - A bucket as handled by file management, see FileManagement
+type StorageBucket = model.Bucket
+
+// StorageParameters - The parameters of the files of a file hash map as returned by file management, see FileManagement
+type StorageParameters = model.StorageParameters
+
+// StorageMetrics - Receiver of counters on probes and bytes from file management, see FileManagement
+type StorageMetrics = model.Metrics
+
+// OverflowRecords - An iterator over the records in an overflow chain, file management without an overflow file
+// returns nil, see FileManagement
+type OverflowRecords = overflow.Records
+
+// CRTConf - The configuration given to a CRTFactory for new files
+//   - Name is the name to base map and overflow file names on, see MapFileName
+//   - NumberOfBucketsNeeded is the number of buckets to calculate storage for
+//   - RecordsPerBucket is the number of records to store per bucket
+//   - KeyLength is the fixed length of keys to store
+//   - ValueLength is the fixed length of values to store
+//   - CollisionResolutionTechnique is the id the collision resolution technique was registered with
+//   - HashAlgorithm is the custom hash algorithm to use, or nil for the internal one given by HashParameters
+//   - HashParameters is the kind and seed of the internal hash algorithm, to persist in the header
+//   - FileSystem is the file system to create files in, nil for the file system of the operating system
+type CRTConf = model.CRTConf
+
+// FileHeader - The header of a map file, see ReadFileHeader and WriteFileHeader
+type FileHeader = storage.Header
+
+// CRTFactory - Creates, opens and estimates the files of a collision resolution technique registered by RegisterCRT
+type CRTFactory interface {
+	// New - Creates new files according to crtConf, and returns the file management for them. The map file has to
+	// start with a header written by WriteFileHeader, holding crtConf.CollisionResolutionTechnique.
+	New(crtConf CRTConf) (fm FileManagement, err error)
+	// Open - Opens the existing files of name in fileSystem, with hashAlgorithm if they were created with a custom one
+	Open(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (fm FileManagement, err error)
+	// Estimate - Returns the storage parameters that New would give files created according to crtConf, without
+	// creating any files
+	Estimate(crtConf CRTConf) (params StorageParameters, err error)
+}
+
+// crtRegistry - Holds the collision resolution techniques registered by RegisterCRT
+var crtRegistry = struct {
+	sync.RWMutex
+	factories map[int]CRTFactory
+}{factories: make(map[int]CRTFactory)}
+
+// RegisterCRT - Registers a collision resolution technique implemented outside this package, so that NewFileHashMap
+// creates files with it when given id as crtType, and so that NewFromExistingFiles, ReorgFiles and the other functions
+// opening existing files route to it when the header of the map file holds id. Registration is process wide and is
+// typically done from an init function of the package implementing the technique. Files created with a registered
+// technique can only be opened in processes where it is registered with the same id. Features depending on the layout
+// of the built-in techniques, e.g. Verify and WhatIsAt, may not apply.
+//   - id is the id of the technique, persisted in the header, which has to be between MinExternalCRT and MaxExternalCRT
+//   - factory is the CRTFactory creating and opening the files of the technique
+//
+// It returns:
+//   - err is a standard error, if id is reserved or already registered, or factory is nil
+func RegisterCRT(id int, factory CRTFactory) (err error) {
+	if id < MinExternalCRT || id > MaxExternalCRT {
+		err = fmt.Errorf("id has to be between %d and %d, lower ids are reserved for the built-in collision resolution techniques", MinExternalCRT, MaxExternalCRT)
+		return
+	}
+	if factory == nil {
+		err = fmt.Errorf("factory can not be nil")
+		return
+	}
+
+	crtRegistry.Lock()
+	defer crtRegistry.Unlock()
+
+	if _, ok := crtRegistry.factories[id]; ok {
+		err = fmt.Errorf("collision resolution technique %d is already registered", id)
+		return
+	}
+	crtRegistry.factories[id] = factory
+
+	return
+}
+
+// registeredCRT - Returns the factory registered by RegisterCRT for id, or false if there is none
+func registeredCRT(id int) (factory CRTFactory, ok bool) {
+	crtRegistry.RLock()
+	defer crtRegistry.RUnlock()
+
+	factory, ok = crtRegistry.factories[id]
+
+	return
+}
+
+// checkCRTType - Returns an error if crtType is neither a built-in collision resolution technique nor one registered by
+// RegisterCRT
+func checkCRTType(crtType int) (err error) {
+	if crtType >= crt.SeparateChaining && crtType <= crt.LinearHashing {
+		return
+	}
+	if _, ok := registeredCRT(crtType); ok {
+		return
+	}
+
+	err = fmt.Errorf("crtType has to be one of SeparateChaining, LinearProbing, QuadraticProbing, DoubleHashing, Hybrid, RobinHood, CuckooHashing, Hopscotch, LinearHashing or registered by RegisterCRT")
+
+	return
+}
+
+// MapFileName - Returns the name of the map file of the file hash map name, for a CRTFactory creating files
+func MapFileName(name string) (fileName string) {
+	fileName = storage.GetMapFileName(name)
+
+	return
+}
+
+// OverflowFileName - Returns the name of the overflow file of the file hash map name, for a CRTFactory creating files
+func OverflowFileName(name string) (fileName string) {
+	fileName = storage.GetOvflFileName(name)
+
+	return
+}
+
+// ReadFileHeader - Reads the header from the start of a map file, for a CRTFactory opening files
+//   - file is the map file
+//
+// It returns:
+//   - header is the header read
+//   - err is a standard error, or of type crt.UnknownFormat if file doesn't start with a known header
+func ReadFileHeader(file vfs.File) (header FileHeader, err error) {
+	header, err = storage.GetHeader(file)
+
+	return
+}
+
+// WriteFileHeader - Writes the header at the start of a map file, in the current format version, for a CRTFactory
+// creating files. The map file has to be at least MapFileHeaderLength bytes long and records stored after that, since
+// the rest of the header holds the system area (see WriteSystemValue).
+//   - file is the map file
+//   - header is the header to write, where CollisionResolutionTechnique has to be the id given to RegisterCRT
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func WriteFileHeader(file vfs.File, header FileHeader) (err error) {
+	err = storage.SetHeader(file, header)
+
+	return
+}
+
+// ReadSystemValue - Reads a value from the system area of a map file header, for file management implementing
+// GetSystemValue
+//   - file is the map file
+//   - id is the id of the value
+//
+// It returns:
+//   - value is the value stored
+//   - err is a standard error, if no value is stored with id or something went wrong
+func ReadSystemValue(file vfs.File, id uint8) (value []byte, err error) {
+	value, err = storage.GetSystemValue(file, id)
+
+	return
+}
+
+// WriteSystemValue - Writes a value to the system area of a map file header, for file management implementing
+// SetSystemValue
+//   - file is the map file
+//   - id is the id of the value
+//   - value is the value to store, replacing any value stored with id
+//
+// It returns:
+//   - err is a standard error, if the system area is full or something went wrong
+func WriteSystemValue(file vfs.File, id uint8, value []byte) (err error) {
+	err = storage.SetSystemValue(file, id, value)
+
+	return
+}
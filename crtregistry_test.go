@@ -0,0 +1,217 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/storage/openaddressing"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+)
+
+// testExternalCRT - Is the id the test collision resolution technique is registered with
+const testExternalCRT = 100
+
+var registerTestCRT sync.Once
+
+// wrappedCRTFactory - A collision resolution technique as a third party would register it, which stores the records in
+// linear probing files of its own next to a map file holding only the header
+type wrappedCRTFactory struct{}
+
+// wrappedFiles - The file management of wrappedCRTFactory
+type wrappedFiles struct {
+	FileManagement
+	name       string
+	fileSystem vfs.FileSystem
+}
+
+func (W wrappedCRTFactory) New(crtConf CRTConf) (fm FileManagement, err error) {
+	inner := crtConf
+	inner.Name = crtConf.Name + "-inner"
+	inner.CollisionResolutionTechnique = crt.LinearProbing
+	innerFiles, err := openaddressing.NewOAFiles(inner)
+	if err != nil {
+		return
+	}
+
+	fileSystem := crtConf.FileSystem
+	if fileSystem == nil {
+		fileSystem = vfs.OS{}
+	}
+	file, err := fileSystem.OpenFile(MapFileName(crtConf.Name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	sp := innerFiles.GetStorageParameters()
+	err = file.Truncate(MapFileHeaderLength)
+	if err == nil {
+		err = WriteFileHeader(file, FileHeader{
+			InternalHash:                 sp.InternalAlgorithm,
+			KeyLength:                    sp.KeyLength,
+			ValueLength:                  sp.ValueLength,
+			NumberOfBucketsNeeded:        sp.NumberOfBucketsNeeded,
+			NumberOfBucketsAvailable:     sp.NumberOfBucketsAvailable,
+			RecordsPerBucket:             sp.RecordsPerBucket,
+			FileSize:                     MapFileHeaderLength,
+			CollisionResolutionTechnique: int64(crtConf.CollisionResolutionTechnique),
+		})
+	}
+	if err != nil {
+		_ = innerFiles.RemoveFiles()
+		return
+	}
+
+	fm = &wrappedFiles{FileManagement: innerFiles, name: crtConf.Name, fileSystem: fileSystem}
+
+	return
+}
+
+func (W wrappedCRTFactory) Open(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (fm FileManagement, err error) {
+	innerFiles, err := openaddressing.NewOAFilesFromExistingFiles(name+"-inner", hashAlgorithm, fileSystem)
+	if err != nil {
+		return
+	}
+	fm = &wrappedFiles{FileManagement: innerFiles, name: name, fileSystem: fileSystem}
+
+	return
+}
+
+func (W wrappedCRTFactory) Estimate(crtConf CRTConf) (params StorageParameters, err error) {
+	crtConf.CollisionResolutionTechnique = crt.LinearProbing
+	params, err = openaddressing.EstimateOAFiles(crtConf)
+	params.CollisionResolutionTechnique = testExternalCRT
+
+	return
+}
+
+func (W *wrappedFiles) GetStorageParameters() (params StorageParameters) {
+	params = W.FileManagement.GetStorageParameters()
+	params.CollisionResolutionTechnique = testExternalCRT
+
+	return
+}
+
+func (W *wrappedFiles) RemoveFiles() (err error) {
+	err = W.FileManagement.RemoveFiles()
+	if err == nil {
+		err = W.fileSystem.Remove(MapFileName(W.name))
+	}
+
+	return
+}
+
+func TestRegisterCRT(t *testing.T) {
+	registerTestCRT.Do(func() {
+		err := RegisterCRT(testExternalCRT, wrappedCRTFactory{})
+		assert.NoError(t, err, "register crt")
+	})
+
+	t.Run("creates and opens files of a registered crt", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, testExternalCRT, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 50; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		// Execute
+		header, err := InspectFiles(testHashMap)
+		assert.NoError(t, err, "inspect files")
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open file hash map")
+
+		// Check
+		assert.Equal(t, testExternalCRT, header.CollisionResolutionTechnique, "crt in header")
+		for i := 0; i < 50; i++ {
+			value, err := fhm.Get([]byte{0, 0, 0, byte(i)})
+			assert.NoErrorf(t, err, "gets record #%d", i)
+			assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+		}
+		plan, err := Plan(1000, 4, 4, testExternalCRT, 1, 0.5)
+		assert.NoError(t, err, "plan with registered crt")
+		assert.Greater(t, plan.BucketsNeeded, 0, "buckets planned")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+		_, err = os.Stat(MapFileName(testHashMap))
+		assert.True(t, os.IsNotExist(err), "map file removed")
+	})
+
+	t.Run("works through the core operations of file management only", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, testExternalCRT, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		_, isCounter := fhm.fileManagement.(recordCounter)
+		records := make([]Record, 20)
+		for i := range records {
+			records[i] = Record{Key: []byte{0, 0, 1, byte(i)}, Value: []byte{2, 2, 2, byte(i)}}
+		}
+
+		// Execute
+		errBatch := fhm.SetBatch(records)
+		exists, errExists := fhm.Exists(records[3].Key)
+		values, errs, errGetBatch := fhm.GetBatch([][]byte{records[4].Key, {9, 9, 9, 9}})
+		existing, loaded, errGetOrSet := fhm.GetOrSet(records[5].Key, []byte{3, 3, 3, 3})
+		errUpdate := fhm.Update(records[6].Key, func(value []byte) ([]byte, error) { return []byte{4, 4, 4, 4}, nil })
+		updated, errUpdated := fhm.Get(records[6].Key)
+		count, errCount := fhm.Count()
+		errClear := fhm.Clear()
+		errEviction := fhm.EnableClockEviction(1)
+		errMapping := fhm.EnableMemoryMapping()
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+		_, _, errTTL := NewFileHashMap(testHashMap, testExternalCRT, 100, 1, 4, 4, nil, FeatureTTL)
+
+		// Check
+		assert.False(t, isCounter, "file management has core operations only")
+		assert.NoError(t, errBatch, "sets records in batch")
+		assert.NoError(t, errExists, "checks existence")
+		assert.True(t, exists, "record exists")
+		assert.NoError(t, errGetBatch, "gets records in batch")
+		assert.Equal(t, records[4].Value, values[0], "value from batch")
+		assert.ErrorIs(t, errs[1], crt.NoRecordFound{}, "missing record in batch")
+		assert.NoError(t, errGetOrSet, "gets or sets record")
+		assert.True(t, loaded, "existing record loaded")
+		assert.Equal(t, records[5].Value, existing, "existing value")
+		assert.NoError(t, errUpdate, "updates record")
+		assert.NoError(t, errUpdated, "gets updated record")
+		assert.Equal(t, []byte{4, 4, 4, 4}, updated, "updated value")
+		assert.NoError(t, errCount, "counts records")
+		assert.Equal(t, int64(len(records)), count, "records counted by reading buckets")
+		assert.Error(t, errClear, "clear not supported")
+		assert.Error(t, errEviction, "eviction not supported")
+		assert.Error(t, errMapping, "memory mapping not supported")
+		assert.Error(t, errTTL, "ttl not supported")
+	})
+
+	t.Run("fails for reserved, duplicate or nil registrations", func(t *testing.T) {
+		// Execute
+		errReserved := RegisterCRT(crt.LinearProbing, wrappedCRTFactory{})
+		errTooHigh := RegisterCRT(MaxExternalCRT+1, wrappedCRTFactory{})
+		errDuplicate := RegisterCRT(testExternalCRT, wrappedCRTFactory{})
+		errNil := RegisterCRT(testExternalCRT+1, nil)
+
+		// Check
+		assert.Error(t, errReserved, "reserved id")
+		assert.Error(t, errTooHigh, "id too high")
+		assert.Error(t, errDuplicate, "duplicate id")
+		assert.Error(t, errNil, "nil factory")
+	})
+
+	t.Run("fails to create with unregistered crt", func(t *testing.T) {
+		// Execute
+		_, _, err := NewFileHashMap(testHashMap, testExternalCRT+2, 10, 1, 4, 4, nil)
+
+		// Check
+		assert.Error(t, err, "unregistered crt")
+	})
+}
@@ -0,0 +1,46 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// WithStrictDirSync - Returns an Option that turns on or off syncing of the directory holding the files of the file
+// hash map each time a file is created or renamed, i.e. when the file hash map is created, when the WAL or key file is
+// created and when files are replaced by automatic growing or ReorgFiles. A new or renamed file is not durable until
+// its directory entry is, so without this a power loss right after creating a file hash map may leave an otherwise
+// written file without a name. It is turned off unless given. Directories are not synced on Windows, nor on file
+// systems given by WithFileSystem that don't implement vfs.DirSyncer.
+//   - enabled set to true turns on directory sync
+//
+// It returns:
+//   - option is the Option to give to NewFileHashMap, NewFromExistingFiles or any function taking options
+func WithStrictDirSync(enabled bool) (option Option) {
+	option = optionFunc(func(o *options) (err error) {
+		o.strictDirSync = enabled
+
+		return
+	})
+
+	return
+}
+
+// syncDirOf - Syncs the directory holding fileName if strict directory sync is turned on by o
+func syncDirOf(fileName string, o options) (err error) {
+	if !o.strictDirSync {
+		return
+	}
+
+	switch fs := o.fileSystem().(type) {
+	case vfs.OS:
+		err = storage.SyncDir(fileName)
+	case vfs.DirSyncer:
+		err = fs.SyncDir(fileName)
+	}
+	if err != nil {
+		err = fmt.Errorf("error while syncing directory: %s", err)
+	}
+
+	return
+}
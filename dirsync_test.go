@@ -0,0 +1,56 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithStrictDirSync(t *testing.T) {
+	t.Run("creates and grows files with strict directory sync", func(t *testing.T) {
+		// Execute
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithStrictDirSync(true))
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+		err = fhm.EnableAutoGrow(0.5)
+		assert.NoError(t, err, "enables auto grow")
+
+		for i := 0; i < 20; i++ {
+			key := make([]byte, 16)
+			key[0] = byte(i)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		// Check
+		key := make([]byte, 16)
+		key[0] = 19
+		_, err = fhm.Get(key)
+		assert.NoError(t, err, "gets record after growing")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("syncs only for file hash maps given the option", func(t *testing.T) {
+		// Prepare
+		fs := newCountingFileSystem()
+
+		// Execute
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithFileSystem(fs))
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+
+		// Check
+		assert.Equal(t, 0, fs.synced, "no directory synced without the option")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
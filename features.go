@@ -0,0 +1,167 @@
+package filehashmap
+
+import (
+	"fmt"
+)
+
+// Feature - Is an optional feature of a file hash map, selected when it is created by NewFileHashMap. Features can be
+// combined freely, and are persisted in the file header, so NewFromExistingFiles, ReorgFiles and automatic growing keep
+// them. The extra bytes a feature stores with each record are not part of valueLength and never visible to the caller.
+type Feature int
+
+const (
+	// FeatureTTL - Records can be set with a time to live (see SetWithTTL). Each record is stored with an 8 byte expiry
+	// time in front of the value. Expired records are deleted lazily, i.e. when they are encountered while probing or
+	// scanning a bucket, which keeps probe chains clean without any dedicated purge pass.
+	FeatureTTL Feature = iota + 1
+	// FeatureVariableKeys - Keys of any length are taken, such as URLs or paths, without padding them, and keyLength is
+	// ignored. Each record is stored with a digest of its key (the first 16 bytes of its SHA-256 hash) together with the
+	// key length in place of the key, and the full key is appended to a key file with a "-keys" inserted in the name.
+	// The address of the key in the key file is stored as 8 bytes in front of the value. Lookups verify the full key,
+	// so a digest collision never returns the value of another key, and setting a key whose digest collides with that
+	// of an existing key fails. Keys of popped records are left in the key file until the files are reorganized (see
+	// ReorgFiles). A custom hash algorithm is given key digests rather than keys.
+	FeatureVariableKeys
+	// FeatureChecksums - A CRC32 checksum of key and value is stored as 4 bytes after the value of every record, in the
+	// map file as well as in the overflow file. Get, GetBatch and Pop validate the checksum of the record found and
+	// return an error of type crt.CorruptRecord if it doesn't match, which detects records that were changed on disk by
+	// e.g. bit rot or a torn write.
+	FeatureChecksums
+	// FeatureTimestamps - The time when each record was created and last modified is stored as 16 bytes after the value,
+	// and read by GetWithMeta e.g. for cache invalidation logic. Keeping the created time of an existing record means
+	// that Set and SetBatch look up the existing record before writing, which costs an extra probe per record.
+	FeatureTimestamps
+)
+
+// featureSet - Holds the features selected for a new file hash map
+type featureSet struct {
+	ttl          bool
+	variableKeys bool
+	checksums    bool
+	timestamps   bool
+}
+
+// newFeatureSet - Returns the set of features selected for a new file hash map and the lengths of keys and values as
+// they are stored in the files with those features
+//   - features is the features selected
+//   - keyLength is the length of keys as given by the caller, ignored with FeatureVariableKeys
+//   - valueLength is the length of values as given by the caller
+//
+// It returns:
+//   - set is the set of features
+//   - fileKeyLength is the length of keys in the files
+//   - fileValueLength is the length of values in the files
+//   - err is a standard error, if a feature is unknown or valueLength is not positive while features are selected
+func newFeatureSet(features []Feature, keyLength, valueLength int) (set featureSet, fileKeyLength, fileValueLength int, err error) {
+	fileKeyLength, fileValueLength = keyLength, valueLength
+	if len(features) == 0 {
+		return
+	}
+
+	// Check if the valueLength is valid, since the features will make the stored value longer anyway
+	if valueLength <= 0 {
+		err = fmt.Errorf("value length must be a positive value higher than 0 (zero)")
+		return
+	}
+
+	for _, feature := range features {
+		switch feature {
+		case FeatureTTL:
+			set.ttl = true
+		case FeatureVariableKeys:
+			set.variableKeys = true
+		case FeatureChecksums:
+			set.checksums = true
+		case FeatureTimestamps:
+			set.timestamps = true
+		default:
+			err = fmt.Errorf("unknown feature %d", feature)
+			return
+		}
+	}
+
+	if set.ttl {
+		fileValueLength += ttlLength
+	}
+	if set.variableKeys {
+		fileKeyLength = storedKeyLength
+		fileValueLength += keyAddressLength
+	}
+	if set.checksums {
+		fileValueLength += checksumLength
+	}
+	if set.timestamps {
+		fileValueLength += timestampsLength
+	}
+
+	return
+}
+
+// enableFeatures - Marks the files of a new file hash map as having the features in set, and turns them on
+//   - set is the set of features
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) enableFeatures(set featureSet) (err error) {
+	if set.ttl {
+		if _, ok := F.fileManagement.(expiryChecker); !ok {
+			err = fmt.Errorf("TTL is not supported by the collision resolution technique")
+			return
+		}
+		err = F.fileManagement.SetSystemValue(ttlSystemValueID, []byte{1})
+		if err != nil {
+			err = fmt.Errorf("error while marking file hash map as supporting TTL: %s", err)
+			return
+		}
+		F.enableTTL()
+	}
+
+	if set.variableKeys {
+		err = F.fileManagement.SetSystemValue(varKeysSystemValueID, []byte{1})
+		if err == nil {
+			err = F.enableVariableKeys()
+		}
+		if err != nil {
+			err = fmt.Errorf("error while setting up variable key support: %s", err)
+			return
+		}
+	}
+
+	if set.checksums {
+		err = F.fileManagement.SetSystemValue(checksumSystemValueID, []byte{1})
+		if err != nil {
+			err = fmt.Errorf("error while marking file hash map as storing checksums: %s", err)
+			return
+		}
+		F.checksums = true
+	}
+
+	if set.timestamps {
+		err = F.fileManagement.SetSystemValue(timestampsSystemValueID, []byte{1})
+		if err != nil {
+			err = fmt.Errorf("error while marking file hash map as storing timestamps: %s", err)
+			return
+		}
+		F.timestamps = true
+	}
+
+	return
+}
+
+// features - Returns the features of the file hash map, to create new files with the same features
+func (F *FileHashMap) features() (features []Feature) {
+	if F.ttl != nil {
+		features = append(features, FeatureTTL)
+	}
+	if F.keyFile != nil {
+		features = append(features, FeatureVariableKeys)
+	}
+	if F.checksums {
+		features = append(features, FeatureChecksums)
+	}
+	if F.timestamps {
+		features = append(features, FeatureTimestamps)
+	}
+
+	return
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestFeatures(t *testing.T) {
+	t.Run("combines all features for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("combines all features for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				before := time.Now()
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, 0, test.valueLength, test.hFunc,
+					FeatureTTL, FeatureVariableKeys, FeatureChecksums, FeatureTimestamps)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 100)
+				for i := range records {
+					records[i] = Record{Key: []byte(fmt.Sprintf("https://example.com/%d", i)), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Value)
+				}
+				for _, record := range records[:50] {
+					err = fhm.SetWithTTL(record.Key, record.Value, 250*time.Millisecond)
+					assert.NoError(t, err, "sets record with ttl")
+				}
+				err = fhm.SetBatch(records[50:])
+				assert.NoError(t, err, "sets records without ttl")
+
+				// Execute
+				time.Sleep(300 * time.Millisecond)
+				_, errExpired := fhm.Get(records[0].Key)
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "reopen file hash map")
+				value, meta, errMeta := fhm.GetWithMeta(records[99].Key)
+				report, errVerify := fhm.Verify()
+
+				// Check
+				assert.ErrorIs(t, errExpired, crt.NoRecordFound{}, "expired record not found")
+				assert.NoError(t, errMeta, "gets record with meta after reopen")
+				assert.Equal(t, records[99].Value, value, "value after reopen")
+				assert.False(t, meta.Created.Before(before.Truncate(time.Second)), "created time kept")
+				assert.NoError(t, errVerify, "verifies files")
+				assert.True(t, report.OK(), "checksums match")
+				assert.ElementsMatch(t, []Feature{FeatureTTL, FeatureVariableKeys, FeatureChecksums, FeatureTimestamps},
+					fhm.features(), "features kept after reopen")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("keeps all features when growing automatically", func(t *testing.T) {
+		// Prepare
+		fhm, hashMapInfo, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 8, 10, nil,
+			FeatureTTL, FeatureChecksums, FeatureTimestamps)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableAutoGrow(0.5)
+		assert.NoError(t, err, "enables auto grow")
+
+		records := make([]Record, 100)
+		for i := range records {
+			records[i] = Record{Key: make([]byte, 8), Value: make([]byte, 10)}
+			rand.Read(records[i].Key)
+			rand.Read(records[i].Value)
+		}
+
+		// Execute
+		for _, record := range records {
+			err = fhm.SetWithTTL(record.Key, record.Value, time.Hour)
+			assert.NoError(t, err, "sets record with ttl")
+		}
+		value, _, errMeta := fhm.GetWithMeta(records[0].Key)
+
+		// Check
+		sp := fhm.fileManagement.GetStorageParameters()
+		assert.Greater(t, int(sp.NumberOfBucketsAvailable), hashMapInfo.NumberOfBucketsAvailable, "number of buckets grown")
+		assert.NoError(t, errMeta, "gets record with meta after growing")
+		assert.Equal(t, records[0].Value, value, "value after growing")
+		assert.Equal(t, []Feature{FeatureTTL, FeatureChecksums, FeatureTimestamps}, fhm.features(), "features kept")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails for unknown features and values without length", func(t *testing.T) {
+		// Execute
+		_, _, errUnknown := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 8, 10, nil, Feature(99))
+		_, _, errLength := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 8, 0, nil, FeatureChecksums)
+
+		// Check
+		assert.Error(t, errUnknown, "unknown feature")
+		assert.Error(t, errLength, "value length zero")
+	})
+}
@@ -0,0 +1,269 @@
+// Package fhmtest provides generators of file hash map files with specific pathological layouts, such as long collision
+// chains, large areas of deleted records, corrupted headers and truncated overflow files. The files are built through
+// the regular API, or by damaging files built that way, so edge cases can be tested against realistic files rather
+// than hand-crafted byte arrays.
+package fhmtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"math/rand"
+	"os"
+)
+
+// Conf - Configuration of the file hash map to generate, with the same meaning as the parameters to
+// filehashmap.NewFileHashMap. The internal hash algorithm is always used.
+//   - Name is the name of the file hash map and will be used to form file name(s)
+//   - CRT is the collision resolution technique to use
+//   - BucketsNeeded is the number of buckets needed
+//   - RecordsPerBucket is the number of records to hold in each bucket in the map file
+//   - KeyLength is the length of the key part in a record
+//   - ValueLength is the length of the value part in a record
+//   - Seed is the seed for generating keys and values, the same seed gives the same records
+type Conf struct {
+	Name             string
+	CRT              int
+	BucketsNeeded    int
+	RecordsPerBucket int
+	KeyLength        int
+	ValueLength      int
+	Seed             int64
+}
+
+// Fixture - Describes the generated files
+//   - Name is the name of the file hash map, to be used with filehashmap.NewFromExistingFiles and Remove
+//   - Records is the records that were set and not popped
+//   - Popped is the keys of records that were set and then popped
+type Fixture struct {
+	Name    string
+	Records []filehashmap.Record
+	Popped  [][]byte
+}
+
+// FullBucketChain - Generates files where chainLength records all have the same home bucket, which gives a
+// collision chain of that length. Depending on the collision resolution technique the chain fills up the home bucket
+// and then continues in the overflow file or in probed buckets.
+//   - conf is the configuration of the file hash map
+//   - chainLength is the number of records in the chain
+//
+// It returns:
+//   - fixture is a Fixture struct describing the generated files, with records in chain order
+//   - err is a standard error, if the files could not be generated, e.g. if the chain doesn't fit in the map file
+func FullBucketChain(conf Conf, chainLength int) (fixture Fixture, err error) {
+	fhm, rnd, err := create(conf)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	fixture.Name = conf.Name
+	homeBucketNo := int64(-1)
+	for len(fixture.Records) < chainLength {
+		record := randomRecord(rnd, conf)
+		bucketNo := fhm.HomeBucket(record.Key)
+		if homeBucketNo == -1 {
+			homeBucketNo = bucketNo
+		}
+		if bucketNo != homeBucketNo {
+			continue
+		}
+
+		err = fhm.Set(record.Key, record.Value)
+		if err != nil {
+			err = fmt.Errorf("error while setting record #%d in chain: %s", len(fixture.Records), err)
+			return
+		}
+		fixture.Records = append(fixture.Records, record)
+	}
+
+	return
+}
+
+// TombstoneDesert - Generates files where records records are set and all but keep of them are then popped, which
+// leaves large areas of deleted records (tombstones) that lookups have to probe or walk past.
+//   - conf is the configuration of the file hash map
+//   - records is the number of records to set
+//   - keep is the number of records to keep, the rest are popped
+//
+// It returns:
+//   - fixture is a Fixture struct describing the generated files
+//   - err is a standard error, if the files could not be generated
+func TombstoneDesert(conf Conf, records, keep int) (fixture Fixture, err error) {
+	fhm, rnd, err := create(conf)
+	if err != nil {
+		return
+	}
+	defer fhm.CloseFiles()
+
+	fixture.Name = conf.Name
+	all, err := setRandomRecords(fhm, rnd, conf, records)
+	if err != nil {
+		return
+	}
+
+	if keep > len(all) {
+		keep = len(all)
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	fixture.Records = all[:keep]
+
+	for _, record := range all[keep:] {
+		_, err = fhm.Pop(record.Key)
+		if err != nil {
+			err = fmt.Errorf("error while popping record: %s", err)
+			return
+		}
+		fixture.Popped = append(fixture.Popped, record.Key)
+	}
+
+	return
+}
+
+// CorruptedHeader - Generates files with records records where the header of the map file is then zeroed, as if a
+// write of the header was torn. Opening the files is expected to fail.
+//   - conf is the configuration of the file hash map
+//   - records is the number of records to set before the header is corrupted
+//
+// It returns:
+//   - fixture is a Fixture struct describing the generated files
+//   - err is a standard error, if the files could not be generated
+func CorruptedHeader(conf Conf, records int) (fixture Fixture, err error) {
+	fhm, rnd, err := create(conf)
+	if err != nil {
+		return
+	}
+
+	fixture.Name = conf.Name
+	fixture.Records, err = setRandomRecords(fhm, rnd, conf, records)
+	fhm.CloseFiles()
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(storage.GetMapFileName(conf.Name), os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+	defer func(file *os.File) { _ = file.Close() }(file)
+
+	_, err = file.WriteAt(make([]byte, storage.MapFileHeaderLength), 0)
+	if err != nil {
+		err = fmt.Errorf("error while corrupting header: %s", err)
+	}
+
+	return
+}
+
+// TruncatedOverflow - Generates files with records records where the overflow file is then truncated to half its size,
+// as if the file system lost the tail of it. Records that were stored in the lost part can not be read. Only
+// collision resolution techniques with an overflow file are supported (crt.SeparateChaining, crt.Hybrid and
+// crt.LinearHashing).
+//   - conf is the configuration of the file hash map
+//   - records is the number of records to set, enough to spill over into the overflow file
+//
+// It returns:
+//   - fixture is a Fixture struct describing the generated files, including records that were truncated away
+//   - err is a standard error, if the files could not be generated or no record ended up in the overflow file
+func TruncatedOverflow(conf Conf, records int) (fixture Fixture, err error) {
+	switch conf.CRT {
+	case crt.SeparateChaining, crt.Hybrid, crt.LinearHashing:
+	default:
+		err = fmt.Errorf("collision resolution technique has no overflow file")
+		return
+	}
+
+	fhm, rnd, err := create(conf)
+	if err != nil {
+		return
+	}
+
+	fixture.Name = conf.Name
+	fixture.Records, err = setRandomRecords(fhm, rnd, conf, records)
+	fhm.CloseFiles()
+	if err != nil {
+		return
+	}
+
+	fileName := storage.GetOvflFileName(conf.Name)
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return
+	}
+	if info.Size() <= storage.OvflFileHeaderLength {
+		err = fmt.Errorf("no records in overflow file, set more records or use fewer buckets")
+		return
+	}
+
+	size := storage.OvflFileHeaderLength + (info.Size()-storage.OvflFileHeaderLength)/2
+	err = os.Truncate(fileName, size)
+	if err != nil {
+		err = fmt.Errorf("error while truncating overflow file: %s", err)
+	}
+
+	return
+}
+
+// Remove - Removes all files of a generated file hash map, also if they can no longer be opened
+//   - name is the name of the file hash map
+//
+// It returns:
+//   - err is a standard error, if some file exists but could not be removed
+func Remove(name string) (err error) {
+	for _, fileName := range []string{
+		storage.GetMapFileName(name),
+		storage.GetOvflFileName(name),
+		storage.GetWALFileName(name),
+		storage.GetKeyFileName(name),
+	} {
+		if rmErr := os.Remove(fileName); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			err = rmErr
+			return
+		}
+	}
+
+	return
+}
+
+// create - Creates a new file hash map according to conf together with a random generator seeded by conf
+func create(conf Conf) (fhm *filehashmap.FileHashMap, rnd *rand.Rand, err error) {
+	fhm, _, err = filehashmap.NewFileHashMap(conf.Name, conf.CRT, conf.BucketsNeeded, conf.RecordsPerBucket, conf.KeyLength, conf.ValueLength, nil)
+	if err != nil {
+		err = fmt.Errorf("error while creating file hash map: %s", err)
+		return
+	}
+
+	rnd = rand.New(rand.NewSource(conf.Seed))
+
+	return
+}
+
+// setRandomRecords - Sets n records with random keys and values
+func setRandomRecords(fhm *filehashmap.FileHashMap, rnd *rand.Rand, conf Conf, n int) (records []filehashmap.Record, err error) {
+	records = make([]filehashmap.Record, 0, n)
+	for i := 0; i < n; i++ {
+		record := randomRecord(rnd, conf)
+		err = fhm.Set(record.Key, record.Value)
+		if err != nil {
+			err = fmt.Errorf("error while setting record #%d: %s", i, err)
+			return
+		}
+		records = append(records, record)
+	}
+
+	return
+}
+
+// randomRecord - Returns a record with random key and value of the lengths in conf
+func randomRecord(rnd *rand.Rand, conf Conf) (record filehashmap.Record) {
+	record.Key = make([]byte, conf.KeyLength)
+	record.Value = make([]byte, conf.ValueLength)
+	rnd.Read(record.Key)
+	rnd.Read(record.Value)
+
+	return
+}
@@ -0,0 +1,146 @@
+//go:build integration
+
+package fhmtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testHashMap = "test"
+
+func TestFullBucketChain(t *testing.T) {
+	tests := []Conf{
+		{Name: testHashMap, CRT: crt.SeparateChaining, BucketsNeeded: 10, RecordsPerBucket: 2, KeyLength: 16, ValueLength: 10},
+		{Name: testHashMap, CRT: crt.LinearProbing, BucketsNeeded: 10, RecordsPerBucket: 2, KeyLength: 16, ValueLength: 10},
+		{Name: testHashMap, CRT: crt.RobinHood, BucketsNeeded: 10, RecordsPerBucket: 2, KeyLength: 16, ValueLength: 10},
+	}
+
+	for _, conf := range tests {
+		t.Run(fmt.Sprintf("generates a chain for crt %d", conf.CRT), func(t *testing.T) {
+			// Execute
+			fixture, err := FullBucketChain(conf, 6)
+
+			// Check
+			assert.NoError(t, err, "generates files")
+			assert.Len(t, fixture.Records, 6, "records in chain")
+
+			fhm, _, err := filehashmap.NewFromExistingFiles(fixture.Name, nil)
+			assert.NoError(t, err, "opens files")
+
+			homeBucketNo := fhm.HomeBucket(fixture.Records[0].Key)
+			for i, record := range fixture.Records {
+				assert.Equalf(t, homeBucketNo, fhm.HomeBucket(record.Key), "record #%d in same home bucket", i)
+				value, err := fhm.Get(record.Key)
+				assert.NoErrorf(t, err, "gets record #%d", i)
+				assert.Truef(t, utils.IsEqual(record.Value, value), "value of record #%d", i)
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		})
+	}
+}
+
+func TestTombstoneDesert(t *testing.T) {
+	t.Run("generates files with mostly deleted records", func(t *testing.T) {
+		// Prepare
+		conf := Conf{Name: testHashMap, CRT: crt.QuadraticProbing, BucketsNeeded: 100, RecordsPerBucket: 2, KeyLength: 16, ValueLength: 10, Seed: 1}
+
+		// Execute
+		fixture, err := TombstoneDesert(conf, 150, 10)
+
+		// Check
+		assert.NoError(t, err, "generates files")
+		assert.Len(t, fixture.Records, 10, "records kept")
+		assert.Len(t, fixture.Popped, 140, "records popped")
+
+		fhm, _, err := filehashmap.NewFromExistingFiles(fixture.Name, nil)
+		assert.NoError(t, err, "opens files")
+
+		for _, record := range fixture.Records {
+			_, err = fhm.Get(record.Key)
+			assert.NoError(t, err, "gets kept record")
+		}
+		for _, key := range fixture.Popped {
+			_, err = fhm.Get(key)
+			assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record not found")
+		}
+
+		stat, err := fhm.Stat(false)
+		assert.NoError(t, err, "gets stat")
+		assert.Equal(t, 10, stat.Records, "only kept records counted")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestCorruptedHeader(t *testing.T) {
+	t.Run("generates files that can not be opened", func(t *testing.T) {
+		// Prepare
+		conf := Conf{Name: testHashMap, CRT: crt.LinearProbing, BucketsNeeded: 10, RecordsPerBucket: 2, KeyLength: 16, ValueLength: 10}
+
+		// Execute
+		fixture, err := CorruptedHeader(conf, 5)
+
+		// Check
+		assert.NoError(t, err, "generates files")
+		_, _, err = filehashmap.NewFromExistingFiles(fixture.Name, nil)
+		assert.Error(t, err, "fails to open files")
+
+		// Clean up
+		err = Remove(fixture.Name)
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestTruncatedOverflow(t *testing.T) {
+	t.Run("generates files with records lost from overflow file", func(t *testing.T) {
+		// Prepare
+		conf := Conf{Name: testHashMap, CRT: crt.SeparateChaining, BucketsNeeded: 4, RecordsPerBucket: 1, KeyLength: 16, ValueLength: 10}
+
+		// Execute
+		fixture, err := TruncatedOverflow(conf, 40)
+
+		// Check
+		assert.NoError(t, err, "generates files")
+
+		fhm, _, err := filehashmap.NewFromExistingFiles(fixture.Name, nil)
+		assert.NoError(t, err, "opens files")
+
+		var failed int
+		for _, record := range fixture.Records {
+			if _, err = fhm.Get(record.Key); err != nil && !errors.Is(err, crt.NoRecordFound{}) {
+				failed++
+			}
+		}
+		assert.Greater(t, failed, 0, "some records can not be read")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("rejects collision resolution techniques without overflow file", func(t *testing.T) {
+		// Prepare
+		conf := Conf{Name: testHashMap, CRT: crt.LinearProbing, BucketsNeeded: 4, RecordsPerBucket: 1, KeyLength: 16, ValueLength: 10}
+
+		// Execute
+		_, err := TruncatedOverflow(conf, 4)
+
+		// Check
+		assert.Error(t, err, "no overflow file")
+
+		// Clean up
+		err = Remove(testHashMap)
+		assert.NoError(t, err, "removes files")
+	})
+}
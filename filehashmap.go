@@ -1,18 +1,37 @@
 package filehashmap
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/bloom"
+	"github.com/gostonefire/filehashmap/internal/keyfile"
 	"github.com/gostonefire/filehashmap/internal/model"
 	"github.com/gostonefire/filehashmap/internal/overflow"
 	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/storage/cuckoo"
+	"github.com/gostonefire/filehashmap/internal/storage/hopscotch"
 	"github.com/gostonefire/filehashmap/internal/storage/openaddressing"
+	"github.com/gostonefire/filehashmap/internal/storage/robinhood"
 	"github.com/gostonefire/filehashmap/internal/storage/separatechaining"
 	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/gostonefire/filehashmap/internal/wal"
+	"github.com/gostonefire/filehashmap/vfs"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// FileManagement - Interface for any file management implementation
+// FileManagement - Interface for any file management implementation, which is what a collision resolution technique
+// registered by RegisterCRT implements, with types from the aliases StorageRecord, StorageBucket, StorageParameters,
+// StorageMetrics and OverflowRecords. It holds the core operations only. File management may implement more of the
+// operations of the built-in techniques, e.g. Exists, GetBatch, SetBatch, GetOrSet, Counts, Clear, SetMetrics or
+// MemoryMap with the same signatures, which are then found by type assertion and used, while others are done through
+// the core operations or reported as not supported.
 type FileManagement interface {
 	CloseFiles()
 	RemoveFiles() (err error)
@@ -21,6 +40,9 @@ type FileManagement interface {
 	Delete(record model.Record) (err error)
 	GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error)
 	GetStorageParameters() (params model.StorageParameters)
+	GetSystemValue(id uint8) (value []byte, err error)
+	SetSystemValue(id uint8, value []byte) (err error)
+	Sync() (err error)
 }
 
 // HashMapInfo - Information structure containing some information about the hash map created
@@ -28,11 +50,23 @@ type FileManagement interface {
 //   - NumberOfBucketsAvailable is the total number of available buckets in the hash map file
 //   - TotalRecords is the total number of records available in the hash map file (not including overflow)
 //   - FileSize is the total size of the map file created.
+//   - Verification is the result of the verification pass of ReorgFiles, see ReorgConf.VerifySamples, otherwise nil
 type HashMapInfo struct {
 	NumberOfBucketsNeeded    int
 	NumberOfBucketsAvailable int
 	TotalRecords             int
 	FileSize                 int
+	Verification             *ReorgVerification
+}
+
+// ReorgVerification - Result of the verification pass made by ReorgFiles after records have been migrated
+//   - Samples is the number of migrated records that were looked up in the new files
+//   - Matched is the number of sampled records that were found in the new files with the expected value
+//   - SuccessRate is Matched divided by Samples, or 1 if there were no records to sample
+type ReorgVerification struct {
+	Samples     int
+	Matched     int
+	SuccessRate float64
 }
 
 // HashMapStat - Statistics on the overall usage and distribution over buckets
@@ -40,17 +74,136 @@ type HashMapInfo struct {
 //   - MapFileRecords is the number of records stored in the fixed sized hash map file
 //   - OverflowRecords is the number of records that has ended up in the overflow file
 //   - BucketDistribution is the number of records stored in each available bucket
+//   - LastSeq is the sequence number of the last applied mutation
+//   - ProbeLengths is the number of records in the map file per probe length, where the probe length of a record is
+//     the number of buckets a lookup probes before reaching the bucket holding it, i.e. zero in its home bucket
+//   - MaxProbeLength is the longest probe length of any record in the map file
+//   - MeanProbeLength is the mean probe length of the records in the map file
+//   - ChainLengths is the number of buckets per overflow chain length, where the chain length of a bucket is the number
+//     of records in the overflow file, deleted or not, linked from it. It is nil unless Separate Chaining, Hybrid or
+//     Linear Hashing is used.
+//   - MaxChainLength is the longest overflow chain of any bucket
+//   - MeanChainLength is the mean overflow chain length over all buckets
+//   - MapFileBytes is the size of the map file in bytes
+//   - OverflowFileBytes is the size of the overflow file in bytes, zero if there is no overflow file
+//   - OverflowDeletedRecords is the number of deleted records left in overflow chains
+//   - LoadFactor is Records divided by the number of records the map file can hold, which may exceed 1 with an
+//     overflow file
 type HashMapStat struct {
-	Records            int
-	MapFileRecords     int
-	OverflowRecords    int
-	BucketDistribution []int
+	Records                int
+	MapFileRecords         int
+	OverflowRecords        int
+	BucketDistribution     []int
+	LastSeq                int64
+	ProbeLengths           []int
+	MaxProbeLength         int
+	MeanProbeLength        float64
+	ChainLengths           []int
+	MaxChainLength         int
+	MeanChainLength        float64
+	MapFileBytes           int64
+	OverflowFileBytes      int64
+	OverflowDeletedRecords int
+	LoadFactor             float64
+}
+
+// DistributionSink - Receiver of the bucket distribution streamed by StatWithSink
+type DistributionSink interface {
+	// Add - Is called with the number of records stored in bucket bucketNo
+	Add(bucketNo int64, count int)
+}
+
+// Record - A key and value pair, used in operations on several records at once
+type Record struct {
+	Key   []byte
+	Value []byte
+}
+
+// StateEmpty - State of a record that has never been in use
+const StateEmpty uint8 = model.RecordEmpty
+
+// StateOccupied - State of an exported record that is in use
+const StateOccupied uint8 = model.RecordOccupied
+
+// StateDeleted - State of an exported record that has been in use but was deleted
+const StateDeleted uint8 = model.RecordDeleted
+
+// ExportBatch - A batch of records in columnar layout, where each slice holds one column and index i in all slices
+// together make up one record. The layout maps directly onto e.g. Arrow record batches.
+//   - Keys is the key of each record
+//   - Values is the value of each record
+//   - Buckets is the bucket number in which each record is stored
+//   - States is the state of each record, either StateOccupied or StateDeleted
+//   - IsOverflow is true for each record that is stored in the overflow file
+//   - AccessCounts is the persisted access count of each record (see EnableAccessCounting)
+//   - Expiries is the expiry time of each record in unix nanoseconds, zero if it never expires, nil without FeatureTTL
+//   - Created is the time each record was created in unix nanoseconds, nil without FeatureTimestamps
+//   - Modified is the time each record was last modified in unix nanoseconds, nil without FeatureTimestamps
+type ExportBatch struct {
+	Keys         [][]byte
+	Values       [][]byte
+	Buckets      []int64
+	States       []uint8
+	IsOverflow   []bool
+	AccessCounts []uint8
+	Expiries     []int64
+	Created      []int64
+	Modified     []int64
+}
+
+// OperationStats - Counters for operations made on the file hash map since it was opened or since the last call to ResetStats
+//   - Gets is the number of calls to Get and Exists
+//   - GetMisses is the number of calls to Get that resulted in crt.NoRecordFound and of calls to Exists finding no record
+//   - Sets is the number of calls to Set
+//   - Pops is the number of calls to Pop
+//   - Errors is the number of operations that failed with an error other than crt.NoRecordFound
+//   - Evictions is the number of records evicted to make room for new records (see EnableClockEviction)
+//   - LastReset is the time when counting started, either when the file hash map was opened or ResetStats was called
+type OperationStats struct {
+	Gets      int64
+	GetMisses int64
+	Sets      int64
+	Pops      int64
+	Errors    int64
+	Evictions int64
+	LastReset time.Time
 }
 
-// FileHashMap - The main implementation struct
+// FileHashMap - The main implementation struct. All methods are safe for concurrent use from multiple goroutines,
+// operations are serialized by an internal lock.
 type FileHashMap struct {
-	fileManagement FileManagement
-	name           string
+	mu                sync.Mutex
+	fileManagement    FileManagement
+	name              string
+	opStats           *opCounters
+	accessCounter     *accessCounter
+	evictionHand      *evictionHand
+	opLog             *FileHashMap
+	wal               *wal.WAL
+	valueValidator    func(key, value []byte) error
+	codec             Codec
+	ttl               *ttlSettings
+	accessHints       bool
+	memoryMapped      bool
+	bucketCacheBudget int64
+	probeCap          int64
+	brentInsertion    bool
+	writeBuffer       *writeBuffer
+	hashAlgorithm     hashfunc.HashAlgorithm
+	autoGrow          *autoGrow
+	keyFile           *keyfile.KeyFile
+	maxKeyLength      int
+	checksums         bool
+	timestamps        bool
+	groupCommit       *groupCommit
+	syncPolicy        SyncPolicy
+	periodicSync      *periodicSync
+	watchdog          *watchdog
+	tracer            *operationTracer
+	lock              *storage.FileLock
+	readOnly          bool
+	options           options
+	bloom             *bloom.Filter
 	// CloseFiles - Closes the hash map file and the ovfl file. Use this preferably in a "defer" directly
 	// after a CreateNewFile or NewFromExistingFile.
 	CloseFiles func()
@@ -69,7 +222,10 @@ type FileHashMap struct {
 //   - recordsPerBucket is the number of records to hold in each bucket in the map file. Since minimum is one, setting this below one will still create one.
 //   - keyLength is the length of the key part in a record
 //   - valueLength is the length of the value part in a record
-//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the HashAlgorithm hashfunc.
+//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the HashAlgorithm hashfunc, or
+//     one returned by BuiltinHash to select the hash function the internal hash algorithm is built on.
+//   - options is any optional features to combine, such as FeatureTTL or FeatureChecksums, and settings such as
+//     WithFileSystem
 //
 // It returns:
 //   - fileHashMap is a pointer to a FileHashMap struct
@@ -83,36 +239,52 @@ func NewFileHashMap(
 	keyLength int,
 	valueLength int,
 	hashAlgorithm hashfunc.HashAlgorithm,
+	options ...Option,
 ) (
 	fileHashMap *FileHashMap,
 	hashMapInfo HashMapInfo,
 	err error,
 ) {
-
-	// Check choice of Collision Resolution Technique
-	if crtType < 1 || crtType > 4 {
-		err = fmt.Errorf("crtType has to be one of SeparateChaining, LinearProbing, QuadraticProbing or DoubleHashing")
+	o, err := newOptions(options, true)
+	if err != nil {
 		return
 	}
 
-	// Check if bucketsNeeded is valid
-	if bucketsNeeded <= 0 {
-		err = fmt.Errorf("bucketsNeeded must be a positive value higher than 0 (zero)")
-		return
+	fileHashMap, hashMapInfo, err = createFileHashMap(name, crtType, bucketsNeeded, recordsPerBucket, keyLength, valueLength, hashAlgorithm, o)
 
-	}
+	return
+}
 
-	// Check if the key length is valid
-	if keyLength <= 0 {
-		err = fmt.Errorf("key length must be a positive value higher than 0 (zero)")
+// createFileHashMap - Creates a new file hash map with the given settings, see NewFileHashMap
+func createFileHashMap(
+	name string,
+	crtType int,
+	bucketsNeeded int,
+	recordsPerBucket int,
+	keyLength int,
+	valueLength int,
+	hashAlgorithm hashfunc.HashAlgorithm,
+	o options,
+) (
+	fileHashMap *FileHashMap,
+	hashMapInfo HashMapInfo,
+	err error,
+) {
+
+	// Check choice of Collision Resolution Technique
+	err = checkCRTType(crtType)
+	if err != nil {
 		return
 	}
 
-	// Check if the valueLength is valid
-	if valueLength <= 0 {
-		err = fmt.Errorf("value length must be a positive value higher than 0 (zero)")
+	selected, keyLength, valueLength, err := newFeatureSet(o.features, keyLength, valueLength)
+	if err != nil {
 		return
+	}
 
+	err = checkDimensions(bucketsNeeded, keyLength, valueLength)
+	if err != nil {
+		return
 	}
 
 	// Check if name is empty
@@ -126,6 +298,21 @@ func NewFileHashMap(
 		recordsPerBucket = 1
 	}
 
+	lock, err := lockFiles(name, false, o)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil && lock != nil {
+			_ = lock.Unlock()
+		}
+	}()
+
+	hashAlgorithm, hashParameters, err := newHashParameters(hashAlgorithm)
+	if err != nil {
+		return
+	}
+
 	crtConf := model.CRTConf{
 		Name:                         name,
 		NumberOfBucketsNeeded:        int64(bucketsNeeded),
@@ -134,13 +321,31 @@ func NewFileHashMap(
 		ValueLength:                  int64(valueLength),
 		CollisionResolutionTechnique: crtType,
 		HashAlgorithm:                hashAlgorithm,
+		HashParameters:               hashParameters,
+		FileSystem:                   o.fileSystem(),
 	}
 
 	var fm FileManagement
-	if crtType == crt.SeparateChaining {
+	switch crtType {
+	case crt.SeparateChaining, crt.Hybrid, crt.LinearHashing:
 		fm, err = separatechaining.NewSCFiles(crtConf)
-	} else {
+	case crt.RobinHood:
+		fm, err = robinhood.NewRHFiles(crtConf)
+	case crt.CuckooHashing:
+		fm, err = cuckoo.NewCHFiles(crtConf)
+	case crt.Hopscotch:
+		fm, err = hopscotch.NewHSFiles(crtConf)
+	case crt.LinearProbing, crt.QuadraticProbing, crt.DoubleHashing:
 		fm, err = openaddressing.NewOAFiles(crtConf)
+	default:
+		factory, _ := registeredCRT(crtType)
+		fm, err = factory.New(crtConf)
+	}
+	if err == nil {
+		err = saveHashIdentity(fm, hashAlgorithm)
+	}
+	if err == nil {
+		err = syncDirOf(storage.GetMapFileName(name), o)
 	}
 	if err != nil {
 		if fm != nil {
@@ -150,23 +355,40 @@ func NewFileHashMap(
 	}
 
 	// Prepare return data
-	fileHashMap = &FileHashMap{
-		fileManagement: fm,
-		name:           name,
-		CloseFiles:     func() { fm.CloseFiles() },
-		RemoveFiles: func() error {
-			fm.CloseFiles()
-			return fm.RemoveFiles()
-		},
+	fileHashMap, hashMapInfo = newFileHashMap(name, fm, o)
+	fileHashMap.hashAlgorithm = hashAlgorithm
+	fileHashMap.lock = lock
+
+	err = fileHashMap.enableFeatures(selected)
+	if err != nil {
+		_ = fileHashMap.RemoveFiles()
+		fileHashMap = nil
+		lock = nil
 	}
 
-	sp := fm.GetStorageParameters()
+	return
+}
+
+// checkDimensions - Checks that the number of buckets and the lengths given when creating a hash map are valid
+func checkDimensions(bucketsNeeded, keyLength, valueLength int) (err error) {
+	// Check if bucketsNeeded is valid
+	if bucketsNeeded <= 0 {
+		err = fmt.Errorf("bucketsNeeded must be a positive value higher than 0 (zero)")
+		return
+
+	}
+
+	// Check if the key length is valid
+	if keyLength <= 0 {
+		err = fmt.Errorf("key length must be a positive value higher than 0 (zero)")
+		return
+	}
+
+	// Check if the valueLength is valid
+	if valueLength <= 0 {
+		err = fmt.Errorf("value length must be a positive value higher than 0 (zero)")
+		return
 
-	hashMapInfo = HashMapInfo{
-		NumberOfBucketsNeeded:    int(sp.NumberOfBucketsNeeded),
-		NumberOfBucketsAvailable: int(sp.NumberOfBucketsAvailable),
-		TotalRecords:             int(sp.NumberOfBucketsAvailable * sp.RecordsPerBucket),
-		FileSize:                 int(sp.MapFileSize),
 	}
 
 	return
@@ -175,41 +397,260 @@ func NewFileHashMap(
 // NewFromExistingFiles - Opens an existing file containing a hash map. The file must have a valid header, and if the
 // file was created and used together with a custom hash algorithm, also that same algorithm has to be supplied.
 //   - name is the name of an existing hash map.
-//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the hashfunc.HashAlgorithm interface.
+//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the hashfunc.HashAlgorithm interface,
+//     or one returned by BuiltinHash to check that the files were created with that hash function.
+//   - options is any optional settings, such as WithFileSystem. Features are kept in the files and can not be given.
 //
 // It returns:
 //   - fileHashMap is a pointer to a FileHashMap struct
 //   - hashMapInfo is a HashMapInfo struct containing some data regarding the hash map opened.
 //   - err is a normal Go Error which should be nil if everything went ok
-func NewFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm) (
+func NewFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, options ...Option) (
 	fileHashMap *FileHashMap,
 	hashMapInfo HashMapInfo,
 	err error,
 ) {
-	header, err := storage.GetFileHeader(storage.GetMapFileName(name))
+	o, err := newOptions(options, false)
 	if err != nil {
 		return
 	}
 
-	var fm FileManagement
-	if header.CollisionResolutionTechnique == int64(crt.SeparateChaining) {
-		fm, err = separatechaining.NewSCFilesFromExistingFiles(name, hashAlgorithm)
-	} else {
-		fm, err = openaddressing.NewOAFilesFromExistingFiles(name, hashAlgorithm)
+	fileHashMap, hashMapInfo, err = openExistingFiles(name, hashAlgorithm, false, o)
+
+	return
+}
+
+// NewFromExistingFilesReadOnly - Opens an existing file containing a hash map for reading only, in the same way as
+// NewFromExistingFiles. Files are opened without write access and all operations that would change them fail, which
+// makes it safe to open a hash map that another process is writing to if file locking is turned on (see
+// WithFileLocking), since a read-only open takes a shared lock that any number of readers can hold at the same time.
+//   - name is the name of an existing hash map.
+//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the hashfunc.HashAlgorithm interface,
+//     or one returned by BuiltinHash to check that the files were created with that hash function.
+//   - options is any optional settings, such as WithFileSystem. Features are kept in the files and can not be given.
+//
+// It returns:
+//   - fileHashMap is a pointer to a FileHashMap struct
+//   - hashMapInfo is a HashMapInfo struct containing some data regarding the hash map opened.
+//   - err is a normal Go Error which should be nil if everything went ok
+func NewFromExistingFilesReadOnly(name string, hashAlgorithm hashfunc.HashAlgorithm, options ...Option) (
+	fileHashMap *FileHashMap,
+	hashMapInfo HashMapInfo,
+	err error,
+) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	fileHashMap, hashMapInfo, err = openExistingFiles(name, hashAlgorithm, true, o)
+
+	return
+}
+
+// openExistingFiles - Opens an existing file hash map, for reading only if readOnly is true, see NewFromExistingFiles
+func openExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, readOnly bool, o options) (
+	fileHashMap *FileHashMap,
+	hashMapInfo HashMapInfo,
+	err error,
+) {
+	lock, err := lockFiles(name, readOnly, o)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil && lock != nil {
+			_ = lock.Unlock()
+		}
+	}()
+
+	builtin := hashAlgorithm
+	hashAlgorithm = customHashAlgorithm(hashAlgorithm)
+
+	fileSystem := o.fileSystem()
+	if readOnly {
+		fileSystem = storage.NewReadOnlyFileSystem(fileSystem)
 	}
+
+	header, err := storage.GetFileHeader(fileSystem, storage.GetMapFileName(name))
 	if err != nil {
 		return
 	}
 
+	fm, err := openFileManagement(name, int(header.CollisionResolutionTechnique), hashAlgorithm, fileSystem)
+	if err != nil {
+		return
+	}
+
+	err = checkBuiltinHash(fm.GetStorageParameters(), builtin)
+	if err == nil {
+		err = checkHashIdentity(fm, hashAlgorithm)
+	}
+	if err != nil {
+		fm.CloseFiles()
+		return
+	}
+
 	// Prepare return data
+	fileHashMap, hashMapInfo = newFileHashMap(name, fm, o)
+	fileHashMap.hashAlgorithm = hashAlgorithm
+	fileHashMap.readOnly = readOnly
+
+	if _, svErr := fm.GetSystemValue(checksumSystemValueID); svErr == nil {
+		fileHashMap.checksums = true
+	}
+
+	if _, svErr := fm.GetSystemValue(timestampsSystemValueID); svErr == nil {
+		fileHashMap.timestamps = true
+	}
+
+	if _, svErr := fm.GetSystemValue(varKeysSystemValueID); svErr == nil {
+		err = fileHashMap.enableVariableKeys()
+		if err != nil {
+			fm.CloseFiles()
+			fileHashMap = nil
+			err = fmt.Errorf("error while opening key file: %s", err)
+			return
+		}
+	}
+
+	err = fileHashMap.openBloomFilter()
+	if err != nil {
+		fileHashMap.CloseFiles()
+		fileHashMap = nil
+		err = fmt.Errorf("error while opening Bloom filter: %s", err)
+		return
+	}
+	fileHashMap.lock = lock
+
+	return
+}
+
+// openFileManagement - Opens existing hash map files using the file management implementing the given collision
+// resolution technique
+func openFileManagement(name string, crtType int, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (fm FileManagement, err error) {
+	switch crtType {
+	case crt.SeparateChaining, crt.Hybrid, crt.LinearHashing:
+		fm, err = separatechaining.NewSCFilesFromExistingFiles(name, hashAlgorithm, fileSystem)
+	case crt.RobinHood:
+		fm, err = robinhood.NewRHFilesFromExistingFiles(name, hashAlgorithm, fileSystem)
+	case crt.CuckooHashing:
+		fm, err = cuckoo.NewCHFilesFromExistingFiles(name, hashAlgorithm, fileSystem)
+	case crt.Hopscotch:
+		fm, err = hopscotch.NewHSFilesFromExistingFiles(name, hashAlgorithm, fileSystem)
+	default:
+		if factory, ok := registeredCRT(crtType); ok {
+			fm, err = factory.Open(name, hashAlgorithm, fileSystem)
+			return
+		}
+		fm, err = openaddressing.NewOAFilesFromExistingFiles(name, hashAlgorithm, fileSystem)
+	}
+
+	return
+}
+
+// estimateFileManagement - Returns the storage parameters that new files of the CRT in crtConf would get, without
+// creating any files
+func estimateFileManagement(crtConf model.CRTConf) (sp model.StorageParameters, err error) {
+	switch crtConf.CollisionResolutionTechnique {
+	case crt.SeparateChaining, crt.Hybrid, crt.LinearHashing:
+		sp, err = separatechaining.EstimateSCFiles(crtConf)
+	case crt.RobinHood:
+		sp, err = robinhood.EstimateRHFiles(crtConf)
+	case crt.CuckooHashing:
+		sp, err = cuckoo.EstimateCHFiles(crtConf)
+	case crt.Hopscotch:
+		sp, err = hopscotch.EstimateHSFiles(crtConf)
+	default:
+		if factory, ok := registeredCRT(crtConf.CollisionResolutionTechnique); ok {
+			sp, err = factory.Estimate(crtConf)
+			return
+		}
+		sp, err = openaddressing.EstimateOAFiles(crtConf)
+	}
+
+	return
+}
+
+// newFileHashMap - Returns a pointer to a FileHashMap struct wrapping the given file management, and reaching its
+// files with the settings of o, together with a HashMapInfo struct describing it.
+func newFileHashMap(name string, fm FileManagement, o options) (fileHashMap *FileHashMap, hashMapInfo HashMapInfo) {
 	fileHashMap = &FileHashMap{
 		fileManagement: fm,
 		name:           name,
-		CloseFiles:     func() { fm.CloseFiles() },
-		RemoveFiles: func() error {
-			fm.CloseFiles()
-			return fm.RemoveFiles()
-		},
+		opStats:        newOpCounters(),
+		options:        o.settings(),
+	}
+	closeFiles := func() {
+		fileHashMap.stopPeriodicSync()
+		fileHashMap.stopWatchdog()
+		_ = fileHashMap.flushAccessCounts()
+		if fileHashMap.groupCommit != nil {
+			_ = fileHashMap.commitSeq()
+		}
+		if fileHashMap.opLog != nil {
+			fileHashMap.opLog.CloseFiles()
+		}
+		if fileHashMap.wal != nil {
+			fileHashMap.wal.Close()
+		}
+		if fileHashMap.keyFile != nil {
+			fileHashMap.keyFile.Close()
+		}
+		_ = fileHashMap.saveBloomFilter()
+		_ = fileHashMap.saveClosedAt()
+		fileHashMap.fileManagement.CloseFiles()
+		if fileHashMap.lock != nil {
+			_ = fileHashMap.lock.Unlock()
+			fileHashMap.lock = nil
+		}
+	}
+	fileHashMap.CloseFiles = func() {
+		fileHashMap.mu.Lock()
+		defer fileHashMap.mu.Unlock()
+
+		closeFiles()
+	}
+	fileHashMap.RemoveFiles = func() error {
+		fileHashMap.mu.Lock()
+		defer fileHashMap.mu.Unlock()
+
+		closeFiles()
+		// A hash map held in memory has no files to remove, and its empty name must not be taken for a file name
+		if name == "" {
+			return nil
+		}
+		if fileHashMap.opLog != nil {
+			if err := fileHashMap.opLog.fileManagement.RemoveFiles(); err != nil {
+				return err
+			}
+			if err := removeLockFile(fileHashMap.opLog.name, fileHashMap.options); err != nil {
+				return err
+			}
+		}
+		if fileHashMap.wal != nil {
+			if err := fileHashMap.fileSystem().Remove(storage.GetWALFileName(name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error while removing WAL file: %s", err)
+			}
+		}
+		if fileHashMap.keyFile != nil {
+			if err := fileHashMap.fileSystem().Remove(storage.GetKeyFileName(name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error while removing key file: %s", err)
+			}
+		}
+		if fileHashMap.bloom != nil {
+			if err := fileHashMap.fileSystem().Remove(storage.GetBloomFileName(name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error while removing Bloom filter file: %s", err)
+			}
+		}
+		if err := fileHashMap.fileManagement.RemoveFiles(); err != nil {
+			return err
+		}
+		return removeLockFile(name, fileHashMap.options)
+	}
+
+	if _, err := fm.GetSystemValue(ttlSystemValueID); err == nil {
+		fileHashMap.enableTTL()
 	}
 
 	sp := fm.GetStorageParameters()
@@ -232,8 +673,26 @@ func NewFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm) (
 //   - PrependKeyExtension whether to prepend the extra space or append it
 //   - ValueExtension is number of bytes to extend the value with
 //   - PrependValueExtension whether to prepend the extra space or append it
+//   - KeyTruncation is number of bytes to shrink the key with, it can not be combined with KeyExtension
+//   - TruncateKeyFromStart whether to remove the bytes from the start of the key or from the end of it
+//   - ValueTruncation is number of bytes to shrink the value with, it can not be combined with ValueExtension
+//   - TruncateValueFromStart whether to remove the bytes from the start of the value or from the end of it
+//   - AllowDataLoss whether truncation may remove bytes that are not zero, otherwise the reorganization stops with an error
 //   - NewHashAlgorithm is the algorithm to use
 //   - OldHashAlgorithm is the algorithm that was used in the original file hash map
+//   - AccessHints whether to drop the pages of the original files from the page cache after reading them, which are always advised for sequential access while read (see EnableAccessHints)
+//   - VerifySamples is the number of migrated records to look up in the new files after reorganization, zero skips verification
+//   - WatchdogThreshold is the duration after which a bucket still being reorganized is reported (see EnableWatchdog), zero turns it off
+//   - WatchdogCallback is the function to report to, nil logs the event using the standard log package
+//   - Progress is called after each bucket of the original files has been reorganized, nil turns it off
+//   - CheckpointInterval is the number of buckets between checkpoints saved in the new files, zero means 1000
+//   - Readers is the number of goroutines reading buckets of the original files in parallel, zero or one reads them one at a time
+//   - TargetName is the name of the new files (including path), empty means the original name with -reorg inserted
+//   - ReplaceInPlace whether to swap the new files into the name of the original files once the reorganization has completed and verified
+//   - KeepBackup whether to keep the original files with a -original inserted in the name(s) when replacing in place
+//   - Filter is called with the key and value of each record in the original files, records it returns false for are dropped, nil keeps all
+//   - TransformKey rewrites each kept key after it has been extended or truncated, nil leaves keys as they are
+//   - TransformValue rewrites each kept value after it has been extended or truncated, it gets the original key as well, nil leaves values as they are
 type ReorgConf struct {
 	CollisionResolutionTechnique int
 	NumberOfBucketsNeeded        int
@@ -242,8 +701,36 @@ type ReorgConf struct {
 	PrependKeyExtension          bool
 	ValueExtension               int
 	PrependValueExtension        bool
+	KeyTruncation                int
+	TruncateKeyFromStart         bool
+	ValueTruncation              int
+	TruncateValueFromStart       bool
+	AllowDataLoss                bool
 	NewHashAlgorithm             hashfunc.HashAlgorithm
 	OldHashAlgorithm             hashfunc.HashAlgorithm
+	AccessHints                  bool
+	VerifySamples                int
+	WatchdogThreshold            time.Duration
+	WatchdogCallback             func(event WatchdogEvent)
+	Progress                     func(progress ReorgProgress)
+	CheckpointInterval           int
+	Readers                      int
+	TargetName                   string
+	ReplaceInPlace               bool
+	KeepBackup                   bool
+	Filter                       func(key, value []byte) bool
+	TransformKey                 func(key []byte) ([]byte, error)
+	TransformValue               func(key, value []byte) ([]byte, error)
+}
+
+// ReorgProgress - Tells how far a reorganization has come, see ReorgConf.Progress
+//   - BucketsProcessed is the number of buckets of the original files that have been reorganized
+//   - TotalBuckets is the number of buckets in the original files
+//   - RecordsMoved is the number of records that have been set in the new files, by this run if resumed
+type ReorgProgress struct {
+	BucketsProcessed int64
+	TotalBuckets     int64
+	RecordsMoved     int64
 }
 
 // ReorgFiles - Is used when existing hash map files needs to reflect new conditions as compared to when they were
@@ -251,142 +738,586 @@ type ReorgConf struct {
 // in overflow, or we need to store more data in each record, or perhaps a better hash algorithm has been found
 // for the particular set of data we are processing.
 //
-// The function will create new files with a -reorg inserted in the name(s). The old files will
-// not be deleted to prevent data loss due to mistakes.
+// The function will create new files with a -reorg inserted in the name(s), or else named by ReorgConf.TargetName,
+// which may put them in another directory e.g. on a faster or larger volume. The old files will
+// not be deleted to prevent data loss due to mistakes, unless asked to through ReorgConf.ReplaceInPlace (see below).
 //
 // The reorganization will happen only if there are detectable changes coming from the ReorgConf struct. If the original
 // file hash map was created with internal hashfunc.HashAlgorithm and an empty (fields are Go zero values) ReorgConf struct is supplied,
 // the function returns with no processing. But values higher than zero in any of CollisionResolutionTechnique, NumberOfBucketsNeeded, RecordsPerBucket,
-// KeyExtension or ValueExtension will result in processing. Also, if the existing hash file map was created with custom HashAlgorithm and
+// KeyExtension, ValueExtension, KeyTruncation or ValueTruncation will result in processing. Also, if the existing hash file map was created with custom HashAlgorithm and
 // HashAlgorithm is nil, processing will happen. A non nil HashAlgorithm will always result in processing
 // even if the existing file hash map happens to be created with the exact same.
 //
+// Keys and values can also be shrunk through ReorgConf.KeyTruncation and ReorgConf.ValueTruncation, e.g. when a file
+// hash map was created with overly generous lengths. Unless ReorgConf.AllowDataLoss is set, the reorganization stops
+// with an error if any record would lose bytes that are not zero, since truncated keys could otherwise collide.
+//
+// Records can be dropped or rewritten on the way through ReorgConf.Filter, ReorgConf.TransformKey and
+// ReorgConf.TransformValue, e.g. to migrate values to a new encoding. Filter sees the key and value as stored in the
+// original files, while the transforms see them extended or truncated and must return them in the length of the new files. Keys
+// transformed into the same key overwrite each other. With ReorgConf.Readers above one the callbacks are called
+// from several goroutines at once. Any of them being set results in processing.
+//
 // To force a reorganization even if there are no changes to apply through the ReorgConf struct, use the force flag in the
 // call to the function. This can be handy if a file hash map has been utilized with lots of records having ended up in overflow
 // and lots of records have been popped leaving records in overflow that could find available spots in the map file.
+//
+// If the original file hash map has a Bloom filter (see EnableBloomFilter), a new one sized the same way is built from
+// the records in the new files, which drops keys of records that were popped from the original files.
+//
+// If ReorgConf.VerifySamples is above zero, a random sample of that many migrated records is looked up through the new
+// files after reorganization, using the new hash algorithm, and the outcome is reported in toHashMapInfo.Verification.
+// This gives some assurance that e.g. a new custom hash algorithm covers the table correctly before traffic is switched
+// over to the new files. A success rate below 1 means that some records can not be found in the new files.
+//
+// Every ReorgConf.CheckpointInterval buckets the new files are synced and the number of buckets of the original files
+// reorganized so far is saved as a checkpoint in the header of the new map file. If the reorganization dies midway,
+// e.g. in a crash, calling ReorgFiles again with the same configuration resumes from the checkpoint instead of starting
+// over from bucket zero. New files with a checkpoint but created with other settings are overwritten.
+// With ReorgConf.ReplaceInPlace set, the new files are swapped into the name of the original files once the
+// reorganization has completed, so the file hash map can be opened by its original name right away. Since files are
+// renamed, ReorgConf.TargetName has to be on the same volume as the original files then. The original files
+// are first renamed with a -original inserted in the name(s) and then removed, unless ReorgConf.KeepBackup is set. If
+// ReorgConf.VerifySamples is above zero and any sample was not found, nothing is swapped and an error is returned.
 //   - name is the name of an existing file hash map (including correct path)
 //   - reorgConfig is an instance of the ReorgConf struct.
 //   - force set to true forces a reorganization regardless of what is changed from the ReorgConf struct
-func ReorgFiles(name string, reorgConf ReorgConf, force bool) (fromHashMapInfo, toHashMapInfo HashMapInfo, err error) {
-	newName := fmt.Sprintf("%s-reorg", name)
+//   - options is any optional settings, such as WithFileSystem, which apply to both the original and the new files
+func ReorgFiles(name string, reorgConf ReorgConf, force bool, options ...Option) (fromHashMapInfo, toHashMapInfo HashMapInfo, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	fromHashMapInfo, toHashMapInfo, err = reorgFiles(context.Background(), name, reorgConf, force, o)
+
+	return
+}
+
+// reorgFiles - Is the implementation of ReorgFiles and ReorgFilesCtx, which stops between buckets once ctx is done
+func reorgFiles(ctx context.Context, name string, reorgConf ReorgConf, force bool, o options) (fromHashMapInfo, toHashMapInfo HashMapInfo, err error) {
+	// An empty name is that of a hash map held in memory, which has no files to reorganize, replace or write next to
+	if name == "" {
+		err = fmt.Errorf("ReorgFiles is not supported for a hash map held in memory")
+		return
+	}
+
+	newName := reorgConf.TargetName
+	if newName == "" {
+		newName = fmt.Sprintf("%s-reorg", name)
+	}
+
+	// Registered first, so it runs after both file hash maps have been closed by the deferred calls below
+	var reorganized bool
+	defer func() {
+		if err != nil || !reorganized || !reorgConf.ReplaceInPlace {
+			return
+		}
+		if v := toHashMapInfo.Verification; v != nil && v.SuccessRate < 1 {
+			err = fmt.Errorf("reorganized files not swapped into place since only %d of %d verification samples were found", v.Matched, v.Samples)
+			return
+		}
+		err = replaceWithReorged(name, newName, reorgConf.KeepBackup, o)
+	}()
 
 	var fromFhm, toFhm *FileHashMap
 
 	// Get data from existing hash map files (and by that also checking that they exist)
 	// Open existing (we won't use get/set/pop so whatever bucket algorithm is used in the original files is not important)
-	fromFhm, _, err = NewFromExistingFiles(name, nil)
+	// for reading only, so the original files are left untouched also when closed
+	fromFhm, _, err = openExistingFiles(name, nil, true, o)
 	if err != nil {
 		return
 	}
 	fromFhm.CloseFiles()
 
 	// Sort out new settings and also make sure there are any changes at all (unless force flag has already overridden that)
-	hasChanges := force
-	sp := fromFhm.fileManagement.GetStorageParameters()
-	var numberOfBucketsNeeded, recordsPerBucket, keyLength, valueLength, crtType int
-	var bucketAlgorithm hashfunc.HashAlgorithm
+	settings, hasChanges, err := newReorgSettings(fromFhm, reorgConf)
+	if err != nil {
+		return
+	}
+	hasChanges = hasChanges || force
+	crtType, numberOfBucketsNeeded, recordsPerBucket := settings.crtType, settings.numberOfBucketsNeeded, settings.recordsPerBucket
+	keyLength, valueLength, bucketAlgorithm := settings.keyLength, settings.valueLength, settings.bucketAlgorithm
+	if !hasChanges {
+		return
+	}
+
+	// Open existing (we won't use get/set/pop so whatever bucket algorithm is used in the original files is not important)
+	fromFhm, fromHashMapInfo, err = openExistingFiles(name, reorgConf.OldHashAlgorithm, true, o)
+	if err != nil {
+		return
+	}
+	defer fromFhm.CloseFiles()
+
+	// Resume into new files left by an earlier reorganization that did not complete, or else create new files
+	toFhm, toHashMapInfo, nextBucketNo := resumeReorg(newName, fromFhm, crtType, numberOfBucketsNeeded, recordsPerBucket, keyLength, valueLength, bucketAlgorithm)
+	if toFhm == nil {
+		o.features = fromFhm.features()
+		toFhm, toHashMapInfo, err = createFileHashMap(newName, crtType, numberOfBucketsNeeded, recordsPerBucket, keyLength, valueLength, bucketAlgorithm, o)
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if toFhm != nil {
+			toFhm.CloseFiles()
+		}
+	}()
+
+	if nextBucketNo == 0 {
+		err = toFhm.saveReorgCheckpoint(0)
+		if err != nil {
+			return
+		}
+	}
+
+	if reorgConf.WatchdogThreshold > 0 {
+		err = fromFhm.EnableWatchdog(reorgConf.WatchdogThreshold, reorgConf.WatchdogCallback)
+		if err != nil {
+			return
+		}
+	}
+
+	fromFhm.accessHints = reorgConf.AccessHints
+	fromFhm.beginScan()
+	defer fromFhm.endScan()
+
+	samples, err := reorgRecords(ctx, fromFhm, toFhm, reorgConf, nextBucketNo, fromFhm.fileManagement.GetStorageParameters().NumberOfBucketsAvailable)
+	if err != nil {
+		// A cancelled reorganization leaves nothing behind but the untouched original files
+		// and the new files are closed by their removal only
+		if ctx.Err() != nil {
+			_ = toFhm.RemoveFiles()
+			toFhm = nil
+		}
+		return
+	}
+
+	// The Bloom filter is rebuilt from the new files, which also drops keys of records popped from the original files
+	if fromFhm.bloom != nil {
+		err = toFhm.enableBloomFilter(fromFhm.bloom.ExpectedKeys(), fromFhm.bloom.FalsePositiveRate())
+		if err != nil {
+			return
+		}
+	}
+
+	if reorgConf.VerifySamples > 0 {
+		toHashMapInfo.Verification = verifyReorg(toFhm, samples)
+	}
+	reorganized = true
+
+	return
+}
+
+// reorgSettings - Holds the settings of the new files of a reorganization, see newReorgSettings
+type reorgSettings struct {
+	crtType               int
+	numberOfBucketsNeeded int
+	recordsPerBucket      int
+	keyLength             int
+	valueLength           int
+	bucketAlgorithm       hashfunc.HashAlgorithm
+}
+
+// newReorgSettings - Sorts out the settings of the new files of a reorganization from the original file hash map and
+// reorgConf, and whether there are any changes at all
+//   - from is the original file hash map
+//   - reorgConf is the configuration of the reorganization
+//
+// It returns:
+//   - settings is the settings of the new files, with the value length being that of the user value
+//   - hasChanges is true if the settings or reorgConf would change anything
+//   - err is a standard error, if reorgConf is not valid for the original file hash map
+func newReorgSettings(from *FileHashMap, reorgConf ReorgConf) (settings reorgSettings, hasChanges bool, err error) {
+	sp := from.fileManagement.GetStorageParameters()
 	if sp.CollisionResolutionTechnique != reorgConf.CollisionResolutionTechnique && reorgConf.CollisionResolutionTechnique > 0 {
-		crtType = reorgConf.CollisionResolutionTechnique
+		settings.crtType = reorgConf.CollisionResolutionTechnique
 		hasChanges = true
 	} else {
-		crtType = sp.CollisionResolutionTechnique
+		settings.crtType = sp.CollisionResolutionTechnique
 	}
 	if int(sp.NumberOfBucketsNeeded) != reorgConf.NumberOfBucketsNeeded && reorgConf.NumberOfBucketsNeeded > 0 {
-		numberOfBucketsNeeded = reorgConf.NumberOfBucketsNeeded
+		settings.numberOfBucketsNeeded = reorgConf.NumberOfBucketsNeeded
 		hasChanges = true
 	} else {
-		numberOfBucketsNeeded = int(sp.NumberOfBucketsNeeded)
+		settings.numberOfBucketsNeeded = int(sp.NumberOfBucketsNeeded)
 	}
 	if int(sp.RecordsPerBucket) != reorgConf.RecordsPerBucket && reorgConf.NumberOfBucketsNeeded > 0 {
-		recordsPerBucket = reorgConf.RecordsPerBucket
+		settings.recordsPerBucket = reorgConf.RecordsPerBucket
 		hasChanges = true
 	} else {
-		recordsPerBucket = int(sp.RecordsPerBucket)
+		settings.recordsPerBucket = int(sp.RecordsPerBucket)
+	}
+	if reorgConf.KeyExtension > 0 && reorgConf.KeyTruncation > 0 || reorgConf.ValueExtension > 0 && reorgConf.ValueTruncation > 0 {
+		err = fmt.Errorf("extension and truncation can not be combined for the same key or value")
+		return
 	}
 	if reorgConf.KeyExtension > 0 {
-		keyLength = int(sp.KeyLength) + reorgConf.KeyExtension
+		settings.keyLength = int(sp.KeyLength) + reorgConf.KeyExtension
 		hasChanges = true
 	} else {
-		keyLength = int(sp.KeyLength)
+		settings.keyLength = int(sp.KeyLength)
+	}
+	if reorgConf.KeyTruncation > 0 {
+		if from.keyFile != nil {
+			err = fmt.Errorf("keys can not be truncated in a file hash map with variable length keys")
+			return
+		}
+		if reorgConf.KeyTruncation >= settings.keyLength {
+			err = fmt.Errorf("key truncation of %d leaves no key out of key length %d", reorgConf.KeyTruncation, settings.keyLength)
+			return
+		}
+		settings.keyLength -= reorgConf.KeyTruncation
+		hasChanges = true
 	}
+	settings.valueLength = from.userValueLength()
 	if reorgConf.ValueExtension > 0 {
-		valueLength = int(sp.ValueLength) + reorgConf.ValueExtension
+		settings.valueLength += reorgConf.ValueExtension
+		hasChanges = true
+	}
+	if reorgConf.ValueTruncation > 0 {
+		if reorgConf.ValueTruncation >= settings.valueLength {
+			err = fmt.Errorf("value truncation of %d leaves no value out of value length %d", reorgConf.ValueTruncation, settings.valueLength)
+			return
+		}
+		settings.valueLength -= reorgConf.ValueTruncation
 		hasChanges = true
-	} else {
-		valueLength = int(sp.ValueLength)
 	}
 	if reorgConf.NewHashAlgorithm != nil || (reorgConf.NewHashAlgorithm == nil && !sp.InternalAlgorithm) {
-		bucketAlgorithm = reorgConf.NewHashAlgorithm
+		settings.bucketAlgorithm = reorgConf.NewHashAlgorithm
 		hasChanges = true
+	} else {
+		settings.bucketAlgorithm = internalHashAlgorithm(sp)
 	}
-	if !hasChanges {
-		return
+	if reorgConf.Filter != nil || reorgConf.TransformKey != nil || reorgConf.TransformValue != nil {
+		hasChanges = true
 	}
 
-	// Open existing (we won't use get/set/pop so whatever bucket algorithm is used in the original files is not important)
-	fromFhm, fromHashMapInfo, err = NewFromExistingFiles(name, reorgConf.OldHashAlgorithm)
+	return
+}
+
+// reorgRecords - Reads bucket by bucket, record by record, transforms, and writes to new hash map files.
+// Expired records are left out, and the expiry time of other records is kept. A uniform random sample of
+// reorgConf.VerifySamples migrated records, as written to the new files, is returned. The error of ctx is returned if
+// it is done before all buckets have been read. Buckets before fromBucketNo are skipped, since they were reorganized by
+// an earlier run, and a checkpoint is saved in the new files every reorgConf.CheckpointInterval buckets. Records are
+// always set in the new files by the calling goroutine, while reorgConf.Readers above one reads buckets in parallel.
+func reorgRecords(ctx context.Context, from *FileHashMap, to *FileHashMap, reorgConf ReorgConf, fromBucketNo, fromNBuckets int64) (samples []Record, err error) {
+	var items []reorgItem
+	var migrated int64
+
+	// Checkpoints, also those of a parallel reorganization, are saved in the header of the new files
+	err = to.requireFiles("ReorgFiles")
 	if err != nil {
 		return
 	}
-	defer fromFhm.CloseFiles()
 
-	// Create new file hash map
-	toFhm, toHashMapInfo, err = NewFileHashMap(newName, crtType, numberOfBucketsNeeded, recordsPerBucket, keyLength, valueLength, bucketAlgorithm)
-	if err != nil {
-		return
+	getBucket := from.bucketScanner()
+	nextBucket := func(bucketNo int64) ([]reorgItem, error) {
+		return readReorgBucket(from, reorgConf, bucketNo, func(bucketNo int64) ([]model.Record, error) {
+			return from.readBucketLocked(getBucket, bucketNo)
+		})
+	}
+	if reorgConf.Readers > 1 {
+		var stop func()
+		nextBucket, stop = readReorgBucketsAhead(from, reorgConf, fromBucketNo, fromNBuckets)
+		defer stop()
 	}
-	defer toFhm.CloseFiles()
 
-	err = reorgRecords(fromFhm, toFhm, reorgConf, fromFhm.fileManagement.GetStorageParameters().NumberOfBucketsAvailable)
-	if err != nil {
-		return
+	checkpointInterval := int64(reorgConf.CheckpointInterval)
+	if checkpointInterval <= 0 {
+		checkpointInterval = defaultReorgCheckpointInterval
 	}
 
-	return
-}
+	for i := fromBucketNo; i < fromNBuckets; i++ {
+		err = ctx.Err()
+		if err != nil {
+			return
+		}
 
-// reorgRecords - Reads bucket by bucket, record by record, transforms, and writes to new hash map files
-func reorgRecords(from *FileHashMap, to *FileHashMap, reorgConf ReorgConf, fromNBuckets int64) (err error) {
-	var bucket model.Bucket
-	var record model.Record
-	var iter *overflow.Records
-	var key, value []byte
-	for i := int64(0); i < fromNBuckets; i++ {
-		bucket, iter, err = from.fileManagement.GetBucket(i)
+		items, err = nextBucket(i)
 		if err != nil {
 			return
 		}
 
-		// Record from map file
-		for _, r := range bucket.Records {
-			if r.State == model.RecordOccupied {
-				key = utils.ExtendByteSlice(r.Key, int64(reorgConf.KeyExtension), reorgConf.PrependKeyExtension)
-				value = utils.ExtendByteSlice(r.Value, int64(reorgConf.ValueExtension), reorgConf.PrependValueExtension)
-				err = to.Set(key, value)
+		for _, item := range items {
+			err = to.set(item.key, item.value, item.expiry)
+			if err != nil {
+				return
+			}
+			if from.timestamps {
+				err = to.setTimestamps(item.key, item.created, item.modified)
 				if err != nil {
 					return
 				}
 			}
 
-			// Record from overflow file
-			if iter != nil {
-				for iter.HasNext() {
-					record, err = iter.Next()
-					if err != nil {
-						return
-					}
-					if record.State == model.RecordOccupied {
-						key = utils.ExtendByteSlice(record.Key, int64(reorgConf.KeyExtension), reorgConf.PrependKeyExtension)
-						value = utils.ExtendByteSlice(record.Value, int64(reorgConf.ValueExtension), reorgConf.PrependValueExtension)
-						err = to.Set(key, value)
-						if err != nil {
-							return
-						}
-					}
-				}
+			// Reservoir sampling of migrated records
+			migrated++
+			if len(samples) < reorgConf.VerifySamples {
+				samples = append(samples, Record{Key: item.key, Value: item.value})
+			} else if j := rand.Int63n(migrated); j < int64(reorgConf.VerifySamples) {
+				samples[j] = Record{Key: item.key, Value: item.value}
+			}
+		}
+
+		if (i+1)%checkpointInterval == 0 {
+			err = to.saveReorgCheckpoint(i + 1)
+			if err != nil {
+				return
+			}
+		}
+
+		if reorgConf.Progress != nil {
+			reorgConf.Progress(ReorgProgress{BucketsProcessed: i + 1, TotalBuckets: fromNBuckets, RecordsMoved: migrated})
+		}
+	}
+
+	err = to.saveReorgCheckpoint(-1)
+
+	return
+}
+
+// reorgItem - Is a record read from the original files by ReorgFiles, transformed and ready to be set in the new files
+//   - key is the key, extended according to ReorgConf
+//   - value is the value, extended according to ReorgConf
+//   - expiry is the expiry time of the record, zero if none
+//   - created is the creation time of the record, if the original files store timestamps
+//   - modified is the modification time of the record, if the original files store timestamps
+type reorgItem struct {
+	key      []byte
+	value    []byte
+	expiry   int64
+	created  int64
+	modified int64
+}
+
+// readReorgBucket - Reads the occupied and unexpired records of a bucket, including its overflow, from the original
+// files using readRecords and transforms them according to reorgConf. Each bucket is a chunk watched by any watchdog on
+// from, see ReorgConf.WatchdogThreshold. It only reads from, so it can be called from several goroutines at the same
+// time, given that readRecords can, e.g. by holding the lock of from (see readBucket).
+func readReorgBucket(from *FileHashMap, reorgConf ReorgConf, bucketNo int64, readRecords func(bucketNo int64) ([]model.Record, error)) (items []reorgItem, err error) {
+	defer from.watch("ReorgFiles")()
+
+	var key []byte
+
+	reorgRecord := func(r model.Record) (err error) {
+		if r.State != model.RecordOccupied || from.hasExpired(r) {
+			return
+		}
+
+		var item reorgItem
+		if from.ttl != nil {
+			item.expiry = storedExpiry(r.Value)
+		}
+		if from.timestamps {
+			item.created, item.modified = from.storedTimestamps(r.Value)
+		}
+
+		key, err = from.userKey(r)
+		if err != nil {
+			return
+		}
+
+		value := from.fromStoredValue(r.Value)
+		if reorgConf.Filter != nil && !reorgConf.Filter(key, value) {
+			return
+		}
+
+		item.key = utils.ExtendByteSlice(key, int64(reorgConf.KeyExtension), reorgConf.PrependKeyExtension)
+		item.value = utils.ExtendByteSlice(value, int64(reorgConf.ValueExtension), reorgConf.PrependValueExtension)
+
+		var keyLost, valueLost bool
+		if reorgConf.KeyTruncation > 0 {
+			item.key, keyLost = utils.TruncateByteSlice(item.key, int64(reorgConf.KeyTruncation), reorgConf.TruncateKeyFromStart)
+		}
+		if reorgConf.ValueTruncation > 0 {
+			item.value, valueLost = utils.TruncateByteSlice(item.value, int64(reorgConf.ValueTruncation), reorgConf.TruncateValueFromStart)
+		}
+		if (keyLost || valueLost) && !reorgConf.AllowDataLoss {
+			err = fmt.Errorf("truncation would lose data of a record in bucket %d, set AllowDataLoss to allow it", bucketNo)
+			return
+		}
+
+		if reorgConf.TransformValue != nil {
+			item.value, err = reorgConf.TransformValue(key, item.value)
+			if err != nil {
+				err = fmt.Errorf("error while transforming value in bucket %d: %s", bucketNo, err)
+				return
+			}
+		}
+		if reorgConf.TransformKey != nil {
+			item.key, err = reorgConf.TransformKey(item.key)
+			if err != nil {
+				err = fmt.Errorf("error while transforming key in bucket %d: %s", bucketNo, err)
+				return
+			}
+		}
+
+		items = append(items, item)
+
+		return
+	}
+
+	// Records from map file followed by records from overflow file
+	records, err := readRecords(bucketNo)
+	if err != nil {
+		return
+	}
+
+	for _, r := range records {
+		err = reorgRecord(r)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// readReorgBucketsAhead - Starts reorgConf.Readers goroutines reading buckets fromBucketNo up to nBuckets of the
+// original files in parallel, ahead of the caller. Buckets are handed out in bucket order, so the records are still
+// written to the new files in the same order as when reading sequentially, and checkpoints and progress mean the same.
+// Each bucket is read while holding the lock of from, since caches and buffers of the file management are not safe for
+// concurrent use, while records are transformed in parallel.
+//
+// It returns:
+//   - next is to be called once for each bucket in order, with the bucket number, and returns its records
+//   - stop stops the readers and waits for them, it must be called before the original files are closed
+func readReorgBucketsAhead(from *FileHashMap, reorgConf ReorgConf, fromBucketNo, nBuckets int64) (next func(bucketNo int64) ([]reorgItem, error), stop func()) {
+	type result struct {
+		items []reorgItem
+		err   error
+	}
+	type job struct {
+		bucketNo int64
+		result   chan result
+	}
+
+	readers := reorgConf.Readers
+	jobs := make(chan job)
+	results := make(chan chan result, 2*readers)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// Dispatcher handing out buckets in order and queueing where their results will arrive
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(results)
+
+		for bucketNo := fromBucketNo; bucketNo < nBuckets; bucketNo++ {
+			r := make(chan result, 1)
+			select {
+			case jobs <- job{bucketNo: bucketNo, result: r}:
+			case <-done:
+				return
+			}
+			select {
+			case results <- r:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for n := 0; n < readers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				items, err := readReorgBucket(from, reorgConf, j.bucketNo, func(bucketNo int64) ([]model.Record, error) {
+					return from.readBucket(from.fileManagement.GetBucket, bucketNo)
+				})
+				j.result <- result{items: items, err: err}
 			}
+		}()
+	}
+
+	next = func(bucketNo int64) (items []reorgItem, err error) {
+		r, ok := <-results
+		if !ok {
+			err = fmt.Errorf("no more buckets to read ahead at bucket %d", bucketNo)
+			return
+		}
+
+		res := <-r
+		items, err = res.items, res.err
+
+		return
+	}
+
+	stop = func() {
+		close(done)
+		wg.Wait()
+	}
+
+	return
+}
+
+// verifyReorg - Looks up each sampled record in the reorganized file hash map and reports how many were found with the
+// expected value, where any error from the lookup counts as a failure
+func verifyReorg(to *FileHashMap, samples []Record) (verification *ReorgVerification) {
+	verification = &ReorgVerification{Samples: len(samples), SuccessRate: 1}
+
+	for _, sample := range samples {
+		value, err := to.Get(sample.Key)
+		if err == nil && utils.IsEqual(sample.Value, value) {
+			verification.Matched++
 		}
 	}
 
+	if verification.Samples > 0 {
+		verification.SuccessRate = float64(verification.Matched) / float64(verification.Samples)
+	}
+
 	return
 }
+
+// opCounters - Holds the live operation counters backing OperationStats, they are safe for concurrent use.
+type opCounters struct {
+	gets      atomic.Int64
+	getMisses atomic.Int64
+	sets      atomic.Int64
+	pops      atomic.Int64
+	errors    atomic.Int64
+	evictions atomic.Int64
+	lastReset atomic.Int64
+	receiver  atomic.Pointer[metricsReceiver]
+}
+
+// newOpCounters - Returns a pointer to a new opCounters struct with last reset set to now
+func newOpCounters() *opCounters {
+	c := &opCounters{}
+	c.lastReset.Store(time.Now().UnixNano())
+
+	return c
+}
+
+// countError - Counts err as an error unless it is nil or of type crt.NoRecordFound
+func (C *opCounters) countError(err error) {
+	if err != nil && !errors.Is(err, crt.NoRecordFound{}) {
+		C.errors.Add(1)
+	}
+}
+
+// recordPosition - Identifies the physical position of a record in either the map file or the overflow file
+type recordPosition struct {
+	isOverflow    bool
+	recordAddress int64
+}
+
+// evictionHand - Is the position of the clock hand sweeping the map file for records to evict
+type evictionHand struct {
+	bucketNo    int64
+	recordIndex int
+}
+
+// accessCounter - Holds access counts registered by Get that are not yet written to file
+type accessCounter struct {
+	flushThreshold int
+	pending        map[recordPosition]int64
+}
@@ -3,12 +3,15 @@
 package filehashmap
 
 import (
+	"context"
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
 	"github.com/gostonefire/filehashmap/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -35,6 +38,10 @@ func TestNewFileHashMap(t *testing.T) {
 			{crtToName: "LinearProbing", toBuckets: 100000, toRpb: 2, keyLength: 16, valueLength: 10, toCrt: crt.LinearProbing},
 			{crtToName: "QuadraticProbing", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.QuadraticProbing},
 			{crtToName: "DoubleHashing", toBuckets: 100000, toRpb: 4, keyLength: 16, valueLength: 10, toCrt: crt.DoubleHashing},
+			{crtToName: "RobinHood", toBuckets: 100000, toRpb: 2, keyLength: 16, valueLength: 10, toCrt: crt.RobinHood},
+			{crtToName: "CuckooHashing", toBuckets: 100000, toRpb: 2, keyLength: 16, valueLength: 10, toCrt: crt.CuckooHashing},
+			{crtToName: "Hopscotch", toBuckets: 100000, toRpb: 2, keyLength: 16, valueLength: 10, toCrt: crt.Hopscotch},
+			{crtToName: "LinearHashing", toBuckets: 100000, toRpb: 2, keyLength: 16, valueLength: 10, toCrt: crt.LinearHashing},
 		}
 
 		for _, test := range tests {
@@ -79,7 +86,7 @@ func TestNewFileHashMap(t *testing.T) {
 		assert.Error(t, err)
 
 		// Execute
-		_, _, err = NewFileHashMap(testHashMap, 5, 10, 1, 16, 10, nil)
+		_, _, err = NewFileHashMap(testHashMap, 10, 10, 1, 16, 10, nil)
 
 		// Check
 		assert.Error(t, err)
@@ -108,6 +115,37 @@ func TestNewFileHashMap(t *testing.T) {
 		// Check
 		assert.Error(t, err)
 	})
+
+	t.Run("rounds buckets according to table size policy of custom hash algorithm", func(t *testing.T) {
+		// Prepare
+		hashAlgorithm := &powerOfTwoQuadraticProbing{NewQuadraticProbingHashAlgorithm(100)}
+
+		// Execute
+		fhm, info, err := NewFileHashMap(testHashMap, crt.QuadraticProbing, 100, 1, 4, 4, hashAlgorithm)
+
+		// Check
+		assert.NoError(t, err, "create file hash map")
+		assert.Equal(t, 128, info.NumberOfBucketsAvailable, "buckets rounded up to power of two")
+		fhm.CloseFiles()
+		fhm, info, err = NewFromExistingFiles(testHashMap, hashAlgorithm)
+		assert.NoError(t, err, "open file hash map")
+		assert.Equal(t, 128, info.NumberOfBucketsAvailable, "buckets when opened")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+// powerOfTwoQuadraticProbing - A QuadraticProbingHashAlgorithm that declares that it needs a table size that is a
+// power of two
+type powerOfTwoQuadraticProbing struct {
+	*QuadraticProbingHashAlgorithm
+}
+
+// TableSizePolicy - Returns hashfunc.TableSizePowerOfTwo
+func (P *powerOfTwoQuadraticProbing) TableSizePolicy() hashfunc.TableSizePolicy {
+	return hashfunc.TableSizePowerOfTwo
 }
 
 func TestNewFromExistingFiles(t *testing.T) {
@@ -118,6 +156,10 @@ func TestNewFromExistingFiles(t *testing.T) {
 			{crtToName: "LinearProbing", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.LinearProbing},
 			{crtToName: "QuadraticProbing", toBuckets: 100000, toRpb: 4, keyLength: 16, valueLength: 10, toCrt: crt.QuadraticProbing},
 			{crtToName: "DoubleHashing", toBuckets: 100000, toRpb: 5, keyLength: 16, valueLength: 10, toCrt: crt.QuadraticProbing},
+			{crtToName: "RobinHood", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.RobinHood},
+			{crtToName: "CuckooHashing", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.CuckooHashing},
+			{crtToName: "Hopscotch", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.Hopscotch},
+			{crtToName: "LinearHashing", toBuckets: 100000, toRpb: 3, keyLength: 16, valueLength: 10, toCrt: crt.LinearHashing},
 		}
 
 		for _, test := range tests {
@@ -182,6 +224,18 @@ func TestReorgFiles(t *testing.T) {
 			{crtFromName: "QuadraticProbing", crtToName: "LinearProbing", fromBuckets: 100, toBuckets: 100, fromRpb: 2, toRpb: 3, keyLength: 5, valueLength: 10, fromCrt: crt.QuadraticProbing, toCrt: crt.LinearProbing},
 			{crtFromName: "DoubleHashing", crtToName: "LinearProbing", fromBuckets: 100, toBuckets: 100, fromRpb: 5, toRpb: 4, keyLength: 5, valueLength: 10, fromCrt: crt.DoubleHashing, toCrt: crt.LinearProbing},
 			{crtFromName: "DoubleHashing", crtToName: "QuadraticProbing", fromBuckets: 100, toBuckets: 100, fromRpb: 2, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.DoubleHashing, toCrt: crt.QuadraticProbing},
+
+			{crtFromName: "RobinHood", crtToName: "RobinHood", fromBuckets: 100, toBuckets: 100, fromRpb: 3, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.RobinHood, toCrt: crt.RobinHood},
+			{crtFromName: "SeparateChaining", crtToName: "RobinHood", fromBuckets: 10, toBuckets: 100, fromRpb: 3, toRpb: 3, keyLength: 5, valueLength: 10, fromCrt: crt.SeparateChaining, toCrt: crt.RobinHood},
+			{crtFromName: "LinearProbing", crtToName: "RobinHood", fromBuckets: 100, toBuckets: 100, fromRpb: 2, toRpb: 3, keyLength: 5, valueLength: 10, fromCrt: crt.LinearProbing, toCrt: crt.RobinHood},
+			{crtFromName: "RobinHood", crtToName: "SeparateChaining", fromBuckets: 100, toBuckets: 10, fromRpb: 2, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.RobinHood, toCrt: crt.SeparateChaining},
+			{crtFromName: "SeparateChaining", crtToName: "CuckooHashing", fromBuckets: 10, toBuckets: 100, fromRpb: 3, toRpb: 4, keyLength: 5, valueLength: 10, fromCrt: crt.SeparateChaining, toCrt: crt.CuckooHashing},
+			{crtFromName: "SeparateChaining", crtToName: "Hopscotch", fromBuckets: 10, toBuckets: 100, fromRpb: 3, toRpb: 4, keyLength: 5, valueLength: 10, fromCrt: crt.SeparateChaining, toCrt: crt.Hopscotch},
+			{crtFromName: "CuckooHashing", crtToName: "SeparateChaining", fromBuckets: 100, toBuckets: 10, fromRpb: 4, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.CuckooHashing, toCrt: crt.SeparateChaining},
+			{crtFromName: "Hopscotch", crtToName: "SeparateChaining", fromBuckets: 100, toBuckets: 10, fromRpb: 4, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.Hopscotch, toCrt: crt.SeparateChaining},
+			{crtFromName: "SeparateChaining", crtToName: "LinearHashing", fromBuckets: 10, toBuckets: 16, fromRpb: 3, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.SeparateChaining, toCrt: crt.LinearHashing},
+			{crtFromName: "LinearHashing", crtToName: "LinearProbing", fromBuckets: 16, toBuckets: 100, fromRpb: 2, toRpb: 2, keyLength: 5, valueLength: 10, fromCrt: crt.LinearHashing, toCrt: crt.LinearProbing},
+			{crtFromName: "RobinHood", crtToName: "DoubleHashing", fromBuckets: 100, toBuckets: 100, fromRpb: 4, toRpb: 4, keyLength: 5, valueLength: 10, fromCrt: crt.RobinHood, toCrt: crt.DoubleHashing},
 		}
 
 		for _, test := range tests {
@@ -281,3 +335,639 @@ func TestReorgFiles(t *testing.T) {
 		}
 	})
 }
+
+// unstableHashAlgorithm - Is a broken hash algorithm that addresses keys to random buckets
+type unstableHashAlgorithm struct {
+	*LinearProbingHashAlgorithm
+}
+
+func (U unstableHashAlgorithm) HashFunc1(key []byte) int64 {
+	return rand.Int63n(U.GetTableSize())
+}
+
+func TestReorgFilesVerification(t *testing.T) {
+	tests := []struct {
+		name          string
+		hashAlgorithm hashfunc.HashAlgorithm
+		allMatched    bool
+	}{
+		{name: "internal to external", hashAlgorithm: NewLinearProbingHashAlgorithm(100), allMatched: true},
+		{name: "internal to broken external", hashAlgorithm: unstableHashAlgorithm{NewLinearProbingHashAlgorithm(100)}, allMatched: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("verifies sampled records for %s", test.name), func(t *testing.T) {
+			// Prepare
+			newName := fmt.Sprintf("%s-reorg", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 5, 10, nil)
+			assert.NoError(t, err, "create file hash map")
+
+			for i := 0; i < 100; i++ {
+				key := make([]byte, 5)
+				rand.Read(key)
+				err = fhm.Set(key, make([]byte, 10))
+				assert.NoError(t, err, "set key/value in file hash map")
+			}
+			fhm.CloseFiles()
+
+			reorgConf := ReorgConf{NewHashAlgorithm: test.hashAlgorithm, VerifySamples: 50}
+
+			// Execute
+			_, toInfo, err := ReorgFiles(testHashMap, reorgConf, false)
+
+			// Check
+			assert.NoError(t, err, "run reorg files")
+			assert.NotNil(t, toInfo.Verification, "verification made")
+			assert.Equal(t, 50, toInfo.Verification.Samples, "records sampled")
+			if test.allMatched {
+				assert.Equal(t, 50, toInfo.Verification.Matched, "all sampled records found")
+				assert.Equal(t, 1.0, toInfo.Verification.SuccessRate, "full success rate")
+			} else {
+				assert.Less(t, toInfo.Verification.SuccessRate, 1.0, "some sampled records not found")
+			}
+
+			// Clean up
+			fhm, _, err = NewFromExistingFiles(newName, test.hashAlgorithm)
+			assert.NoError(t, err, "open reorged files")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "new files can be removed")
+
+			err = os.Remove(fmt.Sprintf("%s-map.bin", testHashMap))
+			assert.NoError(t, err, "original map file can be removed")
+		})
+	}
+}
+
+func TestReorgFilesProgress(t *testing.T) {
+	t.Run("reports progress after each bucket", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		for i := 0; i < 50; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		totalBuckets := fhm.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+		fhm.CloseFiles()
+
+		var progress []ReorgProgress
+		reorgConf := ReorgConf{Progress: func(p ReorgProgress) { progress = append(progress, p) }}
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, reorgConf, true)
+
+		// Check
+		assert.NoError(t, err, "run reorg files")
+		assert.Len(t, progress, int(totalBuckets), "progress reported once per bucket")
+		for i, p := range progress {
+			assert.Equalf(t, int64(i+1), p.BucketsProcessed, "buckets processed in report #%d", i)
+			assert.Equalf(t, totalBuckets, p.TotalBuckets, "total buckets in report #%d", i)
+			if i > 0 {
+				assert.GreaterOrEqualf(t, p.RecordsMoved, progress[i-1].RecordsMoved, "records moved never decrease in report #%d", i)
+			}
+		}
+		assert.Equal(t, int64(50), progress[len(progress)-1].RecordsMoved, "all records moved")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(newName, nil)
+		assert.NoError(t, err, "open reorged files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+
+		err = os.Remove(fmt.Sprintf("%s-map.bin", testHashMap))
+		assert.NoError(t, err, "original map file can be removed")
+	})
+}
+
+func TestReorgFilesParallel(t *testing.T) {
+	tests := []struct {
+		crtName string
+		crt     int
+	}{
+		{crtName: "SeparateChaining", crt: crt.SeparateChaining},
+		{crtName: "LinearProbing", crt: crt.LinearProbing},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("reads buckets in parallel for %s", test.crtName), func(t *testing.T) {
+			// Prepare
+			newName := fmt.Sprintf("%s-reorg", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, test.crt, 100, 3, 16, 10, nil)
+			assert.NoError(t, err, "create file hash map")
+
+			records := make(map[string][]byte)
+			for i := 0; i < 200; i++ {
+				key := make([]byte, 16)
+				value := make([]byte, 10)
+				rand.Read(key)
+				rand.Read(value)
+				err = fhm.Set(key, value)
+				assert.NoErrorf(t, err, "sets record #%d", i)
+				records[string(key)] = value
+			}
+			fhm.CloseFiles()
+
+			var progress []ReorgProgress
+			reorgConf := ReorgConf{Readers: 4, Progress: func(p ReorgProgress) { progress = append(progress, p) }}
+
+			// Execute
+			_, _, err = ReorgFiles(testHashMap, reorgConf, true)
+
+			// Check
+			assert.NoError(t, err, "run reorg files")
+			for i, p := range progress {
+				assert.Equalf(t, int64(i+1), p.BucketsProcessed, "buckets processed in report #%d", i)
+			}
+			assert.Equal(t, int64(len(records)), progress[len(progress)-1].RecordsMoved, "all records moved")
+
+			fhm, _, err = NewFromExistingFiles(newName, nil)
+			assert.NoError(t, err, "open reorged files")
+			count, err := fhm.Count()
+			assert.NoError(t, err, "count records")
+			assert.Equal(t, int64(len(records)), count, "all records in reorged files")
+			for key, value := range records {
+				v, err := fhm.Get([]byte(key))
+				assert.NoError(t, err, "get record from reorged files")
+				assert.Equal(t, value, v, "value of record in reorged files")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "new files can be removed")
+
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open original files")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "original files can be removed")
+		})
+	}
+}
+
+func TestReorgRecordsParallel(t *testing.T) {
+	// Run with -race to detect readers sharing the bucket cache of the original files without the lock
+	t.Run("reads buckets in parallel with the bucket cache enabled", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		from, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		records := make(map[string][]byte)
+		for i := 0; i < 150; i++ {
+			key := make([]byte, 16)
+			value := make([]byte, 10)
+			rand.Read(key)
+			rand.Read(value)
+			err = from.Set(key, value)
+			assert.NoErrorf(t, err, "sets record #%d", i)
+			records[string(key)] = value
+		}
+		err = from.EnableBucketCache(1 << 12)
+		assert.NoError(t, err, "enables bucket cache")
+
+		to, _, err := NewFileHashMap(newName, crt.LinearProbing, 200, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map")
+
+		// Execute
+		nBuckets := from.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+		_, err = reorgRecords(context.Background(), from, to, ReorgConf{Readers: 8}, 0, nBuckets)
+
+		// Check
+		assert.NoError(t, err, "reorganizes records")
+		count, err := to.Count()
+		assert.NoError(t, err, "count records")
+		assert.Equal(t, int64(len(records)), count, "all records in new files")
+		for key, value := range records {
+			v, err := to.Get([]byte(key))
+			assert.NoError(t, err, "get record from new files")
+			assert.Equal(t, value, v, "value of record in new files")
+		}
+
+		// Clean up
+		err = to.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+		err = from.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
+
+func TestReorgFilesTransform(t *testing.T) {
+	t.Run("filters and transforms records", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		for i := 0; i < 50; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 2, 3, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{
+			ValueExtension: 1,
+			Filter:         func(key, value []byte) bool { return key[3]%2 == 0 },
+			TransformKey: func(key []byte) ([]byte, error) {
+				return []byte{1, key[1], key[2], key[3]}, nil
+			},
+			TransformValue: func(key, value []byte) ([]byte, error) {
+				return append(value[:4], key[3]), nil
+			},
+		}
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.NoError(t, err, "run reorg files")
+
+		fhm, _, err = NewFromExistingFiles(newName, nil)
+		assert.NoError(t, err, "open reorged files")
+		count, err := fhm.Count()
+		assert.NoError(t, err, "count records")
+		assert.Equal(t, int64(25), count, "filtered records dropped")
+		for i := 0; i < 50; i += 2 {
+			v, err := fhm.Get([]byte{1, 0, 0, byte(i)})
+			assert.NoErrorf(t, err, "get transformed record #%d", i)
+			assert.Equalf(t, []byte{1, 2, 3, byte(i), byte(i)}, v, "transformed value of record #%d", i)
+		}
+		_, err = fhm.Get([]byte{0, 0, 0, 0})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "original key not in reorged files")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("stops on transform error", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte{0, 0, 0, 1}, []byte{1, 2, 3, 4})
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{
+			TransformValue: func(key, value []byte) ([]byte, error) {
+				return nil, fmt.Errorf("cannot migrate")
+			},
+		}
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.ErrorContains(t, err, "cannot migrate", "transform error returned")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(fmt.Sprintf("%s-reorg", testHashMap), nil)
+		if err == nil {
+			_ = fhm.RemoveFiles()
+		}
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
+
+func TestReorgFilesTruncation(t *testing.T) {
+	t.Run("shrinks keys and values", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 6, 8, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		for i := 0; i < 30; i++ {
+			err = fhm.Set([]byte{0, 0, 1, 2, 3, byte(i)}, []byte{1, 2, 3, byte(i), 0, 0, 0, 0})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{KeyTruncation: 2, TruncateKeyFromStart: true, ValueTruncation: 4}
+
+		// Execute
+		_, toInfo, err := ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.NoError(t, err, "run reorg files")
+
+		fhm, _, err = NewFromExistingFiles(newName, nil)
+		assert.NoError(t, err, "open reorged files")
+		sp := fhm.fileManagement.GetStorageParameters()
+		assert.Equal(t, int64(4), sp.KeyLength, "key length shrunk")
+		assert.Equal(t, 4, fhm.userValueLength(), "value length shrunk")
+		assert.Greater(t, toInfo.FileSize, 0, "new file size reported")
+		for i := 0; i < 30; i++ {
+			v, err := fhm.Get([]byte{1, 2, 3, byte(i)})
+			assert.NoErrorf(t, err, "get truncated record #%d", i)
+			assert.Equalf(t, []byte{1, 2, 3, byte(i)}, v, "truncated value of record #%d", i)
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("stops on data loss unless allowed", func(t *testing.T) {
+		// Prepare
+		newName := fmt.Sprintf("%s-reorg", testHashMap)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte{1, 2, 3, 4}, []byte{1, 2, 3, 4})
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{ValueTruncation: 1}
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.ErrorContains(t, err, "truncation would lose data", "data loss detected")
+
+		// Execute
+		reorgConf.AllowDataLoss = true
+		_, _, err = ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.NoError(t, err, "run reorg files allowing data loss")
+		fhm, _, err = NewFromExistingFiles(newName, nil)
+		assert.NoError(t, err, "open reorged files")
+		v, err := fhm.Get([]byte{1, 2, 3, 4})
+		assert.NoError(t, err, "get truncated record")
+		assert.Equal(t, []byte{1, 2, 3}, v, "value truncated")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+
+	t.Run("rejects invalid truncation", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, errCombined := ReorgFiles(testHashMap, ReorgConf{KeyExtension: 1, KeyTruncation: 1}, false)
+		_, _, errTooMuch := ReorgFiles(testHashMap, ReorgConf{ValueTruncation: 4}, false)
+
+		// Check
+		assert.Error(t, errCombined, "extension and truncation combined")
+		assert.Error(t, errTooMuch, "truncation leaves no value")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
+
+func TestReorgFilesReplaceInPlace(t *testing.T) {
+	tests := []struct {
+		crtName    string
+		crt        int
+		keepBackup bool
+	}{
+		{crtName: "SeparateChaining", crt: crt.SeparateChaining, keepBackup: true},
+		{crtName: "LinearProbing", crt: crt.LinearProbing, keepBackup: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("replaces files in place for %s keeping backup %t", test.crtName, test.keepBackup), func(t *testing.T) {
+			// Prepare
+			backupName := fmt.Sprintf("%s-original", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, test.crt, 10, 2, 16, 10, nil)
+			assert.NoError(t, err, "create file hash map")
+
+			records := make(map[string][]byte)
+			for i := 0; i < 20; i++ {
+				key := make([]byte, 16)
+				value := make([]byte, 10)
+				rand.Read(key)
+				rand.Read(value)
+				err = fhm.Set(key, value)
+				assert.NoErrorf(t, err, "sets record #%d", i)
+				records[string(key)] = value
+			}
+			fhm.CloseFiles()
+
+			reorgConf := ReorgConf{NumberOfBucketsNeeded: 100, VerifySamples: 10, ReplaceInPlace: true, KeepBackup: test.keepBackup}
+
+			// Execute
+			_, toInfo, err := ReorgFiles(testHashMap, reorgConf, false)
+
+			// Check
+			assert.NoError(t, err, "run reorg files")
+
+			_, err = os.Stat(fmt.Sprintf("%s-reorg-map.bin", testHashMap))
+			assert.True(t, os.IsNotExist(err), "reorg map file renamed")
+			_, err = os.Stat(fmt.Sprintf("%s-map.bin", backupName))
+			assert.Equal(t, test.keepBackup, err == nil, "backup of original map file kept as requested")
+
+			fhm, info, err := NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open replaced files")
+			assert.Equal(t, toInfo.NumberOfBucketsAvailable, info.NumberOfBucketsAvailable, "original name holds reorganized files")
+			for key, value := range records {
+				v, err := fhm.Get([]byte(key))
+				assert.NoError(t, err, "get record from replaced files")
+				assert.Equal(t, value, v, "value of record in replaced files")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "replaced files can be removed")
+
+			if test.keepBackup {
+				fhm, _, err = NewFromExistingFiles(backupName, nil)
+				assert.NoError(t, err, "open backup files")
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "backup files can be removed")
+			}
+		})
+	}
+}
+
+func TestReorgFilesTargetName(t *testing.T) {
+	t.Run("writes new files to target name", func(t *testing.T) {
+		// Prepare
+		targetDir := t.TempDir()
+		targetName := filepath.Join(targetDir, "rebuilt")
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		err = fhm.Set(key, []byte("0123456789"))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{NumberOfBucketsNeeded: 100, TargetName: targetName}
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, reorgConf, false)
+
+		// Check
+		assert.NoError(t, err, "run reorg files")
+
+		_, err = os.Stat(fmt.Sprintf("%s-reorg-map.bin", testHashMap))
+		assert.True(t, os.IsNotExist(err), "no files with default name")
+
+		fhm, info, err := NewFromExistingFiles(targetName, nil)
+		assert.NoError(t, err, "open files at target name")
+		assert.Equal(t, 100, info.NumberOfBucketsNeeded, "target holds reorganized files")
+		value, err := fhm.Get(key)
+		assert.NoError(t, err, "get record from target files")
+		assert.Equal(t, []byte("0123456789"), value, "value of record in target files")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "target files can be removed")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
+
+func TestReorgFilesOriginalUntouched(t *testing.T) {
+	t.Run("leaves the original map file untouched", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		err = fhm.Set(key, []byte("0123456789"))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		mapFile := fmt.Sprintf("%s-map.bin", testHashMap)
+		before, err := os.ReadFile(mapFile)
+		assert.NoError(t, err, "read map file")
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{NumberOfBucketsNeeded: 100}, false)
+
+		// Check
+		assert.NoError(t, err, "run reorg files")
+
+		after, err := os.ReadFile(mapFile)
+		assert.NoError(t, err, "read map file again")
+		assert.Equal(t, before, after, "map file unchanged, including the time it was last closed")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(fmt.Sprintf("%s-reorg", testHashMap), nil)
+		assert.NoError(t, err, "open new files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "new files can be removed")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
+
+func TestReorgFilesResume(t *testing.T) {
+	tests := []struct {
+		crtName string
+		crt     int
+	}{
+		{crtName: "SeparateChaining", crt: crt.SeparateChaining},
+		{crtName: "LinearProbing", crt: crt.LinearProbing},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("resumes from checkpoint for %s", test.crtName), func(t *testing.T) {
+			// Prepare
+			newName := fmt.Sprintf("%s-reorg", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, test.crt, 100, 2, 16, 10, nil)
+			assert.NoError(t, err, "create file hash map")
+
+			records := make(map[string][]byte)
+			for i := 0; i < 100; i++ {
+				key := make([]byte, 16)
+				value := make([]byte, 10)
+				rand.Read(key)
+				rand.Read(value)
+				err = fhm.Set(key, value)
+				assert.NoErrorf(t, err, "sets record #%d", i)
+				records[string(key)] = value
+			}
+			fhm.CloseFiles()
+
+			crash := func(p ReorgProgress) {
+				if p.BucketsProcessed == 55 {
+					panic("crash")
+				}
+			}
+			var resumedFrom, restartedFrom int64
+			reorgConf := ReorgConf{CheckpointInterval: 10, Progress: crash}
+
+			// Execute
+			assert.Panics(t, func() { _, _, _ = ReorgFiles(testHashMap, reorgConf, true) }, "reorganization dies midway")
+
+			reorgConf.Progress = func(p ReorgProgress) {
+				if resumedFrom == 0 {
+					resumedFrom = p.BucketsProcessed
+				}
+			}
+			_, _, errResumed := ReorgFiles(testHashMap, reorgConf, true)
+
+			reorgConf.Progress = func(p ReorgProgress) {
+				if restartedFrom == 0 {
+					restartedFrom = p.BucketsProcessed
+				}
+			}
+			_, _, errRestarted := ReorgFiles(testHashMap, reorgConf, true)
+
+			// Check
+			assert.NoError(t, errResumed, "resumed reorganization")
+			assert.Equal(t, int64(51), resumedFrom, "resumed after last checkpoint")
+			assert.NoError(t, errRestarted, "restarted reorganization")
+			assert.Equal(t, int64(1), restartedFrom, "completed reorganization is not resumed")
+
+			fhm, _, err = NewFromExistingFiles(newName, nil)
+			assert.NoError(t, err, "open reorged files")
+			count, err := fhm.Count()
+			assert.NoError(t, err, "count records")
+			assert.Equal(t, int64(len(records)), count, "all records in reorged files")
+			for key, value := range records {
+				v, err := fhm.Get([]byte(key))
+				assert.NoError(t, err, "get record from reorged files")
+				assert.Equal(t, value, v, "value of record in reorged files")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "new files can be removed")
+
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open original files")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "original files can be removed")
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"os"
+)
+
+// LockMode - Tells whether, and how, a file hash map is locked against use by other processes, see WithFileLocking
+type LockMode int
+
+const (
+	// LockNone - File hash maps are not locked, which is the default
+	LockNone LockMode = iota
+	// LockFailFast - Opening a file hash map that another process has locked fails with crt.FileLocked
+	LockFailFast
+	// LockWait - Opening a file hash map that another process has locked blocks until the lock is released
+	LockWait
+)
+
+// WithFileLocking - Returns an Option that turns on advisory locking of the file hash map between processes, using
+// flock on Unix and LockFileEx on Windows. Creating or opening the file hash map then takes an exclusive lock, so that
+// only one writer at a time can have it open, while NewFromExistingFilesReadOnly takes a shared lock that any number of
+// readers can hold at the same time, but not together with a writer. The lock is held on a lock file next to the map
+// file, named <name>-lock.bin, and is released when the files are closed. Locks are held per open file hash map, so
+// opening the same file hash map twice within a process is also excluded, but only opens given this option take locks.
+// It is turned off unless given, is kept by automatic growing, the operation log and the new files of ReorgFiles, and
+// requires files of the operating system's file system (see WithFileSystem).
+//   - mode is one of LockNone, LockFailFast or LockWait
+//
+// It returns:
+//   - option is the Option to give to NewFileHashMap, NewFromExistingFiles or any function taking options, which
+//     fails them with a standard error if mode is not a valid lock mode
+func WithFileLocking(mode LockMode) (option Option) {
+	option = optionFunc(func(o *options) (err error) {
+		if mode < LockNone || mode > LockWait {
+			err = fmt.Errorf("mode has to be one of LockNone, LockFailFast or LockWait")
+			return
+		}
+
+		o.lockMode = mode
+
+		return
+	})
+
+	return
+}
+
+// lockFiles - Takes a lock on the file hash map according to the lock mode given by WithFileLocking
+//   - name is the name of the file hash map
+//   - shared set to true takes a shared lock for reading only instead of an exclusive lock
+//   - o is the settings to reach the lock file with
+//
+// It returns:
+//   - lock is a pointer to the lock taken, or nil if file locking is turned off
+//   - err is crt.FileLocked if the lock is held by another process and the lock mode is LockFailFast
+func lockFiles(name string, shared bool, o options) (lock *storage.FileLock, err error) {
+	if o.lockMode == LockNone {
+		return
+	}
+
+	lock, err = storage.LockFile(o.fileSystem(), storage.GetLockFileName(name), shared, o.lockMode == LockWait)
+	if err == nil && lock == nil {
+		err = crt.FileLocked{Name: name}
+	}
+
+	return
+}
+
+// removeLockFile - Removes the lock file of the file hash map, if there is one
+func removeLockFile(name string, o options) (err error) {
+	err = o.fileSystem().Remove(storage.GetLockFileName(name))
+	if err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("error while removing lock file: %s", err)
+		return
+	}
+
+	err = nil
+
+	return
+}
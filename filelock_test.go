@@ -0,0 +1,182 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithFileLocking(t *testing.T) {
+	testCases := []TestCaseOperations{
+		{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: nil},
+		{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run("excludes a second writer and readers for "+tc.crtName, func(t *testing.T) {
+			// Prepare
+			locking := WithFileLocking(LockFailFast)
+			fhm, _, err := NewFileHashMap(testHashMap, tc.crt, tc.buckets, tc.rpb, tc.keyLength, tc.valueLength, tc.hFunc, locking)
+			assert.NoError(t, err, "create new file hash map struct")
+
+			// Execute
+			_, _, errWriter := NewFromExistingFiles(testHashMap, nil, locking)
+			_, _, errReader := NewFromExistingFilesReadOnly(testHashMap, nil, locking)
+			_, _, errCreate := NewFileHashMap(testHashMap, tc.crt, tc.buckets, tc.rpb, tc.keyLength, tc.valueLength, tc.hFunc, locking)
+			fhm.CloseFiles()
+			fhm, _, errAfterClose := NewFromExistingFiles(testHashMap, nil, locking)
+
+			// Check
+			assert.ErrorIs(t, errWriter, crt.FileLocked{}, "second writer is locked out")
+			assert.ErrorIs(t, errReader, crt.FileLocked{}, "reader is locked out")
+			assert.ErrorIs(t, errCreate, crt.FileLocked{}, "files are not recreated while locked")
+			assert.NoError(t, errAfterClose, "opens after lock is released")
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+			_, err = os.Stat(storage.GetLockFileName(testHashMap))
+			assert.True(t, os.IsNotExist(err), "lock file removed")
+		})
+	}
+
+	t.Run("shares read-only opens and excludes writers", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		key := make([]byte, 16)
+		err = fhm.Set(key, []byte("0123456789"))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+		locking := WithFileLocking(LockFailFast)
+
+		// Execute
+		first, _, errFirst := NewFromExistingFilesReadOnly(testHashMap, nil, locking)
+		second, _, errSecond := NewFromExistingFilesReadOnly(testHashMap, nil, locking)
+		_, _, errWriter := NewFromExistingFiles(testHashMap, nil, locking)
+
+		// Check
+		assert.NoError(t, errFirst, "opens first reader")
+		assert.NoError(t, errSecond, "opens second reader")
+		assert.ErrorIs(t, errWriter, crt.FileLocked{}, "writer is locked out")
+
+		// Clean up
+		first.CloseFiles()
+		second.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil, locking)
+		assert.NoError(t, err, "opens writer after readers closed")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("waits for the lock to be released", func(t *testing.T) {
+		// Prepare
+		locking := WithFileLocking(LockWait)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, locking)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		opened := make(chan error)
+		go func() {
+			second, _, err := NewFromExistingFiles(testHashMap, nil, locking)
+			if err == nil {
+				second.CloseFiles()
+			}
+			opened <- err
+		}()
+
+		var early bool
+		select {
+		case <-opened:
+			early = true
+		case <-time.After(50 * time.Millisecond):
+		}
+		fhm.CloseFiles()
+		errWaiting := <-opened
+
+		// Check
+		assert.False(t, early, "second open blocks while locked")
+		assert.NoError(t, errWaiting, "second open succeeds after release")
+
+		// Clean up
+		fhm, _, _ = NewFromExistingFiles(testHashMap, nil)
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("locks only opens given the option", func(t *testing.T) {
+		// Prepare
+		locking := WithFileLocking(LockFailFast)
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, locking)
+		assert.NoError(t, err, "create new file hash map struct")
+		other, _, err := NewFileHashMap(testHashMap+"-other", crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create other file hash map struct")
+
+		// Execute
+		_, statErr := os.Stat(storage.GetLockFileName(testHashMap + "-other"))
+		_, _, errLocked := NewFromExistingFiles(testHashMap, nil, locking)
+
+		// Check
+		assert.True(t, os.IsNotExist(statErr), "no lock file without the option")
+		assert.ErrorIs(t, errLocked, crt.FileLocked{}, "locked with the option")
+
+		// Clean up
+		err = other.RemoveFiles()
+		assert.NoError(t, err, "removes other files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("rejects invalid lock mode", func(t *testing.T) {
+		// Execute
+		_, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithFileLocking(LockMode(42)))
+
+		// Check
+		assert.Error(t, err, "invalid lock mode rejected")
+		_, statErr := os.Stat(storage.GetMapFileName(testHashMap))
+		assert.True(t, os.IsNotExist(statErr), "no files created")
+	})
+}
+
+func TestNewFromExistingFilesReadOnly(t *testing.T) {
+	t.Run("gets records but does not change files", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		key := make([]byte, 16)
+		err = fhm.Set(key, []byte("0123456789"))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		// Execute
+		ro, _, err := NewFromExistingFilesReadOnly(testHashMap, nil)
+		assert.NoError(t, err, "opens read-only")
+		value, errGet := ro.Get(key)
+		errSet := ro.Set(key, []byte("9876543210"))
+		_, errPop := ro.Pop(key)
+		errWAL := ro.EnableWAL()
+		errRemove := ro.RemoveFiles()
+
+		// Check
+		assert.NoError(t, errGet, "gets record")
+		assert.Equal(t, []byte("0123456789"), value, "reads value")
+		assert.Error(t, errSet, "set fails")
+		assert.Error(t, errPop, "pop fails")
+		assert.Error(t, errWAL, "enabling WAL fails")
+		assert.Error(t, errRemove, "removing files fails")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens existing file hash map")
+		value, err = fhm.Get(key)
+		assert.NoError(t, err, "gets record")
+		assert.Equal(t, []byte("0123456789"), value, "value is unchanged")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
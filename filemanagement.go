@@ -0,0 +1,253 @@
+package filehashmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+)
+
+// existenceChecker - Implemented by file management that can check whether a record exists cheaper than getting it
+type existenceChecker interface {
+	Exists(keyRecord model.Record) (exists bool, err error)
+}
+
+// batchGetter - Implemented by file management that can get many records cheaper than one at a time
+type batchGetter interface {
+	GetBatch(keyRecords []model.Record) (records []model.Record, err error)
+}
+
+// batchSetter - Implemented by file management that can set many records cheaper than one at a time
+type batchSetter interface {
+	SetBatch(records []model.Record) (err error)
+}
+
+// valueSetter - Implemented by file management that can set the value of a record in place at its address
+type valueSetter interface {
+	SetValue(record model.Record) (err error)
+}
+
+// getOrSetter - Implemented by file management that can look for an existing record while probing for where to add
+type getOrSetter interface {
+	GetOrSet(record model.Record) (existing model.Record, loaded bool, err error)
+}
+
+// recordCounter - Implemented by file management that keeps count of its records
+type recordCounter interface {
+	Counts() (occupied, deleted int64, err error)
+}
+
+// accessCountAdder - Implemented by file management that stores an access count with each record
+type accessCountAdder interface {
+	AddAccessCount(record model.Record, increment int64) (err error)
+}
+
+// mutationSequencer - Implemented by file management that persists the sequence number of the last mutation
+type mutationSequencer interface {
+	SetMutationSeq(seq int64) (err error)
+}
+
+// clearer - Implemented by file management that can remove all records without recreating its files
+type clearer interface {
+	Clear() (err error)
+}
+
+// probeInspector - Implemented by file management that can tell where keys are addressed to and how far they are
+// probed for
+type probeInspector interface {
+	HomeBucket(key []byte) (bucketNo int64)
+	ProbeLength(key []byte, bucketNo int64) (probeLength int64)
+}
+
+// recordExists - Returns whether a record with the key of keyRecord exists, using Exists of fm if implemented and Get
+// otherwise
+func recordExists(fm FileManagement, keyRecord model.Record) (exists bool, err error) {
+	if checker, ok := fm.(existenceChecker); ok {
+		exists, err = checker.Exists(keyRecord)
+		return
+	}
+
+	_, err = fm.Get(keyRecord)
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+		return
+	}
+	exists = err == nil
+
+	return
+}
+
+// getRecords - Returns the records with the keys of keyRecords in the same order, with records not found having State
+// set to model.RecordEmpty, using GetBatch of fm if implemented and Get for one record at a time otherwise
+func getRecords(fm FileManagement, keyRecords []model.Record) (records []model.Record, err error) {
+	if getter, ok := fm.(batchGetter); ok {
+		records, err = getter.GetBatch(keyRecords)
+		return
+	}
+
+	records = make([]model.Record, len(keyRecords))
+	for i, keyRecord := range keyRecords {
+		records[i], err = fm.Get(keyRecord)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			records[i], err = model.Record{Key: keyRecord.Key, State: model.RecordEmpty}, nil
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// setRecords - Sets records, using SetBatch of fm if implemented and Set for one record at a time otherwise
+func setRecords(fm FileManagement, records []model.Record) (err error) {
+	if setter, ok := fm.(batchSetter); ok {
+		err = setter.SetBatch(records)
+		return
+	}
+
+	for _, record := range records {
+		err = fm.Set(record)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// setRecordValue - Sets the value of a record previously read, using SetValue of fm if implemented and Set otherwise
+//   - record is the record to update, which must contain Key, RecordAddress and the new Value
+func setRecordValue(fm FileManagement, record model.Record) (err error) {
+	if setter, ok := fm.(valueSetter); ok {
+		err = setter.SetValue(record)
+		return
+	}
+
+	err = fm.Set(model.Record{Key: record.Key, Value: record.Value})
+
+	return
+}
+
+// getOrSetRecord - Returns the existing record with the same key as record if there is one, or else adds record, using
+// GetOrSet of fm if implemented and Get followed by Set otherwise
+func getOrSetRecord(fm FileManagement, record model.Record) (existing model.Record, loaded bool, err error) {
+	if setter, ok := fm.(getOrSetter); ok {
+		existing, loaded, err = setter.GetOrSet(record)
+		return
+	}
+
+	existing, err = fm.Get(model.Record{Key: record.Key})
+	if err == nil {
+		loaded = true
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		return
+	}
+
+	err = fm.Set(record)
+
+	return
+}
+
+// countRecords - Returns the number of occupied and deleted records, using Counts of fm if implemented and reading all
+// buckets and overflow chains otherwise
+func countRecords(fm FileManagement) (occupied, deleted int64, err error) {
+	if counter, ok := fm.(recordCounter); ok {
+		occupied, deleted, err = counter.Counts()
+		return
+	}
+
+	count := func(record model.Record) {
+		switch record.State {
+		case model.RecordOccupied:
+			occupied++
+		case model.RecordDeleted:
+			deleted++
+		}
+	}
+
+	sp := fm.GetStorageParameters()
+	for i := int64(0); i < sp.NumberOfBucketsAvailable; i++ {
+		bucket, iter, bucketErr := fm.GetBucket(i)
+		if bucketErr != nil {
+			err = fmt.Errorf("error while counting records: %s", bucketErr)
+			return
+		}
+		for _, record := range bucket.Records {
+			count(record)
+		}
+		for iter != nil && iter.HasNext() {
+			record, nextErr := iter.Next()
+			if nextErr != nil {
+				err = fmt.Errorf("error while counting records: %s", nextErr)
+				return
+			}
+			count(record)
+		}
+	}
+
+	return
+}
+
+// addAccessCount - Adds increment to the access counter of a record, if fm stores access counts
+func addAccessCount(fm FileManagement, record model.Record, increment int64) (err error) {
+	if adder, ok := fm.(accessCountAdder); ok {
+		err = adder.AddAccessCount(record, increment)
+	}
+
+	return
+}
+
+// setMutationSeq - Persists the sequence number of the last mutation, if fm persists it
+func setMutationSeq(fm FileManagement, seq int64) (err error) {
+	if sequencer, ok := fm.(mutationSequencer); ok {
+		err = sequencer.SetMutationSeq(seq)
+	}
+
+	return
+}
+
+// clearRecords - Removes all records using Clear of fm
+//
+// It returns:
+//   - err is a standard error, if fm doesn't implement Clear or something went wrong
+func clearRecords(fm FileManagement) (err error) {
+	c, ok := fm.(clearer)
+	if !ok {
+		err = fmt.Errorf("clear is not supported by the collision resolution technique")
+		return
+	}
+
+	err = c.Clear()
+
+	return
+}
+
+// homeBucketOf - Returns the bucket that key is addressed to, or -1 if fm doesn't tell
+func homeBucketOf(fm FileManagement, key []byte) (bucketNo int64) {
+	inspector, ok := fm.(probeInspector)
+	if !ok {
+		bucketNo = -1
+		return
+	}
+
+	bucketNo = inspector.HomeBucket(key)
+
+	return
+}
+
+// probeLengthOf - Returns the number of buckets that a lookup of key probes before reaching bucketNo, or -1 if bucketNo
+// is not within the probe sequence of key or fm doesn't tell
+func probeLengthOf(fm FileManagement, key []byte, bucketNo int64) (length int64) {
+	inspector, ok := fm.(probeInspector)
+	if !ok {
+		length = -1
+		return
+	}
+
+	length = inspector.ProbeLength(key, bucketNo)
+
+	return
+}
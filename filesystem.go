@@ -0,0 +1,17 @@
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// fileSystem - Returns the file system to reach the files of the file hash map through, see WithFileSystem, which
+// fails all attempts to change files if the file hash map was opened read-only (see NewFromExistingFilesReadOnly)
+func (F *FileHashMap) fileSystem() (fs vfs.FileSystem) {
+	fs = F.options.fileSystem()
+	if F.readOnly {
+		fs = storage.NewReadOnlyFileSystem(fs)
+	}
+
+	return
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+)
+
+// countingFileSystem - Wraps the operating system's file system and records which files are opened and removed
+type countingFileSystem struct {
+	vfs.OS
+	mu      sync.Mutex
+	opened  map[string]int
+	removed map[string]int
+	synced  int
+}
+
+func newCountingFileSystem() *countingFileSystem {
+	return &countingFileSystem{opened: make(map[string]int), removed: make(map[string]int)}
+}
+
+func (C *countingFileSystem) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	C.mu.Lock()
+	C.opened[name]++
+	C.mu.Unlock()
+	return C.OS.OpenFile(name, flag, perm)
+}
+
+func (C *countingFileSystem) Remove(name string) error {
+	C.mu.Lock()
+	C.removed[name]++
+	C.mu.Unlock()
+	return C.OS.Remove(name)
+}
+
+func (C *countingFileSystem) SyncDir(_ string) error {
+	C.mu.Lock()
+	C.synced++
+	C.mu.Unlock()
+	return nil
+}
+
+func TestWithFileSystem(t *testing.T) {
+	testCases := []TestCaseOperations{
+		{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: nil},
+		{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run("creates, opens and removes files through the file system for "+tc.crtName, func(t *testing.T) {
+			// Prepare
+			fs := newCountingFileSystem()
+
+			fhm, _, err := NewFileHashMap(testHashMap, tc.crt, tc.buckets, tc.rpb, tc.keyLength, tc.valueLength, tc.hFunc, WithFileSystem(fs))
+			assert.NoError(t, err, "create new file hash map struct")
+			err = fhm.EnableWAL()
+			assert.NoError(t, err, "enables WAL")
+
+			key := make([]byte, tc.keyLength)
+			key[0] = 1
+			err = fhm.Set(key, []byte("0123456789"))
+			assert.NoError(t, err, "sets record")
+			fhm.CloseFiles()
+
+			// Execute
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil, WithFileSystem(fs))
+			assert.NoError(t, err, "opens existing file hash map")
+			err = fhm.EnableWAL()
+			assert.NoError(t, err, "enables WAL")
+			value, err := fhm.Get(key)
+
+			// Check
+			assert.NoError(t, err, "gets record")
+			assert.Equal(t, []byte("0123456789"), value, "value is read back")
+			assert.NotZero(t, fs.opened[storage.GetMapFileName(testHashMap)], "map file opened through file system")
+			assert.NotZero(t, fs.opened[storage.GetWALFileName(testHashMap)], "WAL file opened through file system")
+			if tc.crt == crt.SeparateChaining {
+				assert.NotZero(t, fs.opened[storage.GetOvflFileName(testHashMap)], "overflow file opened through file system")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+			assert.Equal(t, 1, fs.removed[storage.GetMapFileName(testHashMap)], "map file removed through file system")
+			assert.Equal(t, 1, fs.removed[storage.GetWALFileName(testHashMap)], "WAL file removed through file system")
+		})
+	}
+
+	t.Run("syncs directories through a file system implementing DirSyncer", func(t *testing.T) {
+		// Prepare
+		fs := newCountingFileSystem()
+
+		// Execute
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithFileSystem(fs), WithStrictDirSync(true))
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Check
+		assert.Equal(t, 1, fs.synced, "directory synced through file system")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("keeps the file system when growing the files", func(t *testing.T) {
+		// Prepare
+		fs := newCountingFileSystem()
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 4, 1, 16, 10, nil, WithFileSystem(fs))
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableAutoGrow(0.5)
+		assert.NoError(t, err, "enables automatic growing")
+
+		// Execute
+		for i := 0; i < 8; i++ {
+			key := make([]byte, 16)
+			key[0] = byte(i + 1)
+			err = fhm.Set(key, []byte("0123456789"))
+			assert.NoError(t, err, "sets record")
+		}
+
+		// Check
+		assert.NotZero(t, fs.opened[storage.GetMapFileName(testHashMap+"-grow")], "grown files created through file system")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails to open with features", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = NewFromExistingFiles(testHashMap, nil, FeatureTTL)
+
+		// Check
+		assert.Error(t, err, "features can not be given on open")
+
+		// Clean up
+		_ = os.Remove(storage.GetMapFileName(testHashMap))
+	})
+}
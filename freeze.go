@@ -0,0 +1,346 @@
+package filehashmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/gostonefire/filehashmap/vfs"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+)
+
+// frozenMagic - Is written first in a frozen file, telling the format and its version
+var frozenMagic = [8]byte{'F', 'H', 'M', 'F', 'R', 'Z', 0, 1}
+
+// frozenHeaderLength - Is the length of the header of a frozen file, which is followed by the records, the bucket index
+// and the record entries
+const frozenHeaderLength = 40
+
+// frozenEntryLength - Is the length of a record entry in a frozen file, i.e. the hash of the key, the length of the
+// record and its offset in the file
+const frozenEntryLength = 16
+
+// frozenEntry - Is a record entry of a frozen file
+type frozenEntry struct {
+	hash   uint32
+	length uint32
+	offset int64
+}
+
+// offsetWriter - Is an io.Writer writing sequentially to a vfs.File from an offset
+type offsetWriter struct {
+	file   vfs.File
+	offset int64
+}
+
+// Write - Writes p at the current offset and moves the offset past it
+func (O *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = O.file.WriteAt(p, O.offset)
+	O.offset += int64(n)
+
+	return
+}
+
+// Freeze - Writes all records stored in the file hash map to a single immutable file in the style of a constant
+// database, which is opened for lookups with OpenFrozen. Records are packed back to back, without empty or deleted
+// records, and are found through an index with one bucket per record, so a lookup reads the index, on average one
+// record entry and the record. It suits data that is built once and served many times, e.g. geo lookups.
+// The file is written to a temporary file that replaces fileName when complete, and expiry times and timestamps are
+// not included.
+//   - fileName is the name of the frozen file (including correct path)
+//
+// It returns:
+//   - records is the number of records written
+//   - err is a standard error, if something went wrong, in which case fileName is left untouched
+func (F *FileHashMap) Freeze(fileName string) (records int64, err error) {
+	tmpName := fileName + ".tmp"
+	fs := F.options.fileSystem()
+
+	file, err := fs.OpenFile(tmpName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while creating frozen file: %s", err)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		if err != nil {
+			_ = fs.Remove(tmpName)
+		}
+	}()
+
+	// Records are written back to back after the header, keeping an entry for each
+	ow := &offsetWriter{file: file, offset: frozenHeaderLength}
+	bw := bufio.NewWriter(ow)
+	offset := int64(frozenHeaderLength)
+	lengths := make([]byte, binary.MaxVarintLen64)
+	var entries []frozenEntry
+
+	err = F.ForEach(func(key, value []byte) (stop bool, err error) {
+		length := 0
+		for _, field := range [][]byte{key, value} {
+			n := binary.PutUvarint(lengths, uint64(len(field)))
+			_, err = bw.Write(lengths[:n])
+			if err == nil {
+				_, err = bw.Write(field)
+			}
+			if err != nil {
+				return
+			}
+			length += n + len(field)
+		}
+
+		entries = append(entries, frozenEntry{hash: crc32.ChecksumIEEE(key), length: uint32(length), offset: offset})
+		offset += int64(length)
+
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("error while writing frozen file: %s", err)
+		return
+	}
+
+	// The bucket index holds the position of the first entry of each bucket, followed by the number of entries
+	buckets := int64(len(entries))
+	if buckets == 0 {
+		buckets = 1
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return int64(entries[i].hash)%buckets < int64(entries[j].hash)%buckets
+	})
+
+	starts := make([]int64, buckets+1)
+	for _, entry := range entries {
+		starts[int64(entry.hash)%buckets+1]++
+	}
+	for i := int64(1); i <= buckets; i++ {
+		starts[i] += starts[i-1]
+	}
+
+	indexOffset := offset
+	buf := make([]byte, frozenEntryLength)
+	for _, start := range starts {
+		binary.LittleEndian.PutUint64(buf, uint64(start))
+		_, err = bw.Write(buf[:8])
+		if err != nil {
+			err = fmt.Errorf("error while writing frozen file: %s", err)
+			return
+		}
+	}
+
+	entriesOffset := indexOffset + (buckets+1)*8
+	for _, entry := range entries {
+		binary.LittleEndian.PutUint32(buf[0:], entry.hash)
+		binary.LittleEndian.PutUint32(buf[4:], entry.length)
+		binary.LittleEndian.PutUint64(buf[8:], uint64(entry.offset))
+		_, err = bw.Write(buf)
+		if err != nil {
+			err = fmt.Errorf("error while writing frozen file: %s", err)
+			return
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		err = fmt.Errorf("error while writing frozen file: %s", err)
+		return
+	}
+
+	header := make([]byte, frozenHeaderLength)
+	copy(header, frozenMagic[:])
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(entries)))
+	binary.LittleEndian.PutUint64(header[16:], uint64(buckets))
+	binary.LittleEndian.PutUint64(header[24:], uint64(indexOffset))
+	binary.LittleEndian.PutUint64(header[32:], uint64(entriesOffset))
+	_, err = file.WriteAt(header, 0)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		err = fmt.Errorf("error while writing frozen file: %s", err)
+		return
+	}
+
+	err = fs.Rename(tmpName, fileName)
+	if err != nil {
+		err = fmt.Errorf("error while renaming frozen file: %s", err)
+		return
+	}
+
+	err = syncDirOf(fileName, F.options)
+	records = int64(len(entries))
+
+	return
+}
+
+// FrozenMap - Read only reader of a file written by Freeze. All methods are safe for concurrent use from multiple
+// goroutines.
+type FrozenMap struct {
+	file          vfs.File
+	records       int64
+	buckets       int64
+	indexOffset   int64
+	entriesOffset int64
+}
+
+// OpenFrozen - Opens a file written by Freeze for lookups.
+//   - fileName is the name of the frozen file (including correct path)
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - frozenMap is a pointer to a FrozenMap struct, which should be closed with Close when no longer needed
+//   - err is a standard error, if the file could not be opened or is not a frozen file
+func OpenFrozen(fileName string, options ...Option) (frozenMap *FrozenMap, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	file, err := o.fileSystem().OpenFile(fileName, os.O_RDONLY, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening frozen file: %s", err)
+		return
+	}
+
+	header := make([]byte, frozenHeaderLength)
+	_, err = file.ReadAt(header, 0)
+	if err == nil && !bytes.Equal(header[:8], frozenMagic[:]) {
+		err = fmt.Errorf("not a frozen file")
+	}
+	if err != nil {
+		_ = file.Close()
+		err = fmt.Errorf("error while reading frozen file header: %s", err)
+		return
+	}
+
+	frozenMap = &FrozenMap{
+		file:          file,
+		records:       int64(binary.LittleEndian.Uint64(header[8:])),
+		buckets:       int64(binary.LittleEndian.Uint64(header[16:])),
+		indexOffset:   int64(binary.LittleEndian.Uint64(header[24:])),
+		entriesOffset: int64(binary.LittleEndian.Uint64(header[32:])),
+	}
+
+	return
+}
+
+// Close - Closes the frozen file
+func (M *FrozenMap) Close() (err error) {
+	return M.file.Close()
+}
+
+// Count - Returns the number of records in the frozen file
+func (M *FrozenMap) Count() (count int64) {
+	return M.records
+}
+
+// Get - Returns the value of the record with the given key.
+//   - key is the key of the record to get
+//
+// It returns:
+//   - value is the value of the record
+//   - err is crt.NoRecordFound if there is no record with the key, or a standard error if something went wrong
+func (M *FrozenMap) Get(key []byte) (value []byte, err error) {
+	hash := crc32.ChecksumIEEE(key)
+
+	index := make([]byte, 16)
+	_, err = M.file.ReadAt(index, M.indexOffset+int64(hash)%M.buckets*8)
+	if err != nil {
+		err = fmt.Errorf("error while reading frozen file index: %s", err)
+		return
+	}
+	first := int64(binary.LittleEndian.Uint64(index[0:]))
+	last := int64(binary.LittleEndian.Uint64(index[8:]))
+	if first == last {
+		err = crt.NoRecordFound{}
+		return
+	}
+
+	entries := make([]byte, (last-first)*frozenEntryLength)
+	_, err = M.file.ReadAt(entries, M.entriesOffset+first*frozenEntryLength)
+	if err != nil {
+		err = fmt.Errorf("error while reading frozen file entries: %s", err)
+		return
+	}
+
+	var recordKey []byte
+	for i := int64(0); i < last-first; i++ {
+		entry := entries[i*frozenEntryLength : (i+1)*frozenEntryLength]
+		if binary.LittleEndian.Uint32(entry[0:]) != hash {
+			continue
+		}
+
+		recordKey, value, err = M.readRecord(binary.LittleEndian.Uint32(entry[4:]), int64(binary.LittleEndian.Uint64(entry[8:])))
+		if err != nil {
+			return
+		}
+		if utils.IsEqual(recordKey, key) {
+			return
+		}
+	}
+
+	value = nil
+	err = crt.NoRecordFound{}
+
+	return
+}
+
+// ForEach - Calls fn for every record in the frozen file, in the order they were written by Freeze.
+//   - fn is called with key and value of each record, returning stop as true or a non nil error ends the iteration
+//
+// It returns:
+//   - err is the error returned from fn, or a standard error if something went wrong while reading
+func (M *FrozenMap) ForEach(fn func(key, value []byte) (stop bool, err error)) (err error) {
+	br := bufio.NewReader(io.NewSectionReader(M.file, frozenHeaderLength, M.indexOffset-frozenHeaderLength))
+
+	var key, value []byte
+	var stop bool
+	for i := int64(0); i < M.records; i++ {
+		key, err = readStreamField(br)
+		if err == nil {
+			value, err = readStreamField(br)
+		}
+		if err != nil {
+			err = fmt.Errorf("error while reading frozen file record #%d: %s", i+1, err)
+			return
+		}
+
+		stop, err = fn(key, value)
+		if err != nil || stop {
+			return
+		}
+	}
+
+	return
+}
+
+// readRecord - Reads the key and value of the record at offset
+func (M *FrozenMap) readRecord(length uint32, offset int64) (key, value []byte, err error) {
+	buf := make([]byte, length)
+	_, err = M.file.ReadAt(buf, offset)
+	if err != nil {
+		err = fmt.Errorf("error while reading frozen file record: %s", err)
+		return
+	}
+
+	keyLength, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(n)+keyLength > uint64(length) {
+		err = fmt.Errorf("damaged record in frozen file at offset %d", offset)
+		return
+	}
+	key = buf[n : n+int(keyLength)]
+	buf = buf[n+int(keyLength):]
+
+	valueLength, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(n)+valueLength != uint64(len(buf)) {
+		err = fmt.Errorf("damaged record in frozen file at offset %d", offset)
+		return
+	}
+	value = buf[n:]
+
+	return
+}
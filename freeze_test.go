@@ -0,0 +1,107 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	frozenName := fmt.Sprintf("%s-frozen.bin", testHashMap)
+
+	t.Run("freezes map and gets records", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 200, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 100; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		_, err = fhm.Pop([]byte{0, 0, 0, 0})
+		assert.NoError(t, err, "pop record")
+
+		// Execute
+		records, err := fhm.Freeze(frozenName)
+
+		// Check
+		assert.NoError(t, err, "freeze map")
+		assert.Equal(t, int64(99), records, "records frozen")
+		_, err = os.Stat(frozenName + ".tmp")
+		assert.True(t, os.IsNotExist(err), "temporary file removed")
+
+		frozen, err := OpenFrozen(frozenName)
+		assert.NoError(t, err, "open frozen file")
+		assert.Equal(t, int64(99), frozen.Count(), "count")
+		for i := 1; i < 100; i++ {
+			value, err := frozen.Get([]byte{0, 0, 0, byte(i)})
+			assert.NoErrorf(t, err, "get record #%d", i)
+			assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+		}
+		_, err = frozen.Get([]byte{0, 0, 0, 0})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record not frozen")
+		_, err = frozen.Get([]byte{0, 0, 1, 0})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "missing record")
+
+		visited := 0
+		err = frozen.ForEach(func(key, value []byte) (stop bool, err error) {
+			assert.Equal(t, key[3], value[3], "key and value belong together")
+			visited++
+			return
+		})
+		assert.NoError(t, err, "for each")
+		assert.Equal(t, 99, visited, "records visited")
+
+		// Clean up
+		err = frozen.Close()
+		assert.NoError(t, err, "close frozen file")
+		err = os.Remove(frozenName)
+		assert.NoError(t, err, "remove frozen file")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("freezes empty map", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		// Execute
+		records, err := fhm.Freeze(frozenName)
+
+		// Check
+		assert.NoError(t, err, "freeze map")
+		assert.Equal(t, int64(0), records, "records frozen")
+		frozen, err := OpenFrozen(frozenName)
+		assert.NoError(t, err, "open frozen file")
+		_, err = frozen.Get([]byte{0, 0, 0, 1})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "no records")
+
+		// Clean up
+		err = frozen.Close()
+		assert.NoError(t, err, "close frozen file")
+		err = os.Remove(frozenName)
+		assert.NoError(t, err, "remove frozen file")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for file that is not frozen", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		// Execute
+		_, err = OpenFrozen(fmt.Sprintf("%s-map.bin", testHashMap))
+
+		// Check
+		assert.Error(t, err, "not a frozen file")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
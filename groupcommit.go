@@ -0,0 +1,120 @@
+package filehashmap
+
+import (
+	"fmt"
+	"time"
+)
+
+// groupCommit - Holds the settings and state for group commit of the sequence number, see EnableGroupCommit
+//   - mutations is the number of mutations after which the sequence number is committed
+//   - interval is the time after which the sequence number is committed at the next mutation, zero means no time limit
+//   - seq is the sequence number of the last applied mutation, which may not yet be committed
+//   - pending is the number of mutations applied since the last commit
+//   - lastCommit is the time of the last commit
+type groupCommit struct {
+	mutations  int64
+	interval   time.Duration
+	seq        int64
+	pending    int64
+	lastCommit time.Time
+}
+
+// EnableGroupCommit - Turns on group commit of the sequence number in the map file header. Instead of writing the
+// header on every mutation, the files are synced and the header is written as a checkpoint after every mutations
+// mutations, or at the first mutation after interval has passed since the last checkpoint, and when files are closed.
+// Every checkpoint is durable, since the files are synced before the header is written.
+//
+// If the WAL is enabled (see EnableWAL), recovery after an unclean shutdown applies only the WAL entries after the last
+// checkpoint, which brings the files and the sequence number up to date. Without the WAL, mutations since the last
+// checkpoint are kept in the files but the sequence number goes back to that of the last checkpoint.
+//   - mutations is the max number of mutations between checkpoints, values below 1 are set to 1
+//   - interval is the max time between checkpoints as long as mutations are made, zero means no time limit
+//
+// It returns:
+//   - err is a standard error, if a checkpoint of an already enabled group commit could not be written
+func (F *FileHashMap) EnableGroupCommit(mutations int, interval time.Duration) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.enableGroupCommit(mutations, interval)
+
+	return
+}
+
+// enableGroupCommit - Is the implementation of EnableGroupCommit, to be called with the lock held
+func (F *FileHashMap) enableGroupCommit(mutations int, interval time.Duration) (err error) {
+	if mutations < 1 {
+		mutations = 1
+	}
+
+	if F.groupCommit != nil {
+		err = F.commitSeq()
+		if err != nil {
+			return
+		}
+	}
+
+	F.groupCommit = &groupCommit{
+		mutations:  int64(mutations),
+		interval:   interval,
+		seq:        F.lastSeq(),
+		lastCommit: time.Now(),
+	}
+
+	return
+}
+
+// DisableGroupCommit - Writes a final checkpoint and turns off group commit, the header is then written on every
+// mutation again.
+//
+// It returns:
+//   - err is a standard error, if the checkpoint could not be written
+func (F *FileHashMap) DisableGroupCommit() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.groupCommit == nil {
+		return
+	}
+
+	err = F.commitSeq()
+	if err != nil {
+		return
+	}
+
+	F.groupCommit = nil
+
+	return
+}
+
+// commitSeq - Syncs the files and writes the sequence number to the map file header, to be called with the lock held
+func (F *FileHashMap) commitSeq() (err error) {
+	err = F.fileManagement.Sync()
+	if err != nil {
+		return
+	}
+
+	err = setMutationSeq(F.fileManagement, F.groupCommit.seq)
+	if err != nil {
+		err = fmt.Errorf("error while writing checkpoint: %s", err)
+		return
+	}
+
+	F.groupCommit.pending = 0
+	F.groupCommit.lastCommit = time.Now()
+
+	return
+}
+
+// groupSeq - Registers n applied mutations with sequence number seq for the last, and writes a checkpoint if due
+func (F *FileHashMap) groupSeq(seq, n int64) (err error) {
+	gc := F.groupCommit
+	gc.seq = seq
+	gc.pending += n
+
+	if gc.pending >= gc.mutations || (gc.interval > 0 && time.Since(gc.lastCommit) >= gc.interval) {
+		err = F.commitSeq()
+	}
+
+	return
+}
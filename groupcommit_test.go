@@ -0,0 +1,116 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGroupCommit(t *testing.T) {
+	t.Run("writes sequence number to header at checkpoints only", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		err = fhm.EnableGroupCommit(5, 0)
+		assert.NoError(t, err, "enables group commit")
+
+		// Execute
+		for i := 0; i < 7; i++ {
+			key := make([]byte, 16)
+			key[0] = byte(i)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoError(t, err, "sets record")
+		}
+
+		// Check
+		assert.Equal(t, int64(7), fhm.LastSeq(), "sequence number kept in memory")
+		assert.Equal(t, int64(5), fhm.fileManagement.GetStorageParameters().MutationSeq, "header written at checkpoint")
+
+		fhm.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+		assert.Equal(t, int64(7), fhm.LastSeq(), "checkpoint written at close")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("writes header on every mutation when disabled", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		err = fhm.EnableGroupCommit(100, 0)
+		assert.NoError(t, err, "enables group commit")
+		err = fhm.Set(make([]byte, 16), make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+
+		// Execute
+		err = fhm.DisableGroupCommit()
+		assert.NoError(t, err, "disables group commit")
+		err = fhm.Set(make([]byte, 16), make([]byte, 10))
+		assert.NoError(t, err, "sets record again")
+
+		// Check
+		assert.Equal(t, int64(2), fhm.LastSeq(), "sequence number")
+		assert.Equal(t, int64(2), fhm.fileManagement.GetStorageParameters().MutationSeq, "header written")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("recovers mutations since last checkpoint from WAL", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+		err = fhm.EnableGroupCommit(100, 0)
+		assert.NoError(t, err, "enables group commit")
+
+		keys := make([][]byte, 3)
+		value := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		for i := range keys {
+			keys[i] = make([]byte, 16)
+			keys[i][0] = byte(i + 1)
+			err = fhm.Set(keys[i], value)
+			assert.NoError(t, err, "sets record")
+		}
+
+		// Simulate a crash where the last mutation never reached the map file
+		record, err := fhm.fileManagement.Get(model.Record{Key: keys[2]})
+		assert.NoError(t, err, "gets last record")
+		err = fhm.fileManagement.Delete(record)
+		assert.NoError(t, err, "loses last mutation")
+		fhm.wal.Close()
+		fhm.fileManagement.CloseFiles()
+
+		// Execute
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+		seqBefore := fhm.LastSeq()
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "reopens WAL")
+
+		// Check
+		assert.Equal(t, int64(0), seqBefore, "no checkpoint written before crash")
+		assert.Equal(t, int64(3), fhm.LastSeq(), "sequence number recovered")
+		for _, key := range keys {
+			v, err := fhm.Get(key)
+			assert.NoError(t, err, "gets record")
+			assert.True(t, utils.IsEqual(value, v), "value recovered")
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,105 @@
+package hashfunc
+
+import (
+	"hash"
+	"math/bits"
+	"sync"
+)
+
+// hash64Algorithm - Is the HashAlgorithm returned by FromHash64
+type hash64Algorithm struct {
+	hashers   sync.Pool
+	policy    TableSizePolicy
+	tableSize int64
+}
+
+// FromHash64 - Returns a HashAlgorithm built on a standard 64-bit hasher, e.g. fnv.New64a, which saves implementing
+// the interface for every hasher. Buckets are given by the hash value modulo the table size, and a second hash value
+// is derived from the first by mixing its bits. The second value is used as the other candidate bucket in Cuckoo
+// hashing, and as the step between probes with TableSizePowerOfTwo and TableSizePrime, which visits every bucket
+// before repeating itself for any of the open addressing collision resolution techniques. With TableSizeExact the
+// table size may share factors with any step, so buckets are probed linearly instead.
+//   - newHash is a function returning a new hasher, which is called once per hasher needed by concurrent callers
+//   - policy is how the table size is rounded, one of TableSizeExact, TableSizePowerOfTwo or TableSizePrime
+//
+// It returns:
+//   - hashAlgorithm is the HashAlgorithm to give when creating or opening a file hash map
+func FromHash64(newHash func() hash.Hash64, policy TableSizePolicy) (hashAlgorithm HashAlgorithm) {
+	ha := &hash64Algorithm{policy: policy, tableSize: 1}
+	ha.hashers.New = func() any { return newHash() }
+	hashAlgorithm = ha
+
+	return
+}
+
+// SetTableSize - Sets the table size for the hash algorithm, rounded according to the table size policy
+//   - tableSize is the number of buckets the map file will address
+func (H *hash64Algorithm) SetTableSize(tableSize int64) {
+	H.tableSize = H.policy.Apply(tableSize)
+}
+
+// HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
+func (H *hash64Algorithm) HashFunc1(key []byte) int64 {
+	return int64(H.sum(key) % uint64(H.tableSize))
+}
+
+// HashFunc2 - Given key it generates a second index (bucket) between 0 and table size - 1, from which ProbeIteration
+// derives the step between probes
+func (H *hash64Algorithm) HashFunc2(key []byte) int64 {
+	return int64(mix64(H.sum(key)) % uint64(H.tableSize))
+}
+
+// GetTableSize - Returns the table size the implemented hash functions are supporting
+func (H *hash64Algorithm) GetTableSize() int64 {
+	return H.tableSize
+}
+
+// TableSizePolicy - Returns the table size policy given to FromHash64
+func (H *hash64Algorithm) TableSizePolicy() TableSizePolicy {
+	return H.policy
+}
+
+// ProbeIteration - Returns the bucket of the probe in iteration. The step between probes is an odd number with
+// TableSizePowerOfTwo and between 1 and table size - 1 with TableSizePrime, so that it never shares any factor with
+// the table size, while it is 1 with TableSizeExact.
+func (H *hash64Algorithm) ProbeIteration(hf1Value, hf2Value, iteration int64) int64 {
+	n := uint64(H.tableSize)
+	if n == 1 {
+		return 0
+	}
+
+	var step uint64
+	switch H.policy {
+	case TableSizePowerOfTwo:
+		step = uint64(hf2Value) | 1
+	case TableSizePrime:
+		step = 1 + uint64(hf2Value)%(n-1)
+	default:
+		step = 1
+	}
+
+	// The offset is calculated in 128 bits to not overflow for big tables
+	hi, lo := bits.Mul64(uint64(iteration)%n, step)
+	offset := bits.Rem64(hi, lo, n)
+
+	return int64((uint64(hf1Value) + offset) % n)
+}
+
+// sum - Returns the 64-bit hash value of key
+func (H *hash64Algorithm) sum(key []byte) uint64 {
+	h := H.hashers.Get().(hash.Hash64)
+	h.Reset()
+	_, _ = h.Write(key)
+	s := h.Sum64()
+	H.hashers.Put(h)
+
+	return s
+}
+
+// mix64 - Mixes the bits of x using the finalizer of SplitMix64, to derive a second hash value from a first one
+func mix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+
+	return x ^ (x >> 31)
+}
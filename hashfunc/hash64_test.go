@@ -0,0 +1,62 @@
+//go:build unit
+
+package hashfunc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hash/fnv"
+	"testing"
+)
+
+func TestFromHash64(t *testing.T) {
+	t.Run("rounds table size according to policy", func(t *testing.T) {
+		// Prepare
+		sizes := map[TableSizePolicy]int64{TableSizeExact: 100, TableSizePowerOfTwo: 128, TableSizePrime: 101}
+
+		for policy, size := range sizes {
+			ha := FromHash64(fnv.New64a, policy)
+
+			// Execute
+			ha.SetTableSize(100)
+
+			// Check
+			assert.Equalf(t, size, ha.GetTableSize(), "table size with policy %d", policy)
+		}
+	})
+
+	t.Run("hashes within table", func(t *testing.T) {
+		// Prepare
+		ha := FromHash64(fnv.New64a, TableSizePrime)
+		ha.SetTableSize(1000)
+
+		for i := 0; i < 1000; i++ {
+			key := []byte{0, 0, byte(i >> 8), byte(i)}
+
+			// Execute
+			hf1, hf2 := ha.HashFunc1(key), ha.HashFunc2(key)
+
+			// Check
+			assert.Truef(t, hf1 >= 0 && hf1 < ha.GetTableSize(), "first hash value of key #%d within table", i)
+			assert.Truef(t, hf2 >= 0 && hf2 < ha.GetTableSize(), "second hash value of key #%d within table", i)
+		}
+	})
+
+	t.Run("probes visit all buckets", func(t *testing.T) {
+		for _, policy := range []TableSizePolicy{TableSizeExact, TableSizePowerOfTwo, TableSizePrime} {
+			// Prepare
+			ha := FromHash64(fnv.New64a, policy)
+			ha.SetTableSize(60)
+			key := []byte("probe")
+			hf1, hf2 := ha.HashFunc1(key), ha.HashFunc2(key)
+
+			// Execute
+			visited := make(map[int64]bool)
+			for i := int64(0); i < ha.GetTableSize(); i++ {
+				visited[ha.ProbeIteration(hf1, hf2, i)] = true
+			}
+
+			// Check
+			assert.Equalf(t, int(ha.GetTableSize()), len(visited), "all buckets visited with policy %d", policy)
+		}
+	})
+}
@@ -33,3 +33,17 @@ type HashAlgorithm interface {
 	// The function is not used for Open Chaining Collision Resolution Technique.
 	ProbeIteration(hf1Value, hf2Value, iteration int64) int64
 }
+
+// Identity - Optional interface that a HashAlgorithm can implement to be recognized when a file hash map is opened.
+// When a file hash map is created with a hash algorithm implementing it, the identifier and seed are persisted in the
+// map file header, and opening the file hash map fails with an error of type crt.HashAlgorithmMismatch unless it is
+// given a hash algorithm with the same identifier and seed. Without it a wrong custom hash algorithm would silently
+// make lookups miss records and place new records in the wrong buckets.
+type Identity interface {
+	// AlgorithmID - Returns a name that identifies the algorithm, including its version if the way it hashes may
+	// change, e.g. "geohash-v2". It can be at most 200 bytes long.
+	AlgorithmID() string
+
+	// AlgorithmSeed - Returns the seed the hash functions are initialized with, zero if the algorithm is not seeded
+	AlgorithmSeed() int64
+}
@@ -0,0 +1,48 @@
+package hashfunc
+
+import "github.com/gostonefire/filehashmap/internal/utils"
+
+// TableSizePolicy - Tells how the table size of a hash algorithm has to be rounded, see TableSizeConstraint
+type TableSizePolicy int
+
+const (
+	// TableSizeExact - The table size is used as given
+	TableSizeExact TableSizePolicy = iota
+	// TableSizePowerOfTwo - The table size is rounded up to the nearest exponent of 2, e.g. for quadratic probing that
+	// only cycles over all buckets of such tables
+	TableSizePowerOfTwo
+	// TableSizePrime - The table size is rounded up to the nearest prime number, e.g. for double hashing where any
+	// probing step then visits all buckets
+	TableSizePrime
+)
+
+// TableSizeConstraint - Optional interface that a HashAlgorithm can implement to declare what table sizes it works
+// with. Each collision resolution technique then rounds the number of buckets needed according to the policy before
+// calling SetTableSize, so the number of buckets available is known to conform with it, and creating or opening a
+// file hash map fails if GetTableSize still returns a table size that doesn't.
+type TableSizeConstraint interface {
+	// TableSizePolicy - Returns how the table size has to be rounded
+	TableSizePolicy() TableSizePolicy
+}
+
+// Apply - Returns tableSize rounded according to the policy, at least 1
+func (P TableSizePolicy) Apply(tableSize int64) (rounded int64) {
+	switch P {
+	case TableSizePowerOfTwo:
+		rounded = utils.RoundUp2(tableSize)
+	case TableSizePrime:
+		rounded = utils.NextPrime(tableSize)
+	default:
+		rounded = tableSize
+	}
+	if rounded < 1 {
+		rounded = 1
+	}
+
+	return
+}
+
+// Allows - Returns true if tableSize conforms with the policy
+func (P TableSizePolicy) Allows(tableSize int64) bool {
+	return tableSize >= 1 && P.Apply(tableSize) == tableSize
+}
@@ -0,0 +1,23 @@
+//go:build unit
+
+package hashfunc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTableSizePolicy(t *testing.T) {
+	t.Run("applies and checks policies", func(t *testing.T) {
+		// Execute and check
+		assert.Equal(t, int64(100), TableSizeExact.Apply(100), "exact")
+		assert.Equal(t, int64(128), TableSizePowerOfTwo.Apply(100), "power of two")
+		assert.Equal(t, int64(101), TableSizePrime.Apply(100), "prime")
+		assert.Equal(t, int64(1), TableSizeExact.Apply(0), "at least one")
+		assert.True(t, TableSizeExact.Allows(100), "exact allows any")
+		assert.True(t, TableSizePowerOfTwo.Allows(64), "power of two allowed")
+		assert.False(t, TableSizePowerOfTwo.Allows(100), "not a power of two")
+		assert.True(t, TableSizePrime.Allows(101), "prime allowed")
+		assert.False(t, TableSizePrime.Allows(100), "not a prime")
+	})
+}
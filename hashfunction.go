@@ -0,0 +1,177 @@
+package filehashmap
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/hash"
+	"github.com/gostonefire/filehashmap/internal/model"
+)
+
+// HashFunction - Tells which hash function the internal hash algorithm is built on, see BuiltinHash
+type HashFunction int
+
+const (
+	// HashCRC32 - crc32 using the IEEE polynomial, which is fast but not seeded, and the default
+	HashCRC32 HashFunction = iota
+	// HashSipHash - SipHash-2-4 keyed by a random seed, which keeps keys from untrusted sources from being chosen to
+	// all end up in the same buckets
+	HashSipHash
+	// HashXXHash - XXH64 initialized with a random seed, which is fast also for long keys
+	HashXXHash
+	// HashFNV1a - 64-bit FNV-1a, which is simple and fast for short keys
+	HashFNV1a
+	// HashMurmur3 - 32-bit MurmurHash3 initialized with a random seed
+	HashMurmur3
+)
+
+// builtinHash - Is the hash algorithm returned by BuiltinHash. It only selects the hash function of the internal hash
+// algorithm, which is built by the collision resolution technique when the file hash map is created, and its methods
+// are those of an internal Separate Chaining algorithm for the same hash function.
+type builtinHash struct {
+	hashfunc.HashAlgorithm
+	function HashFunction
+}
+
+// BuiltinHash - Returns a hash algorithm that selects a built-in hash function for the internal hash algorithm when
+// given to NewFileHashMap, for that file hash map only. Without it the internal hash algorithm is built on crc32, which
+// is fast but lets anyone who knows what keys will be stored choose keys that all end up in the same bucket, turning
+// every operation into a walk through a long probe chain or overflow list. A keyed hash function such as SipHash is
+// given a random seed for each new file hash map, so the buckets of keys can't be predicted without it. The hash
+// function and its seed are persisted in the map file header, so the file hash map can be opened with nil as hash
+// algorithm and uses the same hash function automatically. Giving it when opening a file hash map checks that the files
+// were created with that hash function, and fails with an error of type crt.HashAlgorithmMismatch otherwise. Automatic
+// growing and reorganization keep the hash function of the original files. Creating a file hash map fails if function
+// is not a valid hash function.
+//   - function is one of HashCRC32, HashSipHash, HashXXHash, HashFNV1a or HashMurmur3
+//
+// It returns:
+//   - hashAlgorithm is the hash algorithm to give when creating a file hash map
+func BuiltinHash(function HashFunction) (hashAlgorithm hashfunc.HashAlgorithm) {
+	internal, _ := hash.NewInternalHashAlgorithm(crt.SeparateChaining, 1, model.HashParameters{Kind: hashKinds[function]})
+	hashAlgorithm = &builtinHash{HashAlgorithm: internal, function: function}
+
+	return
+}
+
+// hashKinds - Maps the hash functions to the kinds of internal hash algorithms persisted in the map file header
+var hashKinds = map[HashFunction]int64{
+	HashCRC32:   hash.CRC32,
+	HashSipHash: hash.SipHash,
+	HashXXHash:  hash.XXHash,
+	HashFNV1a:   hash.FNV1a,
+	HashMurmur3: hash.Murmur3,
+}
+
+// hashNames - Names the hash functions in errors
+var hashNames = map[HashFunction]string{
+	HashCRC32:   "crc32",
+	HashSipHash: "SipHash",
+	HashXXHash:  "XXH64",
+	HashFNV1a:   "FNV-1a",
+	HashMurmur3: "MurmurHash3",
+}
+
+// checkHashFunction - Returns an error if function is not a valid hash function
+func checkHashFunction(function HashFunction) (err error) {
+	if _, ok := hashKinds[function]; !ok {
+		err = fmt.Errorf("function has to be one of HashCRC32, HashSipHash, HashXXHash, HashFNV1a or HashMurmur3")
+	}
+
+	return
+}
+
+// customHashAlgorithm - Returns hashAlgorithm if it is a custom hash algorithm, or nil if it is nil or selects a
+// built-in hash function (see BuiltinHash)
+func customHashAlgorithm(hashAlgorithm hashfunc.HashAlgorithm) hashfunc.HashAlgorithm {
+	if _, ok := hashAlgorithm.(*builtinHash); ok {
+		return nil
+	}
+
+	return hashAlgorithm
+}
+
+// internalHashAlgorithm - Returns the hash algorithm that selects the same hash function as the internal hash
+// algorithm of files with the given storage parameters, or nil if they use a custom hash algorithm or a hash function
+// not known to this release
+func internalHashAlgorithm(sp model.StorageParameters) (hashAlgorithm hashfunc.HashAlgorithm) {
+	if !sp.InternalAlgorithm {
+		return
+	}
+	for function, kind := range hashKinds {
+		if kind == sp.HashParameters.Kind {
+			hashAlgorithm = BuiltinHash(function)
+		}
+	}
+
+	return
+}
+
+// sameHashFunction - Returns false if hashAlgorithm selects another built-in hash function (see BuiltinHash) than the
+// internal hash algorithm of files with the given storage parameters is built on
+func sameHashFunction(sp model.StorageParameters, hashAlgorithm hashfunc.HashAlgorithm) bool {
+	if b, ok := hashAlgorithm.(*builtinHash); ok {
+		return sp.HashParameters.Kind == hashKinds[b.function]
+	}
+
+	return true
+}
+
+// checkBuiltinHash - Checks that hashAlgorithm, if it selects a built-in hash function (see BuiltinHash), selects the
+// one that the internal hash algorithm of files with the given storage parameters is built on
+//
+// It returns:
+//   - err is of type crt.HashAlgorithmMismatch if they differ, also if the files use a custom hash algorithm
+func checkBuiltinHash(sp model.StorageParameters, hashAlgorithm hashfunc.HashAlgorithm) (err error) {
+	b, ok := hashAlgorithm.(*builtinHash)
+	if !ok || sp.InternalAlgorithm && sameHashFunction(sp, hashAlgorithm) {
+		return
+	}
+
+	mismatch := crt.HashAlgorithmMismatch{ExpectedID: "custom", ActualID: hashNames[b.function]}
+	if sp.InternalAlgorithm {
+		mismatch.ExpectedID = fmt.Sprintf("unknown (%d)", sp.HashParameters.Kind)
+		if name, known := hashNames[HashFunction(sp.HashParameters.Kind)]; known {
+			mismatch.ExpectedID = name
+		}
+	}
+	err = mismatch
+
+	return
+}
+
+// newHashParameters - Returns the hash algorithm to give the collision resolution technique for a new file hash map,
+// nil unless hashAlgorithm is a custom one, together with the parameters for the internal hash algorithm according to
+// BuiltinHash, or for crc32 if not given. Keyed hash functions are given a random seed.
+func newHashParameters(hashAlgorithm hashfunc.HashAlgorithm) (custom hashfunc.HashAlgorithm, params model.HashParameters, err error) {
+	custom = customHashAlgorithm(hashAlgorithm)
+	if custom != nil {
+		return
+	}
+
+	function := HashCRC32
+	if b, ok := hashAlgorithm.(*builtinHash); ok {
+		function = b.function
+	}
+	err = checkHashFunction(function)
+	if err != nil {
+		return
+	}
+
+	params.Kind = hashKinds[function]
+	if function == HashCRC32 || function == HashFNV1a {
+		return
+	}
+
+	seed := make([]byte, 8)
+	_, err = rand.Read(seed)
+	if err != nil {
+		err = fmt.Errorf("error while generating hash seed: %s", err)
+		return
+	}
+	params.Seed = int64(binary.LittleEndian.Uint64(seed))
+
+	return
+}
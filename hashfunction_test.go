@@ -0,0 +1,175 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuiltinHash(t *testing.T) {
+	t.Run("creates and reopens files using SipHash", func(t *testing.T) {
+		for _, crtType := range []int{crt.SeparateChaining, crt.LinearProbing, crt.DoubleHashing, crt.RobinHood, crt.CuckooHashing, crt.LinearHashing} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crtType, 100, 2, 4, 4, BuiltinHash(HashSipHash))
+			assert.NoErrorf(t, err, "create file hash map with crt %d", crtType)
+			for i := 0; i < 50; i++ {
+				err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			fhm.CloseFiles()
+
+			// Execute
+			header, err := InspectFiles(testHashMap)
+			assert.NoError(t, err, "inspect files")
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open file hash map")
+
+			// Check
+			assert.Equalf(t, HashSipHash, header.HashFunction, "hash function in header with crt %d", crtType)
+			for i := 0; i < 50; i++ {
+				value, err := fhm.Get([]byte{0, 0, 0, byte(i)})
+				assert.NoErrorf(t, err, "gets record #%d", i)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "remove files")
+		}
+	})
+
+	t.Run("defaults to crc32", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		header, err := InspectFiles(testHashMap)
+
+		// Check
+		assert.NoError(t, err, "inspect files")
+		assert.Equal(t, HashCRC32, header.HashFunction, "hash function in header")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open file hash map")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("selects hash function per file hash map", func(t *testing.T) {
+		for _, function := range []HashFunction{HashCRC32, HashSipHash, HashXXHash, HashFNV1a, HashMurmur3} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crt.QuadraticProbing, 100, 1, 4, 4, BuiltinHash(function))
+			assert.NoErrorf(t, err, "create file hash map with hash function %d", function)
+			for i := 0; i < 50; i++ {
+				err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			fhm.CloseFiles()
+
+			// Execute
+			header, err := InspectFiles(testHashMap)
+			assert.NoError(t, err, "inspect files")
+			fhm, _, err = NewFromExistingFiles(testHashMap, BuiltinHash(function))
+			assert.NoError(t, err, "open file hash map")
+
+			// Check
+			assert.True(t, header.InternalHash, "internal hash")
+			assert.Equal(t, function, header.HashFunction, "hash function in header")
+			for i := 0; i < 50; i++ {
+				value, err := fhm.Get([]byte{0, 0, 0, byte(i)})
+				assert.NoErrorf(t, err, "gets record #%d", i)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "remove files")
+		}
+	})
+
+	t.Run("reorganization keeps or changes hash function", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, BuiltinHash(HashMurmur3))
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 20; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{NumberOfBucketsNeeded: 40, RecordsPerBucket: 1, ReplaceInPlace: true}, false)
+		assert.NoError(t, err, "reorganize files")
+		kept, err := InspectFiles(testHashMap)
+		assert.NoError(t, err, "inspect reorganized files")
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{NewHashAlgorithm: BuiltinHash(HashXXHash), ReplaceInPlace: true}, false)
+		assert.NoError(t, err, "reorganize files with new hash function")
+		changed, err := InspectFiles(testHashMap)
+		assert.NoError(t, err, "inspect reorganized files")
+
+		// Check
+		assert.Equal(t, HashMurmur3, kept.HashFunction, "hash function kept")
+		assert.Equal(t, HashXXHash, changed.HashFunction, "hash function changed")
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open file hash map")
+		count, err := fhm.Count()
+		assert.NoError(t, err, "count records")
+		assert.Equal(t, int64(20), count, "all records kept")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails to create with unknown hash function", func(t *testing.T) {
+		// Execute
+		_, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, BuiltinHash(HashFunction(99)))
+
+		// Check
+		assert.Error(t, err, "unknown hash function")
+	})
+
+	t.Run("fails to open with another hash function", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 4, 4, BuiltinHash(HashSipHash))
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, errOther := NewFromExistingFiles(testHashMap, BuiltinHash(HashXXHash))
+		fhm, _, err = NewFromExistingFiles(testHashMap, BuiltinHash(HashSipHash))
+
+		// Check
+		assert.ErrorIs(t, errOther, crt.HashAlgorithmMismatch{}, "opening with another hash function fails")
+		assert.ErrorContains(t, errOther, "SipHash", "error tells the hash function of the files")
+		assert.NoError(t, err, "opening with the same hash function succeeds")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails to open custom hash algorithm files with a built-in hash function", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, NewSeparateChainingHashAlgorithm(10))
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = NewFromExistingFiles(testHashMap, BuiltinHash(HashCRC32))
+
+		// Check
+		assert.Error(t, err, "opening with a built-in hash function fails")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, NewSeparateChainingHashAlgorithm(10))
+		assert.NoError(t, err, "open file hash map")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
@@ -0,0 +1,76 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+)
+
+// hashIdentitySystemValueID - Is the id of the system value in the header holding the identifier and seed of the
+// custom hash algorithm a file hash map was created with, see hashfunc.Identity
+const hashIdentitySystemValueID uint8 = 8
+
+// maxAlgorithmIDLength - Is the max length of the identifier returned by hashfunc.Identity.AlgorithmID
+const maxAlgorithmIDLength = 200
+
+// saveHashIdentity - Persists the identifier and seed of hashAlgorithm in the header, if it implements hashfunc.Identity
+func saveHashIdentity(fm FileManagement, hashAlgorithm hashfunc.HashAlgorithm) (err error) {
+	identity, ok := hashAlgorithm.(hashfunc.Identity)
+	if !ok {
+		return
+	}
+
+	id := identity.AlgorithmID()
+	if id == "" || len(id) > maxAlgorithmIDLength {
+		err = fmt.Errorf("hash algorithm identifier must be between 1 and %d bytes long", maxAlgorithmIDLength)
+		return
+	}
+
+	value := make([]byte, 8+len(id))
+	binary.LittleEndian.PutUint64(value, uint64(identity.AlgorithmSeed()))
+	_ = copy(value[8:], id)
+
+	err = fm.SetSystemValue(hashIdentitySystemValueID, value)
+	if err != nil {
+		err = fmt.Errorf("error while saving hash algorithm identity: %s", err)
+	}
+
+	return
+}
+
+// checkHashIdentity - Checks that hashAlgorithm has the identifier and seed persisted in the header, if any were
+//
+// It returns:
+//   - err is of type crt.HashAlgorithmMismatch if they differ, or a standard error if the header could not be read
+func checkHashIdentity(fm FileManagement, hashAlgorithm hashfunc.HashAlgorithm) (err error) {
+	value, err := fm.GetSystemValue(hashIdentitySystemValueID)
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("error while reading hash algorithm identity: %s", err)
+		return
+	}
+	if len(value) < 8 {
+		err = fmt.Errorf("damaged hash algorithm identity in header")
+		return
+	}
+
+	mismatch := crt.HashAlgorithmMismatch{
+		ExpectedID:   string(value[8:]),
+		ExpectedSeed: int64(binary.LittleEndian.Uint64(value)),
+	}
+	if identity, ok := hashAlgorithm.(hashfunc.Identity); ok {
+		mismatch.ActualID = identity.AlgorithmID()
+		mismatch.ActualSeed = identity.AlgorithmSeed()
+	}
+
+	if mismatch.ActualID != mismatch.ExpectedID || mismatch.ActualSeed != mismatch.ExpectedSeed {
+		err = mismatch
+	}
+
+	return
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// identifiedHashAlgorithm - Is a custom hash algorithm implementing hashfunc.Identity
+type identifiedHashAlgorithm struct {
+	*SeparateChainingHashAlgorithm
+	id   string
+	seed int64
+}
+
+// AlgorithmID - Returns the identifier of the algorithm
+func (I identifiedHashAlgorithm) AlgorithmID() string {
+	return I.id
+}
+
+// AlgorithmSeed - Returns the seed of the algorithm
+func (I identifiedHashAlgorithm) AlgorithmSeed() int64 {
+	return I.seed
+}
+
+func TestHashIdentity(t *testing.T) {
+	t.Run("checks hash algorithm identity when opening", func(t *testing.T) {
+		// Prepare
+		created := identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: "test-v1", seed: 42}
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, created)
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, errID := NewFromExistingFiles(testHashMap, identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: "test-v2", seed: 42})
+		_, _, errSeed := NewFromExistingFiles(testHashMap, identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: "test-v1", seed: 43})
+		_, _, errNoIdentity := NewFromExistingFiles(testHashMap, NewSeparateChainingHashAlgorithm(10))
+		fhm, _, err = NewFromExistingFiles(testHashMap, identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: "test-v1", seed: 42})
+
+		// Check
+		assert.Equal(t, crt.HashAlgorithmMismatch{ExpectedID: "test-v1", ExpectedSeed: 42, ActualID: "test-v2", ActualSeed: 42}, errID, "other identifier")
+		assert.Equal(t, crt.HashAlgorithmMismatch{ExpectedID: "test-v1", ExpectedSeed: 42, ActualID: "test-v1", ActualSeed: 43}, errSeed, "other seed")
+		assert.ErrorIs(t, errNoIdentity, crt.HashAlgorithmMismatch{}, "no identity")
+		assert.NoError(t, err, "same identity")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("accepts any hash algorithm without persisted identity", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, NewSeparateChainingHashAlgorithm(10))
+		assert.NoError(t, err, "create file hash map")
+		fhm.CloseFiles()
+
+		// Execute
+		fhm, _, err = NewFromExistingFiles(testHashMap, identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: "test-v1"})
+
+		// Check
+		assert.NoError(t, err, "open file hash map")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for too long identifier", func(t *testing.T) {
+		// Execute
+		_, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, identifiedHashAlgorithm{SeparateChainingHashAlgorithm: NewSeparateChainingHashAlgorithm(10), id: string(make([]byte, 201))})
+
+		// Check
+		assert.Error(t, err, "too long identifier")
+		_, err = InspectFiles(testHashMap)
+		assert.Error(t, err, "no files left")
+	})
+}
@@ -0,0 +1,162 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+	"time"
+)
+
+// closedAtSystemValueID - Is the id of the system value in the header holding the time the files were last closed
+const closedAtSystemValueID uint8 = 7
+
+// Header - A view of what the headers of the files of a file hash map tell about it, see InspectFiles
+//   - CollisionResolutionTechnique is the collision resolution technique used
+//   - KeyLength is the length of the key part in a record
+//   - ValueLength is the length of the value part in a record
+//   - NumberOfBucketsNeeded is the need provided when the file hash map was created
+//   - NumberOfBucketsAvailable is the total number of available buckets in the map file
+//   - RecordsPerBucket is the number of records held in each bucket in the map file
+//   - InternalHash is true if the internal hash algorithm is used, false if a custom one has to be supplied to open it
+//   - HashFunction is the hash function the internal hash algorithm is built on, see BuiltinHash
+//   - FormatVersion is the format version of the map file header
+//   - LastSeq is the sequence number of the last applied mutation
+//   - CountsKnown is true if the record counts below were saved when the files were last closed properly, which
+//     Separate Chaining, Hybrid and Linear Hashing do, otherwise they are zero and the records have to be counted by
+//     opening the file hash map (see Count)
+//   - OccupiedRecords is the number of records stored, in the map file as well as in the overflow file
+//   - DeletedRecords is the number of deleted records left, in the map file as well as in the overflow file
+//   - ClosedAt is the time the files were last closed after being opened for writing, zero if they never were
+//   - MapFileSize is the size of the map file in bytes
+//   - OverflowFileSize is the size of the overflow file in bytes, zero if there is no overflow file
+type Header struct {
+	CollisionResolutionTechnique int
+	KeyLength                    int
+	ValueLength                  int
+	NumberOfBucketsNeeded        int
+	NumberOfBucketsAvailable     int
+	RecordsPerBucket             int
+	InternalHash                 bool
+	HashFunction                 HashFunction
+	FormatVersion                int
+	LastSeq                      int64
+	CountsKnown                  bool
+	OccupiedRecords              int64
+	DeletedRecords               int64
+	ClosedAt                     time.Time
+	MapFileSize                  int64
+	OverflowFileSize             int64
+}
+
+// InspectFiles - Reads the headers of the files of a file hash map without opening it, which makes it usable for
+// tools and monitoring also while another process has the file hash map open. Nothing is changed in the files and no
+// lock is taken, so a file hash map that is being written to may give a header that is already outdated.
+//   - name is the name of an existing file hash map (including correct path)
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - header is a Header struct with what the headers tell
+//   - err is a standard error, if the files could not be read
+func InspectFiles(name string, options ...Option) (header Header, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	fs := storage.NewReadOnlyFileSystem(o.fileSystem())
+
+	mapFile, err := fs.OpenFile(storage.GetMapFileName(name), os.O_RDONLY, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening map file: %s", err)
+		return
+	}
+	defer func() { _ = mapFile.Close() }()
+
+	h, err := storage.GetHeader(mapFile)
+	var unknownFormat crt.UnknownFormat
+	if errors.As(err, &unknownFormat) {
+		unknownFormat.FileName = storage.GetMapFileName(name)
+		err = unknownFormat
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("error while reading map file header: %s", err)
+		return
+	}
+
+	header = Header{
+		CollisionResolutionTechnique: int(h.CollisionResolutionTechnique),
+		KeyLength:                    int(h.KeyLength),
+		ValueLength:                  int(h.ValueLength),
+		NumberOfBucketsNeeded:        int(h.NumberOfBucketsNeeded),
+		NumberOfBucketsAvailable:     int(h.NumberOfBucketsAvailable),
+		RecordsPerBucket:             int(h.RecordsPerBucket),
+		InternalHash:                 h.InternalHash,
+		HashFunction:                 HashFunction(h.HashAlgorithmKind),
+		FormatVersion:                int(h.FormatVersion),
+		LastSeq:                      h.MutationSeq,
+	}
+
+	header.MapFileSize, err = storage.GetFileSize(mapFile)
+	if err != nil {
+		err = fmt.Errorf("error while getting map file size: %s", err)
+		return
+	}
+
+	if closedAt, svErr := storage.GetSystemValue(mapFile, closedAtSystemValueID); svErr == nil && len(closedAt) == 8 {
+		header.ClosedAt = time.Unix(0, int64(binary.LittleEndian.Uint64(closedAt)))
+	}
+
+	header.OccupiedRecords, header.DeletedRecords, header.CountsKnown, err = storage.SavedCounts(mapFile)
+	if err != nil {
+		err = fmt.Errorf("error while reading record counts from map file header: %s", err)
+		return
+	}
+	if !header.CountsKnown {
+		header.OccupiedRecords, header.DeletedRecords = 0, 0
+	}
+
+	ovflFile, ovflErr := fs.OpenFile(storage.GetOvflFileName(name), os.O_RDONLY, 0644)
+	if ovflErr != nil {
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(ovflFile)
+
+	header.OverflowFileSize, err = storage.GetFileSize(ovflFile)
+	if err != nil {
+		err = fmt.Errorf("error while getting overflow file size: %s", err)
+		return
+	}
+
+	occupied, deleted, clean, err := storage.SavedCounts(ovflFile)
+	if err != nil {
+		err = fmt.Errorf("error while reading record counts from overflow file header: %s", err)
+		return
+	}
+	if header.CountsKnown && clean {
+		header.OccupiedRecords += occupied
+		header.DeletedRecords += deleted
+	} else {
+		header.CountsKnown = false
+		header.OccupiedRecords, header.DeletedRecords = 0, 0
+	}
+
+	return
+}
+
+// saveClosedAt - Saves the current time in the header as the time the files were last closed, see InspectFiles
+func (F *FileHashMap) saveClosedAt() (err error) {
+	if F.readOnly || F.name == "" {
+		return
+	}
+
+	closedAt := make([]byte, 8)
+	binary.LittleEndian.PutUint64(closedAt, uint64(time.Now().UnixNano()))
+	err = F.fileManagement.SetSystemValue(closedAtSystemValueID, closedAt)
+
+	return
+}
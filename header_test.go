@@ -0,0 +1,81 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInspectFiles(t *testing.T) {
+	t.Run("inspects closed files", func(t *testing.T) {
+		// Prepare
+		fhm, info, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 4, 6, nil)
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 30; i++ {
+			err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, 1, 1, byte(i)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		_, err = fhm.Pop([]byte{0, 0, 0, 0})
+		assert.NoError(t, err, "pop record")
+		before := time.Now()
+		fhm.CloseFiles()
+
+		// Execute
+		header, err := InspectFiles(testHashMap)
+
+		// Check
+		assert.NoError(t, err, "inspect files")
+		assert.Equal(t, crt.SeparateChaining, header.CollisionResolutionTechnique, "crt")
+		assert.Equal(t, 4, header.KeyLength, "key length")
+		assert.Equal(t, 6, header.ValueLength, "value length")
+		assert.Equal(t, 10, header.NumberOfBucketsNeeded, "buckets needed")
+		assert.Equal(t, info.NumberOfBucketsAvailable, header.NumberOfBucketsAvailable, "buckets available")
+		assert.Equal(t, 2, header.RecordsPerBucket, "records per bucket")
+		assert.True(t, header.InternalHash, "internal hash")
+		assert.True(t, header.CountsKnown, "counts known")
+		assert.Equal(t, int64(29), header.OccupiedRecords, "occupied records")
+		assert.Equal(t, int64(1), header.DeletedRecords, "deleted records")
+		assert.False(t, header.ClosedAt.Before(before.Truncate(time.Second)), "closed at")
+		assert.Equal(t, int64(info.FileSize), header.MapFileSize, "map file size")
+		assert.Greater(t, header.OverflowFileSize, int64(0), "overflow file size")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open file hash map")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("inspects open files", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte{0, 0, 0, 1}, []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "set record")
+
+		// Execute
+		header, err := InspectFiles(testHashMap)
+
+		// Check
+		assert.NoError(t, err, "inspect files")
+		assert.Equal(t, crt.LinearProbing, header.CollisionResolutionTechnique, "crt")
+		assert.False(t, header.CountsKnown, "counts not known")
+		assert.True(t, header.ClosedAt.IsZero(), "never closed")
+		assert.Equal(t, int64(0), header.OverflowFileSize, "no overflow file")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for missing files", func(t *testing.T) {
+		// Execute
+		_, err := InspectFiles("missing")
+
+		// Check
+		assert.Error(t, err, "missing files")
+	})
+}
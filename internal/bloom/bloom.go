@@ -0,0 +1,222 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"hash/fnv"
+	"math"
+	"os"
+)
+
+// bloomFileHeader - Is the magic at the start of a Bloom filter file
+var bloomFileHeader = []byte("FHMBLOOM")
+
+// headerLength - Length of the file header; magic 8 bytes, number of bits 8 bytes, number of hash functions 4 bytes,
+// expected keys 8 bytes, false positive rate 8 bytes and the dirty flag 1 byte
+const headerLength int64 = 37
+
+// dirtyOffset - File offset to the dirty flag, which is set while the filter is in use and cleared when it is saved
+const dirtyOffset int64 = 36
+
+// Filter - Is a Bloom filter telling whether a key may have been added to it or definitely has not. It never gives
+// false negatives, but gives false positives at a rate depending on how many keys are added compared to its size.
+type Filter struct {
+	bits              []byte
+	m                 uint64
+	k                 uint32
+	expectedKeys      int64
+	falsePositiveRate float64
+}
+
+// New - Returns a pointer to a new empty Filter sized for a number of keys at a false positive rate
+//   - expectedKeys is the number of keys the filter is sized for
+//   - falsePositiveRate is the rate of false positives wanted when expectedKeys keys are added, between 0 and 1
+//
+// It returns:
+//   - filter is a pointer to the new Filter
+func New(expectedKeys int64, falsePositiveRate float64) (filter *Filter) {
+	n := math.Max(float64(expectedKeys), 1)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 8 {
+		m = 8
+	}
+	k := uint32(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	filter = &Filter{
+		bits:              make([]byte, (m+7)/8),
+		m:                 m,
+		k:                 k,
+		expectedKeys:      expectedKeys,
+		falsePositiveRate: falsePositiveRate,
+	}
+
+	return
+}
+
+// ExpectedKeys - Returns the number of keys the filter was sized for
+func (F *Filter) ExpectedKeys() int64 {
+	return F.expectedKeys
+}
+
+// FalsePositiveRate - Returns the false positive rate the filter was sized for
+func (F *Filter) FalsePositiveRate() float64 {
+	return F.falsePositiveRate
+}
+
+// Add - Adds a key to the filter
+//   - key is the key to add
+func (F *Filter) Add(key []byte) {
+	h1, h2 := hashes(key)
+	for i := uint32(0); i < F.k; i++ {
+		bit := (h1 + uint64(i)*h2) % F.m
+		F.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// MayContain - Returns whether a key may have been added to the filter
+//   - key is the key to look for
+//
+// It returns:
+//   - mayContain is false if the key has definitely not been added
+func (F *Filter) MayContain(key []byte) (mayContain bool) {
+	h1, h2 := hashes(key)
+	for i := uint32(0); i < F.k; i++ {
+		bit := (h1 + uint64(i)*h2) % F.m
+		if F.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return
+		}
+	}
+
+	mayContain = true
+
+	return
+}
+
+// Reset - Removes all keys from the filter
+func (F *Filter) Reset() {
+	for i := range F.bits {
+		F.bits[i] = 0
+	}
+}
+
+// Save - Writes the filter to a file, replacing any existing file, with the dirty flag cleared
+//   - fileSystem is the file system holding the file
+//   - fileName is the name of the file
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *Filter) Save(fileSystem vfs.FileSystem, fileName string) (err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while creating Bloom filter file: %s", err)
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	buf := make([]byte, headerLength, headerLength+int64(len(F.bits)))
+	copy(buf, bloomFileHeader)
+	binary.LittleEndian.PutUint64(buf[8:], F.m)
+	binary.LittleEndian.PutUint32(buf[16:], F.k)
+	binary.LittleEndian.PutUint64(buf[20:], uint64(F.expectedKeys))
+	binary.LittleEndian.PutUint64(buf[28:], math.Float64bits(F.falsePositiveRate))
+	buf = append(buf, F.bits...)
+
+	_, err = file.WriteAt(buf, 0)
+	if err != nil {
+		err = fmt.Errorf("error while writing Bloom filter file: %s", err)
+		return
+	}
+
+	err = file.Sync()
+
+	return
+}
+
+// Load - Reads a filter from a file written by Save
+//   - fileSystem is the file system holding the file
+//   - fileName is the name of the file
+//
+// It returns:
+//   - filter is a pointer to the Filter read
+//   - dirty is true if the filter was marked dirty by MarkDirty and not saved since, i.e. it may lack keys
+//   - err is a standard error, if the file could not be read or is not a valid Bloom filter file
+func Load(fileSystem vfs.FileSystem, fileName string) (filter *Filter, dirty bool, err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_RDONLY, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening Bloom filter file: %s", err)
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	header := make([]byte, headerLength)
+	_, err = file.ReadAt(header, 0)
+	if err != nil || string(header[:8]) != string(bloomFileHeader) {
+		err = fmt.Errorf("not a valid Bloom filter file")
+		return
+	}
+
+	f := &Filter{
+		m:                 binary.LittleEndian.Uint64(header[8:]),
+		k:                 binary.LittleEndian.Uint32(header[16:]),
+		expectedKeys:      int64(binary.LittleEndian.Uint64(header[20:])),
+		falsePositiveRate: math.Float64frombits(binary.LittleEndian.Uint64(header[28:])),
+	}
+	if f.m == 0 || f.k == 0 {
+		err = fmt.Errorf("not a valid Bloom filter file")
+		return
+	}
+
+	f.bits = make([]byte, (f.m+7)/8)
+	_, err = file.ReadAt(f.bits, headerLength)
+	if err != nil {
+		err = fmt.Errorf("error while reading Bloom filter file: %s", err)
+		return
+	}
+
+	filter, dirty = f, header[dirtyOffset] != 0
+
+	return
+}
+
+// MarkDirty - Sets the dirty flag of a file written by Save, telling Load that keys added after this point may be
+// missing from the file until it is saved again
+//   - fileSystem is the file system holding the file
+//   - fileName is the name of the file
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func MarkDirty(fileSystem vfs.FileSystem, fileName string) (err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening Bloom filter file: %s", err)
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	_, err = file.WriteAt([]byte{1}, dirtyOffset)
+	if err != nil {
+		err = fmt.Errorf("error while marking Bloom filter file dirty: %s", err)
+		return
+	}
+
+	err = file.Sync()
+
+	return
+}
+
+// hashes - Returns the two hash values used to derive the bits of a key
+func hashes(key []byte) (h1, h2 uint64) {
+	f := fnv.New64a()
+	_, _ = f.Write(key)
+	h1 = f.Sum64()
+
+	g := fnv.New64()
+	_, _ = g.Write(key)
+	h2 = g.Sum64() | 1
+
+	return
+}
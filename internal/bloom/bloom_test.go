@@ -0,0 +1,93 @@
+//go:build unit
+
+package bloom
+
+import (
+	"encoding/binary"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	t.Run("has no false negatives and few false positives", func(t *testing.T) {
+		// Prepare
+		filter := New(1000, 0.01)
+		key := make([]byte, 8)
+
+		// Execute
+		for i := 0; i < 1000; i++ {
+			binary.LittleEndian.PutUint64(key, uint64(i))
+			filter.Add(key)
+		}
+
+		// Check
+		for i := 0; i < 1000; i++ {
+			binary.LittleEndian.PutUint64(key, uint64(i))
+			assert.Truef(t, filter.MayContain(key), "key #%d may be contained", i)
+		}
+		falsePositives := 0
+		for i := 1000; i < 11000; i++ {
+			binary.LittleEndian.PutUint64(key, uint64(i))
+			if filter.MayContain(key) {
+				falsePositives++
+			}
+		}
+		assert.Less(t, falsePositives, 300, "false positive rate close to what was asked for")
+	})
+
+	t.Run("resets to empty", func(t *testing.T) {
+		// Prepare
+		filter := New(10, 0.01)
+		filter.Add([]byte("key"))
+
+		// Execute
+		filter.Reset()
+
+		// Check
+		assert.False(t, filter.MayContain([]byte("key")), "key is gone")
+	})
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Run("loads what was saved and tells whether marked dirty", func(t *testing.T) {
+		// Prepare
+		filter := New(100, 0.05)
+		filter.Add([]byte("key"))
+
+		// Execute
+		err := filter.Save(vfs.OS{}, "testfile")
+		assert.NoError(t, err, "saves filter")
+		loaded, dirtyAfterSave, errLoad := Load(vfs.OS{}, "testfile")
+		err = MarkDirty(vfs.OS{}, "testfile")
+		assert.NoError(t, err, "marks dirty")
+		_, dirtyAfterMark, _ := Load(vfs.OS{}, "testfile")
+
+		// Check
+		assert.NoError(t, errLoad, "loads filter")
+		assert.False(t, dirtyAfterSave, "clean after save")
+		assert.True(t, dirtyAfterMark, "dirty after mark")
+		assert.True(t, loaded.MayContain([]byte("key")), "key is kept")
+		assert.Equal(t, int64(100), loaded.ExpectedKeys(), "expected keys kept")
+		assert.Equal(t, 0.05, loaded.FalsePositiveRate(), "false positive rate kept")
+
+		// Clean up
+		_ = os.Remove("testfile")
+	})
+
+	t.Run("rejects a file that is not a Bloom filter", func(t *testing.T) {
+		// Prepare
+		err := os.WriteFile("testfile", make([]byte, 100), 0644)
+		assert.NoError(t, err, "writes file")
+
+		// Execute
+		_, _, err = Load(vfs.OS{}, "testfile")
+
+		// Check
+		assert.Error(t, err, "not a valid Bloom filter file")
+
+		// Clean up
+		_ = os.Remove("testfile")
+	})
+}
@@ -5,16 +5,17 @@ import (
 	"hash/crc32"
 )
 
-// SeparateChainingHashAlgorithm - The internally used bucket selection algorithm is implemented using crc32.ChecksumIEEE to
+// SeparateChainingHashAlgorithm - The internally used bucket selection algorithm is by default implemented using crc32.ChecksumIEEE to
 // create a hash value over the key and then applying bucket = hash & (actualTableSize - 1) to get the bucket number,
 // where actualTableSize is the nearest bigger exponent of 2 of the requested table size.
 type SeparateChainingHashAlgorithm struct {
 	tableSize int64
+	hash      keyHash
 }
 
 // NewSeparateChainingHashAlgorithm - Returns a pointer to a new SeparateChainingHashAlgorithm instance
 func NewSeparateChainingHashAlgorithm(tableSize int64) *SeparateChainingHashAlgorithm {
-	ha := &SeparateChainingHashAlgorithm{}
+	ha := &SeparateChainingHashAlgorithm{hash: crc32Hash(crc32.IEEETable, 0)}
 	ha.SetTableSize(tableSize)
 	return ha
 }
@@ -28,7 +29,7 @@ func (O *SeparateChainingHashAlgorithm) SetTableSize(tableSize int64) {
 
 // HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
 func (O *SeparateChainingHashAlgorithm) HashFunc1(key []byte) int64 {
-	h := int64(crc32.ChecksumIEEE(key))
+	h := O.hash(key)
 	return h & (O.tableSize - 1)
 }
 
@@ -0,0 +1,61 @@
+package hash
+
+import (
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"hash/crc32"
+)
+
+// castagnoliTable - Table for the Castagnoli polynomial, used by the second hash function in Cuckoo hashing
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CuckooHashAlgorithm - The internally used bucket selection algorithm for Cuckoo hashing. It gives each key two
+// candidate buckets using two independent hash functions, by default crc32 using the IEEE polynomial and crc32 using the
+// Castagnoli polynomial, and applies bucket = hash & (actualTableSize - 1) to both, where actualTableSize is the nearest
+// bigger exponent of 2 of the requested table size.
+type CuckooHashAlgorithm struct {
+	tableSize int64
+	hash1     keyHash
+	hash2     keyHash
+}
+
+// NewCuckooHashAlgorithm - Returns a pointer to a new CuckooHashAlgorithm instance
+// It sets an initial value for the table size but that size may be updated to a new value depending on
+// chosen Collision Probing Algorithm
+func NewCuckooHashAlgorithm(tableSize int64) *CuckooHashAlgorithm {
+	ha := &CuckooHashAlgorithm{hash1: crc32Hash(crc32.IEEETable, 0), hash2: crc32Hash(castagnoliTable, 0)}
+	ha.SetTableSize(tableSize)
+	return ha
+}
+
+// SetTableSize - Sets the table size for the hash algorithm.
+// In this implementation it updates the table size to the nearest bigger exponent of 2 of the requested table size.
+func (C *CuckooHashAlgorithm) SetTableSize(tableSize int64) {
+	C.tableSize = utils.RoundUp2(tableSize)
+}
+
+// HashFunc1 - Given key it generates the first candidate bucket between 0 and table size - 1
+func (C *CuckooHashAlgorithm) HashFunc1(key []byte) int64 {
+	h := C.hash1(key)
+	return h & (C.tableSize - 1)
+}
+
+// HashFunc2 - Given key it generates the second candidate bucket between 0 and table size - 1, which may be the
+// same as the first
+func (C *CuckooHashAlgorithm) HashFunc2(key []byte) int64 {
+	h := C.hash2(key)
+	return h & (C.tableSize - 1)
+}
+
+// GetTableSize - Returns the table size the implemented hash functions are supporting
+func (C *CuckooHashAlgorithm) GetTableSize() int64 {
+	return C.tableSize
+}
+
+// ProbeIteration - Returns the first candidate bucket for even iterations and the second for odd iterations
+func (C *CuckooHashAlgorithm) ProbeIteration(hf1Value, hf2Value, iteration int64) int64 {
+	if iteration%2 == 0 {
+		return hf1Value
+	}
+
+	return hf2Value
+}
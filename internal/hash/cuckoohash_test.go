@@ -0,0 +1,52 @@
+//go:build unit
+
+package hash
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestCuckooHashAlgorithm_GetTableSize(t *testing.T) {
+	t.Run("returns correct table size", func(t *testing.T) {
+		// Prepare
+		h := NewCuckooHashAlgorithm(10)
+
+		// Execute
+		tableSize := h.GetTableSize()
+
+		// Check
+		assert.Equal(t, int64(16), tableSize, "correct tableSize value")
+	})
+}
+
+func TestCuckooHashAlgorithm_HashFunc(t *testing.T) {
+	t.Run("creates two valid and independent bucket numbers", func(t *testing.T) {
+		// Prepare
+		h := NewCuckooHashAlgorithm(1000)
+		tableSize := h.GetTableSize()
+		key := make([]byte, 16)
+
+		// Execute
+		var same int
+		for i := 0; i < 1000; i++ {
+			rand.Read(key)
+			bucketNo1 := h.HashFunc1(key)
+			bucketNo2 := h.HashFunc2(key)
+
+			assert.GreaterOrEqual(t, bucketNo1, int64(0), "first bucket not negative")
+			assert.Less(t, bucketNo1, tableSize, "first bucket less than table size")
+			assert.GreaterOrEqual(t, bucketNo2, int64(0), "second bucket not negative")
+			assert.Less(t, bucketNo2, tableSize, "second bucket less than table size")
+			assert.Equal(t, bucketNo1, h.ProbeIteration(bucketNo1, bucketNo2, 0), "even iteration gives first bucket")
+			assert.Equal(t, bucketNo2, h.ProbeIteration(bucketNo1, bucketNo2, 1), "odd iteration gives second bucket")
+			if bucketNo1 == bucketNo2 {
+				same++
+			}
+		}
+
+		// Check
+		assert.Less(t, same, 10, "buckets rarely coincide")
+	})
+}
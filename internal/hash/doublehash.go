@@ -1,16 +1,20 @@
 package hash
 
-import "hash/crc32"
+import (
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"hash/crc32"
+)
 
-// DoubleHashAlgorithm - The internally used bucket selection algorithm is implemented using crc32.ChecksumIEEE to
+// DoubleHashAlgorithm - The internally used bucket selection algorithm is by default implemented using crc32.ChecksumIEEE to
 // create a hash value over the key and then applying HashFunc1 and HashFunc2 as primary respective probing functions.
 type DoubleHashAlgorithm struct {
 	tableSize int64
+	hash      keyHash
 }
 
 // NewDoubleHashAlgorithm - Returns a pointer to a new DoubleHashAlgorithm instance
 func NewDoubleHashAlgorithm(tableSize int64) *DoubleHashAlgorithm {
-	ha := &DoubleHashAlgorithm{}
+	ha := &DoubleHashAlgorithm{hash: crc32Hash(crc32.IEEETable, 0)}
 	ha.SetTableSize(tableSize)
 	return ha
 }
@@ -26,14 +30,14 @@ func (D *DoubleHashAlgorithm) SetTableSize(tableSize int64) {
 
 // HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
 func (D *DoubleHashAlgorithm) HashFunc1(key []byte) int64 {
-	k := int64(crc32.ChecksumIEEE(key))
+	k := D.hash(key)
 	return k % D.tableSize
 }
 
 // HashFunc2 - Given key it generates an offset probing value that will be used together with the value from HashFunc1 in
 // a call to DoubleHashFunc.
 func (D *DoubleHashAlgorithm) HashFunc2(key []byte) int64 {
-	k := int64(crc32.ChecksumIEEE(key))
+	k := D.hash(key)
 
 	return 1 + ((k / D.tableSize) % (D.tableSize - 1))
 }
@@ -54,28 +58,5 @@ func (D *DoubleHashAlgorithm) ProbeIteration(hf1Value, hf2Value, iteration int64
 // updateToNearestPrime - To ensure that we don't end up in an infinite loop when probing, the easiest way is to
 // ensure the table size is a prime number. This function updates the table size to nearest higher prime number.
 func (D *DoubleHashAlgorithm) updateToNearestPrime() {
-	n := D.tableSize
-
-OUTER:
-	for {
-		if n == 2 || n == 3 {
-			D.tableSize = n
-			return
-		}
-
-		if n <= 1 || n%2 == 0 || n%3 == 0 {
-			n++
-			continue
-		}
-
-		for i := int64(5); i*i <= n; i += 6 {
-			if n%i == 0 || n%(i+2) == 0 {
-				n++
-				continue OUTER
-			}
-		}
-
-		D.tableSize = n
-		return
-	}
+	D.tableSize = utils.NextPrime(D.tableSize)
 }
@@ -0,0 +1,91 @@
+package hash
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"hash/crc32"
+)
+
+// CRC32 - Kind of internal hash algorithm that is built on crc32 using the IEEE polynomial (the default)
+const CRC32 int64 = 0
+
+// SipHash - Kind of internal hash algorithm that is built on SipHash-2-4 keyed by the seed. Since SipHash is a keyed
+// pseudorandom function, a random seed keeps keys from untrusted sources from being chosen to force long probe chains.
+const SipHash int64 = 1
+
+// XXHash - Kind of internal hash algorithm that is built on XXH64 initialized with the seed
+const XXHash int64 = 2
+
+// FNV1a - Kind of internal hash algorithm that is built on 64-bit FNV-1a, with the seed mixed into the offset basis
+const FNV1a int64 = 3
+
+// Murmur3 - Kind of internal hash algorithm that is built on 32-bit MurmurHash3 initialized with the seed
+const Murmur3 int64 = 4
+
+// NewInternalHashAlgorithm - Returns the internal hash algorithm to use for a collision resolution technique.
+// All parameters that affects the outcome of the hash functions are given in params, so calling this function with
+// the same set of arguments (typically from a map file header) will always reconstruct an identical algorithm.
+//   - crtType is the collision resolution technique the algorithm will be used with
+//   - tableSize is the requested table size, the algorithm may round it up (see GetTableSize for actual size)
+//   - params is a model.HashParameters struct with kind and seed for the algorithm
+//
+// It returns:
+//   - hashAlgorithm is the internal hash algorithm
+//   - err is a standard error if the combination of parameters is not supported
+func NewInternalHashAlgorithm(crtType int, tableSize int64, params model.HashParameters) (hashAlgorithm hashfunc.HashAlgorithm, err error) {
+	// Two independent hash functions are prepared, the second one is only used by Cuckoo hashing
+	var hash1, hash2 keyHash
+	switch params.Kind {
+	case CRC32:
+		hash1 = crc32Hash(crc32.IEEETable, uint32(params.Seed))
+		hash2 = crc32Hash(castagnoliTable, uint32(params.Seed))
+	case SipHash:
+		k0 := uint64(params.Seed)
+		k1 := splitMix64(k0)
+		k2 := splitMix64(k1)
+		hash1 = sipHash(k0, k1)
+		hash2 = sipHash(k1, k2)
+	case XXHash:
+		hash1 = xxHash(uint64(params.Seed))
+		hash2 = xxHash(splitMix64(uint64(params.Seed)))
+	case FNV1a:
+		hash1 = fnv1aHash(fnvOffsetBasis64 ^ uint64(params.Seed))
+		hash2 = fnv1aHash(fnvOffsetBasis64 ^ splitMix64(uint64(params.Seed)))
+	case Murmur3:
+		hash1 = murmur3Hash(uint32(params.Seed))
+		hash2 = murmur3Hash(uint32(splitMix64(uint64(params.Seed))))
+	default:
+		err = fmt.Errorf("unsupported internal hash algorithm kind: %d", params.Kind)
+		return
+	}
+
+	switch crtType {
+	case crt.SeparateChaining:
+		ha := NewSeparateChainingHashAlgorithm(tableSize)
+		ha.hash = hash1
+		hashAlgorithm = ha
+	case crt.LinearProbing:
+		ha := NewLinearProbingHashAlgorithm(tableSize)
+		ha.hash = hash1
+		hashAlgorithm = ha
+	case crt.QuadraticProbing:
+		ha := NewQuadraticProbingHashAlgorithm(tableSize)
+		ha.hash = hash1
+		hashAlgorithm = ha
+	case crt.DoubleHashing:
+		ha := NewDoubleHashAlgorithm(tableSize)
+		ha.hash = hash1
+		hashAlgorithm = ha
+	case crt.CuckooHashing:
+		ha := NewCuckooHashAlgorithm(tableSize)
+		ha.hash1 = hash1
+		ha.hash2 = hash2
+		hashAlgorithm = ha
+	default:
+		err = fmt.Errorf("no internal hash algorithm available for collision resolution technique %d", crtType)
+	}
+
+	return
+}
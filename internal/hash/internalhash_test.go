@@ -0,0 +1,169 @@
+//go:build unit
+
+package hash
+
+import (
+	"encoding/binary"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/stretchr/testify/assert"
+	"hash/fnv"
+	"testing"
+)
+
+func TestNewInternalHashAlgorithm(t *testing.T) {
+	t.Run("reconstructs identical algorithms given same parameters", func(t *testing.T) {
+		// Prepare
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		params := model.HashParameters{Kind: CRC32, Seed: 4711}
+
+		for _, crtType := range []int{crt.SeparateChaining, crt.LinearProbing, crt.QuadraticProbing, crt.DoubleHashing, crt.CuckooHashing} {
+			// Execute
+			h1, err1 := NewInternalHashAlgorithm(crtType, 100, params)
+			h2, err2 := NewInternalHashAlgorithm(crtType, 100, params)
+
+			// Check
+			assert.NoError(t, err1, "creates first algorithm")
+			assert.NoError(t, err2, "creates second algorithm")
+			assert.Equal(t, h1.GetTableSize(), h2.GetTableSize(), "same table size")
+			assert.Equal(t, h1.HashFunc1(key), h2.HashFunc1(key), "same hash value")
+			assert.Equal(t, h1.HashFunc2(key), h2.HashFunc2(key), "same probing value")
+		}
+	})
+
+	t.Run("zero seed gives same result as plain algorithm", func(t *testing.T) {
+		// Prepare
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+		// Execute
+		h, err := NewInternalHashAlgorithm(crt.LinearProbing, 10, model.HashParameters{})
+
+		// Check
+		assert.NoError(t, err, "creates algorithm")
+		assert.Equal(t, NewLinearProbingHashAlgorithm(10).HashFunc1(key), h.HashFunc1(key), "same hash value")
+	})
+
+	t.Run("error on unsupported kind or crt", func(t *testing.T) {
+		// Execute
+		_, err := NewInternalHashAlgorithm(crt.LinearProbing, 10, model.HashParameters{Kind: 99})
+
+		// Check
+		assert.Error(t, err, "unsupported kind")
+
+		// Execute
+		_, err = NewInternalHashAlgorithm(0, 10, model.HashParameters{})
+
+		// Check
+		assert.Error(t, err, "unsupported crt")
+	})
+}
+
+func TestSipHash(t *testing.T) {
+	t.Run("matches reference vectors", func(t *testing.T) {
+		// Prepare
+		k0 := uint64(0x0706050403020100)
+		k1 := uint64(0x0f0e0d0c0b0a0908)
+		msg := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+
+		// Execute and check
+		assert.Equal(t, uint64(0x726fdb47dd0e0e31), sipHash24(k0, k1, nil), "empty message")
+		assert.Equal(t, uint64(0xa129ca6149be45e5), sipHash24(k0, k1, msg), "15 byte message")
+		assert.Equal(t, uint64(0x93f5f5799a932462), sipHash24(k0, k1, msg[:8]), "8 byte message")
+	})
+
+	t.Run("seed changes buckets", func(t *testing.T) {
+		// Prepare
+		h1, err := NewInternalHashAlgorithm(crt.SeparateChaining, 1<<20, model.HashParameters{Kind: SipHash, Seed: 1})
+		assert.NoError(t, err, "creates first algorithm")
+		h2, err := NewInternalHashAlgorithm(crt.SeparateChaining, 1<<20, model.HashParameters{Kind: SipHash, Seed: 2})
+		assert.NoError(t, err, "creates second algorithm")
+
+		// Execute
+		var same int
+		for i := 0; i < 100; i++ {
+			key := []byte{0, 0, 0, byte(i)}
+			if h1.HashFunc1(key) == h2.HashFunc1(key) {
+				same++
+			}
+		}
+
+		// Check
+		assert.Less(t, same, 5, "different seeds give different buckets")
+	})
+
+	t.Run("gives independent cuckoo hash functions", func(t *testing.T) {
+		// Prepare
+		h, err := NewInternalHashAlgorithm(crt.CuckooHashing, 1<<20, model.HashParameters{Kind: SipHash, Seed: 4711})
+		assert.NoError(t, err, "creates algorithm")
+
+		// Execute
+		var same int
+		for i := 0; i < 100; i++ {
+			key := []byte{0, 0, 0, byte(i)}
+			if h.HashFunc1(key) == h.HashFunc2(key) {
+				same++
+			}
+		}
+
+		// Check
+		assert.Less(t, same, 5, "different candidate buckets")
+	})
+}
+
+func TestBuiltinHashFunctions(t *testing.T) {
+	t.Run("xxhash matches reference vectors", func(t *testing.T) {
+		// Execute and check
+		assert.Equal(t, uint64(0xef46db3751d8e999), xxHash64(0, nil), "empty message")
+		assert.Equal(t, uint64(0xd24ec4f1a98c6e5b), xxHash64(0, []byte("a")), "1 byte message")
+		assert.Equal(t, uint64(0x44bc2cf5ad770999), xxHash64(0, []byte("abc")), "3 byte message")
+		assert.Equal(t, uint64(0xfbcea83c8a378bf1), xxHash64(0, []byte("Nobody inspects the spammish repetition")), "long message")
+	})
+
+	t.Run("murmur3 matches reference vectors", func(t *testing.T) {
+		// Prepare
+		word := make([]byte, 4)
+		binary.LittleEndian.PutUint32(word, 0x87654321)
+
+		// Execute and check
+		assert.Equal(t, uint32(0), murmur3x86x32(0, nil), "empty message")
+		assert.Equal(t, uint32(0x514e28b7), murmur3x86x32(1, nil), "empty message with seed")
+		assert.Equal(t, uint32(0xf55b516b), murmur3x86x32(0, word), "4 byte message")
+		assert.Equal(t, uint32(0x7e4a8634), murmur3x86x32(0, word[:3]), "3 byte message")
+		assert.Equal(t, uint32(0xa0f7b07a), murmur3x86x32(0, word[:2]), "2 byte message")
+		assert.Equal(t, uint32(0x72661cf4), murmur3x86x32(0, word[:1]), "1 byte message")
+		assert.Equal(t, uint32(0x24884cba), murmur3x86x32(0x9747b28c, []byte("Hello, world!")), "long message")
+	})
+
+	t.Run("fnv-1a without seed matches standard library", func(t *testing.T) {
+		// Prepare
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		h := fnv.New64a()
+		_, _ = h.Write(key)
+
+		// Execute and check
+		assert.Equal(t, int64(h.Sum64()>>1), fnv1aHash(fnvOffsetBasis64)(key), "same hash value")
+	})
+
+	t.Run("all kinds spread keys over buckets", func(t *testing.T) {
+		for _, kind := range []int64{CRC32, SipHash, XXHash, FNV1a, Murmur3} {
+			for _, crtType := range []int{crt.SeparateChaining, crt.DoubleHashing, crt.CuckooHashing} {
+				// Prepare
+				h, err := NewInternalHashAlgorithm(crtType, 64, model.HashParameters{Kind: kind, Seed: 4711})
+				assert.NoErrorf(t, err, "creates algorithm of kind %d", kind)
+
+				// Execute
+				buckets := make(map[int64]bool)
+				for i := 0; i < 1000; i++ {
+					key := []byte{0, 0, byte(i >> 8), byte(i)}
+					b1, b2 := h.HashFunc1(key), h.HashFunc2(key)
+					assert.Truef(t, b1 >= 0 && b1 < h.GetTableSize(), "bucket of kind %d within table", kind)
+					assert.GreaterOrEqualf(t, b2, int64(0), "second hash value of kind %d non-negative", kind)
+					buckets[b1] = true
+				}
+
+				// Check
+				assert.Greaterf(t, len(buckets), int(h.GetTableSize())*9/10, "keys of kind %d spread over buckets", kind)
+			}
+		}
+	})
+}
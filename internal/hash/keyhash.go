@@ -0,0 +1,226 @@
+package hash
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+)
+
+// keyHash - Is a hash function over a key, returning a non-negative hash value that the internal hash algorithms
+// derive buckets from
+type keyHash func(key []byte) int64
+
+// crc32Hash - Returns a keyHash using crc32 with the polynomial of table, initialized with seed
+func crc32Hash(table *crc32.Table, seed uint32) keyHash {
+	return func(key []byte) int64 {
+		return int64(crc32.Update(seed, table, key))
+	}
+}
+
+// sipHash - Returns a keyHash using SipHash-2-4 keyed by k0 and k1. SipHash is a keyed pseudorandom function, so
+// unless the keys are known it is not feasible to construct a set of keys that all end up in the same buckets.
+// The top bit of the 64-bit hash value is dropped to keep it non-negative.
+func sipHash(k0, k1 uint64) keyHash {
+	return func(key []byte) int64 {
+		return int64(sipHash24(k0, k1, key) >> 1)
+	}
+}
+
+// sipHash24 - Returns the SipHash-2-4 hash value of p keyed by k0 and k1
+func sipHash24(k0, k1 uint64, p []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(p)
+	for ; len(p) >= 8; p = p[8:] {
+		m := binary.LittleEndian.Uint64(p)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	// The last block holds the remaining bytes and the length of the message in its top byte
+	m := uint64(n) << 56
+	for i := len(p) - 1; i >= 0; i-- {
+		m |= uint64(p[i]) << (8 * i)
+	}
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// splitMix64 - Returns the next value of the SplitMix64 sequence after x, used to derive additional keys from a seed
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+
+	return x ^ (x >> 31)
+}
+
+// xxHash - Returns a keyHash using XXH64 initialized with seed, dropping the top bit to keep it non-negative
+func xxHash(seed uint64) keyHash {
+	return func(key []byte) int64 {
+		return int64(xxHash64(seed, key) >> 1)
+	}
+}
+
+// fnv1aHash - Returns a keyHash using 64-bit FNV-1a starting from offsetBasis, dropping the top bit to keep it
+// non-negative
+func fnv1aHash(offsetBasis uint64) keyHash {
+	return func(key []byte) int64 {
+		h := offsetBasis
+		for _, b := range key {
+			h ^= uint64(b)
+			h *= fnvPrime64
+		}
+		return int64(h >> 1)
+	}
+}
+
+// murmur3Hash - Returns a keyHash using 32-bit MurmurHash3 initialized with seed
+func murmur3Hash(seed uint32) keyHash {
+	return func(key []byte) int64 {
+		return int64(murmur3x86x32(seed, key))
+	}
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+
+	fnvOffsetBasis64 uint64 = 14695981039346656037
+	fnvPrime64       uint64 = 1099511628211
+
+	murmurC1 uint32 = 0xcc9e2d51
+	murmurC2 uint32 = 0x1b873593
+)
+
+// xxHash64 - Returns the XXH64 hash value of p initialized with seed
+func xxHash64(seed uint64, p []byte) uint64 {
+	round := func(acc, input uint64) uint64 {
+		acc += input * xxPrime2
+		acc = bits.RotateLeft64(acc, 31)
+		return acc * xxPrime1
+	}
+	mergeRound := func(acc, val uint64) uint64 {
+		acc ^= round(0, val)
+		return acc*xxPrime1 + xxPrime4
+	}
+
+	n := len(p)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; len(p) >= 32; p = p[32:] {
+			v1 = round(v1, binary.LittleEndian.Uint64(p))
+			v2 = round(v2, binary.LittleEndian.Uint64(p[8:]))
+			v3 = round(v3, binary.LittleEndian.Uint64(p[16:]))
+			v4 = round(v4, binary.LittleEndian.Uint64(p[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = mergeRound(h, v1)
+		h = mergeRound(h, v2)
+		h = mergeRound(h, v3)
+		h = mergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += uint64(n)
+
+	for ; len(p) >= 8; p = p[8:] {
+		h ^= round(0, binary.LittleEndian.Uint64(p))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(p) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(p)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		h ^= uint64(b) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+
+	return h
+}
+
+// murmur3x86x32 - Returns the 32-bit MurmurHash3 (x86_32) hash value of p initialized with seed
+func murmur3x86x32(seed uint32, p []byte) uint32 {
+	mix := func(k uint32) uint32 {
+		k *= murmurC1
+		k = bits.RotateLeft32(k, 15)
+		return k * murmurC2
+	}
+
+	n := len(p)
+	h := seed
+	for ; len(p) >= 4; p = p[4:] {
+		h ^= mix(binary.LittleEndian.Uint32(p))
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(p) {
+	case 3:
+		k ^= uint32(p[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(p[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(p[0])
+		h ^= mix(k)
+	}
+
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
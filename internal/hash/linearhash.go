@@ -5,18 +5,19 @@ import (
 	"hash/crc32"
 )
 
-// LinearProbingHashAlgorithm - The internally used bucket selection algorithm is implemented using crc32.ChecksumIEEE to
+// LinearProbingHashAlgorithm - The internally used bucket selection algorithm is by default implemented using crc32.ChecksumIEEE to
 // create a hash value over the key and then applying bucket = hash & (actualTableSize - 1) to get the bucket number,
 // where actualTableSize is the nearest bigger exponent of 2 of the requested table size.
 type LinearProbingHashAlgorithm struct {
 	tableSize int64
+	hash      keyHash
 }
 
 // NewLinearProbingHashAlgorithm - Returns a pointer to a new LinearProbingHashAlgorithm instance
 // It sets an initial value for the table size but that size may be updated to a new value depending on
 // chosen Collision Probing Algorithm
 func NewLinearProbingHashAlgorithm(tableSize int64) *LinearProbingHashAlgorithm {
-	ha := &LinearProbingHashAlgorithm{}
+	ha := &LinearProbingHashAlgorithm{hash: crc32Hash(crc32.IEEETable, 0)}
 	ha.SetTableSize(tableSize)
 	return ha
 }
@@ -29,7 +30,7 @@ func (L *LinearProbingHashAlgorithm) SetTableSize(tableSize int64) {
 
 // HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
 func (L *LinearProbingHashAlgorithm) HashFunc1(key []byte) int64 {
-	h := int64(crc32.ChecksumIEEE(key))
+	h := L.hash(key)
 	return h & (L.tableSize - 1)
 }
 
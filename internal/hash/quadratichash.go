@@ -5,17 +5,18 @@ import (
 	"hash/crc32"
 )
 
-// QuadraticProbingHashAlgorithm - The internally used bucket selection algorithm is implemented using crc32.ChecksumIEEE to
+// QuadraticProbingHashAlgorithm - The internally used bucket selection algorithm is by default implemented using crc32.ChecksumIEEE to
 // create a hash value over the key and then applying bucket = hash & (actualTableSize - 1) to get the bucket number,
 // where actualTableSize is the nearest bigger exponent of 2 of the requested table size.
 type QuadraticProbingHashAlgorithm struct {
 	tableSize int64
+	hash      keyHash
 	roundUp2  int64
 }
 
 // NewQuadraticProbingHashAlgorithm - Returns a pointer to a new QuadraticProbingHashAlgorithm instance
 func NewQuadraticProbingHashAlgorithm(tableSize int64) *QuadraticProbingHashAlgorithm {
-	ha := &QuadraticProbingHashAlgorithm{}
+	ha := &QuadraticProbingHashAlgorithm{hash: crc32Hash(crc32.IEEETable, 0)}
 	ha.SetTableSize(tableSize)
 	return ha
 }
@@ -31,7 +32,7 @@ func (Q *QuadraticProbingHashAlgorithm) SetTableSize(tableSize int64) {
 
 // HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
 func (Q *QuadraticProbingHashAlgorithm) HashFunc1(key []byte) int64 {
-	h := int64(crc32.ChecksumIEEE(key))
+	h := Q.hash(key)
 	return h & (Q.tableSize - 1)
 }
 
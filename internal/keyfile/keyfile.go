@@ -0,0 +1,146 @@
+package keyfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+)
+
+// keyFileHeader - Is the header of a key file, which also makes sure no key is stored at address zero
+var keyFileHeader = []byte("FHMKEYS1")
+
+// keyLengthLength - Length of the length field in front of each key - 4 bytes
+const keyLengthLength int64 = 4
+
+// KeyFile - Is an append only file of keys of arbitrary length, each key is identified by its address in the file.
+// Keys that are no longer referred to are left in place until the file is rewritten, e.g. when reorganizing files.
+type KeyFile struct {
+	file vfs.File
+	size int64
+}
+
+// Open - Opens an existing key file or creates a new one if it doesn't exist
+//   - fileSystem is the file system holding the key file
+//   - fileName is the name of the key file
+//
+// It returns:
+//   - keyFile is a pointer to the opened KeyFile
+//   - err is a standard error, if something went wrong
+func Open(fileSystem vfs.FileSystem, fileName string) (keyFile *KeyFile, err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while open/create key file: %s", err)
+		return
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return
+	}
+
+	keyFile = &KeyFile{file: file, size: stat.Size()}
+
+	if keyFile.size == 0 {
+		err = keyFile.Clear()
+		if err != nil {
+			_ = file.Close()
+			keyFile = nil
+		}
+		return
+	}
+
+	buf := make([]byte, len(keyFileHeader))
+	_, err = file.ReadAt(buf, 0)
+	if err != nil || string(buf) != string(keyFileHeader) {
+		_ = file.Close()
+		keyFile = nil
+		err = fmt.Errorf("not a valid key file")
+		return
+	}
+
+	return
+}
+
+// Close - Closes the key file
+func (K *KeyFile) Close() {
+	if K.file != nil {
+		_ = K.file.Close()
+		K.file = nil
+	}
+}
+
+// Sync - Commits the current contents of the key file to stable storage
+func (K *KeyFile) Sync() (err error) {
+	err = K.file.Sync()
+
+	return
+}
+
+// Append - Appends a key to the key file
+//
+// It returns:
+//   - address is the address of the key in the key file
+//   - err is a standard error, if something went wrong
+func (K *KeyFile) Append(key []byte) (address int64, err error) {
+	buf := make([]byte, keyLengthLength, keyLengthLength+int64(len(key)))
+	binary.LittleEndian.PutUint32(buf, uint32(len(key)))
+	buf = append(buf, key...)
+
+	_, err = K.file.WriteAt(buf, K.size)
+	if err != nil {
+		return
+	}
+
+	address = K.size
+	K.size += int64(len(buf))
+
+	return
+}
+
+// Read - Reads the key at address in the key file
+//
+// It returns:
+//   - key is the key found at address
+//   - err is a standard error, if address doesn't point to a key or something went wrong
+func (K *KeyFile) Read(address int64) (key []byte, err error) {
+	if address < int64(len(keyFileHeader)) || address+keyLengthLength > K.size {
+		err = fmt.Errorf("key address %d is out of range", address)
+		return
+	}
+
+	buf := make([]byte, keyLengthLength)
+	_, err = K.file.ReadAt(buf, address)
+	if err != nil {
+		return
+	}
+
+	keyLength := int64(binary.LittleEndian.Uint32(buf))
+	if address+keyLengthLength+keyLength > K.size {
+		err = fmt.Errorf("key at address %d is out of range", address)
+		return
+	}
+
+	key = make([]byte, keyLength)
+	_, err = K.file.ReadAt(key, address+keyLengthLength)
+
+	return
+}
+
+// Clear - Discards all keys from the key file
+func (K *KeyFile) Clear() (err error) {
+	err = K.file.Truncate(0)
+	if err != nil {
+		return
+	}
+
+	_, err = K.file.WriteAt(keyFileHeader, 0)
+	if err != nil {
+		return
+	}
+
+	K.size = int64(len(keyFileHeader))
+
+	return
+}
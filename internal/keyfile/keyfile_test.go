@@ -0,0 +1,51 @@
+//go:build unit
+
+package keyfile
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestKeyFile(t *testing.T) {
+	t.Run("appends, reads and clears keys", func(t *testing.T) {
+		// Prepare
+		k, err := Open(vfs.OS{}, "testfile")
+		assert.NoError(t, err, "creates key file")
+
+		// Execute
+		address1, err := k.Append([]byte("https://example.com/a"))
+		assert.NoError(t, err, "appends first key")
+		address2, err := k.Append([]byte{})
+		assert.NoError(t, err, "appends empty key")
+
+		// Check
+		assert.NotEqual(t, address1, address2, "keys have different addresses")
+
+		k.Close()
+		k, err = Open(vfs.OS{}, "testfile")
+		assert.NoError(t, err, "opens existing key file")
+
+		key, err := k.Read(address1)
+		assert.NoError(t, err, "reads first key")
+		assert.Equal(t, []byte("https://example.com/a"), key, "first key preserved")
+		key, err = k.Read(address2)
+		assert.NoError(t, err, "reads empty key")
+		assert.Empty(t, key, "empty key preserved")
+
+		_, err = k.Read(0)
+		assert.Error(t, err, "address in header rejected")
+
+		err = k.Clear()
+		assert.NoError(t, err, "clears key file")
+		_, err = k.Read(address1)
+		assert.Error(t, err, "cleared key not found")
+
+		// Clean up
+		k.Close()
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+}
@@ -1,6 +1,9 @@
 package model
 
-import "github.com/gostonefire/filehashmap/hashfunc"
+import (
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/vfs"
+)
 
 // RecordEmpty - State indicating a record that is or has never been in use
 const RecordEmpty uint8 = 0
@@ -11,20 +14,60 @@ const RecordOccupied uint8 = 1
 // RecordDeleted - State indicating a record that has been in use but was deleted
 const RecordDeleted uint8 = 2
 
+// MaxAccessCount - Max value of the saturating access counter kept in the upper bits of a record state byte
+const MaxAccessCount uint8 = 63
+
+// recordStateMask - Bit mask for the state part of a record state byte, the remaining upper bits are the access counter
+const recordStateMask uint8 = 0x03
+
+// accessCountShift - Number of bits to shift the access counter within a record state byte
+const accessCountShift = 2
+
+// ToStateByte - Combines a record state and an access count into the state byte that is stored in file
+func ToStateByte(state, accessCount uint8) uint8 {
+	if accessCount > MaxAccessCount {
+		accessCount = MaxAccessCount
+	}
+
+	return state&recordStateMask | accessCount<<accessCountShift
+}
+
+// FromStateByte - Splits a state byte as stored in file into record state and access count
+func FromStateByte(stateByte uint8) (state, accessCount uint8) {
+	return stateByte & recordStateMask, stateByte >> accessCountShift
+}
+
+// Metrics - Receiver of metrics counted by the file management implementations, see SetMetrics of each
+type Metrics interface {
+	// AddProbeIterations - Is called with the number of buckets, and overflow records, read while looking for a key
+	AddProbeIterations(n int64)
+	// AddOverflowAppends - Is called with the number of records added to overflow chains
+	AddOverflowAppends(n int64)
+	// AddBytesRead - Is called with the number of bytes read from a file
+	AddBytesRead(n int64)
+	// AddBytesWritten - Is called with the number of bytes written to a file
+	AddBytesWritten(n int64)
+	// AddRetries - Is called with the number of file operations retried after a transient error
+	AddRetries(n int64)
+}
+
 // Bucket - Represents all records in a bucket (both assigned and still not in use)
 type Bucket struct {
 	Records         []Record
 	BucketAddress   int64
 	OverflowAddress int64
 	HasOverflow     bool
+	Neighborhood    uint32
 }
 
 // Record - Represents one record in a bucket
 type Record struct {
 	State         uint8
+	AccessCount   uint8
 	IsOverflow    bool
 	RecordAddress int64
 	NextOverflow  int64
+	Displacement  int64
 	Key           []byte
 	Value         []byte
 }
@@ -39,6 +82,17 @@ type StorageParameters struct {
 	RecordsPerBucket             int64
 	MapFileSize                  int64
 	InternalAlgorithm            bool
+	HashParameters               HashParameters
+	MutationSeq                  int64
+}
+
+// HashParameters - Represents the parameters needed to reconstruct an internal hash algorithm identically when
+// opening existing files. They are persisted in the map file header.
+//   - Kind is the kind of hash function the internal algorithm is built on
+//   - Seed is the value the hash function is initialized with
+type HashParameters struct {
+	Kind int64
+	Seed int64
 }
 
 // CRTConf - Is a struct to be passed in the call to NewXXFiles and contains configuration that affects
@@ -49,6 +103,8 @@ type StorageParameters struct {
 //   - KeyLength is the fixed length of keys to store
 //   - ValueLength is the fixed length of values to store
 //   - HashAlgorithm is the hash function(s) to use
+//   - HashParameters is the parameters to use for an internal hash algorithm, ignored if HashAlgorithm is given
+//   - FileSystem is the file system to create files in, nil for the file system of the operating system
 type CRTConf struct {
 	Name                         string
 	NumberOfBucketsNeeded        int64
@@ -57,4 +113,6 @@ type CRTConf struct {
 	ValueLength                  int64
 	CollisionResolutionTechnique int
 	HashAlgorithm                hashfunc.HashAlgorithm
+	HashParameters               HashParameters
+	FileSystem                   vfs.FileSystem
 }
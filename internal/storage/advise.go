@@ -0,0 +1,9 @@
+package storage
+
+// Access pattern advice values given to Advise, the values are the ones used by posix_fadvise on Linux
+const (
+	AdviceNormal     int = 0
+	AdviceRandom     int = 1
+	AdviceSequential int = 2
+	AdviceDontNeed   int = 4
+)
@@ -0,0 +1,29 @@
+//go:build linux && (amd64 || arm64)
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"syscall"
+)
+
+// Advise - Declares the expected access pattern for the entire file to the kernel using posix_fadvise.
+// Files not of the operating system, see vfs.FileSystem, are not advised on.
+//   - file is the file to give advice on
+//   - advice is one of AdviceNormal, AdviceRandom, AdviceSequential or AdviceDontNeed
+//
+// It returns:
+//   - err is a standard error, if the advice was rejected
+func Advise(file vfs.File, advice int) (err error) {
+	osFile, ok := OSFile(file)
+	if !ok {
+		return
+	}
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, osFile.Fd(), 0, 0, uintptr(advice), 0, 0)
+	if errno != 0 {
+		err = errno
+	}
+
+	return
+}
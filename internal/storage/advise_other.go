@@ -0,0 +1,12 @@
+//go:build !(linux && (amd64 || arm64))
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// Advise - Does nothing on platforms where access pattern advice is not supported
+func Advise(file vfs.File, advice int) (err error) {
+	return
+}
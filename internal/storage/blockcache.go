@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"container/list"
+)
+
+// BlockCache - Is a least recently used cache of blocks read from a file, e.g. buckets, holding copies of the blocks
+// within a budget of bytes. When adding a block would exceed the budget, the least recently used blocks are evicted.
+// All methods can be called on a nil BlockCache, which caches nothing, so callers need not check whether caching is
+// turned on.
+type BlockCache struct {
+	budget  int64
+	size    int64
+	order   *list.List
+	entries map[int64]*list.Element
+	hits    int64
+	misses  int64
+}
+
+// blockCacheEntry - Is a block held in the cache together with its block number
+type blockCacheEntry struct {
+	blockNo int64
+	data    []byte
+}
+
+// NewBlockCache - Returns a pointer to a new empty BlockCache
+//   - budget is the maximum number of bytes of blocks to hold
+//
+// It returns:
+//   - blockCache is a pointer to the new BlockCache
+func NewBlockCache(budget int64) (blockCache *BlockCache) {
+	blockCache = &BlockCache{
+		budget:  budget,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+
+	return
+}
+
+// Get - Copies a cached block into buf and marks it as most recently used
+//   - blockNo is the number of the block
+//   - buf is the buffer to copy the block into, it must be as long as the block
+//
+// It returns:
+//   - ok is true if the block was in the cache
+func (B *BlockCache) Get(blockNo int64, buf []byte) (ok bool) {
+	if B == nil {
+		return
+	}
+
+	element, ok := B.entries[blockNo]
+	if !ok || len(element.Value.(*blockCacheEntry).data) != len(buf) {
+		B.misses++
+		ok = false
+		return
+	}
+
+	B.hits++
+	B.order.MoveToFront(element)
+	copy(buf, element.Value.(*blockCacheEntry).data)
+
+	return
+}
+
+// Put - Adds a copy of a block to the cache as most recently used, evicting the least recently used blocks as needed
+// to stay within budget. A block larger than the budget is not cached.
+//   - blockNo is the number of the block
+//   - data is the content of the block
+func (B *BlockCache) Put(blockNo int64, data []byte) {
+	if B == nil || int64(len(data)) > B.budget {
+		return
+	}
+
+	B.Invalidate(blockNo)
+
+	for B.size+int64(len(data)) > B.budget {
+		oldest := B.order.Back()
+		B.remove(oldest)
+	}
+
+	entry := &blockCacheEntry{blockNo: blockNo, data: append([]byte(nil), data...)}
+	B.entries[blockNo] = B.order.PushFront(entry)
+	B.size += int64(len(data))
+}
+
+// Invalidate - Removes a block from the cache, which has to be done whenever the block is changed in the file
+//   - blockNo is the number of the block
+func (B *BlockCache) Invalidate(blockNo int64) {
+	if B == nil {
+		return
+	}
+
+	if element, ok := B.entries[blockNo]; ok {
+		B.remove(element)
+	}
+}
+
+// Clear - Removes all blocks from the cache
+func (B *BlockCache) Clear() {
+	if B == nil {
+		return
+	}
+
+	B.order.Init()
+	B.entries = make(map[int64]*list.Element)
+	B.size = 0
+}
+
+// Stats - Returns the number of blocks found and not found in the cache since it was created
+//
+// It returns:
+//   - hits is the number of calls to Get that found the block
+//   - misses is the number of calls to Get that didn't find the block
+func (B *BlockCache) Stats() (hits, misses int64) {
+	if B == nil {
+		return
+	}
+
+	hits, misses = B.hits, B.misses
+
+	return
+}
+
+// remove - Removes an element from the cache
+func (B *BlockCache) remove(element *list.Element) {
+	entry := B.order.Remove(element).(*blockCacheEntry)
+	delete(B.entries, entry.blockNo)
+	B.size -= int64(len(entry.data))
+}
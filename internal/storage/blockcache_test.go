@@ -0,0 +1,70 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBlockCache(t *testing.T) {
+	t.Run("evicts least recently used blocks to stay within budget", func(t *testing.T) {
+		// Prepare
+		cache := NewBlockCache(8)
+		buf := make([]byte, 4)
+
+		// Execute
+		cache.Put(1, []byte{1, 1, 1, 1})
+		cache.Put(2, []byte{2, 2, 2, 2})
+		okFirst := cache.Get(1, buf)
+		cache.Put(3, []byte{3, 3, 3, 3})
+		okEvicted := cache.Get(2, make([]byte, 4))
+		okKept := cache.Get(1, buf)
+		hits, misses := cache.Stats()
+
+		// Check
+		assert.True(t, okFirst, "block 1 cached")
+		assert.False(t, okEvicted, "least recently used block 2 evicted")
+		assert.True(t, okKept, "recently used block 1 kept")
+		assert.Equal(t, []byte{1, 1, 1, 1}, buf, "block 1 content")
+		assert.Equal(t, int64(2), hits, "hits counted")
+		assert.Equal(t, int64(1), misses, "misses counted")
+	})
+
+	t.Run("invalidates and clears blocks and keeps copies", func(t *testing.T) {
+		// Prepare
+		cache := NewBlockCache(100)
+		data := []byte{1, 2, 3}
+		cache.Put(1, data)
+		cache.Put(2, data)
+		data[0] = 9
+
+		// Execute
+		buf := make([]byte, 3)
+		okCopy := cache.Get(1, buf)
+		cache.Invalidate(1)
+		okInvalidated := cache.Get(1, make([]byte, 3))
+		cache.Clear()
+		okCleared := cache.Get(2, make([]byte, 3))
+
+		// Check
+		assert.True(t, okCopy, "block cached")
+		assert.Equal(t, []byte{1, 2, 3}, buf, "cache holds a copy")
+		assert.False(t, okInvalidated, "invalidated block gone")
+		assert.False(t, okCleared, "cleared block gone")
+	})
+
+	t.Run("caches nothing when nil or block exceeds budget", func(t *testing.T) {
+		// Prepare
+		var nilCache *BlockCache
+		cache := NewBlockCache(2)
+
+		// Execute
+		nilCache.Put(1, []byte{1})
+		cache.Put(1, []byte{1, 2, 3})
+
+		// Check
+		assert.False(t, nilCache.Get(1, make([]byte, 1)), "nil cache caches nothing")
+		assert.False(t, cache.Get(1, make([]byte, 3)), "block exceeding budget not cached")
+	})
+}
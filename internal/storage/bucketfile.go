@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+	"time"
+)
+
+// BucketFile - Is the map file of a collision resolution technique keeping all its records in one file of buckets, and
+// implements the parts of file management that concern the file as a whole rather than its buckets. It is embedded in
+// the file implementations of such techniques, which read and write their buckets through File and call the functions
+// set by the caller, i.e. IsExpired, Progress and Interrupt, and count probes in Metrics.
+type BucketFile struct {
+	FileName    string
+	FileSystem  vfs.FileSystem
+	File        vfs.File
+	FileSize    int64
+	MutationSeq int64
+	IsExpired   func(value []byte) bool
+	Progress    func(bucketNo int64)
+	Metrics     model.Metrics
+	Interrupt   func() error
+}
+
+// CreateFile - Creates a new map file and writes header to it.
+// If it already exists it will first be truncated to zero length and then to FileSize,
+// hence deleting all existing data.
+//   - header is the header to write
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (B *BucketFile) CreateFile(header Header) (err error) {
+	B.File, err = B.FileSystem.OpenFile(B.FileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while open/create new map file: %s", err)
+		return
+	}
+	err = B.File.Truncate(B.FileSize)
+	if err != nil {
+		_ = B.File.Close()
+		B.File = nil
+		err = fmt.Errorf("error while truncate new map file to length %d: %s", B.FileSize, err)
+		return
+	}
+
+	err = SetHeader(B.File, header)
+	if err != nil {
+		err = fmt.Errorf("error while writing header to map file: %s", err)
+		return
+	}
+
+	return
+}
+
+// OpenFile - Opens the existing map file and does some rudimentary checks of its validity, after which FileSize and
+// MutationSeq are set from its header
+//
+// It returns:
+//   - header is the header read from the map file
+//   - err is a standard error, if the file doesn't exist, doesn't have a valid header or has the wrong size
+func (B *BucketFile) OpenFile() (header Header, err error) {
+	stat, err := B.FileSystem.Stat(B.FileName)
+	if err != nil {
+		err = fmt.Errorf("hash map file not found")
+		return
+	}
+
+	B.File, err = B.FileSystem.OpenFile(B.FileName, os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("unable to open existing hash map file: %s", err)
+		return
+	}
+
+	header, err = GetHeader(B.File)
+	if err != nil {
+		_ = B.File.Close()
+		B.File = nil
+		err = fmt.Errorf("unable to read header from hash map file: %s", err)
+		return
+	}
+
+	if stat.Size() != header.FileSize {
+		_ = B.File.Close()
+		B.File = nil
+		err = fmt.Errorf("actual file size doesn't conform with header indicated file size")
+		return
+	}
+
+	B.FileSize = header.FileSize
+	B.MutationSeq = header.MutationSeq
+
+	return
+}
+
+// ClearFile - Removes all records by discarding everything after the header in the map file and then extending it to
+// its full size again. The header is rewritten, which resets the mutation sequence number, while the system area is
+// kept.
+//   - header is the header to write
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (B *BucketFile) ClearFile(header Header) (err error) {
+	err = ClearFile(B.File, MapFileHeaderLength, B.FileSize)
+	if err != nil {
+		err = fmt.Errorf("error while clearing map file: %s", err)
+		return
+	}
+	err = SetHeader(B.File, header)
+	if err != nil {
+		err = fmt.Errorf("error while writing header to map file: %s", err)
+		return
+	}
+
+	B.MutationSeq = 0
+
+	return
+}
+
+// CloseFiles - Closes the map file
+func (B *BucketFile) CloseFiles() {
+	if B.File != nil {
+		_ = B.File.Sync()
+		_ = B.File.Close()
+	}
+}
+
+// RemoveFiles - Removes the map file, make sure to close it first before calling this function
+func (B *BucketFile) RemoveFiles() (err error) {
+	// Only try to remove if exists, and are not by accident directories (could happen when testing things out)
+	if stat, ok := B.FileSystem.Stat(B.FileName); ok == nil {
+		if !stat.IsDir() {
+			err = B.FileSystem.Remove(B.FileName)
+			if err != nil {
+				err = fmt.Errorf("error while removing map file: %s", err)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// Sync - Commits the current contents of the map file to stable storage
+func (B *BucketFile) Sync() (err error) {
+	err = B.File.Sync()
+	if err != nil {
+		err = fmt.Errorf("error while syncing map file: %s", err)
+	}
+
+	return
+}
+
+// Advise - Declares the expected access pattern of the map file to the operating system, see Advise
+func (B *BucketFile) Advise(advice int) (err error) {
+	err = Advise(B.File, advice)
+	if err != nil {
+		err = fmt.Errorf("error while advising on map file: %s", err)
+	}
+
+	return
+}
+
+// SetMutationSeq - Persists the sequence number of the last applied mutation in the map file header
+func (B *BucketFile) SetMutationSeq(seq int64) (err error) {
+	err = SetMutationSeq(B.File, seq)
+	if err != nil {
+		err = fmt.Errorf("error while writing mutation sequence number to map file header: %s", err)
+		return
+	}
+
+	B.MutationSeq = seq
+
+	return
+}
+
+// SetExpiryCheck - Sets a function that tells whether the value of an occupied record has expired. Expired records
+// are treated as deleted, and are marked deleted in file when encountered while getting or setting records.
+//   - isExpired is the function to call with the value of a record, nil turns off expiry checks
+func (B *BucketFile) SetExpiryCheck(isExpired func(value []byte) bool) {
+	B.IsExpired = isExpired
+}
+
+// SetProgress - Sets a function that is called with the bucket number each time a bucket is read from the map file,
+// which lets a caller follow the progress of long-running operations such as probe loops and scans.
+//   - progress is the function to call, nil turns off progress reporting
+func (B *BucketFile) SetProgress(progress func(bucketNo int64)) {
+	B.Progress = progress
+}
+
+// SetMetrics - Sets the receiver of metrics counted while accessing the map file, which counts buckets probed and bytes
+// read from and written to the file
+//   - metrics is the receiver, nil turns off counting
+func (B *BucketFile) SetMetrics(metrics model.Metrics) {
+	B.Metrics = metrics
+	B.File = CountBytes(B.File, metrics)
+}
+
+// SetWriteBuffer - Holds writes to the map file in memory, coalesced into as few writes as possible, until they are
+// flushed when maxBytes is reached, by a timer when interval has passed since the oldest pending write, or when the
+// file is synced or closed. Reads see the pending writes. Any pending writes are flushed when the buffer is replaced.
+//   - maxBytes is the max number of bytes to hold before flushing, zero turns buffering off
+//   - interval is the max time to hold writes, zero means no time limit
+//
+// It returns:
+//   - err is a standard error, if pending writes could not be flushed
+func (B *BucketFile) SetWriteBuffer(maxBytes int64, interval time.Duration) (err error) {
+	B.File, err = BufferWrites(B.File, maxBytes, interval)
+
+	return
+}
+
+// SetInterrupt - Sets a function that is called before each bucket read while looking for a key, and stops the lookup
+// with the error it returns, if any. Lookups, including the search for a slot for a new record, are done before
+// anything is written, so interrupting them never leaves the files half updated.
+//   - interrupt is the function to call, nil turns off interruption
+func (B *BucketFile) SetInterrupt(interrupt func() error) {
+	B.Interrupt = interrupt
+}
+
+// AddAccessCount - Adds increment to the saturating access counter of a record
+//   - record is the model.Record to update, and it must contain RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (B *BucketFile) AddAccessCount(record model.Record, increment int64) (err error) {
+	err = AddAccessCount(B.File, record.RecordAddress, increment)
+	if err != nil {
+		err = fmt.Errorf("error while updating access count in bucket: %s", err)
+	}
+
+	return
+}
+
+// GetSystemValue - Returns a value stored by the library for its own use in the system area of the map file header
+//   - id is the identifier of the system value
+//
+// It returns:
+//   - value is the stored value if found, if not found an error of type crt.NoRecordFound is returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (B *BucketFile) GetSystemValue(id uint8) (value []byte, err error) {
+	value, err = GetSystemValue(B.File, id)
+
+	return
+}
+
+// SetSystemValue - Stores a value for the library's own use in the system area of the map file header
+//   - id is the identifier of the system value, it must not be zero
+//   - value is the value to store, at most 255 bytes long
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (B *BucketFile) SetSystemValue(id uint8, value []byte) (err error) {
+	err = SetSystemValue(B.File, id, value)
+	if err != nil {
+		err = fmt.Errorf("error while setting system value in map file header: %s", err)
+	}
+
+	return
+}
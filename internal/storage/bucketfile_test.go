@@ -0,0 +1,112 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestBucketFile(t *testing.T) {
+	t.Run("creates and opens a bucket file", func(t *testing.T) {
+		// Prepare
+		header := Header{
+			InternalHash:                 true,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			NumberOfBucketsNeeded:        10,
+			NumberOfBucketsAvailable:     10,
+			RecordsPerBucket:             2,
+			MaxBucketNo:                  9,
+			FileSize:                     MapFileHeaderLength + 10*2*27,
+			CollisionResolutionTechnique: int64(crt.RobinHood),
+		}
+		bucketFileInit := BucketFile{FileName: "test-map.bin", FileSystem: vfs.OS{}, FileSize: header.FileSize}
+		err := bucketFileInit.CreateFile(header)
+		assert.NoError(t, err, "creates bucket file")
+		err = bucketFileInit.SetMutationSeq(42)
+		assert.NoError(t, err, "sets mutation sequence number")
+		bucketFileInit.CloseFiles()
+
+		// Execute
+		bucketFile := BucketFile{FileName: "test-map.bin", FileSystem: vfs.OS{}}
+		headerOpened, err := bucketFile.OpenFile()
+
+		// Check
+		assert.NoError(t, err, "opens bucket file")
+		assert.Equal(t, header.KeyLength, headerOpened.KeyLength, "key length from header")
+		assert.Equal(t, header.FileSize, bucketFile.FileSize, "file size from header")
+		assert.Equal(t, int64(42), bucketFile.MutationSeq, "mutation sequence number from header")
+
+		// Clean up
+		bucketFile.CloseFiles()
+		err = bucketFile.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		_, err = os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(err), "map file removed")
+	})
+
+	t.Run("clears the bucket file and keeps the system area", func(t *testing.T) {
+		// Prepare
+		header := Header{
+			KeyLength:                    16,
+			ValueLength:                  10,
+			NumberOfBucketsNeeded:        10,
+			NumberOfBucketsAvailable:     10,
+			RecordsPerBucket:             1,
+			MaxBucketNo:                  9,
+			FileSize:                     MapFileHeaderLength + 10*27,
+			CollisionResolutionTechnique: int64(crt.CuckooHashing),
+		}
+		bucketFile := BucketFile{FileName: "test-map.bin", FileSystem: vfs.OS{}, FileSize: header.FileSize}
+		err := bucketFile.CreateFile(header)
+		assert.NoError(t, err, "creates bucket file")
+		err = bucketFile.SetSystemValue(1, []byte{1, 2, 3})
+		assert.NoError(t, err, "sets system value")
+		err = bucketFile.SetMutationSeq(7)
+		assert.NoError(t, err, "sets mutation sequence number")
+		_, err = bucketFile.File.WriteAt([]byte{1, 2, 3, 4}, MapFileHeaderLength)
+		assert.NoError(t, err, "writes to first bucket")
+
+		// Execute
+		err = bucketFile.ClearFile(header)
+
+		// Check
+		assert.NoError(t, err, "clears bucket file")
+		assert.Equal(t, int64(0), bucketFile.MutationSeq, "mutation sequence number reset")
+
+		buf := make([]byte, 4)
+		_, err = bucketFile.File.ReadAt(buf, MapFileHeaderLength)
+		assert.NoError(t, err, "reads first bucket")
+		assert.Equal(t, []byte{0, 0, 0, 0}, buf, "first bucket cleared")
+
+		value, err := bucketFile.GetSystemValue(1)
+		assert.NoError(t, err, "gets system value")
+		assert.Equal(t, []byte{1, 2, 3}, value, "system value kept")
+
+		size, err := GetFileSize(bucketFile.File)
+		assert.NoError(t, err, "gets file size")
+		assert.Equal(t, header.FileSize, size, "file size kept")
+
+		// Clean up
+		bucketFile.CloseFiles()
+		err = bucketFile.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails to open a bucket file that doesn't exist", func(t *testing.T) {
+		// Prepare
+		bucketFile := BucketFile{FileName: "missing-map.bin", FileSystem: vfs.OS{}}
+
+		// Execute
+		_, err := bucketFile.OpenFile()
+
+		// Check
+		assert.Error(t, err, "fails to open missing file")
+		assert.Nil(t, bucketFile.File, "no file kept")
+	})
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"sync"
+)
+
+// BufferPool - Is a pool of byte buffers that are reused between reads to reduce allocations, e.g. buffers for
+// buckets read while probing. The zero value is an empty pool ready for use and a BufferPool is safe for concurrent
+// use. A BufferPool must not be copied after first use.
+type BufferPool struct {
+	pool sync.Pool
+}
+
+// Get - Returns a buffer of the given length, reusing a pooled buffer if one with enough capacity is available.
+// The content of the buffer is undefined.
+//   - length is the length of the buffer
+//
+// It returns:
+//   - buf is the buffer
+func (B *BufferPool) Get(length int64) (buf []byte) {
+	if p, ok := B.pool.Get().(*[]byte); ok && int64(cap(*p)) >= length {
+		buf = (*p)[:length]
+		return
+	}
+
+	buf = make([]byte, length)
+
+	return
+}
+
+// Put - Returns a buffer to the pool, the buffer must not be used by the caller afterwards
+//   - buf is the buffer to return
+func (B *BufferPool) Put(buf []byte) {
+	B.pool.Put(&buf)
+}
@@ -0,0 +1,26 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	t.Run("returns buffers of requested length and reuses returned buffers", func(t *testing.T) {
+		// Prepare
+		var pool BufferPool
+
+		// Execute
+		first := pool.Get(10)
+		pool.Put(first)
+		shorter := pool.Get(5)
+		longer := pool.Get(20)
+
+		// Check
+		assert.Len(t, first, 10, "first buffer has requested length")
+		assert.Len(t, shorter, 5, "shorter buffer has requested length")
+		assert.Len(t, longer, 20, "longer buffer has requested length")
+	})
+}
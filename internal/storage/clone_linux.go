@@ -0,0 +1,18 @@
+//go:build linux && (amd64 || arm64)
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone - Is the FICLONE ioctl request, sharing all data of one file with another on file systems like Btrfs and XFS
+const ficlone = 0x40049409
+
+// cloneFile - Clones src into dst using the FICLONE ioctl, returning false if the file system doesn't support it
+func cloneFile(dst, src *os.File) (cloned bool) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+
+	return errno == 0
+}
@@ -0,0 +1,12 @@
+//go:build !(linux && (amd64 || arm64))
+
+package storage
+
+import (
+	"os"
+)
+
+// cloneFile - Does nothing on platforms where files can not be cloned, returning false
+func cloneFile(dst, src *os.File) (cloned bool) {
+	return
+}
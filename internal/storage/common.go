@@ -2,14 +2,33 @@ package storage
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/vfs"
+	"hash/crc32"
 	"io"
 	"os"
+	"sort"
 )
 
 // MapFileHeaderLength - Length of hash map file header
 const MapFileHeaderLength int64 = 1024
 
+// OvflFileHeaderLength - Length of overflow file header
+const OvflFileHeaderLength int64 = 1024
+
+// systemAreaOffset - Header offset to the system area, the part of the header that is reserved for library
+// metadata stored as id/value entries. Everything before this offset is the fixed header.
+const systemAreaOffset int64 = 512
+
+// systemAreaLength - Length of the system area in the header
+const systemAreaLength = MapFileHeaderLength - systemAreaOffset
+
+// systemEntryHeaderLength - Length of the header of each entry in the system area, one byte id and one byte length
+const systemEntryHeaderLength int64 = 2
+
 // hashAlgorithmOffset - Header offset to whether using internal (1) or external (0) bucket algorithm - 1 byte
 const hashAlgorithmOffset int64 = 0
 
@@ -37,6 +56,53 @@ const fileSizeOffset int64 = 41
 // collisionResolutionTechniqueOffset - Header offset to which collision resolution technique is used - 1 byte
 const collisionResolutionTechniqueOffset int64 = 49
 
+// hashAlgorithmKindOffset - Header offset to which kind of internal hash algorithm is used - 1 byte
+const hashAlgorithmKindOffset int64 = 50
+
+// hashSeedOffset - Header offset to the seed used by the internal hash algorithm - 8 bytes
+const hashSeedOffset int64 = 51
+
+// mutationSeqOffset - Header offset to the sequence number of the last applied mutation - 8 bytes
+const mutationSeqOffset int64 = 59
+
+// legacyHeaderLength - Length of the format version 1 header layout, i.e. the fields at the offsets above
+const legacyHeaderLength int64 = 67
+
+// HeaderFormatVersion - The header format version written by this release. Format version 2 is written in a block of
+// its own at headerBlockOffset, while format version 1 is the legacy layout at the offsets above.
+const HeaderFormatVersion int64 = 2
+
+// writeLegacyHeader - Whether the legacy format version 1 layout is written alongside format version 2, so that a
+// binary of the previous release can still open recently written files after a rollback. To be turned off in the
+// release following the one that introduced format version 2.
+const writeLegacyHeader = true
+
+// headerBlockOffset - Header offset to the format version 2 header block, which is within the fixed header but beyond
+// the legacy layout
+const headerBlockOffset int64 = 256
+
+// headerBlockTag - Tag starting the format version 2 header block
+const headerBlockTag = "FHMH"
+
+// Offsets within the format version 2 header block
+const (
+	blockTagOffset                          int64 = 0  // Tag - 4 bytes
+	blockFormatVersionOffset                int64 = 4  // Format version - 2 bytes
+	blockLegacyChecksumOffset               int64 = 6  // CRC32 of the legacy layout written alongside - 4 bytes
+	blockHashAlgorithmOffset                int64 = 10 // Internal (1) or external (0) bucket algorithm - 1 byte
+	blockKeyLengthOffset                    int64 = 11 // Key length - 8 bytes
+	blockValueLengthOffset                  int64 = 19 // Value length - 8 bytes
+	blockNumberOfBucketsNeededOffset        int64 = 27 // Number of buckets needed - 8 bytes
+	blockNumberOfBucketsAvailableOffset     int64 = 35 // Number of buckets available - 8 bytes
+	blockRecordsPerBucketOffset             int64 = 43 // Number of records per bucket - 8 bytes
+	blockMaxBucketNoOffset                  int64 = 51 // Max (inclusive) bucket number - 8 bytes
+	blockFileSizeOffset                     int64 = 59 // File size - 8 bytes
+	blockCollisionResolutionTechniqueOffset int64 = 67 // Collision resolution technique - 2 bytes
+	blockHashAlgorithmKindOffset            int64 = 69 // Kind of internal hash algorithm - 2 bytes
+	blockHashSeedOffset                     int64 = 71 // Seed used by the internal hash algorithm - 8 bytes
+	blockMutationSeqOffset                  int64 = 79 // Sequence number of the last applied mutation - 8 bytes
+)
+
 // Header - Represents the hash map file header data
 type Header struct {
 	InternalHash                 bool
@@ -48,6 +114,45 @@ type Header struct {
 	MaxBucketNo                  int64
 	FileSize                     int64
 	CollisionResolutionTechnique int64
+	HashAlgorithmKind            int64
+	HashSeed                     int64
+	MutationSeq                  int64
+	FormatVersion                int64
+}
+
+// ResolveFileSystem - Returns fileSystem, or the file system of the operating system if fileSystem is nil
+func ResolveFileSystem(fileSystem vfs.FileSystem) vfs.FileSystem {
+	if fileSystem == nil {
+		return vfs.OS{}
+	}
+
+	return fileSystem
+}
+
+// wrappedFile - Is implemented by files wrapping another file, see OSFile
+type wrappedFile interface {
+	underlying() vfs.File
+}
+
+// OSFile - Returns the operating system file behind file, looking through files wrapped by RetryFileSystem or
+// ReadOnlyFileSystem
+//   - file is the file to look behind
+//
+// It returns:
+//   - osFile is the operating system file
+//   - ok is false if file is not backed by an operating system file
+func OSFile(file vfs.File) (osFile *os.File, ok bool) {
+	for {
+		wrapped, isWrapped := file.(wrappedFile)
+		if !isWrapped {
+			break
+		}
+		file = wrapped.underlying()
+	}
+
+	osFile, ok = file.(*os.File)
+
+	return
 }
 
 // GetMapFileName - Return the map file name given the file hash map name
@@ -60,66 +165,403 @@ func GetOvflFileName(name string) (fileName string) {
 	return fmt.Sprintf("%s-ovfl.bin", name)
 }
 
+// GetWALFileName - Return the write-ahead log file name given the file hash map name
+func GetWALFileName(name string) (fileName string) {
+	return fmt.Sprintf("%s-wal.bin", name)
+}
+
+// GetKeyFileName - Return the key file name given the file hash map name
+func GetKeyFileName(name string) (fileName string) {
+	return fmt.Sprintf("%s-keys.bin", name)
+}
+
+// GetBloomFileName - Return the Bloom filter file name given the file hash map name
+func GetBloomFileName(name string) (fileName string) {
+	return fmt.Sprintf("%s-bloom.bin", name)
+}
+
+// GetLockFileName - Return the lock file name given the file hash map name
+func GetLockFileName(name string) (fileName string) {
+	return fmt.Sprintf("%s-lock.bin", name)
+}
+
 // GetFileHeader - Reads header data from file and returns it as a Header struct
-// This function opens the file for reading, thus expecting it to not already be open.
-func GetFileHeader(fileName string) (header Header, err error) {
-	file, err := os.OpenFile(fileName, os.O_RDONLY, 0644)
+// This function opens the file for reading in fileSystem, thus expecting it to not already be open.
+// An error of type crt.UnknownFormat is returned if the file is not a map file or of a format version that can not be read.
+func GetFileHeader(fileSystem vfs.FileSystem, fileName string) (header Header, err error) {
+	file, err := ResolveFileSystem(fileSystem).OpenFile(fileName, os.O_RDONLY, 0644)
 	if err != nil {
 		return
 	}
-	defer func(file *os.File) { _ = file.Close() }(file)
+	defer func(file vfs.File) { _ = file.Close() }(file)
+
+	header, err = GetHeader(file)
+	var unknownFormat crt.UnknownFormat
+	if errors.As(err, &unknownFormat) {
+		unknownFormat.FileName = fileName
+		err = unknownFormat
+	}
 
-	_, err = file.Seek(0, io.SeekStart)
+	return
+}
+
+// GetHeader - Reads header data from file and returns it as a Header struct
+// An error of type crt.UnknownFormat is returned if the file is not a map file or of a format version that can not be read.
+func GetHeader(file vfs.File) (header Header, err error) {
+	buf := make([]byte, MapFileHeaderLength)
+	_, err = file.ReadAt(buf, 0)
+	if errors.Is(err, io.EOF) {
+		err = crt.UnknownFormat{SupportedVersion: HeaderFormatVersion}
+		return
+	}
 	if err != nil {
 		return
 	}
 
-	buf := make([]byte, MapFileHeaderLength)
-	_, err = file.Read(buf)
+	header, err = parseHeader(buf)
+
+	return
+}
+
+// MigrateHeader - Rewrites the header of file in the current format version if it was read in an older one, i.e. the
+// legacy format version 1 layout. The system area of the header is left untouched.
+//   - file is the map file to migrate the header of
+//
+// It returns:
+//   - fromVersion is the format version the header was read in
+//   - migrated is true if the header was rewritten
+//   - err is a standard error, or of type crt.UnknownFormat if the file is not a map file or of a newer format version
+func MigrateHeader(file vfs.File) (fromVersion int64, migrated bool, err error) {
+	header, err := GetHeader(file)
 	if err != nil {
 		return
 	}
 
-	header = bytesToHeader(buf)
+	fromVersion = header.FormatVersion
+	if fromVersion >= HeaderFormatVersion {
+		return
+	}
+
+	err = SetHeader(file, header)
+	if err == nil {
+		err = file.Sync()
+	}
+	migrated = err == nil
 
 	return
 }
 
-// GetHeader - Reads header data from file and returns it as a Header struct
-func GetHeader(file *os.File) (header Header, err error) {
-	_, err = file.Seek(0, io.SeekStart)
+// GetFileSize - Returns the current size of a file, which is also the address at which to append to it
+func GetFileSize(file vfs.File) (size int64, err error) {
+	stat, err := file.Stat()
 	if err != nil {
 		return
 	}
 
-	buf := make([]byte, MapFileHeaderLength)
-	_, err = file.Read(buf)
+	size = stat.Size()
+
+	return
+}
+
+// ClearFile - Discards everything in file after keepLength and then extends it with zeros to size
+func ClearFile(file vfs.File, keepLength, size int64) (err error) {
+	err = file.Truncate(keepLength)
 	if err != nil {
 		return
 	}
 
-	header = bytesToHeader(buf)
+	err = file.Truncate(size)
 
 	return
 }
 
 // SetHeader - Takes a Header struct and writes header data to file
-func SetHeader(file *os.File, header Header) (err error) {
-	_, err = file.Seek(0, io.SeekStart)
+// The system area of the header is left untouched.
+func SetHeader(file vfs.File, header Header) (err error) {
+	buf := headerToBytes(header)
+
+	_, err = file.WriteAt(buf[:systemAreaOffset], 0)
+
+	return
+}
+
+// SetMutationSeq - Writes the sequence number of the last applied mutation to the header in file.
+// The sequence number is written in every header layout present, hence the header is read and written as a whole.
+func SetMutationSeq(file vfs.File, seq int64) (err error) {
+	buf := make([]byte, systemAreaOffset)
+	_, err = file.ReadAt(buf, 0)
 	if err != nil {
 		return
 	}
 
-	buf := headerToBytes(header)
+	header, err := parseHeader(buf)
+	if err != nil {
+		return
+	}
+	header.MutationSeq = seq
+
+	err = SetHeader(file, header)
+
+	return
+}
 
-	_, err = file.Write(buf)
+// GetSystemValue - Returns the value stored under id in the system area of the header.
+// If there is no value stored for the id an error of type crt.NoRecordFound is returned.
+func GetSystemValue(file vfs.File, id uint8) (value []byte, err error) {
+	area, err := getSystemArea(file)
+	if err != nil {
+		return
+	}
+
+	start, length := findSystemEntry(area, id)
+	if start < 0 {
+		err = crt.NoRecordFound{}
+		return
+	}
+
+	value = make([]byte, length)
+	_ = copy(value, area[start+systemEntryHeaderLength:])
 
 	return
 }
 
-// bytesToHeader - Converts a slice of bytes to a Header struct
+// SetSystemValue - Stores value under id in the system area of the header, replacing any existing value.
+// Id zero is not permitted since it marks the end of entries, and the value can be at most 255 bytes long.
+func SetSystemValue(file vfs.File, id uint8, value []byte) (err error) {
+	if id == 0 {
+		err = fmt.Errorf("system value id zero is reserved")
+		return
+	}
+	if len(value) > 255 {
+		err = fmt.Errorf("system value length %d exceeds max length 255", len(value))
+		return
+	}
+
+	area, err := getSystemArea(file)
+	if err != nil {
+		return
+	}
+
+	area = removeSystemEntry(area, id)
+
+	end, _ := findSystemEntry(area, 0)
+	if end < 0 || end+systemEntryHeaderLength+int64(len(value)) > systemAreaLength {
+		err = fmt.Errorf("no space left in system area for value with id %d", id)
+		return
+	}
+
+	area[end] = id
+	area[end+1] = uint8(len(value))
+	_ = copy(area[end+systemEntryHeaderLength:], value)
+
+	_, err = file.WriteAt(area, systemAreaOffset)
+
+	return
+}
+
+// DeleteSystemValue - Removes the value stored under id from the system area of the header, if any.
+func DeleteSystemValue(file vfs.File, id uint8) (err error) {
+	area, err := getSystemArea(file)
+	if err != nil {
+		return
+	}
+
+	_, err = file.WriteAt(removeSystemEntry(area, id), systemAreaOffset)
+
+	return
+}
+
+// getSystemArea - Reads the system area of the header
+func getSystemArea(file vfs.File) (area []byte, err error) {
+	area = make([]byte, systemAreaLength)
+	_, err = file.ReadAt(area, systemAreaOffset)
+
+	return
+}
+
+// findSystemEntry - Returns start and value length of the entry with id in area, or -1 as start if not found.
+// Searching for id zero returns the start of the free space.
+func findSystemEntry(area []byte, id uint8) (start, length int64) {
+	for start = 0; start+systemEntryHeaderLength <= systemAreaLength; start += systemEntryHeaderLength + length {
+		length = int64(area[start+1])
+		if area[start] == id {
+			return
+		}
+		if area[start] == 0 {
+			break
+		}
+	}
+
+	return -1, 0
+}
+
+// removeSystemEntry - Returns area with the entry with id removed and remaining entries compacted
+func removeSystemEntry(area []byte, id uint8) (result []byte) {
+	result = make([]byte, systemAreaLength)
+
+	var length, end, n int64
+	for start := int64(0); start+systemEntryHeaderLength <= systemAreaLength && area[start] != 0; start = end {
+		length = int64(area[start+1])
+		end = start + systemEntryHeaderLength + length
+		if end > systemAreaLength {
+			break
+		}
+		if area[start] != id {
+			n += int64(copy(result[n:], area[start:end]))
+		}
+	}
+
+	return
+}
+
+// DedupeByKey - Returns records where only the last occurrence of each key is kept, preserving the order of the
+// remaining records
+func DedupeByKey(records []model.Record) (deduped []model.Record) {
+	last := make(map[string]int, len(records))
+	for i, record := range records {
+		last[string(record.Key)] = i
+	}
+
+	deduped = make([]model.Record, 0, len(last))
+	for i, record := range records {
+		if last[string(record.Key)] == i {
+			deduped = append(deduped, record)
+		}
+	}
+
+	return
+}
+
+// SortByBucket - Sorts records in ascending order of bucket number given by bucketNo, records with the same
+// bucket number keep their relative order
+func SortByBucket(records []model.Record, bucketNo func(key []byte) int64) {
+	bucketNos := make(map[string]int64, len(records))
+	for _, record := range records {
+		bucketNos[string(record.Key)] = bucketNo(record.Key)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return bucketNos[string(records[i].Key)] < bucketNos[string(records[j].Key)]
+	})
+}
+
+// BucketOrder - Returns the indexes of keyRecords sorted in ascending order of bucket number given by bucketNo
+func BucketOrder(keyRecords []model.Record, bucketNo func(key []byte) int64) (order []int) {
+	bucketNos := make([]int64, len(keyRecords))
+	order = make([]int, len(keyRecords))
+	for i, record := range keyRecords {
+		bucketNos[i] = bucketNo(record.Key)
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return bucketNos[order[i]] < bucketNos[order[j]] })
+
+	return
+}
+
+// AddAccessCount - Adds increment to the saturating access counter held in the state byte at stateAddress in file.
+// The counter is only updated if the record is still occupied, since the record may have been deleted after the
+// access was registered.
+func AddAccessCount(file vfs.File, stateAddress int64, increment int64) (err error) {
+	buf := make([]byte, 1)
+	_, err = file.ReadAt(buf, stateAddress)
+	if err != nil {
+		return
+	}
+
+	state, accessCount := model.FromStateByte(buf[0])
+	if state != model.RecordOccupied {
+		return
+	}
+
+	count := int64(accessCount) + increment
+	if count > int64(model.MaxAccessCount) {
+		count = int64(model.MaxAccessCount)
+	} else if count < 0 {
+		count = 0
+	}
+	buf[0] = model.ToStateByte(state, uint8(count))
+
+	_, err = file.WriteAt(buf, stateAddress)
+
+	return
+}
+
+// parseHeader - Converts a slice of bytes to a Header struct as bytesToHeader does, but returns an error of type
+// crt.UnknownFormat if the bytes are not the header of a map file. Since the legacy format version 1 layout has no tag
+// of its own, a header read in it is required to hold plausible values, which rejects most files that are not map files.
+func parseHeader(buf []byte) (header Header, err error) {
+	header = bytesToHeader(buf)
+	if header.FormatVersion > 1 || plausibleLegacyHeader(buf, header) {
+		return
+	}
+
+	unknownFormat := crt.UnknownFormat{SupportedVersion: HeaderFormatVersion}
+	block := buf[headerBlockOffset:]
+	if string(block[blockTagOffset:blockTagOffset+4]) == headerBlockTag {
+		unknownFormat.FormatVersion = int64(binary.LittleEndian.Uint16(block[blockFormatVersionOffset:]))
+	}
+	err = unknownFormat
+
+	return
+}
+
+// plausibleLegacyHeader - Returns true if buf holds values in the legacy format version 1 layout that a map file can have
+func plausibleLegacyHeader(buf []byte, header Header) bool {
+	return buf[hashAlgorithmOffset] <= 1 &&
+		header.CollisionResolutionTechnique >= int64(crt.SeparateChaining) &&
+		header.CollisionResolutionTechnique <= int64(crt.LinearHashing) &&
+		header.KeyLength > 0 &&
+		header.ValueLength > 0 &&
+		header.NumberOfBucketsAvailable > 0 &&
+		header.FileSize >= MapFileHeaderLength
+}
+
+// bytesToHeader - Converts a slice of bytes to a Header struct.
+// The format version 2 block is used if present, of a supported version and written together with the legacy layout
+// currently in buf. If the legacy layout has been changed since, e.g. by a binary of a previous release that only knows
+// of format version 1, the block is stale and the legacy layout is used instead.
 func bytesToHeader(buf []byte) (header Header) {
+	block := buf[headerBlockOffset:]
+	if string(block[blockTagOffset:blockTagOffset+4]) == headerBlockTag {
+		formatVersion := int64(binary.LittleEndian.Uint16(block[blockFormatVersionOffset:]))
+		legacyChecksum := binary.LittleEndian.Uint32(block[blockLegacyChecksumOffset:])
+		if formatVersion <= HeaderFormatVersion && legacyChecksum == crc32.ChecksumIEEE(buf[:legacyHeaderLength]) {
+			header = blockToHeader(block)
+			header.FormatVersion = formatVersion
+			return
+		}
+	}
+
+	header = legacyBytesToHeader(buf)
+
+	return
+}
+
+// blockToHeader - Converts a format version 2 header block to a Header struct
+func blockToHeader(block []byte) (header Header) {
+	header = Header{
+		InternalHash:                 block[blockHashAlgorithmOffset] == 1,
+		KeyLength:                    int64(binary.LittleEndian.Uint64(block[blockKeyLengthOffset:])),
+		ValueLength:                  int64(binary.LittleEndian.Uint64(block[blockValueLengthOffset:])),
+		NumberOfBucketsNeeded:        int64(binary.LittleEndian.Uint64(block[blockNumberOfBucketsNeededOffset:])),
+		NumberOfBucketsAvailable:     int64(binary.LittleEndian.Uint64(block[blockNumberOfBucketsAvailableOffset:])),
+		RecordsPerBucket:             int64(binary.LittleEndian.Uint64(block[blockRecordsPerBucketOffset:])),
+		MaxBucketNo:                  int64(binary.LittleEndian.Uint64(block[blockMaxBucketNoOffset:])),
+		FileSize:                     int64(binary.LittleEndian.Uint64(block[blockFileSizeOffset:])),
+		CollisionResolutionTechnique: int64(binary.LittleEndian.Uint16(block[blockCollisionResolutionTechniqueOffset:])),
+		HashAlgorithmKind:            int64(binary.LittleEndian.Uint16(block[blockHashAlgorithmKindOffset:])),
+		HashSeed:                     int64(binary.LittleEndian.Uint64(block[blockHashSeedOffset:])),
+		MutationSeq:                  int64(binary.LittleEndian.Uint64(block[blockMutationSeqOffset:])),
+	}
+
+	return
+}
+
+// legacyBytesToHeader - Converts a slice of bytes in the legacy format version 1 layout to a Header struct
+func legacyBytesToHeader(buf []byte) (header Header) {
 	header = Header{
+		FormatVersion:                1,
 		InternalHash:                 buf[hashAlgorithmOffset] == 1,
 		KeyLength:                    int64(binary.LittleEndian.Uint32(buf[keyLengthOffset:])),
 		ValueLength:                  int64(binary.LittleEndian.Uint32(buf[valueLengthOffset:])),
@@ -129,16 +571,48 @@ func bytesToHeader(buf []byte) (header Header) {
 		MaxBucketNo:                  int64(binary.LittleEndian.Uint64(buf[maxBucketNoOffset:])),
 		FileSize:                     int64(binary.LittleEndian.Uint64(buf[fileSizeOffset:])),
 		CollisionResolutionTechnique: int64(buf[collisionResolutionTechniqueOffset]),
+		HashAlgorithmKind:            int64(buf[hashAlgorithmKindOffset]),
+		HashSeed:                     int64(binary.LittleEndian.Uint64(buf[hashSeedOffset:])),
+		MutationSeq:                  int64(binary.LittleEndian.Uint64(buf[mutationSeqOffset:])),
 	}
 
 	return
 }
 
-// headerToBytes - Converts a Header struct to a slice of bytes
+// headerToBytes - Converts a Header struct to a slice of bytes, in format version 2 and, as long as writeLegacyHeader
+// is set, also in the legacy format version 1 layout
 func headerToBytes(header Header) (buf []byte) {
 	// Create byte buffer
 	buf = make([]byte, MapFileHeaderLength)
 
+	if writeLegacyHeader {
+		putLegacyHeader(buf, header)
+	}
+
+	block := buf[headerBlockOffset:]
+	_ = copy(block[blockTagOffset:], headerBlockTag)
+	binary.LittleEndian.PutUint16(block[blockFormatVersionOffset:], uint16(HeaderFormatVersion))
+	binary.LittleEndian.PutUint32(block[blockLegacyChecksumOffset:], crc32.ChecksumIEEE(buf[:legacyHeaderLength]))
+	if header.InternalHash {
+		block[blockHashAlgorithmOffset] = 1
+	}
+	binary.LittleEndian.PutUint64(block[blockKeyLengthOffset:], uint64(header.KeyLength))
+	binary.LittleEndian.PutUint64(block[blockValueLengthOffset:], uint64(header.ValueLength))
+	binary.LittleEndian.PutUint64(block[blockNumberOfBucketsNeededOffset:], uint64(header.NumberOfBucketsNeeded))
+	binary.LittleEndian.PutUint64(block[blockNumberOfBucketsAvailableOffset:], uint64(header.NumberOfBucketsAvailable))
+	binary.LittleEndian.PutUint64(block[blockRecordsPerBucketOffset:], uint64(header.RecordsPerBucket))
+	binary.LittleEndian.PutUint64(block[blockMaxBucketNoOffset:], uint64(header.MaxBucketNo))
+	binary.LittleEndian.PutUint64(block[blockFileSizeOffset:], uint64(header.FileSize))
+	binary.LittleEndian.PutUint16(block[blockCollisionResolutionTechniqueOffset:], uint16(header.CollisionResolutionTechnique))
+	binary.LittleEndian.PutUint16(block[blockHashAlgorithmKindOffset:], uint16(header.HashAlgorithmKind))
+	binary.LittleEndian.PutUint64(block[blockHashSeedOffset:], uint64(header.HashSeed))
+	binary.LittleEndian.PutUint64(block[blockMutationSeqOffset:], uint64(header.MutationSeq))
+
+	return
+}
+
+// putLegacyHeader - Writes a Header struct to buf in the legacy format version 1 layout
+func putLegacyHeader(buf []byte, header Header) {
 	if header.InternalHash {
 		buf[hashAlgorithmOffset] = 1
 	}
@@ -151,6 +625,7 @@ func headerToBytes(header Header) (buf []byte) {
 	binary.LittleEndian.PutUint64(buf[maxBucketNoOffset:], uint64(header.MaxBucketNo))
 	binary.LittleEndian.PutUint64(buf[fileSizeOffset:], uint64(header.FileSize))
 	buf[collisionResolutionTechniqueOffset] = uint8(header.CollisionResolutionTechnique)
-
-	return
+	buf[hashAlgorithmKindOffset] = uint8(header.HashAlgorithmKind)
+	binary.LittleEndian.PutUint64(buf[hashSeedOffset:], uint64(header.HashSeed))
+	binary.LittleEndian.PutUint64(buf[mutationSeqOffset:], uint64(header.MutationSeq))
 }
@@ -5,6 +5,7 @@ package storage
 import (
 	"encoding/binary"
 	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/vfs"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -119,7 +120,7 @@ func TestGetFileHeader(t *testing.T) {
 		assert.NoError(t, err, "closes file")
 
 		// Execute
-		header, err := GetFileHeader("testfile")
+		header, err := GetFileHeader(vfs.OS{}, "testfile")
 
 		// Check
 		assert.NoError(t, err, "gets header")
@@ -151,6 +152,9 @@ func TestBytesToHeader(t *testing.T) {
 		binary.LittleEndian.PutUint64(buf[maxBucketNoOffset:], 499)
 		binary.LittleEndian.PutUint64(buf[fileSizeOffset:], 100000)
 		buf[collisionResolutionTechniqueOffset] = uint8(crt.LinearProbing)
+		buf[hashAlgorithmKindOffset] = 1
+		binary.LittleEndian.PutUint64(buf[hashSeedOffset:], 12345)
+		binary.LittleEndian.PutUint64(buf[mutationSeqOffset:], 42)
 
 		// execute
 		header := bytesToHeader(buf)
@@ -164,6 +168,9 @@ func TestBytesToHeader(t *testing.T) {
 		assert.Equal(t, int64(499), header.MaxBucketNo)
 		assert.Equal(t, int64(100000), header.FileSize)
 		assert.Equal(t, int64(crt.LinearProbing), header.CollisionResolutionTechnique)
+		assert.Equal(t, int64(1), header.HashAlgorithmKind)
+		assert.Equal(t, int64(12345), header.HashSeed)
+		assert.Equal(t, int64(42), header.MutationSeq)
 	})
 }
 
@@ -179,6 +186,8 @@ func TestHeaderToBytes(t *testing.T) {
 			MaxBucketNo:                  499,
 			FileSize:                     100000,
 			CollisionResolutionTechnique: int64(crt.QuadraticProbing),
+			HashAlgorithmKind:            1,
+			HashSeed:                     12345,
 		}
 
 		// Execute
@@ -193,6 +202,8 @@ func TestHeaderToBytes(t *testing.T) {
 		maxBucketNo := int64(binary.LittleEndian.Uint64(buf[maxBucketNoOffset:]))
 		fileSize := int64(binary.LittleEndian.Uint64(buf[fileSizeOffset:]))
 		collisionResolutionTechnique := int64(buf[collisionResolutionTechniqueOffset])
+		hashAlgorithmKind := int64(buf[hashAlgorithmKindOffset])
+		hashSeed := int64(binary.LittleEndian.Uint64(buf[hashSeedOffset:]))
 
 		assert.True(t, internalHash)
 		assert.Equal(t, header.KeyLength, keyLength)
@@ -202,5 +213,231 @@ func TestHeaderToBytes(t *testing.T) {
 		assert.Equal(t, header.MaxBucketNo, maxBucketNo)
 		assert.Equal(t, header.FileSize, fileSize)
 		assert.Equal(t, header.CollisionResolutionTechnique, collisionResolutionTechnique)
+		assert.Equal(t, header.HashAlgorithmKind, hashAlgorithmKind)
+		assert.Equal(t, header.HashSeed, hashSeed)
+	})
+}
+
+func TestHeaderFormatVersions(t *testing.T) {
+	header := Header{
+		InternalHash:                 true,
+		KeyLength:                    16,
+		ValueLength:                  10,
+		NumberOfBucketsNeeded:        400,
+		NumberOfBucketsAvailable:     500,
+		RecordsPerBucket:             2,
+		MaxBucketNo:                  499,
+		FileSize:                     100000,
+		CollisionResolutionTechnique: int64(crt.DoubleHashing),
+		HashAlgorithmKind:            1,
+		HashSeed:                     12345,
+		MutationSeq:                  42,
+	}
+
+	t.Run("writes both layouts and reads the current format version", func(t *testing.T) {
+		// Execute
+		buf := headerToBytes(header)
+
+		// Check
+		legacy := legacyBytesToHeader(buf)
+		got := bytesToHeader(buf)
+
+		assert.Equal(t, int64(1), legacy.FormatVersion, "legacy layout read as format version 1")
+		legacy.FormatVersion = HeaderFormatVersion
+		assert.Equal(t, got, legacy, "legacy layout has same content")
+		assert.Equal(t, HeaderFormatVersion, got.FormatVersion, "current format version read")
+		got.FormatVersion = 0
+		assert.Equal(t, header, got, "header preserved")
+	})
+
+	t.Run("falls back to legacy layout when changed by an older release", func(t *testing.T) {
+		// Prepare
+		buf := headerToBytes(header)
+
+		// Execute
+		binary.LittleEndian.PutUint64(buf[mutationSeqOffset:], 43)
+		got := bytesToHeader(buf)
+
+		// Check
+		assert.Equal(t, int64(1), got.FormatVersion, "legacy layout used")
+		assert.Equal(t, int64(43), got.MutationSeq, "value from legacy layout")
+	})
+
+	t.Run("falls back to legacy layout for unknown format versions", func(t *testing.T) {
+		// Prepare
+		buf := headerToBytes(header)
+
+		// Execute
+		binary.LittleEndian.PutUint16(buf[headerBlockOffset+blockFormatVersionOffset:], uint16(HeaderFormatVersion+1))
+		got := bytesToHeader(buf)
+
+		// Check
+		assert.Equal(t, int64(1), got.FormatVersion, "legacy layout used")
+		assert.Equal(t, header.FileSize, got.FileSize, "value from legacy layout")
+	})
+
+	t.Run("rejects bytes that are not a header", func(t *testing.T) {
+		// Prepare
+		garbage := make([]byte, MapFileHeaderLength)
+		for i := range garbage {
+			garbage[i] = byte(i)
+		}
+		newer := headerToBytes(header)
+		binary.LittleEndian.PutUint16(newer[headerBlockOffset+blockFormatVersionOffset:], uint16(HeaderFormatVersion+1))
+		_ = copy(newer, make([]byte, legacyHeaderLength))
+
+		// Execute
+		_, errGarbage := parseHeader(garbage)
+		_, errNewer := parseHeader(newer)
+		_, errCurrent := parseHeader(headerToBytes(header))
+
+		// Check
+		assert.ErrorIs(t, errGarbage, crt.UnknownFormat{}, "garbage rejected")
+		assert.Equal(t, crt.UnknownFormat{SupportedVersion: HeaderFormatVersion}, errGarbage, "no format version")
+		assert.Equal(t, crt.UnknownFormat{FormatVersion: HeaderFormatVersion + 1, SupportedVersion: HeaderFormatVersion}, errNewer, "newer format version")
+		assert.NoError(t, errCurrent, "current format version accepted")
+	})
+
+	t.Run("writes mutation sequence number in both layouts", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile("testfile", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		assert.NoError(t, err, "creates a file")
+		err = file.Truncate(MapFileHeaderLength)
+		assert.NoError(t, err, "sets file to header size")
+		err = SetHeader(file, header)
+		assert.NoError(t, err, "sets header")
+
+		// Execute
+		err = SetMutationSeq(file, 100)
+
+		// Check
+		assert.NoError(t, err, "sets mutation sequence number")
+		buf := make([]byte, MapFileHeaderLength)
+		_, err = file.ReadAt(buf, 0)
+		assert.NoError(t, err, "reads header")
+		assert.Equal(t, int64(100), legacyBytesToHeader(buf).MutationSeq, "legacy layout updated")
+		got := bytesToHeader(buf)
+		assert.Equal(t, HeaderFormatVersion, got.FormatVersion, "current format version still valid")
+		assert.Equal(t, int64(100), got.MutationSeq, "current format version updated")
+
+		// Clean up
+		err = file.Close()
+		assert.NoError(t, err, "closes file")
+
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+}
+
+func TestSystemValues(t *testing.T) {
+	t.Run("sets, gets and deletes system values in header", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile("testfile", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		assert.NoError(t, err, "creates a file")
+
+		err = file.Truncate(MapFileHeaderLength)
+		assert.NoError(t, err, "sets file to header size")
+
+		// Execute
+		err = SetSystemValue(file, 1, []byte{1, 2, 3})
+		assert.NoError(t, err, "sets first system value")
+		err = SetSystemValue(file, 2, []byte{4, 5})
+		assert.NoError(t, err, "sets second system value")
+		err = SetSystemValue(file, 1, []byte{6})
+		assert.NoError(t, err, "replaces first system value")
+		err = SetHeader(file, Header{KeyLength: 16})
+		assert.NoError(t, err, "sets header")
+
+		// Check
+		value, err := GetSystemValue(file, 1)
+		assert.NoError(t, err, "gets first system value")
+		assert.Equal(t, []byte{6}, value, "first system value is replaced")
+		value, err = GetSystemValue(file, 2)
+		assert.NoError(t, err, "gets second system value")
+		assert.Equal(t, []byte{4, 5}, value, "second system value survives header update")
+
+		err = DeleteSystemValue(file, 2)
+		assert.NoError(t, err, "deletes second system value")
+		_, err = GetSystemValue(file, 2)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "second system value is gone")
+
+		err = SetSystemValue(file, 0, []byte{1})
+		assert.Error(t, err, "id zero is reserved")
+		err = SetSystemValue(file, 3, make([]byte, 256))
+		assert.Error(t, err, "value too long")
+		for i := uint8(3); i < 7; i++ {
+			err = SetSystemValue(file, i, make([]byte, 150))
+			if i < 6 {
+				assert.NoErrorf(t, err, "sets system value %d", i)
+			} else {
+				assert.Errorf(t, err, "no space left for system value %d", i)
+			}
+		}
+
+		// Clean up
+		err = file.Close()
+		assert.NoError(t, err, "closes file")
+
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+}
+
+func TestGetFileSize(t *testing.T) {
+	t.Run("gets size of file regardless of file offset", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile("testfile", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		assert.NoError(t, err, "creates a file")
+
+		_, err = file.WriteAt(make([]byte, 100), 0)
+		assert.NoError(t, err, "writes to file")
+
+		// Execute
+		size, err := GetFileSize(file)
+
+		// Check
+		assert.NoError(t, err, "gets file size")
+		assert.Equal(t, int64(100), size, "correct file size")
+
+		// Clean up
+		_ = file.Close()
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+}
+
+func TestClearFile(t *testing.T) {
+	t.Run("zeroes everything after kept part", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile("testfile", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		assert.NoError(t, err, "creates a file")
+
+		data := make([]byte, 100)
+		for i := range data {
+			data[i] = 0xFF
+		}
+		_, err = file.WriteAt(data, 0)
+		assert.NoError(t, err, "writes to file")
+
+		// Execute
+		err = ClearFile(file, 10, 50)
+
+		// Check
+		assert.NoError(t, err, "clears file")
+
+		size, err := GetFileSize(file)
+		assert.NoError(t, err, "gets file size")
+		assert.Equal(t, int64(50), size, "correct file size")
+
+		buf := make([]byte, 50)
+		_, err = file.ReadAt(buf, 0)
+		assert.NoError(t, err, "reads file")
+		assert.Equal(t, data[:10], buf[:10], "kept part untouched")
+		assert.Equal(t, make([]byte, 40), buf[10:], "rest is zeroed")
+
+		// Clean up
+		_ = file.Close()
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
 	})
 }
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"io"
+	"os"
+)
+
+// copyBufferLength - Is the length of the buffer used when copying files that are not of the operating system
+const copyBufferLength = 1 << 20
+
+// CopyFile - Copies a file into a new file, replacing any existing file with the new name. Files of the operating
+// system are cloned where the file system supports it (reflink on Linux), or else copied by the kernel where possible,
+// while files of other file systems, see vfs.FileSystem, are copied through a buffer. The new file is synced.
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
+//   - from is the name of the file to copy
+//   - to is the name of the new file
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func CopyFile(fileSystem vfs.FileSystem, from, to string) (err error) {
+	fileSystem = ResolveFileSystem(fileSystem)
+
+	src, err := fileSystem.OpenFile(from, os.O_RDONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(src)
+
+	dst, err := fileSystem.OpenFile(to, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	defer func(file vfs.File) { _ = file.Close() }(dst)
+
+	osSrc, srcOK := OSFile(src)
+	osDst, dstOK := OSFile(dst)
+	switch {
+	case srcOK && dstOK && cloneFile(osDst, osSrc):
+	case srcOK && dstOK:
+		_, err = io.Copy(osDst, osSrc)
+	default:
+		err = copyFileAt(dst, src)
+	}
+	if err != nil {
+		return
+	}
+
+	err = dst.Sync()
+
+	return
+}
+
+// copyFileAt - Copies the contents of src to dst using ReadAt and WriteAt
+func copyFileAt(dst, src vfs.File) (err error) {
+	buf := make([]byte, copyBufferLength)
+	var offset int64
+	var n int
+	for {
+		n, err = src.ReadAt(buf, offset)
+		if n > 0 {
+			_, writeErr := dst.WriteAt(buf[:n], offset)
+			if writeErr != nil {
+				err = writeErr
+				return
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	t.Run("copies file of the operating system", func(t *testing.T) {
+		// Prepare
+		from := filepath.Join(t.TempDir(), "from")
+		to := filepath.Join(t.TempDir(), "to")
+		data := make([]byte, 3*copyBufferLength/2)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		err := os.WriteFile(from, data, 0644)
+		assert.NoError(t, err, "creates a file")
+		err = os.WriteFile(to, []byte("old contents that are longer"), 0644)
+		assert.NoError(t, err, "creates a file to replace")
+
+		// Execute
+		err = CopyFile(nil, from, to)
+
+		// Check
+		assert.NoError(t, err, "copies file")
+		copied, err := os.ReadFile(to)
+		assert.NoError(t, err, "reads copy")
+		assert.Equal(t, data, copied, "copy has same contents")
+	})
+
+	t.Run("copies through buffer", func(t *testing.T) {
+		// Prepare
+		from := NewMemFile()
+		to := NewMemFile()
+		data := make([]byte, 3*copyBufferLength/2)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		_, err := from.WriteAt(data, 0)
+		assert.NoError(t, err, "writes memory file")
+
+		// Execute
+		err = copyFileAt(to, from)
+
+		// Check
+		assert.NoError(t, err, "copies file")
+		copied := make([]byte, len(data))
+		_, err = to.ReadAt(copied, 0)
+		assert.NoError(t, err, "reads copy")
+		assert.Equal(t, data, copied, "copy has same contents")
+	})
+
+	t.Run("fails for missing file", func(t *testing.T) {
+		// Execute
+		err := CopyFile(nil, filepath.Join(t.TempDir(), "missing"), filepath.Join(t.TempDir(), "to"))
+
+		// Check
+		assert.Error(t, err, "missing file")
+	})
+}
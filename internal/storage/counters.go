@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// countersSystemValueID - Is the id of the system value holding counts saved by Counters.Save, taken from the top of
+// the id range to stay clear of the ids used by the file hash map itself
+const countersSystemValueID uint8 = 255
+
+// countersValueLength - Is the length of the saved counts, one byte telling whether they were saved clean followed by
+// eight bytes each for the occupied and deleted counts
+const countersValueLength = 17
+
+// Counters - Keeps count of occupied and deleted records in a map, so that utilization can be reported without
+// scanning. The counts are established by one full count the first time they are asked for, and from then on kept up
+// to date by the state transitions reported while records are set and deleted. Transitions reported before the first
+// count are ignored, since the count will include them anyway. The counts can also be kept in the header of a file
+// between opens, see Save and Load. The zero value has not yet been counted.
+type Counters struct {
+	counted  bool
+	occupied int64
+	deleted  int64
+}
+
+// Transition - Reports that a record changed state, where a new record in a place that didn't exist before, such as
+// an appended overflow record, is reported as a transition from model.RecordEmpty
+//   - from is the state of the record before the change
+//   - to is the state of the record after the change
+func (C *Counters) Transition(from, to uint8) {
+	if !C.counted || from == to {
+		return
+	}
+
+	switch from {
+	case model.RecordOccupied:
+		C.occupied--
+	case model.RecordDeleted:
+		C.deleted--
+	}
+
+	switch to {
+	case model.RecordOccupied:
+		C.occupied++
+	case model.RecordDeleted:
+		C.deleted++
+	}
+}
+
+// Counts - Returns the number of occupied and deleted records, calling count to establish them if not yet counted
+//   - count is the function doing a full count of occupied and deleted records
+//
+// It returns:
+//   - occupied is the number of occupied records
+//   - deleted is the number of deleted records
+//   - err is a standard error, if count failed
+func (C *Counters) Counts(count func() (occupied, deleted int64, err error)) (occupied, deleted int64, err error) {
+	if !C.counted {
+		C.occupied, C.deleted, err = count()
+		if err != nil {
+			return
+		}
+		C.counted = true
+	}
+
+	occupied, deleted = C.occupied, C.deleted
+
+	return
+}
+
+// Reset - Sets both counts to zero, e.g. when all records have been removed
+func (C *Counters) Reset() {
+	C.counted = true
+	C.occupied = 0
+	C.deleted = 0
+}
+
+// Load - Sets the counts from the system area of the header of file, if they were saved there by Save as clean. Counts
+// that were not saved as clean, e.g. since the files were not closed properly, are not loaded and have to be counted.
+//   - file is the file with the header holding the counts
+//
+// It returns:
+//   - loaded is true if clean counts were loaded
+//   - err is a standard error, if the header could not be read
+func (C *Counters) Load(file vfs.File) (loaded bool, err error) {
+	occupied, deleted, clean, err := SavedCounts(file)
+	if err != nil || !clean {
+		return
+	}
+
+	C.occupied = occupied
+	C.deleted = deleted
+	C.counted = true
+	loaded = true
+
+	return
+}
+
+// SavedCounts - Returns the counts saved by Save in the system area of the header of file, without loading them
+//   - file is the file with the header holding the counts
+//
+// It returns:
+//   - occupied is the number of occupied records saved
+//   - deleted is the number of deleted records saved
+//   - clean is true if counts were saved as clean, otherwise the counts are not to be trusted
+//   - err is a standard error, if the header could not be read
+func SavedCounts(file vfs.File) (occupied, deleted int64, clean bool, err error) {
+	value, err := GetSystemValue(file, countersSystemValueID)
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+		return
+	}
+	if err != nil || len(value) != countersValueLength {
+		return
+	}
+
+	occupied = int64(binary.LittleEndian.Uint64(value[1:]))
+	deleted = int64(binary.LittleEndian.Uint64(value[9:]))
+	clean = value[0] == 1
+
+	return
+}
+
+// Save - Saves the counts in the system area of the header of file, to be loaded by Load when the file is opened
+// again. Counts are to be saved as not clean while the file is open for writing, so they are counted again if the
+// file is not closed properly, and as clean when it is closed. Nothing is saved if not yet counted.
+//   - file is the file with the header to hold the counts
+//   - clean is true if the counts are final, i.e. the file is being closed
+//
+// It returns:
+//   - err is a standard error, if the header could not be written
+func (C *Counters) Save(file vfs.File, clean bool) (err error) {
+	if !C.counted {
+		return
+	}
+
+	value := make([]byte, countersValueLength)
+	if clean {
+		value[0] = 1
+	}
+	binary.LittleEndian.PutUint64(value[1:], uint64(C.occupied))
+	binary.LittleEndian.PutUint64(value[9:], uint64(C.deleted))
+
+	err = SetSystemValue(file, countersSystemValueID, value)
+
+	return
+}
@@ -0,0 +1,96 @@
+//go:build unit
+
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	t.Run("counts once and then follows transitions", func(t *testing.T) {
+		// Prepare
+		var counters Counters
+		var calls int
+		count := func() (occupied, deleted int64, err error) {
+			calls++
+			return 10, 2, nil
+		}
+
+		// Execute
+		counters.Transition(model.RecordEmpty, model.RecordOccupied)
+		occupied1, deleted1, err1 := counters.Counts(count)
+		counters.Transition(model.RecordEmpty, model.RecordOccupied)
+		counters.Transition(model.RecordDeleted, model.RecordOccupied)
+		counters.Transition(model.RecordOccupied, model.RecordDeleted)
+		counters.Transition(model.RecordOccupied, model.RecordDeleted)
+		counters.Transition(model.RecordOccupied, model.RecordOccupied)
+		occupied2, deleted2, err2 := counters.Counts(count)
+
+		// Check
+		assert.NoError(t, err1, "counts first time")
+		assert.NoError(t, err2, "counts second time")
+		assert.Equal(t, 1, calls, "full count is done once")
+		assert.Equal(t, int64(10), occupied1, "transitions before first count are ignored")
+		assert.Equal(t, int64(2), deleted1, "deleted from first count")
+		assert.Equal(t, int64(10), occupied2, "occupied after transitions")
+		assert.Equal(t, int64(3), deleted2, "deleted after transitions")
+	})
+
+	t.Run("retries a failed count and counts nothing after reset", func(t *testing.T) {
+		// Prepare
+		var counters Counters
+		failing := func() (occupied, deleted int64, err error) { return 0, 0, fmt.Errorf("failed") }
+		unexpected := func() (occupied, deleted int64, err error) { return 5, 5, nil }
+
+		// Execute
+		_, _, errFailed := counters.Counts(failing)
+		counters.Reset()
+		counters.Transition(model.RecordEmpty, model.RecordOccupied)
+		occupied, deleted, err := counters.Counts(unexpected)
+
+		// Check
+		assert.Error(t, errFailed, "failed count is returned")
+		assert.NoError(t, err, "counts after reset")
+		assert.Equal(t, int64(1), occupied, "occupied after reset and one transition")
+		assert.Equal(t, int64(0), deleted, "deleted after reset")
+	})
+}
+
+func TestCounters_SaveLoad(t *testing.T) {
+	t.Run("loads counts saved as clean only", func(t *testing.T) {
+		// Prepare
+		file := NewMemFile()
+		err := file.Truncate(MapFileHeaderLength)
+		assert.NoError(t, err, "truncates file to header length")
+
+		var saved, clean, dirty, missing Counters
+		saved.Reset()
+		saved.Transition(model.RecordEmpty, model.RecordOccupied)
+		saved.Transition(model.RecordEmpty, model.RecordOccupied)
+		saved.Transition(model.RecordOccupied, model.RecordDeleted)
+
+		// Execute
+		loadedMissing, errMissing := missing.Load(file)
+		err = saved.Save(file, true)
+		assert.NoError(t, err, "saves clean counts")
+		loadedClean, errClean := clean.Load(file)
+		err = saved.Save(file, false)
+		assert.NoError(t, err, "saves counts that are not clean")
+		loadedDirty, errDirty := dirty.Load(file)
+
+		// Check
+		assert.NoError(t, errMissing, "loads missing counts")
+		assert.NoError(t, errClean, "loads clean counts")
+		assert.NoError(t, errDirty, "loads counts that are not clean")
+		assert.False(t, loadedMissing, "missing counts are not loaded")
+		assert.True(t, loadedClean, "clean counts are loaded")
+		assert.False(t, loadedDirty, "counts that are not clean are not loaded")
+		occupied, deleted, err := clean.Counts(nil)
+		assert.NoError(t, err, "gets loaded counts")
+		assert.Equal(t, int64(1), occupied, "loaded occupied count")
+		assert.Equal(t, int64(1), deleted, "loaded deleted count")
+	})
+}
@@ -0,0 +1,5 @@
+package cuckoo
+
+// maxDisplacements - Max number of records to kick out of their slots when inserting a new record, before giving up
+// and treating the map file as full
+const maxDisplacements int = 64
@@ -0,0 +1,543 @@
+package cuckoo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/hash"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// CHFiles - Represents an implementation of file support for the Cuckoo Hashing Collision Resolution Technique.
+// It uses one file of buckets, where each key has two candidate buckets given by HashFunc1 and HashFunc2 of the hash
+// algorithm, and a record is always stored in one of them. Hence, getting a record never reads more than two buckets.
+// If both candidate buckets are full on insert, a record is kicked out to its alternative bucket, which may in turn
+// kick out another record, up to a bounded number of times after which the table is considered full.
+type CHFiles struct {
+	storage.BucketFile
+	keyLength                int64
+	valueLength              int64
+	numberOfBucketsNeeded    int64
+	numberOfBucketsAvailable int64
+	recordsPerBucket         int64
+	maxBucketNo              int64
+	hashAlgorithm            hashfunc.HashAlgorithm
+	internalAlgorithm        bool
+	hashParameters           model.HashParameters
+	bucketBuffers            storage.BufferPool
+	counters                 storage.Counters
+}
+
+// NewCHFiles - Returns a pointer to a new instance of Cuckoo Hashing file implementation.
+// It always creates a new file (or opens and truncate existing file). Both HashFunc1 and HashFunc2 of a custom hash
+// algorithm must return a bucket number within the table size.
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - chFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewCHFiles(crtConf model.CRTConf) (chFiles *CHFiles, err error) {
+	chFiles, err = newCHFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	header := chFiles.createHeader()
+
+	err = chFiles.CreateFile(header)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// EstimateCHFiles - Returns the storage parameters that NewCHFiles would give the files, without creating
+// any of them
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - params is the storage parameters, with MapFileSize being the size the map file would be created with
+//   - err which is a standard Go type of error
+func EstimateCHFiles(crtConf model.CRTConf) (params model.StorageParameters, err error) {
+	chFiles, err := newCHFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	params = chFiles.GetStorageParameters()
+
+	return
+}
+
+// newCHFiles - Returns a pointer to a new instance of Cuckoo Hashing file implementation with all parameters set
+// from crtConf, but without any files
+func newCHFiles(crtConf model.CRTConf) (chFiles *CHFiles, err error) {
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	if crtConf.HashAlgorithm == nil {
+		crtConf.HashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.CuckooHashing, crtConf.NumberOfBucketsNeeded, crtConf.HashParameters)
+		if err != nil {
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(crtConf.HashAlgorithm, crtConf.NumberOfBucketsNeeded)
+		if err != nil {
+			return
+		}
+		crtConf.HashParameters = model.HashParameters{}
+	}
+
+	// Calculate the hash map file various parameters
+	recordLength := 1 + crtConf.KeyLength + crtConf.ValueLength // First byte is record state
+	bucketLength := recordLength * crtConf.RecordsPerBucket
+	maxBucketNo := crtConf.HashAlgorithm.GetTableSize() - 1
+	numberOfBuckets := maxBucketNo + 1
+	fileSize := bucketLength*numberOfBuckets + storage.MapFileHeaderLength
+
+	chFiles = &CHFiles{
+		BucketFile: storage.BucketFile{
+			FileName:   storage.GetMapFileName(crtConf.Name),
+			FileSystem: storage.ResolveFileSystem(crtConf.FileSystem),
+			FileSize:   fileSize,
+		},
+		keyLength:                crtConf.KeyLength,
+		valueLength:              crtConf.ValueLength,
+		numberOfBucketsNeeded:    crtConf.NumberOfBucketsNeeded,
+		numberOfBucketsAvailable: numberOfBuckets,
+		recordsPerBucket:         crtConf.RecordsPerBucket,
+		maxBucketNo:              maxBucketNo,
+		hashAlgorithm:            crtConf.HashAlgorithm,
+		internalAlgorithm:        internalAlg,
+		hashParameters:           crtConf.HashParameters,
+	}
+
+	return
+}
+
+// NewCHFilesFromExistingFiles - Returns a pointer to a new instance of Cuckoo Hashing file implementation given
+// existing files. If files doesn't exist, doesn't have a valid header or if its file size seems wrong given
+// size from header it fails with error.
+//   - Name is the name to base map file name on
+//   - hashAlgorithm is the custom hash algorithm the files were used with, nil for the internal
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
+//
+// It returns:
+//   - chFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewCHFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (chFiles *CHFiles, err error) {
+	mapFileName := storage.GetMapFileName(name)
+
+	chFiles = &CHFiles{BucketFile: storage.BucketFile{FileName: mapFileName, FileSystem: storage.ResolveFileSystem(fileSystem)}}
+
+	header, err := chFiles.OpenFile()
+	if err != nil {
+		return
+	}
+
+	// Check for mismatch in choice of hash algorithm
+	if header.InternalHash && hashAlgorithm != nil {
+		chFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the internal hash algorithm but an external was given")
+		return
+	}
+	if !header.InternalHash && hashAlgorithm == nil {
+		chFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the external hash algorithm but no external was given")
+		return
+	}
+
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	hashParameters := model.HashParameters{Kind: header.HashAlgorithmKind, Seed: header.HashSeed}
+	if hashAlgorithm == nil {
+		hashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.CuckooHashing, header.NumberOfBucketsNeeded, hashParameters)
+		if err != nil {
+			chFiles.CloseFiles()
+			return
+		}
+		if hashAlgorithm.GetTableSize() != header.NumberOfBucketsAvailable {
+			chFiles.CloseFiles()
+			err = fmt.Errorf("reconstructed internal hash algorithm doesn't conform with header indicated number of buckets")
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(hashAlgorithm, header.NumberOfBucketsNeeded)
+		if err != nil {
+			chFiles.CloseFiles()
+			return
+		}
+	}
+
+	chFiles.keyLength = header.KeyLength
+	chFiles.valueLength = header.ValueLength
+	chFiles.numberOfBucketsNeeded = header.NumberOfBucketsNeeded
+	chFiles.numberOfBucketsAvailable = header.NumberOfBucketsAvailable
+	chFiles.recordsPerBucket = header.RecordsPerBucket
+	chFiles.maxBucketNo = header.MaxBucketNo
+	chFiles.hashAlgorithm = hashAlgorithm
+	chFiles.internalAlgorithm = internalAlg
+	chFiles.hashParameters = hashParameters
+
+	return
+}
+
+// GetStorageParameters - Returns a struct with storage parameters from CHFiles
+func (C *CHFiles) GetStorageParameters() (params model.StorageParameters) {
+	params = model.StorageParameters{
+		CollisionResolutionTechnique: crt.CuckooHashing,
+		KeyLength:                    C.keyLength,
+		ValueLength:                  C.valueLength,
+		NumberOfBucketsNeeded:        C.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     C.numberOfBucketsAvailable,
+		RecordsPerBucket:             C.recordsPerBucket,
+		MapFileSize:                  C.FileSize,
+		InternalAlgorithm:            C.internalAlgorithm,
+		HashParameters:               C.hashParameters,
+		MutationSeq:                  C.MutationSeq,
+	}
+
+	return
+}
+
+// Counts - Returns the number of occupied and deleted records in the map file. The records are counted by reading
+// all buckets the first time, after that the counts are kept up to date as records are set and deleted.
+//
+// It returns:
+//   - occupied is the number of occupied records, which includes expired records not yet marked as deleted
+//   - deleted is the number of deleted records
+//   - err is a standard error, if something went wrong while counting
+func (C *CHFiles) Counts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = C.counters.Counts(C.countRecords)
+
+	return
+}
+
+// GetBucket - Returns a bucket with its records given the bucket number
+//   - bucketNo is the identifier of a bucket
+//
+// It returns:
+//   - bucket is a model.Bucket struct containing all records in the map file
+//   - overflowIterator is a OverflowRecords struct that can be used to get any overflow records belonging to the bucket. This will always be nil in Cuckoo Hashing.
+//   - err is standard error
+func (C *CHFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error) {
+	bucket, err = C.getBucketRecords(bucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while getting existing bucket records from hash map file: %s", err)
+		return
+	}
+
+	return
+}
+
+// GetBuckets - Reads the buckets from fromBucketNo in a single read of at most maxBytes, but at least one bucket and
+// never past the last one, which is much faster than reading them one by one when scanning all buckets.
+//   - fromBucketNo is the number of the first bucket to read
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - buckets is the buckets read, in bucket order
+//   - overflowIterators is an iterator over the overflow records of each bucket, with nil for buckets without overflow
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error) {
+	bucketLength := (1 + C.keyLength + C.valueLength) * C.recordsPerBucket
+	n := storage.BucketsPerRead(fromBucketNo, C.numberOfBucketsAvailable, bucketLength, maxBytes)
+
+	buf := C.bucketBuffers.Get(n * bucketLength)
+	defer C.bucketBuffers.Put(buf)
+
+	bucketNo := fromBucketNo
+	err = storage.ReadBuckets(C.File, buf, fromBucketNo, bucketLength, func(raw []byte, bucketAddress int64) error {
+		if C.Progress != nil {
+			C.Progress(bucketNo)
+		}
+		bucketNo++
+		buckets = append(buckets, C.bytesToBucket(raw, bucketAddress))
+		overflowIterators = append(overflowIterators, nil)
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("error while getting buckets from hash map file: %s", err)
+		buckets, overflowIterators = nil, nil
+	}
+
+	return
+}
+
+// HomeBucket - Returns the first of the two candidate buckets of key
+func (C *CHFiles) HomeBucket(key []byte) (bucketNo int64) {
+	bucketNo, _ = C.candidateBuckets(key)
+
+	return
+}
+
+// ProbeLength - Returns the number of buckets that a lookup of key probes before reaching bucketNo, i.e. zero for the
+// first candidate bucket and one for the second, or -1 if bucketNo is neither
+func (C *CHFiles) ProbeLength(key []byte, bucketNo int64) (probeLength int64) {
+	bucketNo1, bucketNo2 := C.candidateBuckets(key)
+	switch bucketNo {
+	case bucketNo1:
+		probeLength = 0
+	case bucketNo2:
+		probeLength = 1
+	default:
+		probeLength = -1
+	}
+
+	return
+}
+
+// Get - Gets record that corresponds to the given key.
+// The model.Record that is returned contains also addresses to the actual files that it came from, this is to speed
+// up higher levels functions such as Pop where the same record is also supposed to be deleted in a call to Delete
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - record is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (C *CHFiles) Get(keyRecord model.Record) (record model.Record, err error) {
+	// Check validity of the key
+	if int64(len(keyRecord.Key)) != C.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: "Get", Expected: int(C.keyLength), Actual: len(keyRecord.Key)}
+		return
+	}
+
+	record, err = C.findRecord(keyRecord.Key, C.getBucketRecords)
+
+	return
+}
+
+// Exists - Checks whether a record with the given key exists. With cuckoo hashing whole records are read.
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) Exists(keyRecord model.Record) (exists bool, err error) {
+	_, err = C.Get(keyRecord)
+	exists = err == nil
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+	}
+
+	return
+}
+
+// GetBatch - Gets records that corresponds to the given keys. Keys are processed in first candidate bucket order and
+// each bucket is read at most once, to reduce random reads.
+//   - keyRecords is the identifiers of records, they have to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - records is the matching records in the same order as keyRecords, records not found are returned with State set to model.RecordEmpty
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) GetBatch(keyRecords []model.Record) (records []model.Record, err error) {
+	for _, keyRecord := range keyRecords {
+		if int64(len(keyRecord.Key)) != C.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "GetBatch", Expected: int(C.keyLength), Actual: len(keyRecord.Key)}
+			return
+		}
+	}
+
+	getBucket, _ := C.cachedBucketReader()
+	records = make([]model.Record, len(keyRecords))
+
+	for _, i := range storage.BucketOrder(keyRecords, C.hashAlgorithm.HashFunc1) {
+		records[i], err = C.findRecord(keyRecords[i].Key, getBucket)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		if err != nil {
+			records = nil
+			return
+		}
+	}
+
+	return
+}
+
+// Set - Updates an existing record with new data or add it if no existing is found with same key. Adding a record may
+// kick other records out to their alternative buckets, in which case the records are written starting from the one
+// kicked out last. Hence, if the write is interrupted a kicked out record may exist in two places but is never lost.
+//   - record is the record to set, it needs only to contain Key and Value, and they have to conform to lengths given when creating the CHFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull, which includes
+//     crt.InsertCycle, no records are written.
+func (C *CHFiles) Set(record model.Record) (err error) {
+	_, _, err = C.set(record, false)
+
+	return
+}
+
+// GetOrSet - Returns the existing record with the same key as record if there is one, or else adds record as Set does.
+// The existing record is looked for through the same bucket reads as are used when adding record, so buckets are read
+// only once.
+//   - record is the record to add, it needs only to contain Key and Value, and they have to conform to lengths given when creating the CHFiles
+//
+// It returns:
+//   - existing is the existing record, if loaded is true
+//   - loaded is true if there was an existing record, in which case record was not added
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull, which includes
+//     crt.InsertCycle, no records are written.
+func (C *CHFiles) GetOrSet(record model.Record) (existing model.Record, loaded bool, err error) {
+	existing, loaded, err = C.set(record, true)
+
+	return
+}
+
+// set - Is the implementation of Set and GetOrSet, where onlyIfAbsent set to true returns an existing record with the
+// same key instead of updating it
+func (C *CHFiles) set(record model.Record, onlyIfAbsent bool) (existing model.Record, loaded bool, err error) {
+	operation := "Set"
+	if onlyIfAbsent {
+		operation = "GetOrSet"
+	}
+
+	// Check validity of the key
+	if int64(len(record.Key)) != C.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: int(C.keyLength), Actual: len(record.Key)}
+		return
+	}
+	// Check validity of the value
+	if int64(len(record.Value)) != C.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: operation, Expected: int(C.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	getBucket, _ := C.cachedBucketReader()
+
+	// Update an existing record in place
+	existing, err = C.findRecord(record.Key, getBucket)
+	if err == nil && onlyIfAbsent {
+		loaded = true
+		return
+	}
+	if err == nil {
+		existing.Value = record.Value
+		err = C.setBucketRecord(existing)
+		if err != nil {
+			err = fmt.Errorf("error while updating record in bucket: %s", err)
+		}
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		return
+	}
+
+	changed, freeState, err := C.insertRecord(record.Key, record.Value, getBucket)
+	if err != nil {
+		return
+	}
+
+	// A slot may have been used more than once while kicking out records, where only the last use is to be written
+	written := make(map[int64]bool)
+	for i := len(changed) - 1; i >= 0; i-- {
+		if written[changed[i].RecordAddress] {
+			continue
+		}
+		written[changed[i].RecordAddress] = true
+
+		err = C.setBucketRecord(changed[i])
+		if err != nil {
+			err = fmt.Errorf("error while adding record to bucket: %s", err)
+			return
+		}
+	}
+
+	C.counters.Transition(freeState, model.RecordOccupied)
+
+	return
+}
+
+// SetBatch - Updates existing records with new data or add them if no existing are found with same keys.
+// Since adding a record may kick out other records, records are set one by one in the order given.
+//   - records is the records to set, they need only to contain Key and Value, and they have to conform to lengths given when creating the CHFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If an error occurs some records may have been set and others not.
+func (C *CHFiles) SetBatch(records []model.Record) (err error) {
+	for _, record := range records {
+		if int64(len(record.Key)) != C.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "SetBatch", Expected: int(C.keyLength), Actual: len(record.Key)}
+			return
+		}
+		if int64(len(record.Value)) != C.valueLength {
+			err = crt.WrongLength{Field: "value", Operation: "SetBatch", Expected: int(C.valueLength), Actual: len(record.Value)}
+			return
+		}
+	}
+
+	for _, record := range records {
+		err = C.Set(record)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Delete - Deletes a record by setting state to RecordDeleted
+//   - record is the model.Record to mark as deleted, and it must contain RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) Delete(record model.Record) (err error) {
+	record.State = model.RecordDeleted
+	record.AccessCount = 0
+	record.Key = make([]byte, C.keyLength)
+	record.Value = make([]byte, C.valueLength)
+
+	err = C.setBucketRecord(record)
+	if err != nil {
+		err = fmt.Errorf("error while updating record in bucket: %s", err)
+		return
+	}
+
+	C.counters.Transition(model.RecordOccupied, model.RecordDeleted)
+
+	return
+}
+
+// Clear - Removes all records by discarding everything after the header in the map file and then extending it to its
+// full size again. The header is rewritten, which resets the mutation sequence number, while the system area is kept.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) Clear() (err error) {
+	err = C.ClearFile(C.createHeader())
+	if err != nil {
+		return
+	}
+
+	C.counters.Reset()
+
+	return
+}
+
+// SetValue - Sets the value of a record previously read, in place at its address, so the key is not probed for again.
+//   - record is the model.Record to update, and it must contain RecordAddress and the new Value
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (C *CHFiles) SetValue(record model.Record) (err error) {
+	// Check validity of the value
+	if int64(len(record.Value)) != C.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "SetValue", Expected: int(C.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	_, err = C.File.WriteAt(record.Value, record.RecordAddress+1+C.keyLength) // First byte is record state
+	if err != nil {
+		err = fmt.Errorf("error while writing value to record: %s", err)
+	}
+
+	return
+}
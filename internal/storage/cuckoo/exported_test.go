@@ -0,0 +1,415 @@
+//go:build unit
+
+package cuckoo
+
+import (
+	"errors"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestNewCHFiles(t *testing.T) {
+	t.Run("creates a new CHFiles instance", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		chFiles, err := NewCHFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "create new CHFiles instance")
+		mapFileSize := storage.MapFileHeaderLength + chFiles.numberOfBucketsAvailable*(1+16+10)*2
+		assert.Equal(t, "test-map.bin", chFiles.FileName, "map filename correct")
+		assert.NotNil(t, chFiles.File, "has map file")
+		assert.GreaterOrEqual(t, chFiles.numberOfBucketsAvailable, int64(10), "needed buckets preserved in number of buckets")
+		assert.Equal(t, mapFileSize, chFiles.FileSize, "map file size in correct length")
+		assert.Equal(t, crt.CuckooHashing, chFiles.GetStorageParameters().CollisionResolutionTechnique, "correct crt")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("opens existing files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFilesInit, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = chFilesInit.Set(record)
+		assert.NoError(t, err, "sets record to file")
+		chFilesInit.CloseFiles()
+
+		// Execute
+		chFiles, err := NewCHFilesFromExistingFiles("test", nil, nil)
+
+		// Check
+		assert.NoError(t, err, "opens existing files")
+		assert.Equal(t, chFilesInit.GetStorageParameters(), chFiles.GetStorageParameters(), "storage parameters preserved")
+
+		got, err := chFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is preserved")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestEstimateCHFiles(t *testing.T) {
+	t.Run("estimates storage parameters without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             3,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		params, err := EstimateCHFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "estimate storage parameters")
+		_, statErr := os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(statErr), "no map file created")
+
+		files, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		assert.Equal(t, files.GetStorageParameters(), params, "estimate matches created files")
+
+		// Clean up
+		err = files.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+func TestCHFiles_Set(t *testing.T) {
+	t.Run("sets records until full and gets them from candidate buckets", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        256,
+			RecordsPerBucket:             4,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		capacity := chFiles.numberOfBucketsAvailable * 4
+		records := make([]model.Record, capacity)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+		bucketLength := (1 + chFiles.keyLength + chFiles.valueLength) * 4
+
+		// Execute
+		var n int
+		for n = range records {
+			err = chFiles.Set(records[n])
+			if err != nil {
+				break
+			}
+		}
+
+		// Check
+		assert.ErrorIs(t, err, crt.MapFileFull{}, "correct error when map file is full")
+		assert.Greater(t, float64(n)/float64(capacity), 0.8, "high load factor before full")
+
+		for i, record := range records[:n] {
+			got, err := chFiles.Get(model.Record{Key: record.Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Truef(t, utils.IsEqual(record.Value, got.Value), "value of record #%d is preserved", i)
+
+			bucketNo := (got.RecordAddress - storage.MapFileHeaderLength) / bucketLength
+			bucketNo1, bucketNo2 := chFiles.candidateBuckets(record.Key)
+			assert.Truef(t, bucketNo == bucketNo1 || bucketNo == bucketNo2, "record #%d in a candidate bucket", i)
+		}
+
+		_, err = chFiles.Get(model.Record{Key: records[n].Key})
+		assert.True(t, errors.Is(err, crt.NoRecordFound{}), "record failed to set is not found")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		_, err = os.Stat(chFiles.FileName)
+		assert.True(t, os.IsNotExist(err), "map file removed")
+	})
+
+	t.Run("updates an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = chFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		record.Value = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = chFiles.Set(record)
+
+		// Check
+		assert.NoError(t, err, "updates record in file")
+		got, err := chFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is updated")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails with insert cycle while free slots remain", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                &twoBucketHashAlgorithm{},
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		records := make([]model.Record, 3)
+		for i := range records {
+			records[i] = model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+			rand.Read(records[i].Key)
+		}
+		err = chFiles.Set(records[0])
+		assert.NoError(t, err, "sets first record to file")
+		err = chFiles.Set(records[1])
+		assert.NoError(t, err, "sets second record to file")
+
+		// Execute
+		err = chFiles.Set(records[2])
+
+		// Check
+		assert.ErrorIs(t, err, crt.InsertCycle{}, "correct error when kicking out records goes around in a cycle")
+		assert.ErrorIs(t, err, crt.MapFileFull{}, "insert cycle matches map file full")
+		occupied, _, err := chFiles.Counts()
+		assert.NoError(t, err, "counts records")
+		assert.Equal(t, int64(2), occupied, "nothing written")
+
+		for i, record := range records[:2] {
+			_, err = chFiles.Get(model.Record{Key: record.Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+		}
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+// twoBucketHashAlgorithm - Is a hash algorithm giving every key the first two buckets as candidate buckets
+type twoBucketHashAlgorithm struct {
+	tableSize int64
+}
+
+func (T *twoBucketHashAlgorithm) SetTableSize(tableSize int64) { T.tableSize = tableSize }
+func (T *twoBucketHashAlgorithm) HashFunc1(_ []byte) int64     { return 0 }
+func (T *twoBucketHashAlgorithm) HashFunc2(_ []byte) int64     { return 1 }
+func (T *twoBucketHashAlgorithm) GetTableSize() int64          { return T.tableSize }
+func (T *twoBucketHashAlgorithm) ProbeIteration(hf1Value, _, _ int64) int64 {
+	return hf1Value
+}
+
+func TestCHFiles_Delete(t *testing.T) {
+	t.Run("deletes records and reuses their slots", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        64,
+			RecordsPerBucket:             4,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		records := make([]model.Record, chFiles.numberOfBucketsAvailable*3)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+		for _, record := range records {
+			err := chFiles.Set(record)
+			assert.NoError(t, err, "sets record to file")
+		}
+
+		// Execute
+		half := len(records) / 2
+		for _, record := range records[:half] {
+			got, err := chFiles.Get(model.Record{Key: record.Key})
+			assert.NoError(t, err, "gets record from file")
+			err = chFiles.Delete(got)
+			assert.NoError(t, err, "deletes record from file")
+		}
+
+		// Check
+		for i, record := range records {
+			_, err := chFiles.Get(model.Record{Key: record.Key})
+			if i < half {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "deleted record #%d not found", i)
+			} else {
+				assert.NoErrorf(t, err, "record #%d still found", i)
+			}
+		}
+
+		newRecords := make([]model.Record, int64(half))
+		for i := range newRecords {
+			newRecords[i].Key = make([]byte, 16)
+			rand.Read(newRecords[i].Key)
+			newRecords[i].Value = make([]byte, 10)
+			rand.Read(newRecords[i].Value)
+		}
+		for i, record := range newRecords {
+			err := chFiles.Set(record)
+			assert.NoErrorf(t, err, "sets new record #%d in deleted slot", i)
+		}
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestCHFiles_GetOrSet(t *testing.T) {
+	t.Run("sets an absent record and returns an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+
+		// Execute
+		_, loadedFirst, errFirst := chFiles.GetOrSet(record)
+		existing, loadedSecond, errSecond := chFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.False(t, loadedFirst, "absent record is not loaded")
+		assert.NoError(t, errSecond, "gets existing record")
+		assert.True(t, loadedSecond, "existing record is loaded")
+		assert.True(t, utils.IsEqual(record.Value, existing.Value), "existing value is returned")
+
+		got, err := chFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "existing value is kept")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("treats an expired record as absent", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.CuckooHashing,
+			HashAlgorithm:                nil,
+		}
+
+		chFiles, err := NewCHFiles(crtConf)
+		assert.NoError(t, err, "create new CHFiles instance")
+		chFiles.SetExpiryCheck(func(value []byte) bool { return value[0] == 0xff })
+		record := model.Record{Key: make([]byte, 16), Value: []byte{0xff, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = chFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+		_, loaded, err := chFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, err, "sets record in place of expired record")
+		assert.False(t, loaded, "expired record is not loaded")
+
+		got, err := chFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(other.Value, got.Value), "new value is set")
+
+		// Clean up
+		chFiles.CloseFiles()
+		err = chFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
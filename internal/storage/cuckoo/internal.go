@@ -0,0 +1,333 @@
+package cuckoo
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+)
+
+// getBucketRecords - Returns record for a given bucket number in a model.Bucket struct
+func (C *CHFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err error) {
+	recordLength := 1 + C.keyLength + C.valueLength // First byte is record state
+	bucketLength := recordLength * C.recordsPerBucket
+	bucketAddress := storage.MapFileHeaderLength + bucketNo*bucketLength
+
+	buf := C.bucketBuffers.Get(bucketLength)
+	defer C.bucketBuffers.Put(buf)
+	if C.Progress != nil {
+		C.Progress(bucketNo)
+	}
+
+	_, err = C.File.ReadAt(buf, bucketAddress)
+	if err != nil {
+		return
+	}
+
+	bucket = C.bytesToBucket(buf, bucketAddress)
+
+	return
+}
+
+// setBucketRecord - Sets a bucket record in the hash map file
+func (C *CHFiles) setBucketRecord(record model.Record) (err error) {
+	buf := make([]byte, 1, 1+C.keyLength+C.valueLength) // First byte is record state
+	buf[0] = model.ToStateByte(record.State, record.AccessCount)
+
+	buf = append(buf, record.Key...)
+	buf = append(buf, record.Value...)
+
+	_, err = C.File.WriteAt(buf, record.RecordAddress)
+
+	return
+}
+
+// bytesToBucket - Converts bucket raw data to a Bucket struct
+func (C *CHFiles) bytesToBucket(buf []byte, bucketAddress int64) (bucket model.Bucket) {
+	records := make([]model.Record, C.recordsPerBucket)
+
+	recordLength := 1 + C.keyLength + C.valueLength // First byte is record state
+
+	var keyStart, valueStart int64
+	for n := range records {
+		i := int64(n) * recordLength
+		keyStart = i + 1
+		valueStart = keyStart + C.keyLength
+
+		key := make([]byte, C.keyLength)
+		value := make([]byte, C.valueLength)
+		_ = copy(key, buf[keyStart:keyStart+C.keyLength])
+		_ = copy(value, buf[valueStart:valueStart+C.valueLength])
+		state, accessCount := model.FromStateByte(buf[i])
+
+		records[n] = model.Record{
+			State:         state,
+			AccessCount:   accessCount,
+			RecordAddress: bucketAddress + i,
+			Key:           key,
+			Value:         value,
+		}
+	}
+
+	bucket = model.Bucket{
+		Records:       records,
+		BucketAddress: bucketAddress,
+	}
+
+	return
+}
+
+// createHeader - Creates a header instance
+func (C *CHFiles) createHeader() (header storage.Header) {
+	header = storage.Header{
+		InternalHash:                 C.internalAlgorithm,
+		KeyLength:                    C.keyLength,
+		ValueLength:                  C.valueLength,
+		NumberOfBucketsNeeded:        C.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     C.numberOfBucketsAvailable,
+		RecordsPerBucket:             C.recordsPerBucket,
+		MaxBucketNo:                  C.maxBucketNo,
+		FileSize:                     C.FileSize,
+		CollisionResolutionTechnique: int64(crt.CuckooHashing),
+		HashAlgorithmKind:            C.hashParameters.Kind,
+		HashSeed:                     C.hashParameters.Seed,
+	}
+
+	return
+}
+
+// cachedBucketReader - Returns a function reading buckets that keeps every bucket read in cache, and the cache itself
+func (C *CHFiles) cachedBucketReader() (getBucket func(int64) (model.Bucket, error), cache map[int64]model.Bucket) {
+	cache = make(map[int64]model.Bucket)
+	getBucket = func(bucketNo int64) (bucket model.Bucket, err error) {
+		bucket, ok := cache[bucketNo]
+		if !ok {
+			bucket, err = C.getBucketRecords(bucketNo)
+			if err != nil {
+				return
+			}
+			cache[bucketNo] = bucket
+		}
+		return
+	}
+
+	return
+}
+
+// candidateBuckets - Returns the two candidate buckets for key, which may be the same bucket
+func (C *CHFiles) candidateBuckets(key []byte) (bucketNo1, bucketNo2 int64) {
+	bucketNo1 = C.toBucketNo(C.hashAlgorithm.HashFunc1(key))
+	bucketNo2 = C.toBucketNo(C.hashAlgorithm.HashFunc2(key))
+
+	return
+}
+
+// toBucketNo - Makes sure a hash value from a custom hash algorithm is within the table
+func (C *CHFiles) toBucketNo(hashValue int64) int64 {
+	return (hashValue%C.numberOfBucketsAvailable + C.numberOfBucketsAvailable) % C.numberOfBucketsAvailable
+}
+
+// findRecord - Is the Cuckoo Hashing algorithm for getting a record, which only has to look in the two candidate buckets.
+// Buckets are read using getBucket, which makes it possible to read buckets cached in memory.
+func (C *CHFiles) findRecord(key []byte, getBucket func(int64) (model.Bucket, error)) (record model.Record, err error) {
+	getBucket = storage.CountProbes(getBucket, C.Metrics)
+	getBucket = storage.Interruptible(getBucket, C.Interrupt)
+
+	var bucket model.Bucket
+	var expired bool
+
+	bucketNo1, bucketNo2 := C.candidateBuckets(key)
+
+	for _, bucketNo := range []int64{bucketNo1, bucketNo2} {
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for j, r := range bucket.Records {
+			if r.State != model.RecordOccupied {
+				continue
+			}
+
+			expired, err = C.expireRecord(bucket.Records, j)
+			if err != nil {
+				return
+			}
+			if !expired && utils.IsEqual(key, r.Key) {
+				record = r
+				return
+			}
+		}
+
+		if bucketNo2 == bucketNo1 {
+			break
+		}
+	}
+
+	err = crt.NoRecordFound{}
+	return
+}
+
+// insertRecord - Is the Cuckoo Hashing algorithm for adding a record with a key that doesn't already exist. If none
+// of the candidate buckets has a free slot, a record in the first candidate bucket is kicked out to its alternative
+// bucket, and so on until a record finds a free slot or the max number of displacements is reached.
+// Buckets are read using getBucket, and are updated in memory only.
+//
+// It returns:
+//   - changed is the records to write, in the order they were placed
+//   - freeState is the state of the free slot that was taken, either model.RecordEmpty or model.RecordDeleted
+//   - err is a standard error, of type crt.InsertCycle if no free slot was found while the map file has free slots,
+//     or of type crt.MapFileFull if it hasn't
+func (C *CHFiles) insertRecord(key, value []byte, getBucket func(int64) (model.Bucket, error)) (changed []model.Record, freeState uint8, err error) {
+	getBucket = storage.CountProbes(getBucket, C.Metrics)
+	getBucket = storage.Interruptible(getBucket, C.Interrupt)
+
+	var bucket model.Bucket
+	var placed bool
+
+	carried := model.Record{State: model.RecordOccupied, Key: key, Value: value}
+	bucketNo1, bucketNo2 := C.candidateBuckets(key)
+
+	// Try both candidate buckets before kicking out any record
+	for _, bucketNo := range []int64{bucketNo1, bucketNo2} {
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+		placed, freeState, err = C.placeInFreeSlot(bucket.Records, &carried)
+		if err != nil {
+			return
+		}
+		if placed {
+			changed = append(changed, carried)
+			return
+		}
+	}
+
+	bucketNo := bucketNo1
+	for i := 0; i < maxDisplacements; i++ {
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		// Kick out a record, rotating over slots so the same record isn't kicked out over and over
+		j := i % len(bucket.Records)
+		victim := bucket.Records[j]
+		carried.RecordAddress = victim.RecordAddress
+		bucket.Records[j] = carried
+		changed = append(changed, carried)
+		carried = victim
+
+		// Move the kicked out record to its alternative bucket
+		victimBucketNo1, victimBucketNo2 := C.candidateBuckets(victim.Key)
+		if victimBucketNo1 == bucketNo {
+			bucketNo = victimBucketNo2
+		} else {
+			bucketNo = victimBucketNo1
+		}
+
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+		placed, freeState, err = C.placeInFreeSlot(bucket.Records, &carried)
+		if err != nil {
+			return
+		}
+		if placed {
+			changed = append(changed, carried)
+			return
+		}
+	}
+
+	changed = nil
+	err = C.fullOrCycle()
+	return
+}
+
+// fullOrCycle - Returns the error to give when no free slot was found for a new record, which is crt.InsertCycle if
+// the map file has free slots left, and crt.MapFileFull if not
+func (C *CHFiles) fullOrCycle() (err error) {
+	occupied, _, err := C.counters.Counts(C.countRecords)
+	if err != nil {
+		return
+	}
+
+	if occupied < C.numberOfBucketsAvailable*C.recordsPerBucket {
+		err = crt.InsertCycle{Displacements: maxDisplacements}
+		return
+	}
+
+	err = crt.MapFileFull{}
+
+	return
+}
+
+// placeInFreeSlot - Places record in the first free slot among records, if any, where an expired record counts as free.
+// The address of the slot is set in record, and freeState is the state the slot had before.
+func (C *CHFiles) placeInFreeSlot(records []model.Record, record *model.Record) (placed bool, freeState uint8, err error) {
+	for j := range records {
+		_, err = C.expireRecord(records, j)
+		if err != nil {
+			return
+		}
+
+		if records[j].State != model.RecordOccupied {
+			freeState = records[j].State
+			record.RecordAddress = records[j].RecordAddress
+			records[j] = *record
+			placed = true
+			return
+		}
+	}
+
+	return
+}
+
+// expireRecord - Marks the record at index i in records as deleted, both in file and in records, if it is occupied
+// and has expired according to the expiry check (see SetExpiryCheck)
+func (C *CHFiles) expireRecord(records []model.Record, i int) (expired bool, err error) {
+	if C.IsExpired == nil || records[i].State != model.RecordOccupied || !C.IsExpired(records[i].Value) {
+		return
+	}
+
+	err = C.Delete(records[i])
+	if err != nil {
+		return
+	}
+
+	records[i].State = model.RecordDeleted
+	records[i].AccessCount = 0
+	expired = true
+
+	return
+}
+
+// countRecords - Counts the occupied and deleted records by reading all buckets in the map file
+func (C *CHFiles) countRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	for bucketNo := int64(0); bucketNo < C.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = C.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, record := range bucket.Records {
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
+	return
+}
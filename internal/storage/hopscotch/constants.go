@@ -0,0 +1,8 @@
+package hopscotch
+
+// neighborhoodSize - Max number of consecutive buckets, starting with the home bucket, that a record can be stored in.
+// It equals the number of bits in the neighborhood bitmap.
+const neighborhoodSize int64 = 32
+
+// bucketHeaderLength - Length of the bucket header holding the neighborhood bitmap - 4 bytes
+const bucketHeaderLength int64 = 4
@@ -0,0 +1,565 @@
+package hopscotch
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/hash"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// HSFiles - Represents an implementation of file support for the Hopscotch Collision Resolution Technique.
+// It uses the same file layout as Open Addressing, but where each bucket starts with a header holding a neighborhood
+// bitmap. A record is always stored within the neighborhood of its home bucket, i.e. the home bucket or one of the
+// following buckets up to the neighborhood size, and bit i of the home bucket bitmap is set if bucket home+i may hold
+// records belonging to the home bucket. Hence, getting a record never touches more buckets than the neighborhood size.
+// On insert a free slot is found by probing linearly, and if it is outside the neighborhood, records are moved towards
+// it within their own neighborhoods until the free slot is close enough. If that is not possible the table is
+// considered full.
+type HSFiles struct {
+	storage.BucketFile
+	keyLength                int64
+	valueLength              int64
+	numberOfBucketsNeeded    int64
+	numberOfBucketsAvailable int64
+	recordsPerBucket         int64
+	maxBucketNo              int64
+	neighborhood             int64
+	hashAlgorithm            hashfunc.HashAlgorithm
+	internalAlgorithm        bool
+	hashParameters           model.HashParameters
+	bucketBuffers            storage.BufferPool
+	counters                 storage.Counters
+}
+
+// NewHSFiles - Returns a pointer to a new instance of Hopscotch file implementation.
+// It always creates a new file (or opens and truncate existing file). Only HashFunc1 of a custom hash algorithm is
+// used, since probing is always linear.
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - hsFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewHSFiles(crtConf model.CRTConf) (hsFiles *HSFiles, err error) {
+	hsFiles, err = newHSFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	header := hsFiles.createHeader()
+
+	err = hsFiles.CreateFile(header)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// EstimateHSFiles - Returns the storage parameters that NewHSFiles would give the files, without creating
+// any of them
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - params is the storage parameters, with MapFileSize being the size the map file would be created with
+//   - err which is a standard Go type of error
+func EstimateHSFiles(crtConf model.CRTConf) (params model.StorageParameters, err error) {
+	hsFiles, err := newHSFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	params = hsFiles.GetStorageParameters()
+
+	return
+}
+
+// newHSFiles - Returns a pointer to a new instance of Hopscotch file implementation with all parameters set
+// from crtConf, but without any files
+func newHSFiles(crtConf model.CRTConf) (hsFiles *HSFiles, err error) {
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	if crtConf.HashAlgorithm == nil {
+		crtConf.HashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.LinearProbing, crtConf.NumberOfBucketsNeeded, crtConf.HashParameters)
+		if err != nil {
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(crtConf.HashAlgorithm, crtConf.NumberOfBucketsNeeded)
+		if err != nil {
+			return
+		}
+		crtConf.HashParameters = model.HashParameters{}
+	}
+
+	// Calculate the hash map file various parameters
+	recordLength := 1 + crtConf.KeyLength + crtConf.ValueLength // First byte is record state
+	bucketLength := bucketHeaderLength + recordLength*crtConf.RecordsPerBucket
+	maxBucketNo := crtConf.HashAlgorithm.GetTableSize() - 1
+	numberOfBuckets := maxBucketNo + 1
+	fileSize := bucketLength*numberOfBuckets + storage.MapFileHeaderLength
+
+	hsFiles = &HSFiles{
+		BucketFile: storage.BucketFile{
+			FileName:   storage.GetMapFileName(crtConf.Name),
+			FileSystem: storage.ResolveFileSystem(crtConf.FileSystem),
+			FileSize:   fileSize,
+		},
+		keyLength:                crtConf.KeyLength,
+		valueLength:              crtConf.ValueLength,
+		numberOfBucketsNeeded:    crtConf.NumberOfBucketsNeeded,
+		numberOfBucketsAvailable: numberOfBuckets,
+		recordsPerBucket:         crtConf.RecordsPerBucket,
+		maxBucketNo:              maxBucketNo,
+		neighborhood:             neighborhoodOf(numberOfBuckets),
+		hashAlgorithm:            crtConf.HashAlgorithm,
+		internalAlgorithm:        internalAlg,
+		hashParameters:           crtConf.HashParameters,
+	}
+
+	return
+}
+
+// NewHSFilesFromExistingFiles - Returns a pointer to a new instance of Hopscotch file implementation given
+// existing files. If files doesn't exist, doesn't have a valid header or if its file size seems wrong given
+// size from header it fails with error.
+//   - Name is the name to base map file name on
+//   - hashAlgorithm is the custom hash algorithm the files were used with, nil for the internal
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
+//
+// It returns:
+//   - hsFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewHSFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (hsFiles *HSFiles, err error) {
+	mapFileName := storage.GetMapFileName(name)
+
+	hsFiles = &HSFiles{BucketFile: storage.BucketFile{FileName: mapFileName, FileSystem: storage.ResolveFileSystem(fileSystem)}}
+
+	header, err := hsFiles.OpenFile()
+	if err != nil {
+		return
+	}
+
+	// Check for mismatch in choice of hash algorithm
+	if header.InternalHash && hashAlgorithm != nil {
+		hsFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the internal hash algorithm but an external was given")
+		return
+	}
+	if !header.InternalHash && hashAlgorithm == nil {
+		hsFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the external hash algorithm but no external was given")
+		return
+	}
+
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	hashParameters := model.HashParameters{Kind: header.HashAlgorithmKind, Seed: header.HashSeed}
+	if hashAlgorithm == nil {
+		hashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.LinearProbing, header.NumberOfBucketsNeeded, hashParameters)
+		if err != nil {
+			hsFiles.CloseFiles()
+			return
+		}
+		if hashAlgorithm.GetTableSize() != header.NumberOfBucketsAvailable {
+			hsFiles.CloseFiles()
+			err = fmt.Errorf("reconstructed internal hash algorithm doesn't conform with header indicated number of buckets")
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(hashAlgorithm, header.NumberOfBucketsNeeded)
+		if err != nil {
+			hsFiles.CloseFiles()
+			return
+		}
+	}
+
+	hsFiles.keyLength = header.KeyLength
+	hsFiles.valueLength = header.ValueLength
+	hsFiles.numberOfBucketsNeeded = header.NumberOfBucketsNeeded
+	hsFiles.numberOfBucketsAvailable = header.NumberOfBucketsAvailable
+	hsFiles.recordsPerBucket = header.RecordsPerBucket
+	hsFiles.maxBucketNo = header.MaxBucketNo
+	hsFiles.neighborhood = neighborhoodOf(header.NumberOfBucketsAvailable)
+	hsFiles.hashAlgorithm = hashAlgorithm
+	hsFiles.internalAlgorithm = internalAlg
+	hsFiles.hashParameters = hashParameters
+
+	return
+}
+
+// GetStorageParameters - Returns a struct with storage parameters from HSFiles
+func (H *HSFiles) GetStorageParameters() (params model.StorageParameters) {
+	params = model.StorageParameters{
+		CollisionResolutionTechnique: crt.Hopscotch,
+		KeyLength:                    H.keyLength,
+		ValueLength:                  H.valueLength,
+		NumberOfBucketsNeeded:        H.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     H.numberOfBucketsAvailable,
+		RecordsPerBucket:             H.recordsPerBucket,
+		MapFileSize:                  H.FileSize,
+		InternalAlgorithm:            H.internalAlgorithm,
+		HashParameters:               H.hashParameters,
+		MutationSeq:                  H.MutationSeq,
+	}
+
+	return
+}
+
+// Counts - Returns the number of occupied and deleted records in the map file. The records are counted by reading
+// all buckets the first time, after that the counts are kept up to date as records are set and deleted.
+//
+// It returns:
+//   - occupied is the number of occupied records, which includes expired records not yet marked as deleted
+//   - deleted is the number of deleted records
+//   - err is a standard error, if something went wrong while counting
+func (H *HSFiles) Counts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = H.counters.Counts(H.countRecords)
+
+	return
+}
+
+// GetBucket - Returns a bucket with its records given the bucket number
+//   - bucketNo is the identifier of a bucket
+//
+// It returns:
+//   - bucket is a model.Bucket struct containing all records in the map file
+//   - overflowIterator is a OverflowRecords struct that can be used to get any overflow records belonging to the bucket. This will always be nil in Hopscotch.
+//   - err is standard error
+func (H *HSFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error) {
+	bucket, err = H.getBucketRecords(bucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while getting existing bucket records from hash map file: %s", err)
+		return
+	}
+
+	return
+}
+
+// GetBuckets - Reads the buckets from fromBucketNo in a single read of at most maxBytes, but at least one bucket and
+// never past the last one, which is much faster than reading them one by one when scanning all buckets.
+//   - fromBucketNo is the number of the first bucket to read
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - buckets is the buckets read, in bucket order
+//   - overflowIterators is an iterator over the overflow records of each bucket, with nil for buckets without overflow
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error) {
+	bucketLength := H.bucketLength()
+	n := storage.BucketsPerRead(fromBucketNo, H.numberOfBucketsAvailable, bucketLength, maxBytes)
+
+	buf := H.bucketBuffers.Get(n * bucketLength)
+	defer H.bucketBuffers.Put(buf)
+
+	bucketNo := fromBucketNo
+	err = storage.ReadBuckets(H.File, buf, fromBucketNo, bucketLength, func(raw []byte, bucketAddress int64) error {
+		if H.Progress != nil {
+			H.Progress(bucketNo)
+		}
+		bucketNo++
+		buckets = append(buckets, H.bytesToBucket(raw, bucketAddress))
+		overflowIterators = append(overflowIterators, nil)
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("error while getting buckets from hash map file: %s", err)
+		buckets, overflowIterators = nil, nil
+	}
+
+	return
+}
+
+// HomeBucket - Returns the bucket that key is addressed to, where probing for it starts
+func (H *HSFiles) HomeBucket(key []byte) (bucketNo int64) {
+	bucketNo = H.hashAlgorithm.HashFunc1(key)
+
+	return
+}
+
+// ProbeLength - Returns the number of buckets that a lookup of key probes before reaching bucketNo, i.e. zero if
+// bucketNo is the home bucket, which is the distance from the home bucket
+func (H *HSFiles) ProbeLength(key []byte, bucketNo int64) (probeLength int64) {
+	probeLength = H.distance(H.hashAlgorithm.HashFunc1(key), bucketNo)
+
+	return
+}
+
+// Get - Gets record that corresponds to the given key.
+// The model.Record that is returned contains also addresses to the actual files that it came from, this is to speed
+// up higher levels functions such as Pop where the same record is also supposed to be deleted in a call to Delete
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - record is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (H *HSFiles) Get(keyRecord model.Record) (record model.Record, err error) {
+	// Check validity of the key
+	if int64(len(keyRecord.Key)) != H.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: "Get", Expected: int(H.keyLength), Actual: len(keyRecord.Key)}
+		return
+	}
+
+	record, err = H.findRecord(keyRecord.Key, H.getBucketRecords)
+
+	return
+}
+
+// Exists - Checks whether a record with the given key exists. With hopscotch hashing whole records are read.
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) Exists(keyRecord model.Record) (exists bool, err error) {
+	_, err = H.Get(keyRecord)
+	exists = err == nil
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+	}
+
+	return
+}
+
+// GetBatch - Gets records that corresponds to the given keys. Keys are processed in home bucket order and
+// each bucket is read at most once, to reduce random reads.
+//   - keyRecords is the identifiers of records, they have to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - records is the matching records in the same order as keyRecords, records not found are returned with State set to model.RecordEmpty
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) GetBatch(keyRecords []model.Record) (records []model.Record, err error) {
+	for _, keyRecord := range keyRecords {
+		if int64(len(keyRecord.Key)) != H.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "GetBatch", Expected: int(H.keyLength), Actual: len(keyRecord.Key)}
+			return
+		}
+	}
+
+	getBucket, _ := H.cachedBucketReader()
+	records = make([]model.Record, len(keyRecords))
+
+	for _, i := range storage.BucketOrder(keyRecords, H.hashAlgorithm.HashFunc1) {
+		records[i], err = H.findRecord(keyRecords[i].Key, getBucket)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		if err != nil {
+			records = nil
+			return
+		}
+	}
+
+	return
+}
+
+// Set - Updates an existing record with new data or add it if no existing is found with same key. Adding a record may
+// move other records within their neighborhoods, in which case each record is written to its new slot before its old
+// slot is reused. Hence, if the write is interrupted a moved record may exist in two places but is never lost.
+//   - record is the record to set, it needs only to contain Key and Value, and they have to conform to lengths given when creating the HSFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull no records are written.
+func (H *HSFiles) Set(record model.Record) (err error) {
+	_, _, err = H.set(record, false)
+
+	return
+}
+
+// GetOrSet - Returns the existing record with the same key as record if there is one, or else adds record as Set does.
+// The existing record is looked for through the same bucket reads as are used when adding record, so buckets are read
+// only once.
+//   - record is the record to add, it needs only to contain Key and Value, and they have to conform to lengths given when creating the HSFiles
+//
+// It returns:
+//   - existing is the existing record, if loaded is true
+//   - loaded is true if there was an existing record, in which case record was not added
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull no records are written.
+func (H *HSFiles) GetOrSet(record model.Record) (existing model.Record, loaded bool, err error) {
+	existing, loaded, err = H.set(record, true)
+
+	return
+}
+
+// set - Is the implementation of Set and GetOrSet, where onlyIfAbsent set to true returns an existing record with the
+// same key instead of updating it
+func (H *HSFiles) set(record model.Record, onlyIfAbsent bool) (existing model.Record, loaded bool, err error) {
+	operation := "Set"
+	if onlyIfAbsent {
+		operation = "GetOrSet"
+	}
+
+	// Check validity of the key
+	if int64(len(record.Key)) != H.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: int(H.keyLength), Actual: len(record.Key)}
+		return
+	}
+	// Check validity of the value
+	if int64(len(record.Value)) != H.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: operation, Expected: int(H.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	getBucket, _ := H.cachedBucketReader()
+
+	// Update an existing record in place
+	existing, err = H.findRecord(record.Key, getBucket)
+	if err == nil && onlyIfAbsent {
+		loaded = true
+		return
+	}
+	if err == nil {
+		existing.Value = record.Value
+		err = H.setBucketRecord(existing)
+		if err != nil {
+			err = fmt.Errorf("error while updating record in bucket: %s", err)
+		}
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		return
+	}
+
+	updates, freeState, err := H.insertRecord(record.Key, record.Value, getBucket)
+	if err != nil {
+		return
+	}
+
+	for _, u := range updates {
+		if u.isRecord {
+			err = H.setBucketRecord(u.record)
+		} else {
+			err = H.setNeighborhood(u.bucketNo, u.neighborhood)
+		}
+		if err != nil {
+			err = fmt.Errorf("error while adding record to bucket: %s", err)
+			return
+		}
+	}
+
+	H.counters.Transition(freeState, model.RecordOccupied)
+
+	return
+}
+
+// SetBatch - Updates existing records with new data or add them if no existing are found with same keys.
+// Since adding a record may move other records, records are set one by one in the order given.
+//   - records is the records to set, they need only to contain Key and Value, and they have to conform to lengths given when creating the HSFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If an error occurs some records may have been set and others not.
+func (H *HSFiles) SetBatch(records []model.Record) (err error) {
+	for _, record := range records {
+		if int64(len(record.Key)) != H.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "SetBatch", Expected: int(H.keyLength), Actual: len(record.Key)}
+			return
+		}
+		if int64(len(record.Value)) != H.valueLength {
+			err = crt.WrongLength{Field: "value", Operation: "SetBatch", Expected: int(H.valueLength), Actual: len(record.Value)}
+			return
+		}
+	}
+
+	for _, record := range records {
+		err = H.Set(record)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Delete - Deletes a record by setting state to RecordDeleted. If no other record in the same bucket belongs to the
+// home bucket of the deleted record, the corresponding bit in the neighborhood bitmap of the home bucket is cleared.
+//   - record is the model.Record to mark as deleted, and it must contain RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) Delete(record model.Record) (err error) {
+	bucketNo := H.bucketNoOf(record.RecordAddress)
+	bucket, err := H.getBucketRecords(bucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while reading bucket from file: %s", err)
+		return
+	}
+
+	var homeBucketNo int64 = -1
+	for _, r := range bucket.Records {
+		if r.RecordAddress == record.RecordAddress && r.State == model.RecordOccupied {
+			homeBucketNo = H.hashAlgorithm.HashFunc1(r.Key)
+		}
+	}
+
+	record.State = model.RecordDeleted
+	record.AccessCount = 0
+	record.Key = make([]byte, H.keyLength)
+	record.Value = make([]byte, H.valueLength)
+
+	err = H.setBucketRecord(record)
+	if err != nil {
+		err = fmt.Errorf("error while updating record in bucket: %s", err)
+		return
+	}
+
+	H.counters.Transition(model.RecordOccupied, model.RecordDeleted)
+
+	if homeBucketNo < 0 || H.belongsTo(bucket.Records, homeBucketNo, record.RecordAddress) {
+		return
+	}
+
+	home, err := H.getBucketRecords(homeBucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while reading bucket from file: %s", err)
+		return
+	}
+	err = H.setNeighborhood(homeBucketNo, home.Neighborhood&^(1<<H.distance(homeBucketNo, bucketNo)))
+	if err != nil {
+		err = fmt.Errorf("error while updating neighborhood in bucket: %s", err)
+	}
+
+	return
+}
+
+// Clear - Removes all records by discarding everything after the header in the map file and then extending it to its
+// full size again. The header is rewritten, which resets the mutation sequence number, while the system area is kept.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) Clear() (err error) {
+	err = H.ClearFile(H.createHeader())
+	if err != nil {
+		return
+	}
+
+	H.counters.Reset()
+
+	return
+}
+
+// SetValue - Sets the value of a record previously read, in place at its address, so the key is not probed for again.
+//   - record is the model.Record to update, and it must contain RecordAddress and the new Value
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (H *HSFiles) SetValue(record model.Record) (err error) {
+	// Check validity of the value
+	if int64(len(record.Value)) != H.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "SetValue", Expected: int(H.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	_, err = H.File.WriteAt(record.Value, record.RecordAddress+1+H.keyLength) // First byte is record state
+	if err != nil {
+		err = fmt.Errorf("error while writing value to record: %s", err)
+	}
+
+	return
+}
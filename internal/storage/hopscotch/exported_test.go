@@ -0,0 +1,363 @@
+//go:build unit
+
+package hopscotch
+
+import (
+	"errors"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestNewHSFiles(t *testing.T) {
+	t.Run("creates a new HSFiles instance", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		hsFiles, err := NewHSFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "create new HSFiles instance")
+		mapFileSize := storage.MapFileHeaderLength + hsFiles.numberOfBucketsAvailable*(bucketHeaderLength+(1+16+10)*2)
+		assert.Equal(t, "test-map.bin", hsFiles.FileName, "map filename correct")
+		assert.NotNil(t, hsFiles.File, "has map file")
+		assert.GreaterOrEqual(t, hsFiles.numberOfBucketsAvailable, int64(10), "needed buckets preserved in number of buckets")
+		assert.Equal(t, mapFileSize, hsFiles.FileSize, "map file size in correct length")
+		assert.Equal(t, hsFiles.numberOfBucketsAvailable, hsFiles.neighborhood, "neighborhood limited by table size")
+		assert.Equal(t, crt.Hopscotch, hsFiles.GetStorageParameters().CollisionResolutionTechnique, "correct crt")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("opens existing files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFilesInit, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = hsFilesInit.Set(record)
+		assert.NoError(t, err, "sets record to file")
+		hsFilesInit.CloseFiles()
+
+		// Execute
+		hsFiles, err := NewHSFilesFromExistingFiles("test", nil, nil)
+
+		// Check
+		assert.NoError(t, err, "opens existing files")
+		assert.Equal(t, hsFilesInit.GetStorageParameters(), hsFiles.GetStorageParameters(), "storage parameters preserved")
+		assert.Equal(t, neighborhoodSize, hsFiles.neighborhood, "neighborhood restored")
+
+		got, err := hsFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is preserved")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestEstimateHSFiles(t *testing.T) {
+	t.Run("estimates storage parameters without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             3,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		params, err := EstimateHSFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "estimate storage parameters")
+		_, statErr := os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(statErr), "no map file created")
+
+		files, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		assert.Equal(t, files.GetStorageParameters(), params, "estimate matches created files")
+
+		// Clean up
+		err = files.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+func TestHSFiles_Set(t *testing.T) {
+	t.Run("sets records until full and keeps them within their neighborhood", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        256,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFiles, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		capacity := hsFiles.numberOfBucketsAvailable * 2
+		records := make([]model.Record, capacity+1)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+
+		// Execute
+		var n int
+		for n = range records {
+			err = hsFiles.Set(records[n])
+			if err != nil {
+				break
+			}
+		}
+
+		// Check
+		assert.ErrorIs(t, err, crt.MapFileFull{}, "correct error when map file is full")
+		assert.Greater(t, float64(n)/float64(capacity), 0.9, "high load factor before full")
+
+		for i, record := range records[:n] {
+			got, err := hsFiles.Get(model.Record{Key: record.Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Truef(t, utils.IsEqual(record.Value, got.Value), "value of record #%d is preserved", i)
+
+			homeBucketNo := hsFiles.hashAlgorithm.HashFunc1(record.Key)
+			distance := hsFiles.distance(homeBucketNo, hsFiles.bucketNoOf(got.RecordAddress))
+			assert.Lessf(t, distance, neighborhoodSize, "record #%d within neighborhood", i)
+
+			home, err := hsFiles.getBucketRecords(homeBucketNo)
+			assert.NoError(t, err, "gets home bucket from file")
+			assert.NotZerof(t, home.Neighborhood&(1<<distance), "record #%d flagged in neighborhood", i)
+		}
+
+		_, err = hsFiles.Get(model.Record{Key: records[n].Key})
+		assert.True(t, errors.Is(err, crt.NoRecordFound{}), "record failed to set is not found")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		_, err = os.Stat(hsFiles.FileName)
+		assert.True(t, os.IsNotExist(err), "map file removed")
+	})
+
+	t.Run("updates an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFiles, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = hsFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		record.Value = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = hsFiles.Set(record)
+
+		// Check
+		assert.NoError(t, err, "updates record in file")
+		got, err := hsFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is updated")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestHSFiles_Delete(t *testing.T) {
+	t.Run("deletes records, clears neighborhood bits and reuses slots", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        64,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFiles, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		records := make([]model.Record, hsFiles.numberOfBucketsAvailable/2)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+		for _, record := range records {
+			err := hsFiles.Set(record)
+			assert.NoError(t, err, "sets record to file")
+		}
+
+		// Execute
+		for _, record := range records {
+			got, err := hsFiles.Get(model.Record{Key: record.Key})
+			assert.NoError(t, err, "gets record from file")
+			err = hsFiles.Delete(got)
+			assert.NoError(t, err, "deletes record from file")
+		}
+
+		// Check
+		for i, record := range records {
+			_, err := hsFiles.Get(model.Record{Key: record.Key})
+			assert.ErrorIsf(t, err, crt.NoRecordFound{}, "deleted record #%d not found", i)
+		}
+
+		for bucketNo := int64(0); bucketNo < hsFiles.numberOfBucketsAvailable; bucketNo++ {
+			bucket, err := hsFiles.getBucketRecords(bucketNo)
+			assert.NoError(t, err, "gets bucket from file")
+			assert.Zerof(t, bucket.Neighborhood, "neighborhood of bucket #%d cleared", bucketNo)
+		}
+
+		newRecords := make([]model.Record, hsFiles.numberOfBucketsAvailable/2)
+		for i := range newRecords {
+			newRecords[i].Key = make([]byte, 16)
+			rand.Read(newRecords[i].Key)
+			newRecords[i].Value = make([]byte, 10)
+			rand.Read(newRecords[i].Value)
+		}
+		for i, record := range newRecords {
+			err := hsFiles.Set(record)
+			assert.NoErrorf(t, err, "sets new record #%d in deleted slot", i)
+		}
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestHSFiles_GetOrSet(t *testing.T) {
+	t.Run("sets an absent record and returns an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFiles, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+
+		// Execute
+		_, loadedFirst, errFirst := hsFiles.GetOrSet(record)
+		existing, loadedSecond, errSecond := hsFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.False(t, loadedFirst, "absent record is not loaded")
+		assert.NoError(t, errSecond, "gets existing record")
+		assert.True(t, loadedSecond, "existing record is loaded")
+		assert.True(t, utils.IsEqual(record.Value, existing.Value), "existing value is returned")
+
+		got, err := hsFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "existing value is kept")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("treats an expired record as absent", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hopscotch,
+			HashAlgorithm:                nil,
+		}
+
+		hsFiles, err := NewHSFiles(crtConf)
+		assert.NoError(t, err, "create new HSFiles instance")
+		hsFiles.SetExpiryCheck(func(value []byte) bool { return value[0] == 0xff })
+		record := model.Record{Key: make([]byte, 16), Value: []byte{0xff, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = hsFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+		_, loaded, err := hsFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, err, "sets record in place of expired record")
+		assert.False(t, loaded, "expired record is not loaded")
+
+		got, err := hsFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(other.Value, got.Value), "new value is set")
+
+		// Clean up
+		hsFiles.CloseFiles()
+		err = hsFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,394 @@
+package hopscotch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+)
+
+// getBucketRecords - Returns record for a given bucket number in a model.Bucket struct, including the neighborhood
+// bitmap of the bucket
+func (H *HSFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err error) {
+	bucketAddress := storage.MapFileHeaderLength + bucketNo*H.bucketLength()
+
+	buf := H.bucketBuffers.Get(H.bucketLength())
+	defer H.bucketBuffers.Put(buf)
+	if H.Progress != nil {
+		H.Progress(bucketNo)
+	}
+
+	_, err = H.File.ReadAt(buf, bucketAddress)
+	if err != nil {
+		return
+	}
+
+	bucket = H.bytesToBucket(buf, bucketAddress)
+
+	return
+}
+
+// setBucketRecord - Sets a bucket record in the hash map file
+func (H *HSFiles) setBucketRecord(record model.Record) (err error) {
+	buf := make([]byte, 1, 1+H.keyLength+H.valueLength) // First byte is record state
+	buf[0] = model.ToStateByte(record.State, record.AccessCount)
+
+	buf = append(buf, record.Key...)
+	buf = append(buf, record.Value...)
+
+	_, err = H.File.WriteAt(buf, record.RecordAddress)
+
+	return
+}
+
+// setNeighborhood - Sets the neighborhood bitmap in the header of a bucket in the hash map file
+func (H *HSFiles) setNeighborhood(bucketNo int64, neighborhood uint32) (err error) {
+	buf := make([]byte, bucketHeaderLength)
+	binary.LittleEndian.PutUint32(buf, neighborhood)
+
+	_, err = H.File.WriteAt(buf, storage.MapFileHeaderLength+bucketNo*H.bucketLength())
+
+	return
+}
+
+// bytesToBucket - Converts bucket raw data to a Bucket struct
+func (H *HSFiles) bytesToBucket(buf []byte, bucketAddress int64) (bucket model.Bucket) {
+	records := make([]model.Record, H.recordsPerBucket)
+
+	recordLength := 1 + H.keyLength + H.valueLength // First byte is record state
+
+	var keyStart, valueStart int64
+	for n := range records {
+		i := bucketHeaderLength + int64(n)*recordLength
+		keyStart = i + 1
+		valueStart = keyStart + H.keyLength
+
+		key := make([]byte, H.keyLength)
+		value := make([]byte, H.valueLength)
+		_ = copy(key, buf[keyStart:keyStart+H.keyLength])
+		_ = copy(value, buf[valueStart:valueStart+H.valueLength])
+		state, accessCount := model.FromStateByte(buf[i])
+
+		records[n] = model.Record{
+			State:         state,
+			AccessCount:   accessCount,
+			RecordAddress: bucketAddress + i,
+			Key:           key,
+			Value:         value,
+		}
+	}
+
+	bucket = model.Bucket{
+		Records:       records,
+		BucketAddress: bucketAddress,
+		Neighborhood:  binary.LittleEndian.Uint32(buf),
+	}
+
+	return
+}
+
+// createHeader - Creates a header instance
+func (H *HSFiles) createHeader() (header storage.Header) {
+	header = storage.Header{
+		InternalHash:                 H.internalAlgorithm,
+		KeyLength:                    H.keyLength,
+		ValueLength:                  H.valueLength,
+		NumberOfBucketsNeeded:        H.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     H.numberOfBucketsAvailable,
+		RecordsPerBucket:             H.recordsPerBucket,
+		MaxBucketNo:                  H.maxBucketNo,
+		FileSize:                     H.FileSize,
+		CollisionResolutionTechnique: int64(crt.Hopscotch),
+		HashAlgorithmKind:            H.hashParameters.Kind,
+		HashSeed:                     H.hashParameters.Seed,
+	}
+
+	return
+}
+
+// cachedBucketReader - Returns a function reading buckets that keeps every bucket read in cache, and the cache itself
+func (H *HSFiles) cachedBucketReader() (getBucket func(int64) (model.Bucket, error), cache map[int64]model.Bucket) {
+	cache = make(map[int64]model.Bucket)
+	getBucket = func(bucketNo int64) (bucket model.Bucket, err error) {
+		bucket, ok := cache[bucketNo]
+		if !ok {
+			bucket, err = H.getBucketRecords(bucketNo)
+			if err != nil {
+				return
+			}
+			cache[bucketNo] = bucket
+		}
+		return
+	}
+
+	return
+}
+
+// neighborhoodOf - Returns the neighborhood size to use for a table with numberOfBuckets buckets, which can't be
+// larger than the table itself
+func neighborhoodOf(numberOfBuckets int64) int64 {
+	if numberOfBuckets < neighborhoodSize {
+		return numberOfBuckets
+	}
+
+	return neighborhoodSize
+}
+
+// bucketLength - Returns the length of a bucket including its header
+func (H *HSFiles) bucketLength() int64 {
+	return bucketHeaderLength + (1+H.keyLength+H.valueLength)*H.recordsPerBucket
+}
+
+// bucketNoOf - Returns the number of the bucket that holds the record at recordAddress
+func (H *HSFiles) bucketNoOf(recordAddress int64) int64 {
+	return (recordAddress - storage.MapFileHeaderLength) / H.bucketLength()
+}
+
+// distance - Returns the number of buckets from bucket number from to bucket number to, wrapping around at the end of
+// the table
+func (H *HSFiles) distance(from, to int64) int64 {
+	return (to - from + H.numberOfBucketsAvailable) % H.numberOfBucketsAvailable
+}
+
+// belongsTo - Tells whether any occupied record among records, other than the one at exceptAddress, has homeBucketNo
+// as home bucket
+func (H *HSFiles) belongsTo(records []model.Record, homeBucketNo, exceptAddress int64) bool {
+	for _, r := range records {
+		if r.State == model.RecordOccupied && r.RecordAddress != exceptAddress && H.hashAlgorithm.HashFunc1(r.Key) == homeBucketNo {
+			return true
+		}
+	}
+
+	return false
+}
+
+// update - Represents a write to the map file planned while inserting a record, either a record or the neighborhood
+// bitmap of a bucket
+type update struct {
+	isRecord     bool
+	record       model.Record
+	bucketNo     int64
+	neighborhood uint32
+}
+
+// findRecord - Is the Hopscotch algorithm for getting a record, which only reads the buckets flagged in the
+// neighborhood bitmap of the home bucket. Buckets are read using getBucket, which makes it possible to read buckets
+// cached in memory.
+func (H *HSFiles) findRecord(key []byte, getBucket func(int64) (model.Bucket, error)) (record model.Record, err error) {
+	getBucket = storage.CountProbes(getBucket, H.Metrics)
+	getBucket = storage.Interruptible(getBucket, H.Interrupt)
+
+	var bucket model.Bucket
+	var expired bool
+
+	homeBucketNo := H.hashAlgorithm.HashFunc1(key)
+	home, err := getBucket(homeBucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while reading bucket from file: %s", err)
+		return
+	}
+
+	for d := int64(0); d < H.neighborhood; d++ {
+		if home.Neighborhood&(1<<d) == 0 {
+			continue
+		}
+
+		bucket, err = getBucket((homeBucketNo + d) % H.numberOfBucketsAvailable)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for j, r := range bucket.Records {
+			if r.State != model.RecordOccupied {
+				continue
+			}
+
+			expired, err = H.expireRecord(bucket.Records, j)
+			if err != nil {
+				return
+			}
+			if !expired && utils.IsEqual(key, r.Key) {
+				record = r
+				return
+			}
+		}
+	}
+
+	err = crt.NoRecordFound{}
+	return
+}
+
+// insertRecord - Is the Hopscotch algorithm for adding a record with a key that doesn't already exist. The closest free
+// slot is found by probing linearly from the home bucket. As long as the free slot is outside the neighborhood of the
+// home bucket, a record from a bucket before the free slot, which still has the free slot within its own neighborhood,
+// is moved into it, and the slot it leaves becomes the new free slot.
+// Buckets are read using getBucket, and are updated in memory only.
+//
+// It returns:
+//   - updates is the writes to do, in the order they were planned
+//   - freeState is the state of the free slot that was taken, either model.RecordEmpty or model.RecordDeleted
+//   - err is a standard error, of type crt.MapFileFull if no free slot was found or could be moved close enough
+func (H *HSFiles) insertRecord(key, value []byte, getBucket func(int64) (model.Bucket, error)) (updates []update, freeState uint8, err error) {
+	getBucket = storage.CountProbes(getBucket, H.Metrics)
+	getBucket = storage.Interruptible(getBucket, H.Interrupt)
+
+	var bucket, freeBucket model.Bucket
+	var free, dist int64
+	var found bool
+
+	// Neighborhood bitmaps as changed while planning, since buckets are cached by value
+	neighborhoods := make(map[int64]uint32)
+	getNeighborhood := func(bucketNo int64) (neighborhood uint32, err error) {
+		neighborhood, ok := neighborhoods[bucketNo]
+		if !ok {
+			var b model.Bucket
+			b, err = getBucket(bucketNo)
+			neighborhood = b.Neighborhood
+		}
+		return
+	}
+
+	// Find the closest free slot, where an expired record counts as free
+	homeBucketNo := H.hashAlgorithm.HashFunc1(key)
+	freeBucketNo := homeBucketNo
+	for dist = 0; dist < H.numberOfBucketsAvailable && !found; dist++ {
+		freeBucketNo = (homeBucketNo + dist) % H.numberOfBucketsAvailable
+		freeBucket, err = getBucket(freeBucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for j := range freeBucket.Records {
+			_, err = H.expireRecord(freeBucket.Records, j)
+			if err != nil {
+				return
+			}
+			if freeBucket.Records[j].State != model.RecordOccupied {
+				freeState = freeBucket.Records[j].State
+				free = int64(j)
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		err = crt.MapFileFull{}
+		return
+	}
+	dist--
+
+	// Move the free slot towards the home bucket until it is within the neighborhood
+	for dist >= H.neighborhood {
+		moved := false
+		for d := dist - H.neighborhood + 1; d < dist && !moved; d++ {
+			bucketNo := (homeBucketNo + d) % H.numberOfBucketsAvailable
+			bucket, err = getBucket(bucketNo)
+			if err != nil {
+				err = fmt.Errorf("error while reading bucket from file: %s", err)
+				return
+			}
+
+			for j, r := range bucket.Records {
+				rHomeBucketNo := H.hashAlgorithm.HashFunc1(r.Key)
+				if r.State != model.RecordOccupied || H.distance(rHomeBucketNo, freeBucketNo) >= H.neighborhood {
+					continue
+				}
+
+				var neighborhood uint32
+				neighborhood, err = getNeighborhood(rHomeBucketNo)
+				if err != nil {
+					err = fmt.Errorf("error while reading bucket from file: %s", err)
+					return
+				}
+				neighborhood |= 1 << H.distance(rHomeBucketNo, freeBucketNo)
+				if !H.belongsTo(bucket.Records, rHomeBucketNo, r.RecordAddress) {
+					neighborhood &^= 1 << H.distance(rHomeBucketNo, bucketNo)
+				}
+				neighborhoods[rHomeBucketNo] = neighborhood
+
+				r.RecordAddress = freeBucket.Records[free].RecordAddress
+				freeBucket.Records[free] = r
+				bucket.Records[j].State = model.RecordDeleted
+				updates = append(updates, update{isRecord: true, record: r})
+				updates = append(updates, update{bucketNo: rHomeBucketNo, neighborhood: neighborhood})
+
+				freeBucketNo, freeBucket, free, dist = bucketNo, bucket, int64(j), d
+				moved = true
+				break
+			}
+		}
+
+		if !moved {
+			updates = nil
+			err = crt.MapFileFull{}
+			return
+		}
+	}
+
+	record := model.Record{
+		State:         model.RecordOccupied,
+		RecordAddress: freeBucket.Records[free].RecordAddress,
+		Key:           key,
+		Value:         value,
+	}
+	freeBucket.Records[free] = record
+
+	neighborhood, err := getNeighborhood(homeBucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while reading bucket from file: %s", err)
+		return
+	}
+	neighborhood |= 1 << dist
+	neighborhoods[homeBucketNo] = neighborhood
+
+	updates = append(updates, update{isRecord: true, record: record})
+	updates = append(updates, update{bucketNo: homeBucketNo, neighborhood: neighborhood})
+
+	return
+}
+
+// expireRecord - Marks the record at index i in records as deleted, both in file and in records, if it is occupied
+// and has expired according to the expiry check (see SetExpiryCheck)
+func (H *HSFiles) expireRecord(records []model.Record, i int) (expired bool, err error) {
+	if H.IsExpired == nil || records[i].State != model.RecordOccupied || !H.IsExpired(records[i].Value) {
+		return
+	}
+
+	err = H.Delete(records[i])
+	if err != nil {
+		return
+	}
+
+	records[i].State = model.RecordDeleted
+	records[i].AccessCount = 0
+	expired = true
+
+	return
+}
+
+// countRecords - Counts the occupied and deleted records by reading all buckets in the map file
+func (H *HSFiles) countRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	for bucketNo := int64(0); bucketNo < H.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = H.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, record := range bucket.Records {
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,28 @@
+package storage
+
+import "github.com/gostonefire/filehashmap/internal/model"
+
+// Interruptible - Returns getBucket wrapped so that interrupt is called before every bucket read, and the read is not
+// done if interrupt returns an error, which is returned instead. This is meant for loops that only read until they
+// are done, such as probe loops, so that interrupting them never leaves the files half updated. If interrupt is nil
+// getBucket is returned as is.
+//   - getBucket is the function reading buckets while probing
+//   - interrupt is the function telling whether to stop reading
+//
+// It returns:
+//   - interruptible is the function to read buckets through
+func Interruptible(getBucket func(int64) (model.Bucket, error), interrupt func() error) (interruptible func(int64) (model.Bucket, error)) {
+	if interrupt == nil {
+		interruptible = getBucket
+		return
+	}
+
+	interruptible = func(bucketNo int64) (model.Bucket, error) {
+		if err := interrupt(); err != nil {
+			return model.Bucket{}, err
+		}
+		return getBucket(bucketNo)
+	}
+
+	return
+}
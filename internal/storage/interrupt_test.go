@@ -0,0 +1,42 @@
+//go:build unit
+
+package storage
+
+import (
+	"errors"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterruptible(t *testing.T) {
+	t.Run("stops reading buckets once interrupted", func(t *testing.T) {
+		// Prepare
+		var read []int64
+		getBucket := func(bucketNo int64) (model.Bucket, error) {
+			read = append(read, bucketNo)
+			return model.Bucket{}, nil
+		}
+		stop := errors.New("stop")
+		var interrupted bool
+		interrupt := func() error {
+			if interrupted {
+				return stop
+			}
+			return nil
+		}
+
+		// Execute
+		interruptible := Interruptible(getBucket, interrupt)
+		_, errFirst := interruptible(1)
+		interrupted = true
+		_, errSecond := interruptible(2)
+		_, errUninterrupted := Interruptible(getBucket, nil)(3)
+
+		// Check
+		assert.NoError(t, errFirst, "first bucket read")
+		assert.ErrorIs(t, errSecond, stop, "second bucket interrupted")
+		assert.NoError(t, errUninterrupted, "no interrupt function")
+		assert.Equal(t, []int64{1, 3}, read, "buckets read")
+	})
+}
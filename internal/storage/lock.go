@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+)
+
+// FileLock - Is an advisory lock held on a lock file, which other processes honour when taking locks of their own.
+// The lock is released when Unlock is called or when the process ends.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile - Opens, or creates, the named lock file and takes an exclusive or shared lock on it. Any number of
+// shared locks can be held at the same time, while an exclusive lock excludes all other locks.
+//   - fileSystem is the file system holding the lock file, its files have to be files of the operating system
+//   - fileName is the name of the lock file
+//   - shared set to true takes a shared lock instead of an exclusive one
+//   - wait set to true blocks until the lock can be taken instead of failing fast
+//
+// It returns:
+//   - lock is a pointer to a FileLock struct, or nil if the lock is held by another process and wait is false
+//   - err is a standard error, if the lock file could not be opened or locked
+func LockFile(fileSystem vfs.FileSystem, fileName string, shared, wait bool) (lock *FileLock, err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening lock file: %s", err)
+		return
+	}
+
+	osFile, ok := OSFile(file)
+	if !ok {
+		_ = file.Close()
+		err = fmt.Errorf("file locking is only supported for files of the operating system")
+		return
+	}
+
+	locked, err := lockFile(osFile, shared, wait)
+	if err != nil || !locked {
+		_ = file.Close()
+		if err != nil {
+			err = fmt.Errorf("error while locking file: %s", err)
+		}
+		return
+	}
+
+	lock = &FileLock{file: osFile}
+
+	return
+}
+
+// Unlock - Releases the lock and closes the lock file
+//
+// It returns:
+//   - err is a standard error, if the lock could not be released
+func (F *FileLock) Unlock() (err error) {
+	err = unlockFile(F.file)
+	_ = F.file.Close()
+
+	return
+}
@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile - Returns an error since file locking is not supported on this platform
+func lockFile(file *os.File, shared, wait bool) (locked bool, err error) {
+	err = fmt.Errorf("file locking is not supported on this platform")
+
+	return
+}
+
+// unlockFile - Does nothing since file locking is not supported on this platform
+func unlockFile(file *os.File) (err error) {
+	return
+}
@@ -0,0 +1,77 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestLockFile(t *testing.T) {
+	t.Run("excludes other locks while exclusive", func(t *testing.T) {
+		// Prepare
+		lock, err := LockFile(vfs.OS{}, "testfile", false, false)
+		assert.NoError(t, err, "takes exclusive lock")
+		assert.NotNil(t, lock, "lock returned")
+
+		// Execute
+		exclusive, errExclusive := LockFile(vfs.OS{}, "testfile", false, false)
+		shared, errShared := LockFile(vfs.OS{}, "testfile", true, false)
+
+		// Check
+		assert.NoError(t, errExclusive, "no error when locked elsewhere")
+		assert.Nil(t, exclusive, "exclusive lock not taken")
+		assert.NoError(t, errShared, "no error when locked elsewhere")
+		assert.Nil(t, shared, "shared lock not taken")
+
+		// Clean up
+		err = lock.Unlock()
+		assert.NoError(t, err, "releases lock")
+		_ = os.Remove("testfile")
+	})
+
+	t.Run("shares shared locks and is taken again after release", func(t *testing.T) {
+		// Prepare
+		first, err := LockFile(vfs.OS{}, "testfile", true, false)
+		assert.NoError(t, err, "takes first shared lock")
+
+		// Execute
+		second, errSecond := LockFile(vfs.OS{}, "testfile", true, false)
+		exclusive, errExclusive := LockFile(vfs.OS{}, "testfile", false, false)
+		_ = first.Unlock()
+		_ = second.Unlock()
+		released, errReleased := LockFile(vfs.OS{}, "testfile", false, false)
+
+		// Check
+		assert.NoError(t, errSecond, "takes second shared lock")
+		assert.NotNil(t, second, "second shared lock returned")
+		assert.NoError(t, errExclusive, "no error when shared elsewhere")
+		assert.Nil(t, exclusive, "exclusive lock not taken while shared")
+		assert.NoError(t, errReleased, "takes exclusive lock after release")
+		assert.NotNil(t, released, "exclusive lock returned after release")
+
+		// Clean up
+		_ = released.Unlock()
+		_ = os.Remove("testfile")
+	})
+
+	t.Run("requires files of the operating system", func(t *testing.T) {
+		// Execute
+		lock, err := LockFile(memFileSystem{}, "testfile", false, false)
+
+		// Check
+		assert.Error(t, err, "error for file not of the operating system")
+		assert.Nil(t, lock, "no lock returned")
+	})
+}
+
+// memFileSystem - Is a file system handing out MemFile files
+type memFileSystem struct {
+	vfs.OS
+}
+
+func (M memFileSystem) OpenFile(_ string, _ int, _ os.FileMode) (vfs.File, error) {
+	return NewMemFile(), nil
+}
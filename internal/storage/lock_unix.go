@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile - Takes a lock on file using flock
+//   - file is the file to lock
+//   - shared set to true takes a shared lock instead of an exclusive one
+//   - wait set to true blocks until the lock can be taken
+//
+// It returns:
+//   - locked is false if the lock is held elsewhere and wait is false
+//   - err is a standard error, if the lock could not be taken for another reason
+func lockFile(file *os.File, shared, wait bool) (locked bool, err error) {
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err = syscall.Flock(int(file.Fd()), how)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		err = nil
+		return
+	}
+
+	locked = err == nil
+
+	return
+}
+
+// unlockFile - Releases a lock taken by lockFile
+func unlockFile(file *os.File) (err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	return
+}
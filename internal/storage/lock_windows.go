@@ -0,0 +1,66 @@
+//go:build windows
+
+package storage
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// lockfileFailImmediately, lockfileExclusiveLock - Flags to LockFileEx
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+)
+
+// errorLockViolation - Windows error returned by LockFileEx if the lock is held elsewhere
+const errorLockViolation syscall.Errno = 33
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+// lockFile - Takes a lock on the first byte of file using LockFileEx
+//   - file is the file to lock
+//   - shared set to true takes a shared lock instead of an exclusive one
+//   - wait set to true blocks until the lock can be taken
+//
+// It returns:
+//   - locked is false if the lock is held elsewhere and wait is false
+//   - err is a standard error, if the lock could not be taken for another reason
+func lockFile(file *os.File, shared, wait bool) (locked bool, err error) {
+	var flags uintptr
+	if !shared {
+		flags |= lockfileExclusiveLock
+	}
+	if !wait {
+		flags |= lockfileFailImmediately
+	}
+
+	overlapped := new(syscall.Overlapped)
+	r1, _, e1 := procLockFileEx.Call(file.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r1 == 0 {
+		if e1 != errorLockViolation {
+			err = e1
+		}
+		return
+	}
+
+	locked = true
+
+	return
+}
+
+// unlockFile - Releases a lock taken by lockFile
+func unlockFile(file *os.File) (err error) {
+	overlapped := new(syscall.Overlapped)
+	r1, _, e1 := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r1 == 0 {
+		err = e1
+	}
+
+	return
+}
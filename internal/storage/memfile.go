@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MemFile - Is a vfs.File held in a byte slice, which makes it possible to keep a hash map in memory without touching the
+// filesystem. Contents are lost when it is closed.
+//   - data is the contents of the file
+//   - closed is true once Close has been called
+type MemFile struct {
+	data   []byte
+	closed bool
+}
+
+// NewMemFile - Returns a pointer to a new and empty MemFile
+func NewMemFile() (memFile *MemFile) {
+	memFile = &MemFile{}
+
+	return
+}
+
+// ReadAt - Reads len(p) bytes starting at offset off, as os.File.ReadAt does
+//   - p is the buffer to read into
+//   - off is the offset in the file to read from
+//
+// It returns:
+//   - n is the number of bytes read
+//   - err is io.EOF if fewer than len(p) bytes are within the file, or a standard error if the file is closed
+func (M *MemFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if M.closed {
+		err = fmt.Errorf("read from closed memory file")
+		return
+	}
+	if off < 0 {
+		err = fmt.Errorf("negative offset %d", off)
+		return
+	}
+	if off >= int64(len(M.data)) {
+		err = io.EOF
+		return
+	}
+
+	n = copy(p, M.data[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+
+	return
+}
+
+// WriteAt - Writes len(p) bytes starting at offset off, as os.File.WriteAt does, extending the file if needed
+//   - p is the bytes to write
+//   - off is the offset in the file to write to
+//
+// It returns:
+//   - n is the number of bytes written
+//   - err is a standard error, if the file is closed
+func (M *MemFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if M.closed {
+		err = fmt.Errorf("write to closed memory file")
+		return
+	}
+	if off < 0 {
+		err = fmt.Errorf("negative offset %d", off)
+		return
+	}
+
+	if end := off + int64(len(p)); end > int64(len(M.data)) {
+		err = M.Truncate(end)
+		if err != nil {
+			return
+		}
+	}
+
+	n = copy(M.data[off:], p)
+
+	return
+}
+
+// Truncate - Changes the size of the file, extending it with zeros if it grows
+//   - size is the new size of the file
+//
+// It returns:
+//   - err is a standard error, if the file is closed
+func (M *MemFile) Truncate(size int64) (err error) {
+	if M.closed {
+		err = fmt.Errorf("truncate of closed memory file")
+		return
+	}
+	if size < 0 {
+		err = fmt.Errorf("negative size %d", size)
+		return
+	}
+
+	if size <= int64(cap(M.data)) {
+		tail := M.data[len(M.data):cap(M.data)]
+		if size > int64(len(M.data)) {
+			// Bytes beyond the length may hold data from before an earlier truncate
+			zeros := tail[:size-int64(len(M.data))]
+			for i := range zeros {
+				zeros[i] = 0
+			}
+		}
+		M.data = M.data[:size]
+		return
+	}
+
+	data := make([]byte, size)
+	_ = copy(data, M.data)
+	M.data = data
+
+	return
+}
+
+// Sync - Does nothing since there is no stable storage to commit to
+func (M *MemFile) Sync() (err error) {
+	return
+}
+
+// Stat - Returns information about the file, of which only Size is of any use
+func (M *MemFile) Stat() (info os.FileInfo, err error) {
+	if M.closed {
+		err = fmt.Errorf("stat of closed memory file")
+		return
+	}
+
+	info = memFileInfo{size: int64(len(M.data))}
+
+	return
+}
+
+// Close - Releases the contents of the file
+func (M *MemFile) Close() (err error) {
+	M.data = nil
+	M.closed = true
+
+	return
+}
+
+// memFileInfo - Is the os.FileInfo of a MemFile
+type memFileInfo struct {
+	size int64
+}
+
+func (I memFileInfo) Name() string       { return "" }
+func (I memFileInfo) Size() int64        { return I.size }
+func (I memFileInfo) Mode() os.FileMode  { return 0644 }
+func (I memFileInfo) ModTime() time.Time { return time.Time{} }
+func (I memFileInfo) IsDir() bool        { return false }
+func (I memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,63 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestMemFile(t *testing.T) {
+	t.Run("reads what was written and extends on write", func(t *testing.T) {
+		// Prepare
+		memFile := NewMemFile()
+
+		// Execute
+		_, errWrite := memFile.WriteAt([]byte{1, 2, 3}, 4)
+		buf := make([]byte, 8)
+		n, errRead := memFile.ReadAt(buf, 0)
+
+		// Check
+		assert.NoError(t, errWrite, "writes to file")
+		assert.Equal(t, 7, n, "file extended by write")
+		assert.ErrorIs(t, errRead, io.EOF, "read past end of file")
+		assert.Equal(t, []byte{0, 0, 0, 0, 1, 2, 3, 0}, buf, "reads what was written")
+	})
+
+	t.Run("zeroes data when extended after truncate", func(t *testing.T) {
+		// Prepare
+		memFile := NewMemFile()
+		_, err := memFile.WriteAt([]byte{1, 2, 3, 4}, 0)
+		assert.NoError(t, err, "writes to file")
+
+		// Execute
+		errShrink := memFile.Truncate(1)
+		errExtend := memFile.Truncate(4)
+		buf := make([]byte, 4)
+		_, errRead := memFile.ReadAt(buf, 0)
+
+		// Check
+		assert.NoError(t, errShrink, "shrinks file")
+		assert.NoError(t, errExtend, "extends file")
+		assert.NoError(t, errRead, "reads file")
+		assert.Equal(t, []byte{1, 0, 0, 0}, buf, "extended part is zero")
+	})
+
+	t.Run("fails after close", func(t *testing.T) {
+		// Prepare
+		memFile := NewMemFile()
+		_, err := memFile.WriteAt([]byte{1}, 0)
+		assert.NoError(t, err, "writes to file")
+
+		// Execute
+		err = memFile.Close()
+		_, errRead := memFile.ReadAt(make([]byte, 1), 0)
+		_, errWrite := memFile.WriteAt([]byte{1}, 0)
+
+		// Check
+		assert.NoError(t, err, "closes file")
+		assert.Error(t, errRead, "read from closed file")
+		assert.Error(t, errWrite, "write to closed file")
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// countingFile - Is a vfs.File counting the bytes read from and written to it in a model.Metrics
+type countingFile struct {
+	vfs.File
+	metrics model.Metrics
+}
+
+// ReadAt - Reads from the underlying file and counts the bytes read
+func (C *countingFile) ReadAt(p []byte, off int64) (n int, err error) {
+	n, err = C.File.ReadAt(p, off)
+	C.metrics.AddBytesRead(int64(n))
+
+	return
+}
+
+// WriteAt - Writes to the underlying file and counts the bytes written
+func (C *countingFile) WriteAt(p []byte, off int64) (n int, err error) {
+	n, err = C.File.WriteAt(p, off)
+	C.metrics.AddBytesWritten(int64(n))
+
+	return
+}
+
+// underlying - Returns the underlying file
+func (C *countingFile) underlying() vfs.File {
+	return C.File
+}
+
+// CountBytes - Returns file wrapped so that bytes read from and written to it are counted in metrics, replacing any
+// counting already wrapped around file. Retries made by a RetryFileSystem the file was opened through are counted in
+// metrics as well. If metrics is nil the file is returned without counting.
+//   - file is the file to count bytes for, nil is returned as is
+//   - metrics is the receiver of the counts
+//
+// It returns:
+//   - counted is the file to read and write through
+func CountBytes(file vfs.File, metrics model.Metrics) (counted vfs.File) {
+	if c, ok := file.(*countingFile); ok {
+		file = c.File
+	}
+
+	counted = file
+	if file != nil && metrics != nil {
+		counted = &countingFile{File: file, metrics: metrics}
+	}
+
+	countRetries(file, metrics)
+
+	return
+}
+
+// countRetries - Sets metrics as receiver of retries made by the retrier of file, looking through wrapping files
+func countRetries(file vfs.File, metrics model.Metrics) {
+	for file != nil {
+		if r, ok := file.(*retryFile); ok {
+			r.retrier.Metrics = metrics
+			return
+		}
+		wrapped, isWrapped := file.(wrappedFile)
+		if !isWrapped {
+			return
+		}
+		file = wrapped.underlying()
+	}
+}
+
+// CountProbes - Returns getBucket wrapped so that every bucket read through it is counted as a probe iteration in
+// metrics. If metrics is nil getBucket is returned as is.
+//   - getBucket is the function reading buckets while probing
+//   - metrics is the receiver of the counts
+//
+// It returns:
+//   - counted is the function to read buckets through
+func CountProbes(getBucket func(int64) (model.Bucket, error), metrics model.Metrics) (counted func(int64) (model.Bucket, error)) {
+	if metrics == nil {
+		counted = getBucket
+		return
+	}
+
+	counted = func(bucketNo int64) (model.Bucket, error) {
+		metrics.AddProbeIterations(1)
+		return getBucket(bucketNo)
+	}
+
+	return
+}
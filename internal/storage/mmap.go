@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+)
+
+// MappedFile - Is a file mapped into memory, which is read and written by copying to and from the mapping instead of
+// through system calls. The mapping is shared with the file, so writes made through the file are seen through the
+// mapping and the other way around.
+//   - data is the mapped memory
+//   - flush commits changes made through the mapping to stable storage
+//   - unmap releases the mapping
+type MappedFile struct {
+	data  []byte
+	flush func() error
+	unmap func() error
+}
+
+// ReadAt - Reads len(p) bytes from the mapping starting at offset off, as os.File.ReadAt does
+//   - p is the buffer to read into
+//   - off is the offset in the file to read from
+//
+// It returns:
+//   - n is the number of bytes read
+//   - err is io.EOF if fewer than len(p) bytes are within the mapping, or a standard error if the mapping is closed
+func (M *MappedFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if M.data == nil {
+		err = fmt.Errorf("read from closed memory mapping")
+		return
+	}
+	if off < 0 || off >= int64(len(M.data)) {
+		err = io.EOF
+		return
+	}
+
+	n = copy(p, M.data[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+
+	return
+}
+
+// WriteAt - Writes len(p) bytes to the mapping starting at offset off, as os.File.WriteAt does except that the file
+// can not be extended through the mapping
+//   - p is the bytes to write
+//   - off is the offset in the file to write to
+//
+// It returns:
+//   - n is the number of bytes written
+//   - err is a standard error, if p doesn't fit within the mapping or the mapping is closed
+func (M *MappedFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if M.data == nil {
+		err = fmt.Errorf("write to closed memory mapping")
+		return
+	}
+	if off < 0 || off+int64(len(p)) > int64(len(M.data)) {
+		err = fmt.Errorf("write of %d bytes at offset %d outside memory mapping of %d bytes", len(p), off, len(M.data))
+		return
+	}
+
+	n = copy(M.data[off:], p)
+
+	return
+}
+
+// Sync - Commits changes made through the mapping to stable storage
+func (M *MappedFile) Sync() (err error) {
+	if M.data == nil {
+		return
+	}
+
+	err = M.flush()
+
+	return
+}
+
+// Close - Releases the mapping, changes made through it are still written to the file by the operating system
+func (M *MappedFile) Close() (err error) {
+	if M.data == nil {
+		return
+	}
+
+	err = M.unmap()
+	M.data = nil
+
+	return
+}
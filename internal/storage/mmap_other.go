@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// MapFile - Returns an error on platforms where memory mapping is not supported
+func MapFile(file vfs.File, size int64) (mapped *MappedFile, err error) {
+	err = fmt.Errorf("memory mapping is not supported on this platform")
+
+	return
+}
@@ -0,0 +1,72 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	t.Run("reads and writes through mapping coherent with file", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile(filepath.Join(t.TempDir(), "testfile"), os.O_CREATE|os.O_RDWR, 0644)
+		assert.NoError(t, err, "creates a file")
+		err = file.Truncate(16)
+		assert.NoError(t, err, "sets file size")
+		_, err = file.WriteAt([]byte{1, 2, 3}, 0)
+		assert.NoError(t, err, "writes to file")
+
+		// Execute
+		mapped, err := MapFile(file, 16)
+		assert.NoError(t, err, "maps file")
+
+		buf := make([]byte, 3)
+		_, errRead := mapped.ReadAt(buf, 0)
+		_, errWrite := mapped.WriteAt([]byte{4, 5, 6}, 8)
+		errSync := mapped.Sync()
+
+		// Check
+		assert.NoError(t, errRead, "reads from mapping")
+		assert.Equal(t, []byte{1, 2, 3}, buf, "reads what was written to file")
+		assert.NoError(t, errWrite, "writes to mapping")
+		assert.NoError(t, errSync, "syncs mapping")
+
+		_, err = file.ReadAt(buf, 8)
+		assert.NoError(t, err, "reads from file")
+		assert.Equal(t, []byte{4, 5, 6}, buf, "file has what was written to mapping")
+
+		// Clean up
+		err = mapped.Close()
+		assert.NoError(t, err, "unmaps file")
+		_ = file.Close()
+	})
+
+	t.Run("rejects access outside mapping", func(t *testing.T) {
+		// Prepare
+		file, err := os.OpenFile(filepath.Join(t.TempDir(), "testfile"), os.O_CREATE|os.O_RDWR, 0644)
+		assert.NoError(t, err, "creates a file")
+		err = file.Truncate(16)
+		assert.NoError(t, err, "sets file size")
+		mapped, err := MapFile(file, 16)
+		assert.NoError(t, err, "maps file")
+
+		// Execute
+		n, errRead := mapped.ReadAt(make([]byte, 4), 14)
+		_, errWrite := mapped.WriteAt(make([]byte, 4), 14)
+		_ = mapped.Close()
+		_, errClosed := mapped.ReadAt(make([]byte, 4), 0)
+
+		// Check
+		assert.Equal(t, 2, n, "reads up to end of mapping")
+		assert.ErrorIs(t, errRead, io.EOF, "read past end of mapping")
+		assert.Error(t, errWrite, "write past end of mapping")
+		assert.Error(t, errClosed, "read from closed mapping")
+
+		// Clean up
+		_ = file.Close()
+	})
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"syscall"
+	"unsafe"
+)
+
+// MapFile - Maps the first size bytes of file into memory for reading and writing using mmap.
+//   - file is the file to map, it has to be a file of the operating system opened for reading and writing and be at
+//     least size bytes long
+//   - size is the number of bytes to map
+//
+// It returns:
+//   - mapped is a pointer to a MappedFile struct
+//   - err is a standard error, if the file could not be mapped
+func MapFile(file vfs.File, size int64) (mapped *MappedFile, err error) {
+	osFile, ok := OSFile(file)
+	if !ok {
+		err = fmt.Errorf("memory mapping is only supported for files of the operating system")
+		return
+	}
+
+	data, err := syscall.Mmap(int(osFile.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return
+	}
+
+	mapped = &MappedFile{
+		data: data,
+		flush: func() (err error) {
+			_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), syscall.MS_SYNC)
+			if errno != 0 {
+				err = errno
+			}
+			return
+		},
+		unmap: func() error {
+			return syscall.Munmap(data)
+		},
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"syscall"
+	"unsafe"
+)
+
+// MapFile - Maps the first size bytes of file into memory for reading and writing using a file mapping object.
+//   - file is the file to map, it has to be a file of the operating system opened for reading and writing and be at
+//     least size bytes long
+//   - size is the number of bytes to map
+//
+// It returns:
+//   - mapped is a pointer to a MappedFile struct
+//   - err is a standard error, if the file could not be mapped
+func MapFile(file vfs.File, size int64) (mapped *MappedFile, err error) {
+	osFile, ok := OSFile(file)
+	if !ok {
+		err = fmt.Errorf("memory mapping is only supported for files of the operating system")
+		return
+	}
+
+	handle, err := syscall.CreateFileMapping(syscall.Handle(osFile.Fd()), nil, syscall.PAGE_READWRITE, uint32(size>>32), uint32(size), nil)
+	if err != nil {
+		return
+	}
+
+	addr, err := syscall.MapViewOfFile(handle, syscall.FILE_MAP_WRITE, 0, 0, uintptr(size))
+	if err != nil {
+		_ = syscall.CloseHandle(handle)
+		return
+	}
+
+	// addr is memory outside the Go heap, so it is converted through a pointer to avoid a uintptr conversion
+	mapped = &MappedFile{
+		data: unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size),
+		flush: func() error {
+			return syscall.FlushViewOfFile(addr, uintptr(size))
+		},
+		unmap: func() (err error) {
+			err = syscall.UnmapViewOfFile(addr)
+			if closeErr := syscall.CloseHandle(handle); err == nil {
+				err = closeErr
+			}
+			return
+		},
+	}
+
+	return
+}
@@ -0,0 +1,139 @@
+package openaddressing
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+)
+
+// SetBrentInsertion - Turns Brent's variation of insertion on or off, which is only supported for double hashing.
+// When on, adding a new key may move a record already stored along its own probe sequence, if that shortens the sum of
+// the probes needed to reach both records (see brentSet).
+//   - enabled is true to turn Brent's variation on, false to turn it off
+//
+// It returns:
+//   - err is a standard error, if the collision resolution technique is not double hashing
+func (Q *OAFiles) SetBrentInsertion(enabled bool) (err error) {
+	if enabled && Q.CollisionResolutionTechnique != crt.DoubleHashing {
+		err = fmt.Errorf("brent's variation is only supported by double hashing")
+		return
+	}
+
+	Q.brentInsertion = enabled
+
+	return
+}
+
+// brentSet - Adds record as a new record using Brent's variation. selected is the free record found by probingForSet,
+// at position s in the probe sequence of the key. For each occupied record r in the buckets at positions i < s, the probe
+// sequence of r is followed from its bucket, and if a free record is found j positions further on, with i + j < s, r
+// is moved there and record takes its place. The move with the smallest i + j is chosen, if any, otherwise record is
+// written to selected. Moving a record never leaves an empty record behind, so no other key stops being reachable.
+// The moved record is written before it is replaced, a failure in between leaves it stored twice rather than lost.
+//   - record is the record to add, with Key and Value
+//   - selected is the free record found by probingForSet for the key of record
+//
+// It returns:
+//   - previousState is the state of the free record that got occupied
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) brentSet(record model.Record, selected model.Record) (previousState uint8, err error) {
+	getBucket, _ := Q.cachedBucketReader()
+
+	// The buckets of the probe sequence of the key up to the one holding selected
+	selectedBucketNo := Q.bucketNoOf(selected.RecordAddress)
+	var sequence []int64
+	Q.probeSequence(Q.hashAlgorithm.HashFunc1(record.Key), Q.hashAlgorithm.HashFunc2(record.Key), func(position, bucketNo int64) bool {
+		sequence = append(sequence, bucketNo)
+		return bucketNo == selectedBucketNo
+	})
+
+	best := int64(len(sequence) - 1)
+	var moveFrom, moveTo model.Record
+	for i := int64(0); i < best-1; i++ {
+		var bucket model.Bucket
+		bucket, err = getBucket(sequence[i])
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, r := range bucket.Records {
+			if r.State != model.RecordOccupied {
+				continue
+			}
+
+			// Follow the probe sequence of r from its current bucket, as long as a free record would be an improvement
+			home := int64(-1)
+			Q.probeSequence(Q.hashAlgorithm.HashFunc1(r.Key), Q.hashAlgorithm.HashFunc2(r.Key), func(position, bucketNo int64) bool {
+				if home < 0 {
+					if bucketNo == sequence[i] {
+						home = position
+					}
+					return false
+				}
+				if i+position-home >= best {
+					return true
+				}
+
+				var candidate model.Bucket
+				candidate, err = getBucket(bucketNo)
+				if err != nil {
+					return true
+				}
+				for _, c := range candidate.Records {
+					if c.State != model.RecordOccupied {
+						best = i + position - home
+						moveFrom, moveTo = r, c
+						return true
+					}
+				}
+
+				return false
+			})
+			if err != nil {
+				err = fmt.Errorf("error while reading bucket from file: %s", err)
+				return
+			}
+		}
+	}
+
+	if moveFrom.State != model.RecordOccupied {
+		previousState = selected.State
+		selected.State = model.RecordOccupied
+		selected.Key = record.Key
+		selected.Value = record.Value
+		err = Q.setBucketRecord(selected)
+		return
+	}
+
+	previousState = moveTo.State
+	moved := moveFrom
+	moved.RecordAddress = moveTo.RecordAddress
+	err = Q.setBucketRecord(moved)
+	if err != nil {
+		return
+	}
+
+	moveFrom.AccessCount = 0
+	moveFrom.Key = record.Key
+	moveFrom.Value = record.Value
+	err = Q.setBucketRecord(moveFrom)
+
+	return
+}
+
+// probeSequence - Calls fn with the position and bucket number of each probe within the table in the probe sequence
+// given by the hash values of a key, until fn returns true or the probing cap is reached (see SetProbeCap)
+func (Q *OAFiles) probeSequence(hf1Value, hf2Value int64, fn func(position, bucketNo int64) (stop bool)) {
+	var position int64
+	iMax := Q.probeIterations()
+	for i := int64(0); i < iMax; i++ {
+		probe := Q.hashAlgorithm.ProbeIteration(hf1Value, hf2Value, i)
+		if probe < Q.numberOfBucketsAvailable && probe >= 0 {
+			if fn(position, probe) {
+				return
+			}
+			position++
+		}
+	}
+}
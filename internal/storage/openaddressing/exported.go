@@ -1,6 +1,7 @@
 package openaddressing
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/hashfunc"
@@ -8,7 +9,9 @@ import (
 	"github.com/gostonefire/filehashmap/internal/model"
 	"github.com/gostonefire/filehashmap/internal/overflow"
 	"github.com/gostonefire/filehashmap/internal/storage"
-	"os"
+	"github.com/gostonefire/filehashmap/vfs"
+	"sort"
+	"time"
 )
 
 // OAFiles - Represents an implementation of file support for the Open Addressing Collision Resolution Techniques.
@@ -17,7 +20,10 @@ import (
 // Once all free slots are occupied the table will accept no more records.
 type OAFiles struct {
 	mapFileName                  string
-	mapFile                      *os.File
+	fileSystem                   vfs.FileSystem
+	mapFile                      vfs.File
+	mapped                       *storage.MappedFile
+	cache                        *storage.BlockCache
 	keyLength                    int64
 	valueLength                  int64
 	numberOfBucketsNeeded        int64
@@ -27,6 +33,16 @@ type OAFiles struct {
 	mapFileSize                  int64
 	hashAlgorithm                hashfunc.HashAlgorithm
 	internalAlgorithm            bool
+	hashParameters               model.HashParameters
+	mutationSeq                  int64
+	isExpired                    func(value []byte) bool
+	progress                     func(bucketNo int64)
+	metrics                      model.Metrics
+	interrupt                    func() error
+	probeCap                     int64
+	brentInsertion               bool
+	bucketBuffers                storage.BufferPool
+	counters                     storage.Counters
 	CollisionResolutionTechnique int
 }
 
@@ -38,20 +54,79 @@ type OAFiles struct {
 //   - oaFiles which is a pointer to the created instance
 //   - err which is a standard Go type of error
 func NewOAFiles(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
+	oaFiles, err = newOAFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	err = oaFiles.createNewHashMapFile(oaFiles.createHeader())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// NewOAFilesInMemory - Returns a pointer to a new instance of Open Addressing file implementation that keeps the map
+// file in memory, see storage.MemFile, so no files are created. The name in crtConf is not used, and everything is
+// lost when CloseFiles is called.
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting processing
+//
+// It returns:
+//   - oaFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewOAFilesInMemory(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
+	oaFiles, err = newOAFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	oaFiles.mapFileName = ""
+	oaFiles.mapFile = storage.NewMemFile()
+
+	err = oaFiles.initHashMapFile(oaFiles.createHeader())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// EstimateOAFiles - Returns the storage parameters that NewOAFiles would give the files, without creating
+// any of them
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - params is the storage parameters, with MapFileSize being the size the map file would be created with
+//   - err which is a standard Go type of error
+func EstimateOAFiles(crtConf model.CRTConf) (params model.StorageParameters, err error) {
+	oaFiles, err := newOAFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	params = oaFiles.GetStorageParameters()
+
+	return
+}
+
+// newOAFiles - Returns a pointer to a new instance of Open Addressing file implementation with all parameters set
+// from crtConf, but without any map file
+func newOAFiles(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
 	// If no HashAlgorithm was given then use the default internal
 	var internalAlg bool
 	if crtConf.HashAlgorithm == nil {
-		switch crtConf.CollisionResolutionTechnique {
-		case crt.LinearProbing:
-			crtConf.HashAlgorithm = hash.NewLinearProbingHashAlgorithm(crtConf.NumberOfBucketsNeeded)
-		case crt.QuadraticProbing:
-			crtConf.HashAlgorithm = hash.NewQuadraticProbingHashAlgorithm(crtConf.NumberOfBucketsNeeded)
-		case crt.DoubleHashing:
-			crtConf.HashAlgorithm = hash.NewDoubleHashAlgorithm(crtConf.NumberOfBucketsNeeded)
+		crtConf.HashAlgorithm, err = hash.NewInternalHashAlgorithm(crtConf.CollisionResolutionTechnique, crtConf.NumberOfBucketsNeeded, crtConf.HashParameters)
+		if err != nil {
+			return
 		}
 		internalAlg = true
 	} else {
-		crtConf.HashAlgorithm.SetTableSize(crtConf.NumberOfBucketsNeeded)
+		err = storage.SetTableSize(crtConf.HashAlgorithm, crtConf.NumberOfBucketsNeeded)
+		if err != nil {
+			return
+		}
+		crtConf.HashParameters = model.HashParameters{}
 	}
 
 	// Calculate the hash map file various parameters
@@ -63,6 +138,7 @@ func NewOAFiles(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
 
 	oaFiles = &OAFiles{
 		mapFileName:                  storage.GetMapFileName(crtConf.Name),
+		fileSystem:                   storage.ResolveFileSystem(crtConf.FileSystem),
 		keyLength:                    crtConf.KeyLength,
 		valueLength:                  crtConf.ValueLength,
 		numberOfBucketsNeeded:        crtConf.NumberOfBucketsNeeded,
@@ -72,16 +148,10 @@ func NewOAFiles(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
 		mapFileSize:                  fileSize,
 		hashAlgorithm:                crtConf.HashAlgorithm,
 		internalAlgorithm:            internalAlg,
+		hashParameters:               crtConf.HashParameters,
 		CollisionResolutionTechnique: crtConf.CollisionResolutionTechnique,
 	}
 
-	header := oaFiles.createHeader()
-
-	err = oaFiles.createNewHashMapFile(header)
-	if err != nil {
-		return
-	}
-
 	return
 }
 
@@ -89,14 +159,16 @@ func NewOAFiles(crtConf model.CRTConf) (oaFiles *OAFiles, err error) {
 // existing files. If files doesn't exist, doesn't have a valid header or if its file size seems wrong given
 // size from header it fails with error.
 //   - Name is the name to base map file name on
+//   - hashAlgorithm is the custom hash algorithm the files were used with, nil for the internal
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
 //
 // It returns:
 //   - oaFiles which is a pointer to the created instance
 //   - err which is a standard Go type of error
-func NewOAFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm) (oaFiles *OAFiles, err error) {
+func NewOAFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (oaFiles *OAFiles, err error) {
 	mapFileName := storage.GetMapFileName(name)
 
-	oaFiles = &OAFiles{mapFileName: mapFileName}
+	oaFiles = &OAFiles{fileSystem: storage.ResolveFileSystem(fileSystem), mapFileName: mapFileName}
 
 	header, err := oaFiles.openHashMapFile()
 	if err != nil {
@@ -117,18 +189,25 @@ func NewOAFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorit
 
 	// If no HashAlgorithm was given then use the default internal
 	var internalAlg bool
+	hashParameters := model.HashParameters{Kind: header.HashAlgorithmKind, Seed: header.HashSeed}
 	if hashAlgorithm == nil {
-		switch int(header.CollisionResolutionTechnique) {
-		case crt.LinearProbing:
-			hashAlgorithm = hash.NewLinearProbingHashAlgorithm(header.NumberOfBucketsNeeded)
-		case crt.QuadraticProbing:
-			hashAlgorithm = hash.NewQuadraticProbingHashAlgorithm(header.NumberOfBucketsNeeded)
-		case crt.DoubleHashing:
-			hashAlgorithm = hash.NewDoubleHashAlgorithm(header.NumberOfBucketsNeeded)
+		hashAlgorithm, err = hash.NewInternalHashAlgorithm(int(header.CollisionResolutionTechnique), header.NumberOfBucketsNeeded, hashParameters)
+		if err != nil {
+			oaFiles.CloseFiles()
+			return
+		}
+		if hashAlgorithm.GetTableSize() != header.NumberOfBucketsAvailable {
+			oaFiles.CloseFiles()
+			err = fmt.Errorf("reconstructed internal hash algorithm doesn't conform with header indicated number of buckets")
+			return
 		}
 		internalAlg = true
 	} else {
-		hashAlgorithm.SetTableSize(header.NumberOfBucketsNeeded)
+		err = storage.SetTableSize(hashAlgorithm, header.NumberOfBucketsNeeded)
+		if err != nil {
+			oaFiles.CloseFiles()
+			return
+		}
 	}
 
 	oaFiles.keyLength = header.KeyLength
@@ -140,6 +219,8 @@ func NewOAFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorit
 	oaFiles.mapFileSize = header.FileSize
 	oaFiles.hashAlgorithm = hashAlgorithm
 	oaFiles.internalAlgorithm = internalAlg
+	oaFiles.hashParameters = hashParameters
+	oaFiles.mutationSeq = header.MutationSeq
 	oaFiles.CollisionResolutionTechnique = int(header.CollisionResolutionTechnique)
 
 	return
@@ -147,18 +228,118 @@ func NewOAFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorit
 
 // CloseFiles - Closes the map files
 func (Q *OAFiles) CloseFiles() {
+	if Q.mapped != nil {
+		_ = Q.mapped.Close()
+		Q.mapped = nil
+	}
 	if Q.mapFile != nil {
 		_ = Q.mapFile.Sync()
 		_ = Q.mapFile.Close()
 	}
 }
 
+// Sync - Commits the current contents of the map file to stable storage
+func (Q *OAFiles) Sync() (err error) {
+	if Q.mapped != nil {
+		err = Q.mapped.Sync()
+		if err != nil {
+			err = fmt.Errorf("error while syncing memory mapping of map file: %s", err)
+			return
+		}
+	}
+
+	err = Q.mapFile.Sync()
+	if err != nil {
+		err = fmt.Errorf("error while syncing map file: %s", err)
+	}
+
+	return
+}
+
+// Advise - Declares the expected access pattern of the map file to the operating system, see storage.Advise
+func (Q *OAFiles) Advise(advice int) (err error) {
+	err = storage.Advise(Q.mapFile, advice)
+	if err != nil {
+		err = fmt.Errorf("error while advising on map file: %s", err)
+	}
+
+	return
+}
+
+// MemoryMap - Turns memory mapping of the map file on or off. While on, buckets are read and written by copying to and
+// from a shared mapping of the map file instead of through read and write system calls, while the header is still
+// accessed through the file. The mapping is shared with the file so both stay coherent.
+//   - enabled is true to map the map file into memory, false to release any mapping
+//
+// It returns:
+//   - err is a standard error, if the map file could not be mapped or the mapping could not be released
+func (Q *OAFiles) MemoryMap(enabled bool) (err error) {
+	if enabled == (Q.mapped != nil) {
+		return
+	}
+
+	if enabled {
+		if storage.IsWriteBuffered(Q.mapFile) {
+			err = fmt.Errorf("map file can not be memory mapped while writes are buffered")
+			return
+		}
+		Q.mapped, err = storage.MapFile(Q.mapFile, Q.mapFileSize)
+		if err != nil {
+			Q.mapped = nil
+			err = fmt.Errorf("error while memory mapping map file: %s", err)
+		}
+		return
+	}
+
+	err = Q.mapped.Close()
+	Q.mapped = nil
+	if err != nil {
+		err = fmt.Errorf("error while releasing memory mapping of map file: %s", err)
+	}
+
+	return
+}
+
+// SetBucketCache - Turns caching of recently read buckets on or off. While on, buckets read from the map file are
+// kept in a least recently used cache within a budget of bytes, so buckets visited again while probing are not read
+// from disk again. Cached buckets are invalidated whenever they are written.
+//   - budget is the maximum number of bytes of buckets to cache, zero turns caching off
+//
+// It returns:
+//   - err is a standard error, if budget is negative
+func (Q *OAFiles) SetBucketCache(budget int64) (err error) {
+	if budget < 0 {
+		err = fmt.Errorf("bucket cache budget can not be negative")
+		return
+	}
+
+	Q.cache = nil
+	if budget > 0 {
+		Q.cache = storage.NewBlockCache(budget)
+	}
+
+	return
+}
+
+// SetMutationSeq - Persists the sequence number of the last applied mutation in the map file header
+func (Q *OAFiles) SetMutationSeq(seq int64) (err error) {
+	err = storage.SetMutationSeq(Q.mapFile, seq)
+	if err != nil {
+		err = fmt.Errorf("error while writing mutation sequence number to map file header: %s", err)
+		return
+	}
+
+	Q.mutationSeq = seq
+
+	return
+}
+
 // RemoveFiles - Removes the map files, make sure to close them first before calling this function
 func (Q *OAFiles) RemoveFiles() (err error) {
 	// Only try to remove if exists, and are not by accident directories (could happen when testing things out)
-	if stat, ok := os.Stat(Q.mapFileName); ok == nil {
+	if stat, ok := Q.fileSystem.Stat(Q.mapFileName); ok == nil {
 		if !stat.IsDir() {
-			err = os.Remove(Q.mapFileName)
+			err = Q.fileSystem.Remove(Q.mapFileName)
 			if err != nil {
 				err = fmt.Errorf("error while removing map file: %s", err)
 				return
@@ -180,11 +361,26 @@ func (Q *OAFiles) GetStorageParameters() (params model.StorageParameters) {
 		RecordsPerBucket:             Q.recordsPerBucket,
 		MapFileSize:                  Q.mapFileSize,
 		InternalAlgorithm:            Q.internalAlgorithm,
+		HashParameters:               Q.hashParameters,
+		MutationSeq:                  Q.mutationSeq,
 	}
 
 	return
 }
 
+// Counts - Returns the number of occupied and deleted records in the map file. The records are counted by reading
+// all buckets the first time, after that the counts are kept up to date as records are set and deleted.
+//
+// It returns:
+//   - occupied is the number of occupied records, which includes expired records not yet marked as deleted
+//   - deleted is the number of deleted records
+//   - err is a standard error, if something went wrong while counting
+func (Q *OAFiles) Counts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = Q.counters.Counts(Q.countRecords)
+
+	return
+}
+
 // GetBucket - Returns a bucket with its records given the bucket number
 //   - bucketNo is the identifier of a bucket, the number can be retrieved by call to getBucketNo
 //
@@ -203,6 +399,77 @@ func (Q *OAFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterat
 	return
 }
 
+// GetBuckets - Reads the buckets from fromBucketNo in a single read of at most maxBytes, but at least one bucket and
+// never past the last one, which is much faster than reading them one by one when scanning all buckets.
+//   - fromBucketNo is the number of the first bucket to read
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - buckets is the buckets read, in bucket order
+//   - overflowIterators is an iterator over the overflow records of each bucket, with nil for buckets without overflow
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error) {
+	bucketLength := (1 + Q.keyLength + Q.valueLength) * Q.recordsPerBucket
+	n := storage.BucketsPerRead(fromBucketNo, Q.numberOfBucketsAvailable, bucketLength, maxBytes)
+
+	buf := Q.bucketBuffers.Get(n * bucketLength)
+	defer Q.bucketBuffers.Put(buf)
+
+	bucketNo := fromBucketNo
+	err = storage.ReadBuckets(Q.bucketAccess(), buf, fromBucketNo, bucketLength, func(raw []byte, bucketAddress int64) error {
+		if Q.progress != nil {
+			Q.progress(bucketNo)
+		}
+		bucketNo++
+		bucket, decodeErr := Q.bytesToBucket(raw, bucketAddress, Q.recordsPerBucket)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		buckets = append(buckets, bucket)
+		overflowIterators = append(overflowIterators, nil)
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("error while getting buckets from hash map file: %s", err)
+		buckets, overflowIterators = nil, nil
+	}
+
+	return
+}
+
+// HomeBucket - Returns the bucket that key is addressed to, where probing for it starts
+func (Q *OAFiles) HomeBucket(key []byte) (bucketNo int64) {
+	bucketNo = Q.hashAlgorithm.HashFunc1(key)
+
+	return
+}
+
+// ProbeLength - Returns the number of buckets that a lookup of key probes before reaching bucketNo, i.e. zero if
+// bucketNo is the home bucket, or -1 if bucketNo is not within the probe sequence of key
+func (Q *OAFiles) ProbeLength(key []byte, bucketNo int64) (probeLength int64) {
+	var probe int64
+
+	hf1Value := Q.hashAlgorithm.HashFunc1(key)
+	hf2Value := Q.hashAlgorithm.HashFunc2(key)
+
+	iMax := Q.probeIterations() // To avoid infinite loop if hash algorithm is behaving bad
+
+	for i := int64(0); i < iMax; i++ {
+		probe = Q.hashAlgorithm.ProbeIteration(hf1Value, hf2Value, i)
+		if probe < Q.numberOfBucketsAvailable && probe >= 0 {
+			if probe == bucketNo {
+				return
+			}
+			probeLength++
+		}
+	}
+
+	probeLength = -1
+
+	return
+}
+
 // Get - Gets record that corresponds to the given key.
 // The model.Record that is returned contains also addresses to the actual files that it came from, this is to speed
 // up higher levels functions such as Pop where the same record is also supposed to be deleted in a call to Delete
@@ -214,12 +481,73 @@ func (Q *OAFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterat
 func (Q *OAFiles) Get(keyRecord model.Record) (record model.Record, err error) {
 	// Check validity of the key
 	if int64(len(keyRecord.Key)) != Q.keyLength {
-		err = fmt.Errorf("wrong length of key, should be %d", Q.keyLength)
+		err = crt.WrongLength{Field: "key", Operation: "Get", Expected: int(Q.keyLength), Actual: len(keyRecord.Key)}
 		return
 	}
 
 	// Tro to find the key in the file
-	record, err = Q.probingForGet(keyRecord.Key)
+	record, err = Q.probingForGet(keyRecord.Key, Q.getBucketRecords)
+
+	return
+}
+
+// Exists - Checks whether a record with the given key exists. Only the state byte and key of each probed record is
+// read, unless an expiry check is set (see SetExpiryCheck) or buckets are cached (see SetBucketCache) in which case
+// whole buckets are read since expiry times are kept in values and cached buckets are cheaper to read as a whole.
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) Exists(keyRecord model.Record) (exists bool, err error) {
+	// Check validity of the key
+	if int64(len(keyRecord.Key)) != Q.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: "Exists", Expected: int(Q.keyLength), Actual: len(keyRecord.Key)}
+		return
+	}
+
+	getBucket := Q.getBucketKeys
+	if Q.isExpired != nil || Q.cache != nil {
+		getBucket = Q.getBucketRecords
+	}
+
+	_, err = Q.probingForGet(keyRecord.Key, getBucket)
+	exists = err == nil
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+	}
+
+	return
+}
+
+// GetBatch - Gets records that corresponds to the given keys. Keys are processed in home bucket order and each
+// bucket is read at most once, to reduce random reads.
+//   - keyRecords is the identifiers of records, they have to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - records is the matching records in the same order as keyRecords, records not found are returned with State set to model.RecordEmpty
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) GetBatch(keyRecords []model.Record) (records []model.Record, err error) {
+	for _, keyRecord := range keyRecords {
+		if int64(len(keyRecord.Key)) != Q.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "GetBatch", Expected: int(Q.keyLength), Actual: len(keyRecord.Key)}
+			return
+		}
+	}
+
+	getBucket, _ := Q.cachedBucketReader()
+	records = make([]model.Record, len(keyRecords))
+
+	for _, i := range storage.BucketOrder(keyRecords, Q.hashAlgorithm.HashFunc1) {
+		records[i], err = Q.probingForGet(keyRecords[i].Key, getBucket)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		if err != nil {
+			records = nil
+			return
+		}
+	}
 
 	return
 }
@@ -230,32 +558,149 @@ func (Q *OAFiles) Get(keyRecord model.Record) (record model.Record, err error) {
 // It returns:
 //   - err is a standard error, if something went wrong
 func (Q *OAFiles) Set(record model.Record) (err error) {
+	_, _, err = Q.set(record, false)
+
+	return
+}
+
+// GetOrSet - Returns the existing record with the same key as record if there is one, or else adds record. The existing
+// record is looked for while probing for where to add record, so buckets are searched only once.
+//   - record is the record to add, it needs only to contain Key and Value, and they have to conform to lengths given when creating the SCFiles
+//
+// It returns:
+//   - existing is the existing record, if loaded is true
+//   - loaded is true if there was an existing record, in which case record was not added
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) GetOrSet(record model.Record) (existing model.Record, loaded bool, err error) {
+	existing, loaded, err = Q.set(record, true)
+
+	return
+}
+
+// set - Is the implementation of Set and GetOrSet, where onlyIfAbsent set to true returns an existing record with the
+// same key instead of updating it
+func (Q *OAFiles) set(record model.Record, onlyIfAbsent bool) (existing model.Record, loaded bool, err error) {
+	operation := "Set"
+	if onlyIfAbsent {
+		operation = "GetOrSet"
+	}
+
 	// Check validity of the key
 	if int64(len(record.Key)) != Q.keyLength {
-		err = fmt.Errorf("wrong length of key, should be %d", Q.keyLength)
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: int(Q.keyLength), Actual: len(record.Key)}
 		return
 	}
 	// Check validity of the value
 	if int64(len(record.Value)) != Q.valueLength {
-		err = fmt.Errorf("wrong length of value, should be %d", Q.valueLength)
+		err = crt.WrongLength{Field: "value", Operation: operation, Expected: int(Q.valueLength), Actual: len(record.Value)}
 		return
 	}
 
-	selectedRecord, err := Q.probingForSet(record.Key)
+	selectedRecord, err := Q.probingForSet(record.Key, Q.getBucketRecords)
 	if err != nil {
 		return
 	}
 
-	selectedRecord.State = model.RecordOccupied
-	selectedRecord.Key = record.Key
-	selectedRecord.Value = record.Value
+	// An occupied record returned from probing always has the same key
+	if onlyIfAbsent && selectedRecord.State == model.RecordOccupied && (Q.isExpired == nil || !Q.isExpired(selectedRecord.Value)) {
+		existing, loaded = selectedRecord, true
+		return
+	}
 
-	err = Q.setBucketRecord(selectedRecord)
+	var previousState uint8
+	if Q.brentInsertion && selectedRecord.State != model.RecordOccupied {
+		previousState, err = Q.brentSet(record, selectedRecord)
+	} else {
+		previousState = selectedRecord.State
+		selectedRecord.State = model.RecordOccupied
+		selectedRecord.Key = record.Key
+		selectedRecord.Value = record.Value
+		err = Q.setBucketRecord(selectedRecord)
+	}
 	if err != nil {
 		err = fmt.Errorf("error while updating or adding record to bucket: %s", err)
 		return
 	}
 
+	Q.counters.Transition(previousState, model.RecordOccupied)
+
+	return
+}
+
+// SetBatch - Updates existing records with new data or add them if no existing are found with same keys.
+// Buckets are read at most once and kept in memory while probing for all records, then all changed buckets are
+// written in file offset order where adjacent buckets are written together in one operation.
+// If the same key occurs more than once in records, the last occurrence wins.
+//   - records is the records to set, they need only to contain Key and Value, and they have to conform to lengths given when creating the OAFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull no records are written.
+func (Q *OAFiles) SetBatch(records []model.Record) (err error) {
+	for _, record := range records {
+		if int64(len(record.Key)) != Q.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "SetBatch", Expected: int(Q.keyLength), Actual: len(record.Key)}
+			return
+		}
+		if int64(len(record.Value)) != Q.valueLength {
+			err = crt.WrongLength{Field: "value", Operation: "SetBatch", Expected: int(Q.valueLength), Actual: len(record.Value)}
+			return
+		}
+	}
+
+	// Visit records in home bucket order to make reads as sequential as possible
+	records = storage.DedupeByKey(records)
+	storage.SortByBucket(records, Q.hashAlgorithm.HashFunc1)
+
+	getBucket, cache := Q.cachedBucketReader()
+	dirty := make(map[int64]bool)
+
+	recordLength := 1 + Q.keyLength + Q.valueLength // First byte is record state
+	bucketLength := recordLength * Q.recordsPerBucket
+
+	var selectedRecord model.Record
+	var transitions []uint8
+	for _, record := range records {
+		selectedRecord, err = Q.probingForSet(record.Key, getBucket)
+		if err != nil {
+			return
+		}
+
+		transitions = append(transitions, selectedRecord.State)
+		selectedRecord.State = model.RecordOccupied
+		selectedRecord.Key = record.Key
+		selectedRecord.Value = record.Value
+
+		bucketNo := (selectedRecord.RecordAddress - storage.MapFileHeaderLength) / bucketLength
+		bucket := cache[bucketNo]
+		bucket.Records[(selectedRecord.RecordAddress-bucket.BucketAddress)/recordLength] = selectedRecord
+		dirty[bucketNo] = true
+	}
+
+	bucketNos := make([]int64, 0, len(dirty))
+	for bucketNo := range dirty {
+		bucketNos = append(bucketNos, bucketNo)
+	}
+	sort.Slice(bucketNos, func(i, j int) bool { return bucketNos[i] < bucketNos[j] })
+
+	// Write runs of adjacent buckets in one operation each
+	var buf []byte
+	for i, bucketNo := range bucketNos {
+		Q.cache.Invalidate(bucketNo)
+		buf = append(buf, Q.bucketToBytes(cache[bucketNo])...)
+		if i == len(bucketNos)-1 || bucketNos[i+1] != bucketNo+1 {
+			_, err = Q.bucketAccess().WriteAt(buf, cache[bucketNo].BucketAddress-int64(len(buf))+bucketLength)
+			if err != nil {
+				err = fmt.Errorf("error while writing buckets to map file: %s", err)
+				return
+			}
+			buf = buf[:0]
+		}
+	}
+
+	for _, previousState := range transitions {
+		Q.counters.Transition(previousState, model.RecordOccupied)
+	}
+
 	return
 }
 
@@ -266,12 +711,176 @@ func (Q *OAFiles) Set(record model.Record) (err error) {
 //   - err is a standard error, if something went wrong
 func (Q *OAFiles) Delete(record model.Record) (err error) {
 	record.State = model.RecordDeleted
+	record.AccessCount = 0
 	record.Key = make([]byte, Q.keyLength)
 	record.Value = make([]byte, Q.valueLength)
 
 	err = Q.setBucketRecord(record)
 	if err != nil {
 		err = fmt.Errorf("error while updating record in bucket: %s", err)
+		return
+	}
+
+	Q.counters.Transition(model.RecordOccupied, model.RecordDeleted)
+
+	return
+}
+
+// Clear - Removes all records by discarding everything after the header in the map file and then extending it to its
+// full size again. The header is rewritten, which resets the mutation sequence number, while the system area is kept.
+// A memory mapping of the map file is released while the file is cleared and then mapped again, since some platforms
+// don't allow truncating a mapped file.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) Clear() (err error) {
+	Q.cache.Clear()
+
+	mapped := Q.mapped != nil
+	if mapped {
+		err = Q.MemoryMap(false)
+		if err != nil {
+			return
+		}
+	}
+
+	err = storage.ClearFile(Q.mapFile, storage.MapFileHeaderLength, Q.mapFileSize)
+	if err != nil {
+		err = fmt.Errorf("error while clearing map file: %s", err)
+		return
+	}
+
+	if mapped {
+		err = Q.MemoryMap(true)
+		if err != nil {
+			return
+		}
+	}
+	err = storage.SetHeader(Q.mapFile, Q.createHeader())
+	if err != nil {
+		err = fmt.Errorf("error while writing header to map file: %s", err)
+		return
+	}
+
+	Q.mutationSeq = 0
+	Q.counters.Reset()
+
+	return
+}
+
+// SetExpiryCheck - Sets a function that tells whether the value of an occupied record has expired. Expired records
+// are treated as deleted, and are marked deleted in file when encountered while getting or setting records.
+//   - isExpired is the function to call with the value of a record, nil turns off expiry checks
+func (Q *OAFiles) SetExpiryCheck(isExpired func(value []byte) bool) {
+	Q.isExpired = isExpired
+}
+
+// SetProgress - Sets a function that is called with the bucket number each time a bucket is read from the map file,
+// which lets a caller follow the progress of long-running operations such as probe loops and scans.
+//   - progress is the function to call, nil turns off progress reporting
+func (Q *OAFiles) SetProgress(progress func(bucketNo int64)) {
+	Q.progress = progress
+}
+
+// SetMetrics - Sets the receiver of metrics counted while accessing the map file, which counts buckets probed and bytes
+// read from and written to the file
+//   - metrics is the receiver, nil turns off counting
+func (Q *OAFiles) SetMetrics(metrics model.Metrics) {
+	Q.metrics = metrics
+	Q.mapFile = storage.CountBytes(Q.mapFile, metrics)
+}
+
+// SetWriteBuffer - Holds writes to the map file in memory, coalesced into as few writes as possible, until they are
+// flushed when maxBytes is reached, when interval has passed since the first pending write, or when the file is synced
+// or closed. Reads see the pending writes. Any pending writes are flushed when the buffer is replaced.
+//   - maxBytes is the max number of bytes to hold before flushing, zero turns buffering off
+//   - interval is the max time to hold writes, zero means no time limit
+//
+// It returns:
+//   - err is a standard error, if the map file is memory mapped or pending writes could not be flushed
+func (Q *OAFiles) SetWriteBuffer(maxBytes int64, interval time.Duration) (err error) {
+	if maxBytes > 0 && Q.mapped != nil {
+		err = fmt.Errorf("write buffer can not be used while the map file is memory mapped")
+		return
+	}
+
+	Q.mapFile, err = storage.BufferWrites(Q.mapFile, maxBytes, interval)
+
+	return
+}
+
+// SetProbeCap - Sets the number of probe iterations to attempt for a key before giving up with an error of type
+// crt.ProbingAlgorithm, zero restores the default of ten times the number of buckets
+func (Q *OAFiles) SetProbeCap(probeCap int64) {
+	Q.probeCap = probeCap
+}
+
+// SetInterrupt - Sets a function that is called before each bucket read while looking for a key, and stops the lookup
+// with the error it returns, if any. Lookups, including the search for a slot for a new record, are done before
+// anything is written, so interrupting them never leaves the files half updated.
+//   - interrupt is the function to call, nil turns off interruption
+func (Q *OAFiles) SetInterrupt(interrupt func() error) {
+	Q.interrupt = interrupt
+}
+
+// SetValue - Sets the value of a record previously read, in place at its address, so the key is not probed for again.
+//   - record is the model.Record to update, and it must contain RecordAddress and the new Value
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) SetValue(record model.Record) (err error) {
+	// Check validity of the value
+	if int64(len(record.Value)) != Q.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "SetValue", Expected: int(Q.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	Q.cache.Invalidate(Q.bucketNoOf(record.RecordAddress))
+	_, err = Q.bucketAccess().WriteAt(record.Value, record.RecordAddress+1+Q.keyLength) // First byte is record state
+	if err != nil {
+		err = fmt.Errorf("error while writing value to record: %s", err)
+	}
+
+	return
+}
+
+// AddAccessCount - Adds increment to the saturating access counter of a record
+//   - record is the model.Record to update, and it must contain RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) AddAccessCount(record model.Record, increment int64) (err error) {
+	Q.cache.Invalidate(Q.bucketNoOf(record.RecordAddress))
+	err = storage.AddAccessCount(Q.mapFile, record.RecordAddress, increment)
+	if err != nil {
+		err = fmt.Errorf("error while updating access count in bucket: %s", err)
+	}
+
+	return
+}
+
+// GetSystemValue - Returns a value stored by the library for its own use in the system area of the map file header
+//   - id is the identifier of the system value
+//
+// It returns:
+//   - value is the stored value if found, if not found an error of type crt.NoRecordFound is returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (Q *OAFiles) GetSystemValue(id uint8) (value []byte, err error) {
+	value, err = storage.GetSystemValue(Q.mapFile, id)
+
+	return
+}
+
+// SetSystemValue - Stores a value for the library's own use in the system area of the map file header
+//   - id is the identifier of the system value, it must not be zero
+//   - value is the value to store, at most 255 bytes long
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (Q *OAFiles) SetSystemValue(id uint8, value []byte) (err error) {
+	err = storage.SetSystemValue(Q.mapFile, id, value)
+	if err != nil {
+		err = fmt.Errorf("error while setting system value in map file header: %s", err)
 	}
 
 	return
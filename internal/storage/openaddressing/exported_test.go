@@ -77,6 +77,37 @@ func TestNewOAFiles(t *testing.T) {
 	})
 }
 
+func TestEstimateOAFiles(t *testing.T) {
+	t.Run("estimates storage parameters without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             3,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.QuadraticProbing,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		params, err := EstimateOAFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "estimate storage parameters")
+		_, statErr := os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(statErr), "no map file created")
+
+		files, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new OAFiles instance")
+		assert.Equal(t, files.GetStorageParameters(), params, "estimate matches created files")
+
+		// Clean up
+		err = files.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
 func TestNewOAFilesFromExistingFiles(t *testing.T) {
 	t.Run("opens existing QAFiles for all CRTs", func(t *testing.T) {
 		// Prepare
@@ -104,7 +135,7 @@ func TestNewOAFilesFromExistingFiles(t *testing.T) {
 				oaFilesInit.CloseFiles()
 
 				// Execute
-				oaFiles, err := NewOAFilesFromExistingFiles("test", nil)
+				oaFiles, err := NewOAFilesFromExistingFiles("test", nil, nil)
 
 				// Check
 				mapFileSize := storage.MapFileHeaderLength + oaFiles.numberOfBucketsAvailable*(crtConf.KeyLength+crtConf.ValueLength+1)*test.rpb
@@ -308,6 +339,59 @@ func TestOAFiles_Set(t *testing.T) {
 				_, err = os.Stat(oaFiles.mapFileName)
 				assert.True(t, os.IsNotExist(err), "map file removed")
 			})
+
+			t.Run(fmt.Sprintf("reuses a deleted record when the map file is otherwise full for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				crtConf := model.CRTConf{
+					Name:                         "test",
+					NumberOfBucketsNeeded:        test.buckets,
+					RecordsPerBucket:             test.rpb,
+					KeyLength:                    test.keyLength,
+					ValueLength:                  test.valueLength,
+					CollisionResolutionTechnique: test.crt,
+					HashAlgorithm:                nil,
+				}
+
+				oaFiles, err := NewOAFiles(crtConf)
+				assert.NoError(t, err, "create new OAFiles instance")
+
+				nRecords := oaFiles.numberOfBucketsAvailable * test.rpb
+				records := make([]model.Record, nRecords+1)
+				for i := range records {
+					records[i].Key = make([]byte, 16)
+					rand.Read(records[i].Key)
+					records[i].Value = make([]byte, 10)
+					rand.Read(records[i].Value)
+				}
+
+				for i := int64(0); i < nRecords; i++ {
+					err = oaFiles.Set(records[i])
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+				deleted, err := oaFiles.Get(model.Record{Key: records[nRecords/2].Key})
+				assert.NoError(t, err, "gets record to delete")
+				err = oaFiles.Delete(deleted)
+				assert.NoError(t, err, "deletes record")
+
+				// Execute
+				err = oaFiles.Set(records[nRecords])
+
+				// Check
+				assert.NoError(t, err, "sets record in place of deleted record")
+				record, err := oaFiles.Get(model.Record{Key: records[nRecords].Key})
+				assert.NoError(t, err, "gets new record")
+				assert.Equal(t, deleted.RecordAddress, record.RecordAddress, "new record takes place of deleted record")
+				err = oaFiles.Set(records[nRecords/2])
+				assert.ErrorIs(t, err, crt.MapFileFull{}, "map file full again")
+
+				// Clean up
+				oaFiles.CloseFiles()
+				err = oaFiles.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+
+				_, err = os.Stat(oaFiles.mapFileName)
+				assert.True(t, os.IsNotExist(err), "map file removed")
+			})
 		}
 	})
 }
@@ -370,6 +454,73 @@ func TestOAFiles_Get(t *testing.T) {
 	})
 }
 
+func TestOAFiles_Exists(t *testing.T) {
+	t.Run("checks existence of records for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOAFiles{
+			{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("checks existence of records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				crtConf := model.CRTConf{
+					Name:                         "test",
+					NumberOfBucketsNeeded:        test.buckets,
+					RecordsPerBucket:             test.rpb,
+					KeyLength:                    test.keyLength,
+					ValueLength:                  test.valueLength,
+					CollisionResolutionTechnique: test.crt,
+					HashAlgorithm:                nil,
+				}
+
+				oaFiles, err := NewOAFiles(crtConf)
+				assert.NoError(t, err, "create new OAFiles instance")
+
+				records := make([]model.Record, 15)
+				for i := range records {
+					records[i] = model.Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					records[i].Key[0] = byte(i + 1)
+					err = oaFiles.Set(records[i])
+					assert.NoError(t, err, "sets record to file")
+				}
+
+				deleted, err := oaFiles.Get(model.Record{Key: records[0].Key})
+				assert.NoError(t, err, "gets record to delete")
+				err = oaFiles.Delete(deleted)
+				assert.NoError(t, err, "deletes record")
+
+				missing := make([]byte, test.keyLength)
+				missing[0] = 100
+
+				// Execute
+				existsDeleted, errDeleted := oaFiles.Exists(model.Record{Key: records[0].Key})
+				existsMissing, errMissing := oaFiles.Exists(model.Record{Key: missing})
+				_, errLength := oaFiles.Exists(model.Record{Key: []byte{1}})
+
+				// Check
+				for _, record := range records[1:] {
+					exists, err := oaFiles.Exists(model.Record{Key: record.Key})
+					assert.NoError(t, err, "checks existence of record")
+					assert.True(t, exists, "record exists")
+				}
+				assert.NoError(t, errDeleted, "checks existence of deleted record")
+				assert.False(t, existsDeleted, "deleted record doesn't exist")
+				assert.NoError(t, errMissing, "checks existence of missing record")
+				assert.False(t, existsMissing, "missing record doesn't exist")
+				assert.ErrorIs(t, errLength, crt.WrongLength{}, "key of wrong length is rejected")
+
+				// Clean up
+				oaFiles.CloseFiles()
+				err = oaFiles.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
 func TestOAFiles_Delete(t *testing.T) {
 	t.Run("deletes a bucket record from file for all CRTs", func(t *testing.T) {
 		// Prepare
@@ -497,3 +648,361 @@ func TestOAFiles_GetBucket(t *testing.T) {
 		}
 	})
 }
+
+func TestOAFiles_GetOrSet(t *testing.T) {
+	t.Run("sets an absent record and returns an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+
+		// Execute
+		_, loadedFirst, errFirst := oaFiles.GetOrSet(record)
+		existing, loadedSecond, errSecond := oaFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.False(t, loadedFirst, "absent record is not loaded")
+		assert.NoError(t, errSecond, "gets existing record")
+		assert.True(t, loadedSecond, "existing record is loaded")
+		assert.True(t, utils.IsEqual(record.Value, existing.Value), "existing value is returned")
+
+		got, err := oaFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "existing value is kept")
+
+		// Clean up
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("treats an expired record as absent", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		oaFiles.SetExpiryCheck(func(value []byte) bool { return value[0] == 0xff })
+		record := model.Record{Key: make([]byte, 16), Value: []byte{0xff, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = oaFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+		_, loaded, err := oaFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, err, "sets record in place of expired record")
+		assert.False(t, loaded, "expired record is not loaded")
+
+		got, err := oaFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(other.Value, got.Value), "new value is set")
+
+		// Clean up
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestOAFiles_MemoryMap(t *testing.T) {
+	t.Run("sets and gets records through memory mapping", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.QuadraticProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+
+		// Execute
+		err = oaFiles.MemoryMap(true)
+		assert.NoError(t, err, "maps map file")
+		err = oaFiles.Set(record)
+		assert.NoError(t, err, "sets record through mapping")
+		mappedRecord, errMapped := oaFiles.Get(model.Record{Key: record.Key})
+		err = oaFiles.MemoryMap(false)
+		assert.NoError(t, err, "releases mapping")
+		fileRecord, errFile := oaFiles.Get(model.Record{Key: record.Key})
+
+		// Check
+		assert.NoError(t, errMapped, "gets record through mapping")
+		assert.True(t, utils.IsEqual(record.Value, mappedRecord.Value), "value through mapping")
+		assert.NoError(t, errFile, "gets record through file")
+		assert.True(t, utils.IsEqual(record.Value, fileRecord.Value), "value through file")
+
+		// Clean up
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("clears the map file while mapped and keeps the mapping", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = oaFiles.MemoryMap(true)
+		assert.NoError(t, err, "maps map file")
+		err = oaFiles.Set(record)
+		assert.NoError(t, err, "sets record through mapping")
+
+		// Execute
+		errClear := oaFiles.Clear()
+		_, errCleared := oaFiles.Get(model.Record{Key: record.Key})
+		errSet := oaFiles.Set(record)
+		setRecord, errGet := oaFiles.Get(model.Record{Key: record.Key})
+
+		// Check
+		assert.NoError(t, errClear, "clears map file")
+		assert.NotNil(t, oaFiles.mapped, "map file still mapped")
+		assert.ErrorIs(t, errCleared, crt.NoRecordFound{}, "record gone after clear")
+		assert.NoError(t, errSet, "sets record after clear")
+		assert.NoError(t, errGet, "gets record after clear")
+		assert.True(t, utils.IsEqual(record.Value, setRecord.Value), "value after clear")
+
+		// Clean up
+		err = oaFiles.MemoryMap(false)
+		assert.NoError(t, err, "releases mapping")
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestOAFiles_SetBucketCache(t *testing.T) {
+	t.Run("gets records through cache and invalidates on set and pop", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+
+		// Execute
+		err = oaFiles.SetBucketCache(1024)
+		assert.NoError(t, err, "turns on bucket cache")
+		err = oaFiles.Set(record)
+		assert.NoError(t, err, "sets record")
+		_, err = oaFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record first time")
+		cachedRecord, errCached := oaFiles.Get(model.Record{Key: record.Key})
+		hits, _ := oaFiles.cache.Stats()
+		err = oaFiles.Set(model.Record{Key: record.Key, Value: []byte{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}})
+		assert.NoError(t, err, "updates record")
+		updatedRecord, errUpdated := oaFiles.Get(model.Record{Key: record.Key})
+		err = oaFiles.Delete(updatedRecord)
+		assert.NoError(t, err, "deletes record")
+		_, errDeleted := oaFiles.Get(model.Record{Key: record.Key})
+
+		// Check
+		assert.NoError(t, errCached, "gets record through cache")
+		assert.True(t, utils.IsEqual(record.Value, cachedRecord.Value), "value through cache")
+		assert.Greater(t, hits, int64(0), "bucket found in cache")
+		assert.NoError(t, errUpdated, "gets updated record")
+		assert.True(t, utils.IsEqual([]byte{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, updatedRecord.Value), "updated value")
+		assert.ErrorIs(t, errDeleted, crt.NoRecordFound{}, "deleted record gone")
+
+		// Clean up
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestNewOAFilesInMemory(t *testing.T) {
+	t.Run("sets and gets records without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+
+		// Execute
+		oaFiles, err := NewOAFilesInMemory(crtConf)
+		assert.NoError(t, err, "create new instance")
+		err = oaFiles.Set(record)
+		assert.NoError(t, err, "sets record")
+		got, err := oaFiles.Get(model.Record{Key: record.Key})
+
+		// Check
+		assert.NoError(t, err, "gets record")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value of record")
+		_, err = os.Stat(storage.GetMapFileName(crtConf.Name))
+		assert.True(t, os.IsNotExist(err), "no map file created")
+
+		// Clean up
+		oaFiles.CloseFiles()
+		err = oaFiles.RemoveFiles()
+		assert.NoError(t, err, "removes nothing")
+	})
+}
+
+func TestOAFiles_SetBrentInsertion(t *testing.T) {
+	t.Run("shortens probe lengths and keeps all records reachable", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             1,
+			KeyLength:                    4,
+			ValueLength:                  4,
+			CollisionResolutionTechnique: crt.DoubleHashing,
+			HashAlgorithm:                nil,
+		}
+		plain, err := NewOAFilesInMemory(crtConf)
+		assert.NoError(t, err, "create plain instance")
+		brent, err := NewOAFilesInMemory(crtConf)
+		assert.NoError(t, err, "create brent instance")
+		err = brent.SetBrentInsertion(true)
+		assert.NoError(t, err, "turns on brent insertion")
+		keys := make([][]byte, 90)
+		for i := range keys {
+			keys[i] = []byte{0, 0, byte(i), byte(i)}
+		}
+
+		// Execute
+		for i, key := range keys {
+			err = plain.Set(model.Record{Key: key, Value: []byte{1, 1, 1, byte(i)}})
+			assert.NoErrorf(t, err, "sets record #%d in plain", i)
+			err = brent.Set(model.Record{Key: key, Value: []byte{1, 1, 1, byte(i)}})
+			assert.NoErrorf(t, err, "sets record #%d in brent", i)
+		}
+
+		// Check
+		var plainProbes, brentProbes int64
+		for i, key := range keys {
+			record, err := plain.Get(model.Record{Key: key})
+			assert.NoErrorf(t, err, "gets record #%d from plain", i)
+			plainProbes += plain.ProbeLength(key, plain.bucketNoOf(record.RecordAddress))
+			record, err = brent.Get(model.Record{Key: key})
+			assert.NoErrorf(t, err, "gets record #%d from brent", i)
+			assert.Equalf(t, []byte{1, 1, 1, byte(i)}, record.Value, "value of record #%d", i)
+			brentProbes += brent.ProbeLength(key, brent.bucketNoOf(record.RecordAddress))
+		}
+		occupied, _, err := brent.Counts()
+		assert.NoError(t, err, "counts records")
+		assert.Equal(t, int64(len(keys)), occupied, "occupied records")
+		assert.Less(t, brentProbes, plainProbes, "fewer probes with brent insertion")
+
+		// Clean up
+		plain.CloseFiles()
+		brent.CloseFiles()
+	})
+
+	t.Run("fails for other than double hashing", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    4,
+			ValueLength:                  4,
+			CollisionResolutionTechnique: crt.LinearProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFilesInMemory(crtConf)
+		assert.NoError(t, err, "create new instance")
+
+		// Execute
+		err = oaFiles.SetBrentInsertion(true)
+
+		// Check
+		assert.Error(t, err, "linear probing")
+
+		// Clean up
+		oaFiles.CloseFiles()
+	})
+}
+
+func TestOAFiles_GetBuckets(t *testing.T) {
+	t.Run("gets the same buckets as one by one", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             2,
+			KeyLength:                    4,
+			ValueLength:                  4,
+			CollisionResolutionTechnique: crt.QuadraticProbing,
+			HashAlgorithm:                nil,
+		}
+		oaFiles, err := NewOAFilesInMemory(crtConf)
+		assert.NoError(t, err, "create new instance")
+		for i := 0; i < 100; i++ {
+			err = oaFiles.Set(model.Record{Key: []byte{0, 0, 0, byte(i)}, Value: []byte{1, 1, 1, byte(i)}})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		nBuckets := oaFiles.GetStorageParameters().NumberOfBucketsAvailable
+
+		// Execute
+		var buckets []model.Bucket
+		for bucketNo := int64(0); bucketNo < nBuckets; bucketNo += int64(len(buckets)) {
+			buckets, _, err = oaFiles.GetBuckets(bucketNo, 200)
+			if !assert.NoErrorf(t, err, "gets buckets from #%d", bucketNo) {
+				break
+			}
+
+			// Check
+			assert.Equal(t, storage.BucketsPerRead(bucketNo, nBuckets, 18, 200), int64(len(buckets)), "buckets per read")
+			for i, bucket := range buckets {
+				expected, _, err := oaFiles.GetBucket(bucketNo + int64(i))
+				assert.NoErrorf(t, err, "gets bucket #%d", bucketNo+int64(i))
+				assert.Equalf(t, expected, bucket, "bucket #%d", bucketNo+int64(i))
+			}
+		}
+
+		// Clean up
+		oaFiles.CloseFiles()
+	})
+}
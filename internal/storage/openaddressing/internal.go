@@ -14,11 +14,19 @@ import (
 // If it already exists it will first be truncated to zero length and then to expected length,
 // hence deleting all existing data.
 func (Q *OAFiles) createNewHashMapFile(header storage.Header) (err error) {
-	Q.mapFile, err = os.OpenFile(Q.mapFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	Q.mapFile, err = Q.fileSystem.OpenFile(Q.mapFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
 	if err != nil {
 		err = fmt.Errorf("error while open/create new map file: %s", err)
 		return
 	}
+
+	err = Q.initHashMapFile(header)
+
+	return
+}
+
+// initHashMapFile - Extends the opened and empty map file to its full size and writes Header data to it
+func (Q *OAFiles) initHashMapFile(header storage.Header) (err error) {
 	err = Q.mapFile.Truncate(Q.mapFileSize)
 	if err != nil {
 		_ = Q.mapFile.Close()
@@ -39,8 +47,8 @@ func (Q *OAFiles) createNewHashMapFile(header storage.Header) (err error) {
 // openHashMapFile - Opens the hash map file and does some rudimentary checks of its validity and
 // returns a Header struct read from file
 func (Q *OAFiles) openHashMapFile() (header storage.Header, err error) {
-	if stat, ok := os.Stat(Q.mapFileName); ok == nil {
-		Q.mapFile, err = os.OpenFile(Q.mapFileName, os.O_RDWR, 0644)
+	if stat, ok := Q.fileSystem.Stat(Q.mapFileName); ok == nil {
+		Q.mapFile, err = Q.fileSystem.OpenFile(Q.mapFileName, os.O_RDWR, 0644)
 		if err != nil {
 			err = fmt.Errorf("unable to open existing hash map file: %s", err)
 			return
@@ -69,21 +77,37 @@ func (Q *OAFiles) openHashMapFile() (header storage.Header, err error) {
 	return
 }
 
+// bucketAccess - Returns what to read and write buckets through, which is the memory mapping if the map file is
+// memory mapped, otherwise the map file itself
+func (Q *OAFiles) bucketAccess() interface {
+	io.ReaderAt
+	io.WriterAt
+} {
+	if Q.mapped != nil {
+		return Q.mapped
+	}
+
+	return Q.mapFile
+}
+
 // getBucketRecords - Returns record for a given bucket number in a model.Bucket struct
 func (Q *OAFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err error) {
 	trueRecordLength := 1 + Q.keyLength + Q.valueLength // First byte is record state
 	bucketLength := trueRecordLength * Q.recordsPerBucket
 	bucketAddress := storage.MapFileHeaderLength + bucketNo*bucketLength
 
-	_, err = Q.mapFile.Seek(bucketAddress, io.SeekStart)
-	if err != nil {
-		return
+	buf := Q.bucketBuffers.Get(bucketLength)
+	defer Q.bucketBuffers.Put(buf)
+	if Q.progress != nil {
+		Q.progress(bucketNo)
 	}
 
-	buf := make([]byte, bucketLength)
-	_, err = Q.mapFile.Read(buf)
-	if err != nil {
-		return
+	if !Q.cache.Get(bucketNo, buf) {
+		_, err = Q.bucketAccess().ReadAt(buf, bucketAddress)
+		if err != nil {
+			return
+		}
+		Q.cache.Put(bucketNo, buf)
 	}
 
 	bucket, err = Q.bytesToBucket(buf, bucketAddress, Q.recordsPerBucket)
@@ -91,20 +115,76 @@ func (Q *OAFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err err
 	return
 }
 
+// getBucketKeys - Gets the records of a bucket reading only the state byte and key of each record, values are left nil.
+// Reading stops at the first empty record since probing never goes past one, the remaining records are left empty.
+func (Q *OAFiles) getBucketKeys(bucketNo int64) (bucket model.Bucket, err error) {
+	trueRecordLength := 1 + Q.keyLength + Q.valueLength // First byte is record state
+	bucketAddress := storage.MapFileHeaderLength + bucketNo*trueRecordLength*Q.recordsPerBucket
+
+	records := make([]model.Record, Q.recordsPerBucket)
+	if Q.progress != nil {
+		Q.progress(bucketNo)
+	}
+
+	for n := range records {
+		recordAddress := bucketAddress + int64(n)*trueRecordLength
+		buf := make([]byte, 1+Q.keyLength)
+		_, err = Q.bucketAccess().ReadAt(buf, recordAddress)
+		if err != nil {
+			return
+		}
+
+		state, accessCount := model.FromStateByte(buf[0])
+		records[n] = model.Record{
+			State:         state,
+			AccessCount:   accessCount,
+			RecordAddress: recordAddress,
+			Key:           buf[1:],
+		}
+		if state == model.RecordEmpty {
+			break
+		}
+	}
+
+	bucket = model.Bucket{
+		Records:       records,
+		BucketAddress: bucketAddress,
+	}
+
+	return
+}
+
 // setBucketRecord - Sets a bucket record in the hash map file
 func (Q *OAFiles) setBucketRecord(record model.Record) (err error) {
 	buf := make([]byte, 1, 1+Q.keyLength+Q.valueLength) // First byte is record state
-	buf[0] = record.State
+	buf[0] = model.ToStateByte(record.State, record.AccessCount)
 
 	buf = append(buf, record.Key...)
 	buf = append(buf, record.Value...)
 
-	_, err = Q.mapFile.Seek(record.RecordAddress, io.SeekStart)
-	if err != nil {
-		return
-	}
+	Q.cache.Invalidate(Q.bucketNoOf(record.RecordAddress))
+	_, err = Q.bucketAccess().WriteAt(buf, record.RecordAddress)
+
+	return
+}
 
-	_, err = Q.mapFile.Write(buf)
+// bucketNoOf - Returns the number of the bucket holding the record at recordAddress
+func (Q *OAFiles) bucketNoOf(recordAddress int64) (bucketNo int64) {
+	bucketLength := (1 + Q.keyLength + Q.valueLength) * Q.recordsPerBucket // First byte in each record is record state
+	bucketNo = (recordAddress - storage.MapFileHeaderLength) / bucketLength
+
+	return
+}
+
+// bucketToBytes - Converts a Bucket struct to raw data
+func (Q *OAFiles) bucketToBytes(bucket model.Bucket) (buf []byte) {
+	buf = make([]byte, 0, (1+Q.keyLength+Q.valueLength)*int64(len(bucket.Records))) // First byte in each record is record state
+
+	for _, record := range bucket.Records {
+		buf = append(buf, model.ToStateByte(record.State, record.AccessCount))
+		buf = append(buf, record.Key...)
+		buf = append(buf, record.Value...)
+	}
 
 	return
 }
@@ -127,9 +207,11 @@ func (Q *OAFiles) bytesToBucket(buf []byte, bucketAddress, recordsPerBucket int6
 		value = make([]byte, Q.valueLength)
 		_ = copy(key, buf[keyStart:keyStart+Q.keyLength])
 		_ = copy(value, buf[valueStart:valueStart+Q.valueLength])
+		state, accessCount := model.FromStateByte(buf[i])
 
 		records[n] = model.Record{
-			State:         buf[i],
+			State:         state,
+			AccessCount:   accessCount,
 			IsOverflow:    false,
 			RecordAddress: bucketAddress + i,
 			Key:           key,
@@ -161,31 +243,37 @@ func (Q *OAFiles) createHeader() (header storage.Header) {
 		MaxBucketNo:                  Q.maxBucketNo,
 		FileSize:                     Q.mapFileSize,
 		CollisionResolutionTechnique: int64(Q.CollisionResolutionTechnique),
+		HashAlgorithmKind:            Q.hashParameters.Kind,
+		HashSeed:                     Q.hashParameters.Seed,
 	}
 
 	return
 }
 
 // probingForGet - Is the Probing Collision Resolution Technique algorithm for getting a record.
-func (Q *OAFiles) probingForGet(key []byte) (record model.Record, err error) {
+// Buckets are read using getBucket, which makes it possible to probe through buckets cached in memory.
+func (Q *OAFiles) probingForGet(key []byte, getBucket func(int64) (model.Bucket, error)) (record model.Record, err error) {
+	getBucket = storage.CountProbes(getBucket, Q.metrics)
+	getBucket = storage.Interruptible(getBucket, Q.interrupt)
+
 	var bucket model.Bucket
 	var probe, n int64
 
 	hf1Value := Q.hashAlgorithm.HashFunc1(key)
 	hf2Value := Q.hashAlgorithm.HashFunc2(key)
 
-	iMax := Q.numberOfBucketsAvailable * 10 // To avoid infinite loop if hash algorithm is behaving bad
+	iMax := Q.probeIterations() // To avoid infinite loop if hash algorithm is behaving bad
 
 	for i := int64(0); i < iMax; i++ {
 		probe = Q.hashAlgorithm.ProbeIteration(hf1Value, hf2Value, i)
 		if probe < Q.numberOfBucketsAvailable && probe >= 0 {
-			bucket, err = Q.getBucketRecords(probe)
+			bucket, err = getBucket(probe)
 			if err != nil {
 				err = fmt.Errorf("error while reading bucket from file: %s", err)
 				return
 			}
 
-			for _, r := range bucket.Records {
+			for j, r := range bucket.Records {
 				switch r.State {
 				case model.RecordEmpty:
 					record = model.Record{}
@@ -193,7 +281,17 @@ func (Q *OAFiles) probingForGet(key []byte) (record model.Record, err error) {
 					return
 
 				case model.RecordOccupied:
+					var expired bool
+					expired, err = Q.expireRecord(bucket.Records, j)
+					if err != nil {
+						return
+					}
 					if utils.IsEqual(key, r.Key) {
+						if expired {
+							record = model.Record{}
+							err = crt.NoRecordFound{}
+							return
+						}
 						record = r
 						return
 					}
@@ -213,12 +311,36 @@ func (Q *OAFiles) probingForGet(key []byte) (record model.Record, err error) {
 	// When we have traversed long enough we just have to give up
 	// This is just a failsafe, should (with emphasis on should) never occur
 	record = model.Record{}
-	err = crt.ProbingAlgorithm{}
+	err = Q.probingError(hf1Value, hf2Value, iMax)
+	return
+}
+
+// cachedBucketReader - Returns a function reading buckets that keeps every bucket read in cache, and the cache itself
+func (Q *OAFiles) cachedBucketReader() (getBucket func(int64) (model.Bucket, error), cache map[int64]model.Bucket) {
+	cache = make(map[int64]model.Bucket)
+	getBucket = func(bucketNo int64) (bucket model.Bucket, err error) {
+		bucket, ok := cache[bucketNo]
+		if !ok {
+			bucket, err = Q.getBucketRecords(bucketNo)
+			if err != nil {
+				return
+			}
+			cache[bucketNo] = bucket
+		}
+		return
+	}
+
 	return
 }
 
 // probingForSet - Is the Probing Collision Resolution Technique algorithm for getting a record for set.
-func (Q *OAFiles) probingForSet(key []byte) (record model.Record, err error) {
+// Buckets are read using getBucket, which makes it possible to probe through buckets cached in memory. If all buckets
+// are probed without finding the key or an empty record, the first deleted record found is returned, and only if there
+// is none the map file is full.
+func (Q *OAFiles) probingForSet(key []byte, getBucket func(int64) (model.Bucket, error)) (record model.Record, err error) {
+	getBucket = storage.CountProbes(getBucket, Q.metrics)
+	getBucket = storage.Interruptible(getBucket, Q.interrupt)
+
 	var bucket model.Bucket
 	var deletedRecord model.Record
 	var hasCached bool
@@ -227,18 +349,18 @@ func (Q *OAFiles) probingForSet(key []byte) (record model.Record, err error) {
 	hf1Value := Q.hashAlgorithm.HashFunc1(key)
 	hf2Value := Q.hashAlgorithm.HashFunc2(key)
 
-	iMax := Q.numberOfBucketsAvailable * 10 // To avoid infinite loop if hash algorithm is behaving bad
+	iMax := Q.probeIterations() // To avoid infinite loop if hash algorithm is behaving bad
 
 	for i := int64(0); i < iMax; i++ {
 		probe = Q.hashAlgorithm.ProbeIteration(hf1Value, hf2Value, i)
 		if probe < Q.numberOfBucketsAvailable && probe >= 0 {
-			bucket, err = Q.getBucketRecords(probe)
+			bucket, err = getBucket(probe)
 			if err != nil {
 				err = fmt.Errorf("error while reading bucket from file: %s", err)
 				return
 			}
 
-			for _, r := range bucket.Records {
+			for j, r := range bucket.Records {
 				switch r.State {
 				case model.RecordEmpty:
 					if hasCached {
@@ -255,6 +377,17 @@ func (Q *OAFiles) probingForSet(key []byte) (record model.Record, err error) {
 						return
 					}
 
+					// An expired record is as good as a deleted one
+					var expired bool
+					expired, err = Q.expireRecord(bucket.Records, j)
+					if err != nil {
+						return
+					}
+					if expired && !hasCached {
+						deletedRecord = bucket.Records[j]
+						hasCached = true
+					}
+
 				case model.RecordDeleted:
 					if !hasCached {
 						deletedRecord = r
@@ -266,6 +399,10 @@ func (Q *OAFiles) probingForSet(key []byte) (record model.Record, err error) {
 			// Relies on the underlying probing function to distinctively go through the entire set of buckets
 			n++
 			if n >= Q.numberOfBucketsAvailable {
+				if hasCached {
+					record = deletedRecord
+					return
+				}
 				err = crt.MapFileFull{}
 				return
 			}
@@ -274,6 +411,79 @@ func (Q *OAFiles) probingForSet(key []byte) (record model.Record, err error) {
 
 	// When we have traversed long enough we just have to give up
 	// This is just a failsafe, should (with emphasis on should) never occur
-	err = crt.ProbingAlgorithm{}
+	err = Q.probingError(hf1Value, hf2Value, iMax)
+	return
+}
+
+// probeIterations - Returns the number of probe iterations to attempt before giving up, see SetProbeCap
+func (Q *OAFiles) probeIterations() int64 {
+	if Q.probeCap > 0 {
+		return Q.probeCap
+	}
+
+	return Q.numberOfBucketsAvailable * 10
+}
+
+// probingError - Returns a crt.ProbingAlgorithm error for a probe sequence that was given up on after probes
+// iterations, replaying it to count the distinct buckets it visited
+func (Q *OAFiles) probingError(hf1Value, hf2Value, probes int64) (err error) {
+	visited := make(map[int64]struct{})
+	for i := int64(0); i < probes && int64(len(visited)) < Q.numberOfBucketsAvailable; i++ {
+		probe := Q.hashAlgorithm.ProbeIteration(hf1Value, hf2Value, i)
+		if probe < Q.numberOfBucketsAvailable && probe >= 0 {
+			visited[probe] = struct{}{}
+		}
+	}
+
+	err = crt.ProbingAlgorithm{
+		HashValue1: hf1Value,
+		HashValue2: hf2Value,
+		Probes:     probes,
+		Visited:    int64(len(visited)),
+		Buckets:    Q.numberOfBucketsAvailable,
+	}
+
+	return
+}
+
+// expireRecord - Marks the record at index i in records as deleted, both in file and in records, if it is occupied
+// and has expired according to the expiry check (see SetExpiryCheck)
+func (Q *OAFiles) expireRecord(records []model.Record, i int) (expired bool, err error) {
+	if Q.isExpired == nil || records[i].State != model.RecordOccupied || !Q.isExpired(records[i].Value) {
+		return
+	}
+
+	err = Q.Delete(records[i])
+	if err != nil {
+		return
+	}
+
+	records[i].State = model.RecordDeleted
+	records[i].AccessCount = 0
+	expired = true
+
+	return
+}
+
+// countRecords - Counts the occupied and deleted records by reading all buckets in the map file
+func (Q *OAFiles) countRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	for bucketNo := int64(0); bucketNo < Q.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = Q.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, record := range bucket.Records {
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
 	return
 }
@@ -0,0 +1,52 @@
+package storage
+
+import "io"
+
+// BucketsPerRead - Returns the number of buckets to read at once from fromBucketNo when reading in chunks of at most
+// maxBytes, which is at least one and never goes past the last bucket
+//   - fromBucketNo is the number of the first bucket to read
+//   - numberOfBuckets is the number of buckets in the map file
+//   - bucketLength is the length of each bucket in bytes
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - n is the number of buckets to read
+func BucketsPerRead(fromBucketNo, numberOfBuckets, bucketLength, maxBytes int64) (n int64) {
+	n = maxBytes / bucketLength
+	if n < 1 {
+		n = 1
+	}
+	if n > numberOfBuckets-fromBucketNo {
+		n = numberOfBuckets - fromBucketNo
+	}
+
+	return
+}
+
+// ReadBuckets - Reads consecutive buckets from a map file in a single read into buf, and calls decode with the raw data
+// and address of each bucket in order. The number of buckets read is the length of buf divided by bucketLength, and the
+// raw data given to decode is only valid during the call.
+//   - reader is what to read the map file through
+//   - buf is the buffer to read into, holding a whole number of buckets
+//   - fromBucketNo is the number of the first bucket to read
+//   - bucketLength is the length of each bucket in bytes
+//   - decode is called with the raw data and address of each bucket, and stops the reading by returning an error
+//
+// It returns:
+//   - err is a standard error, if reading failed or decode returned an error
+func ReadBuckets(reader io.ReaderAt, buf []byte, fromBucketNo, bucketLength int64, decode func(raw []byte, bucketAddress int64) error) (err error) {
+	address := MapFileHeaderLength + fromBucketNo*bucketLength
+	_, err = reader.ReadAt(buf, address)
+	if err != nil {
+		return
+	}
+
+	for start := int64(0); start+bucketLength <= int64(len(buf)); start += bucketLength {
+		err = decode(buf[start:start+bucketLength], address+start)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
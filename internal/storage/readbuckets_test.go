@@ -0,0 +1,49 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBucketsPerRead(t *testing.T) {
+	t.Run("fits buckets in chunk without passing the last bucket", func(t *testing.T) {
+		// Execute
+		full := BucketsPerRead(0, 100, 10, 95)
+		single := BucketsPerRead(0, 100, 10, 5)
+		last := BucketsPerRead(95, 100, 10, 95)
+
+		// Check
+		assert.Equal(t, int64(9), full, "buckets fitting in chunk")
+		assert.Equal(t, int64(1), single, "at least one bucket")
+		assert.Equal(t, int64(5), last, "buckets left")
+	})
+}
+
+func TestReadBuckets(t *testing.T) {
+	t.Run("reads consecutive buckets in one read", func(t *testing.T) {
+		// Prepare
+		file := NewMemFile()
+		data := make([]byte, 5*4)
+		for i := range data {
+			data[i] = byte(i / 4)
+		}
+		_, err := file.WriteAt(data, MapFileHeaderLength)
+		assert.NoError(t, err, "writes buckets")
+
+		// Execute
+		var raws [][]byte
+		var addresses []int64
+		err = ReadBuckets(file, make([]byte, 3*4), 1, 4, func(raw []byte, bucketAddress int64) error {
+			raws = append(raws, append([]byte(nil), raw...))
+			addresses = append(addresses, bucketAddress)
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "reads buckets")
+		assert.Equal(t, [][]byte{{1, 1, 1, 1}, {2, 2, 2, 2}, {3, 3, 3, 3}}, raws, "raw data of buckets")
+		assert.Equal(t, []int64{MapFileHeaderLength + 4, MapFileHeaderLength + 8, MapFileHeaderLength + 12}, addresses, "addresses of buckets")
+	})
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+)
+
+// errReadOnly - Error returned by all attempts to change files opened through a ReadOnlyFileSystem
+var errReadOnly = fmt.Errorf("file hash map is opened read-only")
+
+// ReadOnlyFileSystem - Is a vfs.FileSystem opening files of an underlying file system for reading only. Files are
+// never created, and all attempts to write, truncate, remove or rename files fail.
+type ReadOnlyFileSystem struct {
+	fileSystem vfs.FileSystem
+}
+
+// NewReadOnlyFileSystem - Returns a ReadOnlyFileSystem wrapping fileSystem
+//   - fileSystem is the underlying file system
+//
+// It returns:
+//   - readOnlyFileSystem is a pointer to the wrapping file system
+func NewReadOnlyFileSystem(fileSystem vfs.FileSystem) (readOnlyFileSystem *ReadOnlyFileSystem) {
+	readOnlyFileSystem = &ReadOnlyFileSystem{fileSystem: fileSystem}
+
+	return
+}
+
+// OpenFile - Opens the named file of the underlying file system for reading, regardless of flag
+func (R *ReadOnlyFileSystem) OpenFile(name string, _ int, perm os.FileMode) (file vfs.File, err error) {
+	inner, err := R.fileSystem.OpenFile(name, os.O_RDONLY, perm)
+	if err != nil {
+		return
+	}
+
+	file = &readOnlyFile{File: inner}
+
+	return
+}
+
+// Stat - Returns information about the named file from the underlying file system
+func (R *ReadOnlyFileSystem) Stat(name string) (os.FileInfo, error) {
+	return R.fileSystem.Stat(name)
+}
+
+// Remove - Fails since the file system is read-only
+func (R *ReadOnlyFileSystem) Remove(_ string) error {
+	return errReadOnly
+}
+
+// Rename - Fails since the file system is read-only
+func (R *ReadOnlyFileSystem) Rename(_, _ string) error {
+	return errReadOnly
+}
+
+// readOnlyFile - Is a vfs.File failing all attempts to change it
+type readOnlyFile struct {
+	vfs.File
+}
+
+// WriteAt - Fails since the file is read-only
+func (R *readOnlyFile) WriteAt(_ []byte, _ int64) (int, error) {
+	return 0, errReadOnly
+}
+
+// Truncate - Fails since the file is read-only
+func (R *readOnlyFile) Truncate(_ int64) error {
+	return errReadOnly
+}
+
+// underlying - Returns the underlying file
+func (R *readOnlyFile) underlying() vfs.File {
+	return R.File
+}
+
+// IsReadOnly - Returns true if file was opened through a ReadOnlyFileSystem, looking through files wrapped by
+// RetryFileSystem
+//   - file is the file to check
+func IsReadOnly(file vfs.File) bool {
+	for {
+		if _, ok := file.(*readOnlyFile); ok {
+			return true
+		}
+		wrapped, isWrapped := file.(wrappedFile)
+		if !isWrapped {
+			return false
+		}
+		file = wrapped.underlying()
+	}
+}
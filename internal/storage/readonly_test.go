@@ -0,0 +1,43 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestReadOnlyFileSystem(t *testing.T) {
+	t.Run("reads but does not change files", func(t *testing.T) {
+		// Prepare
+		err := os.WriteFile("testfile", []byte{1, 2, 3}, 0644)
+		assert.NoError(t, err, "writes test file")
+		fs := NewReadOnlyFileSystem(vfs.OS{})
+
+		// Execute
+		file, err := fs.OpenFile("testfile", os.O_RDWR|os.O_CREATE, 0644)
+		assert.NoError(t, err, "opens file")
+		buf := make([]byte, 3)
+		_, errRead := file.ReadAt(buf, 0)
+		_, errWrite := file.WriteAt([]byte{4}, 0)
+		errTruncate := file.Truncate(0)
+		errRemove := fs.Remove("testfile")
+		_, errCreate := fs.OpenFile("otherfile", os.O_RDWR|os.O_CREATE, 0644)
+		_, isOSFile := OSFile(file)
+
+		// Check
+		assert.NoError(t, errRead, "reads from file")
+		assert.Equal(t, []byte{1, 2, 3}, buf, "reads file content")
+		assert.Error(t, errWrite, "write fails")
+		assert.Error(t, errTruncate, "truncate fails")
+		assert.Error(t, errRemove, "remove fails")
+		assert.Error(t, errCreate, "file is not created")
+		assert.True(t, isOSFile, "operating system file found behind read-only file")
+
+		// Clean up
+		_ = file.Close()
+		_ = os.Remove("testfile")
+	})
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+	"time"
+)
+
+// Retrier - Retries file operations that fail with errors classified as retryable, waiting an exponentially growing
+// backoff between attempts.
+//   - Attempts is the total number of attempts made for an operation, including the first
+//   - Backoff is the wait before the first retry, doubled for each following retry
+//   - MaxBackoff caps the wait between retries, zero means no cap
+//   - Retryable classifies whether an error is worth retrying
+//   - OnRetry is called, if not nil, before each retry with the operation, the attempt that failed and its error
+//   - Metrics is the receiver, if not nil, of a count for each retry
+type Retrier struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Retryable  func(err error) bool
+	OnRetry    func(op string, attempt int, err error)
+	Metrics    model.Metrics
+}
+
+// Do - Calls fn until it succeeds, fails with an error that is not retryable, or all attempts are spent
+//   - op is the name of the operation, passed on to OnRetry
+//   - fn is the operation to make
+//
+// It returns:
+//   - err is the error from the last attempt, or nil if it succeeded
+func (R Retrier) Do(op string, fn func() error) (err error) {
+	backoff := R.Backoff
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= R.Attempts || !R.Retryable(err) {
+			return
+		}
+
+		if R.OnRetry != nil {
+			R.OnRetry(op, attempt, err)
+		}
+		if R.Metrics != nil {
+			R.Metrics.AddRetries(1)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if R.MaxBackoff > 0 && backoff > R.MaxBackoff {
+			backoff = R.MaxBackoff
+		}
+	}
+}
+
+// RetryFileSystem - Is a vfs.FileSystem that retries operations of an underlying file system, and of the files opened
+// through it, according to a Retrier. Since files are read and written at explicit offsets, a retried read or write
+// repeats exactly the same access, so an operation in the middle of a probe is completed rather than left half done.
+type RetryFileSystem struct {
+	fileSystem vfs.FileSystem
+	retrier    Retrier
+}
+
+// NewRetryFileSystem - Returns a RetryFileSystem wrapping fileSystem
+//   - fileSystem is the underlying file system
+//   - retrier is the retry policy to apply
+//
+// It returns:
+//   - retryFileSystem is a pointer to the wrapping file system
+func NewRetryFileSystem(fileSystem vfs.FileSystem, retrier Retrier) (retryFileSystem *RetryFileSystem) {
+	retryFileSystem = &RetryFileSystem{fileSystem: fileSystem, retrier: retrier}
+
+	return
+}
+
+// OpenFile - Opens the named file in the underlying file system, returning a file that retries its operations
+func (R *RetryFileSystem) OpenFile(name string, flag int, perm os.FileMode) (file vfs.File, err error) {
+	var inner vfs.File
+	err = R.retrier.Do("open", func() (opErr error) {
+		inner, opErr = R.fileSystem.OpenFile(name, flag, perm)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	file = &retryFile{file: inner, retrier: R.retrier}
+
+	return
+}
+
+// Stat - Returns information about the named file from the underlying file system
+func (R *RetryFileSystem) Stat(name string) (info os.FileInfo, err error) {
+	err = R.retrier.Do("stat", func() (opErr error) {
+		info, opErr = R.fileSystem.Stat(name)
+		return
+	})
+
+	return
+}
+
+// Remove - Removes the named file from the underlying file system
+func (R *RetryFileSystem) Remove(name string) (err error) {
+	err = R.retrier.Do("remove", func() error {
+		return R.fileSystem.Remove(name)
+	})
+
+	return
+}
+
+// Rename - Renames a file in the underlying file system
+func (R *RetryFileSystem) Rename(oldName, newName string) (err error) {
+	err = R.retrier.Do("rename", func() error {
+		return R.fileSystem.Rename(oldName, newName)
+	})
+
+	return
+}
+
+// SyncDir - Syncs the directory holding the named file the way the underlying file system does, i.e. by SyncDir for
+// the file system of the operating system and by vfs.DirSyncer for others implementing it
+func (R *RetryFileSystem) SyncDir(name string) (err error) {
+	err = R.retrier.Do("syncdir", func() (opErr error) {
+		switch fs := R.fileSystem.(type) {
+		case vfs.OS:
+			opErr = SyncDir(name)
+		case vfs.DirSyncer:
+			opErr = fs.SyncDir(name)
+		}
+		return
+	})
+
+	return
+}
+
+// retryFile - Is a vfs.File retrying the operations of an underlying file
+type retryFile struct {
+	file    vfs.File
+	retrier Retrier
+}
+
+// ReadAt - Reads len(p) bytes at offset off, retrying the whole read if it fails with a retryable error
+func (R *retryFile) ReadAt(p []byte, off int64) (n int, err error) {
+	err = R.retrier.Do("read", func() (opErr error) {
+		n, opErr = R.file.ReadAt(p, off)
+		return
+	})
+
+	return
+}
+
+// WriteAt - Writes p at offset off, retrying the whole write if it fails with a retryable error
+func (R *retryFile) WriteAt(p []byte, off int64) (n int, err error) {
+	err = R.retrier.Do("write", func() (opErr error) {
+		n, opErr = R.file.WriteAt(p, off)
+		return
+	})
+
+	return
+}
+
+// Truncate - Changes the size of the file
+func (R *retryFile) Truncate(size int64) (err error) {
+	err = R.retrier.Do("truncate", func() error {
+		return R.file.Truncate(size)
+	})
+
+	return
+}
+
+// Sync - Commits the contents of the file to stable storage
+func (R *retryFile) Sync() (err error) {
+	err = R.retrier.Do("sync", R.file.Sync)
+
+	return
+}
+
+// Close - Closes the file, which is never retried since the state of the file is unknown after a failed close
+func (R *retryFile) Close() (err error) {
+	err = R.file.Close()
+
+	return
+}
+
+// Stat - Returns information about the file
+func (R *retryFile) Stat() (info os.FileInfo, err error) {
+	err = R.retrier.Do("stat", func() (opErr error) {
+		info, opErr = R.file.Stat()
+		return
+	})
+
+	return
+}
+
+// underlying - Returns the underlying file
+func (R *retryFile) underlying() vfs.File {
+	return R.file
+}
@@ -0,0 +1,131 @@
+//go:build unit
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// retryMetrics - Counts the retries given to it
+type retryMetrics struct {
+	testMetrics
+	retries int64
+}
+
+func (R *retryMetrics) AddRetries(n int64) { R.retries += n }
+
+func TestRetrier_Do(t *testing.T) {
+	transient := func(err error) bool { return errors.Is(err, syscall.EIO) }
+
+	t.Run("retries retryable errors until success", func(t *testing.T) {
+		// Prepare
+		var retries []int
+		r := Retrier{Attempts: 3, Backoff: time.Millisecond, Retryable: transient, OnRetry: func(op string, attempt int, err error) {
+			retries = append(retries, attempt)
+		}}
+		calls := 0
+
+		// Execute
+		err := r.Do("read", func() error {
+			calls++
+			if calls < 3 {
+				return syscall.EIO
+			}
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "succeeds on third attempt")
+		assert.Equal(t, 3, calls, "called three times")
+		assert.Equal(t, []int{1, 2}, retries, "retries reported")
+	})
+
+	t.Run("counts retries in metrics", func(t *testing.T) {
+		// Prepare
+		metrics := &retryMetrics{}
+		r := Retrier{Attempts: 4, Retryable: transient, Metrics: metrics}
+		calls := 0
+
+		// Execute
+		err := r.Do("write", func() error {
+			calls++
+			if calls < 4 {
+				return syscall.EIO
+			}
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "succeeds on fourth attempt")
+		assert.Equal(t, int64(3), metrics.retries, "three retries counted")
+	})
+
+	t.Run("gives up after all attempts", func(t *testing.T) {
+		// Prepare
+		r := Retrier{Attempts: 2, Retryable: transient}
+		calls := 0
+
+		// Execute
+		err := r.Do("write", func() error {
+			calls++
+			return syscall.EIO
+		})
+
+		// Check
+		assert.ErrorIs(t, err, syscall.EIO, "returns last error")
+		assert.Equal(t, 2, calls, "called twice")
+	})
+
+	t.Run("does not retry errors that are not retryable", func(t *testing.T) {
+		// Prepare
+		r := Retrier{Attempts: 5, Retryable: transient}
+		calls := 0
+
+		// Execute
+		err := r.Do("write", func() error {
+			calls++
+			return fmt.Errorf("permanent")
+		})
+
+		// Check
+		assert.Error(t, err, "returns error")
+		assert.Equal(t, 1, calls, "called once")
+	})
+}
+
+func TestRetryFileSystem(t *testing.T) {
+	t.Run("retries file operations and keeps operating system files reachable", func(t *testing.T) {
+		// Prepare
+		retried := 0
+		fs := NewRetryFileSystem(vfs.OS{}, Retrier{Attempts: 3, Retryable: func(err error) bool { return true }, OnRetry: func(op string, attempt int, err error) {
+			retried++
+		}})
+
+		// Execute
+		file, err := fs.OpenFile("testfile", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		assert.NoError(t, err, "opens file")
+		_, errWrite := file.WriteAt([]byte{1, 2, 3}, 0)
+		buf := make([]byte, 3)
+		_, errRead := file.ReadAt(buf, 0)
+		osFile, ok := OSFile(file)
+
+		// Check
+		assert.NoError(t, errWrite, "writes to file")
+		assert.NoError(t, errRead, "reads from file")
+		assert.Equal(t, []byte{1, 2, 3}, buf, "reads what was written")
+		assert.Equal(t, 0, retried, "no retries needed")
+		assert.True(t, ok, "operating system file found")
+		assert.NotNil(t, osFile, "operating system file returned")
+
+		// Clean up
+		_ = file.Close()
+		_ = os.Remove("testfile")
+	})
+}
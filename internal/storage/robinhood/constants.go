@@ -0,0 +1,10 @@
+package robinhood
+
+// displacementLength - Length of the displacement stored after the state byte in each record
+const displacementLength int64 = 4
+
+// displacementOffset - Record offset to the displacement - 4 bytes
+const displacementOffset int64 = 1
+
+// keyOffset - Record offset to the key
+const keyOffset = displacementOffset + displacementLength
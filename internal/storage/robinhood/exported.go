@@ -0,0 +1,529 @@
+package robinhood
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/hash"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// RHFiles - Represents an implementation of file support for the Robin Hood Collision Resolution Technique.
+// It uses one file of buckets, and in case of a collision it probes linearly through the hash table. Each record stores
+// its displacement, i.e. the number of buckets from its home bucket, and on insert a record with a lower displacement
+// than the one being inserted gives up its slot and continues probing instead. Displacements stored for a slot never
+// decrease, also not when the record is deleted, which is what makes it safe to stop probing for a key as soon as a slot
+// with a lower displacement than the current probe length is found.
+// Once all free slots are occupied the table will accept no more records.
+type RHFiles struct {
+	storage.BucketFile
+	keyLength                int64
+	valueLength              int64
+	numberOfBucketsNeeded    int64
+	numberOfBucketsAvailable int64
+	recordsPerBucket         int64
+	maxBucketNo              int64
+	hashAlgorithm            hashfunc.HashAlgorithm
+	internalAlgorithm        bool
+	hashParameters           model.HashParameters
+	bucketBuffers            storage.BufferPool
+	counters                 storage.Counters
+}
+
+// NewRHFiles - Returns a pointer to a new instance of Robin Hood file implementation.
+// It always creates a new file (or opens and truncate existing file). Only HashFunc1 of a custom hash algorithm is
+// used, since probing is always linear.
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - rhFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewRHFiles(crtConf model.CRTConf) (rhFiles *RHFiles, err error) {
+	rhFiles, err = newRHFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	header := rhFiles.createHeader()
+
+	err = rhFiles.CreateFile(header)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// EstimateRHFiles - Returns the storage parameters that NewRHFiles would give the files, without creating
+// any of them
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - params is the storage parameters, with MapFileSize being the size the map file would be created with
+//   - err which is a standard Go type of error
+func EstimateRHFiles(crtConf model.CRTConf) (params model.StorageParameters, err error) {
+	rhFiles, err := newRHFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	params = rhFiles.GetStorageParameters()
+
+	return
+}
+
+// newRHFiles - Returns a pointer to a new instance of Robin Hood file implementation with all parameters set
+// from crtConf, but without any files
+func newRHFiles(crtConf model.CRTConf) (rhFiles *RHFiles, err error) {
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	if crtConf.HashAlgorithm == nil {
+		crtConf.HashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.LinearProbing, crtConf.NumberOfBucketsNeeded, crtConf.HashParameters)
+		if err != nil {
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(crtConf.HashAlgorithm, crtConf.NumberOfBucketsNeeded)
+		if err != nil {
+			return
+		}
+		crtConf.HashParameters = model.HashParameters{}
+	}
+
+	// Calculate the hash map file various parameters
+	recordLength := keyOffset + crtConf.KeyLength + crtConf.ValueLength
+	bucketLength := recordLength * crtConf.RecordsPerBucket
+	maxBucketNo := crtConf.HashAlgorithm.GetTableSize() - 1
+	numberOfBuckets := maxBucketNo + 1
+	fileSize := bucketLength*numberOfBuckets + storage.MapFileHeaderLength
+
+	rhFiles = &RHFiles{
+		BucketFile: storage.BucketFile{
+			FileName:   storage.GetMapFileName(crtConf.Name),
+			FileSystem: storage.ResolveFileSystem(crtConf.FileSystem),
+			FileSize:   fileSize,
+		},
+		keyLength:                crtConf.KeyLength,
+		valueLength:              crtConf.ValueLength,
+		numberOfBucketsNeeded:    crtConf.NumberOfBucketsNeeded,
+		numberOfBucketsAvailable: numberOfBuckets,
+		recordsPerBucket:         crtConf.RecordsPerBucket,
+		maxBucketNo:              maxBucketNo,
+		hashAlgorithm:            crtConf.HashAlgorithm,
+		internalAlgorithm:        internalAlg,
+		hashParameters:           crtConf.HashParameters,
+	}
+
+	return
+}
+
+// NewRHFilesFromExistingFiles - Returns a pointer to a new instance of Robin Hood file implementation given
+// existing files. If files doesn't exist, doesn't have a valid header or if its file size seems wrong given
+// size from header it fails with error.
+//   - Name is the name to base map file name on
+//   - hashAlgorithm is the custom hash algorithm the files were used with, nil for the internal
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
+//
+// It returns:
+//   - rhFiles which is a pointer to the created instance
+//   - err which is a standard Go type of error
+func NewRHFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (rhFiles *RHFiles, err error) {
+	mapFileName := storage.GetMapFileName(name)
+
+	rhFiles = &RHFiles{BucketFile: storage.BucketFile{FileName: mapFileName, FileSystem: storage.ResolveFileSystem(fileSystem)}}
+
+	header, err := rhFiles.OpenFile()
+	if err != nil {
+		return
+	}
+
+	// Check for mismatch in choice of hash algorithm
+	if header.InternalHash && hashAlgorithm != nil {
+		rhFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the internal hash algorithm but an external was given")
+		return
+	}
+	if !header.InternalHash && hashAlgorithm == nil {
+		rhFiles.CloseFiles()
+		err = fmt.Errorf("seems the hash map file was used with the external hash algorithm but no external was given")
+		return
+	}
+
+	// If no HashAlgorithm was given then use the default internal
+	var internalAlg bool
+	hashParameters := model.HashParameters{Kind: header.HashAlgorithmKind, Seed: header.HashSeed}
+	if hashAlgorithm == nil {
+		hashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.LinearProbing, header.NumberOfBucketsNeeded, hashParameters)
+		if err != nil {
+			rhFiles.CloseFiles()
+			return
+		}
+		if hashAlgorithm.GetTableSize() != header.NumberOfBucketsAvailable {
+			rhFiles.CloseFiles()
+			err = fmt.Errorf("reconstructed internal hash algorithm doesn't conform with header indicated number of buckets")
+			return
+		}
+		internalAlg = true
+	} else {
+		err = storage.SetTableSize(hashAlgorithm, header.NumberOfBucketsNeeded)
+		if err != nil {
+			rhFiles.CloseFiles()
+			return
+		}
+	}
+
+	rhFiles.keyLength = header.KeyLength
+	rhFiles.valueLength = header.ValueLength
+	rhFiles.numberOfBucketsNeeded = header.NumberOfBucketsNeeded
+	rhFiles.numberOfBucketsAvailable = header.NumberOfBucketsAvailable
+	rhFiles.recordsPerBucket = header.RecordsPerBucket
+	rhFiles.maxBucketNo = header.MaxBucketNo
+	rhFiles.hashAlgorithm = hashAlgorithm
+	rhFiles.internalAlgorithm = internalAlg
+	rhFiles.hashParameters = hashParameters
+
+	return
+}
+
+// GetStorageParameters - Returns a struct with storage parameters from RHFiles
+func (R *RHFiles) GetStorageParameters() (params model.StorageParameters) {
+	params = model.StorageParameters{
+		CollisionResolutionTechnique: crt.RobinHood,
+		KeyLength:                    R.keyLength,
+		ValueLength:                  R.valueLength,
+		NumberOfBucketsNeeded:        R.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     R.numberOfBucketsAvailable,
+		RecordsPerBucket:             R.recordsPerBucket,
+		MapFileSize:                  R.FileSize,
+		InternalAlgorithm:            R.internalAlgorithm,
+		HashParameters:               R.hashParameters,
+		MutationSeq:                  R.MutationSeq,
+	}
+
+	return
+}
+
+// Counts - Returns the number of occupied and deleted records in the map file. The records are counted by reading
+// all buckets the first time, after that the counts are kept up to date as records are set and deleted.
+//
+// It returns:
+//   - occupied is the number of occupied records, which includes expired records not yet marked as deleted
+//   - deleted is the number of deleted records
+//   - err is a standard error, if something went wrong while counting
+func (R *RHFiles) Counts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = R.counters.Counts(R.countRecords)
+
+	return
+}
+
+// GetBucket - Returns a bucket with its records given the bucket number
+//   - bucketNo is the identifier of a bucket
+//
+// It returns:
+//   - bucket is a model.Bucket struct containing all records in the map file
+//   - overflowIterator is a OverflowRecords struct that can be used to get any overflow records belonging to the bucket. This will always be nil in Robin Hood.
+//   - err is standard error
+func (R *RHFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterator *overflow.Records, err error) {
+	bucket, err = R.getBucketRecords(bucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while getting existing bucket records from hash map file: %s", err)
+		return
+	}
+
+	return
+}
+
+// GetBuckets - Reads the buckets from fromBucketNo in a single read of at most maxBytes, but at least one bucket and
+// never past the last one, which is much faster than reading them one by one when scanning all buckets.
+//   - fromBucketNo is the number of the first bucket to read
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - buckets is the buckets read, in bucket order
+//   - overflowIterators is an iterator over the overflow records of each bucket, with nil for buckets without overflow
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error) {
+	bucketLength := (keyOffset + R.keyLength + R.valueLength) * R.recordsPerBucket
+	n := storage.BucketsPerRead(fromBucketNo, R.numberOfBucketsAvailable, bucketLength, maxBytes)
+
+	buf := R.bucketBuffers.Get(n * bucketLength)
+	defer R.bucketBuffers.Put(buf)
+
+	bucketNo := fromBucketNo
+	err = storage.ReadBuckets(R.File, buf, fromBucketNo, bucketLength, func(raw []byte, bucketAddress int64) error {
+		if R.Progress != nil {
+			R.Progress(bucketNo)
+		}
+		bucketNo++
+		buckets = append(buckets, R.bytesToBucket(raw, bucketAddress))
+		overflowIterators = append(overflowIterators, nil)
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("error while getting buckets from hash map file: %s", err)
+		buckets, overflowIterators = nil, nil
+	}
+
+	return
+}
+
+// HomeBucket - Returns the bucket that key is addressed to, where probing for it starts
+func (R *RHFiles) HomeBucket(key []byte) (bucketNo int64) {
+	bucketNo = R.hashAlgorithm.HashFunc1(key)
+
+	return
+}
+
+// ProbeLength - Returns the number of buckets that a lookup of key probes before reaching bucketNo, i.e. zero if
+// bucketNo is the home bucket, which for linear probing is the distance from the home bucket
+func (R *RHFiles) ProbeLength(key []byte, bucketNo int64) (probeLength int64) {
+	probeLength = (bucketNo - R.hashAlgorithm.HashFunc1(key) + R.numberOfBucketsAvailable) % R.numberOfBucketsAvailable
+
+	return
+}
+
+// Get - Gets record that corresponds to the given key.
+// The model.Record that is returned contains also addresses to the actual files that it came from, this is to speed
+// up higher levels functions such as Pop where the same record is also supposed to be deleted in a call to Delete
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - record is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (R *RHFiles) Get(keyRecord model.Record) (record model.Record, err error) {
+	// Check validity of the key
+	if int64(len(keyRecord.Key)) != R.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: "Get", Expected: int(R.keyLength), Actual: len(keyRecord.Key)}
+		return
+	}
+
+	record, err = R.probingForGet(keyRecord.Key, R.getBucketRecords)
+
+	return
+}
+
+// Exists - Checks whether a record with the given key exists. With Robin Hood hashing whole records are read.
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) Exists(keyRecord model.Record) (exists bool, err error) {
+	_, err = R.Get(keyRecord)
+	exists = err == nil
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+	}
+
+	return
+}
+
+// GetBatch - Gets records that corresponds to the given keys. Keys are processed in home bucket order and each
+// bucket is read at most once, to reduce random reads.
+//   - keyRecords is the identifiers of records, they have to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - records is the matching records in the same order as keyRecords, records not found are returned with State set to model.RecordEmpty
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) GetBatch(keyRecords []model.Record) (records []model.Record, err error) {
+	for _, keyRecord := range keyRecords {
+		if int64(len(keyRecord.Key)) != R.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "GetBatch", Expected: int(R.keyLength), Actual: len(keyRecord.Key)}
+			return
+		}
+	}
+
+	getBucket, _ := R.cachedBucketReader()
+	records = make([]model.Record, len(keyRecords))
+
+	for _, i := range storage.BucketOrder(keyRecords, R.hashAlgorithm.HashFunc1) {
+		records[i], err = R.probingForGet(keyRecords[i].Key, getBucket)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		if err != nil {
+			records = nil
+			return
+		}
+	}
+
+	return
+}
+
+// Set - Updates an existing record with new data or add it if no existing is found with same key. Adding a record may
+// move other records further away from their home buckets, in which case the records are written starting from the one
+// moved furthest. Hence, if the write is interrupted a moved record may exist in two places but is never lost.
+//   - record is the record to set, it needs only to contain Key and Value, and they have to conform to lengths given when creating the RHFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull no records are written.
+func (R *RHFiles) Set(record model.Record) (err error) {
+	_, _, err = R.set(record, false)
+
+	return
+}
+
+// GetOrSet - Returns the existing record with the same key as record if there is one, or else adds record as Set does.
+// The existing record is looked for through the same bucket reads as are used when adding record, so buckets are read
+// only once.
+//   - record is the record to add, it needs only to contain Key and Value, and they have to conform to lengths given when creating the RHFiles
+//
+// It returns:
+//   - existing is the existing record, if loaded is true
+//   - loaded is true if there was an existing record, in which case record was not added
+//   - err is a standard error, if something went wrong. If the error is of type crt.MapFileFull no records are written.
+func (R *RHFiles) GetOrSet(record model.Record) (existing model.Record, loaded bool, err error) {
+	existing, loaded, err = R.set(record, true)
+
+	return
+}
+
+// set - Is the implementation of Set and GetOrSet, where onlyIfAbsent set to true returns an existing record with the
+// same key instead of updating it
+func (R *RHFiles) set(record model.Record, onlyIfAbsent bool) (existing model.Record, loaded bool, err error) {
+	operation := "Set"
+	if onlyIfAbsent {
+		operation = "GetOrSet"
+	}
+
+	// Check validity of the key
+	if int64(len(record.Key)) != R.keyLength {
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: int(R.keyLength), Actual: len(record.Key)}
+		return
+	}
+	// Check validity of the value
+	if int64(len(record.Value)) != R.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: operation, Expected: int(R.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	getBucket, _ := R.cachedBucketReader()
+
+	// Update an existing record in place
+	existing, err = R.probingForGet(record.Key, getBucket)
+	if err == nil && onlyIfAbsent {
+		loaded = true
+		return
+	}
+	if err == nil {
+		existing.Value = record.Value
+		err = R.setBucketRecord(existing)
+		if err != nil {
+			err = fmt.Errorf("error while updating record in bucket: %s", err)
+		}
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		return
+	}
+
+	changed, freeState, err := R.probingForInsert(record.Key, record.Value, getBucket)
+	if err != nil {
+		return
+	}
+
+	for i := len(changed) - 1; i >= 0; i-- {
+		err = R.setBucketRecord(changed[i])
+		if err != nil {
+			err = fmt.Errorf("error while adding record to bucket: %s", err)
+			return
+		}
+	}
+
+	R.counters.Transition(freeState, model.RecordOccupied)
+
+	return
+}
+
+// SetBatch - Updates existing records with new data or add them if no existing are found with same keys.
+// Since adding a record may move other records, records are set one by one in the order given.
+//   - records is the records to set, they need only to contain Key and Value, and they have to conform to lengths given when creating the RHFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong. If an error occurs some records may have been set and others not.
+func (R *RHFiles) SetBatch(records []model.Record) (err error) {
+	for _, record := range records {
+		if int64(len(record.Key)) != R.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "SetBatch", Expected: int(R.keyLength), Actual: len(record.Key)}
+			return
+		}
+		if int64(len(record.Value)) != R.valueLength {
+			err = crt.WrongLength{Field: "value", Operation: "SetBatch", Expected: int(R.valueLength), Actual: len(record.Value)}
+			return
+		}
+	}
+
+	for _, record := range records {
+		err = R.Set(record)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Delete - Deletes a record by setting state to RecordDeleted. The displacement stored for the record is left as is.
+//   - record is the model.Record to mark as deleted, and it must contain RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) Delete(record model.Record) (err error) {
+	_, err = R.File.WriteAt([]byte{model.ToStateByte(model.RecordDeleted, 0)}, record.RecordAddress)
+	if err != nil {
+		err = fmt.Errorf("error while updating record state in bucket: %s", err)
+		return
+	}
+
+	_, err = R.File.WriteAt(make([]byte, R.keyLength+R.valueLength), record.RecordAddress+keyOffset)
+	if err != nil {
+		err = fmt.Errorf("error while clearing record in bucket: %s", err)
+		return
+	}
+
+	R.counters.Transition(model.RecordOccupied, model.RecordDeleted)
+
+	return
+}
+
+// Clear - Removes all records by discarding everything after the header in the map file and then extending it to its
+// full size again. The header is rewritten, which resets the mutation sequence number, while the system area is kept.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) Clear() (err error) {
+	err = R.ClearFile(R.createHeader())
+	if err != nil {
+		return
+	}
+
+	R.counters.Reset()
+
+	return
+}
+
+// SetValue - Sets the value of a record previously read, in place at its address, so the key is not probed for again.
+//   - record is the model.Record to update, and it must contain RecordAddress and the new Value
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (R *RHFiles) SetValue(record model.Record) (err error) {
+	// Check validity of the value
+	if int64(len(record.Value)) != R.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "SetValue", Expected: int(R.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	_, err = R.File.WriteAt(record.Value, record.RecordAddress+keyOffset+R.keyLength)
+	if err != nil {
+		err = fmt.Errorf("error while writing value to record: %s", err)
+	}
+
+	return
+}
@@ -0,0 +1,354 @@
+//go:build unit
+
+package robinhood
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestNewRHFiles(t *testing.T) {
+	t.Run("creates a new RHFiles instance", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		rhFiles, err := NewRHFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "create new RHFiles instance")
+		mapFileSize := storage.MapFileHeaderLength + rhFiles.numberOfBucketsAvailable*(keyOffset+16+10)*2
+		assert.Equal(t, "test-map.bin", rhFiles.FileName, "map filename correct")
+		assert.NotNil(t, rhFiles.File, "has map file")
+		assert.GreaterOrEqual(t, rhFiles.numberOfBucketsAvailable, int64(10), "needed buckets preserved in number of buckets")
+		assert.Equal(t, mapFileSize, rhFiles.FileSize, "map file size in correct length")
+		assert.Equal(t, crt.RobinHood, rhFiles.GetStorageParameters().CollisionResolutionTechnique, "correct crt")
+
+		stat, err := os.Stat(rhFiles.FileName)
+		assert.NoError(t, err, "map file exists")
+		assert.Equal(t, mapFileSize, stat.Size(), "map file has correct size")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("opens existing files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFilesInit, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = rhFilesInit.Set(record)
+		assert.NoError(t, err, "sets record to file")
+		rhFilesInit.CloseFiles()
+
+		// Execute
+		rhFiles, err := NewRHFilesFromExistingFiles("test", nil, nil)
+
+		// Check
+		assert.NoError(t, err, "opens existing files")
+		assert.Equal(t, rhFilesInit.GetStorageParameters(), rhFiles.GetStorageParameters(), "storage parameters preserved")
+
+		got, err := rhFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is preserved")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestEstimateRHFiles(t *testing.T) {
+	t.Run("estimates storage parameters without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             3,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		params, err := EstimateRHFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "estimate storage parameters")
+		_, statErr := os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(statErr), "no map file created")
+
+		files, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		assert.Equal(t, files.GetStorageParameters(), params, "estimate matches created files")
+
+		// Clean up
+		err = files.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+func TestRHFiles_Set(t *testing.T) {
+	t.Run("sets and gets all available records", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        64,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFiles, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		records := make([]model.Record, rhFiles.numberOfBucketsAvailable*2+1)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+		n := len(records) - 1
+
+		// Execute
+		for i, record := range records[:n] {
+			err := rhFiles.Set(record)
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+		errFull := rhFiles.Set(records[n])
+
+		// Check
+		assert.ErrorIs(t, errFull, crt.MapFileFull{}, "correct error when map file is full")
+
+		for i, record := range records[:n] {
+			got, err := rhFiles.Get(model.Record{Key: record.Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Truef(t, utils.IsEqual(record.Value, got.Value), "value of record #%d is preserved", i)
+
+			homeBucketNo := rhFiles.hashAlgorithm.HashFunc1(record.Key)
+			bucketNo := (got.RecordAddress - storage.MapFileHeaderLength) / ((keyOffset + 26) * 2)
+			distance := (bucketNo - homeBucketNo + rhFiles.numberOfBucketsAvailable) % rhFiles.numberOfBucketsAvailable
+			assert.Equalf(t, distance, got.Displacement, "displacement of record #%d is tracked", i)
+		}
+
+		_, err = rhFiles.Get(model.Record{Key: records[n].Key})
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "record not set is not found")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("updates an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFiles, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+		rand.Read(record.Key)
+		rand.Read(record.Value)
+		err = rhFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		record.Value = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = rhFiles.Set(record)
+
+		// Check
+		assert.NoError(t, err, "updates record in file")
+		got, err := rhFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "value is updated")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestRHFiles_Delete(t *testing.T) {
+	t.Run("deletes records and reuses their slots", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        64,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFiles, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		capacity := rhFiles.numberOfBucketsAvailable * 2
+		records := make([]model.Record, capacity)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+		for _, record := range records {
+			err := rhFiles.Set(record)
+			assert.NoError(t, err, "sets record to file")
+		}
+
+		// Execute
+		for _, record := range records[:capacity/2] {
+			got, err := rhFiles.Get(model.Record{Key: record.Key})
+			assert.NoError(t, err, "gets record from file")
+			err = rhFiles.Delete(got)
+			assert.NoError(t, err, "deletes record from file")
+		}
+
+		// Check
+		for i, record := range records {
+			_, err := rhFiles.Get(model.Record{Key: record.Key})
+			if int64(i) < capacity/2 {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "deleted record #%d not found", i)
+			} else {
+				assert.NoErrorf(t, err, "record #%d still found", i)
+			}
+		}
+
+		newRecords := make([]model.Record, capacity/2)
+		for i := range newRecords {
+			newRecords[i].Key = make([]byte, 16)
+			rand.Read(newRecords[i].Key)
+			newRecords[i].Value = make([]byte, 10)
+			rand.Read(newRecords[i].Value)
+		}
+		for i, record := range newRecords {
+			err := rhFiles.Set(record)
+			assert.NoErrorf(t, err, "sets new record #%d in deleted slot", i)
+		}
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestRHFiles_GetOrSet(t *testing.T) {
+	t.Run("sets an absent record and returns an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFiles, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+
+		// Execute
+		_, loadedFirst, errFirst := rhFiles.GetOrSet(record)
+		existing, loadedSecond, errSecond := rhFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.False(t, loadedFirst, "absent record is not loaded")
+		assert.NoError(t, errSecond, "gets existing record")
+		assert.True(t, loadedSecond, "existing record is loaded")
+		assert.True(t, utils.IsEqual(record.Value, existing.Value), "existing value is returned")
+
+		got, err := rhFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "existing value is kept")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("treats an expired record as absent", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.RobinHood,
+			HashAlgorithm:                nil,
+		}
+
+		rhFiles, err := NewRHFiles(crtConf)
+		assert.NoError(t, err, "create new RHFiles instance")
+		rhFiles.SetExpiryCheck(func(value []byte) bool { return value[0] == 0xff })
+		record := model.Record{Key: make([]byte, 16), Value: []byte{0xff, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = rhFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+		_, loaded, err := rhFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, err, "sets record in place of expired record")
+		assert.False(t, loaded, "expired record is not loaded")
+
+		got, err := rhFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(other.Value, got.Value), "new value is set")
+
+		// Clean up
+		rhFiles.CloseFiles()
+		err = rhFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,276 @@
+package robinhood
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+)
+
+// getBucketRecords - Returns record for a given bucket number in a model.Bucket struct
+func (R *RHFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err error) {
+	recordLength := keyOffset + R.keyLength + R.valueLength
+	bucketLength := recordLength * R.recordsPerBucket
+	bucketAddress := storage.MapFileHeaderLength + bucketNo*bucketLength
+
+	buf := R.bucketBuffers.Get(bucketLength)
+	defer R.bucketBuffers.Put(buf)
+	if R.Progress != nil {
+		R.Progress(bucketNo)
+	}
+
+	_, err = R.File.ReadAt(buf, bucketAddress)
+	if err != nil {
+		return
+	}
+
+	bucket = R.bytesToBucket(buf, bucketAddress)
+
+	return
+}
+
+// setBucketRecord - Sets a bucket record, including its displacement, in the hash map file
+func (R *RHFiles) setBucketRecord(record model.Record) (err error) {
+	buf := make([]byte, keyOffset, keyOffset+R.keyLength+R.valueLength)
+	buf[0] = model.ToStateByte(record.State, record.AccessCount)
+	binary.LittleEndian.PutUint32(buf[displacementOffset:], uint32(record.Displacement))
+
+	buf = append(buf, record.Key...)
+	buf = append(buf, record.Value...)
+
+	_, err = R.File.WriteAt(buf, record.RecordAddress)
+
+	return
+}
+
+// bytesToBucket - Converts bucket raw data to a Bucket struct
+func (R *RHFiles) bytesToBucket(buf []byte, bucketAddress int64) (bucket model.Bucket) {
+	records := make([]model.Record, R.recordsPerBucket)
+
+	recordLength := keyOffset + R.keyLength + R.valueLength
+
+	var keyStart, valueStart int64
+	for n := range records {
+		i := int64(n) * recordLength
+		keyStart = i + keyOffset
+		valueStart = keyStart + R.keyLength
+
+		key := make([]byte, R.keyLength)
+		value := make([]byte, R.valueLength)
+		_ = copy(key, buf[keyStart:keyStart+R.keyLength])
+		_ = copy(value, buf[valueStart:valueStart+R.valueLength])
+		state, accessCount := model.FromStateByte(buf[i])
+
+		records[n] = model.Record{
+			State:         state,
+			AccessCount:   accessCount,
+			RecordAddress: bucketAddress + i,
+			Displacement:  int64(binary.LittleEndian.Uint32(buf[i+displacementOffset:])),
+			Key:           key,
+			Value:         value,
+		}
+	}
+
+	bucket = model.Bucket{
+		Records:       records,
+		BucketAddress: bucketAddress,
+	}
+
+	return
+}
+
+// createHeader - Creates a header instance
+func (R *RHFiles) createHeader() (header storage.Header) {
+	header = storage.Header{
+		InternalHash:                 R.internalAlgorithm,
+		KeyLength:                    R.keyLength,
+		ValueLength:                  R.valueLength,
+		NumberOfBucketsNeeded:        R.numberOfBucketsNeeded,
+		NumberOfBucketsAvailable:     R.numberOfBucketsAvailable,
+		RecordsPerBucket:             R.recordsPerBucket,
+		MaxBucketNo:                  R.maxBucketNo,
+		FileSize:                     R.FileSize,
+		CollisionResolutionTechnique: int64(crt.RobinHood),
+		HashAlgorithmKind:            R.hashParameters.Kind,
+		HashSeed:                     R.hashParameters.Seed,
+	}
+
+	return
+}
+
+// cachedBucketReader - Returns a function reading buckets that keeps every bucket read in cache, and the cache itself
+func (R *RHFiles) cachedBucketReader() (getBucket func(int64) (model.Bucket, error), cache map[int64]model.Bucket) {
+	cache = make(map[int64]model.Bucket)
+	getBucket = func(bucketNo int64) (bucket model.Bucket, err error) {
+		bucket, ok := cache[bucketNo]
+		if !ok {
+			bucket, err = R.getBucketRecords(bucketNo)
+			if err != nil {
+				return
+			}
+			cache[bucketNo] = bucket
+		}
+		return
+	}
+
+	return
+}
+
+// probingForGet - Is the Robin Hood algorithm for getting a record. Probing stops at the first empty slot, or at the
+// first slot with a lower displacement than the current probe length, since the key would have taken that slot on insert.
+// Buckets are read using getBucket, which makes it possible to probe through buckets cached in memory.
+func (R *RHFiles) probingForGet(key []byte, getBucket func(int64) (model.Bucket, error)) (record model.Record, err error) {
+	getBucket = storage.CountProbes(getBucket, R.Metrics)
+	getBucket = storage.Interruptible(getBucket, R.Interrupt)
+
+	var bucket model.Bucket
+	var expired bool
+
+	bucketNo := R.hashAlgorithm.HashFunc1(key)
+
+	for probeLength := int64(0); probeLength < R.numberOfBucketsAvailable; probeLength++ {
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for j, r := range bucket.Records {
+			if r.State == model.RecordEmpty || r.Displacement < probeLength {
+				err = crt.NoRecordFound{}
+				return
+			}
+
+			if r.State == model.RecordOccupied {
+				expired, err = R.expireRecord(bucket.Records, j)
+				if err != nil {
+					return
+				}
+				if !expired && utils.IsEqual(key, r.Key) {
+					record = r
+					return
+				}
+			}
+		}
+
+		bucketNo = R.nextBucketNo(bucketNo)
+	}
+
+	err = crt.NoRecordFound{}
+	return
+}
+
+// probingForInsert - Is the Robin Hood algorithm for adding a record with a key that doesn't already exist. A record
+// with a lower displacement than the one being inserted is replaced, and the replaced record continues probing.
+// The first empty or deleted slot ends the probing, where a deleted slot keeps its displacement if higher.
+// Buckets are read using getBucket, and are updated in memory only.
+//
+// It returns:
+//   - changed is the records to write, in the order they were placed
+//   - freeState is the state of the free slot that was taken, either model.RecordEmpty or model.RecordDeleted
+//   - err is a standard error, of type crt.MapFileFull if no free slot was found
+func (R *RHFiles) probingForInsert(key, value []byte, getBucket func(int64) (model.Bucket, error)) (changed []model.Record, freeState uint8, err error) {
+	getBucket = storage.CountProbes(getBucket, R.Metrics)
+	getBucket = storage.Interruptible(getBucket, R.Interrupt)
+
+	var bucket model.Bucket
+
+	carried := model.Record{State: model.RecordOccupied, Key: key, Value: value}
+	bucketNo := R.hashAlgorithm.HashFunc1(key)
+
+	for i := int64(0); i < R.numberOfBucketsAvailable; i++ {
+		bucket, err = getBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for j := range bucket.Records {
+			// An expired record is as good as a deleted one
+			_, err = R.expireRecord(bucket.Records, j)
+			if err != nil {
+				return
+			}
+
+			r := bucket.Records[j]
+			switch {
+			case r.State != model.RecordOccupied:
+				freeState = r.State
+				carried.RecordAddress = r.RecordAddress
+				if r.Displacement > carried.Displacement {
+					carried.Displacement = r.Displacement
+				}
+				bucket.Records[j] = carried
+				changed = append(changed, carried)
+				return
+
+			case r.Displacement < carried.Displacement:
+				carried.RecordAddress = r.RecordAddress
+				bucket.Records[j] = carried
+				changed = append(changed, carried)
+				carried = r
+			}
+		}
+
+		bucketNo = R.nextBucketNo(bucketNo)
+		carried.Displacement++
+	}
+
+	changed = nil
+	err = crt.MapFileFull{}
+	return
+}
+
+// nextBucketNo - Returns the bucket number following bucketNo, wrapping around at the end of the table
+func (R *RHFiles) nextBucketNo(bucketNo int64) int64 {
+	bucketNo++
+	if bucketNo >= R.numberOfBucketsAvailable {
+		bucketNo = 0
+	}
+
+	return bucketNo
+}
+
+// expireRecord - Marks the record at index i in records as deleted, both in file and in records, if it is occupied
+// and has expired according to the expiry check (see SetExpiryCheck)
+func (R *RHFiles) expireRecord(records []model.Record, i int) (expired bool, err error) {
+	if R.IsExpired == nil || records[i].State != model.RecordOccupied || !R.IsExpired(records[i].Value) {
+		return
+	}
+
+	err = R.Delete(records[i])
+	if err != nil {
+		return
+	}
+
+	records[i].State = model.RecordDeleted
+	records[i].AccessCount = 0
+	expired = true
+
+	return
+}
+
+// countRecords - Counts the occupied and deleted records by reading all buckets in the map file
+func (R *RHFiles) countRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	for bucketNo := int64(0); bucketNo < R.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = R.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, record := range bucket.Records {
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
+	return
+}
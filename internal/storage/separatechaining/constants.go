@@ -1,7 +1,13 @@
 package separatechaining
 
+import "github.com/gostonefire/filehashmap/internal/storage"
+
 // ovflFileHeaderLength - Length of overflow file header
-const ovflFileHeaderLength int64 = 1024
+const ovflFileHeaderLength = storage.OvflFileHeaderLength
+
+// freeListHeadOffset - Overflow file header offset to the address of the first record in the list of free overflow
+// records, zero if the list is empty - 8 bytes
+const freeListHeadOffset int64 = 0
 
 // overflowAddressLength - Length of address to next record in overflow file
 const overflowAddressLength int64 = 8
@@ -9,5 +15,13 @@ const overflowAddressLength int64 = 8
 // bucketHeaderLength - Length of header in each bucket
 const bucketHeaderLength int64 = 8
 
+// hybridProbeLimit - Max number of buckets, including the home bucket, to probe in the map file before spilling
+// a record into the overflow file when using the Hybrid collision resolution technique
+const hybridProbeLimit int64 = 4
+
 // bucketOverflowAddressOffset - Bucket header offset to the overflow address - 8 bytes
 const bucketOverflowAddressOffset int64 = 0
+
+// linearHashingAddressSpace - Table size given to the hash algorithm when using the Linear Hashing collision resolution
+// technique, which makes HashFunc1 return a full hash value that is then reduced to the current number of buckets
+const linearHashingAddressSpace int64 = 1 << 32
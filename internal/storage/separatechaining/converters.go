@@ -26,9 +26,11 @@ func bytesToBucket(buf []byte, bucketAddress, recordsPerBucket, keyLength, value
 		value = make([]byte, valueLength)
 		_ = copy(key, buf[keyStart:keyStart+keyLength])
 		_ = copy(value, buf[valueStart:valueStart+valueLength])
+		state, accessCount := model.FromStateByte(buf[i])
 
 		records[n] = model.Record{
-			State:         buf[i],
+			State:         state,
+			AccessCount:   accessCount,
 			RecordAddress: bucketAddress + i,
 			Key:           key,
 			Value:         value,
@@ -47,6 +49,21 @@ func bytesToBucket(buf []byte, bucketAddress, recordsPerBucket, keyLength, value
 	return
 }
 
+// bucketToBytes - Converts a Bucket struct to raw data, including the bucket header
+func bucketToBytes(bucket model.Bucket, keyLength, valueLength int64) (buf []byte) {
+	recordLength := 1 + keyLength + valueLength // First byte is record state
+	buf = make([]byte, bucketHeaderLength, bucketHeaderLength+recordLength*int64(len(bucket.Records)))
+	binary.LittleEndian.PutUint64(buf[bucketOverflowAddressOffset:], uint64(bucket.OverflowAddress))
+
+	for _, record := range bucket.Records {
+		buf = append(buf, model.ToStateByte(record.State, record.AccessCount))
+		buf = append(buf, record.Key...)
+		buf = append(buf, record.Value...)
+	}
+
+	return
+}
+
 // overflowBytesToRecord - Converts record raw data for overflow to Record struct
 func overflowBytesToRecord(buf []byte, recordAddress, keyLength, valueLength int64) (record model.Record, err error) {
 	actual := int64(len(buf))
@@ -66,8 +83,11 @@ func overflowBytesToRecord(buf []byte, recordAddress, keyLength, valueLength int
 	_ = copy(key, buf[keyStart:keyStart+keyLength])
 	_ = copy(value, buf[valueStart:valueStart+valueLength])
 
+	state, accessCount := model.FromStateByte(buf[overflowAddressLength])
+
 	record = model.Record{
-		State:         buf[overflowAddressLength],
+		State:         state,
+		AccessCount:   accessCount,
 		IsOverflow:    true,
 		RecordAddress: recordAddress,
 		NextOverflow:  int64(binary.LittleEndian.Uint64(buf)),
@@ -82,7 +102,7 @@ func overflowBytesToRecord(buf []byte, recordAddress, keyLength, valueLength int
 func recordToOverflowBytes(record model.Record, keyLength, valueLength int64) (buf []byte) {
 	buf = make([]byte, 1+overflowAddressLength, keyLength+valueLength+overflowAddressLength) // First byte is record state
 	binary.LittleEndian.PutUint64(buf, uint64(record.NextOverflow))
-	buf[overflowAddressLength] = record.State
+	buf[overflowAddressLength] = model.ToStateByte(record.State, record.AccessCount)
 	buf = append(buf, record.Key...)
 	buf = append(buf, record.Value...)
 
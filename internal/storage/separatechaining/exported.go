@@ -1,6 +1,7 @@
 package separatechaining
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/hashfunc"
@@ -9,17 +10,21 @@ import (
 	"github.com/gostonefire/filehashmap/internal/overflow"
 	"github.com/gostonefire/filehashmap/internal/storage"
 	"github.com/gostonefire/filehashmap/internal/utils"
-	"os"
+	"github.com/gostonefire/filehashmap/vfs"
+	"time"
 )
 
 // SCFiles - Represents an implementation of file support for the Separate Chaining Collision Resolution Technique.
 // It uses two files in this particular implementation where one stores directly addressable buckets and the
-// other manages overflow in single linked lists.
+// other manages overflow in single linked lists. It also implements the Hybrid Collision Resolution Technique, where
+// a number of buckets following the home bucket are probed before a record is put in overflow, and the Linear Hashing
+// Collision Resolution Technique, where the map file grows by splitting one bucket at a time.
 type SCFiles struct {
 	mapFileName              string
 	ovflFileName             string
-	mapFile                  *os.File
-	ovflFile                 *os.File
+	fileSystem               vfs.FileSystem
+	mapFile                  vfs.File
+	ovflFile                 vfs.File
 	keyLength                int64
 	valueLength              int64
 	numberOfBucketsNeeded    int64
@@ -29,6 +34,18 @@ type SCFiles struct {
 	mapFileSize              int64
 	hashAlgorithm            hashfunc.HashAlgorithm
 	internalAlgorithm        bool
+	hashParameters           model.HashParameters
+	mutationSeq              int64
+	crtType                  int
+	probeLimit               int64
+	isExpired                func(value []byte) bool
+	progress                 func(bucketNo int64)
+	metrics                  model.Metrics
+	interrupt                func() error
+	bucketBuffers            storage.BufferPool
+	counters                 storage.Counters
+	ovflCounters             storage.Counters
+	freeListHead             int64
 }
 
 // NewSCFiles - Returns a pointer to a new instance of Separate Chaining file implementation.
@@ -39,24 +56,88 @@ type SCFiles struct {
 //   - scFiles which is a pointer to the created instance
 //   - err which is a standard Go type of error
 func NewSCFiles(crtConf model.CRTConf) (scFiles *SCFiles, err error) {
+	scFiles, err = newSCFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	header := scFiles.createHeader()
+
+	err = scFiles.createNewHashMapFile(header)
+	if err != nil {
+		return
+	}
+	err = scFiles.createNewOverflowFile()
+	if err != nil {
+		return
+	}
+
+	scFiles.counters.Reset()
+	scFiles.ovflCounters.Reset()
+	err = scFiles.saveCounters(false)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// EstimateSCFiles - Returns the storage parameters that NewSCFiles would give the files, without creating
+// any of them
+//   - crtConf is a model.CRTConf struct providing configuration parameter affecting files creation and processing
+//
+// It returns:
+//   - params is the storage parameters, with MapFileSize being the size the map file would be created with
+//   - err which is a standard Go type of error
+func EstimateSCFiles(crtConf model.CRTConf) (params model.StorageParameters, err error) {
+	scFiles, err := newSCFiles(crtConf)
+	if err != nil {
+		return
+	}
+
+	params = scFiles.GetStorageParameters()
+
+	return
+}
+
+// newSCFiles - Returns a pointer to a new instance of Separate Chaining file implementation with all parameters set
+// from crtConf, but without any files
+func newSCFiles(crtConf model.CRTConf) (scFiles *SCFiles, err error) {
+	crtType := crt.SeparateChaining
+	if crtConf.CollisionResolutionTechnique == crt.Hybrid || crtConf.CollisionResolutionTechnique == crt.LinearHashing {
+		crtType = crtConf.CollisionResolutionTechnique
+	}
+
 	// If no HashAlgorithm was given then use the default internal
 	var internalAlg bool
+	tableSize := getTableSize(crtType, crtConf.NumberOfBucketsNeeded)
 	if crtConf.HashAlgorithm == nil {
-		crtConf.HashAlgorithm = hash.NewSeparateChainingHashAlgorithm(crtConf.NumberOfBucketsNeeded)
+		crtConf.HashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.SeparateChaining, tableSize, crtConf.HashParameters)
+		if err != nil {
+			return
+		}
 		internalAlg = true
 	} else {
-		crtConf.HashAlgorithm.SetTableSize(crtConf.NumberOfBucketsNeeded)
+		err = storage.SetTableSize(crtConf.HashAlgorithm, tableSize)
+		if err != nil {
+			return
+		}
+		crtConf.HashParameters = model.HashParameters{}
 	}
 
 	// Calculate the hash map file various parameters
 	recordLength := 1 + crtConf.KeyLength + crtConf.ValueLength // First byte is record state
 	bucketLength := bucketHeaderLength + recordLength*crtConf.RecordsPerBucket
 	maxBucketNo := crtConf.HashAlgorithm.GetTableSize() - 1
+	if crtType == crt.LinearHashing {
+		maxBucketNo = initialLinearBuckets(crtConf.NumberOfBucketsNeeded) - 1
+	}
 	numberOfBuckets := maxBucketNo + 1
 	fileSize := bucketLength*numberOfBuckets + storage.MapFileHeaderLength
 
 	scFiles = &SCFiles{
 		mapFileName:              storage.GetMapFileName(crtConf.Name),
+		fileSystem:               storage.ResolveFileSystem(crtConf.FileSystem),
 		ovflFileName:             storage.GetOvflFileName(crtConf.Name),
 		keyLength:                crtConf.KeyLength,
 		valueLength:              crtConf.ValueLength,
@@ -67,17 +148,9 @@ func NewSCFiles(crtConf model.CRTConf) (scFiles *SCFiles, err error) {
 		mapFileSize:              fileSize,
 		hashAlgorithm:            crtConf.HashAlgorithm,
 		internalAlgorithm:        internalAlg,
-	}
-
-	header := scFiles.createHeader()
-
-	err = scFiles.createNewHashMapFile(header)
-	if err != nil {
-		return
-	}
-	err = scFiles.createNewOverflowFile()
-	if err != nil {
-		return
+		hashParameters:           crtConf.HashParameters,
+		crtType:                  crtType,
+		probeLimit:               getProbeLimit(crtType, numberOfBuckets),
 	}
 
 	return
@@ -87,15 +160,17 @@ func NewSCFiles(crtConf model.CRTConf) (scFiles *SCFiles, err error) {
 // existing files. If files doesn't exist, doesn't have a valid header or if its file size seems wrong given
 // size from header it fails with error.
 //   - Name is the name to base map and overflow file names on
+//   - hashAlgorithm is the custom hash algorithm the files were used with, nil for the internal
+//   - fileSystem is the file system holding the files, nil for the file system of the operating system
 //
 // It returns:
 //   - scFiles which is a pointer to the created instance
 //   - err which is a standard Go type of error
-func NewSCFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm) (scFiles *SCFiles, err error) {
+func NewSCFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorithm, fileSystem vfs.FileSystem) (scFiles *SCFiles, err error) {
 	mapFileName := storage.GetMapFileName(name)
 	ovflFileName := storage.GetOvflFileName(name)
 
-	scFiles = &SCFiles{mapFileName: mapFileName, ovflFileName: ovflFileName}
+	scFiles = &SCFiles{fileSystem: storage.ResolveFileSystem(fileSystem), mapFileName: mapFileName, ovflFileName: ovflFileName}
 
 	header, err := scFiles.openHashMapFile()
 	if err != nil {
@@ -120,11 +195,27 @@ func NewSCFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorit
 
 	// If no HashAlgorithm was given then use the default internal
 	var internalAlg bool
+	hashParameters := model.HashParameters{Kind: header.HashAlgorithmKind, Seed: header.HashSeed}
+	crtType := int(header.CollisionResolutionTechnique)
+	tableSize := getTableSize(crtType, header.NumberOfBucketsNeeded)
 	if hashAlgorithm == nil {
-		hashAlgorithm = hash.NewSeparateChainingHashAlgorithm(header.NumberOfBucketsNeeded)
+		hashAlgorithm, err = hash.NewInternalHashAlgorithm(crt.SeparateChaining, tableSize, hashParameters)
+		if err != nil {
+			scFiles.CloseFiles()
+			return
+		}
+		if crtType != crt.LinearHashing && hashAlgorithm.GetTableSize() != header.NumberOfBucketsAvailable {
+			scFiles.CloseFiles()
+			err = fmt.Errorf("reconstructed internal hash algorithm doesn't conform with header indicated number of buckets")
+			return
+		}
 		internalAlg = true
 	} else {
-		hashAlgorithm.SetTableSize(header.NumberOfBucketsNeeded)
+		err = storage.SetTableSize(hashAlgorithm, tableSize)
+		if err != nil {
+			scFiles.CloseFiles()
+			return
+		}
 	}
 
 	scFiles.keyLength = header.KeyLength
@@ -136,12 +227,26 @@ func NewSCFilesFromExistingFiles(name string, hashAlgorithm hashfunc.HashAlgorit
 	scFiles.mapFileSize = header.FileSize
 	scFiles.hashAlgorithm = hashAlgorithm
 	scFiles.internalAlgorithm = internalAlg
+	scFiles.hashParameters = hashParameters
+	scFiles.mutationSeq = header.MutationSeq
+	scFiles.crtType = crtType
+	scFiles.probeLimit = getProbeLimit(scFiles.crtType, header.NumberOfBucketsAvailable)
+
+	err = scFiles.loadCounters()
+	if err != nil {
+		scFiles.CloseFiles()
+		return
+	}
 
 	return
 }
 
-// CloseFiles - Closes the map files
+// CloseFiles - Closes the map files, saving the record counts as clean in the file headers first
 func (S *SCFiles) CloseFiles() {
+	if S.ovflFile != nil && S.mapFile != nil {
+		_ = S.saveCounters(true)
+	}
+
 	if S.ovflFile != nil {
 		_ = S.ovflFile.Sync()
 		_ = S.ovflFile.Close()
@@ -153,21 +258,93 @@ func (S *SCFiles) CloseFiles() {
 	}
 }
 
+// Sync - Commits the current contents of the map file and the overflow file to stable storage
+func (S *SCFiles) Sync() (err error) {
+	err = S.ovflFile.Sync()
+	if err != nil {
+		err = fmt.Errorf("error while syncing overflow file: %s", err)
+		return
+	}
+
+	err = S.mapFile.Sync()
+	if err != nil {
+		err = fmt.Errorf("error while syncing map file: %s", err)
+	}
+
+	return
+}
+
+// Advise - Declares the expected access pattern of the map file and the overflow file to the operating system,
+// see storage.Advise
+func (S *SCFiles) Advise(advice int) (err error) {
+	err = storage.Advise(S.mapFile, advice)
+	if err != nil {
+		err = fmt.Errorf("error while advising on map file: %s", err)
+		return
+	}
+
+	err = storage.Advise(S.ovflFile, advice)
+	if err != nil {
+		err = fmt.Errorf("error while advising on overflow file: %s", err)
+	}
+
+	return
+}
+
+// MemoryMap - Memory mapping of the map file is only supported by open addressing, so turning it on fails
+//   - enabled is true to map the map file into memory, false to release any mapping
+//
+// It returns:
+//   - err is a standard error, if enabled is true
+func (S *SCFiles) MemoryMap(enabled bool) (err error) {
+	if enabled {
+		err = fmt.Errorf("memory mapping of the map file is not supported by separate chaining")
+	}
+
+	return
+}
+
+// SetBucketCache - Caching of buckets is only supported by open addressing, so turning it on fails
+//   - budget is the maximum number of bytes of buckets to cache, zero turns caching off
+//
+// It returns:
+//   - err is a standard error, if budget is not zero
+func (S *SCFiles) SetBucketCache(budget int64) (err error) {
+	if budget != 0 {
+		err = fmt.Errorf("caching of buckets is not supported by separate chaining")
+	}
+
+	return
+}
+
+// SetMutationSeq - Persists the sequence number of the last applied mutation in the map file header
+func (S *SCFiles) SetMutationSeq(seq int64) (err error) {
+	err = storage.SetMutationSeq(S.mapFile, seq)
+	if err != nil {
+		err = fmt.Errorf("error while writing mutation sequence number to map file header: %s", err)
+		return
+	}
+
+	S.mutationSeq = seq
+
+	return
+}
+
 // RemoveFiles - Removes the map files, make sure to close them first before calling this function
 func (S *SCFiles) RemoveFiles() (err error) {
 	// Only try to remove if exists, and are not by accident directories (could happen when testing things out)
-	if stat, ok := os.Stat(S.ovflFileName); ok == nil {
+	if stat, ok := S.fileSystem.Stat(S.ovflFileName); ok == nil {
 		if !stat.IsDir() {
-			err = os.Remove(S.ovflFileName)
+			err = S.fileSystem.Remove(S.ovflFileName)
 			if err != nil {
 				err = fmt.Errorf("error while removing overflow file: %s", err)
 				return
 			}
 		}
 	}
-	if stat, ok := os.Stat(S.mapFileName); ok == nil {
+	if stat, ok := S.fileSystem.Stat(S.mapFileName); ok == nil {
 		if !stat.IsDir() {
-			err = os.Remove(S.mapFileName)
+			err = S.fileSystem.Remove(S.mapFileName)
 			if err != nil {
 				err = fmt.Errorf("error while removing map file: %s", err)
 				return
@@ -181,7 +358,7 @@ func (S *SCFiles) RemoveFiles() (err error) {
 // GetStorageParameters - Returns a struct with storage parameters from SCFiles
 func (S *SCFiles) GetStorageParameters() (params model.StorageParameters) {
 	params = model.StorageParameters{
-		CollisionResolutionTechnique: crt.SeparateChaining,
+		CollisionResolutionTechnique: S.crtType,
 		KeyLength:                    S.keyLength,
 		ValueLength:                  S.valueLength,
 		NumberOfBucketsNeeded:        S.numberOfBucketsNeeded,
@@ -189,8 +366,47 @@ func (S *SCFiles) GetStorageParameters() (params model.StorageParameters) {
 		RecordsPerBucket:             S.recordsPerBucket,
 		MapFileSize:                  S.mapFileSize,
 		InternalAlgorithm:            S.internalAlgorithm,
+		HashParameters:               S.hashParameters,
+		MutationSeq:                  S.mutationSeq,
+	}
+
+	return
+}
+
+// Counts - Returns the number of occupied and deleted records in the map and overflow files. The counts are kept in
+// the header of each file, and are counted again by reading all buckets and overflow chains when opening files that
+// were not closed properly.
+//
+// It returns:
+//   - occupied is the number of occupied records, which includes expired records not yet marked as deleted
+//   - deleted is the number of deleted records
+//   - err is a standard error, if something went wrong while counting
+func (S *SCFiles) Counts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = S.counters.Counts(S.countMapRecords)
+	if err != nil {
+		return
+	}
+
+	ovflOccupied, ovflDeleted, err := S.ovflCounters.Counts(S.countOverflowRecords)
+	if err != nil {
+		return
 	}
 
+	occupied += ovflOccupied
+	deleted += ovflDeleted
+
+	return
+}
+
+// OverflowCounts - Returns the number of occupied and deleted records in the overflow file only, see Counts
+//
+// It returns:
+//   - occupied is the number of occupied overflow records
+//   - deleted is the number of deleted overflow records
+//   - err is a standard error, if something went wrong while counting
+func (S *SCFiles) OverflowCounts() (occupied, deleted int64, err error) {
+	occupied, deleted, err = S.ovflCounters.Counts(S.countOverflowRecords)
+
 	return
 }
 
@@ -209,8 +425,69 @@ func (S *SCFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterat
 		return
 	}
 
-	getOvflFunc := func(recordAddress int64) (model.Record, error) { return S.getOverflowRecord(recordAddress) }
-	overflowIterator = overflow.NewRecords(getOvflFunc, bucket.OverflowAddress)
+	overflowIterator = S.getOverflowIterator(bucket)
+
+	return
+}
+
+// GetBuckets - Reads the buckets from fromBucketNo in a single read of at most maxBytes, but at least one bucket and
+// never past the last one, which is much faster than reading them one by one when scanning all buckets.
+//   - fromBucketNo is the number of the first bucket to read
+//   - maxBytes is the largest number of bytes to read at once
+//
+// It returns:
+//   - buckets is the buckets read, in bucket order
+//   - overflowIterators is an iterator over the overflow records of each bucket, with nil for buckets without overflow
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) GetBuckets(fromBucketNo, maxBytes int64) (buckets []model.Bucket, overflowIterators []*overflow.Records, err error) {
+	bucketLength := bucketHeaderLength + (1+S.keyLength+S.valueLength)*S.recordsPerBucket
+	n := storage.BucketsPerRead(fromBucketNo, S.numberOfBucketsAvailable, bucketLength, maxBytes)
+
+	buf := S.bucketBuffers.Get(n * bucketLength)
+	defer S.bucketBuffers.Put(buf)
+
+	bucketNo := fromBucketNo
+	err = storage.ReadBuckets(S.mapFile, buf, fromBucketNo, bucketLength, func(raw []byte, bucketAddress int64) error {
+		if S.progress != nil {
+			S.progress(bucketNo)
+		}
+		bucketNo++
+		bucket, decodeErr := bytesToBucket(raw, bucketAddress, S.recordsPerBucket, S.keyLength, S.valueLength)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		buckets = append(buckets, bucket)
+		overflowIterators = append(overflowIterators, S.getOverflowIterator(bucket))
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("error while getting buckets from hash map file: %s", err)
+		buckets, overflowIterators = nil, nil
+	}
+
+	return
+}
+
+// HomeBucket - Returns the bucket that key is addressed to
+func (S *SCFiles) HomeBucket(key []byte) (bucketNo int64) {
+	bucketNo = S.bucketNoOf(key)
+
+	return
+}
+
+// ProbeLength - Returns the number of buckets that a lookup of key probes in the map file before reaching bucketNo,
+// i.e. zero if bucketNo is the home bucket, or -1 if bucketNo is not among the buckets probed for key. Only Hybrid probes
+// other buckets than the home bucket.
+func (S *SCFiles) ProbeLength(key []byte, bucketNo int64) (probeLength int64) {
+	homeBucketNo := S.bucketNoOf(key)
+	for probeLength = 0; probeLength < S.probeLimit; probeLength++ {
+		if S.getProbeBucketNo(homeBucketNo, probeLength) == bucketNo {
+			return
+		}
+	}
+
+	probeLength = -1
 
 	return
 }
@@ -226,33 +503,62 @@ func (S *SCFiles) GetBucket(bucketNo int64) (bucket model.Bucket, overflowIterat
 func (S *SCFiles) Get(keyRecord model.Record) (record model.Record, err error) {
 	// Check validity of the key
 	if int64(len(keyRecord.Key)) != S.keyLength {
-		err = fmt.Errorf("wrong length of key, should be %d", S.keyLength)
+		err = crt.WrongLength{Field: "key", Operation: "Get", Expected: int(S.keyLength), Actual: len(keyRecord.Key)}
 		return
 	}
 
-	// Get current contents from within the bucket
-	bucketNo, err := S.getBucketNo(keyRecord.Key)
-	if err != nil {
-		return
-	}
-	bucket, ovflIter, err := S.GetBucket(bucketNo)
+	// Get current contents from within the home bucket and any probed buckets. A record is only put in overflow
+	// when all probed buckets are full, and an empty record is never used again once occupied, so an empty record
+	// means the record can't be found further on.
+	homeBucketNo, err := S.getBucketNo(keyRecord.Key)
 	if err != nil {
 		return
 	}
 
-	// Sort out record with correct key
-	for _, record = range bucket.Records {
-		if record.State == model.RecordOccupied && utils.IsEqual(keyRecord.Key, record.Key) {
+	var bucket, homeBucket model.Bucket
+	getBucket := storage.CountProbes(S.getBucketRecords, S.metrics)
+	getBucket = storage.Interruptible(getBucket, S.interrupt)
+	for i := int64(0); i < S.probeLimit; i++ {
+		bucket, err = getBucket(S.getProbeBucketNo(homeBucketNo, i))
+		if err != nil {
 			return
 		}
+		if i == 0 {
+			homeBucket = bucket
+		}
+
+		// Sort out record with correct key
+		for j := range bucket.Records {
+			record, err = S.getUnexpired(bucket.Records, j)
+			if err != nil {
+				return
+			}
+			if record.State == model.RecordOccupied && utils.IsEqual(keyRecord.Key, record.Key) {
+				return
+			}
+			if record.State == model.RecordEmpty {
+				record = model.Record{}
+				err = crt.NoRecordFound{}
+				return
+			}
+		}
 	}
 
 	// Check if record may be in overflow file
+	ovflIter := S.getOverflowIterator(homeBucket)
 	for ovflIter.HasNext() {
 		record, err = ovflIter.Next()
 		if err != nil {
 			return
 		}
+		err = S.overflowProbe()
+		if err != nil {
+			return
+		}
+		record, err = S.getUnexpired([]model.Record{record}, 0)
+		if err != nil {
+			return
+		}
 		if record.State == model.RecordOccupied && utils.IsEqual(keyRecord.Key, record.Key) {
 			return
 		}
@@ -264,64 +570,195 @@ func (S *SCFiles) Get(keyRecord model.Record) (record model.Record, err error) {
 	return
 }
 
+// Exists - Checks whether a record with the given key exists. With separate chaining whole records are read.
+//   - keyRecord is the identifier of a record, it has to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) Exists(keyRecord model.Record) (exists bool, err error) {
+	_, err = S.Get(keyRecord)
+	exists = err == nil
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+	}
+
+	return
+}
+
+// GetBatch - Gets records that corresponds to the given keys. Keys are processed in bucket order and each bucket,
+// including its overflow chain, is read only once, to reduce random reads.
+//   - keyRecords is the identifiers of records, they have to have the Key set and with the same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - records is the matching records in the same order as keyRecords, records not found are returned with State set to model.RecordEmpty
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) GetBatch(keyRecords []model.Record) (records []model.Record, err error) {
+	for _, keyRecord := range keyRecords {
+		if int64(len(keyRecord.Key)) != S.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "GetBatch", Expected: int(S.keyLength), Actual: len(keyRecord.Key)}
+			return
+		}
+	}
+
+	records = make([]model.Record, len(keyRecords))
+
+	var bucketNo int64
+	var candidates []model.Record
+	currentBucketNo := int64(-1)
+	for _, i := range storage.BucketOrder(keyRecords, S.bucketNoOf) {
+		bucketNo, err = S.getBucketNo(keyRecords[i].Key)
+		if err != nil {
+			return
+		}
+
+		if bucketNo != currentBucketNo {
+			candidates, err = S.getOccupiedRecords(bucketNo)
+			if err != nil {
+				records = nil
+				return
+			}
+			currentBucketNo = bucketNo
+		}
+
+		for _, candidate := range candidates {
+			if utils.IsEqual(keyRecords[i].Key, candidate.Key) {
+				records[i] = candidate
+				break
+			}
+		}
+	}
+
+	return
+}
+
 // Set - Updates an existing record with new data or add it if no existing is found with same key.
 //   - record is the record to set, it needs only to contain Key and Value, and they have to conform to lengths given when creating the SCFiles
 //
 // It returns:
 //   - err is a standard error, if something went wrong
 func (S *SCFiles) Set(record model.Record) (err error) {
+	_, _, err = S.set(record, false)
+
+	return
+}
+
+// GetOrSet - Returns the existing record with the same key as record if there is one, or else adds record. The existing
+// record is looked for while probing for where to add record, so buckets are searched only once.
+//   - record is the record to add, it needs only to contain Key and Value, and they have to conform to lengths given when creating the SCFiles
+//
+// It returns:
+//   - existing is the existing record, if loaded is true
+//   - loaded is true if there was an existing record, in which case record was not added
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) GetOrSet(record model.Record) (existing model.Record, loaded bool, err error) {
+	existing, loaded, err = S.set(record, true)
+
+	return
+}
+
+// set - Is the implementation of Set and GetOrSet, where onlyIfAbsent set to true returns an existing record with the
+// same key instead of updating it
+func (S *SCFiles) set(record model.Record, onlyIfAbsent bool) (existing model.Record, loaded bool, err error) {
+	operation := "Set"
+	if onlyIfAbsent {
+		operation = "GetOrSet"
+	}
+
 	// Check validity of the key
 	if int64(len(record.Key)) != S.keyLength {
-		err = fmt.Errorf("wrong length of key, should be %d", S.keyLength)
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: int(S.keyLength), Actual: len(record.Key)}
 		return
 	}
 	// Check validity of the value
 	if int64(len(record.Value)) != S.valueLength {
-		err = fmt.Errorf("wrong length of value, should be %d", S.valueLength)
+		err = crt.WrongLength{Field: "value", Operation: operation, Expected: int(S.valueLength), Actual: len(record.Value)}
 		return
 	}
 
-	// Get current contents from within the bucket
-	bucketNo, err := S.getBucketNo(record.Key)
-	if err != nil {
-		return
-	}
-	bucket, ovflIter, err := S.GetBucket(bucketNo)
+	// Get current contents from within the home bucket
+	homeBucketNo, err := S.getBucketNo(record.Key)
 	if err != nil {
 		return
 	}
 
-	// First check if there is a record to update in the map file bucket, if there is or if the bucket record is
-	// empty (never used) then we now that we can set the record and avoid searching in overflow file.
-	// If we have a deleted record then save that for potential later use, but we have to search in overflow file as well.
+	// First check if there is a record to update in the home bucket or any probed bucket, if there is or if a bucket
+	// record is empty (never used) then we now that we can set the record and avoid searching further.
+	// If we have a deleted record then save that for potential later use, but we have to search further as well.
 	var hasDeleted bool
+	var bucket, homeBucket model.Bucket
 	var deletedRecord, ovflRecord model.Record
 
-	for _, r := range bucket.Records {
-		if (r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key)) || r.State == model.RecordEmpty {
-			r.State = model.RecordOccupied
-			r.Key = record.Key
-			r.Value = record.Value
-			err = S.setBucketRecord(r)
-			if err != nil {
-				err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
-			}
+	getBucket := storage.CountProbes(S.getBucketRecords, S.metrics)
+	getBucket = storage.Interruptible(getBucket, S.interrupt)
+	for i := int64(0); i < S.probeLimit; i++ {
+		bucket, err = getBucket(S.getProbeBucketNo(homeBucketNo, i))
+		if err != nil {
+			err = fmt.Errorf("error while getting existing bucket records from hash map file: %s", err)
 			return
-		} else if r.State == model.RecordDeleted {
-			hasDeleted = true
-			deletedRecord = r
+		}
+		if i == 0 {
+			homeBucket = bucket
+		}
+
+		for j := range bucket.Records {
+			r := bucket.Records[j]
+			if !(r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key)) {
+				r, err = S.getUnexpired(bucket.Records, j)
+				if err != nil {
+					return
+				}
+			}
+			if onlyIfAbsent && r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key) && !S.hasExpired(r) {
+				existing, loaded = r, true
+				return
+			}
+			if (r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key)) || r.State == model.RecordEmpty {
+				if r.State == model.RecordEmpty && hasDeleted {
+					r = deletedRecord
+				}
+				previousState := r.State
+				r.State = model.RecordOccupied
+				r.Key = record.Key
+				r.Value = record.Value
+				err = S.setBucketRecord(r)
+				if err != nil {
+					err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
+					return
+				}
+				S.counters.Transition(previousState, model.RecordOccupied)
+				return
+			} else if !hasDeleted && r.State == model.RecordDeleted {
+				hasDeleted = true
+				deletedRecord = r
+			}
 		}
 	}
 
 	// Search through all overflow records until we find a matching record, in the process save first deleted record for
 	// potential later use (unless we already have a deleted record from the bucket file).
 	// If we have no match in overflow records we have to continue our search for best option.
+	ovflIter := S.getOverflowIterator(homeBucket)
 	for ovflIter.HasNext() {
 		ovflRecord, err = ovflIter.Next()
 		if err != nil {
 			err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
 			return
 		}
+		err = S.overflowProbe()
+		if err != nil {
+			return
+		}
+		if ovflRecord.State == model.RecordOccupied && !utils.IsEqual(ovflRecord.Key, record.Key) {
+			ovflRecord, err = S.getUnexpired([]model.Record{ovflRecord}, 0)
+			if err != nil {
+				return
+			}
+		}
+		if onlyIfAbsent && ovflRecord.State == model.RecordOccupied && utils.IsEqual(ovflRecord.Key, record.Key) && !S.hasExpired(ovflRecord) {
+			existing, loaded = ovflRecord, true
+			return
+		}
 		if ovflRecord.State == model.RecordOccupied && utils.IsEqual(ovflRecord.Key, record.Key) {
 			ovflRecord.Key = record.Key
 			ovflRecord.Value = record.Value
@@ -346,50 +783,135 @@ func (S *SCFiles) Set(record model.Record) (err error) {
 			err = S.setOverflowRecord(deletedRecord)
 			if err != nil {
 				err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
+				return
 			}
 		} else {
 			err = S.setBucketRecord(deletedRecord)
 			if err != nil {
 				err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
+				return
 			}
 		}
+		S.countersOf(deletedRecord).Transition(model.RecordDeleted, model.RecordOccupied)
 		return
 	}
 
 	// There was no available (deleted) record to use, so now we will either append (link) a new record in overflow file.
-	// Or if the bucket has no overflow since earlier, create a new overflow for it and update the bucket accordingly.
+	// Or if the home bucket has no overflow since earlier, create a new overflow for it and update the bucket accordingly.
 	if ovflRecord.IsOverflow {
 		err = S.appendOverflowRecord(ovflRecord, record.Key, record.Value)
 		if err != nil {
 			err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
+			return
 		}
-		return
 	} else {
 		var overflowAddress int64
 		overflowAddress, err = S.newBucketOverflow(record.Key, record.Value)
 		if err != nil {
 			return
 		}
-		err = S.setBucketOverflowAddress(bucket.BucketAddress, overflowAddress)
+		err = S.setBucketOverflowAddress(homeBucket.BucketAddress, overflowAddress)
 		if err != nil {
+			err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
 			return
 		}
 	}
+	S.ovflCounters.Transition(model.RecordEmpty, model.RecordOccupied)
 
-	if err != nil {
-		err = fmt.Errorf("error while updating or adding record to bucket or overflow: %s", err)
+	// With Linear Hashing every new overflow record grows the map file by one bucket
+	if S.crtType == crt.LinearHashing {
+		err = S.splitBucket()
+		if err != nil {
+			err = fmt.Errorf("error while splitting bucket: %s", err)
+		}
 	}
 
 	return
 }
 
-// Delete - Deletes a record by setting it to in use is false
-//   - record is the model.Record to mark as deleted, and it must contain IsOverflow, RecordAddress and NextOverflow
+// SetBatch - Updates existing records with new data or add them if no existing are found with same keys.
+// Records are grouped by bucket and buckets are processed in file offset order. Each bucket and its overflow chain is
+// read once, all records for the bucket are resolved in memory, and then the bucket is written in one operation.
+// Records that need new overflow space are appended to the overflow file in one operation per bucket, and synced before
+// they are linked into the chain. If the same key occurs more than once in records, the last occurrence wins.
+//   - records is the records to set, they need only to contain Key and Value, and they have to conform to lengths given when creating the SCFiles
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) SetBatch(records []model.Record) (err error) {
+	for _, record := range records {
+		if int64(len(record.Key)) != S.keyLength {
+			err = crt.WrongLength{Field: "key", Operation: "SetBatch", Expected: int(S.keyLength), Actual: len(record.Key)}
+			return
+		}
+		if int64(len(record.Value)) != S.valueLength {
+			err = crt.WrongLength{Field: "value", Operation: "SetBatch", Expected: int(S.valueLength), Actual: len(record.Value)}
+			return
+		}
+	}
+
+	records = storage.DedupeByKey(records)
+	storage.SortByBucket(records, S.bucketNoOf)
+
+	// Records may end up in any of the probed buckets when using the Hybrid technique, and buckets may be split while
+	// setting records when using the Linear Hashing technique, so set them one by one
+	if S.probeLimit > 1 || S.crtType == crt.LinearHashing {
+		for _, record := range records {
+			err = S.Set(record)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	var bucketNo, start int64
+	for i := range records {
+		bucketNo, err = S.getBucketNo(records[i].Key)
+		if err != nil {
+			return
+		}
+
+		if i == len(records)-1 {
+			err = S.setBucketBatch(bucketNo, records[start:])
+		} else if next := S.bucketNoOf(records[i+1].Key); next != bucketNo {
+			err = S.setBucketBatch(bucketNo, records[start:i+1])
+			start = int64(i + 1)
+		}
+		if err != nil {
+			err = fmt.Errorf("error while setting batch of records in bucket %d: %s", bucketNo, err)
+			return
+		}
+	}
+
+	return
+}
+
+// Delete - Deletes a record by setting it to in use is false. A record in the overflow file is also unlinked from its
+// overflow chain and put in the list of free overflow records, to be reused when a new overflow record is needed.
+//   - record is the model.Record to mark as deleted, and it must contain IsOverflow, RecordAddress and NextOverflow, and for an overflow record also Key
 //
 // It returns:
 //   - err is a standard error, if something went wrong
 func (S *SCFiles) Delete(record model.Record) (err error) {
+	if record.IsOverflow {
+		var freed bool
+		freed, err = S.freeOverflowRecord(record)
+		if err != nil || freed {
+			return
+		}
+	}
+
+	err = S.markDeleted(record)
+
+	return
+}
+
+// markDeleted - Marks a record as deleted where it is, which leaves an overflow record in its overflow chain. This is
+// used rather than Delete while walking an overflow chain, since the chain must not change under the walk.
+func (S *SCFiles) markDeleted(record model.Record) (err error) {
 	record.State = model.RecordDeleted
+	record.AccessCount = 0
 	record.Key = make([]byte, S.keyLength)
 	record.Value = make([]byte, S.valueLength)
 
@@ -397,12 +919,359 @@ func (S *SCFiles) Delete(record model.Record) (err error) {
 		err = S.setOverflowRecord(record)
 		if err != nil {
 			err = fmt.Errorf("error while updating record in overflow: %s", err)
+			return
 		}
 	} else {
 		err = S.setBucketRecord(record)
 		if err != nil {
 			err = fmt.Errorf("error while updating record in bucket: %s", err)
+			return
+		}
+	}
+
+	S.countersOf(record).Transition(model.RecordOccupied, model.RecordDeleted)
+
+	return
+}
+
+// Clear - Removes all records by discarding everything after the header in the map file and then extending it to its
+// full size again, and by discarding all records in the overflow file. The header is rewritten, which resets the
+// mutation sequence number, while the system area is kept. With Linear Hashing the map file shrinks back to its
+// initial number of buckets.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) Clear() (err error) {
+	if S.crtType == crt.LinearHashing {
+		S.setNumberOfBuckets(initialLinearBuckets(S.numberOfBucketsNeeded))
+	}
+
+	err = storage.ClearFile(S.mapFile, storage.MapFileHeaderLength, S.mapFileSize)
+	if err != nil {
+		err = fmt.Errorf("error while clearing map file: %s", err)
+		return
+	}
+
+	err = storage.ClearFile(S.ovflFile, ovflFileHeaderLength, ovflFileHeaderLength)
+	if err != nil {
+		err = fmt.Errorf("error while clearing overflow file: %s", err)
+		return
+	}
+
+	err = S.setFreeListHead(0)
+	if err != nil {
+		err = fmt.Errorf("error while writing free list head to overflow file header: %s", err)
+		return
+	}
+
+	err = storage.SetHeader(S.mapFile, S.createHeader())
+	if err != nil {
+		err = fmt.Errorf("error while writing header to map file: %s", err)
+		return
+	}
+
+	S.mutationSeq = 0
+	S.counters.Reset()
+	S.ovflCounters.Reset()
+
+	return
+}
+
+// SetExpiryCheck - Sets a function that tells whether the value of an occupied record has expired. Expired records
+// are treated as deleted, and are marked deleted in file when encountered while getting or setting records.
+//   - isExpired is the function to call with the value of a record, nil turns off expiry checks
+func (S *SCFiles) SetExpiryCheck(isExpired func(value []byte) bool) {
+	S.isExpired = isExpired
+}
+
+// SetProgress - Sets a function that is called with the bucket number each time a bucket is read from the map file,
+// which lets a caller follow the progress of long-running operations such as probe loops and scans.
+//   - progress is the function to call, nil turns off progress reporting
+func (S *SCFiles) SetProgress(progress func(bucketNo int64)) {
+	S.progress = progress
+}
+
+// SetMetrics - Sets the receiver of metrics counted while accessing the map file and the overflow file, which counts
+// buckets probed, overflow records walked and appended, and bytes read from and written to the files
+//   - metrics is the receiver, nil turns off counting
+func (S *SCFiles) SetMetrics(metrics model.Metrics) {
+	S.metrics = metrics
+	S.mapFile = storage.CountBytes(S.mapFile, metrics)
+	S.ovflFile = storage.CountBytes(S.ovflFile, metrics)
+}
+
+// SetWriteBuffer - Holds writes to the map and overflow files in memory, coalesced into as few writes as possible, until
+// they are flushed when maxBytes is reached, when interval has passed since the first pending write, or when the files
+// are synced or closed. Reads see the pending writes. Any pending writes are flushed when the buffer is replaced.
+//   - maxBytes is the max number of bytes to hold per file before flushing, zero turns buffering off
+//   - interval is the max time to hold writes, zero means no time limit
+//
+// It returns:
+//   - err is a standard error, if pending writes could not be flushed
+func (S *SCFiles) SetWriteBuffer(maxBytes int64, interval time.Duration) (err error) {
+	S.mapFile, err = storage.BufferWrites(S.mapFile, maxBytes, interval)
+	if err != nil {
+		return
+	}
+	S.ovflFile, err = storage.BufferWrites(S.ovflFile, maxBytes, interval)
+
+	return
+}
+
+// SetInterrupt - Sets a function that is called before each bucket or overflow record read while looking for a key, and
+// stops the lookup with the error it returns, if any. Lookups, including the search for a slot for a new record, are
+// done before anything is written, so interrupting them never leaves the files half updated.
+//   - interrupt is the function to call, nil turns off interruption
+func (S *SCFiles) SetInterrupt(interrupt func() error) {
+	S.interrupt = interrupt
+}
+
+// SetValue - Sets the value of a record previously read, in place at its address, so the key is not probed for again.
+//   - record is the model.Record to update, and it must contain RecordAddress and IsOverflow and the new Value
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) SetValue(record model.Record) (err error) {
+	// Check validity of the value
+	if int64(len(record.Value)) != S.valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "SetValue", Expected: int(S.valueLength), Actual: len(record.Value)}
+		return
+	}
+
+	if record.IsOverflow {
+		_, err = S.ovflFile.WriteAt(record.Value, record.RecordAddress+overflowAddressLength+1+S.keyLength) // Record state follows the overflow address
+	} else {
+		_, err = S.mapFile.WriteAt(record.Value, record.RecordAddress+1+S.keyLength) // First byte is record state
+	}
+	if err != nil {
+		err = fmt.Errorf("error while writing value to record: %s", err)
+	}
+
+	return
+}
+
+// AddAccessCount - Adds increment to the saturating access counter of a record
+//   - record is the model.Record to update, and it must contain IsOverflow and RecordAddress
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) AddAccessCount(record model.Record, increment int64) (err error) {
+	if record.IsOverflow {
+		err = storage.AddAccessCount(S.ovflFile, record.RecordAddress+overflowAddressLength, increment)
+		if err != nil {
+			err = fmt.Errorf("error while updating access count in overflow: %s", err)
+		}
+	} else {
+		err = storage.AddAccessCount(S.mapFile, record.RecordAddress, increment)
+		if err != nil {
+			err = fmt.Errorf("error while updating access count in bucket: %s", err)
+		}
+	}
+
+	return
+}
+
+// GetSystemValue - Returns a value stored by the library for its own use in the system area of the map file header
+//   - id is the identifier of the system value
+//
+// It returns:
+//   - value is the stored value if found, if not found an error of type crt.NoRecordFound is returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (S *SCFiles) GetSystemValue(id uint8) (value []byte, err error) {
+	value, err = storage.GetSystemValue(S.mapFile, id)
+
+	return
+}
+
+// SetSystemValue - Stores a value for the library's own use in the system area of the map file header
+//   - id is the identifier of the system value, it must not be zero
+//   - value is the value to store, at most 255 bytes long
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) SetSystemValue(id uint8, value []byte) (err error) {
+	err = storage.SetSystemValue(S.mapFile, id, value)
+	if err != nil {
+		err = fmt.Errorf("error while setting system value in map file header: %s", err)
+	}
+
+	return
+}
+
+// UnreachableOverflow - Returns the addresses of overflow records that are neither reachable from any bucket nor in the
+// list of free overflow records, which ScavengeOverflow would put on the list. Nothing is changed in the files.
+//
+// It returns:
+//   - addresses is the addresses of the unreachable records, in increasing order
+//   - err is a standard error, if an overflow chain or the list of free overflow records is broken or loops
+func (S *SCFiles) UnreachableOverflow() (addresses []int64, err error) {
+	reachable, err := S.getReachableOverflowAddresses()
+	if err != nil {
+		err = fmt.Errorf("error while walking overflow chains: %s", err)
+		return
+	}
+	err = S.addFreeOverflowAddresses(reachable)
+	if err != nil {
+		err = fmt.Errorf("error while walking list of free overflow records: %s", err)
+		return
+	}
+
+	stat, err := S.ovflFile.Stat()
+	if err != nil {
+		return
+	}
+
+	overflowRecordLength := overflowAddressLength + 1 + S.keyLength + S.valueLength // First byte after address is record state
+	for address := ovflFileHeaderLength; address+overflowRecordLength <= stat.Size(); address += overflowRecordLength {
+		if !reachable[address] {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return
+}
+
+// ScavengeOverflow - Finds overflow records that are not reachable from any bucket nor in the list of free overflow
+// records, which may happen if a crash occurred after a record was appended to the overflow file but before it was
+// linked into an overflow chain, or while a record was being freed. Unreachable records are marked as deleted, if not
+// already, and put in the list of free overflow records so their space is reused.
+//
+// It returns:
+//   - leaked is the number of unreachable records found
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) ScavengeOverflow() (leaked int64, err error) {
+	addresses, err := S.UnreachableOverflow()
+	if err != nil {
+		return
+	}
+
+	var record model.Record
+	for _, address := range addresses {
+		leaked++
+
+		record, err = S.getOverflowRecord(address)
+		if err != nil {
+			err = fmt.Errorf("error while reading unreachable overflow record: %s", err)
+			return
+		}
+		// Unreachable records are not counted, so they are marked without going through Delete
+		if record.State != model.RecordDeleted {
+			record.State = model.RecordDeleted
+			record.AccessCount = 0
+			record.Key = make([]byte, S.keyLength)
+			record.Value = make([]byte, S.valueLength)
+			err = S.setOverflowRecord(record)
+			if err != nil {
+				err = fmt.Errorf("error while updating record in overflow: %s", err)
+				return
+			}
+		}
+
+		err = S.pushFreeOverflowRecord(address)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// CompactOverflow - Rewrites the overflow file so that it holds nothing but the records in overflow chains. Records not
+// occupied are unlinked from their chains, records towards the end of the file are moved into the space left by
+// unlinked, free and unreachable records closer to the beginning, chains and bucket overflow addresses are relinked to
+// the moved records, and the file is finally truncated. Moved records are written and synced before any link to them is
+// changed, and the old records are kept until all links are synced, so a crash while compacting leaves at most
+// unreachable records (see ScavengeOverflow), never a broken chain.
+//
+// It returns:
+//   - reclaimed is the number of overflow records removed from the overflow file
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) CompactOverflow() (reclaimed int64, err error) {
+	// Free records will either be overwritten or truncated away, a crash from here on leaves them unreachable
+	err = S.setFreeListHead(0)
+	if err != nil {
+		err = fmt.Errorf("error while writing free list head to overflow file header: %s", err)
+		return
+	}
+
+	links, err := S.unlinkUnoccupiedOverflowRecords()
+	if err != nil {
+		return
+	}
+	err = S.Sync()
+	if err != nil {
+		return
+	}
+
+	fileSize, err := storage.GetFileSize(S.ovflFile)
+	if err != nil {
+		return
+	}
+	overflowRecordLength := overflowAddressLength + 1 + S.keyLength + S.valueLength // First byte after address is record state
+	end := ovflFileHeaderLength + int64(len(links))*overflowRecordLength
+
+	// Records at or beyond the new end of file are moved into the addresses before it that no record in a chain is using
+	inUse := make(map[int64]bool, len(links))
+	for _, link := range links {
+		inUse[link.address] = true
+	}
+	var moved []int
+	var record model.Record
+	hole := ovflFileHeaderLength
+	for i := range links {
+		if links[i].address < end {
+			continue
 		}
+		for inUse[hole] {
+			hole += overflowRecordLength
+		}
+
+		record, err = S.getOverflowRecord(links[i].address)
+		if err != nil {
+			err = fmt.Errorf("error while reading overflow record to move: %s", err)
+			return
+		}
+		record.RecordAddress = hole
+		err = S.setOverflowRecord(record)
+		if err != nil {
+			err = fmt.Errorf("error while writing moved overflow record: %s", err)
+			return
+		}
+
+		links[i].address = hole
+		moved = append(moved, i)
+		hole += overflowRecordLength
+	}
+	err = S.ovflFile.Sync()
+	if err != nil {
+		return
+	}
+
+	// Links are changed once all moved records are durable, each to the new address of the record linking to it
+	for _, i := range moved {
+		err = S.setOverflowLink(links, links[i].linking, links[i].bucketAddress, links[i].address)
+		if err != nil {
+			err = fmt.Errorf("error while relinking moved overflow record: %s", err)
+			return
+		}
+	}
+	err = S.Sync()
+	if err != nil {
+		return
+	}
+
+	err = S.ovflFile.Truncate(end)
+	if err != nil {
+		err = fmt.Errorf("error while truncating overflow file: %s", err)
+		return
+	}
+	err = S.ovflFile.Sync()
+	if err != nil {
+		return
+	}
+
+	if fileSize > end {
+		reclaimed = (fileSize - end) / overflowRecordLength
 	}
 
 	return
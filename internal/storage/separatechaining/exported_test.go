@@ -61,6 +61,37 @@ func TestNewSCFiles(t *testing.T) {
 	})
 }
 
+func TestEstimateSCFiles(t *testing.T) {
+	t.Run("estimates storage parameters without creating files", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        100,
+			RecordsPerBucket:             3,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.LinearHashing,
+			HashAlgorithm:                nil,
+		}
+
+		// Execute
+		params, err := EstimateSCFiles(crtConf)
+
+		// Check
+		assert.NoError(t, err, "estimate storage parameters")
+		_, statErr := os.Stat("test-map.bin")
+		assert.True(t, os.IsNotExist(statErr), "no map file created")
+
+		files, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		assert.Equal(t, files.GetStorageParameters(), params, "estimate matches created files")
+
+		// Clean up
+		err = files.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
 func TestNewSCFilesFromExistingFiles(t *testing.T) {
 	t.Run("opens SCFiles on existing files", func(t *testing.T) {
 		// Prepare
@@ -78,7 +109,7 @@ func TestNewSCFilesFromExistingFiles(t *testing.T) {
 		scFilesInit.CloseFiles()
 
 		// Execute
-		scFiles, err := NewSCFilesFromExistingFiles("test", nil)
+		scFiles, err := NewSCFilesFromExistingFiles("test", nil, nil)
 
 		// Check
 		mapFileSize := storage.MapFileHeaderLength + scFiles.numberOfBucketsAvailable*((crtConf.KeyLength+crtConf.ValueLength+1)*3+bucketHeaderLength)
@@ -337,6 +368,305 @@ func TestSCFiles_Overflow(t *testing.T) {
 	})
 }
 
+func TestSCFiles_SetValue(t *testing.T) {
+	t.Run("sets values in place in both map and overflow file", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 10,
+			RecordsPerBucket:      2,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		records := make([]model.Record, 100)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+
+			err = scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+
+		// Execute
+		var hadOverflow bool
+		for i := range records {
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			hadOverflow = hadOverflow || record.IsOverflow
+
+			record.Value = make([]byte, 10)
+			record.Value[0] = byte(i)
+			err = scFiles.SetValue(record)
+			assert.NoErrorf(t, err, "sets value of record #%d", i)
+		}
+		errLength := scFiles.SetValue(model.Record{Value: []byte{1}})
+
+		// Check
+		assert.True(t, hadOverflow, "some record(s) is in overflow")
+		for i := range records {
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Equalf(t, byte(i), record.Value[0], "value of record #%d is updated", i)
+		}
+		assert.ErrorIs(t, errLength, crt.WrongLength{}, "value of wrong length is rejected")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSCFiles_Counts(t *testing.T) {
+	crtConf := model.CRTConf{
+		Name:                  "test",
+		NumberOfBucketsNeeded: 10,
+		RecordsPerBucket:      2,
+		KeyLength:             16,
+		ValueLength:           10,
+		HashAlgorithm:         nil,
+	}
+
+	setRecords := func(t *testing.T, scFiles *SCFiles) (freed int64) {
+		records := make([]model.Record, 100)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+
+			err := scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+		for i := 0; i < 30; i++ {
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			err = scFiles.Delete(record)
+			assert.NoErrorf(t, err, "deletes record #%d", i)
+			if record.IsOverflow {
+				freed++
+			}
+		}
+
+		return
+	}
+
+	t.Run("loads counts saved when files were closed", func(t *testing.T) {
+		// Prepare
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		freed := setRecords(t, scFiles)
+
+		occupied, deleted, err := scFiles.Counts()
+		assert.NoError(t, err, "gets counts")
+		ovflOccupied, ovflDeleted, err := scFiles.OverflowCounts()
+		assert.NoError(t, err, "gets overflow counts")
+		scFiles.CloseFiles()
+
+		// Execute
+		scFiles, err = NewSCFilesFromExistingFiles(crtConf.Name, nil, nil)
+		assert.NoError(t, err, "opens existing files")
+		reOccupied, reDeleted, errCounts := scFiles.Counts()
+		reOvflOccupied, reOvflDeleted, errOvflCounts := scFiles.OverflowCounts()
+		mapOccupied, mapDeleted, errMap := scFiles.countMapRecords()
+		countedOvflOccupied, countedOvflDeleted, errOvfl := scFiles.countOverflowRecords()
+		value, errValue := scFiles.GetSystemValue(255)
+
+		// Check
+		assert.NoError(t, errCounts, "gets counts of reopened files")
+		assert.NoError(t, errOvflCounts, "gets overflow counts of reopened files")
+		assert.NoError(t, errMap, "counts map file records")
+		assert.NoError(t, errOvfl, "counts overflow file records")
+		assert.NoError(t, errValue, "gets saved counts from map file header")
+		assert.Equal(t, int64(70), occupied, "occupied records")
+		assert.Equal(t, int64(30), deleted+freed, "deleted records, where deleted overflow records are freed")
+		assert.Greater(t, ovflOccupied, int64(0), "some record(s) is in overflow")
+		assert.Equal(t, int64(0), ovflDeleted, "deleted overflow records are freed")
+		assert.Equal(t, occupied, reOccupied, "occupied records of reopened files")
+		assert.Equal(t, deleted, reDeleted, "deleted records of reopened files")
+		assert.Equal(t, ovflOccupied, reOvflOccupied, "occupied overflow records of reopened files")
+		assert.Equal(t, ovflDeleted, reOvflDeleted, "deleted overflow records of reopened files")
+		assert.Equal(t, occupied, mapOccupied+countedOvflOccupied, "occupied records agree with full count")
+		assert.Equal(t, deleted, mapDeleted+countedOvflDeleted, "deleted records agree with full count")
+		assert.Equal(t, countedOvflOccupied, ovflOccupied, "occupied overflow records agree with full count")
+		assert.Equal(t, byte(0), value[0], "counts are saved as not clean while files are open")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("counts records again when files were not closed properly", func(t *testing.T) {
+		// Prepare
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		setRecords(t, scFiles)
+
+		_, deleted, err := scFiles.Counts()
+		assert.NoError(t, err, "gets counts")
+
+		// Simulate a crash by closing the files without saving the counts
+		_ = scFiles.ovflFile.Close()
+		_ = scFiles.mapFile.Close()
+
+		// Execute
+		scFiles, err = NewSCFilesFromExistingFiles(crtConf.Name, nil, nil)
+		assert.NoError(t, err, "opens existing files")
+		occupied, reDeleted, errCounts := scFiles.Counts()
+
+		// Check
+		assert.NoError(t, errCounts, "gets counts of reopened files")
+		assert.Equal(t, int64(70), occupied, "occupied records are counted again")
+		assert.Equal(t, deleted, reDeleted, "deleted records are counted again")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+func TestSCFiles_FreeList(t *testing.T) {
+	t.Run("reuses deleted overflow records in other overflow chains", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 10,
+			RecordsPerBucket:      1,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		keysFor := func(bucketNo int64, n int) (records []model.Record) {
+			for len(records) < n {
+				key := make([]byte, 16)
+				rand.Read(key)
+				if scFiles.bucketNoOf(key) == bucketNo {
+					records = append(records, model.Record{Key: key, Value: make([]byte, 10)})
+				}
+			}
+			return
+		}
+
+		first := keysFor(0, 20)
+		for i := range first {
+			err = scFiles.Set(first[i])
+			assert.NoErrorf(t, err, "sets record #%d to bucket 0", i)
+		}
+		stat, err := scFiles.ovflFile.Stat()
+		assert.NoError(t, err, "gets overflow file size")
+
+		// Execute
+		for i := range first {
+			record, err := scFiles.Get(model.Record{Key: first[i].Key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			err = scFiles.Delete(record)
+			assert.NoErrorf(t, err, "deletes record #%d", i)
+		}
+
+		scFiles.CloseFiles()
+		scFiles, err = NewSCFilesFromExistingFiles(crtConf.Name, nil, nil)
+		assert.NoError(t, err, "opens existing files")
+
+		second := keysFor(1, 10)
+		for i := range second {
+			err = scFiles.Set(second[i])
+			assert.NoErrorf(t, err, "sets record #%d to bucket 1", i)
+		}
+		third := keysFor(2, 10)
+		err = scFiles.SetBatch(third)
+		assert.NoError(t, err, "sets batch of records to bucket 2")
+
+		// Check
+		statAfter, err := scFiles.ovflFile.Stat()
+		assert.NoError(t, err, "gets overflow file size after new records")
+		assert.Equal(t, stat.Size(), statAfter.Size(), "overflow file doesn't grow")
+		for i, record := range append(second, third...) {
+			_, err = scFiles.Get(model.Record{Key: record.Key})
+			assert.NoErrorf(t, err, "gets new record #%d", i)
+		}
+		for i := range first {
+			_, err = scFiles.Get(model.Record{Key: first[i].Key})
+			assert.ErrorIsf(t, err, crt.NoRecordFound{}, "deleted record #%d is gone", i)
+		}
+		leaked, err := scFiles.ScavengeOverflow()
+		assert.NoError(t, err, "scavenges overflow")
+		assert.Equal(t, int64(0), leaked, "all overflow records are either in a chain or free")
+		occupied, deleted, err := scFiles.Counts()
+		assert.NoError(t, err, "gets counts")
+		assert.Equal(t, int64(20), occupied, "occupied records")
+		assert.Equal(t, int64(1), deleted, "only the deleted record in the map file is left as deleted")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
+
+func TestSCFiles_RecordsPerBucket(t *testing.T) {
+	t.Run("keeps chains within records per bucket in map file", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 10,
+			RecordsPerBucket:      4,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		// Find five keys that collide in the same bucket
+		var keys [][]byte
+		var bucketNo int64
+		homeBucketNo := int64(-1)
+		for len(keys) < 5 {
+			key := make([]byte, 16)
+			rand.Read(key)
+			bucketNo, err = scFiles.getBucketNo(key)
+			assert.NoError(t, err, "gets bucket number")
+			if homeBucketNo == -1 {
+				homeBucketNo = bucketNo
+			}
+			if bucketNo == homeBucketNo {
+				keys = append(keys, key)
+			}
+		}
+
+		// Execute
+		for i, key := range keys {
+			err = scFiles.Set(model.Record{Key: key, Value: make([]byte, 10)})
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+
+		// Check
+		var record model.Record
+		for i, key := range keys {
+			record, err = scFiles.Get(model.Record{Key: key})
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Equalf(t, i >= 4, record.IsOverflow, "record #%d in overflow only when bucket is full", i)
+		}
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
 func TestSCFiles_GetBucket(t *testing.T) {
 	t.Run("returns a bucket", func(t *testing.T) {
 		// Prepare
@@ -402,3 +732,518 @@ func TestSCFiles_GetBucket(t *testing.T) {
 		assert.True(t, os.IsNotExist(err), "overflow file removed")
 	})
 }
+
+func TestSCFiles_ScavengeOverflow(t *testing.T) {
+	t.Run("finds and marks unreachable overflow records", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 1,
+			RecordsPerBucket:      1,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		for i := 0; i < 3; i++ {
+			key := make([]byte, 16)
+			key[0] = byte(i)
+			err = scFiles.Set(model.Record{Key: key, Value: make([]byte, 10)})
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		// Simulate a crash between appending a record and linking it
+		leakedKey := make([]byte, 16)
+		leakedKey[0] = 99
+		leakedAddress, err := scFiles.newBucketOverflow(leakedKey, make([]byte, 10))
+		assert.NoError(t, err, "appends unlinked record")
+
+		// Execute
+		unreachable, errUnreachable := scFiles.UnreachableOverflow()
+		leaked, err := scFiles.ScavengeOverflow()
+
+		// Check
+		assert.NoError(t, errUnreachable, "finds unreachable records")
+		assert.Equal(t, []int64{leakedAddress}, unreachable, "unreachable record found without changes")
+		assert.NoError(t, err, "scavenges overflow")
+		assert.Equal(t, int64(1), leaked, "finds unreachable record")
+
+		leaked, err = scFiles.ScavengeOverflow()
+		assert.NoError(t, err, "scavenges overflow again")
+		assert.Equal(t, int64(0), leaked, "unreachable record is put in list of free records")
+
+		stat, err := scFiles.ovflFile.Stat()
+		assert.NoError(t, err, "gets overflow file size")
+		err = scFiles.Set(model.Record{Key: leakedKey, Value: make([]byte, 10)})
+		assert.NoError(t, err, "sets record in overflow")
+		statAfter, err := scFiles.ovflFile.Stat()
+		assert.NoError(t, err, "gets overflow file size after set")
+		assert.Equal(t, stat.Size(), statAfter.Size(), "unreachable record is reused")
+
+		for i := 0; i < 3; i++ {
+			key := make([]byte, 16)
+			key[0] = byte(i)
+			_, err = scFiles.Get(model.Record{Key: key})
+			assert.NoErrorf(t, err, "reachable record #%d is untouched", i)
+		}
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSCFiles_CompactOverflow(t *testing.T) {
+	t.Run("drops records not in use and relinks moved records", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 10,
+			RecordsPerBucket:      1,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		var records []model.Record
+		for bucketNo := int64(0); bucketNo < 3; bucketNo++ {
+			for n := 0; n < 20; {
+				key := make([]byte, 16)
+				rand.Read(key)
+				if scFiles.bucketNoOf(key) == bucketNo {
+					value := make([]byte, 10)
+					rand.Read(value)
+					records = append(records, model.Record{Key: key, Value: value})
+					n++
+				}
+			}
+		}
+		for i := range records {
+			err = scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		// Per bucket, ten overflow records are freed and three are left deleted in their overflow chain
+		deleted := make(map[int]bool)
+		for i := range records {
+			if i%20 == 0 || i%20 > 13 {
+				continue
+			}
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			assert.NoErrorf(t, err, "gets record #%d to delete", i)
+			if i%20 <= 10 {
+				err = scFiles.Delete(record)
+			} else {
+				err = scFiles.markDeleted(record)
+			}
+			assert.NoErrorf(t, err, "deletes record #%d", i)
+			deleted[i] = true
+		}
+
+		// Simulate a crash between taking a free record and linking it
+		_, err = scFiles.newBucketOverflow(make([]byte, 16), make([]byte, 10))
+		assert.NoError(t, err, "takes unlinked record")
+
+		occupied, _, err := scFiles.Counts()
+		assert.NoError(t, err, "gets counts before compaction")
+
+		// Execute
+		reclaimed, err := scFiles.CompactOverflow()
+
+		// Check
+		assert.NoError(t, err, "compacts overflow")
+		assert.Equal(t, int64(39), reclaimed, "reclaims all overflow records but the occupied ones")
+
+		size, err := storage.GetFileSize(scFiles.ovflFile)
+		assert.NoError(t, err, "gets overflow file size")
+		assert.Equal(t, ovflFileHeaderLength+18*(overflowAddressLength+1+16+10), size, "overflow file is truncated")
+
+		for i := range records {
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			if deleted[i] {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "deleted record #%d is not found", i)
+				continue
+			}
+			assert.NoErrorf(t, err, "gets record #%d", i)
+			assert.Equalf(t, records[i].Value, record.Value, "value of record #%d", i)
+		}
+
+		occupiedAfter, deletedAfter, err := scFiles.OverflowCounts()
+		assert.NoError(t, err, "gets overflow counts")
+		assert.Equal(t, int64(18), occupiedAfter, "occupied overflow records")
+		assert.Equal(t, int64(0), deletedAfter, "no deleted overflow records")
+		total, _, err := scFiles.Counts()
+		assert.NoError(t, err, "gets counts")
+		assert.Equal(t, occupied, total, "occupied records are unchanged")
+
+		leaked, err := scFiles.ScavengeOverflow()
+		assert.NoError(t, err, "scavenges overflow")
+		assert.Equal(t, int64(0), leaked, "no unreachable records are left")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSCFiles_Hybrid(t *testing.T) {
+	t.Run("probes buckets before using overflow", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             2,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.Hybrid,
+			HashAlgorithm:                nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		records := make([]model.Record, 1000)
+		for i := 0; i < 1000; i++ {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+
+		// Execute
+		for i := 0; i < 1000; i++ {
+			err = scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+		for i := 0; i < 1000; i += 3 {
+			err = scFiles.Delete(mustGet(t, scFiles, records[i].Key))
+			assert.NoErrorf(t, err, "deletes record #%d from file", i)
+		}
+		for i := 0; i < 1000; i += 6 {
+			err = scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d to file again", i)
+		}
+
+		// Check
+		var record model.Record
+		var hadProbed, hadOverflow bool
+		bucketLength := bucketHeaderLength + (1+crtConf.KeyLength+crtConf.ValueLength)*crtConf.RecordsPerBucket
+		for i := 0; i < 1000; i++ {
+			record, err = scFiles.Get(model.Record{Key: records[i].Key})
+			if i%3 == 0 && i%6 != 0 {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "record #%d deleted", i)
+				continue
+			}
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Truef(t, utils.IsEqual(records[i].Value, record.Value), "value of record #%d is correct", i)
+			if record.IsOverflow {
+				hadOverflow = true
+			} else if (record.RecordAddress-storage.MapFileHeaderLength)/bucketLength != scFiles.hashAlgorithm.HashFunc1(records[i].Key) {
+				hadProbed = true
+			}
+		}
+		assert.True(t, hadProbed, "some record(s) is in a probed bucket")
+		assert.True(t, hadOverflow, "some record(s) is in overflow")
+
+		var count int
+		for i := int64(0); i < scFiles.numberOfBucketsAvailable; i++ {
+			bucket, ovflIter, err := scFiles.GetBucket(i)
+			assert.NoError(t, err, "gets bucket")
+			for _, r := range bucket.Records {
+				if r.State == model.RecordOccupied {
+					count++
+				}
+			}
+			for ovflIter.HasNext() {
+				record, err = ovflIter.Next()
+				assert.NoError(t, err, "gets overflow record")
+				if record.State == model.RecordOccupied {
+					count++
+				}
+			}
+		}
+		assert.Equal(t, 1000-334+167, count, "no duplicate records")
+
+		scFiles.CloseFiles()
+		scFiles, err = NewSCFilesFromExistingFiles("test", nil, nil)
+		assert.NoError(t, err, "opens existing files")
+		assert.Equal(t, crt.Hybrid, scFiles.GetStorageParameters().CollisionResolutionTechnique, "hybrid technique preserved")
+		assert.Equal(t, hybridProbeLimit, scFiles.probeLimit, "probe limit restored")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSCFiles_LinearHashing(t *testing.T) {
+	crtConf := model.CRTConf{
+		Name:                         "test",
+		NumberOfBucketsNeeded:        4,
+		RecordsPerBucket:             2,
+		KeyLength:                    16,
+		ValueLength:                  10,
+		CollisionResolutionTechnique: crt.LinearHashing,
+		HashAlgorithm:                nil,
+	}
+
+	t.Run("grows the map file by splitting buckets", func(t *testing.T) {
+		// Prepare
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		assert.Equal(t, int64(4), scFiles.numberOfBucketsAvailable, "starts with buckets needed")
+
+		records := make([]model.Record, 1000)
+		for i := range records {
+			records[i].Key = make([]byte, 16)
+			rand.Read(records[i].Key)
+			records[i].Value = make([]byte, 10)
+			rand.Read(records[i].Value)
+		}
+
+		// Execute
+		for i := range records {
+			err = scFiles.Set(records[i])
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+		for i := 0; i < 1000; i += 2 {
+			err = scFiles.Delete(mustGet(t, scFiles, records[i].Key))
+			assert.NoErrorf(t, err, "deletes record #%d from file", i)
+		}
+
+		// Check
+		assert.Greater(t, scFiles.numberOfBucketsAvailable, int64(250), "map file has grown")
+		stat, err := os.Stat(scFiles.mapFileName)
+		assert.NoError(t, err, "map file exists")
+		assert.Equal(t, scFiles.mapFileSize, stat.Size(), "map file size follows number of buckets")
+
+		var count int
+		for i := int64(0); i < scFiles.numberOfBucketsAvailable; i++ {
+			bucket, ovflIter, err := scFiles.GetBucket(i)
+			assert.NoError(t, err, "gets bucket")
+			for _, r := range bucket.Records {
+				if r.State == model.RecordOccupied {
+					count++
+				}
+			}
+			for ovflIter.HasNext() {
+				r, err := ovflIter.Next()
+				assert.NoError(t, err, "gets overflow record")
+				if r.State == model.RecordOccupied {
+					count++
+				}
+			}
+		}
+		assert.Equal(t, 500, count, "no duplicate records")
+
+		scFiles.CloseFiles()
+		scFiles, err = NewSCFilesFromExistingFiles("test", nil, nil)
+		assert.NoError(t, err, "opens existing files")
+		assert.Equal(t, crt.LinearHashing, scFiles.GetStorageParameters().CollisionResolutionTechnique, "linear hashing technique preserved")
+
+		for i := range records {
+			record, err := scFiles.Get(model.Record{Key: records[i].Key})
+			if i%2 == 0 {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "record #%d deleted", i)
+				continue
+			}
+			assert.NoErrorf(t, err, "gets record #%d from file", i)
+			assert.Truef(t, utils.IsEqual(records[i].Value, record.Value), "value of record #%d is correct", i)
+		}
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("discards an interrupted split when opening files", func(t *testing.T) {
+		// Prepare
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		mapFileSize := scFiles.mapFileSize
+		scFiles.CloseFiles()
+
+		file, err := os.OpenFile(scFiles.mapFileName, os.O_RDWR, 0644)
+		assert.NoError(t, err, "opens map file")
+		err = file.Truncate(mapFileSize + 100)
+		assert.NoError(t, err, "extends map file")
+		err = file.Close()
+		assert.NoError(t, err, "closes map file")
+
+		// Execute
+		scFiles, err = NewSCFilesFromExistingFiles("test", nil, nil)
+
+		// Check
+		assert.NoError(t, err, "opens existing files")
+		stat, err := os.Stat(scFiles.mapFileName)
+		assert.NoError(t, err, "map file exists")
+		assert.Equal(t, mapFileSize, stat.Size(), "partly written bucket discarded")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("shrinks back to initial size when cleared", func(t *testing.T) {
+		// Prepare
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+		mapFileSize := scFiles.mapFileSize
+		for i := 0; i < 100; i++ {
+			record := model.Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+			rand.Read(record.Key)
+			err = scFiles.Set(record)
+			assert.NoError(t, err, "sets record to file")
+		}
+
+		// Execute
+		err = scFiles.Clear()
+
+		// Check
+		assert.NoError(t, err, "clears files")
+		assert.Equal(t, int64(4), scFiles.numberOfBucketsAvailable, "initial number of buckets")
+		stat, err := os.Stat(scFiles.mapFileName)
+		assert.NoError(t, err, "map file exists")
+		assert.Equal(t, mapFileSize, stat.Size(), "initial map file size")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+// mustGet - Gets a record and fails the test if not found
+func mustGet(t *testing.T, scFiles *SCFiles, key []byte) (record model.Record) {
+	record, err := scFiles.Get(model.Record{Key: key})
+	assert.NoError(t, err, "gets record")
+
+	return
+}
+
+func TestSCFiles_SetProgress(t *testing.T) {
+	t.Run("reports buckets read", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                  "test",
+			NumberOfBucketsNeeded: 10,
+			RecordsPerBucket:      2,
+			KeyLength:             16,
+			ValueLength:           10,
+			HashAlgorithm:         nil,
+		}
+
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new SCFiles instance")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		err = scFiles.Set(model.Record{Key: key, Value: make([]byte, 10)})
+		assert.NoError(t, err, "sets record to file")
+
+		var read []int64
+		scFiles.SetProgress(func(bucketNo int64) { read = append(read, bucketNo) })
+
+		// Execute
+		_, err = scFiles.Get(model.Record{Key: key})
+		assert.NoError(t, err, "gets record from file")
+		scFiles.SetProgress(nil)
+		_, err = scFiles.Get(model.Record{Key: key})
+		assert.NoError(t, err, "gets record from file without progress")
+
+		// Check
+		assert.Equal(t, []int64{scFiles.HomeBucket(key)}, read, "home bucket read once")
+
+		// Clean up
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSCFiles_GetOrSet(t *testing.T) {
+	t.Run("sets an absent record and returns an existing record", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.SeparateChaining,
+			HashAlgorithm:                nil,
+		}
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		record := model.Record{Key: make([]byte, 16), Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+
+		// Execute
+		_, loadedFirst, errFirst := scFiles.GetOrSet(record)
+		existing, loadedSecond, errSecond := scFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.False(t, loadedFirst, "absent record is not loaded")
+		assert.NoError(t, errSecond, "gets existing record")
+		assert.True(t, loadedSecond, "existing record is loaded")
+		assert.True(t, utils.IsEqual(record.Value, existing.Value), "existing value is returned")
+
+		got, err := scFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(record.Value, got.Value), "existing value is kept")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("treats an expired record as absent", func(t *testing.T) {
+		// Prepare
+		crtConf := model.CRTConf{
+			Name:                         "test",
+			NumberOfBucketsNeeded:        10,
+			RecordsPerBucket:             1,
+			KeyLength:                    16,
+			ValueLength:                  10,
+			CollisionResolutionTechnique: crt.SeparateChaining,
+			HashAlgorithm:                nil,
+		}
+		scFiles, err := NewSCFiles(crtConf)
+		assert.NoError(t, err, "create new instance")
+		scFiles.SetExpiryCheck(func(value []byte) bool { return value[0] == 0xff })
+		record := model.Record{Key: make([]byte, 16), Value: []byte{0xff, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+		rand.Read(record.Key)
+		err = scFiles.Set(record)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		other := model.Record{Key: record.Key, Value: make([]byte, 10)}
+		_, loaded, err := scFiles.GetOrSet(other)
+
+		// Check
+		assert.NoError(t, err, "sets record in place of expired record")
+		assert.False(t, loaded, "expired record is not loaded")
+
+		got, err := scFiles.Get(model.Record{Key: record.Key})
+		assert.NoError(t, err, "gets record from file")
+		assert.True(t, utils.IsEqual(other.Value, got.Value), "new value is set")
+
+		// Clean up
+		scFiles.CloseFiles()
+		err = scFiles.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
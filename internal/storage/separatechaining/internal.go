@@ -5,16 +5,18 @@ import (
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
 	"github.com/gostonefire/filehashmap/internal/storage"
-	"io"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/gostonefire/filehashmap/vfs"
 	"os"
 )
 
 // openHashMapFile - Opens the hash map file and does some rudimentary checks of its validity and
 // returns a Header struct read from file
 func (S *SCFiles) openHashMapFile() (header storage.Header, err error) {
-	if stat, ok := os.Stat(S.mapFileName); ok == nil {
-		S.mapFile, err = os.OpenFile(S.mapFileName, os.O_RDWR, 0644)
+	if stat, ok := S.fileSystem.Stat(S.mapFileName); ok == nil {
+		S.mapFile, err = S.fileSystem.OpenFile(S.mapFileName, os.O_RDWR, 0644)
 		if err != nil {
 			err = fmt.Errorf("unable to open existing hash map file: %s", err)
 			return
@@ -28,7 +30,17 @@ func (S *SCFiles) openHashMapFile() (header storage.Header, err error) {
 			return
 		}
 
-		if stat.Size() != header.FileSize {
+		// A Linear Hashing split that was interrupted before the header was updated leaves a partly written bucket at
+		// the end of the map file, which is discarded
+		if int(header.CollisionResolutionTechnique) == crt.LinearHashing && stat.Size() > header.FileSize {
+			err = S.mapFile.Truncate(header.FileSize)
+			if err != nil {
+				_ = S.mapFile.Close()
+				S.mapFile = nil
+				err = fmt.Errorf("unable to discard interrupted bucket split from hash map file: %s", err)
+				return
+			}
+		} else if stat.Size() != header.FileSize {
 			_ = S.mapFile.Close()
 			S.mapFile = nil
 			err = fmt.Errorf("actual file size doesn't conform with header indicated file size")
@@ -45,8 +57,8 @@ func (S *SCFiles) openHashMapFile() (header storage.Header, err error) {
 
 // openOverflowFile - Opens the overflow file and does som rudimentary checks of its validity
 func (S *SCFiles) openOverflowFile() (err error) {
-	if stat, ok := os.Stat(S.ovflFileName); ok == nil {
-		S.ovflFile, err = os.OpenFile(S.ovflFileName, os.O_RDWR, 0644)
+	if stat, ok := S.fileSystem.Stat(S.ovflFileName); ok == nil {
+		S.ovflFile, err = S.fileSystem.OpenFile(S.ovflFileName, os.O_RDWR, 0644)
 		if err != nil {
 			err = fmt.Errorf("unable to open existing overflow file: %s", err)
 			return
@@ -58,6 +70,16 @@ func (S *SCFiles) openOverflowFile() (err error) {
 			err = fmt.Errorf("actual file size is smaller than minimum overflow file size")
 			return
 		}
+
+		buf := make([]byte, overflowAddressLength)
+		_, err = S.ovflFile.ReadAt(buf, freeListHeadOffset)
+		if err != nil {
+			_ = S.ovflFile.Close()
+			S.ovflFile = nil
+			err = fmt.Errorf("unable to read free list head from overflow file: %s", err)
+			return
+		}
+		S.freeListHead = int64(binary.LittleEndian.Uint64(buf))
 	} else {
 		err = fmt.Errorf("overflow file not found")
 		return
@@ -70,7 +92,7 @@ func (S *SCFiles) openOverflowFile() (err error) {
 // If it already exists it will first be truncated to zero length and then to expected length,
 // hence deleting all existing data.
 func (S *SCFiles) createNewHashMapFile(header storage.Header) (err error) {
-	S.mapFile, err = os.OpenFile(S.mapFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	S.mapFile, err = S.fileSystem.OpenFile(S.mapFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
 	if err != nil {
 		err = fmt.Errorf("error while open/create new map file: %s", err)
 		return
@@ -95,7 +117,7 @@ func (S *SCFiles) createNewHashMapFile(header storage.Header) (err error) {
 // createNewOverflowFile - Creates a new overflow file. If it already exists it will first be truncated to zero length
 // and then to expected length, hence deleting all existing data.
 func (S *SCFiles) createNewOverflowFile() (err error) {
-	S.ovflFile, err = os.OpenFile(S.ovflFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	S.ovflFile, err = S.fileSystem.OpenFile(S.ovflFileName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
 	if err != nil {
 		err = fmt.Errorf("error while open/create new overflow file: %s", err)
 		return
@@ -116,13 +138,13 @@ func (S *SCFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err err
 	bucketLength := bucketHeaderLength + trueRecordLength*S.recordsPerBucket
 	bucketAddress := storage.MapFileHeaderLength + bucketNo*bucketLength
 
-	_, err = S.mapFile.Seek(bucketAddress, io.SeekStart)
-	if err != nil {
-		return
+	buf := S.bucketBuffers.Get(bucketLength)
+	defer S.bucketBuffers.Put(buf)
+	if S.progress != nil {
+		S.progress(bucketNo)
 	}
 
-	buf := make([]byte, bucketLength)
-	_, err = S.mapFile.Read(buf)
+	_, err = S.mapFile.ReadAt(buf, bucketAddress)
 	if err != nil {
 		return
 	}
@@ -135,35 +157,22 @@ func (S *SCFiles) getBucketRecords(bucketNo int64) (bucket model.Bucket, err err
 // setBucketRecord - Sets a bucket record in the hash map file
 func (S *SCFiles) setBucketRecord(record model.Record) (err error) {
 	buf := make([]byte, 1, 1+S.keyLength+S.valueLength) // First byte is record state
-	buf[0] = record.State
+	buf[0] = model.ToStateByte(record.State, record.AccessCount)
 
 	buf = append(buf, record.Key...)
 	buf = append(buf, record.Value...)
 
-	_, err = S.mapFile.Seek(record.RecordAddress, io.SeekStart)
-	if err != nil {
-		return
-	}
-
-	_, err = S.mapFile.Write(buf)
+	_, err = S.mapFile.WriteAt(buf, record.RecordAddress)
 
 	return
 }
 
 // setBucketOverflowAddress - Sets the overflow address for a bucket identified by its address in file
 func (S *SCFiles) setBucketOverflowAddress(bucketAddress, overflowAddress int64) (err error) {
-	_, err = S.mapFile.Seek(bucketAddress+bucketOverflowAddressOffset, io.SeekStart)
-	if err != nil {
-		return
-	}
-
 	buf := make([]byte, 8)
 	binary.LittleEndian.PutUint64(buf, uint64(overflowAddress))
 
-	_, err = S.mapFile.Write(buf)
-	if err != nil {
-		return
-	}
+	_, err = S.mapFile.WriteAt(buf, bucketAddress+bucketOverflowAddressOffset)
 
 	return
 }
@@ -171,13 +180,8 @@ func (S *SCFiles) setBucketOverflowAddress(bucketAddress, overflowAddress int64)
 // getOverflowRecord - Gets a model.Record from the overflow file
 func (S *SCFiles) getOverflowRecord(recordAddress int64) (record model.Record, err error) {
 	trueRecordLength := 1 + S.keyLength + S.valueLength // First byte is record state
-	_, err = S.ovflFile.Seek(recordAddress, io.SeekStart)
-	if err != nil {
-		return
-	}
-
 	buf := make([]byte, trueRecordLength+overflowAddressLength)
-	_, err = S.ovflFile.Read(buf)
+	_, err = S.ovflFile.ReadAt(buf, recordAddress)
 	if err != nil {
 		return
 	}
@@ -190,12 +194,7 @@ func (S *SCFiles) getOverflowRecord(recordAddress int64) (record model.Record, e
 func (S *SCFiles) setOverflowRecord(record model.Record) (err error) {
 	buf := recordToOverflowBytes(record, S.keyLength, S.valueLength)
 
-	_, err = S.ovflFile.Seek(record.RecordAddress, io.SeekStart)
-	if err != nil {
-		return
-	}
-
-	_, err = S.ovflFile.Write(buf)
+	_, err = S.ovflFile.WriteAt(buf, record.RecordAddress)
 
 	return
 }
@@ -208,47 +207,192 @@ func (S *SCFiles) appendOverflowRecord(linkingRecord model.Record, key, value []
 		return
 	}
 
+	err = S.setOverflowNext(linkingRecord.RecordAddress, overflowAddress)
+
+	return
+}
+
+// setOverflowNext - Sets the address of the next record in an overflow chain, or in the list of free overflow records,
+// of the overflow record at recordAddress
+func (S *SCFiles) setOverflowNext(recordAddress, nextAddress int64) (err error) {
 	buf := make([]byte, overflowAddressLength)
-	binary.LittleEndian.PutUint64(buf, uint64(overflowAddress))
+	binary.LittleEndian.PutUint64(buf, uint64(nextAddress))
+
+	_, err = S.ovflFile.WriteAt(buf, recordAddress)
+
+	return
+}
+
+// setFreeListHead - Sets the address of the first record in the list of free overflow records in the overflow file
+// header, zero for an empty list
+func (S *SCFiles) setFreeListHead(address int64) (err error) {
+	buf := make([]byte, overflowAddressLength)
+	binary.LittleEndian.PutUint64(buf, uint64(address))
 
-	_, err = S.ovflFile.Seek(linkingRecord.RecordAddress, io.SeekStart)
+	_, err = S.ovflFile.WriteAt(buf, freeListHeadOffset)
 	if err != nil {
 		return
 	}
 
-	_, err = S.ovflFile.Write(buf)
+	S.freeListHead = address
 
 	return
 }
 
-// getBucketNo - Returns which bucket number that the given key results in
-func (S *SCFiles) getBucketNo(key []byte) (bucketNo int64, err error) {
-	bucketNo = S.hashAlgorithm.HashFunc1(key)
-	if bucketNo < 0 || bucketNo >= S.numberOfBucketsAvailable {
-		err = fmt.Errorf("recieved bucket number from bucket algorithm is outside permitted range")
+// allocateOverflowRecords - Returns addresses for n new overflow records, taking records from the list of free overflow
+// records first and appending to the end of the overflow file for the rest. Records taken from the list are removed
+// from it in the overflow file header, which is synced, before the addresses are returned. A crash before the records
+// are linked into an overflow chain then leaves them unreachable (see ScavengeOverflow) rather than both free and in use.
+func (S *SCFiles) allocateOverflowRecords(n int) (addresses []int64, err error) {
+	end, err := storage.GetFileSize(S.ovflFile)
+	if err != nil {
 		return
 	}
 
+	var record model.Record
+	head := S.freeListHead
+	for len(addresses) < n && head != 0 {
+		record, err = S.getOverflowRecord(head)
+		if err != nil {
+			err = fmt.Errorf("error while reading free overflow record: %s", err)
+			return
+		}
+		addresses = append(addresses, head)
+		head = record.NextOverflow
+	}
+
+	if head != S.freeListHead {
+		err = S.setFreeListHead(head)
+		if err != nil {
+			err = fmt.Errorf("error while writing free list head to overflow file header: %s", err)
+			return
+		}
+		err = S.ovflFile.Sync()
+		if err != nil {
+			return
+		}
+	}
+
+	overflowRecordLength := overflowAddressLength + 1 + S.keyLength + S.valueLength // First byte after address is record state
+	for len(addresses) < n {
+		addresses = append(addresses, end)
+		end += overflowRecordLength
+	}
+
 	return
 }
 
-// newBucketOverflow - Adds a new overflow record to a file.
-func (S *SCFiles) newBucketOverflow(key, value []byte) (overflowAddress int64, err error) {
-	overflowAddress, err = S.ovflFile.Seek(0, io.SeekEnd)
+// freeOverflowRecord - Marks an overflow record as deleted, unlinks it from the overflow chain of its home bucket and
+// puts it first in the list of free overflow records. The unlinking is synced before the record is put in the list, so
+// a crash in between leaves the record unreachable (see ScavengeOverflow) rather than both in a chain and free.
+//   - record is the overflow record to free, and it must contain Key and RecordAddress
+//
+// It returns:
+//   - freed is false if the record was not found in the overflow chain of its home bucket, in which case nothing was changed
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) freeOverflowRecord(record model.Record) (freed bool, err error) {
+	homeBucketNo, err := S.getBucketNo(record.Key)
+	if err != nil {
+		return
+	}
+	bucket, err := S.getBucketRecords(homeBucketNo)
 	if err != nil {
+		err = fmt.Errorf("error while reading bucket from file: %s", err)
 		return
 	}
 
-	buf := make([]byte, 1+overflowAddressLength, S.keyLength+S.valueLength+overflowAddressLength) // First byte is record state
-	buf[overflowAddressLength] = model.RecordOccupied
-	buf = append(buf, key...)
-	buf = append(buf, value...)
+	// Find the record together with the record linking to it, if any
+	var current, linking model.Record
+	var hasLinking bool
+	for address := bucket.OverflowAddress; address != 0; {
+		current, err = S.getOverflowRecord(address)
+		if err != nil {
+			err = fmt.Errorf("error while reading overflow record: %s", err)
+			return
+		}
+		if address == record.RecordAddress {
+			freed = true
+			break
+		}
+		linking, hasLinking = current, true
+		address = current.NextOverflow
+	}
+	if !freed {
+		return
+	}
 
-	_, err = S.ovflFile.Write(buf)
+	err = S.markDeleted(current)
 	if err != nil {
 		return
 	}
 
+	if hasLinking {
+		err = S.setOverflowNext(linking.RecordAddress, current.NextOverflow)
+		if err == nil {
+			err = S.ovflFile.Sync()
+		}
+	} else {
+		err = S.setBucketOverflowAddress(bucket.BucketAddress, current.NextOverflow)
+		if err == nil {
+			err = S.mapFile.Sync()
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("error while unlinking record from overflow chain: %s", err)
+		return
+	}
+	S.ovflCounters.Transition(model.RecordDeleted, model.RecordEmpty)
+
+	err = S.pushFreeOverflowRecord(current.RecordAddress)
+
+	return
+}
+
+// pushFreeOverflowRecord - Puts the overflow record at recordAddress first in the list of free overflow records, the
+// record must not be part of any overflow chain
+func (S *SCFiles) pushFreeOverflowRecord(recordAddress int64) (err error) {
+	err = S.setOverflowNext(recordAddress, S.freeListHead)
+	if err != nil {
+		err = fmt.Errorf("error while linking record into free list: %s", err)
+		return
+	}
+
+	err = S.setFreeListHead(recordAddress)
+	if err != nil {
+		err = fmt.Errorf("error while writing free list head to overflow file header: %s", err)
+	}
+
+	return
+}
+
+// getBucketNo - Returns which bucket number that the given key results in
+func (S *SCFiles) getBucketNo(key []byte) (bucketNo int64, err error) {
+	bucketNo = S.bucketNoOf(key)
+	if bucketNo < 0 || bucketNo >= S.numberOfBucketsAvailable {
+		err = fmt.Errorf("recieved bucket number from bucket algorithm is outside permitted range")
+		return
+	}
+
+	return
+}
+
+// bucketNoOf - Returns the bucket number for key without any range check, which for Linear Hashing depends on the
+// current number of buckets
+func (S *SCFiles) bucketNoOf(key []byte) int64 {
+	if S.crtType == crt.LinearHashing {
+		return S.linearBucketNo(S.hashAlgorithm.HashFunc1(key))
+	}
+
+	return S.hashAlgorithm.HashFunc1(key)
+}
+
+// newBucketOverflow - Adds a new overflow record to a file, reusing a free overflow record if there is one.
+// The overflow file is synced before returning, acting as a barrier so that the new record is durable before the
+// caller links to it from a bucket or a previous overflow record. A crash before the link is written can then only
+// leave an unreachable record (see ScavengeOverflow), never a link to a record that was not written.
+func (S *SCFiles) newBucketOverflow(key, value []byte) (overflowAddress int64, err error) {
+	overflowAddress, err = S.appendOverflowChain([]model.Record{{Key: key, Value: value}})
+
 	return
 }
 
@@ -263,7 +407,500 @@ func (S *SCFiles) createHeader() (header storage.Header) {
 		RecordsPerBucket:             S.recordsPerBucket,
 		MaxBucketNo:                  S.maxBucketNo,
 		FileSize:                     S.mapFileSize,
-		CollisionResolutionTechnique: int64(crt.SeparateChaining),
+		CollisionResolutionTechnique: int64(S.crtType),
+		HashAlgorithmKind:            S.hashParameters.Kind,
+		HashSeed:                     S.hashParameters.Seed,
+	}
+
+	return
+}
+
+// getReachableOverflowAddresses - Walks all overflow chains starting from the buckets and returns the set of
+// overflow record addresses that can be reached
+func (S *SCFiles) getReachableOverflowAddresses() (reachable map[int64]bool, err error) {
+	var bucket model.Bucket
+	var record model.Record
+
+	reachable = make(map[int64]bool)
+	for i := int64(0); i < S.numberOfBucketsAvailable; i++ {
+		bucket, err = S.getBucketRecords(i)
+		if err != nil {
+			return
+		}
+
+		for address := bucket.OverflowAddress; address != 0; address = record.NextOverflow {
+			if reachable[address] {
+				err = fmt.Errorf("overflow chain for bucket %d loops at address %d", i, address)
+				return
+			}
+			reachable[address] = true
+
+			record, err = S.getOverflowRecord(address)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// addFreeOverflowAddresses - Walks the list of free overflow records and adds their addresses to addresses
+func (S *SCFiles) addFreeOverflowAddresses(addresses map[int64]bool) (err error) {
+	var record model.Record
+	for address := S.freeListHead; address != 0; address = record.NextOverflow {
+		if addresses[address] {
+			err = fmt.Errorf("list of free overflow records loops or links into an overflow chain at address %d", address)
+			return
+		}
+		addresses[address] = true
+
+		record, err = S.getOverflowRecord(address)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// overflowLink - An occupied record in an overflow chain as found by unlinkUnoccupiedOverflowRecords
+//   - address is the address of the record in the overflow file
+//   - linking is the index of the record linking to this record, or -1 if linked from the bucket
+//   - bucketAddress is the address in the map file of the bucket the overflow chain belongs to
+type overflowLink struct {
+	address       int64
+	linking       int
+	bucketAddress int64
+}
+
+// unlinkUnoccupiedOverflowRecords - Walks all overflow chains starting from the buckets, unlinks any record that is not
+// occupied from its chain and returns the remaining records in the order they were found, so that the record linking
+// to a record always comes before it. Unlinked records are left unreachable, they are neither synced nor put in the list
+// of free overflow records.
+func (S *SCFiles) unlinkUnoccupiedOverflowRecords() (links []overflowLink, err error) {
+	var bucket model.Bucket
+	var record model.Record
+
+	visited := make(map[int64]bool)
+	for i := int64(0); i < S.numberOfBucketsAvailable; i++ {
+		bucket, err = S.getBucketRecords(i)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		linking := -1
+		for address := bucket.OverflowAddress; address != 0; address = record.NextOverflow {
+			if visited[address] {
+				err = fmt.Errorf("overflow chain for bucket %d loops at address %d", i, address)
+				return
+			}
+			visited[address] = true
+
+			record, err = S.getOverflowRecord(address)
+			if err != nil {
+				err = fmt.Errorf("error while reading overflow record: %s", err)
+				return
+			}
+
+			if record.State == model.RecordOccupied {
+				links = append(links, overflowLink{address: address, linking: linking, bucketAddress: bucket.BucketAddress})
+				linking = len(links) - 1
+				continue
+			}
+
+			err = S.setOverflowLink(links, linking, bucket.BucketAddress, record.NextOverflow)
+			if err != nil {
+				err = fmt.Errorf("error while unlinking record from overflow chain: %s", err)
+				return
+			}
+			S.ovflCounters.Transition(record.State, model.RecordEmpty)
+		}
+	}
+
+	return
+}
+
+// setOverflowLink - Sets the link to address, either in the record at index linking of links or, if linking is -1, as
+// the overflow address of the bucket at bucketAddress
+func (S *SCFiles) setOverflowLink(links []overflowLink, linking int, bucketAddress, address int64) (err error) {
+	if linking < 0 {
+		err = S.setBucketOverflowAddress(bucketAddress, address)
+	} else {
+		err = S.setOverflowNext(links[linking].address, address)
+	}
+
+	return
+}
+
+// setBucketBatch - Sets a batch of records that all belong to the same bucket, keys must be unique within the batch
+func (S *SCFiles) setBucketBatch(bucketNo int64, records []model.Record) (err error) {
+	bucket, err := S.getBucketRecords(bucketNo)
+	if err != nil {
+		return
+	}
+
+	var chain []model.Record
+	var record model.Record
+	for address := bucket.OverflowAddress; address != 0; address = record.NextOverflow {
+		record, err = S.getOverflowRecord(address)
+		if err != nil {
+			return
+		}
+		chain = append(chain, record)
+	}
+
+	var bucketDirty bool
+	dirtyChain := make(map[int]bool)
+	var appends []model.Record
+	var transitions, ovflTransitions []uint8
+
+RECORDS:
+	for _, record = range records {
+		// Update an existing record with same key
+		for i, r := range bucket.Records {
+			if r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key) {
+				bucket.Records[i].Value = record.Value
+				bucketDirty = true
+				continue RECORDS
+			}
+		}
+		for i, r := range chain {
+			if r.State == model.RecordOccupied && utils.IsEqual(record.Key, r.Key) {
+				chain[i].Value = record.Value
+				dirtyChain[i] = true
+				continue RECORDS
+			}
+		}
+
+		// Use a free record in bucket or chain, where an expired record is as good as a deleted one
+		for i, r := range bucket.Records {
+			if r.State != model.RecordOccupied || S.hasExpired(r) {
+				transitions = append(transitions, r.State)
+				bucket.Records[i] = model.Record{State: model.RecordOccupied, RecordAddress: r.RecordAddress, Key: record.Key, Value: record.Value}
+				bucketDirty = true
+				continue RECORDS
+			}
+		}
+		for i, r := range chain {
+			if r.State != model.RecordOccupied || S.hasExpired(r) {
+				ovflTransitions = append(ovflTransitions, r.State)
+				chain[i] = model.Record{State: model.RecordOccupied, IsOverflow: true, RecordAddress: r.RecordAddress, NextOverflow: r.NextOverflow, Key: record.Key, Value: record.Value}
+				dirtyChain[i] = true
+				continue RECORDS
+			}
+		}
+
+		appends = append(appends, record)
+	}
+
+	// Append new overflow records already linked together, and sync them before linking them into the chain
+	if len(appends) > 0 {
+		var firstAddress int64
+		firstAddress, err = S.appendOverflowChain(appends)
+		if err != nil {
+			return
+		}
+
+		if len(chain) == 0 {
+			bucket.OverflowAddress = firstAddress
+			bucketDirty = true
+		} else {
+			last := len(chain) - 1
+			chain[last].NextOverflow = firstAddress
+			dirtyChain[last] = true
+		}
+	}
+
+	for i := range dirtyChain {
+		err = S.setOverflowRecord(chain[i])
+		if err != nil {
+			return
+		}
+	}
+
+	if bucketDirty {
+		_, err = S.mapFile.WriteAt(bucketToBytes(bucket, S.keyLength, S.valueLength), bucket.BucketAddress)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, previousState := range transitions {
+		S.counters.Transition(previousState, model.RecordOccupied)
+	}
+	for _, previousState := range ovflTransitions {
+		S.ovflCounters.Transition(previousState, model.RecordOccupied)
+	}
+	for range appends {
+		S.ovflCounters.Transition(model.RecordEmpty, model.RecordOccupied)
+	}
+
+	return
+}
+
+// appendOverflowChain - Appends records to the overflow file as a chain of records linked together, reusing free
+// overflow records before appending to the end of the file, and syncs the overflow file before returning so the chain
+// is durable before the caller links to it.
+//
+// It returns:
+//   - firstAddress is the address of the first record in the chain
+//   - err is a standard error, if something went wrong
+func (S *SCFiles) appendOverflowChain(records []model.Record) (firstAddress int64, err error) {
+	addresses, err := S.allocateOverflowRecords(len(records))
+	if err != nil {
+		return
+	}
+
+	// Write runs of adjacent records in one operation each
+	overflowRecordLength := overflowAddressLength + 1 + S.keyLength + S.valueLength // First byte after address is record state
+	buf := make([]byte, 0, overflowRecordLength*int64(len(records)))
+	for i, r := range records {
+		r.State = model.RecordOccupied
+		r.NextOverflow = 0
+		if i < len(records)-1 {
+			r.NextOverflow = addresses[i+1]
+		}
+		buf = append(buf, recordToOverflowBytes(r, S.keyLength, S.valueLength)...)
+
+		if i == len(records)-1 || addresses[i+1] != addresses[i]+overflowRecordLength {
+			_, err = S.ovflFile.WriteAt(buf, addresses[i]+overflowRecordLength-int64(len(buf)))
+			if err != nil {
+				return
+			}
+			buf = buf[:0]
+		}
+	}
+
+	err = S.ovflFile.Sync()
+	if err != nil {
+		return
+	}
+
+	if S.metrics != nil {
+		S.metrics.AddOverflowAppends(int64(len(records)))
+	}
+	firstAddress = addresses[0]
+
+	return
+}
+
+// getOccupiedRecords - Returns all occupied records in a home bucket and its probed buckets, including the overflow
+// chain of the home bucket, which together are all places a record with that home bucket can be found in
+func (S *SCFiles) getOccupiedRecords(bucketNo int64) (records []model.Record, err error) {
+	var bucket, homeBucket model.Bucket
+	for i := int64(0); i < S.probeLimit; i++ {
+		bucket, err = S.getBucketRecords(S.getProbeBucketNo(bucketNo, i))
+		if err != nil {
+			return
+		}
+		if i == 0 {
+			homeBucket = bucket
+		}
+
+		for _, record := range bucket.Records {
+			if record.State == model.RecordOccupied && !S.hasExpired(record) {
+				records = append(records, record)
+			}
+		}
+	}
+
+	ovflIter := S.getOverflowIterator(homeBucket)
+
+	var record model.Record
+	for ovflIter.HasNext() {
+		record, err = ovflIter.Next()
+		if err != nil {
+			return
+		}
+		if record.State == model.RecordOccupied && !S.hasExpired(record) {
+			records = append(records, record)
+		}
+	}
+
+	return
+}
+
+// getOverflowIterator - Returns an iterator over the overflow chain of a bucket
+func (S *SCFiles) getOverflowIterator(bucket model.Bucket) *overflow.Records {
+	getOvflFunc := func(recordAddress int64) (model.Record, error) { return S.getOverflowRecord(recordAddress) }
+
+	return overflow.NewRecords(getOvflFunc, bucket.OverflowAddress)
+}
+
+// getProbeBucketNo - Returns the bucket number to use in probe iteration i given the home bucket number
+func (S *SCFiles) getProbeBucketNo(homeBucketNo, iteration int64) int64 {
+	return (homeBucketNo + iteration) % S.numberOfBucketsAvailable
+}
+
+// getProbeLimit - Returns the number of buckets to probe before putting records in overflow, which is one (the home
+// bucket only) for Separate Chaining
+func getProbeLimit(crtType int, numberOfBuckets int64) (probeLimit int64) {
+	probeLimit = 1
+	if crtType == crt.Hybrid {
+		probeLimit = hybridProbeLimit
+		if probeLimit > numberOfBuckets {
+			probeLimit = numberOfBuckets
+		}
+	}
+
+	return
+}
+
+// overflowProbe - Counts an overflow record walked while looking for a key as a probe iteration, see SetMetrics, and
+// checks whether to stop walking, see SetInterrupt
+//
+// It returns:
+//   - err is the error returned by any interrupt function
+func (S *SCFiles) overflowProbe() (err error) {
+	if S.metrics != nil {
+		S.metrics.AddProbeIterations(1)
+	}
+	if S.interrupt != nil {
+		err = S.interrupt()
+	}
+
+	return
+}
+
+// hasExpired - Returns true if the record is occupied and has expired according to the expiry check (see SetExpiryCheck)
+func (S *SCFiles) hasExpired(record model.Record) bool {
+	return S.isExpired != nil && record.State == model.RecordOccupied && S.isExpired(record.Value)
+}
+
+// getUnexpired - Returns the record at index i in records, but if it has expired it is first marked as deleted, both
+// in file and in records
+func (S *SCFiles) getUnexpired(records []model.Record, i int) (record model.Record, err error) {
+	if S.hasExpired(records[i]) {
+		err = S.markDeleted(records[i])
+		if err != nil {
+			return
+		}
+		records[i].State = model.RecordDeleted
+		records[i].AccessCount = 0
+	}
+
+	record = records[i]
+
+	return
+}
+
+// countMapRecords - Counts the occupied and deleted records by reading all buckets in the map file
+func (S *SCFiles) countMapRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	for bucketNo := int64(0); bucketNo < S.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = S.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		for _, record := range bucket.Records {
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
+	return
+}
+
+// countOverflowRecords - Counts the occupied and deleted records in the overflow file by walking the overflow chains
+// of all buckets, so records that are not reachable from any bucket are not counted
+func (S *SCFiles) countOverflowRecords() (occupied, deleted int64, err error) {
+	var bucket model.Bucket
+	var record model.Record
+	for bucketNo := int64(0); bucketNo < S.numberOfBucketsAvailable; bucketNo++ {
+		bucket, err = S.getBucketRecords(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket from file: %s", err)
+			return
+		}
+
+		ovflIter := S.getOverflowIterator(bucket)
+		for ovflIter.HasNext() {
+			record, err = ovflIter.Next()
+			if err != nil {
+				err = fmt.Errorf("error while reading overflow record from file: %s", err)
+				return
+			}
+
+			switch record.State {
+			case model.RecordOccupied:
+				occupied++
+			case model.RecordDeleted:
+				deleted++
+			}
+		}
+	}
+
+	return
+}
+
+// countersOf - Returns the counters of the file holding record
+func (S *SCFiles) countersOf(record model.Record) *storage.Counters {
+	if record.IsOverflow {
+		return &S.ovflCounters
+	}
+
+	return &S.counters
+}
+
+// loadCounters - Loads the record counts from the headers of the map and overflow files, counting the records of a
+// file again if its counts were not saved when it was last closed. Unless the files are opened read-only the counts
+// are then saved as not clean, so that they are counted again if the files are not closed properly.
+func (S *SCFiles) loadCounters() (err error) {
+	for _, c := range []struct {
+		counters *storage.Counters
+		file     vfs.File
+		count    func() (int64, int64, error)
+	}{
+		{counters: &S.counters, file: S.mapFile, count: S.countMapRecords},
+		{counters: &S.ovflCounters, file: S.ovflFile, count: S.countOverflowRecords},
+	} {
+		var loaded bool
+		loaded, err = c.counters.Load(c.file)
+		if err != nil {
+			err = fmt.Errorf("error while reading record counts from file header: %s", err)
+			return
+		}
+		if !loaded {
+			_, _, err = c.counters.Counts(c.count)
+			if err != nil {
+				err = fmt.Errorf("error while counting records: %s", err)
+				return
+			}
+		}
+	}
+
+	if storage.IsReadOnly(S.mapFile) {
+		return
+	}
+
+	err = S.saveCounters(false)
+
+	return
+}
+
+// saveCounters - Saves the record counts in the headers of the map and overflow files, see storage.Counters.Save
+func (S *SCFiles) saveCounters(clean bool) (err error) {
+	if storage.IsReadOnly(S.mapFile) {
+		return
+	}
+
+	err = S.counters.Save(S.mapFile, clean)
+	if err != nil {
+		err = fmt.Errorf("error while writing record counts to map file header: %s", err)
+		return
+	}
+
+	err = S.ovflCounters.Save(S.ovflFile, clean)
+	if err != nil {
+		err = fmt.Errorf("error while writing record counts to overflow file header: %s", err)
 	}
 
 	return
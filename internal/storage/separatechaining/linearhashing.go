@@ -0,0 +1,160 @@
+package separatechaining
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+)
+
+// getTableSize - Returns the table size to give the hash algorithm, which is the number of buckets needed except for
+// Linear Hashing where the hash algorithm has to produce a full hash value
+func getTableSize(crtType int, numberOfBucketsNeeded int64) (tableSize int64) {
+	tableSize = numberOfBucketsNeeded
+	if crtType == crt.LinearHashing {
+		tableSize = linearHashingAddressSpace
+	}
+
+	return
+}
+
+// initialLinearBuckets - Returns the number of buckets a Linear Hashing map file starts with, which is the nearest
+// bigger exponent of 2 of the number of buckets needed
+func initialLinearBuckets(numberOfBucketsNeeded int64) int64 {
+	return utils.RoundUp2(numberOfBucketsNeeded)
+}
+
+// linearBucketNo - Returns the bucket number for a full hash value given the current number of buckets.
+// With p being the biggest exponent of 2 not bigger than the number of buckets, buckets below the number of buckets
+// minus p (the split pointer) have been split, and are addressed using one more bit of the hash value than the others.
+func (S *SCFiles) linearBucketNo(hashValue int64) (bucketNo int64) {
+	p := utils.RoundUp2(S.numberOfBucketsAvailable)
+	if p > S.numberOfBucketsAvailable {
+		p >>= 1
+	}
+
+	bucketNo = hashValue & (p - 1)
+	if bucketNo < S.numberOfBucketsAvailable-p {
+		bucketNo = hashValue & (p<<1 - 1)
+	}
+
+	return
+}
+
+// setNumberOfBuckets - Sets the number of buckets together with the parameters depending on it
+func (S *SCFiles) setNumberOfBuckets(numberOfBuckets int64) {
+	recordLength := 1 + S.keyLength + S.valueLength // First byte is record state
+	bucketLength := bucketHeaderLength + recordLength*S.recordsPerBucket
+
+	S.numberOfBucketsAvailable = numberOfBuckets
+	S.maxBucketNo = numberOfBuckets - 1
+	S.mapFileSize = bucketLength*numberOfBuckets + storage.MapFileHeaderLength
+	S.probeLimit = getProbeLimit(S.crtType, numberOfBuckets)
+}
+
+// splitBucket - Grows the map file by one bucket by splitting the bucket at the split pointer, where the records that
+// are addressed to the new bucket using one more bit of their hash value are moved to it.
+// The new bucket, and any overflow chain it needs, is written and synced before the header is updated to include it,
+// and the moved records are deleted from the split bucket after that. Hence, an interrupted split either leaves a
+// bucket beyond the header indicated file size, which is discarded when opening the files, or moved records left
+// behind in the split bucket where they are never found by key since they are addressed to the new bucket.
+func (S *SCFiles) splitBucket() (err error) {
+	if S.numberOfBucketsAvailable >= linearHashingAddressSpace {
+		return
+	}
+
+	p := utils.RoundUp2(S.numberOfBucketsAvailable)
+	if p > S.numberOfBucketsAvailable {
+		p >>= 1
+	}
+	splitBucketNo := S.numberOfBucketsAvailable - p
+	newBucketNo := S.numberOfBucketsAvailable
+
+	bucket, err := S.getBucketRecords(splitBucketNo)
+	if err != nil {
+		err = fmt.Errorf("error while reading bucket to split: %s", err)
+		return
+	}
+
+	// Find the records to move, where expired records are deleted rather than moved
+	var moved []model.Record
+	var record model.Record
+	for i := range bucket.Records {
+		record, err = S.getUnexpired(bucket.Records, i)
+		if err != nil {
+			return
+		}
+		if record.State == model.RecordOccupied && S.hashAlgorithm.HashFunc1(record.Key)&(p<<1-1) == newBucketNo {
+			moved = append(moved, record)
+		}
+	}
+	ovflIter := S.getOverflowIterator(bucket)
+	for ovflIter.HasNext() {
+		record, err = ovflIter.Next()
+		if err != nil {
+			return
+		}
+		record, err = S.getUnexpired([]model.Record{record}, 0)
+		if err != nil {
+			return
+		}
+		if record.State == model.RecordOccupied && S.hashAlgorithm.HashFunc1(record.Key)&(p<<1-1) == newBucketNo {
+			moved = append(moved, record)
+		}
+	}
+
+	// Write the new bucket, with records that don't fit in it in an overflow chain of its own
+	newBucket := model.Bucket{Records: make([]model.Record, S.recordsPerBucket)}
+	for i := range newBucket.Records {
+		newBucket.Records[i] = model.Record{Key: make([]byte, S.keyLength), Value: make([]byte, S.valueLength)}
+		if i < len(moved) {
+			newBucket.Records[i] = model.Record{State: model.RecordOccupied, AccessCount: moved[i].AccessCount, Key: moved[i].Key, Value: moved[i].Value}
+		}
+	}
+	if int64(len(moved)) > S.recordsPerBucket {
+		newBucket.OverflowAddress, err = S.appendOverflowChain(moved[S.recordsPerBucket:])
+		if err != nil {
+			err = fmt.Errorf("error while appending overflow chain for new bucket: %s", err)
+			return
+		}
+	}
+
+	_, err = S.mapFile.WriteAt(bucketToBytes(newBucket, S.keyLength, S.valueLength), S.mapFileSize)
+	if err != nil {
+		err = fmt.Errorf("error while writing new bucket: %s", err)
+		return
+	}
+	err = S.mapFile.Sync()
+	if err != nil {
+		return
+	}
+
+	// Include the new bucket in the table
+	S.setNumberOfBuckets(newBucketNo + 1)
+	header := S.createHeader()
+	header.MutationSeq = S.mutationSeq
+	err = storage.SetHeader(S.mapFile, header)
+	if err != nil {
+		err = fmt.Errorf("error while writing header to map file: %s", err)
+		return
+	}
+
+	// The moved records are now in the new bucket and its overflow chain, while Delete counts them as deleted where
+	// they were moved from
+	for i := range moved {
+		if int64(i) < S.recordsPerBucket {
+			S.counters.Transition(model.RecordEmpty, model.RecordOccupied)
+		} else {
+			S.ovflCounters.Transition(model.RecordEmpty, model.RecordOccupied)
+		}
+	}
+	for _, record = range moved {
+		err = S.markDeleted(record)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SyncDir - Commits the directory entries of the directory holding fileName to stable storage, which makes a newly
+// created or renamed file durable also when it comes to its name.
+//   - fileName is the name of a file in the directory to sync
+//
+// It returns:
+//   - err is a standard error, if the directory could not be opened or synced
+func SyncDir(fileName string) (err error) {
+	dir, err := os.Open(filepath.Dir(fileName))
+	if err != nil {
+		return
+	}
+	defer func(dir *os.File) { _ = dir.Close() }(dir)
+
+	err = dir.Sync()
+
+	return
+}
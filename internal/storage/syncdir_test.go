@@ -0,0 +1,33 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDir(t *testing.T) {
+	t.Run("syncs directory of file", func(t *testing.T) {
+		// Prepare
+		fileName := filepath.Join(t.TempDir(), "testfile")
+		err := os.WriteFile(fileName, []byte{1}, 0644)
+		assert.NoError(t, err, "creates a file")
+
+		// Execute
+		err = SyncDir(fileName)
+
+		// Check
+		assert.NoError(t, err, "syncs directory")
+	})
+
+	t.Run("fails for missing directory", func(t *testing.T) {
+		// Execute
+		err := SyncDir(filepath.Join(t.TempDir(), "missing", "testfile"))
+
+		// Check
+		assert.Error(t, err, "missing directory")
+	})
+}
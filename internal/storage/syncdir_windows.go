@@ -0,0 +1,9 @@
+//go:build windows
+
+package storage
+
+// SyncDir - Does nothing on Windows, where directories can not be synced and directory entries are made durable
+// together with the file
+func SyncDir(fileName string) (err error) {
+	return
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+)
+
+// SetTableSize - Sets the table size of a custom hash algorithm. If it implements hashfunc.TableSizeConstraint the
+// table size is first rounded according to its policy, and the table size it then reports is checked against it.
+//   - hashAlgorithm is the custom hash algorithm
+//   - tableSize is the table size the collision resolution technique needs
+//
+// It returns:
+//   - err is a standard error, if the hash algorithm reports a table size that breaks its own table size policy
+func SetTableSize(hashAlgorithm hashfunc.HashAlgorithm, tableSize int64) (err error) {
+	constraint, ok := hashAlgorithm.(hashfunc.TableSizeConstraint)
+	if !ok {
+		hashAlgorithm.SetTableSize(tableSize)
+		return
+	}
+
+	policy := constraint.TableSizePolicy()
+	hashAlgorithm.SetTableSize(policy.Apply(tableSize))
+	if actual := hashAlgorithm.GetTableSize(); !policy.Allows(actual) {
+		err = fmt.Errorf("hash algorithm reports table size %d which doesn't conform with its table size policy %d", actual, policy)
+	}
+
+	return
+}
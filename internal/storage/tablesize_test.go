@@ -0,0 +1,57 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// constrainedAlgorithm - A hash algorithm keeping the table size as given, while declaring a table size policy
+type constrainedAlgorithm struct {
+	policy    hashfunc.TableSizePolicy
+	tableSize int64
+	fixedSize int64
+}
+
+func (C *constrainedAlgorithm) SetTableSize(tableSize int64) {
+	C.tableSize = tableSize
+	if C.fixedSize > 0 {
+		C.tableSize = C.fixedSize
+	}
+}
+func (C *constrainedAlgorithm) HashFunc1(key []byte) int64                       { return 0 }
+func (C *constrainedAlgorithm) HashFunc2(key []byte) int64                       { return 0 }
+func (C *constrainedAlgorithm) GetTableSize() int64                              { return C.tableSize }
+func (C *constrainedAlgorithm) ProbeIteration(hf1Value, hf2Value, i int64) int64 { return 0 }
+func (C *constrainedAlgorithm) TableSizePolicy() hashfunc.TableSizePolicy        { return C.policy }
+
+func TestSetTableSize(t *testing.T) {
+	t.Run("rounds according to policy", func(t *testing.T) {
+		// Prepare
+		sizes := map[hashfunc.TableSizePolicy]int64{hashfunc.TableSizeExact: 100, hashfunc.TableSizePowerOfTwo: 128, hashfunc.TableSizePrime: 101}
+
+		for policy, size := range sizes {
+			ha := &constrainedAlgorithm{policy: policy}
+
+			// Execute
+			err := SetTableSize(ha, 100)
+
+			// Check
+			assert.NoErrorf(t, err, "sets table size with policy %d", policy)
+			assert.Equalf(t, size, ha.GetTableSize(), "table size with policy %d", policy)
+		}
+	})
+
+	t.Run("fails if table size breaks policy", func(t *testing.T) {
+		// Prepare
+		ha := &constrainedAlgorithm{policy: hashfunc.TableSizePrime, fixedSize: 100}
+
+		// Execute
+		err := SetTableSize(ha, 100)
+
+		// Check
+		assert.Error(t, err, "table size not a prime")
+	})
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// writeBackPageSize - Is the size of the pages pending writes are held in
+const writeBackPageSize int64 = 4096
+
+// writeBackPage - Is a page of pending writes
+//   - data is the content of the page, of which only dirty bytes are valid
+//   - dirty has a bit set for each byte of data that is written
+type writeBackPage struct {
+	data  [writeBackPageSize]byte
+	dirty [writeBackPageSize / 64]uint64
+}
+
+// isDirty - Returns true if the byte at offset i of the page is written
+func (W *writeBackPage) isDirty(i int64) bool {
+	return W.dirty[i/64]&(1<<uint(i%64)) != 0
+}
+
+// writeBackFile - Is a vfs.File holding writes in memory until they are flushed to the underlying file, either when
+// the pending writes reach maxBytes, by a timer when interval has passed since the first pending write, or when the file
+// is synced, truncated or closed. Reads see the pending writes.
+type writeBackFile struct {
+	vfs.File
+	mu       sync.Mutex
+	maxBytes int64
+	interval time.Duration
+	pages    map[int64]*writeBackPage
+	end      int64
+	timer    *time.Timer
+}
+
+// writeBackInfo - Is the information about a write-back file, with the size including pending writes past the end of
+// the underlying file
+type writeBackInfo struct {
+	os.FileInfo
+	size int64
+}
+
+// Size - Returns the size of the file including pending writes
+func (W writeBackInfo) Size() int64 {
+	return W.size
+}
+
+// ReadAt - Reads from the underlying file and overlays the pending writes. Bytes past the end of the underlying file
+// read as zeros as long as pending writes extend the file beyond them.
+func (W *writeBackFile) ReadAt(p []byte, off int64) (n int, err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	n, err = W.File.ReadAt(p, off)
+	if err == io.EOF && off+int64(len(p)) <= W.end {
+		for i := n; i < len(p); i++ {
+			p[i] = 0
+		}
+		n, err = len(p), nil
+	}
+	if err != nil && err != io.EOF {
+		return
+	}
+
+	W.overlay(p[:n], off)
+
+	return
+}
+
+// WriteAt - Adds p to the pending writes, and flushes them if either threshold is reached
+func (W *writeBackFile) WriteAt(p []byte, off int64) (n int, err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	if len(W.pages) == 0 && W.interval > 0 {
+		W.timer = time.AfterFunc(W.interval, W.flushDue)
+	}
+
+	for n < len(p) {
+		pageNo := (off + int64(n)) / writeBackPageSize
+		page, ok := W.pages[pageNo]
+		if !ok {
+			page = &writeBackPage{}
+			W.pages[pageNo] = page
+		}
+
+		start := (off + int64(n)) % writeBackPageSize
+		c := copy(page.data[start:], p[n:])
+		for i := start; i < start+int64(c); i++ {
+			page.dirty[i/64] |= 1 << uint(i%64)
+		}
+		n += c
+	}
+
+	if off+int64(n) > W.end {
+		W.end = off + int64(n)
+	}
+
+	if int64(len(W.pages))*writeBackPageSize >= W.maxBytes {
+		err = W.flush()
+	}
+
+	return
+}
+
+// Truncate - Flushes the pending writes and changes the size of the underlying file
+func (W *writeBackFile) Truncate(size int64) (err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	err = W.flush()
+	if err != nil {
+		return
+	}
+
+	err = W.File.Truncate(size)
+
+	return
+}
+
+// Sync - Flushes the pending writes and commits the underlying file to stable storage
+func (W *writeBackFile) Sync() (err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	err = W.flush()
+	if err != nil {
+		return
+	}
+
+	err = W.File.Sync()
+
+	return
+}
+
+// Close - Flushes the pending writes and closes the underlying file, which is closed even if the flush fails
+func (W *writeBackFile) Close() (err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	err = W.flush()
+	W.stopTimer()
+	closeErr := W.File.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return
+}
+
+// Stat - Returns information about the underlying file, with the size including pending writes
+func (W *writeBackFile) Stat() (info os.FileInfo, err error) {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	info, err = W.File.Stat()
+	if err != nil {
+		return
+	}
+
+	if W.end > info.Size() {
+		info = writeBackInfo{FileInfo: info, size: W.end}
+	}
+
+	return
+}
+
+// underlying - Returns the underlying file
+func (W *writeBackFile) underlying() vfs.File {
+	return W.File
+}
+
+// overlay - Copies the pending writes within p, read from off, into p, to be called with the lock held
+func (W *writeBackFile) overlay(p []byte, off int64) {
+	if len(W.pages) == 0 {
+		return
+	}
+
+	for pageNo := off / writeBackPageSize; pageNo*writeBackPageSize < off+int64(len(p)); pageNo++ {
+		page, ok := W.pages[pageNo]
+		if !ok {
+			continue
+		}
+
+		pageStart := pageNo * writeBackPageSize
+		for i := int64(0); i < writeBackPageSize; i++ {
+			pos := pageStart + i - off
+			if pos < 0 || pos >= int64(len(p)) {
+				continue
+			}
+			if page.isDirty(i) {
+				p[pos] = page.data[i]
+			}
+		}
+	}
+}
+
+// flushDue - Flushes the pending writes when interval has passed since the first of them. If the flush fails the writes
+// are kept and a new flush is scheduled, while the error is left for the next write, sync or close to report when it
+// flushes.
+func (W *writeBackFile) flushDue() {
+	W.mu.Lock()
+	defer W.mu.Unlock()
+
+	if err := W.flush(); err != nil && W.interval > 0 {
+		W.timer = time.AfterFunc(W.interval, W.flushDue)
+	}
+}
+
+// stopTimer - Stops any scheduled flush, to be called with the lock held
+func (W *writeBackFile) stopTimer() {
+	if W.timer != nil {
+		W.timer.Stop()
+		W.timer = nil
+	}
+}
+
+// flush - Writes the pending writes to the underlying file in order of offset, with one write per run of contiguous
+// bytes, to be called with the lock held. The first page, holding the header with the sequence number, is written last
+// so the header never tells of mutations whose records are not yet written. Pending writes are kept if a write fails,
+// so that a later flush retries them.
+func (W *writeBackFile) flush() (err error) {
+	if len(W.pages) == 0 {
+		return
+	}
+
+	pageNos := make([]int64, 0, len(W.pages))
+	for pageNo := range W.pages {
+		pageNos = append(pageNos, pageNo)
+	}
+	sort.Slice(pageNos, func(i, j int) bool { return pageNos[i] < pageNos[j] })
+	if pageNos[0] == 0 {
+		pageNos = append(pageNos[1:], 0)
+	}
+
+	var run []byte
+	var runStart int64
+	write := func() (err error) {
+		if len(run) > 0 {
+			_, err = W.File.WriteAt(run, runStart)
+			run = run[:0]
+		}
+		return
+	}
+
+	for _, pageNo := range pageNos {
+		page := W.pages[pageNo]
+		pageStart := pageNo * writeBackPageSize
+		for i := int64(0); i < writeBackPageSize; i++ {
+			if !page.isDirty(i) {
+				continue
+			}
+			if len(run) > 0 && runStart+int64(len(run)) != pageStart+i {
+				err = write()
+				if err != nil {
+					return
+				}
+			}
+			if len(run) == 0 {
+				runStart = pageStart + i
+			}
+			run = append(run, page.data[i])
+		}
+	}
+
+	err = write()
+	if err != nil {
+		return
+	}
+
+	W.pages = make(map[int64]*writeBackPage)
+	W.end = 0
+	W.stopTimer()
+
+	return
+}
+
+// BufferWrites - Returns file wrapped so that writes are held in memory and coalesced into as few writes as possible,
+// which are made when the pending writes reach maxBytes, by a timer when interval has passed since the first pending
+// write, or when the file is synced, truncated or closed. Reads through the returned file see the pending
+// writes, but reads of the file by other means don't until they are flushed. Any buffering already wrapped around file
+// is flushed and replaced, also when wrapped beneath counting by CountBytes, which is kept on top.
+//   - file is the file to buffer writes for, nil is returned as is
+//   - maxBytes is the max number of bytes, rounded up to whole pages, to hold before flushing, zero turns buffering off
+//   - interval is the max time to hold writes, zero means no time limit
+//
+// It returns:
+//   - buffered is the file to read and write through
+//   - err is a standard error, if pending writes of buffering already wrapped around file could not be flushed
+func BufferWrites(file vfs.File, maxBytes int64, interval time.Duration) (buffered vfs.File, err error) {
+	buffered = file
+	if file == nil {
+		return
+	}
+
+	if c, ok := file.(*countingFile); ok {
+		var inner vfs.File
+		inner, err = BufferWrites(c.File, maxBytes, interval)
+		if err != nil {
+			return
+		}
+		buffered = &countingFile{File: inner, metrics: c.metrics}
+		return
+	}
+
+	if w, ok := file.(*writeBackFile); ok {
+		w.mu.Lock()
+		err = w.flush()
+		w.stopTimer()
+		w.mu.Unlock()
+		if err != nil {
+			return
+		}
+		file = w.File
+		buffered = file
+	}
+
+	if maxBytes > 0 {
+		buffered = &writeBackFile{
+			File:     file,
+			maxBytes: maxBytes,
+			interval: interval,
+			pages:    make(map[int64]*writeBackPage),
+		}
+	}
+
+	return
+}
+
+// IsWriteBuffered - Returns true if writes to file are buffered by BufferWrites, looking through counting wrapped by
+// CountBytes
+//   - file is the file to check
+func IsWriteBuffered(file vfs.File) bool {
+	if c, ok := file.(*countingFile); ok {
+		file = c.File
+	}
+	_, ok := file.(*writeBackFile)
+
+	return ok
+}
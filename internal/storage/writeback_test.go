@@ -0,0 +1,138 @@
+//go:build unit
+
+package storage
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeCountingFile - Counts the writes made to a file
+type writeCountingFile struct {
+	vfs.File
+	writes atomic.Int64
+}
+
+func (W *writeCountingFile) WriteAt(p []byte, off int64) (int, error) {
+	n, err := W.File.WriteAt(p, off)
+	W.writes.Add(1)
+	return n, err
+}
+
+// testMetrics - Ignores the metrics counted
+type testMetrics struct{}
+
+func (T *testMetrics) AddProbeIterations(int64) {}
+func (T *testMetrics) AddOverflowAppends(int64) {}
+func (T *testMetrics) AddBytesRead(int64)       {}
+func (T *testMetrics) AddBytesWritten(int64)    {}
+func (T *testMetrics) AddRetries(int64)         {}
+
+func TestBufferWrites(t *testing.T) {
+	t.Run("coalesces writes and reads them through", func(t *testing.T) {
+		// Prepare
+		inner := &writeCountingFile{File: NewMemFile()}
+		_, err := inner.File.WriteAt(make([]byte, 100), 0)
+		assert.NoError(t, err, "writes initial content")
+		file, err := BufferWrites(inner, 1<<20, 0)
+		assert.NoError(t, err, "buffers writes")
+
+		// Execute
+		_, err = file.WriteAt([]byte{1, 2, 3}, 10)
+		assert.NoError(t, err, "writes first")
+		_, err = file.WriteAt([]byte{4, 5}, 13)
+		assert.NoError(t, err, "writes adjacent")
+		_, err = file.WriteAt([]byte{9}, 11)
+		assert.NoError(t, err, "overwrites pending")
+		_, err = file.WriteAt([]byte{7, 7}, 4096*3-1)
+		assert.NoError(t, err, "writes across pages past end of file")
+		buf := make([]byte, 7)
+		_, err = file.ReadAt(buf, 9)
+		assert.NoError(t, err, "reads pending")
+		writesBeforeSync := inner.writes.Load()
+		info, errStat := file.Stat()
+		err = file.Sync()
+
+		// Check
+		assert.Equal(t, []byte{0, 1, 9, 3, 4, 5, 0}, buf, "reads pending writes over file content")
+		assert.Equal(t, int64(0), writesBeforeSync, "no writes before sync")
+		assert.NoError(t, errStat, "stats file")
+		assert.Equal(t, int64(4096*3+1), info.Size(), "size includes pending writes")
+		assert.NoError(t, err, "syncs")
+		assert.Equal(t, int64(2), inner.writes.Load(), "one write per contiguous run")
+		buf = make([]byte, 5)
+		_, err = inner.ReadAt(buf, 10)
+		assert.NoError(t, err, "reads underlying file")
+		assert.Equal(t, []byte{1, 9, 3, 4, 5}, buf, "underlying file holds flushed writes")
+		buf = make([]byte, 2)
+		_, err = inner.ReadAt(buf, 4096*3-1)
+		assert.NoError(t, err, "reads extended underlying file")
+		assert.Equal(t, []byte{7, 7}, buf, "underlying file extended by flushed writes")
+	})
+
+	t.Run("flushes when max bytes is reached", func(t *testing.T) {
+		// Prepare
+		inner := &writeCountingFile{File: NewMemFile()}
+		file, err := BufferWrites(inner, 2*writeBackPageSize, 0)
+		assert.NoError(t, err, "buffers writes")
+
+		// Execute
+		_, err = file.WriteAt([]byte{1}, 0)
+		assert.NoError(t, err, "writes first page")
+		writesAfterFirst := inner.writes.Load()
+		_, err = file.WriteAt([]byte{2}, writeBackPageSize)
+		assert.NoError(t, err, "writes second page")
+
+		// Check
+		assert.Equal(t, int64(0), writesAfterFirst, "first write pending")
+		assert.Equal(t, int64(2), inner.writes.Load(), "flushed when two pages are held")
+	})
+
+	t.Run("flushes by timer when interval has passed", func(t *testing.T) {
+		// Prepare
+		inner := &writeCountingFile{File: NewMemFile()}
+		file, err := BufferWrites(inner, 1<<20, 10*time.Millisecond)
+		assert.NoError(t, err, "buffers writes")
+
+		// Execute
+		_, err = file.WriteAt([]byte{1, 2}, 0)
+		assert.NoError(t, err, "writes")
+		writesAfterWrite := inner.writes.Load()
+
+		// Check
+		assert.Equal(t, int64(0), writesAfterWrite, "write pending")
+		assert.Eventually(t, func() bool { return inner.writes.Load() == 1 }, time.Second, time.Millisecond,
+			"flushed without further writes")
+		buf := make([]byte, 2)
+		_, err = inner.File.ReadAt(buf, 0)
+		assert.NoError(t, err, "reads underlying file")
+		assert.Equal(t, []byte{1, 2}, buf, "underlying file holds flushed write")
+
+		// Clean up
+		err = file.Close()
+		assert.NoError(t, err, "close file")
+	})
+
+	t.Run("replaces and turns off buffering beneath counting", func(t *testing.T) {
+		// Prepare
+		inner := &writeCountingFile{File: NewMemFile()}
+		file, err := BufferWrites(inner, 1<<20, 0)
+		assert.NoError(t, err, "buffers writes")
+		file = CountBytes(file, &testMetrics{})
+		_, err = file.WriteAt([]byte{1}, 0)
+		assert.NoError(t, err, "writes")
+
+		// Execute
+		file, err = BufferWrites(file, 0, 0)
+
+		// Check
+		assert.NoError(t, err, "turns off buffering")
+		assert.Equal(t, int64(1), inner.writes.Load(), "pending write flushed")
+		assert.False(t, IsWriteBuffered(file), "writes no longer buffered")
+		_, isCounting := file.(*countingFile)
+		assert.True(t, isCounting, "counting kept on top")
+	})
+}
@@ -31,6 +31,39 @@ func ExtendByteSlice(a []byte, extension int64, prepend bool) (b []byte) {
 	return
 }
 
+// TruncateByteSlice - Truncates a byte slice by removing a number of bytes from the start or from the end of it
+//   - a is the byte slice to truncate
+//   - truncation is the number of bytes to remove
+//   - fromStart whether to remove the bytes from the start or from the end
+//
+// It returns:
+//   - b is a copy of the byte slice with the bytes removed
+//   - lost is true if any of the removed bytes was not zero
+func TruncateByteSlice(a []byte, truncation int64, fromStart bool) (b []byte, lost bool) {
+	if truncation > int64(len(a)) {
+		truncation = int64(len(a))
+	}
+	var removed []byte
+	if fromStart {
+		removed = a[:truncation]
+		b = make([]byte, int64(len(a))-truncation)
+		_ = copy(b, a[truncation:])
+	} else {
+		removed = a[int64(len(a))-truncation:]
+		b = make([]byte, int64(len(a))-truncation)
+		_ = copy(b, a)
+	}
+
+	for _, v := range removed {
+		if v != 0 {
+			lost = true
+			break
+		}
+	}
+
+	return
+}
+
 // RoundUp2 - Rounds up to the nearest exponent of 2
 func RoundUp2(a int64) int64 {
 	r := uint64(a - 1)
@@ -42,3 +75,29 @@ func RoundUp2(a int64) int64 {
 	r |= r >> 32
 	return int64(r + 1)
 }
+
+// NextPrime - Returns the smallest prime number that is equal to or bigger than a
+func NextPrime(a int64) int64 {
+	n := a
+
+OUTER:
+	for {
+		if n == 2 || n == 3 {
+			return n
+		}
+
+		if n <= 1 || n%2 == 0 || n%3 == 0 {
+			n++
+			continue
+		}
+
+		for i := int64(5); i*i <= n; i += 6 {
+			if n%i == 0 || n%(i+2) == 0 {
+				n++
+				continue OUTER
+			}
+		}
+
+		return n
+	}
+}
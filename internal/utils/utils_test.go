@@ -91,6 +91,44 @@ func TestExtendByteSlice(t *testing.T) {
 	})
 }
 
+func TestTruncateByteSlice(t *testing.T) {
+	t.Run("bytes are removed from start of byte slice", func(t *testing.T) {
+		// Prepare
+		a := []byte{0, 0, 3, 4, 5}
+
+		// Execute
+		b, lost := TruncateByteSlice(a, 2, true)
+
+		// Check
+		assert.Equal(t, []byte{3, 4, 5}, b, "data correctly kept at end of slice")
+		assert.False(t, lost, "only zeros removed")
+	})
+
+	t.Run("bytes are removed from end of byte slice", func(t *testing.T) {
+		// Prepare
+		a := []byte{1, 2, 3, 0, 0}
+
+		// Execute
+		b, lost := TruncateByteSlice(a, 2, false)
+
+		// Check
+		assert.Equal(t, []byte{1, 2, 3}, b, "data correctly kept in beginning of slice")
+		assert.False(t, lost, "only zeros removed")
+	})
+
+	t.Run("removing non zero bytes is reported", func(t *testing.T) {
+		// Prepare
+		a := []byte{1, 2, 3, 4, 5}
+
+		// Execute
+		b, lost := TruncateByteSlice(a, 2, false)
+
+		// Check
+		assert.Equal(t, []byte{1, 2, 3}, b, "slice truncated anyway")
+		assert.True(t, lost, "data lost")
+	})
+}
+
 func TestRoundUp2(t *testing.T) {
 	t.Run("bytes are prepended to byte slice", func(t *testing.T) {
 		// Prepare
@@ -104,3 +142,16 @@ func TestRoundUp2(t *testing.T) {
 		}
 	})
 }
+
+func TestNextPrime(t *testing.T) {
+	t.Run("rounds up to nearest prime", func(t *testing.T) {
+		// Prepare
+		primes := []int64{2, 2, 2, 3, 5, 11, 11, 101, 1009, 7919}
+		input := []int64{-1, 0, 2, 3, 4, 8, 11, 100, 1000, 7908}
+
+		// Execute and Check
+		for i := 0; i < len(input); i++ {
+			assert.Equal(t, primes[i], NextPrime(input[i]), "rounds up correct")
+		}
+	})
+}
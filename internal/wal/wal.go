@@ -0,0 +1,293 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+)
+
+// OpSet - Entry operation for a record that was set
+const OpSet uint8 = 1
+
+// OpPop - Entry operation for a record that was popped (deleted)
+const OpPop uint8 = 2
+
+// flagHadOld - Flag in the second byte of an entry telling that there was a record with the key before the mutation
+const flagHadOld uint8 = 1
+
+// flagContinued - Flag in the second byte of an entry telling that the entry is part of a group that continues with
+// the next entry
+const flagContinued uint8 = 2
+
+// walHeaderLength - Length of the WAL file header, which holds the sequence number of the first entry - 8 bytes
+const walHeaderLength int64 = 8
+
+// Entry - Represents one mutation in the WAL
+//   - Op is the operation, either OpSet or OpPop
+//   - HadOld is true if there was a record with the key before the mutation
+//   - Key is the key of the mutated record
+//   - OldValue is the value before the mutation, only valid if HadOld is true
+//   - NewValue is the value after the mutation, only valid if Op is OpSet
+//   - Continued is true if the entry is part of a group that continues with the next entry, see AppendGroup
+type Entry struct {
+	Op        uint8
+	HadOld    bool
+	Key       []byte
+	OldValue  []byte
+	NewValue  []byte
+	Continued bool
+}
+
+// WAL - Is a write-ahead log of fixed length entries. Every entry is synced to disk before it is considered written,
+// and each entry gets a sequence number that is implied by its position in the file, which means that sequence numbers
+// are strictly increasing without gaps.
+type WAL struct {
+	file        vfs.File
+	keyLength   int64
+	valueLength int64
+	entryLength int64
+	baseSeq     int64
+	entries     int64
+}
+
+// Open - Opens an existing WAL file or creates a new one if it doesn't exist
+//   - fileSystem is the file system holding the WAL file
+//   - fileName is the name of the WAL file
+//   - keyLength is the fixed length of keys in entries
+//   - valueLength is the fixed length of values in entries
+//   - firstSeq is the sequence number to give the first entry if a new WAL is created
+//
+// It returns:
+//   - wal is a pointer to the opened WAL
+//   - err is a standard error, if something went wrong
+func Open(fileSystem vfs.FileSystem, fileName string, keyLength, valueLength, firstSeq int64) (wal *WAL, err error) {
+	file, err := fileSystem.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while open/create WAL file: %s", err)
+		return
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return
+	}
+
+	wal = &WAL{
+		file:        file,
+		keyLength:   keyLength,
+		valueLength: valueLength,
+		entryLength: 2 + keyLength + 2*valueLength, // Two first bytes are operation and flags
+	}
+
+	if stat.Size() < walHeaderLength {
+		err = wal.writeBaseSeq(firstSeq)
+		if err != nil {
+			_ = file.Close()
+			wal = nil
+		}
+		return
+	}
+
+	buf := make([]byte, walHeaderLength)
+	_, err = file.ReadAt(buf, 0)
+	if err != nil {
+		_ = file.Close()
+		wal = nil
+		return
+	}
+
+	// Any partially written entry at the end was never synced and hence never applied, so it is ignored
+	wal.baseSeq = int64(binary.LittleEndian.Uint64(buf))
+	wal.entries = (stat.Size() - walHeaderLength) / wal.entryLength
+
+	// The same goes for entries of a group that was not completely written
+	var entry Entry
+	for wal.entries > 0 {
+		entry, err = wal.readEntry(wal.entries - 1)
+		if err != nil {
+			_ = file.Close()
+			wal = nil
+			return
+		}
+		if !entry.Continued {
+			break
+		}
+		wal.entries--
+	}
+
+	return
+}
+
+// Close - Closes the WAL file
+func (W *WAL) Close() {
+	if W.file != nil {
+		_ = W.file.Close()
+		W.file = nil
+	}
+}
+
+// NextSeq - Returns the sequence number that the next appended entry will get
+func (W *WAL) NextSeq() int64 {
+	return W.baseSeq + W.entries
+}
+
+// FirstSeq - Returns the oldest sequence number still available in the WAL
+func (W *WAL) FirstSeq() int64 {
+	return W.baseSeq
+}
+
+// Append - Appends an entry to the WAL and syncs it to disk
+//
+// It returns:
+//   - seq is the sequence number given to the entry
+//   - err is a standard error, if something went wrong
+func (W *WAL) Append(entry Entry) (seq int64, err error) {
+	seq, err = W.AppendGroup([]Entry{entry})
+
+	return
+}
+
+// AppendGroup - Appends entries to the WAL as one group and syncs them to disk. Every entry but the last is marked as
+// continued, so if the group is not completely written, e.g. due to a crash, none of its entries are seen when the WAL
+// is opened again.
+//
+// It returns:
+//   - seq is the sequence number given to the last entry, the entries before it got the numbers just before
+//   - err is a standard error, if something went wrong
+func (W *WAL) AppendGroup(entries []Entry) (seq int64, err error) {
+	buf := make([]byte, 0, W.entryLength*int64(len(entries)))
+	for i, entry := range entries {
+		flags := uint8(0)
+		if entry.HadOld {
+			flags |= flagHadOld
+		}
+		if i < len(entries)-1 {
+			flags |= flagContinued
+		}
+		buf = append(buf, entry.Op, flags)
+		buf = append(buf, fit(entry.Key, W.keyLength)...)
+		buf = append(buf, fit(entry.OldValue, W.valueLength)...)
+		buf = append(buf, fit(entry.NewValue, W.valueLength)...)
+	}
+
+	seq = W.NextSeq() + int64(len(entries)) - 1
+
+	_, err = W.file.WriteAt(buf, walHeaderLength+W.entries*W.entryLength)
+	if err != nil {
+		return
+	}
+
+	err = W.file.Sync()
+	if err != nil {
+		return
+	}
+
+	W.entries += int64(len(entries))
+
+	return
+}
+
+// Last - Returns the last entry in the WAL, ok is false if the WAL is empty
+func (W *WAL) Last() (entry Entry, seq int64, ok bool, err error) {
+	if W.entries == 0 {
+		return
+	}
+
+	seq = W.NextSeq() - 1
+	entry, err = W.readEntry(W.entries - 1)
+	ok = err == nil
+
+	return
+}
+
+// Iterate - Calls fn for each entry in the WAL in sequence order until fn returns false
+func (W *WAL) Iterate(fn func(seq int64, entry Entry) bool) (err error) {
+	var entry Entry
+	for i := int64(0); i < W.entries; i++ {
+		entry, err = W.readEntry(i)
+		if err != nil {
+			return
+		}
+		if !fn(W.baseSeq+i, entry) {
+			return
+		}
+	}
+
+	return
+}
+
+// Checkpoint - Discards all entries from the WAL. It must only be called once all mutations covered by the entries
+// are durable in the hash map files. Sequence numbers continue from where they were.
+func (W *WAL) Checkpoint() (err error) {
+	err = W.Restart(W.NextSeq())
+
+	return
+}
+
+// Restart - Discards all entries from the WAL and lets sequence numbers continue from firstSeq.
+func (W *WAL) Restart(firstSeq int64) (err error) {
+	err = W.writeBaseSeq(firstSeq)
+	if err != nil {
+		return
+	}
+
+	err = W.file.Truncate(walHeaderLength)
+	if err != nil {
+		return
+	}
+
+	W.entries = 0
+
+	err = W.file.Sync()
+
+	return
+}
+
+// readEntry - Reads entry number n (zero based) from file
+func (W *WAL) readEntry(n int64) (entry Entry, err error) {
+	buf := make([]byte, W.entryLength)
+	_, err = W.file.ReadAt(buf, walHeaderLength+n*W.entryLength)
+	if err != nil {
+		return
+	}
+
+	keyStart := int64(2)
+	oldStart := keyStart + W.keyLength
+	newStart := oldStart + W.valueLength
+
+	entry = Entry{
+		Op:        buf[0],
+		HadOld:    buf[1]&flagHadOld != 0,
+		Key:       buf[keyStart:oldStart],
+		OldValue:  buf[oldStart:newStart],
+		NewValue:  buf[newStart:],
+		Continued: buf[1]&flagContinued != 0,
+	}
+
+	return
+}
+
+// writeBaseSeq - Writes the sequence number of the first entry to the WAL header
+func (W *WAL) writeBaseSeq(baseSeq int64) (err error) {
+	buf := make([]byte, walHeaderLength)
+	binary.LittleEndian.PutUint64(buf, uint64(baseSeq))
+
+	_, err = W.file.WriteAt(buf, 0)
+	if err != nil {
+		return
+	}
+
+	W.baseSeq = baseSeq
+
+	return
+}
+
+// fit - Returns a slice of exactly length bytes, zero padded if a is shorter
+func fit(a []byte, length int64) (b []byte) {
+	b = make([]byte, length)
+	_ = copy(b, a)
+
+	return
+}
@@ -0,0 +1,103 @@
+//go:build unit
+
+package wal
+
+import (
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestWAL(t *testing.T) {
+	t.Run("appends, iterates and checkpoints entries", func(t *testing.T) {
+		// Prepare
+		w, err := Open(vfs.OS{}, "testfile", 2, 3, 1)
+		assert.NoError(t, err, "creates WAL")
+
+		// Execute
+		seq1, err := w.Append(Entry{Op: OpSet, Key: []byte{1, 2}, NewValue: []byte{3, 4, 5}})
+		assert.NoError(t, err, "appends first entry")
+		seq2, err := w.Append(Entry{Op: OpPop, HadOld: true, Key: []byte{1, 2}, OldValue: []byte{3, 4, 5}})
+		assert.NoError(t, err, "appends second entry")
+
+		// Check
+		assert.Equal(t, int64(1), seq1, "first sequence number")
+		assert.Equal(t, int64(2), seq2, "second sequence number")
+
+		w.Close()
+		w, err = Open(vfs.OS{}, "testfile", 2, 3, 1)
+		assert.NoError(t, err, "opens existing WAL")
+		assert.Equal(t, int64(3), w.NextSeq(), "next sequence number preserved")
+
+		entry, seq, ok, err := w.Last()
+		assert.NoError(t, err, "gets last entry")
+		assert.True(t, ok, "has last entry")
+		assert.Equal(t, int64(2), seq, "last sequence number")
+		assert.Equal(t, OpPop, entry.Op, "last operation")
+		assert.True(t, entry.HadOld, "last had old")
+		assert.Equal(t, []byte{3, 4, 5}, entry.OldValue, "last old value")
+
+		var seqs []int64
+		err = w.Iterate(func(seq int64, entry Entry) bool {
+			seqs = append(seqs, seq)
+			return true
+		})
+		assert.NoError(t, err, "iterates entries")
+		assert.Equal(t, []int64{1, 2}, seqs, "iterates all entries in order")
+
+		err = w.Checkpoint()
+		assert.NoError(t, err, "checkpoints WAL")
+		_, _, ok, err = w.Last()
+		assert.NoError(t, err, "gets last entry after checkpoint")
+		assert.False(t, ok, "no entries after checkpoint")
+		assert.Equal(t, int64(3), w.FirstSeq(), "sequence numbers continue after checkpoint")
+
+		// Clean up
+		w.Close()
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+
+	t.Run("ignores incomplete group", func(t *testing.T) {
+		// Prepare
+		w, err := Open(vfs.OS{}, "testfile", 2, 3, 1)
+		assert.NoError(t, err, "creates WAL")
+		_, err = w.Append(Entry{Op: OpSet, Key: []byte{1, 1}, NewValue: []byte{1, 1, 1}})
+		assert.NoError(t, err, "appends single entry")
+
+		// Execute
+		seq, err := w.AppendGroup([]Entry{
+			{Op: OpSet, Key: []byte{2, 2}, NewValue: []byte{2, 2, 2}},
+			{Op: OpSet, Key: []byte{3, 3}, NewValue: []byte{3, 3, 3}},
+		})
+		assert.NoError(t, err, "appends group")
+		w.Close()
+
+		// Check
+		assert.Equal(t, int64(3), seq, "sequence number of last entry in group")
+
+		w, err = Open(vfs.OS{}, "testfile", 2, 3, 1)
+		assert.NoError(t, err, "opens complete WAL")
+		assert.Equal(t, int64(4), w.NextSeq(), "complete group kept")
+		w.Close()
+
+		// Remove the last entry of the group, as if the crash happened while writing it
+		err = os.Truncate("testfile", walHeaderLength+2*w.entryLength)
+		assert.NoError(t, err, "truncates WAL")
+
+		w, err = Open(vfs.OS{}, "testfile", 2, 3, 1)
+		assert.NoError(t, err, "opens WAL with incomplete group")
+		assert.Equal(t, int64(2), w.NextSeq(), "incomplete group ignored")
+		entry, _, ok, err := w.Last()
+		assert.NoError(t, err, "gets last entry")
+		assert.True(t, ok, "has last entry")
+		assert.Equal(t, []byte{1, 1}, entry.Key, "entry before group is last")
+		assert.False(t, entry.Continued, "last entry is not continued")
+
+		// Clean up
+		w.Close()
+		err = os.Remove("testfile")
+		assert.NoError(t, err, "removes file")
+	})
+}
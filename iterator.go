@@ -0,0 +1,176 @@
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+)
+
+// Iterator - Cursor over all records stored in a file hash map. It walks the map file bucket by bucket, including any
+// overflow chain, and skips empty, deleted and expired records. Buckets are read ahead in large chunks, which are read
+// again if the file hash map is changed while iterating, so records popped while iterating are not visited, while records
+// set while iterating may or may not be visited.
+// The file hash map is only locked while a bucket is read, so other methods may be called while iterating.
+type Iterator struct {
+	fileHashMap *FileHashMap
+	nBuckets    int64
+	bucketNo    int64
+	getBucket   bucketGetter
+	records     []model.Record
+	consumed    int
+	seq         int64
+	fm          FileManagement
+	key         []byte
+	value       []byte
+	err         error
+	done        bool
+}
+
+// Iterator - Returns a new Iterator positioned before the first record, call Next to advance to the first record.
+//
+//	iter := fhm.Iterator()
+//	for iter.Next() {
+//		key, value := iter.Key(), iter.Value()
+//		...
+//	}
+//	if iter.Err() != nil {
+//		...
+//	}
+func (F *FileHashMap) Iterator() (iterator *Iterator) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	iterator = &Iterator{
+		fileHashMap: F,
+		nBuckets:    F.fileManagement.GetStorageParameters().NumberOfBucketsAvailable,
+		getBucket:   F.bucketScanner(),
+	}
+	F.beginScan()
+
+	return
+}
+
+// Next - Advances the iterator to the next record.
+//
+// It returns:
+//   - hasNext is true if the iterator is positioned at a record, false if there are no more records or an error occurred (see Err)
+func (I *Iterator) Next() (hasNext bool) {
+	var record model.Record
+
+	for I.err == nil {
+		// Records left from current bucket
+		for len(I.records) > 0 {
+			if I.reread(); I.err != nil || len(I.records) == 0 {
+				break
+			}
+			record = I.records[0]
+			I.records = I.records[1:]
+			I.consumed++
+			if record.State == model.RecordOccupied && !I.fileHashMap.hasExpired(record) {
+				I.key, I.err = I.fileHashMap.userKey(record)
+				if I.err != nil {
+					break
+				}
+				I.value = I.fileHashMap.fromStoredValue(record.Value)
+				return true
+			}
+		}
+
+		// Move on to the next bucket
+		if I.bucketNo >= I.nBuckets {
+			break
+		}
+		I.read(false)
+		I.bucketNo++
+	}
+
+	I.key, I.value = nil, nil
+	I.finish()
+
+	return false
+}
+
+// read - Reads the records of the bucket at bucketNo, or of the current bucket if again is true, in which case the
+// records already visited are skipped
+func (I *Iterator) read(again bool) {
+	F := I.fileHashMap
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	bucketNo := I.bucketNo
+	if again {
+		bucketNo--
+	} else {
+		I.consumed = 0
+	}
+
+	I.records, I.err = F.readBucketLocked(I.getBucket, bucketNo)
+	I.seq, I.fm = F.lastSeq(), F.fileManagement
+
+	if I.consumed < len(I.records) {
+		I.records = I.records[I.consumed:]
+	} else {
+		I.records = nil
+	}
+}
+
+// reread - Reads the current bucket again if the file hash map was changed since it was read, so records popped
+// while iterating are not visited
+func (I *Iterator) reread() {
+	F := I.fileHashMap
+	F.mu.Lock()
+	changed := I.fm != F.fileManagement || I.seq != F.lastSeq()
+	F.mu.Unlock()
+
+	if changed {
+		I.read(true)
+	}
+}
+
+// finish - Ends the scan started when the iterator was created, if not already ended
+func (I *Iterator) finish() {
+	if I.done {
+		return
+	}
+	I.done = true
+
+	I.fileHashMap.mu.Lock()
+	defer I.fileHashMap.mu.Unlock()
+
+	I.fileHashMap.endScan()
+}
+
+// Key - Returns the key of the record the iterator is positioned at, or nil if not positioned at a record
+func (I *Iterator) Key() []byte {
+	return I.key
+}
+
+// Value - Returns the value of the record the iterator is positioned at, or nil if not positioned at a record
+func (I *Iterator) Value() []byte {
+	return I.value
+}
+
+// Err - Returns the error, if any, that stopped the iteration
+func (I *Iterator) Err() error {
+	return I.err
+}
+
+// ForEach - Calls fn for every record stored in the file hash map, see Iterator for the order of records.
+//   - fn is called with key and value of each record, returning stop as true or a non nil error ends the iteration
+//
+// It returns:
+//   - err is the error returned from fn, or a standard error if something went wrong while reading
+func (F *FileHashMap) ForEach(fn func(key, value []byte) (stop bool, err error)) (err error) {
+	var stop bool
+
+	iter := F.Iterator()
+	for iter.Next() {
+		stop, err = fn(iter.Key(), iter.Value())
+		if err != nil || stop {
+			iter.finish()
+			return
+		}
+	}
+
+	err = iter.Err()
+
+	return
+}
@@ -0,0 +1,137 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	t.Run("iterates all records for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("iterates records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 300)
+				values := make(map[string][]byte)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				err = fhm.SetBatch(records)
+				assert.NoError(t, err, "sets batch")
+
+				for i := 0; i < 300; i++ {
+					if i%3 == 0 {
+						_, err = fhm.Pop(records[i].Key)
+						assert.NoErrorf(t, err, "pops record #%d", i)
+					} else {
+						values[string(records[i].Key)] = records[i].Value
+					}
+				}
+
+				// Execute
+				seen := make(map[string]bool)
+				iter := fhm.Iterator()
+				for iter.Next() {
+					assert.Falsef(t, seen[string(iter.Key())], "key visited once")
+					seen[string(iter.Key())] = true
+					assert.True(t, utils.IsEqual(values[string(iter.Key())], iter.Value()), "correct value")
+				}
+
+				var count int
+				errForEach := fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					count++
+					return count == 10, nil
+				})
+
+				// Check
+				assert.NoError(t, iter.Err(), "iterates without error")
+				assert.Len(t, seen, len(values), "all occupied records visited")
+				assert.False(t, iter.Next(), "exhausted iterator stays exhausted")
+				assert.Nil(t, iter.Key(), "no key when exhausted")
+
+				assert.NoError(t, errForEach, "for each without error")
+				assert.Equal(t, 10, count, "for each stops when asked")
+
+				errForEach = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					return false, fmt.Errorf("stop")
+				})
+				assert.EqualError(t, errForEach, "stop", "error from fn is returned")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestForEach_ReadAhead(t *testing.T) {
+	t.Run("skips records popped while iterating for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("skips popped records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 100)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+
+				// Execute
+				var visited int
+				err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					visited++
+					for _, k := range keys {
+						if !utils.IsEqual(k, key) {
+							_, _ = fhm.Pop(k)
+						}
+					}
+					return
+				})
+
+				// Check
+				assert.NoError(t, err, "iterates records")
+				assert.Equal(t, 1, visited, "records popped by the first call are not visited")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage/openaddressing"
+)
+
+// NewMemoryHashMap - Returns a new hash map that is held entirely in byte slices in memory, without touching the
+// filesystem. It behaves as a hash map created by NewFileHashMap with the same parameters, which makes it suitable for
+// unit testing code that depends on FileHashMap, and for ephemeral maps. Only the open addressing CRTs
+// (LinearProbing, QuadraticProbing and DoubleHashing) are supported. Everything is lost when CloseFiles or RemoveFiles
+// is called, which leave all files alone. Features that need files of their own (EnableWAL, GetAsOf, EnableOperationLog,
+// EnableAutoGrow, EnableMemoryMapping, EnableWriteBuffer and reorganizing into or from memory) return an error.
+//   - crtType is the collision resolution technique to use, one of LinearProbing, QuadraticProbing or DoubleHashing
+//   - bucketsNeeded is the max number of buckets needed, but depending on hash algorithm it may result in a different number of actual available buckets.
+//   - recordsPerBucket is the number of records to hold in each bucket. Since minimum is one, setting this below one will still create one.
+//   - keyLength is the length of the key part in a record
+//   - valueLength is the length of the value part in a record
+//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the HashAlgorithm hashfunc.
+//
+// It returns:
+//   - fileHashMap is a pointer to a FileHashMap struct
+//   - hashMapInfo is a HashMapInfo struct containing some data regarding the hash map created.
+//   - err is a normal go Error which should be nil if everything went ok
+func NewMemoryHashMap(
+	crtType int,
+	bucketsNeeded int,
+	recordsPerBucket int,
+	keyLength int,
+	valueLength int,
+	hashAlgorithm hashfunc.HashAlgorithm,
+) (
+	fileHashMap *FileHashMap,
+	hashMapInfo HashMapInfo,
+	err error,
+) {
+
+	// Check choice of Collision Resolution Technique
+	if crtType != crt.LinearProbing && crtType != crt.QuadraticProbing && crtType != crt.DoubleHashing {
+		err = fmt.Errorf("crtType has to be one of LinearProbing, QuadraticProbing or DoubleHashing for a hash map held in memory")
+		return
+	}
+
+	err = checkDimensions(bucketsNeeded, keyLength, valueLength)
+	if err != nil {
+		return
+	}
+
+	// Check and correct recordsPerBucket
+	if recordsPerBucket < 1 {
+		recordsPerBucket = 1
+	}
+
+	hashAlgorithm, hashParameters, err := newHashParameters(hashAlgorithm)
+	if err != nil {
+		return
+	}
+
+	crtConf := model.CRTConf{
+		NumberOfBucketsNeeded:        int64(bucketsNeeded),
+		RecordsPerBucket:             int64(recordsPerBucket),
+		KeyLength:                    int64(keyLength),
+		ValueLength:                  int64(valueLength),
+		CollisionResolutionTechnique: crtType,
+		HashAlgorithm:                hashAlgorithm,
+		HashParameters:               hashParameters,
+	}
+
+	fm, err := openaddressing.NewOAFilesInMemory(crtConf)
+	if err != nil {
+		return
+	}
+
+	// Prepare return data, an empty name marks the hash map as held in memory
+	fileHashMap, hashMapInfo = newFileHashMap("", fm, options{})
+	fileHashMap.hashAlgorithm = hashAlgorithm
+
+	return
+}
+
+// requireFiles - Returns an error if the hash map is held in memory (see NewMemoryHashMap) or opened read-only (see
+// NewFromExistingFilesReadOnly), for operations that need files of their own to write to
+//   - operation is the name of the operation to mention in the error
+func (F *FileHashMap) requireFiles(operation string) (err error) {
+	if F.name == "" {
+		err = fmt.Errorf("%s is not supported for a hash map held in memory", operation)
+	} else if F.readOnly {
+		err = fmt.Errorf("%s is not supported for a hash map opened read-only", operation)
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestNewMemoryHashMap(t *testing.T) {
+	t.Run("sets, gets and pops records in memory for open addressing", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("sets, gets and pops records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, info, err := NewMemoryHashMap(test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new memory hash map")
+				assert.GreaterOrEqual(t, info.NumberOfBucketsAvailable, test.buckets, "buckets available")
+
+				keys := make([][]byte, 50)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], keys[i][:test.valueLength])
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+
+				// Execute
+				popped, errPop := fhm.Pop(keys[0])
+				hms, errStat := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, errPop, "pops record")
+				assert.True(t, utils.IsEqual(keys[0][:test.valueLength], popped), "popped value")
+				assert.NoError(t, errStat, "gets stat")
+				assert.Equal(t, len(keys)-1, hms.Records, "records left")
+				for i := 1; i < len(keys); i++ {
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(keys[i][:test.valueLength], value), "value of record #%d", i)
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes nothing")
+			})
+		}
+	})
+
+	t.Run("touches no files", func(t *testing.T) {
+		// Prepare
+		dir := t.TempDir()
+		wd, err := os.Getwd()
+		assert.NoError(t, err, "gets working directory")
+		err = os.Chdir(dir)
+		assert.NoError(t, err, "changes working directory")
+		defer func() { _ = os.Chdir(wd) }()
+
+		// Execute
+		fhm, _, err := NewMemoryHashMap(crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new memory hash map")
+		err = fhm.Set(make([]byte, 16), make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+		fhm.CloseFiles()
+
+		// Check
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err, "reads directory")
+		assert.Empty(t, entries, "no files created")
+	})
+
+	t.Run("removes no files", func(t *testing.T) {
+		// Prepare
+		dir := t.TempDir()
+		wd, err := os.Getwd()
+		assert.NoError(t, err, "gets working directory")
+		err = os.Chdir(dir)
+		assert.NoError(t, err, "changes working directory")
+		defer func() { _ = os.Chdir(wd) }()
+
+		fileNames := []string{storage.GetMapFileName(""), storage.GetLockFileName("")}
+		for _, fileName := range fileNames {
+			err = os.WriteFile(fileName, []byte{1}, 0644)
+			assert.NoErrorf(t, err, "writes file %s", fileName)
+		}
+
+		fhm, _, err := NewMemoryHashMap(crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new memory hash map")
+
+		// Execute
+		err = fhm.RemoveFiles()
+
+		// Check
+		assert.NoError(t, err, "removes nothing")
+		for _, fileName := range fileNames {
+			_, err = os.Stat(fileName)
+			assert.NoErrorf(t, err, "file %s kept", fileName)
+		}
+	})
+
+	t.Run("rejects unsupported CRTs and file based features", func(t *testing.T) {
+		// Prepare
+		_, _, errCRT := NewMemoryHashMap(crt.SeparateChaining, 10, 1, 16, 10, nil)
+		fhm, _, err := NewMemoryHashMap(crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new memory hash map")
+
+		// Execute
+		errWAL := fhm.EnableWAL()
+		errAutoGrow := fhm.EnableAutoGrow(0.8)
+		errMapping := fhm.EnableMemoryMapping()
+		_, errAsOf := fhm.GetAsOf(make([]byte, 16), 0)
+		_, _, errReorg := ReorgFiles("", ReorgConf{ReplaceInPlace: true}, true)
+		from, _, err := NewMemoryHashMap(crt.LinearProbing, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new memory hash map to reorganize")
+		_, errReorgRecords := reorgRecords(context.Background(), from, fhm, ReorgConf{Readers: 2}, 0, 10)
+
+		// Check
+		assert.Error(t, errCRT, "separate chaining not supported")
+		assert.Error(t, errWAL, "WAL not supported")
+		assert.Error(t, errAutoGrow, "auto grow not supported")
+		assert.Error(t, errMapping, "memory mapping not supported")
+		assert.Error(t, errAsOf, "reading as of a sequence number not supported")
+		assert.Error(t, errReorg, "reorganization not supported")
+		assert.Error(t, errReorgRecords, "reorganization into memory not supported")
+
+		// Clean up
+		fhm.CloseFiles()
+		from.CloseFiles()
+	})
+}
@@ -0,0 +1,76 @@
+package filehashmap
+
+import (
+	"fmt"
+)
+
+// memoryMapper - Implemented by file management that can map its map file into memory
+type memoryMapper interface {
+	MemoryMap(enabled bool) (err error)
+}
+
+// EnableMemoryMapping - Maps the map file into memory, so buckets are read and written by copying to and from the
+// mapping instead of through a read or write system call each (mmap on Unix, a file mapping object on Windows). This
+// mostly pays off for read heavy workloads on maps that fit in memory. It is only supported for the open addressing
+// CRTs (LinearProbing, QuadraticProbing and DoubleHashing), other CRTs return an error. The mapping is shared with the
+// file, so the files on disk stay valid and Sync, the sync policy and CloseFiles commit changes made through it.
+// Memory mapping is not persisted, so it has to be enabled each time the FileHashMap is opened, but it is kept when
+// the map grows automatically (see EnableAutoGrow). It can not be combined with the write buffer (see EnableWriteBuffer).
+//
+// It returns:
+//   - err is a standard error, if the CRT or platform doesn't support memory mapping, the write buffer is enabled or
+//     the file could not be mapped
+func (F *FileHashMap) EnableMemoryMapping() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("EnableMemoryMapping")
+	if err != nil {
+		return
+	}
+
+	if F.writeBuffer != nil {
+		err = fmt.Errorf("memory mapping can not be used while the write buffer is enabled")
+		return
+	}
+
+	mapper, ok := F.fileManagement.(memoryMapper)
+	if !ok {
+		err = fmt.Errorf("memory mapping is not supported by the collision resolution technique")
+		return
+	}
+
+	err = mapper.MemoryMap(true)
+	if err != nil {
+		err = fmt.Errorf("error while enabling memory mapping: %s", err)
+		return
+	}
+
+	F.memoryMapped = true
+
+	return
+}
+
+// DisableMemoryMapping - Releases any mapping made by EnableMemoryMapping, after which buckets are again read and
+// written through system calls.
+//
+// It returns:
+//   - err is a standard error, if the mapping could not be released
+func (F *FileHashMap) DisableMemoryMapping() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.memoryMapped = false
+
+	mapper, ok := F.fileManagement.(memoryMapper)
+	if !ok {
+		return
+	}
+
+	err = mapper.MemoryMap(false)
+	if err != nil {
+		err = fmt.Errorf("error while disabling memory mapping: %s", err)
+	}
+
+	return
+}
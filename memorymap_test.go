@@ -0,0 +1,110 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestEnableMemoryMapping(t *testing.T) {
+	t.Run("keeps records across memory mapping and reopen for open addressing", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("keeps records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				err = fhm.EnableMemoryMapping()
+				assert.NoError(t, err, "enables memory mapping")
+
+				keys := make([][]byte, 50)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					value := make([]byte, test.valueLength)
+					copy(value, keys[i])
+					err = fhm.Set(keys[i], value)
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+
+				// Execute
+				_, err = fhm.Pop(keys[0])
+				assert.NoError(t, err, "pops record")
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "reopens file hash map")
+
+				// Check
+				_, err = fhm.Get(keys[0])
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record is gone")
+				for i := 1; i < len(keys); i++ {
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(keys[i][:test.valueLength], value), "value of record #%d", i)
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("clears memory mapped files", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		err = fhm.EnableMemoryMapping()
+		assert.NoError(t, err, "enables memory mapping")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+
+		// Execute
+		errClear := fhm.Clear()
+		_, errGet := fhm.Get(key)
+		errSet := fhm.Set(key, make([]byte, 10))
+
+		// Check
+		assert.NoError(t, errClear, "clears files")
+		assert.ErrorIs(t, errGet, crt.NoRecordFound{}, "record is gone")
+		assert.NoError(t, errSet, "sets record after clear")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails for other CRTs", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errEnable := fhm.EnableMemoryMapping()
+		errDisable := fhm.DisableMemoryMapping()
+
+		// Check
+		assert.Error(t, errEnable, "memory mapping not supported")
+		assert.NoError(t, errDisable, "disabling is always possible")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,122 @@
+package filehashmap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+)
+
+// ConflictPolicy - Decides the value to keep when MergeFiles finds a key in both file hash maps, see KeepDestination
+// and OverwriteDestination for the common choices
+//   - key is the key found in both file hash maps
+//   - destValue is the value stored in the destination file hash map
+//   - srcValue is the value stored in the source file hash map
+//
+// It returns:
+//   - value is the value to store in the destination file hash map, of the value length of it
+//   - err is returned from MergeFiles, stopping the merge
+type ConflictPolicy func(key, destValue, srcValue []byte) (value []byte, err error)
+
+// KeepDestination - Is a ConflictPolicy keeping the value of the destination file hash map
+func KeepDestination(key, destValue, srcValue []byte) (value []byte, err error) {
+	value = destValue
+
+	return
+}
+
+// OverwriteDestination - Is a ConflictPolicy overwriting the value of the destination file hash map with the value of
+// the source file hash map
+func OverwriteDestination(key, destValue, srcValue []byte) (value []byte, err error) {
+	value = srcValue
+
+	return
+}
+
+// MergeStats - Tells what MergeFiles did with the records of the source file hash map
+//   - Added is the number of records whose key was not in the destination file hash map
+//   - Overwritten is the number of records whose key was in the destination and that got a new value from the policy
+//   - Kept is the number of records whose key was in the destination and that kept its value
+type MergeStats struct {
+	Added       int64
+	Overwritten int64
+	Kept        int64
+}
+
+// MergeFiles - Streams all records of the source file hash map into the destination file hash map. Keys found in both
+// are handled by conflictPolicy. Both file hash maps must have the same key and value lengths, and either both or none
+// use variable length keys, while CRT and number of buckets may differ. The source files are opened read-only and are
+// left untouched. Records are set in the destination as if new, so e.g. a TTL of the source records is not kept. Both
+// file hash maps are opened with the internal hash algorithm, so a destination created with a custom hash algorithm
+// can not be merged into.
+//   - destName is the name of an existing file hash map to merge into (including correct path)
+//   - srcName is the name of an existing file hash map to merge from (including correct path)
+//   - conflictPolicy decides the value of keys found in both, e.g. KeepDestination, OverwriteDestination or a
+//     callback of your own, nil means KeepDestination
+//   - options is any optional settings, such as WithFileSystem, which apply to both file hash maps
+//
+// It returns:
+//   - stats is what was done with the records of the source file hash map, also when an error stopped the merge
+//   - err is a standard error, if the file hash maps could not be opened or don't match, or an error from conflictPolicy
+func MergeFiles(destName, srcName string, conflictPolicy ConflictPolicy, options ...Option) (stats MergeStats, err error) {
+	if conflictPolicy == nil {
+		conflictPolicy = KeepDestination
+	}
+
+	src, _, err := NewFromExistingFilesReadOnly(srcName, nil, options...)
+	if err != nil {
+		err = fmt.Errorf("error while opening source files: %s", err)
+		return
+	}
+	defer src.CloseFiles()
+
+	dest, _, err := NewFromExistingFiles(destName, nil, options...)
+	if err != nil {
+		err = fmt.Errorf("error while opening destination files: %s", err)
+		return
+	}
+	defer dest.CloseFiles()
+
+	srcSp := src.fileManagement.GetStorageParameters()
+	destSp := dest.fileManagement.GetStorageParameters()
+	if (src.keyFile != nil) != (dest.keyFile != nil) {
+		err = fmt.Errorf("both or none of the file hash maps must have variable length keys")
+		return
+	}
+	if srcSp.KeyLength != destSp.KeyLength || src.userValueLength() != dest.userValueLength() {
+		err = fmt.Errorf("key and value lengths of the source file hash map don't match those of the destination")
+		return
+	}
+
+	err = src.ForEach(func(key, srcValue []byte) (stop bool, err error) {
+		destValue, err := dest.Get(key)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = dest.Set(key, srcValue)
+			if err == nil {
+				stats.Added++
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		value, err := conflictPolicy(key, destValue, srcValue)
+		if err != nil {
+			return
+		}
+		if bytes.Equal(value, destValue) {
+			stats.Kept++
+			return
+		}
+
+		err = dest.Set(key, value)
+		if err == nil {
+			stats.Overwritten++
+		}
+
+		return
+	})
+
+	return
+}
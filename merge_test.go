@@ -0,0 +1,142 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newMergeTestMaps(t *testing.T, destName, srcName string) {
+	dest, _, err := NewFileHashMap(destName, crt.LinearProbing, 50, 2, 4, 4, nil)
+	assert.NoError(t, err, "create destination file hash map")
+	src, _, err := NewFileHashMap(srcName, crt.SeparateChaining, 10, 1, 4, 4, nil)
+	assert.NoError(t, err, "create source file hash map")
+
+	for i := 0; i < 20; i++ {
+		err = dest.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+		assert.NoErrorf(t, err, "sets destination record #%d", i)
+	}
+	for i := 10; i < 30; i++ {
+		err = src.Set([]byte{0, 0, 0, byte(i)}, []byte{2, 2, 2, byte(i)})
+		assert.NoErrorf(t, err, "sets source record #%d", i)
+	}
+
+	dest.CloseFiles()
+	src.CloseFiles()
+}
+
+func TestMergeFiles(t *testing.T) {
+	destName := fmt.Sprintf("%s-dest", testHashMap)
+	srcName := fmt.Sprintf("%s-src", testHashMap)
+
+	tests := []struct {
+		policyName  string
+		policy      ConflictPolicy
+		stats       MergeStats
+		conflictTag byte
+	}{
+		{policyName: "nil", policy: nil, stats: MergeStats{Added: 10, Kept: 10}, conflictTag: 1},
+		{policyName: "KeepDestination", policy: KeepDestination, stats: MergeStats{Added: 10, Kept: 10}, conflictTag: 1},
+		{policyName: "OverwriteDestination", policy: OverwriteDestination, stats: MergeStats{Added: 10, Overwritten: 10}, conflictTag: 2},
+		{
+			policyName: "callback",
+			policy: func(key, destValue, srcValue []byte) ([]byte, error) {
+				return []byte{3, 3, 3, key[3]}, nil
+			},
+			stats:       MergeStats{Added: 10, Overwritten: 10},
+			conflictTag: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("merges records with %s policy", test.policyName), func(t *testing.T) {
+			// Prepare
+			newMergeTestMaps(t, destName, srcName)
+
+			// Execute
+			stats, err := MergeFiles(destName, srcName, test.policy)
+
+			// Check
+			assert.NoError(t, err, "merge files")
+			assert.Equal(t, test.stats, stats, "merge stats")
+
+			dest, _, err := NewFromExistingFiles(destName, nil)
+			assert.NoError(t, err, "open destination files")
+			count, err := dest.Count()
+			assert.NoError(t, err, "count destination records")
+			assert.Equal(t, int64(30), count, "all keys in destination")
+			for i := 0; i < 30; i++ {
+				value, err := dest.Get([]byte{0, 0, 0, byte(i)})
+				assert.NoErrorf(t, err, "get record #%d", i)
+				tag := test.conflictTag
+				if i < 10 {
+					tag = 1
+				} else if i >= 20 {
+					tag = 2
+				}
+				assert.Equalf(t, []byte{tag, tag, tag, byte(i)}, value, "value of record #%d", i)
+			}
+
+			// Clean up
+			err = dest.RemoveFiles()
+			assert.NoError(t, err, "destination files can be removed")
+			src, _, err := NewFromExistingFiles(srcName, nil)
+			assert.NoError(t, err, "open source files")
+			err = src.RemoveFiles()
+			assert.NoError(t, err, "source files can be removed")
+		})
+	}
+
+	t.Run("stops on policy error", func(t *testing.T) {
+		// Prepare
+		newMergeTestMaps(t, destName, srcName)
+		policy := func(key, destValue, srcValue []byte) ([]byte, error) {
+			return nil, fmt.Errorf("conflict on %v", key)
+		}
+
+		// Execute
+		_, err := MergeFiles(destName, srcName, policy)
+
+		// Check
+		assert.ErrorContains(t, err, "conflict on", "policy error returned")
+
+		// Clean up
+		dest, _, err := NewFromExistingFiles(destName, nil)
+		assert.NoError(t, err, "open destination files")
+		err = dest.RemoveFiles()
+		assert.NoError(t, err, "destination files can be removed")
+		src, _, err := NewFromExistingFiles(srcName, nil)
+		assert.NoError(t, err, "open source files")
+		err = src.RemoveFiles()
+		assert.NoError(t, err, "source files can be removed")
+	})
+
+	t.Run("rejects maps of different lengths", func(t *testing.T) {
+		// Prepare
+		dest, _, err := NewFileHashMap(destName, crt.LinearProbing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create destination file hash map")
+		dest.CloseFiles()
+		src, _, err := NewFileHashMap(srcName, crt.LinearProbing, 10, 1, 4, 8, nil)
+		assert.NoError(t, err, "create source file hash map")
+		src.CloseFiles()
+
+		// Execute
+		_, err = MergeFiles(destName, srcName, nil)
+
+		// Check
+		assert.Error(t, err, "lengths don't match")
+
+		// Clean up
+		dest, _, err = NewFromExistingFiles(destName, nil)
+		assert.NoError(t, err, "open destination files")
+		err = dest.RemoveFiles()
+		assert.NoError(t, err, "destination files can be removed")
+		src, _, err = NewFromExistingFiles(srcName, nil)
+		assert.NoError(t, err, "open source files")
+		err = src.RemoveFiles()
+		assert.NoError(t, err, "source files can be removed")
+	})
+}
@@ -0,0 +1,122 @@
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+)
+
+// Metrics - Receiver of metrics from a file hash map, e.g. to be exposed as Prometheus counters and gauges or through
+// expvar, see SetMetrics. Methods are called synchronously from the operations being counted, so they have to be
+// quick, and they must be safe for concurrent use if the same receiver is set on several file hash maps.
+type Metrics interface {
+	// AddGets - Is called with the number of keys looked up, as counted in OperationStats.Gets
+	AddGets(n int64)
+	// AddSets - Is called with the number of records set, as counted in OperationStats.Sets
+	AddSets(n int64)
+	// AddMisses - Is called with the number of keys looked up but not found, as counted in OperationStats.GetMisses
+	AddMisses(n int64)
+	// AddProbeIterations - Is called with the number of buckets read while looking for a key, which with Separate
+	// Chaining, Hybrid and Linear Hashing also includes the overflow records walked
+	AddProbeIterations(n int64)
+	// AddOverflowAppends - Is called with the number of records added to overflow chains
+	AddOverflowAppends(n int64)
+	// AddBytesRead - Is called with the number of bytes read from the map file or the overflow file
+	AddBytesRead(n int64)
+	// AddBytesWritten - Is called with the number of bytes written to the map file or the overflow file
+	AddBytesWritten(n int64)
+	// AddRetries - Is called with the number of operations on the map file or the overflow file retried after a
+	// transient error, see WithRetryPolicy
+	AddRetries(n int64)
+	// SetLoadFactor - Is called with the load factor, see LoadFactor, when metrics are set and after each mutation
+	SetLoadFactor(loadFactor float64)
+}
+
+// metricsReceiver - Holds a Metrics so that it can be kept in an atomic.Pointer
+type metricsReceiver struct {
+	Metrics
+}
+
+// metricsSetter - Implemented by file management that counts probes and bytes
+type metricsSetter interface {
+	SetMetrics(metrics model.Metrics)
+}
+
+// setStorageMetrics - Sets the receiver of the counters of fm, nil turns counting off. It is a no-op if fm doesn't
+// count.
+func setStorageMetrics(fm FileManagement, metrics model.Metrics) {
+	if setter, ok := fm.(metricsSetter); ok {
+		setter.SetMetrics(metrics)
+	}
+}
+
+// SetMetrics - Sets a receiver of metrics counted from now on, which can be wired into e.g. Prometheus or expvar.
+// Gets, sets and misses are counted as in OperationStats, while probe iterations, overflow appends, bytes read and
+// written and retries of file operations are counted by the storage of the collision resolution technique in use. Reads from buckets cached in memory
+// (see EnableBucketCache) or through a memory mapping (see EnableMemoryMapping) are not counted as bytes read. The load
+// factor is reported right away, which counts the records if not already counted (see Count).
+//   - metrics is the receiver, nil turns off metrics
+func (F *FileHashMap) SetMetrics(metrics Metrics) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.opStats.setMetrics(metrics)
+	setStorageMetrics(F.fileManagement, F.storageMetrics())
+	F.reportLoadFactor()
+}
+
+// reportLoadFactor - Reports the load factor to the receiver of metrics, if any, see SetMetrics
+func (F *FileHashMap) reportLoadFactor() {
+	metrics := F.opStats.metrics()
+	if metrics == nil {
+		return
+	}
+
+	occupied, _, err := countRecords(F.fileManagement)
+	if err != nil {
+		return
+	}
+
+	metrics.SetLoadFactor(float64(occupied) / float64(F.capacity()))
+}
+
+// setMetrics - Sets the receiver of metrics that counts are forwarded to, nil turns off forwarding
+func (C *opCounters) setMetrics(metrics Metrics) {
+	if metrics == nil {
+		C.receiver.Store(nil)
+		return
+	}
+
+	C.receiver.Store(&metricsReceiver{Metrics: metrics})
+}
+
+// metrics - Returns the receiver of metrics, or nil if there is none
+func (C *opCounters) metrics() Metrics {
+	if r := C.receiver.Load(); r != nil {
+		return r.Metrics
+	}
+
+	return nil
+}
+
+// addGets - Counts n gets, also in the receiver of metrics if any
+func (C *opCounters) addGets(n int64) {
+	C.gets.Add(n)
+	if m := C.metrics(); m != nil {
+		m.AddGets(n)
+	}
+}
+
+// addSets - Counts n sets, also in the receiver of metrics if any
+func (C *opCounters) addSets(n int64) {
+	C.sets.Add(n)
+	if m := C.metrics(); m != nil {
+		m.AddSets(n)
+	}
+}
+
+// addMisses - Counts n get misses, also in the receiver of metrics if any
+func (C *opCounters) addMisses(n int64) {
+	C.getMisses.Add(n)
+	if m := C.metrics(); m != nil {
+		m.AddMisses(n)
+	}
+}
@@ -0,0 +1,110 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+// metricsRecorder - Is a Metrics that records what it is given
+type metricsRecorder struct {
+	gets            atomic.Int64
+	sets            atomic.Int64
+	misses          atomic.Int64
+	probeIterations atomic.Int64
+	overflowAppends atomic.Int64
+	bytesRead       atomic.Int64
+	bytesWritten    atomic.Int64
+	retries         atomic.Int64
+	loadFactor      atomic.Value
+}
+
+func (m *metricsRecorder) AddGets(n int64)            { m.gets.Add(n) }
+func (m *metricsRecorder) AddSets(n int64)            { m.sets.Add(n) }
+func (m *metricsRecorder) AddMisses(n int64)          { m.misses.Add(n) }
+func (m *metricsRecorder) AddProbeIterations(n int64) { m.probeIterations.Add(n) }
+func (m *metricsRecorder) AddOverflowAppends(n int64) { m.overflowAppends.Add(n) }
+func (m *metricsRecorder) AddBytesRead(n int64)       { m.bytesRead.Add(n) }
+func (m *metricsRecorder) AddBytesWritten(n int64)    { m.bytesWritten.Add(n) }
+func (m *metricsRecorder) AddRetries(n int64)         { m.retries.Add(n) }
+func (m *metricsRecorder) SetLoadFactor(l float64)    { m.loadFactor.Store(l) }
+
+func TestSetMetrics(t *testing.T) {
+	t.Run("metrics tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("forwards metrics for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				metrics := &metricsRecorder{}
+				keys := make([][]byte, 100)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+				}
+
+				// Execute
+				fhm.SetMetrics(metrics)
+				for i := range keys {
+					err = fhm.Set(keys[i], make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+				for i := range keys {
+					_, err = fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+				}
+				for i := 0; i < 10; i++ {
+					key := make([]byte, test.keyLength)
+					rand.Read(key)
+					_, err = fhm.Get(key)
+					assert.ErrorIsf(t, err, crt.NoRecordFound{}, "misses record #%d", i)
+				}
+
+				// Check
+				assert.Equal(t, int64(110), metrics.gets.Load(), "gets")
+				assert.Equal(t, int64(100), metrics.sets.Load(), "sets")
+				assert.Equal(t, int64(10), metrics.misses.Load(), "misses")
+				assert.GreaterOrEqual(t, metrics.probeIterations.Load(), int64(210), "at least one probe per operation")
+				assert.NotZero(t, metrics.bytesRead.Load(), "bytes read")
+				assert.NotZero(t, metrics.bytesWritten.Load(), "bytes written")
+				if test.crt == crt.SeparateChaining || test.crt == crt.Hybrid || test.crt == crt.LinearHashing {
+					assert.NotZero(t, metrics.overflowAppends.Load(), "records appended to overflow")
+				} else {
+					assert.Zero(t, metrics.overflowAppends.Load(), "no overflow")
+				}
+
+				loadFactor, err := fhm.LoadFactor()
+				assert.NoError(t, err, "gets load factor")
+				assert.Equal(t, loadFactor, metrics.loadFactor.Load(), "load factor gauge")
+
+				fhm.SetMetrics(nil)
+				_, err = fhm.Get(keys[0])
+				assert.NoError(t, err, "gets record after metrics are turned off")
+				assert.Equal(t, int64(110), metrics.gets.Load(), "no more gets are forwarded")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package filehashmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"os"
+)
+
+// Migrate - Upgrades the header of the map file of a file hash map in place to the current format version, if it was
+// written in an older one by a previous release, i.e. in the legacy format version 1 layout only. Files in an older
+// format version can still be opened and are upgraded whenever the header is written, but files that are only opened
+// read-only keep the older layout. Migrate makes the upgrade explicit, so the files can still be opened by a later
+// release that drops reading of older layouts. Records and the system area of the header are left untouched.
+// Opening a file that is not a map file, or of a format version newer than this release can read, fails with an error
+// of type crt.UnknownFormat, and so does Migrate.
+//   - name is the name of an existing file hash map (including correct path), which must not be open
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - fromVersion is the format version the header was in
+//   - migrated is true if the header was upgraded, false if it already was in the current format version
+//   - err is a standard error, or of type crt.UnknownFormat (see above)
+func Migrate(name string, options ...Option) (fromVersion int, migrated bool, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+
+	lock, err := lockFiles(name, false, o)
+	if err != nil {
+		return
+	}
+	if lock != nil {
+		defer func() { _ = lock.Unlock() }()
+	}
+
+	mapFileName := storage.GetMapFileName(name)
+	file, err := o.fileSystem().OpenFile(mapFileName, os.O_RDWR, 0644)
+	if err != nil {
+		err = fmt.Errorf("error while opening map file: %s", err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	version, migrated, err := storage.MigrateHeader(file)
+	fromVersion = int(version)
+	var unknownFormat crt.UnknownFormat
+	if errors.As(err, &unknownFormat) {
+		unknownFormat.FileName = mapFileName
+		err = unknownFormat
+	} else if err != nil {
+		err = fmt.Errorf("error while migrating map file header: %s", err)
+	}
+
+	return
+}
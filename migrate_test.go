@@ -0,0 +1,86 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("migrates legacy header", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte{0, 0, 0, 1}, []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "set record")
+		fhm.CloseFiles()
+
+		file, err := os.OpenFile(fmt.Sprintf("%s-map.bin", testHashMap), os.O_RDWR, 0644)
+		assert.NoError(t, err, "open map file")
+		_, err = file.WriteAt(make([]byte, 256), 256)
+		assert.NoError(t, err, "remove current format version block")
+		err = file.Close()
+		assert.NoError(t, err, "close map file")
+
+		header, err := InspectFiles(testHashMap)
+		assert.NoError(t, err, "inspect legacy files")
+		assert.Equal(t, 1, header.FormatVersion, "legacy format version")
+
+		// Execute
+		fromVersion, migrated, err := Migrate(testHashMap)
+
+		// Check
+		assert.NoError(t, err, "migrate")
+		assert.Equal(t, 1, fromVersion, "migrated from legacy format version")
+		assert.True(t, migrated, "migrated")
+
+		header, err = InspectFiles(testHashMap)
+		assert.NoError(t, err, "inspect migrated files")
+		assert.Equal(t, 2, header.FormatVersion, "current format version")
+
+		fromVersion, migrated, err = Migrate(testHashMap)
+		assert.NoError(t, err, "migrate again")
+		assert.Equal(t, 2, fromVersion, "already current format version")
+		assert.False(t, migrated, "nothing to migrate")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open migrated files")
+		value, err := fhm.Get([]byte{0, 0, 0, 1})
+		assert.NoError(t, err, "get record")
+		assert.Equal(t, []byte{1, 1, 1, 1}, value, "record kept")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for files that are not map files", func(t *testing.T) {
+		// Prepare
+		mapFileName := fmt.Sprintf("%s-map.bin", testHashMap)
+
+		for _, content := range [][]byte{bytes.Repeat([]byte{0xff}, 4096), []byte("too short")} {
+			err := os.WriteFile(mapFileName, content, 0644)
+			assert.NoError(t, err, "write file")
+
+			// Execute
+			_, _, errOpen := NewFromExistingFiles(testHashMap, nil)
+			_, errInspect := InspectFiles(testHashMap)
+			_, _, errMigrate := Migrate(testHashMap)
+
+			// Check
+			assert.ErrorIs(t, errOpen, crt.UnknownFormat{}, "open fails")
+			assert.ErrorIs(t, errInspect, crt.UnknownFormat{}, "inspect fails")
+			assert.ErrorIs(t, errMigrate, crt.UnknownFormat{}, "migrate fails")
+			assert.Contains(t, errOpen.Error(), mapFileName, "file name in error")
+		}
+
+		// Clean up
+		err := os.Remove(mapFileName)
+		assert.NoError(t, err, "remove file")
+	})
+}
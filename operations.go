@@ -1,8 +1,18 @@
 package filehashmap
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/internal/model"
 	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/gostonefire/filehashmap/internal/wal"
+	"io"
+	"time"
 )
 
 // Get - Gets record that corresponds to the given recordId.
@@ -12,103 +22,1897 @@ import (
 //   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
 //   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
 func (F *FileHashMap) Get(key []byte) (value []byte, err error) {
-	record, err := F.fileManagement.Get(model.Record{Key: key})
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	value, err = F.get(key)
+
+	return
+}
+
+// get - Is the implementation of Get, to be called with the lock held
+func (F *FileHashMap) get(key []byte) (value []byte, err error) {
+	defer F.watch("Get")()
+	defer F.trace("Get", key)(&err)
+
+	F.opStats.addGets(1)
+	record, err := F.lookup(key)
+	if err != nil {
+		if errors.Is(err, crt.NoRecordFound{}) {
+			F.opStats.addMisses(1)
+		}
+		F.opStats.countError(err)
+		return
+	}
+
+	value = F.fromStoredValue(record.Value)
+
+	err = F.countAccess(record)
+	F.opStats.countError(err)
+
+	return
+}
+
+// GetInto - Gets value given a key and copies it into a buffer supplied by the caller, which saves allocating a new
+// value on each call when reading in tight loops. It is otherwise the same as Get.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - buf is the buffer to copy the value into, it has to be at least as long as values are
+//
+// It returns:
+//   - n is the number of bytes copied into buf
+//   - err is either of type crt.NoRecordFound, io.ErrShortBuffer if buf is too short for values or a standard error
+//     if something went wrong
+func (F *FileHashMap) GetInto(key []byte, buf []byte) (n int, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("GetInto")()
+
+	F.opStats.addGets(1)
+	if len(buf) < F.userValueLength() {
+		err = io.ErrShortBuffer
+		F.opStats.countError(err)
+		return
+	}
+
+	record, err := F.lookup(key)
+	if err != nil {
+		if errors.Is(err, crt.NoRecordFound{}) {
+			F.opStats.addMisses(1)
+		}
+		F.opStats.countError(err)
+		return
+	}
+
+	n = copy(buf, F.fromStoredValue(record.Value))
+
+	err = F.countAccess(record)
+	F.opStats.countError(err)
+
+	return
+}
+
+// Exists - Checks whether a record with the given key exists without reading its value. Only the state and key of
+// each probed record are read where the collision resolution technique allows it, which makes it cheaper than Get
+// when only membership matters. Maps with variable length keys or checksums still read the full record since the
+// value is needed to verify the key or the checksum. An Exists is counted as a get but not as an access to the record.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - exists is true if a record with the key was found
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) Exists(key []byte) (exists bool, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("Exists")()
+
+	F.opStats.addGets(1)
+	defer func() {
+		if err == nil && !exists {
+			F.opStats.addMisses(1)
+		}
+		F.opStats.countError(err)
+	}()
+
+	if F.keyFile != nil || F.checksums {
+		_, err = F.lookup(key)
+		exists = err == nil
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		return
+	}
+
+	if F.definitelyMissing(key) {
+		return
+	}
+
+	exists, err = recordExists(F.fileManagement, model.Record{Key: key})
+
+	return
+}
+
+// countAccess - Counts an access to record if access counting is enabled (see EnableAccessCounting), flushing the
+// pending counts to file when the flush threshold is reached
+func (F *FileHashMap) countAccess(record model.Record) (err error) {
+	if F.accessCounter == nil {
+		return
+	}
+
+	F.accessCounter.pending[recordPosition{isOverflow: record.IsOverflow, recordAddress: record.RecordAddress}]++
+	if len(F.accessCounter.pending) >= F.accessCounter.flushThreshold {
+		err = F.flushAccessCounts()
+	}
+
+	return
+}
+
+// GetBatch - Gets values for many keys in one call. Keys are processed in bucket order and buckets are read only
+// once each, which reduces random reads compared to calling Get for each key.
+//   - keys is the identifiers of records, they have to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - values is the values in the same order as keys, with nil for keys that were not found
+//   - errs is per key errors in the same order as keys, nil if found or an error of type crt.NoRecordFound if not
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) GetBatch(keys [][]byte) (values [][]byte, errs []error, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("GetBatch")()
+
+	F.opStats.addGets(int64(len(keys)))
+	defer func() { F.opStats.countError(err) }()
+
+	// Keys that the Bloom filter, if any, tells are missing are not looked up in the files
+	keyRecords := make([]model.Record, 0, len(keys))
+	candidates := make([]int, 0, len(keys))
+	for i, key := range keys {
+		storedKey := F.toStoredKey(key)
+		if F.definitelyMissing(storedKey) {
+			continue
+		}
+		keyRecords = append(keyRecords, model.Record{Key: storedKey})
+		candidates = append(candidates, i)
+	}
+
+	found, err := getRecords(F.fileManagement, keyRecords)
+	if err != nil {
+		return
+	}
+
+	records := make([]model.Record, len(keys))
+	for j, i := range candidates {
+		records[i] = found[j]
+	}
+
+	values = make([][]byte, len(keys))
+	errs = make([]error, len(keys))
+	for i, record := range records {
+		if record.State == model.RecordOccupied {
+			if errs[i] = F.checkRecord(record); errs[i] != nil {
+				continue
+			}
+		}
+		if record.State == model.RecordOccupied && F.keyFile != nil {
+			err = F.verifyKey(record, keys[i])
+			if errors.Is(err, crt.NoRecordFound{}) {
+				record.State, err = model.RecordEmpty, nil
+			}
+			if err != nil {
+				return
+			}
+		}
+		if record.State != model.RecordOccupied {
+			errs[i] = crt.NoRecordFound{}
+			F.opStats.addMisses(1)
+			continue
+		}
+
+		values[i] = F.fromStoredValue(record.Value)
+		if F.accessCounter != nil {
+			F.accessCounter.pending[recordPosition{isOverflow: record.IsOverflow, recordAddress: record.RecordAddress}]++
+		}
+	}
+
+	if F.accessCounter != nil && len(F.accessCounter.pending) >= F.accessCounter.flushThreshold {
+		err = F.flushAccessCounts()
+	}
+
+	return
+}
+
+// Set - Updates an existing record with new data or add it if no existing is found with same key.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) Set(key []byte, value []byte) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.set(key, value, 0)
+
+	return
+}
+
+// set - Is the implementation of Set, to be called with the lock held. The expiry is only stored if TTL is supported,
+// see FeatureTTL, where zero means that the record never expires.
+func (F *FileHashMap) set(key []byte, value []byte, expiry int64) (err error) {
+	defer F.watch("Set")()
+	defer F.trace("Set", key)(&err)
+	F.opStats.addSets(1)
+	defer func() { F.opStats.countError(err) }()
+
+	err = F.validateValue(key, value)
+	if err != nil {
+		return
+	}
+
+	key, value, err = F.toStoredKeyValue(key, value)
+	if err != nil {
+		return
+	}
+
+	value, err = F.toStoredValue(key, value, expiry)
+	if err != nil {
+		return
+	}
+
+	value, err = F.keepCreated(key, value)
+	if err != nil {
+		return
+	}
+
+	if F.wal != nil {
+		err = F.logSet(key, value)
+		if err != nil {
+			return
+		}
+		// A logged set is applied when replaying the log, so it must not be interrupted from here on, see SetCtx
+		setInterrupt(F.fileManagement, nil)
+	}
+
+	err = F.setEvicting(key, value)
+
+	return
+}
+
+// setEvicting - Sets a record in the files, growing if auto grow is enabled (see EnableAutoGrow) or else evicting
+// another record first if the map file is full and eviction is enabled, and then advances the sequence number
+func (F *FileHashMap) setEvicting(key, value []byte) (err error) {
+	F.addToBloomFilter(key)
+	if F.autoGrow != nil {
+		err = F.setGrowing(key, value)
+	} else {
+		err = F.fileManagement.Set(model.Record{Key: key, Value: value})
+	}
+	if errors.Is(err, crt.MapFileFull{}) && F.evictionHand != nil {
+		err = F.evict()
+		if err != nil {
+			return
+		}
+		err = F.fileManagement.Set(model.Record{Key: key, Value: value})
+	}
+	if err != nil {
+		return
+	}
+
+	_, err = F.advanceSeq(1)
+
+	return
+}
+
+// GetOrSet - Returns the value of an existing record with the same key, or else sets the record, which is the
+// equivalent of LoadOrStore in sync.Map. The existing record is looked for while probing for where to set the record,
+// so buckets are searched only once. If the WAL, automatic growing or variable length keys are enabled, the existing
+// record is instead looked up before setting the record, as Set does in those cases anyway. A record set never expires.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to set if no record with key exists, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - existing is the value of the existing record if loaded is true, otherwise nil
+//   - loaded is true if a record with key already existed, in which case nothing was set
+//   - err is a standard error, if something went wrong. If the checksum of the existing record doesn't match (see
+//     FeatureChecksums) the error is of type crt.CorruptRecord.
+func (F *FileHashMap) GetOrSet(key []byte, value []byte) (existing []byte, loaded bool, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("GetOrSet")()
+	defer F.trace("Set", key)(&err)
+
+	F.opStats.addGets(1)
+	if F.wal != nil || F.autoGrow != nil || F.keyFile != nil {
+		existing, loaded, err = F.lookupOrSet(key, value)
+		return
+	}
+
+	defer func() { F.opStats.countError(err) }()
+
+	err = F.validateValue(key, value)
+	if err != nil {
+		return
+	}
+
+	storedValue, err := F.toStoredValue(key, value, 0)
+	if err != nil {
+		return
+	}
+
+	F.addToBloomFilter(key)
+	record, loaded, err := getOrSetRecord(F.fileManagement, model.Record{Key: key, Value: storedValue})
+	if errors.Is(err, crt.MapFileFull{}) && F.evictionHand != nil {
+		err = F.evict()
+		if err != nil {
+			return
+		}
+		record, loaded, err = getOrSetRecord(F.fileManagement, model.Record{Key: key, Value: storedValue})
+	}
+	if err != nil {
+		return
+	}
+
+	if loaded {
+		err = F.checkRecord(record)
+		if err != nil {
+			return
+		}
+		existing = F.fromStoredValue(record.Value)
+		err = F.countAccess(record)
+		return
+	}
+
+	F.opStats.addMisses(1)
+	F.opStats.addSets(1)
+	_, err = F.advanceSeq(1)
+
+	return
+}
+
+// SetIfAbsent - Sets the record only if no record with the same key exists, returning the value of the existing
+// record otherwise, the equivalent of SETNX in Redis. It is GetOrSet seen from the setting side, so the key is probed
+// for only once and no other operation can get in between the check and the set, as it can when calling Get followed
+// by Set.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to set if no record with key exists, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - existing is the value of the existing record if stored is false, otherwise nil
+//   - stored is true if the record was set, false if a record with key already existed
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) SetIfAbsent(key []byte, value []byte) (existing []byte, stored bool, err error) {
+	existing, loaded, err := F.GetOrSet(key, value)
+	stored = err == nil && !loaded
+
+	return
+}
+
+// lookupOrSet - Is the implementation of GetOrSet that looks up the existing record before setting the record, to be
+// called with the lock held
+func (F *FileHashMap) lookupOrSet(key []byte, value []byte) (existing []byte, loaded bool, err error) {
+	record, err := F.lookup(key)
+	if err == nil {
+		existing, loaded = F.fromStoredValue(record.Value), true
+		err = F.countAccess(record)
+		F.opStats.countError(err)
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		F.opStats.countError(err)
+		return
+	}
+
+	// Errors from here on are counted by set
+	F.opStats.addMisses(1)
+	err = F.set(key, value, 0)
+
+	return
+}
+
+// Swap - Exchanges the values of the records corresponding to keyA and keyB in one logical operation, so no other
+// operation can see or change either record in between. Each record keeps its own expiry time if TTL is supported.
+// If the WAL is enabled, both changes are written to it as one group of entries, so after a crash either both or none
+// of them are applied when the WAL is enabled again. Each change is given a sequence number.
+//   - keyA is the identifier of the first record, it has to be of same length as given in call to NewFileHashMap
+//   - keyB is the identifier of the second record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - err is either of type crt.NoRecordFound if either record doesn't exist, or a standard error, if something went
+//     wrong. If the value validator rejects a value for the other key (see SetValueValidator) nothing is changed.
+func (F *FileHashMap) Swap(keyA, keyB []byte) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("Swap")()
+
+	F.opStats.addSets(2)
+	defer func() { F.opStats.countError(err) }()
+
+	recordA, err := F.lookup(keyA)
+	if err != nil {
+		return
+	}
+	recordB, err := F.lookup(keyB)
+	if err != nil {
+		return
+	}
+
+	// Swapping a record with itself changes nothing
+	if recordA.IsOverflow == recordB.IsOverflow && recordA.RecordAddress == recordB.RecordAddress {
+		return
+	}
+
+	valueA, valueB := F.fromStoredValue(recordA.Value), F.fromStoredValue(recordB.Value)
+	err = F.validateValue(keyA, valueB)
+	if err != nil {
+		return
+	}
+	err = F.validateValue(keyB, valueA)
+	if err != nil {
+		return
+	}
+
+	storedA, err := F.replaceValue(recordA, valueB)
+	if err != nil {
+		return
+	}
+	storedB, err := F.replaceValue(recordB, valueA)
+	if err != nil {
+		return
+	}
+
+	if F.wal != nil {
+		_, err = F.wal.AppendGroup([]wal.Entry{
+			{Op: wal.OpSet, HadOld: true, Key: recordA.Key, OldValue: recordA.Value, NewValue: storedA},
+			{Op: wal.OpSet, HadOld: true, Key: recordB.Key, OldValue: recordB.Value, NewValue: storedB},
+		})
+		if err != nil {
+			err = fmt.Errorf("error while writing to WAL: %s", err)
+			return
+		}
+	}
+
+	err = F.fileManagement.Set(model.Record{Key: recordA.Key, Value: storedA})
+	if err != nil {
+		return
+	}
+	err = F.fileManagement.Set(model.Record{Key: recordB.Key, Value: storedB})
+	if err != nil {
+		return
+	}
+
+	_, err = F.advanceSeq(2)
+
+	return
+}
+
+// CAS - Compare-and-swap, sets a new value for an existing record only if its current value equals expected, which
+// makes it possible to build counters and optimistic concurrency on top of the file hash map. The record keeps its
+// expiry time, if any.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - expected is the value the record must currently have
+//   - newValue is the value to set, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - err is either of type crt.NoRecordFound if no record with key exists, crt.ValueConflict holding the current
+//     value if it differs from expected, or a standard error if something went wrong
+func (F *FileHashMap) CAS(key, expected, newValue []byte) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("CAS")()
+
+	F.opStats.addSets(1)
+	defer func() { F.opStats.countError(err) }()
+
+	err = F.validateValue(key, newValue)
+	if err != nil {
+		return
+	}
+
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	current := F.fromStoredValue(record.Value)
+	if !utils.IsEqual(current, expected) {
+		err = crt.ValueConflict{Actual: current}
+		return
+	}
+
+	err = F.rewriteValue(record, newValue)
+
+	return
+}
+
+// Update - Reads a record, passes its value to fn and writes the value returned by fn back to the record, all under
+// one lock and with the record written in place at the address it was read from, so the key is probed for only once
+// instead of twice as with Get followed by Set. The record keeps its expiry time, if any.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - fn is called with the current value, which must not be kept, and returns the new value or an error which aborts the update
+//
+// It returns:
+//   - err is either of type crt.NoRecordFound if no record with key exists, the error returned by fn or a standard
+//     error if something went wrong
+func (F *FileHashMap) Update(key []byte, fn func(value []byte) (newValue []byte, err error)) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("Update")()
+
+	F.opStats.addSets(1)
+	defer func() { F.opStats.countError(err) }()
+
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	newValue, err := fn(F.fromStoredValue(record.Value))
+	if err != nil {
+		return
+	}
+
+	err = F.validateValue(key, newValue)
+	if err != nil {
+		return
+	}
+
+	err = F.rewriteValue(record, newValue)
+
+	return
+}
+
+// rewriteValue - Writes a new value to a record previously read from the files, in place at its address, logging it
+// to the WAL if enabled, and then advances the sequence number
+func (F *FileHashMap) rewriteValue(record model.Record, value []byte) (err error) {
+	stored, err := F.replaceValue(record, value)
+	if err != nil {
+		return
+	}
+
+	if F.wal != nil {
+		_, err = F.wal.Append(wal.Entry{Op: wal.OpSet, HadOld: true, Key: record.Key, OldValue: record.Value, NewValue: stored})
+		if err != nil {
+			err = fmt.Errorf("error while writing to WAL: %s", err)
+			return
+		}
+	}
+
+	err = setRecordValue(F.fileManagement, model.Record{Key: record.Key, IsOverflow: record.IsOverflow, RecordAddress: record.RecordAddress, Value: stored})
+	if err != nil {
+		return
+	}
+
+	_, err = F.advanceSeq(1)
+
+	return
+}
+
+// replaceValue - Returns value in the form it is stored in files for an existing record, keeping the key address and
+// expiry time of the record
+func (F *FileHashMap) replaceValue(record model.Record, value []byte) (stored []byte, err error) {
+	stored = value
+	if F.keyFile != nil {
+		stored = make([]byte, keyAddressLength, keyAddressLength+len(value))
+		binary.LittleEndian.PutUint64(stored, uint64(keyAddressOf(F.withoutExpiry(record.Value))))
+		stored = append(stored, value...)
+	}
+
+	var expiry int64
+	if F.ttl != nil {
+		expiry = storedExpiry(record.Value)
+	}
+
+	stored, err = F.toStoredValue(record.Key, stored, expiry)
+	if err != nil || !F.timestamps {
+		return
+	}
+
+	created, _ := F.storedTimestamps(record.Value)
+	_, modified := F.storedTimestamps(stored)
+	stored = F.restamp(record.Key, stored, created, modified)
+
+	return
+}
+
+// SetWithSeq - Works as Set but also returns the sequence number given to the mutation.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - seq is the sequence number of the mutation
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) SetWithSeq(key []byte, value []byte) (seq int64, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.set(key, value, 0)
+	if err != nil {
+		return
+	}
+
+	seq = F.lastSeq()
+
+	return
+}
+
+// LastSeq - Returns the sequence number of the last applied mutation. Each Set and Pop that changes the hash map is
+// given a strictly increasing sequence number, which is persisted in the map file header and hence continues from
+// where it was when the hash map is opened again. If the WAL is enabled the sequence numbers are the same as those
+// of the WAL entries.
+func (F *FileHashMap) LastSeq() (seq int64) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	return F.lastSeq()
+}
+
+// lastSeq - Is the implementation of LastSeq, to be called with the lock held
+func (F *FileHashMap) lastSeq() (seq int64) {
+	if F.groupCommit != nil {
+		return F.groupCommit.seq
+	}
+
+	return F.fileManagement.GetStorageParameters().MutationSeq
+}
+
+// advanceSeq - Advances and persists the mutation sequence number after n mutations were applied. If the WAL is
+// enabled the sequence number is aligned with the WAL. If group commit is enabled the sequence number is only
+// persisted at the next checkpoint. Files are then synced according to the sync policy.
+func (F *FileHashMap) advanceSeq(n int64) (seq int64, err error) {
+	if F.wal != nil {
+		seq = F.wal.NextSeq() - 1
+	} else {
+		seq = F.lastSeq() + n
+	}
+
+	if F.groupCommit != nil {
+		err = F.groupSeq(seq, n)
+	} else {
+		err = setMutationSeq(F.fileManagement, seq)
+	}
+	if err != nil {
+		return
+	}
+
+	err = F.syncAfterMutation()
+	if err != nil {
+		return
+	}
+
+	F.reportLoadFactor()
+
+	return
+}
+
+// SetBatch - Works as calling Set for each record, but is considerably faster when setting many records at once.
+// Records are grouped by bucket and processed in file offset order, where each bucket is read and written at most
+// once, which reduces the number of seek/read/write cycles. If the same key occurs more than once in records, the
+// last occurrence wins. If the WAL is enabled, records are set one by one so each gets its own WAL entry, and the same
+// goes if auto grow is enabled (see EnableAutoGrow).
+//   - records is the key and value pairs to set, lengths must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - err is a standard error, if something went wrong. All records are validated before anything is written, but if
+//     an error occurs while writing, some records may have been set and others not.
+func (F *FileHashMap) SetBatch(records []Record) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("SetBatch")()
+
+	F.opStats.addSets(int64(len(records)))
+	defer func() { F.opStats.countError(err) }()
+
+	for _, record := range records {
+		err = F.validateValue(record.Key, record.Value)
+		if err != nil {
+			return
+		}
+	}
+
+	if F.ttl != nil || F.keyFile != nil || F.checksums || F.timestamps {
+		storedRecords := make([]Record, len(records))
+		for i, record := range records {
+			storedRecords[i].Key, storedRecords[i].Value, err = F.toStoredKeyValue(record.Key, record.Value)
+			if err != nil {
+				return
+			}
+			storedRecords[i].Value, err = F.toStoredValue(storedRecords[i].Key, storedRecords[i].Value, 0)
+			if err != nil {
+				return
+			}
+			storedRecords[i].Value, err = F.keepCreated(storedRecords[i].Key, storedRecords[i].Value)
+			if err != nil {
+				return
+			}
+		}
+		records = storedRecords
+	}
+
+	if F.wal != nil || F.autoGrow != nil {
+		for _, record := range records {
+			if F.wal != nil {
+				err = F.logSet(record.Key, record.Value)
+				if err != nil {
+					return
+				}
+			}
+			err = F.setEvicting(record.Key, record.Value)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	modelRecords := make([]model.Record, len(records))
+	for i, record := range records {
+		modelRecords[i] = model.Record{Key: record.Key, Value: record.Value}
+		F.addToBloomFilter(record.Key)
+	}
+
+	err = setRecords(F.fileManagement, modelRecords)
+	if errors.Is(err, crt.MapFileFull{}) && F.evictionHand != nil {
+		// Nothing was written, so fall back to setting records one by one, evicting as needed
+		for _, record := range records {
+			err = F.setEvicting(record.Key, record.Value)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	_, err = F.advanceSeq(int64(len(records)))
+
+	return
+}
+
+// SetValueValidator - Registers a function that validates every key and value given to Set, SetBatch and
+// SetIdempotent before any I/O is made. This makes it possible to enforce constraints on values (e.g. magic bytes or
+// version ranges) centrally instead of in every producer. Only one validator can be registered, a nil validator turns
+// validation off.
+//   - validator is called with key and value, returning a non nil error rejects the write
+func (F *FileHashMap) SetValueValidator(validator func(key, value []byte) error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.valueValidator = validator
+}
+
+// validateValue - Runs the registered value validator, if any, and wraps a rejection in crt.InvalidValue
+func (F *FileHashMap) validateValue(key, value []byte) (err error) {
+	if F.valueValidator == nil {
+		return
+	}
+
+	if vErr := F.valueValidator(key, value); vErr != nil {
+		err = crt.InvalidValue{Err: vErr}
+	}
+
+	return
+}
+
+// EnableOperationLog - Turns on the operation log used by SetIdempotent. The log is itself a file hash map, using
+// Separate Chaining, with the name of this file hash map plus an -oplog suffix. If the log already exists it is opened,
+// otherwise it is created.
+//   - bucketsNeeded is the number of buckets to create the log with, it is ignored if the log already exists
+func (F *FileHashMap) EnableOperationLog(bucketsNeeded int) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("EnableOperationLog")
+	if err != nil {
+		return
+	}
+
+	if F.opLog != nil {
+		return
+	}
+
+	opLogName := fmt.Sprintf("%s-oplog", F.name)
+	if _, statErr := F.fileSystem().Stat(storage.GetMapFileName(opLogName)); statErr == nil {
+		F.opLog, _, err = openExistingFiles(opLogName, nil, false, F.options)
+	} else {
+		F.opLog, _, err = createFileHashMap(opLogName, crt.SeparateChaining, bucketsNeeded, 1, sha256.Size, 1, nil, F.options)
+	}
+	if err != nil {
+		err = fmt.Errorf("error while opening operation log: %s", err)
+	}
+
+	return
+}
+
+// SetIdempotent - Works as Set but skips the operation if a Set with the same operation ID has already been applied,
+// which gives exactly-once semantics for upstream at-least-once deliveries. Operation IDs are recorded in the operation
+// log, which has to be enabled through EnableOperationLog first. The ID is recorded after the record is set, so if a crash
+// occurs in between, a retry will set the same record again, which has the same outcome.
+//   - opID is a client supplied identifier of the operation, of any length
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - applied is true if the record was set, false if the operation ID was already present in the log
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) SetIdempotent(opID []byte, key []byte, value []byte) (applied bool, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.opLog == nil {
+		err = fmt.Errorf("operation log is not enabled")
+		return
+	}
+
+	opKey := sha256.Sum256(opID)
+	_, err = F.opLog.Get(opKey[:])
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, crt.NoRecordFound{}) {
+		err = fmt.Errorf("error while looking up operation ID in operation log: %s", err)
+		return
+	}
+
+	err = F.set(key, value, 0)
+	if err != nil {
+		return
+	}
+
+	err = F.opLog.Set(opKey[:], []byte{1})
+	if err != nil {
+		err = fmt.Errorf("error while recording operation ID in operation log: %s", err)
+		return
+	}
+
+	applied = true
+
+	return
+}
+
+// EnableWAL - Turns on the write-ahead log (WAL). Every Set and Pop is then written and synced to the WAL before
+// it is applied to the hash map files. If the WAL file already exists it is opened and all entries after the sequence
+// number in the map file header are applied again, since they may have been written but not applied, or not made
+// durable, if a crash occurred. This is normally only the last entry, or the entries since the last checkpoint if
+// group commit is enabled (see EnableGroupCommit). Applying entries again has the same outcome as applying them once.
+//
+// The WAL keeps the value before and after each mutation, which makes it possible to read values as they were at an
+// earlier sequence number using GetAsOf. The WAL grows with every mutation until CheckpointWAL is called.
+func (F *FileHashMap) EnableWAL() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("EnableWAL")
+	if err != nil {
+		return
+	}
+
+	if F.wal != nil {
+		return
+	}
+
+	sp := F.fileManagement.GetStorageParameters()
+	lastSeq := F.lastSeq()
+	w, err := wal.Open(F.fileSystem(), storage.GetWALFileName(F.name), sp.KeyLength, sp.ValueLength, lastSeq+1)
+	if err != nil {
+		return
+	}
+
+	err = syncDirOf(storage.GetWALFileName(F.name), F.options)
+	if err != nil {
+		w.Close()
+		return
+	}
+
+	var redoErr error
+	err = w.Iterate(func(seq int64, entry wal.Entry) bool {
+		if seq <= lastSeq {
+			return true
+		}
+		redoErr = F.redo(entry)
+		return redoErr == nil
+	})
+	if err != nil {
+		w.Close()
+		err = fmt.Errorf("error while reading entries in WAL: %s", err)
+		return
+	}
+	if redoErr != nil {
+		w.Close()
+		err = fmt.Errorf("error while applying entries in WAL: %s", redoErr)
+		return
+	}
+
+	// If mutations were applied while the WAL was not enabled, its entries can no longer be used to reconstruct values
+	if w.NextSeq() <= lastSeq {
+		err = w.Restart(lastSeq + 1)
+		if err != nil {
+			w.Close()
+			err = fmt.Errorf("error while restarting WAL: %s", err)
+			return
+		}
+	}
+
+	F.wal = w
+
+	if w.NextSeq()-1 > lastSeq {
+		_, err = F.advanceSeq(0)
+	}
+
+	return
+}
+
+// CheckpointWAL - Syncs the hash map files and discards all entries in the WAL. Sequence numbers continue from where
+// they were, but values as of sequence numbers before the checkpoint are no longer available through GetAsOf.
+// If group commit is enabled a checkpoint of the sequence number is written as well.
+func (F *FileHashMap) CheckpointWAL() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.wal == nil {
+		err = fmt.Errorf("WAL is not enabled")
+		return
+	}
+
+	if F.groupCommit != nil {
+		err = F.commitSeq()
+	} else {
+		err = F.fileManagement.Sync()
+	}
+	if err != nil {
+		return
+	}
+
+	err = F.wal.Checkpoint()
+	if err != nil {
+		err = fmt.Errorf("error while checkpointing WAL: %s", err)
+	}
+
+	return
+}
+
+// GetAsOf - Returns the value that the record corresponding to key had right after the mutation with sequence number
+// seq was applied, by replaying and undoing entries in the WAL. Sequence numbers are given to each Set and Pop while
+// the WAL is enabled.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - seq is the sequence number to read the value as of
+//
+// It returns:
+//   - value is the value of the record as of seq, if the record didn't exist an error of type crt.NoRecordFound is returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) GetAsOf(key []byte, seq int64) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("GetAsOf")
+	if err != nil {
+		return
+	}
+	if F.wal == nil {
+		err = fmt.Errorf("WAL is not enabled")
+		return
+	}
+	if seq < F.wal.FirstSeq()-1 {
+		err = fmt.Errorf("sequence number %d is before the oldest entry in WAL (%d)", seq, F.wal.FirstSeq())
+		return
+	}
+
+	var before, after wal.Entry
+	var hasBefore, hasAfter bool
+	storedKey := F.toStoredKey(key)
+	err = F.wal.Iterate(func(entrySeq int64, entry wal.Entry) bool {
+		if !utils.IsEqual(storedKey, entry.Key) {
+			return true
+		}
+		if entrySeq <= seq {
+			before, hasBefore = entry, true
+			return true
+		}
+		after, hasAfter = entry, true
+		return false
+	})
+	if err != nil {
+		err = fmt.Errorf("error while reading WAL: %s", err)
+		return
+	}
+
+	switch {
+	case hasBefore && before.Op == wal.OpSet:
+		value = F.fromStoredValue(before.NewValue)
+	case hasBefore:
+		err = crt.NoRecordFound{}
+	case hasAfter && after.HadOld:
+		value = F.fromStoredValue(after.OldValue)
+	case hasAfter:
+		err = crt.NoRecordFound{}
+	default:
+		var record model.Record
+		record, err = F.lookup(key)
+		value = F.fromStoredValue(record.Value)
+	}
+
+	return
+}
+
+// logSet - Writes a set entry to the WAL, including the value of any existing record
+func (F *FileHashMap) logSet(key, value []byte) (err error) {
+	sp := F.fileManagement.GetStorageParameters()
+	if int64(len(key)) != sp.KeyLength {
+		err = crt.WrongLength{Field: "key", Operation: "Set", Expected: int(sp.KeyLength), Actual: len(key)}
+		return
+	}
+	if int64(len(value)) != sp.ValueLength {
+		err = crt.WrongLength{Field: "value", Operation: "Set", Expected: int(sp.ValueLength), Actual: len(value)}
+		return
+	}
+
+	old, err := F.fileManagement.Get(model.Record{Key: key})
+	hadOld := err == nil
+	if err != nil && !errors.Is(err, crt.NoRecordFound{}) {
+		return
+	}
+
+	_, err = F.wal.Append(wal.Entry{Op: wal.OpSet, HadOld: hadOld, Key: key, OldValue: old.Value, NewValue: value})
+	if err != nil {
+		err = fmt.Errorf("error while writing to WAL: %s", err)
+	}
+
+	return
+}
+
+// redo - Applies a WAL entry to the hash map files
+func (F *FileHashMap) redo(entry wal.Entry) (err error) {
+	if entry.Op == wal.OpSet {
+		F.addToBloomFilter(entry.Key)
+		err = F.fileManagement.Set(model.Record{Key: entry.Key, Value: entry.NewValue})
+		return
+	}
+
+	record, err := F.fileManagement.Get(model.Record{Key: entry.Key})
+	if err != nil {
+		if errors.Is(err, crt.NoRecordFound{}) {
+			err = nil
+		}
+		return
+	}
+
+	err = F.fileManagement.Delete(record)
+
+	return
+}
+
+// Pop - Returns the record corresponding to key and removes it from the file hash map.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) Pop(key []byte) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	value, err = F.pop(key)
+
+	return
+}
+
+// pop - Is the implementation of Pop, to be called with the lock held
+func (F *FileHashMap) pop(key []byte) (value []byte, err error) {
+	defer F.watch("Pop")()
+	defer F.trace("Pop", key)(&err)
+	F.opStats.pops.Add(1)
+	defer func() { F.opStats.countError(err) }()
+
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	err = F.deleteRecord(record)
+	if err != nil {
+		return
+	}
+
+	value = F.fromStoredValue(record.Value)
+
+	return
+}
+
+// PopIf - Works as Pop but only deletes the record if approve returns true for its current value. The record is looked
+// up once and deleted at the address it was found at, so the key is not probed for twice.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - approve is called with the current value of the record, which must not be modified, and returns true if the record is to be deleted
+//
+// It returns:
+//   - value is the value of the matching record if found, whether deleted or not, if not found an error of type crt.NoRecordFound is also returned.
+//   - popped is true if the record was deleted
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) PopIf(key []byte, approve func(value []byte) bool) (value []byte, popped bool, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("PopIf")()
+
+	defer func() { F.opStats.countError(err) }()
+
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	value = F.fromStoredValue(record.Value)
+	if !approve(value) {
+		return
+	}
+
+	F.opStats.pops.Add(1)
+	err = F.deleteRecord(record)
+	if err != nil {
+		return
+	}
+
+	popped = true
+
+	return
+}
+
+// PopIfEqual - Works as Pop but only deletes the record if its current value equals expected, see PopIf.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - expected is the value the record must have to be deleted
+//
+// It returns:
+//   - value is the value of the matching record if found, whether deleted or not, if not found an error of type crt.NoRecordFound is also returned.
+//   - popped is true if the record was deleted
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) PopIfEqual(key []byte, expected []byte) (value []byte, popped bool, err error) {
+	value, popped, err = F.PopIf(key, func(value []byte) bool { return utils.IsEqual(value, expected) })
+
+	return
+}
+
+// PopAll - Pops records for many keys in one call, holding the lock for the whole call so no other operation is seen
+// in between. Each record is deleted at the address it was found at, as Pop does.
+//   - keys is the identifiers of records, they have to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - values is the values in the same order as keys, with nil for keys that were not found
+//   - errs is per key errors in the same order as keys, nil if popped or an error of type crt.NoRecordFound if not found
+//   - err is a standard error, if something went wrong, in which case keys after the failing one are not popped
+func (F *FileHashMap) PopAll(keys [][]byte) (values [][]byte, errs []error, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	values = make([][]byte, len(keys))
+	errs = make([]error, len(keys))
+	for i, key := range keys {
+		values[i], err = F.pop(key)
+		if errors.Is(err, crt.NoRecordFound{}) {
+			errs[i], err = err, nil
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// deleteRecord - Deletes a record previously read from the files, logging it to the WAL if enabled, and then
+// advances the sequence number
+func (F *FileHashMap) deleteRecord(record model.Record) (err error) {
+	if F.accessCounter != nil {
+		delete(F.accessCounter.pending, recordPosition{isOverflow: record.IsOverflow, recordAddress: record.RecordAddress})
+	}
+
+	if F.wal != nil {
+		_, err = F.wal.Append(wal.Entry{Op: wal.OpPop, HadOld: true, Key: record.Key, OldValue: record.Value})
+		if err != nil {
+			err = fmt.Errorf("error while writing to WAL: %s", err)
+			return
+		}
+	}
+
+	err = F.fileManagement.Delete(
+		model.Record{
+			IsOverflow:    record.IsOverflow,
+			RecordAddress: record.RecordAddress,
+			NextOverflow:  record.NextOverflow,
+		})
+	if err != nil {
+		return
+	}
+
+	if F.autoGrow != nil {
+		F.autoGrow.records--
+	}
+
+	_, err = F.advanceSeq(1)
+
+	return
+}
+
+// PopWithSeq - Works as Pop but also returns the sequence number given to the mutation.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - seq is the sequence number of the mutation
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) PopWithSeq(key []byte) (value []byte, seq int64, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	value, err = F.pop(key)
+	if err != nil {
+		return
+	}
+
+	seq = F.lastSeq()
+
+	return
+}
+
+// Clear - Removes all records from the file hash map, without closing, removing or recreating any files. The files keep
+// their size and configuration, as well as TTL support, but the sequence number is reset to zero. If the WAL is enabled
+// it is restarted, hence values as of sequence numbers before the clear are no longer available through GetAsOf.
+// Operation stats and the operation log are not affected.
+// This is both cheaper and safer than calling RemoveFiles followed by NewFileHashMap, e.g. when rebuilding the same map
+// over and over in batch jobs.
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) Clear() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("Clear")()
+
+	defer func() { F.opStats.countError(err) }()
+
+	// Pending access counts and the eviction hand refer to records that are about to be removed
+	if F.accessCounter != nil {
+		F.accessCounter.pending = make(map[recordPosition]int64)
+	}
+	if F.evictionHand != nil {
+		*F.evictionHand = evictionHand{}
+	}
+
+	err = clearRecords(F.fileManagement)
 	if err != nil {
 		return
 	}
+	F.reportLoadFactor()
+
+	if F.bloom != nil {
+		F.bloom.Reset()
+	}
+
+	if F.keyFile != nil {
+		err = F.keyFile.Clear()
+		if err != nil {
+			err = fmt.Errorf("error while clearing key file: %s", err)
+			return
+		}
+	}
+
+	if F.autoGrow != nil {
+		F.autoGrow.records = 0
+	}
+
+	if F.groupCommit != nil {
+		F.groupCommit.seq = 0
+		F.groupCommit.pending = 0
+	}
 
-	value = record.Value
+	if F.wal != nil {
+		err = F.wal.Restart(1)
+		if err != nil {
+			err = fmt.Errorf("error while restarting WAL: %s", err)
+		}
+	}
 
 	return
 }
 
-// Set - Updates an existing record with new data or add it if no existing is found with same key.
-//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
-//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+// Stat - Walks through the entire set of buckets and produce a HashMapStat struct with information.
+// If the hash map file and overflow file are very big, this can take a considerable amount of time and
+// the HashMapStat.BucketDistribution slice can be very memory heavy (there will be one entry per bucket), in which case
+// StatWithSink can be used to stream the distribution instead.
+//   - includeDistribution set to true will include a slice of length numberOfBuckets with number of records per bucket, false will set HashMapStat.BucketDistribution to nil.
+func (F *FileHashMap) Stat(includeDistribution bool) (hashMapStat *HashMapStat, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("Stat")()
+	hashMapStat, err = F.stat(includeDistribution, nil)
+
+	return
+}
+
+// StatWithSink - Works as Stat but streams the bucket distribution into sink instead of allocating a slice with one
+// entry per bucket, so that e.g. exporters can feed it straight into histograms or files. Add is called once for every
+// bucket, in bucket order and also for empty buckets, while the lock is held. HashMapStat.BucketDistribution is nil.
+//   - sink is the receiver of the number of records in each bucket
 //
 // It returns:
+//   - hashMapStat is a pointer to a HashMapStat struct
 //   - err is a standard error, if something went wrong
-func (F *FileHashMap) Set(key []byte, value []byte) (err error) {
-	err = F.fileManagement.Set(model.Record{Key: key, Value: value})
+func (F *FileHashMap) StatWithSink(sink DistributionSink) (hashMapStat *HashMapStat, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("StatWithSink")()
+	hashMapStat, err = F.stat(false, func(bucketNo int64, records int) error {
+		sink.Add(bucketNo, records)
+		return nil
+	})
 
 	return
 }
 
-// Pop - Returns the record corresponding to key and removes it from the file hash map.
-//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+// StatStream - Works as Stat but passes the number of records in each bucket to fn instead of allocating a slice with
+// one entry per bucket, so that the distribution of very large maps can be analyzed without the memory to hold it. fn
+// is called once for every bucket, in bucket order and also for empty buckets, while the lock is held. If fn returns
+// an error the walk through the buckets stops and that error is returned. HashMapStat.BucketDistribution is nil.
+//   - fn is called with the bucket number and the number of records in the bucket
 //
 // It returns:
-//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
-//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
-func (F *FileHashMap) Pop(key []byte) (value []byte, err error) {
-	record, err := F.fileManagement.Get(model.Record{Key: key})
+//   - hashMapStat is a pointer to a HashMapStat struct, nil if fn stopped the walk
+//   - err is a standard error, if fn returned an error or something went wrong
+func (F *FileHashMap) StatStream(fn func(bucketNo int64, records int) error) (hashMapStat *HashMapStat, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("StatStream")()
+	hashMapStat, err = F.stat(false, fn)
+
+	return
+}
+
+// Count - Returns the number of records stored without walking through the buckets. The records are counted once,
+// the first time any of Count, LoadFactor or DeletedRatio is called after the files were opened, and from then on the
+// count is kept up to date as records are set and deleted. With Separate Chaining, Hybrid and Linear Hashing the counts
+// are kept in the file headers instead, and are only counted again when opening files that were not closed properly.
+// Records that have expired but not yet been encountered and marked as deleted are included.
+//
+// It returns:
+//   - count is the number of records stored
+//   - err is a standard error, if the records could not be counted
+func (F *FileHashMap) Count() (count int64, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("Count")()
+	count, _, err = countRecords(F.fileManagement)
+	if err != nil {
+		err = fmt.Errorf("error while counting records: %s", err)
+	}
+
+	return
+}
+
+// LoadFactor - Returns the number of records stored divided by the number of records the map file can hold, see Count
+// for how records are counted. With Separate Chaining, Hybrid and Linear Hashing records in the overflow file are
+// included, so the load factor may exceed 1.
+//
+// It returns:
+//   - loadFactor is the share of the map file holding records
+//   - err is a standard error, if the records could not be counted
+func (F *FileHashMap) LoadFactor() (loadFactor float64, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("LoadFactor")()
+	occupied, _, err := countRecords(F.fileManagement)
 	if err != nil {
+		err = fmt.Errorf("error while counting records: %s", err)
 		return
 	}
 
-	err = F.fileManagement.Delete(
-		model.Record{
-			IsOverflow:    record.IsOverflow,
-			RecordAddress: record.RecordAddress,
-			NextOverflow:  record.NextOverflow,
-		})
+	loadFactor = float64(occupied) / float64(F.capacity())
+
+	return
+}
+
+// DeletedRatio - Returns the number of deleted records divided by the number of records the map file can hold, see
+// Count for how records are counted. Deleted records are kept as markers which make probing longer until reused or
+// removed by ReorgFiles, so a high ratio is a sign that a reorganization may be due.
+//
+// It returns:
+//   - deletedRatio is the share of the map file holding deleted records
+//   - err is a standard error, if the records could not be counted
+func (F *FileHashMap) DeletedRatio() (deletedRatio float64, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	defer F.watch("DeletedRatio")()
+	_, deleted, err := countRecords(F.fileManagement)
+	if err != nil {
+		err = fmt.Errorf("error while counting records: %s", err)
+		return
+	}
 
-	value = record.Value
+	deletedRatio = float64(deleted) / float64(F.capacity())
 
 	return
 }
 
-// Stat - Walks through the entire set of buckets and produce a HashMapStat struct with information.
-// If the hash map file and overflow file are very big, this can take a considerable amount of time and
-// the HashMapStat.BucketDistribution slice can be very memory heavy (there will be one entry per bucket).
-//   - includeDistribution set to true will include a slice of length numberOfBuckets with number of records per bucket, false will set HashMapStat.BucketDistribution to nil.
-func (F *FileHashMap) Stat(includeDistribution bool) (hashMapStat *HashMapStat, err error) {
+// capacity - Returns the number of records the map file can hold, not including any overflow file
+func (F *FileHashMap) capacity() int64 {
+	params := F.fileManagement.GetStorageParameters()
+
+	return params.NumberOfBucketsAvailable * params.RecordsPerBucket
+}
+
+// stat - Is the implementation of Stat, StatWithSink and StatStream, to be called with the lock held. add, if not nil,
+// is called with the number of records in each bucket and stops the walk by returning an error.
+func (F *FileHashMap) stat(includeDistribution bool, add func(bucketNo int64, records int) error) (hashMapStat *HashMapStat, err error) {
 	var bucket model.Bucket
 	var record model.Record
 	var iter *overflow.Records
 	var hms HashMapStat
+	var count, chainLength int
+	var probeLength int64
+	var probed, probeLengthSum, chainLengthSum int64
 
 	sp := F.fileManagement.GetStorageParameters()
+	hasOverflow := sp.CollisionResolutionTechnique == crt.SeparateChaining ||
+		sp.CollisionResolutionTechnique == crt.Hybrid ||
+		sp.CollisionResolutionTechnique == crt.LinearHashing
+
+	F.beginScan()
+	defer F.endScan()
 
 	if includeDistribution {
 		hms.BucketDistribution = make([]int, sp.NumberOfBucketsAvailable)
 	}
 
-	// Iterate over every available bucket
+	// Iterate over every available bucket, reading them in chunks
+	getBucket := F.bucketScanner()
 	for i := int64(0); i < sp.NumberOfBucketsAvailable; i++ {
-		bucket, iter, err = F.fileManagement.GetBucket(i)
+		bucket, iter, err = getBucket(i)
 		if err != nil {
 			return
 		}
+		count = 0
 
 		// Process map file records
 		for _, r := range bucket.Records {
-			if r.State == model.RecordOccupied {
+			if r.State == model.RecordOccupied && !F.hasExpired(r) {
 				hms.Records++
 				hms.MapFileRecords++
-				if includeDistribution {
-					hms.BucketDistribution[i]++
+				count++
+
+				probeLength = probeLengthOf(F.fileManagement, r.Key, i)
+				if probeLength >= 0 {
+					hms.ProbeLengths = addToHistogram(hms.ProbeLengths, int(probeLength))
+					probeLengthSum += probeLength
+					probed++
 				}
 			}
 
 		}
 
 		// Process overflow file records
+		chainLength = 0
 		for iter != nil && iter.HasNext() {
 			record, err = iter.Next()
 			if err != nil {
 				return
 			}
-			if record.State == model.RecordOccupied {
+			chainLength++
+			if record.State == model.RecordOccupied && !F.hasExpired(record) {
 				hms.Records++
 				hms.OverflowRecords++
-				if includeDistribution {
-					hms.BucketDistribution[i]++
-				}
+				count++
+			} else if record.State == model.RecordDeleted {
+				hms.OverflowDeletedRecords++
+			}
+		}
+		if hasOverflow {
+			hms.ChainLengths = addToHistogram(hms.ChainLengths, chainLength)
+			chainLengthSum += int64(chainLength)
+		}
+
+		if includeDistribution {
+			hms.BucketDistribution[i] = count
+		}
+		if add != nil {
+			err = add(i, count)
+			if err != nil {
+				return
 			}
 		}
 	}
 
+	hms.LastSeq = F.lastSeq()
+
+	hms.MapFileBytes, hms.OverflowFileBytes, err = F.fileSizes(hasOverflow)
+	if err != nil {
+		return
+	}
+	hms.LoadFactor = float64(hms.Records) / float64(F.capacity())
+
+	hms.MaxProbeLength = len(hms.ProbeLengths) - 1
+	if hms.MaxProbeLength < 0 {
+		hms.MaxProbeLength = 0
+	}
+	if probed > 0 {
+		hms.MeanProbeLength = float64(probeLengthSum) / float64(probed)
+	}
+	if hasOverflow {
+		hms.MaxChainLength = len(hms.ChainLengths) - 1
+		if sp.NumberOfBucketsAvailable > 0 {
+			hms.MeanChainLength = float64(chainLengthSum) / float64(sp.NumberOfBucketsAvailable)
+		}
+	}
+
 	hashMapStat = &hms
 	return
 }
+
+// fileSizes - Returns the sizes of the map file and, if hasOverflow is true, the overflow file. The map file size is
+// taken from the storage parameters for a hash map held in memory.
+func (F *FileHashMap) fileSizes(hasOverflow bool) (mapFileBytes, overflowFileBytes int64, err error) {
+	if F.name == "" {
+		mapFileBytes = F.fileManagement.GetStorageParameters().MapFileSize
+		return
+	}
+
+	info, err := F.fileSystem().Stat(storage.GetMapFileName(F.name))
+	if err != nil {
+		err = fmt.Errorf("error while getting size of map file: %s", err)
+		return
+	}
+	mapFileBytes = info.Size()
+
+	if hasOverflow {
+		overflowFileBytes, err = F.overflowFileSize()
+	}
+
+	return
+}
+
+// addToHistogram - Counts value in histogram, where the index is the value, extending histogram as needed
+func addToHistogram(histogram []int, value int) []int {
+	for len(histogram) <= value {
+		histogram = append(histogram, 0)
+	}
+	histogram[value]++
+
+	return histogram
+}
+
+// Export - Reads every record in bucket order and passes them to fn in batches using a columnar layout, which makes it
+// straightforward to build e.g. Arrow record batches for analytical tools without parsing the file format. Arrow is not
+// written by the package itself, to keep the Apache Arrow module out of its dependencies. The file hash map is only
+// read, and slices in a batch are not reused once passed to fn.
+//   - batchSize is the max number of records in each batch, values below 1 are set to 1
+//   - includeDeleted is whether records in state StateDeleted should also be exported
+//   - fn is called once for each batch, if it returns an error the export stops and that error is returned
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) Export(batchSize int, includeDeleted bool, fn func(batch *ExportBatch) error) (err error) {
+	var records []model.Record
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	batch := &ExportBatch{}
+
+	F.mu.Lock()
+	nBuckets := F.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+	getBucket := F.bucketScanner()
+	F.beginScan()
+	F.mu.Unlock()
+	defer func() {
+		F.mu.Lock()
+		F.endScan()
+		F.mu.Unlock()
+	}()
+
+	add := func(bucketNo int64, r model.Record) (err error) {
+		if (r.State != model.RecordOccupied || F.hasExpired(r)) && !(includeDeleted && r.State == model.RecordDeleted) {
+			return
+		}
+
+		key, err := F.userKey(r)
+		if err != nil {
+			return
+		}
+
+		batch.Keys = append(batch.Keys, key)
+		batch.Values = append(batch.Values, F.fromStoredValue(r.Value))
+		batch.Buckets = append(batch.Buckets, bucketNo)
+		batch.States = append(batch.States, r.State)
+		batch.IsOverflow = append(batch.IsOverflow, r.IsOverflow)
+		batch.AccessCounts = append(batch.AccessCounts, r.AccessCount)
+		if F.ttl != nil {
+			batch.Expiries = append(batch.Expiries, storedExpiry(r.Value))
+		}
+		if F.timestamps {
+			created, modified := F.storedTimestamps(r.Value)
+			batch.Created = append(batch.Created, created)
+			batch.Modified = append(batch.Modified, modified)
+		}
+
+		if len(batch.Keys) >= batchSize {
+			err = fn(batch)
+			batch = &ExportBatch{}
+		}
+		return
+	}
+
+	// The lock is only held while reading each bucket, so fn is free to call other methods on the file hash map
+	for i := int64(0); i < nBuckets; i++ {
+		records, err = F.readBucket(getBucket, i)
+		if err != nil {
+			return
+		}
+
+		for _, r := range records {
+			if err = add(i, r); err != nil {
+				return
+			}
+		}
+	}
+
+	if len(batch.Keys) > 0 {
+		err = fn(batch)
+	}
+
+	return
+}
+
+// readBucket - Reads all records in a bucket using getBucket, including any overflow chain, while holding the lock
+func (F *FileHashMap) readBucket(getBucket bucketGetter, bucketNo int64) (records []model.Record, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	records, err = F.readBucketLocked(getBucket, bucketNo)
+
+	return
+}
+
+// readBucketLocked - Reads all records in a bucket using getBucket, including any overflow chain, to be called with
+// the lock held
+func (F *FileHashMap) readBucketLocked(getBucket bucketGetter, bucketNo int64) (records []model.Record, err error) {
+	var bucket model.Bucket
+	var record model.Record
+	var iter *overflow.Records
+
+	bucket, iter, err = getBucket(bucketNo)
+	if err != nil {
+		return
+	}
+
+	records = append(records, bucket.Records...)
+
+	for iter != nil && iter.HasNext() {
+		record, err = iter.Next()
+		if err != nil {
+			return
+		}
+		records = append(records, record)
+	}
+
+	return
+}
+
+// EnableAccessCounting - Turns on counting of accesses made by Get. Each record has a saturating access counter
+// (0 to 63) stored together with the record state, which can be used to identify cold data in an LFU fashion.
+// To avoid a write for every read, counts are kept in memory and written behind in batches when the number of
+// records with pending counts reaches flushThreshold, when FlushAccessCounts is called or when files are closed.
+//   - flushThreshold is the number of records with pending counts that triggers a write, values below 1 are set to 1
+func (F *FileHashMap) EnableAccessCounting(flushThreshold int) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.enableAccessCounting(flushThreshold)
+}
+
+// enableAccessCounting - Is the implementation of EnableAccessCounting, to be called with the lock held
+func (F *FileHashMap) enableAccessCounting(flushThreshold int) {
+	if flushThreshold < 1 {
+		flushThreshold = 1
+	}
+
+	if F.accessCounter == nil {
+		F.accessCounter = &accessCounter{pending: make(map[recordPosition]int64)}
+	}
+	F.accessCounter.flushThreshold = flushThreshold
+}
+
+// EnableClockEviction - Turns on CLOCK eviction, which makes Set and SetBatch evict an existing record instead of
+// failing with crt.MapFileFull when the map file is full, turning the file hash map into a capacity bounded disk backed
+// cache. Records to evict are chosen with the CLOCK (second chance) algorithm over the access counts kept by access
+// counting: a hand sweeps the map file and halves the access count of each record it passes, evicting the first record
+// found with a count of zero. Records rarely read are thereby evicted before records often read, with counts decaying
+// as the hand passes. This approximates least recently used without keeping the order of accesses, which would take
+// memory for every record and be lost when the files are closed, so the record evicted is not always the one least
+// recently used.
+// Access counting is enabled, with flushThreshold, if not already enabled (see EnableAccessCounting).
+// Eviction is only available for the open addressing techniques and Robin Hood, where a record set can use any free
+// record of the map file. Separate Chaining, Hybrid and Linear Hashing never become full, while Cuckoo Hashing can only
+// store a key in its two candidate buckets and Hopscotch within the neighborhood of its home bucket, which a record
+// evicted from elsewhere doesn't free.
+//   - flushThreshold is the number of records with pending counts that triggers a write, values below 1 are set to 1
+//
+// It returns:
+//   - err is a standard error, if the collision resolution technique doesn't support eviction
+func (F *FileHashMap) EnableClockEviction(flushThreshold int) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if crtType := F.fileManagement.GetStorageParameters().CollisionResolutionTechnique; crtType == crt.SeparateChaining || crtType == crt.Hybrid || crtType == crt.LinearHashing || crtType == crt.CuckooHashing || crtType == crt.Hopscotch {
+		err = fmt.Errorf("eviction is not supported for separate chaining, hybrid, linear hashing, cuckoo hashing or hopscotch")
+		return
+	}
+	if _, ok := F.fileManagement.(accessCountAdder); !ok {
+		err = fmt.Errorf("eviction is not supported by the collision resolution technique, which doesn't store access counts")
+		return
+	}
+
+	if F.accessCounter == nil {
+		F.enableAccessCounting(flushThreshold)
+	}
+
+	if F.evictionHand == nil {
+		F.evictionHand = &evictionHand{}
+	}
+
+	return
+}
+
+// DisableClockEviction - Turns off CLOCK eviction, access counting is left as is.
+func (F *FileHashMap) DisableClockEviction() {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.evictionHand = nil
+}
+
+// evict - Sweeps the map file with the clock hand, halving access counts, until a record with zero count is found
+// and then deletes it. Since access counts are at most 63, every occupied record has a count of zero within seven
+// laps of the hand.
+func (F *FileHashMap) evict() (err error) {
+	var bucket model.Bucket
+	var record model.Record
+
+	err = F.flushAccessCounts()
+	if err != nil {
+		return
+	}
+
+	sp := F.fileManagement.GetStorageParameters()
+	hand := F.evictionHand
+	maxBuckets := sp.NumberOfBucketsAvailable * 8
+
+	for n := int64(0); n <= maxBuckets; n++ {
+		bucket, _, err = F.fileManagement.GetBucket(hand.bucketNo)
+		if err != nil {
+			return
+		}
+
+		for ; hand.recordIndex < len(bucket.Records); hand.recordIndex++ {
+			record = bucket.Records[hand.recordIndex]
+			if record.State != model.RecordOccupied {
+				continue
+			}
+
+			if record.AccessCount == 0 {
+				hand.recordIndex++
+				err = F.deleteRecord(record)
+				if err == nil {
+					F.opStats.evictions.Add(1)
+				}
+				return
+			}
+
+			err = addAccessCount(F.fileManagement, record, -int64(record.AccessCount-record.AccessCount/2))
+			if err != nil {
+				return
+			}
+		}
+
+		hand.recordIndex = 0
+		hand.bucketNo = (hand.bucketNo + 1) % sp.NumberOfBucketsAvailable
+	}
+
+	err = crt.MapFileFull{}
+
+	return
+}
+
+// DisableAccessCounting - Turns off counting of accesses made by Get, any pending counts are first written to file.
+func (F *FileHashMap) DisableAccessCounting() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.flushAccessCounts()
+	F.accessCounter = nil
+
+	return
+}
+
+// FlushAccessCounts - Writes all pending access counts to file. It is a no-op if access counting is not enabled.
+func (F *FileHashMap) FlushAccessCounts() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.flushAccessCounts()
+
+	return
+}
+
+// flushAccessCounts - Is the implementation of FlushAccessCounts, to be called with the lock held
+func (F *FileHashMap) flushAccessCounts() (err error) {
+	if F.accessCounter == nil {
+		return
+	}
+
+	for position, increment := range F.accessCounter.pending {
+		err = addAccessCount(F.fileManagement, model.Record{IsOverflow: position.isOverflow, RecordAddress: position.recordAddress}, increment)
+		if err != nil {
+			return
+		}
+		delete(F.accessCounter.pending, position)
+	}
+
+	return
+}
+
+// GetAccessCount - Returns the access count for the record that corresponds to key, including any pending counts
+// not yet written to file. Reading the count is not in itself counted as an access.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - count is the number of registered accesses, saturated at 63
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) GetAccessCount(key []byte) (count int, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	count = int(record.AccessCount)
+	if F.accessCounter != nil {
+		count += int(F.accessCounter.pending[recordPosition{isOverflow: record.IsOverflow, recordAddress: record.RecordAddress}])
+	}
+	if count > int(model.MaxAccessCount) {
+		count = int(model.MaxAccessCount)
+	}
+
+	return
+}
+
+// OperationStats - Returns a snapshot of the operation counters collected since the file hash map was opened or
+// since the last call to ResetStats. Together with the LastReset timestamp it can be used to compute rates over a
+// controlled window of time.
+func (F *FileHashMap) OperationStats() (operationStats OperationStats) {
+	operationStats = OperationStats{
+		Gets:      F.opStats.gets.Load(),
+		GetMisses: F.opStats.getMisses.Load(),
+		Sets:      F.opStats.sets.Load(),
+		Pops:      F.opStats.pops.Load(),
+		Errors:    F.opStats.errors.Load(),
+		Evictions: F.opStats.evictions.Load(),
+		LastReset: time.Unix(0, F.opStats.lastReset.Load()),
+	}
+
+	return
+}
+
+// ResetStats - Sets all operation counters to zero and records the time of the reset.
+func (F *FileHashMap) ResetStats() {
+	F.opStats.gets.Store(0)
+	F.opStats.getMisses.Store(0)
+	F.opStats.sets.Store(0)
+	F.opStats.pops.Store(0)
+	F.opStats.errors.Store(0)
+	F.opStats.evictions.Store(0)
+	F.opStats.lastReset.Store(time.Now().UnixNano())
+}
@@ -3,15 +3,21 @@
 package filehashmap
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/gostonefire/filehashmap/crt"
 	"github.com/gostonefire/filehashmap/hashfunc"
+	"github.com/gostonefire/filehashmap/internal/storage/separatechaining"
 	"github.com/gostonefire/filehashmap/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"hash/crc32"
+	"hash/fnv"
+	"io"
 	"math/rand"
 	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 type TestCaseOperations struct {
@@ -32,9 +38,18 @@ func TestFileHashMap_Set(t *testing.T) {
 			{crtName: "LinearProbing", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
 			{crtName: "QuadraticProbing", buckets: 10000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
 			{crtName: "DoubleHashing", buckets: 10000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
 			{crtName: "SeparateChainingCustomHash", buckets: 10000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: NewSeparateChainingHashAlgorithm(10000)},
 			{crtName: "LinearProbingCustomHash", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: NewLinearProbingHashAlgorithm(10000)},
 			{crtName: "QuadraticProbingCustomHash", buckets: 10000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing, hFunc: NewQuadraticProbingHashAlgorithm(10000)},
+			{crtName: "DoubleHashingCustomHash", buckets: 10000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing, hFunc: NewDoubleHashingHashAlgorithm(10000)},
+			{crtName: "LinearProbingHash64", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: hashfunc.FromHash64(fnv.New64a, hashfunc.TableSizeExact)},
+			{crtName: "DoubleHashingHash64", buckets: 10000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing, hFunc: hashfunc.FromHash64(fnv.New64a, hashfunc.TableSizePrime)},
+			{crtName: "CuckooHashingHash64", buckets: 10000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing, hFunc: hashfunc.FromHash64(fnv.New64a, hashfunc.TableSizePowerOfTwo)},
 		}
 
 		for _, test := range tests {
@@ -170,6 +185,192 @@ func TestFileHashMap_Get(t *testing.T) {
 }
 */
 
+func TestSetBatch(t *testing.T) {
+	t.Run("set batch tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("sets a batch of records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 1000)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+
+				// Some records exist already, and some of those are then popped to leave deleted records
+				for i := 0; i < 200; i++ {
+					err = fhm.Set(records[i].Key, make([]byte, 10))
+					assert.NoErrorf(t, err, "sets existing record #%d", i)
+				}
+				for i := 100; i < 200; i++ {
+					_, err = fhm.Pop(records[i].Key)
+					assert.NoErrorf(t, err, "pops existing record #%d", i)
+				}
+
+				// A duplicate key where the last occurrence should win
+				records = append(records, Record{Key: records[0].Key, Value: records[1].Value})
+
+				// Execute
+				err = fhm.SetBatch(records)
+
+				// Check
+				assert.NoError(t, err, "sets batch")
+
+				for i := 1; i < 1000; i++ {
+					value, err := fhm.Get(records[i].Key)
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(records[i].Value, value), "record #%d has correct value", i)
+				}
+				value, err := fhm.Get(records[0].Key)
+				assert.NoError(t, err, "gets duplicate record")
+				assert.True(t, utils.IsEqual(records[1].Value, value), "last occurrence wins")
+
+				stat, err := fhm.Stat(false)
+				assert.NoError(t, err, "gets statistics")
+				assert.Equal(t, 1000, stat.Records, "no duplicates stored")
+
+				err = fhm.SetBatch([]Record{{Key: records[0].Key, Value: []byte{1}}})
+				assert.Error(t, err, "error on wrong value length")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestValueValidator(t *testing.T) {
+	t.Run("validates values on write", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		validatorErr := fmt.Errorf("missing magic byte")
+		fhm.SetValueValidator(func(key, value []byte) error {
+			if value[0] != 0xCA {
+				return validatorErr
+			}
+			return nil
+		})
+
+		keyA := make([]byte, 16)
+		keyB := make([]byte, 16)
+		rand.Read(keyA)
+		rand.Read(keyB)
+		valid := []byte{0xCA, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		invalid := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+		// Execute
+		errValid := fhm.Set(keyA, valid)
+		errInvalid := fhm.Set(keyB, invalid)
+		errBatch := fhm.SetBatch([]Record{{Key: keyB, Value: valid}, {Key: keyB, Value: invalid}})
+
+		// Check
+		assert.NoError(t, errValid, "valid value is set")
+		assert.ErrorIs(t, errInvalid, crt.InvalidValue{}, "invalid value is rejected")
+		assert.ErrorIs(t, errInvalid, validatorErr, "validator error is wrapped")
+		assert.ErrorIs(t, errBatch, crt.InvalidValue{}, "invalid value in batch is rejected")
+
+		_, err = fhm.Get(keyB)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "nothing written from rejected batch")
+		assert.Equal(t, int64(1), fhm.LastSeq(), "only valid write advanced sequence")
+
+		fhm.SetValueValidator(nil)
+		err = fhm.Set(keyB, invalid)
+		assert.NoError(t, err, "validation turned off")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestGetBatch(t *testing.T) {
+	t.Run("get batch tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("gets a batch of records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 500)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				err = fhm.SetBatch(records[:400])
+				assert.NoError(t, err, "sets batch")
+
+				// Some records are popped to leave deleted records in the way
+				for i := 300; i < 400; i++ {
+					_, err = fhm.Pop(records[i].Key)
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+
+				keys := make([][]byte, len(records))
+				for i := range records {
+					keys[i] = records[i].Key
+				}
+
+				// Execute
+				values, errs, err := fhm.GetBatch(keys)
+
+				// Check
+				assert.NoError(t, err, "gets batch")
+				assert.Len(t, values, len(keys), "one value per key")
+				assert.Len(t, errs, len(keys), "one error per key")
+
+				for i := 0; i < 300; i++ {
+					assert.NoErrorf(t, errs[i], "finds record #%d", i)
+					assert.Truef(t, utils.IsEqual(records[i].Value, values[i]), "record #%d has correct value", i)
+				}
+				for i := 300; i < 500; i++ {
+					assert.ErrorIsf(t, errs[i], crt.NoRecordFound{}, "record #%d not found", i)
+					assert.Nilf(t, values[i], "no value for record #%d", i)
+				}
+
+				_, _, err = fhm.GetBatch([][]byte{{1}})
+				assert.Error(t, err, "error on wrong key length")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
 func TestPop(t *testing.T) {
 	t.Run("pop tests for all CRTs", func(t *testing.T) {
 		// Prepare
@@ -178,160 +379,1968 @@ func TestPop(t *testing.T) {
 			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
 			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
 			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
-			{crtName: "SeparateChainingCustomHash", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: NewSeparateChainingHashAlgorithm(10)},
-			{crtName: "LinearProbingCustomHash", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: NewLinearProbingHashAlgorithm(1000)},
-			{crtName: "QuadraticProbingCustomHash", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing, hFunc: NewQuadraticProbingHashAlgorithm(1000)},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+			{crtName: "SeparateChainingCustomHash", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: NewSeparateChainingHashAlgorithm(10)},
+			{crtName: "LinearProbingCustomHash", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: NewLinearProbingHashAlgorithm(1000)},
+			{crtName: "QuadraticProbingCustomHash", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing, hFunc: NewQuadraticProbingHashAlgorithm(1000)},
+			{crtName: "DoubleHashingCustomHash", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing, hFunc: NewDoubleHashingHashAlgorithm(1000)},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("pops records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 1000)
+				values := make([][]byte, 1000)
+
+				for i := 0; i < 1000; i++ {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					values[i] = make([]byte, 10)
+					rand.Read(values[i])
+
+					err = fhm.Set(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				// Execute
+				var value []byte
+				for i := 0; i < 1000; i++ {
+					value, err = fhm.Pop(keys[i])
+					assert.NoErrorf(t, err, "pops record #%d", i)
+					assert.Truef(t, utils.IsEqual(values[i], value), "record #%d has correct crt", i)
+				}
+
+				// Check
+				for i := 0; i < 1000; i++ {
+					value, err = fhm.Get(keys[i])
+					assert.ErrorIsf(t, err, crt.NoRecordFound{}, "gets correct error when geting poped record #%d", i)
+					assert.Nilf(t, value, "crt is nil in record #%d", i)
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+
+				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "map file removed")
+				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "overflow file removed")
+			})
+		}
+	})
+}
+
+func TestPopIf(t *testing.T) {
+	t.Run("pops records only when approved", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		keyA, keyB := make([]byte, 16), make([]byte, 16)
+		keyA[0], keyB[0] = 1, 2
+		valueA, valueB := make([]byte, 10), make([]byte, 10)
+		valueA[0], valueB[0] = 1, 2
+		err = fhm.Set(keyA, valueA)
+		assert.NoError(t, err, "sets record A to file")
+		err = fhm.Set(keyB, valueB)
+		assert.NoError(t, err, "sets record B to file")
+
+		// Execute
+		valueRejected, poppedRejected, errRejected := fhm.PopIf(keyA, func(value []byte) bool { return false })
+		_, poppedApproved, errApproved := fhm.PopIf(keyA, func(value []byte) bool { return value[0] == 1 })
+		_, poppedMismatch, errMismatch := fhm.PopIfEqual(keyB, valueA)
+		_, poppedEqual, errEqual := fhm.PopIfEqual(keyB, valueB)
+		_, poppedMissing, errMissing := fhm.PopIf(keyA, func(value []byte) bool { return true })
+
+		// Check
+		assert.NoError(t, errRejected, "rejected pop")
+		assert.False(t, poppedRejected, "rejected record not popped")
+		assert.Equal(t, valueA, valueRejected, "value of rejected record returned")
+		assert.NoError(t, errApproved, "approved pop")
+		assert.True(t, poppedApproved, "approved record popped")
+		assert.NoError(t, errMismatch, "pop of mismatching value")
+		assert.False(t, poppedMismatch, "mismatching record not popped")
+		assert.NoError(t, errEqual, "pop of equal value")
+		assert.True(t, poppedEqual, "equal record popped")
+		assert.ErrorIs(t, errMissing, crt.NoRecordFound{}, "popped record not found")
+		assert.False(t, poppedMissing, "missing record not popped")
+		assert.Equal(t, int64(2), fhm.OperationStats().Pops, "only deletions counted as pops")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestPopAll(t *testing.T) {
+	t.Run("pops many records in one call", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		keys := make([][]byte, 50)
+		values := make([][]byte, 50)
+		for i := range keys {
+			keys[i] = make([]byte, 16)
+			rand.Read(keys[i])
+			values[i] = make([]byte, 10)
+			rand.Read(values[i])
+			if i%5 != 0 {
+				err = fhm.Set(keys[i], values[i])
+				assert.NoErrorf(t, err, "sets record #%d to file", i)
+			}
+		}
+
+		// Execute
+		popped, errs, err := fhm.PopAll(keys)
+
+		// Check
+		assert.NoError(t, err, "pops records")
+		for i := range keys {
+			if i%5 == 0 {
+				assert.ErrorIsf(t, errs[i], crt.NoRecordFound{}, "record #%d not found", i)
+				assert.Nilf(t, popped[i], "no value for record #%d", i)
+				continue
+			}
+			assert.NoErrorf(t, errs[i], "record #%d popped", i)
+			assert.Equalf(t, values[i], popped[i], "value of record #%d", i)
+			_, err = fhm.Get(keys[i])
+			assert.ErrorIsf(t, err, crt.NoRecordFound{}, "record #%d removed", i)
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestGetInto(t *testing.T) {
+	t.Run("gets values into caller buffer", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		value := make([]byte, 10)
+		rand.Read(value)
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record to file")
+
+		missing := make([]byte, 16)
+		buf := make([]byte, 12)
+
+		// Execute
+		n, err := fhm.GetInto(key, buf)
+		_, errMissing := fhm.GetInto(missing, buf)
+		_, errShort := fhm.GetInto(key, make([]byte, 9))
+
+		// Check
+		assert.NoError(t, err, "gets value into buffer")
+		assert.Equal(t, 10, n, "number of bytes copied")
+		assert.Equal(t, value, buf[:n], "value copied into buffer")
+		assert.ErrorIs(t, errMissing, crt.NoRecordFound{}, "missing record not found")
+		assert.ErrorIs(t, errShort, io.ErrShortBuffer, "short buffer is rejected")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestExists(t *testing.T) {
+	t.Run("exists tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("checks existence of records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 200)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], make([]byte, 10))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+				for i := 0; i < 100; i++ {
+					_, err = fhm.Pop(keys[i])
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+				fhm.ResetStats()
+
+				// Execute
+				exists := make([]bool, len(keys))
+				for i := range keys {
+					exists[i], err = fhm.Exists(keys[i])
+					assert.NoErrorf(t, err, "checks existence of record #%d", i)
+				}
+				_, errLength := fhm.Exists([]byte{1})
+
+				// Check
+				for i := range keys {
+					assert.Equalf(t, i >= 100, exists[i], "existence of record #%d", i)
+				}
+				assert.ErrorIs(t, errLength, crt.WrongLength{}, "key of wrong length is rejected")
+				stats := fhm.OperationStats()
+				assert.Equal(t, int64(201), stats.Gets, "each check is counted as a get")
+				assert.Equal(t, int64(100), stats.GetMisses, "checks of missing records are counted as misses")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestCount(t *testing.T) {
+	t.Run("count tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("counts records without scanning for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				countEmpty, err := fhm.Count()
+				assert.NoError(t, err, "counts records in empty map")
+
+				keys := make([][]byte, 150)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], make([]byte, 10))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+				for i := 0; i < 50; i++ {
+					_, err = fhm.Pop(keys[i])
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+				err = fhm.SetBatch([]Record{{Key: keys[0], Value: make([]byte, 10)}, {Key: keys[100], Value: make([]byte, 10)}})
+				assert.NoError(t, err, "sets batch of one new and one existing record")
+
+				// Execute
+				count, errCount := fhm.Count()
+				loadFactor, errLoadFactor := fhm.LoadFactor()
+				deletedRatio, errDeletedRatio := fhm.DeletedRatio()
+				hms, errStat := fhm.Stat(false)
+				params := fhm.fileManagement.GetStorageParameters()
+
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "opens existing files")
+				recount, errRecount := fhm.Count()
+				reDeletedRatio, errReDeletedRatio := fhm.DeletedRatio()
+
+				err = fhm.Clear()
+				assert.NoError(t, err, "clears file hash map")
+				countCleared, errCleared := fhm.Count()
+
+				// Check
+				assert.NoError(t, errCount, "counts records")
+				assert.NoError(t, errLoadFactor, "gets load factor")
+				assert.NoError(t, errDeletedRatio, "gets deleted ratio")
+				assert.NoError(t, errStat, "gets stat")
+				assert.NoError(t, errRecount, "counts records of reopened files")
+				assert.NoError(t, errReDeletedRatio, "gets deleted ratio of reopened files")
+				assert.NoError(t, errCleared, "counts records of cleared files")
+				assert.Equal(t, int64(0), countEmpty, "empty map has no records")
+				assert.Equal(t, int64(101), count, "count of records set and not popped")
+				assert.Equal(t, int64(hms.Records), count, "count agrees with stat")
+				assert.InDelta(t, float64(count)/float64(params.NumberOfBucketsAvailable*params.RecordsPerBucket), loadFactor, 1e-9, "load factor")
+				assert.Greater(t, deletedRatio, 0.0, "popped records leave deleted records")
+				assert.Equal(t, count, recount, "count of reopened files")
+				assert.InDelta(t, deletedRatio, reDeletedRatio, 1e-9, "deleted ratio of reopened files")
+				assert.Equal(t, int64(0), countCleared, "cleared map has no records")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestGetOrSet(t *testing.T) {
+	t.Run("get or set tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("gets or sets records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 500)
+				values := make([][]byte, 500)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					values[i] = make([]byte, 10)
+					rand.Read(values[i])
+				}
+
+				// Execute
+				for i := range keys {
+					existing, loaded, err := fhm.GetOrSet(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d", i)
+					assert.Falsef(t, loaded, "record #%d is not loaded when absent", i)
+					assert.Nilf(t, existing, "no existing value for record #%d", i)
+				}
+				seq := fhm.LastSeq()
+
+				// Check
+				for i := range keys {
+					existing, loaded, err := fhm.GetOrSet(keys[i], make([]byte, 10))
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, loaded, "record #%d is loaded when existing", i)
+					assert.Truef(t, utils.IsEqual(values[i], existing), "existing value of record #%d", i)
+
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(values[i], value), "value of record #%d is kept", i)
+				}
+				assert.Equal(t, int64(500), seq, "sequence number advanced for each record set")
+				assert.Equal(t, seq, fhm.LastSeq(), "sequence number kept when records are loaded")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("looks up before setting with WAL and variable length keys", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 0, 10, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+
+		key := []byte("a key of some length")
+		value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+		// Execute
+		_, loadedFirst, errFirst := fhm.GetOrSet(key, value)
+		existing, loadedSecond, errSecond := fhm.GetOrSet(key, make([]byte, 10))
+
+		// Check
+		assert.NoError(t, errFirst, "sets record")
+		assert.False(t, loadedFirst, "record is not loaded when absent")
+		assert.NoError(t, errSecond, "gets record")
+		assert.True(t, loadedSecond, "record is loaded when existing")
+		assert.True(t, utils.IsEqual(value, existing), "existing value")
+		assert.Equal(t, int64(1), fhm.LastSeq(), "one mutation")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("returns value without checksum", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil, FeatureChecksums)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		_, _, err = fhm.GetOrSet(key, value)
+		assert.NoError(t, err, "sets record")
+
+		// Execute
+		existing, loaded, err := fhm.GetOrSet(key, make([]byte, 10))
+
+		// Check
+		assert.NoError(t, err, "gets record")
+		assert.True(t, loaded, "record is loaded")
+		assert.True(t, utils.IsEqual(value, existing), "existing value")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	t.Run("sets records only if absent", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.QuadraticProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		first, second := make([]byte, 10), make([]byte, 10)
+		first[0], second[0] = 1, 2
+
+		// Execute
+		existingFirst, storedFirst, errFirst := fhm.SetIfAbsent(key, first)
+		existingSecond, storedSecond, errSecond := fhm.SetIfAbsent(key, second)
+		value, err := fhm.Get(key)
+
+		// Check
+		assert.NoError(t, errFirst, "sets absent record")
+		assert.True(t, storedFirst, "absent record stored")
+		assert.Nil(t, existingFirst, "no existing value for absent record")
+		assert.NoError(t, errSecond, "skips present record")
+		assert.False(t, storedSecond, "present record not stored")
+		assert.Equal(t, first, existingSecond, "existing value returned")
+		assert.NoError(t, err, "gets record")
+		assert.Equal(t, first, value, "first value kept")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestCAS(t *testing.T) {
+	t.Run("sets new value only if current value is expected", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.DoubleHashing, 100, 2, 16, 8, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key, missing := make([]byte, 16), make([]byte, 16)
+		key[0] = 1
+		zero, one, two := make([]byte, 8), make([]byte, 8), make([]byte, 8)
+		one[0], two[0] = 1, 2
+		err = fhm.Set(key, zero)
+		assert.NoError(t, err, "sets record to file")
+
+		// Execute
+		errSwapped := fhm.CAS(key, zero, one)
+		errConflict := fhm.CAS(key, zero, two)
+		errMissing := fhm.CAS(missing, zero, one)
+		value, err := fhm.Get(key)
+
+		// Check
+		assert.NoError(t, errSwapped, "swaps expected value")
+		assert.ErrorIs(t, errConflict, crt.ValueConflict{}, "conflict on unexpected value")
+		var conflict crt.ValueConflict
+		assert.ErrorAs(t, errConflict, &conflict, "conflict holds current value")
+		assert.Equal(t, one, conflict.Actual, "current value in conflict")
+		assert.ErrorIs(t, errMissing, crt.NoRecordFound{}, "missing record not found")
+		assert.NoError(t, err, "gets record")
+		assert.Equal(t, one, value, "swapped value stored")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("update tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 8, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 8, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 4, keyLength: 16, valueLength: 8, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 5, keyLength: 16, valueLength: 8, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 8, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 8, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 8, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 8, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 8, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("updates records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc, FeatureChecksums)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 100)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], make([]byte, 8))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+				increment := func(value []byte) (newValue []byte, err error) {
+					newValue = make([]byte, 8)
+					binary.LittleEndian.PutUint64(newValue, binary.LittleEndian.Uint64(value)+1)
+					return
+				}
+
+				// Execute
+				for n := 0; n < 3; n++ {
+					for i := range keys {
+						err = fhm.Update(keys[i], increment)
+						assert.NoErrorf(t, err, "updates record #%d", i)
+					}
+				}
+				errMissing := fhm.Update(make([]byte, 16), increment)
+				errAborted := fhm.Update(keys[0], func(value []byte) ([]byte, error) { return nil, fmt.Errorf("aborted") })
+
+				// Check
+				for i := range keys {
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Equalf(t, uint64(3), binary.LittleEndian.Uint64(value), "record #%d updated three times", i)
+				}
+				assert.ErrorIs(t, errMissing, crt.NoRecordFound{}, "missing record not found")
+				assert.EqualError(t, errAborted, "aborted", "error from fn returned")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestSwap(t *testing.T) {
+	t.Run("swap tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("swaps values for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 50)
+				values := make([][]byte, 50)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					values[i] = make([]byte, 10)
+					rand.Read(values[i])
+					err = fhm.Set(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+				seq := fhm.LastSeq()
+
+				// Execute
+				for i := 0; i < len(keys); i += 2 {
+					err = fhm.Swap(keys[i], keys[i+1])
+					assert.NoErrorf(t, err, "swaps records #%d and #%d", i, i+1)
+				}
+
+				// Check
+				for i := range keys {
+					value, err := fhm.Get(keys[i])
+					assert.NoErrorf(t, err, "gets record #%d", i)
+					assert.Truef(t, utils.IsEqual(values[i^1], value), "value of record #%d is swapped", i)
+				}
+				assert.Equal(t, seq+int64(len(keys)), fhm.LastSeq(), "sequence number advanced for each change")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("keeps key file addresses and WAL coverage with variable length keys", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 0, 10, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+
+		keyA, keyB := []byte("promoted entry"), []byte("demoted")
+		valueA, valueB := []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, []byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+		err = fhm.Set(keyA, valueA)
+		assert.NoError(t, err, "sets first record")
+		err = fhm.Set(keyB, valueB)
+		assert.NoError(t, err, "sets second record")
+
+		// Execute
+		err = fhm.Swap(keyA, keyB)
+
+		// Check
+		assert.NoError(t, err, "swaps records")
+		got, err := fhm.Get(keyA)
+		assert.NoError(t, err, "gets first record")
+		assert.True(t, utils.IsEqual(valueB, got), "first record has second value")
+		got, err = fhm.Get(keyB)
+		assert.NoError(t, err, "gets second record")
+		assert.True(t, utils.IsEqual(valueA, got), "second record has first value")
+
+		asOf, err := fhm.GetAsOf(keyA, 2)
+		assert.NoError(t, err, "gets first record as before swap")
+		assert.True(t, utils.IsEqual(valueA, asOf), "value before swap from WAL")
+
+		report, err := fhm.Verify()
+		assert.NoError(t, err, "verifies files")
+		assert.True(t, report.OK(), "key file addresses intact")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("keeps expiry times", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil, FeatureTTL)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		keyA, keyB := make([]byte, 16), make([]byte, 16)
+		keyB[0] = 1
+		valueA, valueB := []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, []byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+		err = fhm.SetWithTTL(keyA, valueA, 50*time.Millisecond)
+		assert.NoError(t, err, "sets expiring record")
+		err = fhm.Set(keyB, valueB)
+		assert.NoError(t, err, "sets record that never expires")
+
+		// Execute
+		err = fhm.Swap(keyA, keyB)
+
+		// Check
+		assert.NoError(t, err, "swaps records")
+		got, err := fhm.Get(keyA)
+		assert.NoError(t, err, "gets expiring record before expiry")
+		assert.True(t, utils.IsEqual(valueB, got), "expiring record has second value")
+
+		time.Sleep(60 * time.Millisecond)
+		_, err = fhm.Get(keyA)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "expiry time kept by key")
+		got, err = fhm.Get(keyB)
+		assert.NoError(t, err, "gets record that never expires")
+		assert.True(t, utils.IsEqual(valueA, got), "record that never expires has first value")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("fails without change if a record is missing", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil, FeatureChecksums)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key, missing := make([]byte, 16), make([]byte, 16)
+		missing[0] = 1
+		value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record")
+
+		// Execute
+		err = fhm.Swap(key, missing)
+
+		// Check
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "missing record")
+		got, err := fhm.Get(key)
+		assert.NoError(t, err, "gets record")
+		assert.True(t, utils.IsEqual(value, got), "value unchanged")
+		assert.Equal(t, int64(1), fhm.LastSeq(), "no mutation")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestStat(t *testing.T) {
+	t.Run("stat tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 1000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1001, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1001, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1001, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "SeparateChainingCustomHash", buckets: 1000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: NewSeparateChainingHashAlgorithm(1000)},
+			{crtName: "LinearProbingCustomHash", buckets: 1001, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: NewLinearProbingHashAlgorithm(1001)},
+			{crtName: "QuadraticProbingCustomHash", buckets: 1001, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing, hFunc: NewQuadraticProbingHashAlgorithm(1001)},
+			{crtName: "DoubleHashingCustomHash", buckets: 1001, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing, hFunc: NewDoubleHashingHashAlgorithm(1001)},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("produces statistics without distribution for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 1001)
+				values := make([][]byte, 1001)
+
+				for i := 0; i < 1001; i++ {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					values[i] = make([]byte, 10)
+					rand.Read(values[i])
+
+					err = fhm.Set(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				// Execute
+				stat, err := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, err, "gets statistics")
+				assert.Equal(t, 1001, stat.Records, "correct number of record reported")
+				assert.NotZero(t, stat.MapFileRecords, "map file is used")
+				if test.crt == crt.SeparateChaining {
+					assert.NotZero(t, stat.OverflowRecords, "overflow file is used")
+				} else {
+					assert.Zero(t, stat.OverflowRecords, "overflow file is not used")
+				}
+				assert.Nil(t, stat.BucketDistribution, "no distribution is provided")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+
+				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "map file removed")
+				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "overflow file removed")
+			})
+
+			t.Run(fmt.Sprintf("produces statistics with distribution for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, nil)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 1001)
+				values := make([][]byte, 1001)
+
+				for i := 0; i < 1001; i++ {
+					keys[i] = make([]byte, 16)
+					rand.Read(keys[i])
+					values[i] = make([]byte, 10)
+					rand.Read(values[i])
+
+					err = fhm.Set(keys[i], values[i])
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				sp := fhm.fileManagement.GetStorageParameters()
+
+				// Execute
+				stat, err := fhm.Stat(true)
+
+				// Check
+				assert.NoError(t, err, "gets statistics")
+				assert.Equal(t, 1001, stat.Records, "correct number of record reported")
+				assert.NotZero(t, stat.MapFileRecords, "map file is used")
+				if test.crt == crt.SeparateChaining {
+					assert.NotZero(t, stat.OverflowRecords, "overflow file is used")
+				} else {
+					assert.Zero(t, stat.OverflowRecords, "overflow file is not used")
+				}
+				assert.Equal(t, int(sp.NumberOfBucketsAvailable), len(stat.BucketDistribution), "bucket distribution has correct length")
+				var dRecords int
+				for _, v := range stat.BucketDistribution {
+					dRecords += v
+				}
+				assert.Equal(t, 1001, dRecords, "correct number of records reported in distribution")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+
+				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "map file removed")
+				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
+				assert.True(t, os.IsNotExist(err), "overflow file removed")
+			})
+		}
+	})
+}
+
+// distributionRecorder - Is a DistributionSink that records what it is given
+type distributionRecorder struct {
+	bucketNos []int64
+	counts    []int
+}
+
+func (d *distributionRecorder) Add(bucketNo int64, count int) {
+	d.bucketNos = append(d.bucketNos, bucketNo)
+	d.counts = append(d.counts, count)
+}
+
+func TestStatWithSink(t *testing.T) {
+	t.Run("streams distribution into sink", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		for i := 0; i < 300; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+
+		stat, err := fhm.Stat(true)
+		assert.NoError(t, err, "gets statistics with distribution")
+		sink := &distributionRecorder{}
+
+		// Execute
+		sinkStat, err := fhm.StatWithSink(sink)
+
+		// Check
+		assert.NoError(t, err, "gets statistics with sink")
+		assert.Equal(t, stat.Records, sinkStat.Records, "same number of records")
+		assert.Nil(t, sinkStat.BucketDistribution, "no distribution slice")
+		assert.Equal(t, stat.BucketDistribution, sink.counts, "same distribution")
+		for i, bucketNo := range sink.bucketNos {
+			assert.Equalf(t, int64(i), bucketNo, "bucket #%d in order", i)
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestStatStream(t *testing.T) {
+	t.Run("streams distribution into callback", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		for i := 0; i < 300; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d to file", i)
+		}
+
+		stat, err := fhm.Stat(true)
+		assert.NoError(t, err, "gets statistics with distribution")
+		var bucketNos []int64
+		var counts []int
+
+		// Execute
+		streamStat, err := fhm.StatStream(func(bucketNo int64, records int) error {
+			bucketNos = append(bucketNos, bucketNo)
+			counts = append(counts, records)
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "gets statistics with callback")
+		assert.Equal(t, stat.Records, streamStat.Records, "same number of records")
+		assert.Nil(t, streamStat.BucketDistribution, "no distribution slice")
+		assert.Equal(t, stat.BucketDistribution, counts, "same distribution")
+		for i, bucketNo := range bucketNos {
+			assert.Equalf(t, int64(i), bucketNo, "bucket #%d in order", i)
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("stops when callback returns an error", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		stop := fmt.Errorf("stop")
+		var calls int
+
+		// Execute
+		streamStat, err := fhm.StatStream(func(bucketNo int64, records int) error {
+			calls++
+			if bucketNo == 9 {
+				return stop
+			}
+			return nil
+		})
+
+		// Check
+		assert.ErrorIs(t, err, stop, "error from callback is returned")
+		assert.Nil(t, streamStat, "no statistics")
+		assert.Equal(t, 10, calls, "walk stops at error")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestStat_Histograms(t *testing.T) {
+	t.Run("histogram tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 100, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 100, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 100, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("produces probe and chain length histograms for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				for i := 0; i < 120; i++ {
+					key := make([]byte, test.keyLength)
+					rand.Read(key)
+					err = fhm.Set(key, make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				sp := fhm.fileManagement.GetStorageParameters()
+
+				// Execute
+				stat, err := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, err, "gets statistics")
+
+				var probed, probeLengthSum int
+				for probeLength, n := range stat.ProbeLengths {
+					probed += n
+					probeLengthSum += probeLength * n
+				}
+				assert.Equal(t, stat.MapFileRecords, probed, "every record in map file has a probe length")
+				assert.Equal(t, len(stat.ProbeLengths)-1, stat.MaxProbeLength, "max probe length")
+				assert.NotZero(t, stat.ProbeLengths[stat.MaxProbeLength], "max probe length is in histogram")
+				assert.InDelta(t, float64(probeLengthSum)/float64(probed), stat.MeanProbeLength, 1e-9, "mean probe length")
+
+				if test.crt == crt.SeparateChaining || test.crt == crt.Hybrid || test.crt == crt.LinearHashing {
+					var buckets, chainLengthSum int
+					for chainLength, n := range stat.ChainLengths {
+						buckets += n
+						chainLengthSum += chainLength * n
+					}
+					assert.Equal(t, int(sp.NumberOfBucketsAvailable), buckets, "every bucket has a chain length")
+					assert.GreaterOrEqual(t, chainLengthSum, stat.OverflowRecords, "chains hold all overflow records and any deleted ones")
+					assert.Equal(t, len(stat.ChainLengths)-1, stat.MaxChainLength, "max chain length")
+					assert.InDelta(t, float64(chainLengthSum)/float64(buckets), stat.MeanChainLength, 1e-9, "mean chain length")
+				} else {
+					assert.Nil(t, stat.ChainLengths, "no chain lengths without overflow file")
+					assert.Zero(t, stat.MaxChainLength, "no max chain length without overflow file")
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestStat_Chunks(t *testing.T) {
+	t.Run("distribution read in chunks for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 50000, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 50000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "Hybrid", buckets: 50000, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "RobinHood", buckets: 50000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 50000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 50000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("matches buckets read one by one for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				for i := 0; i < 2000; i++ {
+					key := make([]byte, test.keyLength)
+					rand.Read(key)
+					err = fhm.Set(key, make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				sp := fhm.fileManagement.GetStorageParameters()
+				expected := make([]int, sp.NumberOfBucketsAvailable)
+				for i := range expected {
+					bucket, iter, err := fhm.fileManagement.GetBucket(int64(i))
+					assert.NoErrorf(t, err, "gets bucket #%d", i)
+					for _, r := range bucket.Records {
+						if r.State == StateOccupied {
+							expected[i]++
+						}
+					}
+					for iter != nil && iter.HasNext() {
+						r, err := iter.Next()
+						assert.NoErrorf(t, err, "gets overflow record of bucket #%d", i)
+						if r.State == StateOccupied {
+							expected[i]++
+						}
+					}
+				}
+
+				// Execute
+				stat, err := fhm.Stat(true)
+
+				// Check
+				assert.NoError(t, err, "gets statistics")
+				assert.Equal(t, 2000, stat.Records, "records")
+				assert.Equal(t, expected, stat.BucketDistribution, "bucket distribution")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestStat_FileSizes(t *testing.T) {
+	t.Run("file size tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("reports file sizes and load factor for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 100)
+				for i := range keys {
+					keys[i] = make([]byte, test.keyLength)
+					rand.Read(keys[i])
+					err = fhm.Set(keys[i], make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+				for i := 0; i < 20; i++ {
+					_, err = fhm.Pop(keys[i])
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+
+				sp := fhm.fileManagement.GetStorageParameters()
+
+				// Execute
+				stat, err := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, err, "gets statistics")
+
+				mapInfo, err := os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
+				assert.NoError(t, err, "gets map file size")
+				assert.Equal(t, mapInfo.Size(), stat.MapFileBytes, "map file size")
+
+				ovflInfo, err := os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
+				if test.crt == crt.SeparateChaining || test.crt == crt.Hybrid || test.crt == crt.LinearHashing {
+					assert.NoError(t, err, "gets overflow file size")
+					assert.Equal(t, ovflInfo.Size(), stat.OverflowFileBytes, "overflow file size")
+					_, ovflDeleted, err := fhm.fileManagement.(*separatechaining.SCFiles).OverflowCounts()
+					assert.NoError(t, err, "gets overflow counts")
+					assert.Equal(t, int(ovflDeleted), stat.OverflowDeletedRecords, "deleted records in overflow")
+				} else {
+					assert.Zero(t, stat.OverflowFileBytes, "no overflow file")
+					assert.Zero(t, stat.OverflowDeletedRecords, "no deleted records in overflow")
+				}
+
+				assert.InDelta(t, 80/float64(sp.NumberOfBucketsAvailable*sp.RecordsPerBucket), stat.LoadFactor, 1e-9, "load factor")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("reports map file size for hash map held in memory", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewMemoryHashMap(crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new hash map in memory")
+
+		// Execute
+		stat, err := fhm.Stat(false)
+
+		// Check
+		assert.NoError(t, err, "gets statistics")
+		assert.Equal(t, fhm.fileManagement.GetStorageParameters().MapFileSize, stat.MapFileBytes, "map file size")
+		assert.Zero(t, stat.OverflowFileBytes, "no overflow file")
+		assert.Zero(t, stat.LoadFactor, "empty hash map")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes hash map")
+	})
+}
+
+func TestExport(t *testing.T) {
+	t.Run("export tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("exports records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 300)
+				values := make(map[string][]byte)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+					values[string(records[i].Key)] = records[i].Value
+				}
+				err = fhm.SetBatch(records)
+				assert.NoError(t, err, "sets batch")
+
+				for i := 200; i < 300; i++ {
+					_, err = fhm.Pop(records[i].Key)
+					assert.NoErrorf(t, err, "pops record #%d", i)
+				}
+
+				// Execute
+				var batches, occupied, deleted int
+				err = fhm.Export(64, true, func(batch *ExportBatch) error {
+					batches++
+					assert.LessOrEqual(t, len(batch.Keys), 64, "batch size respected")
+					for i := range batch.Keys {
+						assert.Len(t, batch.Values, len(batch.Keys), "same length of all columns")
+						assert.Len(t, batch.Buckets, len(batch.Keys), "same length of all columns")
+						assert.Len(t, batch.States, len(batch.Keys), "same length of all columns")
+						assert.Len(t, batch.IsOverflow, len(batch.Keys), "same length of all columns")
+						assert.Len(t, batch.AccessCounts, len(batch.Keys), "same length of all columns")
+						if batch.States[i] == StateOccupied {
+							occupied++
+							assert.True(t, utils.IsEqual(values[string(batch.Keys[i])], batch.Values[i]), "correct value")
+						} else {
+							deleted++
+						}
+					}
+					return nil
+				})
+
+				// Check
+				assert.NoError(t, err, "exports records")
+				assert.Equal(t, 200, occupied, "all occupied records exported")
+				assert.GreaterOrEqual(t, batches, 4, "exported in batches")
+				if test.crt != crt.SeparateChaining {
+					assert.Equal(t, 100, deleted, "all deleted records exported")
+				}
+
+				err = fhm.Export(64, false, func(batch *ExportBatch) error { return fmt.Errorf("stop") })
+				assert.EqualError(t, err, "stop", "error from fn is returned")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestExport_Metadata(t *testing.T) {
+	t.Run("exports expiry times", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil, FeatureTTL)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		before := time.Now()
+		err = fhm.SetWithTTL(key, make([]byte, 10), time.Hour)
+		assert.NoError(t, err, "sets record with ttl")
+
+		// Execute
+		var expiries, created []int64
+		err = fhm.Export(10, false, func(batch *ExportBatch) error {
+			expiries = append(expiries, batch.Expiries...)
+			created = append(created, batch.Created...)
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "exports records")
+		assert.Len(t, expiries, 1, "expiry exported")
+		assert.GreaterOrEqual(t, expiries[0], before.Add(time.Hour).UnixNano(), "expiry time")
+		assert.Nil(t, created, "no timestamps exported")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("exports timestamps", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil, FeatureTimestamps)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		before := time.Now().UnixNano()
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+		after := time.Now().UnixNano()
+
+		// Execute
+		var expiries, created, modified []int64
+		err = fhm.Export(10, false, func(batch *ExportBatch) error {
+			expiries = append(expiries, batch.Expiries...)
+			created = append(created, batch.Created...)
+			modified = append(modified, batch.Modified...)
+			return nil
+		})
+
+		// Check
+		assert.NoError(t, err, "exports records")
+		assert.Nil(t, expiries, "no expiry times exported")
+		assert.Len(t, created, 1, "created time exported")
+		assert.Len(t, modified, 1, "modified time exported")
+		assert.True(t, created[0] >= before && created[0] <= after, "created time")
+		assert.Equal(t, created[0], modified[0], "modified time")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestAccessCounting(t *testing.T) {
+	t.Run("access counting tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 1, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("counts accesses made by get for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := make([][]byte, 3)
+				for i := range keys {
+					keys[i] = make([]byte, 16)
+					keys[i][0] = byte(i)
+					err = fhm.Set(keys[i], make([]byte, 10))
+					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				}
+
+				// Execute
+				fhm.EnableAccessCounting(2)
+				for i := 0; i < 100; i++ {
+					_, err = fhm.Get(keys[0])
+					assert.NoError(t, err, "gets hot record")
+				}
+				_, err = fhm.Get(keys[1])
+				assert.NoError(t, err, "gets cold record")
+
+				// Check
+				count, err := fhm.GetAccessCount(keys[0])
+				assert.NoError(t, err, "gets access count")
+				assert.Equal(t, 63, count, "access count saturates")
+				count, err = fhm.GetAccessCount(keys[1])
+				assert.NoError(t, err, "gets access count")
+				assert.Equal(t, 1, count, "access count for cold record")
+
+				err = fhm.DisableAccessCounting()
+				assert.NoError(t, err, "flushes pending counts")
+				count, err = fhm.GetAccessCount(keys[1])
+				assert.NoError(t, err, "gets access count")
+				assert.Equal(t, 1, count, "access count persisted")
+				count, err = fhm.GetAccessCount(keys[2])
+				assert.NoError(t, err, "gets access count")
+				assert.Equal(t, 0, count, "no access registered")
+
+				_, err = fhm.Pop(keys[1])
+				assert.NoError(t, err, "pops record")
+				err = fhm.Set(keys[1], make([]byte, 10))
+				assert.NoError(t, err, "sets record again")
+				count, err = fhm.GetAccessCount(keys[1])
+				assert.NoError(t, err, "gets access count")
+				assert.Equal(t, 0, count, "access count reset when record is deleted")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
+
+func TestEviction(t *testing.T) {
+	t.Run("evicts records with the lowest access counts when full", func(t *testing.T) {
+		// Prepare
+		fhm, hashMapInfo, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		capacity := hashMapInfo.NumberOfBucketsAvailable * 2
+		keys := make([][]byte, capacity)
+		value := make([]byte, 10)
+		for i := range keys {
+			keys[i] = make([]byte, 16)
+			rand.Read(keys[i])
+			err = fhm.Set(keys[i], value)
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		extraKey := make([]byte, 16)
+		rand.Read(extraKey)
+		errFull := fhm.Set(extraKey, value)
+
+		err = fhm.EnableClockEviction(1)
+		assert.NoError(t, err, "enables eviction")
+
+		// Every other record is read, which gives it an access count
+		for i := 0; i < capacity; i += 2 {
+			_, err = fhm.Get(keys[i])
+			assert.NoErrorf(t, err, "gets record #%d", i)
+		}
+
+		// Execute
+		newKeys := make([][]byte, capacity/2)
+		for i := range newKeys {
+			newKeys[i] = make([]byte, 16)
+			rand.Read(newKeys[i])
+			err = fhm.Set(newKeys[i], value)
+			assert.NoErrorf(t, err, "sets new record #%d", i)
+		}
+
+		// Check
+		assert.ErrorIs(t, errFull, crt.MapFileFull{}, "map file full without eviction")
+
+		for i := 0; i < capacity; i++ {
+			_, err = fhm.Get(keys[i])
+			if i%2 == 0 {
+				assert.NoErrorf(t, err, "hot record #%d kept", i)
+			} else {
+				assert.ErrorIsf(t, err, crt.NoRecordFound{}, "cold record #%d evicted", i)
+			}
+		}
+		for i := range newKeys {
+			_, err = fhm.Get(newKeys[i])
+			assert.NoErrorf(t, err, "new record #%d found", i)
+		}
+		assert.Equal(t, int64(capacity/2), fhm.OperationStats().Evictions, "evictions counted")
+
+		fhm.DisableClockEviction()
+		err = fhm.Set(extraKey, value)
+		assert.ErrorIs(t, err, crt.MapFileFull{}, "map file full when eviction is disabled")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("never returns map file full while enabled", func(t *testing.T) {
+		for _, crtType := range []int{crt.LinearProbing, crt.QuadraticProbing, crt.DoubleHashing, crt.RobinHood} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crtType, 64, 1, 16, 10, nil)
+			assert.NoErrorf(t, err, "create new file hash map struct with crt %d", crtType)
+			err = fhm.EnableClockEviction(1)
+			assert.NoErrorf(t, err, "enables eviction with crt %d", crtType)
+
+			// Execute
+			value := make([]byte, 10)
+			failed := 0
+			for i := 0; i < 3000; i++ {
+				key := make([]byte, 16)
+				rand.Read(key)
+				if err = fhm.Set(key, value); err != nil {
+					failed++
+				}
+			}
+
+			// Check
+			assert.Zerof(t, failed, "all sets succeed with crt %d", crtType)
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		}
+	})
+
+	t.Run("eviction not supported for other techniques", func(t *testing.T) {
+		for _, crtType := range []int{crt.SeparateChaining, crt.CuckooHashing, crt.Hopscotch} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crtType, 10, 2, 16, 10, nil)
+			assert.NoErrorf(t, err, "create new file hash map struct with crt %d", crtType)
+
+			// Execute
+			err = fhm.EnableClockEviction(1)
+
+			// Check
+			assert.Errorf(t, err, "eviction not supported with crt %d", crtType)
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		}
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	t.Run("concurrent operations for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 1000, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 1000, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 1000, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
 		}
+
 		for _, test := range tests {
-			t.Run(fmt.Sprintf("pops records for %s", test.crtName), func(t *testing.T) {
+			t.Run(fmt.Sprintf("sets, gets and pops concurrently for %s", test.crtName), func(t *testing.T) {
 				// Prepare
 				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
 				assert.NoError(t, err, "create new file hash map struct")
-
-				keys := make([][]byte, 1000)
-				values := make([][]byte, 1000)
-
-				for i := 0; i < 1000; i++ {
-					keys[i] = make([]byte, 16)
-					rand.Read(keys[i])
-					values[i] = make([]byte, 10)
-					rand.Read(values[i])
-
-					err = fhm.Set(keys[i], values[i])
-					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				fhm.EnableAccessCounting(10)
+
+				workers := 8
+				perWorker := 50
+				records := make([][]Record, workers)
+				for w := range records {
+					records[w] = make([]Record, perWorker)
+					for i := range records[w] {
+						records[w][i] = Record{Key: make([]byte, 16), Value: make([]byte, 10)}
+						rand.Read(records[w][i].Key)
+						rand.Read(records[w][i].Value)
+					}
 				}
 
 				// Execute
-				var value []byte
-				for i := 0; i < 1000; i++ {
-					value, err = fhm.Pop(keys[i])
-					assert.NoErrorf(t, err, "pops record #%d", i)
-					assert.Truef(t, utils.IsEqual(values[i], value), "record #%d has correct crt", i)
+				var wg sync.WaitGroup
+				errs := make(chan error, workers*perWorker*3)
+				for w := 0; w < workers; w++ {
+					wg.Add(1)
+					go func(records []Record) {
+						defer wg.Done()
+						for i, record := range records {
+							if err := fhm.Set(record.Key, record.Value); err != nil {
+								errs <- err
+							}
+							value, err := fhm.Get(record.Key)
+							if err != nil {
+								errs <- err
+							} else if !utils.IsEqual(record.Value, value) {
+								errs <- fmt.Errorf("wrong value for record #%d", i)
+							}
+							if i%2 == 0 {
+								if _, err = fhm.Pop(record.Key); err != nil {
+									errs <- err
+								}
+							}
+						}
+					}(records[w])
 				}
+				wg.Wait()
+				close(errs)
 
 				// Check
-				for i := 0; i < 1000; i++ {
-					value, err = fhm.Get(keys[i])
-					assert.ErrorIsf(t, err, crt.NoRecordFound{}, "gets correct error when geting poped record #%d", i)
-					assert.Nilf(t, value, "crt is nil in record #%d", i)
+				for err = range errs {
+					assert.NoError(t, err, "no error from concurrent operations")
 				}
 
+				stat, err := fhm.Stat(false)
+				assert.NoError(t, err, "gets statistics")
+				assert.Equal(t, workers*perWorker/2, stat.Records, "correct number of records")
+				assert.Equal(t, int64(workers*perWorker*3/2), fhm.LastSeq(), "one sequence number per mutation")
+
 				// Clean up
 				err = fhm.RemoveFiles()
 				assert.NoError(t, err, "removes files")
-
-				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "map file removed")
-				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "overflow file removed")
 			})
 		}
 	})
+
+	t.Run("exports concurrently with sets while growing", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableAutoGrow(0.75)
+		assert.NoError(t, err, "enables auto grow")
+
+		// Execute
+		var wg sync.WaitGroup
+		errs := make(chan error, 1000)
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := fhm.Export(10, false, func(batch *ExportBatch) error { return nil }); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		for i := 0; i < 500; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			if err := fhm.Set(key, make([]byte, 10)); err != nil {
+				errs <- err
+			}
+		}
+		close(stop)
+		wg.Wait()
+		close(errs)
+
+		// Check
+		for err = range errs {
+			assert.NoError(t, err, "no error from concurrent export and sets")
+		}
+		count, err := fhm.Count()
+		assert.NoError(t, err, "counts records")
+		assert.Equal(t, int64(500), count, "all records set")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
 }
 
-func TestStat(t *testing.T) {
-	t.Run("stat tests for all CRTs", func(t *testing.T) {
+func TestSetIdempotent(t *testing.T) {
+	t.Run("skips retried operations also after reopen", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		value1 := []byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25}
+		value2 := []byte{25, 24, 23, 22, 21, 20, 19, 18, 17, 16}
+
+		_, err = fhm.SetIdempotent([]byte("op-1"), key, value1)
+		assert.Error(t, err, "operation log not enabled")
+
+		err = fhm.EnableOperationLog(100)
+		assert.NoError(t, err, "enables operation log")
+
+		// Execute
+		applied1, err1 := fhm.SetIdempotent([]byte("op-1"), key, value1)
+		applied2, err2 := fhm.SetIdempotent([]byte("op-1"), key, value2)
+
+		// Check
+		assert.NoError(t, err1, "first operation")
+		assert.NoError(t, err2, "retried operation")
+		assert.True(t, applied1, "first operation applied")
+		assert.False(t, applied2, "retried operation skipped")
+
+		value, err := fhm.Get(key)
+		assert.NoError(t, err, "gets record")
+		assert.True(t, utils.IsEqual(value1, value), "value from first operation")
+
+		fhm.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+		err = fhm.EnableOperationLog(100)
+		assert.NoError(t, err, "reopens operation log")
+
+		applied, err := fhm.SetIdempotent([]byte("op-1"), key, value2)
+		assert.NoError(t, err, "retried operation after reopen")
+		assert.False(t, applied, "retried operation skipped after reopen")
+		applied, err = fhm.SetIdempotent([]byte("op-2"), key, value2)
+		assert.NoError(t, err, "new operation after reopen")
+		assert.True(t, applied, "new operation applied after reopen")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		_, err = os.Stat(fmt.Sprintf("%s-oplog-map.bin", testHashMap))
+		assert.True(t, os.IsNotExist(err), "operation log map file removed")
+	})
+}
+
+func TestWAL(t *testing.T) {
+	t.Run("reads values as of earlier sequence numbers", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		value1 := []byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25}
+		value2 := []byte{25, 24, 23, 22, 21, 20, 19, 18, 17, 16}
+
+		err = fhm.Set(key, value1)
+		assert.NoError(t, err, "sets record before WAL is enabled")
+
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "enables WAL")
+
+		seq0 := fhm.LastSeq()
+		seq1, err := fhm.SetWithSeq(key, value2)
+		assert.NoError(t, err, "updates record")
+		_, seq2, err := fhm.PopWithSeq(key)
+		assert.NoError(t, err, "pops record")
+		seq3, err := fhm.SetWithSeq(key, value1)
+		assert.NoError(t, err, "sets record again")
+		assert.Equal(t, []int64{seq0 + 1, seq0 + 2, seq0 + 3}, []int64{seq1, seq2, seq3}, "sequence numbers increase")
+
+		// Execute and check
+		value, err := fhm.GetAsOf(key, seq0)
+		assert.NoError(t, err, "gets value before WAL entries")
+		assert.True(t, utils.IsEqual(value1, value), "value before first entry")
+
+		value, err = fhm.GetAsOf(key, seq1)
+		assert.NoError(t, err, "gets value as of first entry")
+		assert.True(t, utils.IsEqual(value2, value), "value as of first entry")
+
+		_, err = fhm.GetAsOf(key, seq2)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "record popped as of second entry")
+
+		value, err = fhm.GetAsOf(key, seq3)
+		assert.NoError(t, err, "gets value as of third entry")
+		assert.True(t, utils.IsEqual(value1, value), "value as of third entry")
+
+		fhm.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+		err = fhm.EnableWAL()
+		assert.NoError(t, err, "reopens WAL")
+
+		assert.Equal(t, seq3, fhm.LastSeq(), "sequence number persisted")
+		value, err = fhm.GetAsOf(key, seq1)
+		assert.NoError(t, err, "gets value as of first entry after reopen")
+		assert.True(t, utils.IsEqual(value2, value), "value as of first entry after reopen")
+
+		err = fhm.CheckpointWAL()
+		assert.NoError(t, err, "checkpoints WAL")
+		_, err = fhm.GetAsOf(key, seq1)
+		assert.Error(t, err, "history discarded by checkpoint")
+		value, err = fhm.GetAsOf(key, seq3)
+		assert.NoError(t, err, "gets value as of last entry after checkpoint")
+		assert.True(t, utils.IsEqual(value1, value), "value as of last entry after checkpoint")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+
+		_, err = os.Stat(fmt.Sprintf("%s-wal.bin", testHashMap))
+		assert.True(t, os.IsNotExist(err), "WAL file removed")
+	})
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	t.Run("gives mutations increasing sequence numbers that survive reopen", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.DoubleHashing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		value := []byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25}
+
+		// Execute
+		seq1, err := fhm.SetWithSeq(key, value)
+		assert.NoError(t, err, "sets record")
+		_, seq2, err := fhm.PopWithSeq(key)
+		assert.NoError(t, err, "pops record")
+		_, err = fhm.Pop(key)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "pops missing record")
+		err = fhm.SetBatch([]Record{{Key: key, Value: value}})
+		assert.NoError(t, err, "sets batch")
+
+		// Check
+		assert.Equal(t, int64(1), seq1, "first sequence number")
+		assert.Equal(t, int64(2), seq2, "second sequence number")
+		assert.Equal(t, int64(3), fhm.LastSeq(), "failed pop is not a mutation")
+
+		fhm.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+
+		stat, err := fhm.Stat(false)
+		assert.NoError(t, err, "gets statistics")
+		assert.Equal(t, int64(3), stat.LastSeq, "sequence number persisted")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestClear(t *testing.T) {
+	t.Run("clears all records for all CRTs", func(t *testing.T) {
 		// Prepare
 		tests := []TestCaseOperations{
-			{crtName: "SeparateChaining", buckets: 1000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
-			{crtName: "LinearProbing", buckets: 1001, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
-			{crtName: "QuadraticProbing", buckets: 1001, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
-			{crtName: "DoubleHashing", buckets: 1001, rpb: 5, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
-			{crtName: "SeparateChainingCustomHash", buckets: 1000, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: NewSeparateChainingHashAlgorithm(1000)},
-			{crtName: "LinearProbingCustomHash", buckets: 1001, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: NewLinearProbingHashAlgorithm(1001)},
-			{crtName: "QuadraticProbingCustomHash", buckets: 1001, rpb: 4, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing, hFunc: NewQuadraticProbingHashAlgorithm(1001)},
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
 		}
 
 		for _, test := range tests {
-			t.Run(fmt.Sprintf("produces statistics without distribution for %s", test.crtName), func(t *testing.T) {
+			t.Run(fmt.Sprintf("clears records for %s", test.crtName), func(t *testing.T) {
 				// Prepare
 				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
 				assert.NoError(t, err, "create new file hash map struct")
+				err = fhm.EnableWAL()
+				assert.NoError(t, err, "enables WAL")
+
+				records := make([]Record, 100)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				err = fhm.SetBatch(records)
+				assert.NoError(t, err, "sets records")
 
-				keys := make([][]byte, 1001)
-				values := make([][]byte, 1001)
+				// Execute
+				err = fhm.Clear()
 
-				for i := 0; i < 1001; i++ {
-					keys[i] = make([]byte, 16)
-					rand.Read(keys[i])
-					values[i] = make([]byte, 10)
-					rand.Read(values[i])
+				// Check
+				assert.NoError(t, err, "clears file hash map")
+				assert.Equal(t, int64(0), fhm.LastSeq(), "sequence number reset")
 
-					err = fhm.Set(keys[i], values[i])
-					assert.NoErrorf(t, err, "sets record #%d to file", i)
+				for _, record := range records {
+					_, err = fhm.Get(record.Key)
+					assert.ErrorIs(t, err, crt.NoRecordFound{}, "record not found after clear")
 				}
 
-				// Execute
 				stat, err := fhm.Stat(false)
-
-				// Check
 				assert.NoError(t, err, "gets statistics")
-				assert.Equal(t, 1001, stat.Records, "correct number of record reported")
-				assert.NotZero(t, stat.MapFileRecords, "map file is used")
-				if test.crt == crt.SeparateChaining {
-					assert.NotZero(t, stat.OverflowRecords, "overflow file is used")
-				} else {
-					assert.Zero(t, stat.OverflowRecords, "overflow file is not used")
-				}
-				assert.Nil(t, stat.BucketDistribution, "no distribution is provided")
+				assert.Equal(t, 0, stat.Records, "no records after clear")
+
+				seq, err := fhm.SetWithSeq(records[0].Key, records[0].Value)
+				assert.NoError(t, err, "sets record after clear")
+				assert.Equal(t, int64(1), seq, "sequence numbers start over")
+
+				value, err := fhm.GetAsOf(records[0].Key, 1)
+				assert.NoError(t, err, "gets value from restarted WAL")
+				assert.Equal(t, records[0].Value, value, "correct value from restarted WAL")
 
 				// Clean up
 				err = fhm.RemoveFiles()
 				assert.NoError(t, err, "removes files")
-
-				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "map file removed")
-				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "overflow file removed")
 			})
+		}
+	})
 
-			t.Run(fmt.Sprintf("produces statistics with distribution for %s", test.crtName), func(t *testing.T) {
-				// Prepare
-				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, nil)
-				assert.NoError(t, err, "create new file hash map struct")
+	t.Run("keeps ttl support", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil, FeatureTTL)
+		assert.NoError(t, err, "create new file hash map struct")
 
-				keys := make([][]byte, 1001)
-				values := make([][]byte, 1001)
+		// Execute
+		err = fhm.Clear()
+		assert.NoError(t, err, "clears file hash map")
+		fhm.CloseFiles()
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
 
-				for i := 0; i < 1001; i++ {
-					keys[i] = make([]byte, 16)
-					rand.Read(keys[i])
-					values[i] = make([]byte, 10)
-					rand.Read(values[i])
+		// Check
+		err = fhm.SetWithTTL(make([]byte, 16), make([]byte, 10), time.Hour)
+		assert.NoError(t, err, "ttl support preserved")
 
-					err = fhm.Set(keys[i], values[i])
-					assert.NoErrorf(t, err, "sets record #%d to file", i)
-				}
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
 
-				sp := fhm.fileManagement.GetStorageParameters()
+func TestOperationStats(t *testing.T) {
+	t.Run("counts operations and resets counters", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		value := []byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25}
+		opened := fhm.OperationStats().LastReset
+
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "set a record to file")
+		_, err = fhm.Get(key)
+		assert.NoError(t, err, "get record from file")
+		_, err = fhm.Pop(key)
+		assert.NoError(t, err, "pop record from file")
+		_, err = fhm.Get(key)
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "get correct error")
+		err = fhm.Set(key, []byte{0})
+		assert.Error(t, err, "set record with wrong value length")
+
+		// Execute
+		stats := fhm.OperationStats()
+
+		// Check
+		assert.Equal(t, int64(2), stats.Gets, "correct number of gets")
+		assert.Equal(t, int64(1), stats.GetMisses, "correct number of get misses")
+		assert.Equal(t, int64(2), stats.Sets, "correct number of sets")
+		assert.Equal(t, int64(1), stats.Pops, "correct number of pops")
+		assert.Equal(t, int64(1), stats.Errors, "correct number of errors")
+
+		// Execute
+		fhm.ResetStats()
+		stats = fhm.OperationStats()
+
+		// Check
+		assert.Zero(t, stats.Gets, "gets reset")
+		assert.Zero(t, stats.GetMisses, "get misses reset")
+		assert.Zero(t, stats.Sets, "sets reset")
+		assert.Zero(t, stats.Pops, "pops reset")
+		assert.Zero(t, stats.Errors, "errors reset")
+		assert.False(t, stats.LastReset.Before(opened), "last reset updated")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestWrongLength(t *testing.T) {
+	t.Run("reports wrong lengths with a dedicated error", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errEmptyKey := fhm.Set(nil, make([]byte, 10))
+		errShortKey := fhm.Set(make([]byte, 12), make([]byte, 10))
+		errLongValue := fhm.Set(make([]byte, 16), make([]byte, 11))
+		_, errGet := fhm.Get(make([]byte, 17))
+
+		// Check
+		var wrongLength crt.WrongLength
+		assert.ErrorAs(t, errEmptyKey, &wrongLength, "empty key rejected")
+		assert.Equal(t, crt.WrongLength{Field: "key", Operation: "Set", Expected: 16, Actual: 0}, wrongLength, "correct details")
+		assert.Contains(t, errEmptyKey.Error(), "empty key", "empty key told apart")
+		assert.ErrorAs(t, errShortKey, &wrongLength, "short key rejected")
+		assert.Equal(t, crt.WrongLength{Field: "key", Operation: "Set", Expected: 16, Actual: 12}, wrongLength, "correct details")
+		assert.ErrorAs(t, errLongValue, &wrongLength, "long value rejected")
+		assert.Equal(t, crt.WrongLength{Field: "value", Operation: "Set", Expected: 10, Actual: 11}, wrongLength, "correct details")
+		assert.ErrorIs(t, errGet, crt.WrongLength{}, "long key rejected")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
+
+func TestAccessHints(t *testing.T) {
+	t.Run("scans with access hints enabled", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("scans with hints for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 50)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
 
 				// Execute
-				stat, err := fhm.Stat(true)
+				errEnable := fhm.EnableAccessHints()
+				errSet := fhm.SetBatch(records)
+				stat, errStat := fhm.Stat(false)
+				n := 0
+				errForEach := fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					n++
+					return n == 10, nil
+				})
+				exported := 0
+				errExport := fhm.Export(10, false, func(batch *ExportBatch) error {
+					exported += len(batch.Keys)
+					return nil
+				})
+				errDisable := fhm.DisableAccessHints()
 
 				// Check
-				assert.NoError(t, err, "gets statistics")
-				assert.Equal(t, 1001, stat.Records, "correct number of record reported")
-				assert.NotZero(t, stat.MapFileRecords, "map file is used")
-				if test.crt == crt.SeparateChaining {
-					assert.NotZero(t, stat.OverflowRecords, "overflow file is used")
-				} else {
-					assert.Zero(t, stat.OverflowRecords, "overflow file is not used")
-				}
-				assert.Equal(t, int(sp.NumberOfBucketsAvailable), len(stat.BucketDistribution), "bucket distribution has correct length")
-				var dRecords int
-				for _, v := range stat.BucketDistribution {
-					dRecords += v
+				assert.NoError(t, errEnable, "enables access hints")
+				assert.NoError(t, errSet, "sets records")
+				assert.NoError(t, errStat, "gets stat")
+				assert.Equal(t, len(records), stat.Records, "all records counted")
+				assert.NoError(t, errForEach, "iterates records")
+				assert.Equal(t, 10, n, "iteration stopped")
+				assert.NoError(t, errExport, "exports records")
+				assert.Equal(t, len(records), exported, "all records exported")
+				assert.NoError(t, errDisable, "disables access hints")
+
+				for _, record := range records {
+					value, err := fhm.Get(record.Key)
+					assert.NoError(t, err, "gets record")
+					assert.Equal(t, record.Value, value, "correct value")
 				}
-				assert.Equal(t, 1001, dRecords, "correct number of records reported in distribution")
 
 				// Clean up
 				err = fhm.RemoveFiles()
 				assert.NoError(t, err, "removes files")
-
-				_, err = os.Stat(fmt.Sprintf("%s-map.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "map file removed")
-				_, err = os.Stat(fmt.Sprintf("%s-ovfl.bin", testHashMap))
-				assert.True(t, os.IsNotExist(err), "overflow file removed")
 			})
 		}
 	})
@@ -474,3 +2483,54 @@ func (Q *QuadraticProbingHashAlgorithm) ProbeIteration(hf1Value, hf2Value, itera
 
 	return probe
 }
+
+// DoubleHashingHashAlgorithm - A bucket selection algorithm for Double Hashing using crc32 with the IEEE polynomial to
+// select the home bucket and crc32 with the Castagnoli polynomial for the probing step, which makes the step
+// independent of the home bucket. The table size is rounded up to its nearest prime, so that every step visits all
+// buckets before repeating itself.
+type DoubleHashingHashAlgorithm struct {
+	tableSize int64
+}
+
+// castagnoliTable - Table for the Castagnoli polynomial used by DoubleHashingHashAlgorithm
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// NewDoubleHashingHashAlgorithm - Returns a pointer to a new DoubleHashingHashAlgorithm instance
+// It sets an initial value for the table size but that size may be updated to a new value depending on
+// chosen Collision Probing Algorithm
+func NewDoubleHashingHashAlgorithm(tableSize int64) *DoubleHashingHashAlgorithm {
+	ha := &DoubleHashingHashAlgorithm{}
+	ha.SetTableSize(tableSize)
+	return ha
+}
+
+// SetTableSize - Sets the table size for the hash algorithm, rounded up to its nearest prime.
+func (D *DoubleHashingHashAlgorithm) SetTableSize(tableSize int64) {
+	D.tableSize = utils.NextPrime(tableSize)
+}
+
+// HashFunc1 - Given key it generates an index (bucket) between 0 and table size - 1
+// Any number returned outside the table size (0 -> table size - 1) will result in an error down stream.
+func (D *DoubleHashingHashAlgorithm) HashFunc1(key []byte) int64 {
+	h := int64(crc32.ChecksumIEEE(key))
+	return h % D.tableSize
+}
+
+// HashFunc2 - Given key it generates a probing step between 1 and table size - 1
+func (D *DoubleHashingHashAlgorithm) HashFunc2(key []byte) int64 {
+	if D.tableSize < 2 {
+		return 1
+	}
+	h := int64(crc32.Checksum(key, castagnoliTable))
+	return 1 + h%(D.tableSize-1)
+}
+
+// GetTableSize - Returns the table size the implemented hash functions are supporting
+func (D *DoubleHashingHashAlgorithm) GetTableSize() int64 {
+	return D.tableSize
+}
+
+// ProbeIteration - Implements Double Hashing
+func (D *DoubleHashingHashAlgorithm) ProbeIteration(hf1Value, hf2Value, iteration int64) int64 {
+	return (hf1Value + (iteration%D.tableSize)*hf2Value) % D.tableSize
+}
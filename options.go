@@ -0,0 +1,108 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+)
+
+// Option - Is an optional setting given when creating or opening a file hash map, or to the functions working on the
+// files of a file hash map by name. A Feature is an Option given to NewFileHashMap, while the options returned by
+// WithFileSystem and the other With functions tell how the files are reached, locked, retried and synced, and apply to that file hash
+// map only.
+type Option interface {
+	apply(o *options) (err error)
+}
+
+// options - Holds the settings given as Option
+type options struct {
+	features      []Feature
+	fs            vfs.FileSystem
+	lockMode      LockMode
+	retrier       *storage.Retrier
+	strictDirSync bool
+}
+
+// optionFunc - Is an Option applying a function to the settings
+type optionFunc func(o *options) (err error)
+
+// apply - Applies the option to the settings
+func (O optionFunc) apply(o *options) (err error) {
+	err = O(o)
+
+	return
+}
+
+// apply - Selects the feature for a new file hash map
+func (F Feature) apply(o *options) (err error) {
+	o.features = append(o.features, F)
+
+	return
+}
+
+// WithFileSystem - Returns an Option that makes the file hash map create, open and remove its files through fs,
+// which lets callers keep file hash maps in e.g. an afero file system or any other implementation of vfs.FileSystem.
+// The operating system's file system is used unless given. The same file system has to be given each time the file
+// hash map is opened, and is kept by automatic growing, the operation log and the new files of ReorgFiles. Memory
+// mapping is only available for files of the operating system's file system, and directories are only synced by file
+// systems implementing vfs.DirSyncer.
+//   - fs is the file system to use, nil means the operating system's file system
+//
+// It returns:
+//   - option is the Option to give to NewFileHashMap, NewFromExistingFiles or any function taking options
+func WithFileSystem(fs vfs.FileSystem) (option Option) {
+	option = optionFunc(func(o *options) (err error) {
+		o.fs = fs
+
+		return
+	})
+
+	return
+}
+
+// newOptions - Returns the settings given by opts
+//   - opts is the options given by the caller
+//   - allowFeatures set to false fails if a Feature is given, since features are only selected when files are created
+//
+// It returns:
+//   - o is the settings
+//   - err is a standard error, if an option is not valid
+func newOptions(opts []Option, allowFeatures bool) (o options, err error) {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		err = opt.apply(&o)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(o.features) > 0 && !allowFeatures {
+		err = fmt.Errorf("features are kept in the files and can only be selected when creating a file hash map")
+	}
+
+	return
+}
+
+// settings - Returns the settings without features, to reach other files the same way
+func (O options) settings() options {
+	O.features = nil
+
+	return O
+}
+
+// fileSystem - Returns the file system given by WithFileSystem, or the operating system's file system if none is
+// given, wrapped to retry its operations if a retry policy is given by WithRetryPolicy
+func (O options) fileSystem() (fs vfs.FileSystem) {
+	fs = O.fs
+	if fs == nil {
+		fs = vfs.OS{}
+	}
+
+	if O.retrier != nil {
+		fs = storage.NewRetryFileSystem(fs, *O.retrier)
+	}
+
+	return
+}
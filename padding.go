@@ -0,0 +1,36 @@
+package filehashmap
+
+// Policy - Decides in which end PadOrTrim pads or trims a byte slice
+type Policy int
+
+const (
+	// KeepStart - Pads with zeros, or trims, at the end of the byte slice, keeping its start intact
+	KeepStart Policy = iota
+	// KeepEnd - Pads with zeros, or trims, at the start of the byte slice, keeping its end intact
+	KeepEnd
+)
+
+// PadOrTrim - Normalizes a key or value to the fixed length given when the file hash map was created, so that inputs
+// of varying length can be treated consistently instead of being rejected with crt.WrongLength.
+// Note that trimming makes different inputs sharing the kept part equal, so for keys it is only safe if the trimmed
+// part never tells keys apart.
+//   - b is the key or value to normalize, it is never modified
+//   - n is the length to normalize to
+//   - policy is KeepStart or KeepEnd
+//
+// It returns:
+//   - normalized is a new byte slice of length n
+func PadOrTrim(b []byte, n int, policy Policy) (normalized []byte) {
+	normalized = make([]byte, n)
+
+	switch {
+	case policy == KeepEnd && len(b) >= n:
+		copy(normalized, b[len(b)-n:])
+	case policy == KeepEnd:
+		copy(normalized[n-len(b):], b)
+	default:
+		copy(normalized, b)
+	}
+
+	return
+}
@@ -0,0 +1,30 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPadOrTrim(t *testing.T) {
+	t.Run("pads and trims according to policy", func(t *testing.T) {
+		// Prepare
+		b := []byte{1, 2, 3}
+
+		// Execute
+		padStart := PadOrTrim(b, 5, KeepStart)
+		padEnd := PadOrTrim(b, 5, KeepEnd)
+		trimStart := PadOrTrim(b, 2, KeepStart)
+		trimEnd := PadOrTrim(b, 2, KeepEnd)
+		same := PadOrTrim(b, 3, KeepEnd)
+
+		// Check
+		assert.Equal(t, []byte{1, 2, 3, 0, 0}, padStart, "padded at end")
+		assert.Equal(t, []byte{0, 0, 1, 2, 3}, padEnd, "padded at start")
+		assert.Equal(t, []byte{1, 2}, trimStart, "trimmed at end")
+		assert.Equal(t, []byte{2, 3}, trimEnd, "trimmed at start")
+		assert.Equal(t, b, same, "unchanged when of correct length")
+		assert.Equal(t, []byte{1, 2, 3}, b, "input not modified")
+	})
+}
@@ -0,0 +1,91 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"math"
+)
+
+// CapacityPlan - Is the recommended sizing of a new file hash map, see Plan
+//   - BucketsNeeded is the value to give as bucketsNeeded when creating the file hash map
+//   - NumberOfBucketsAvailable is the number of buckets the map file will actually get, after table size rounding
+//   - RecordsPerBucket is the number of records per bucket that the plan was made for
+//   - FileSize is the size the map file will be created with, not counting any overflow file
+//   - LoadFactor is the load factor expected once all keys are stored, given the number of buckets available
+type CapacityPlan struct {
+	BucketsNeeded            int
+	NumberOfBucketsAvailable int
+	RecordsPerBucket         int
+	FileSize                 int
+	LoadFactor               float64
+}
+
+// Plan - Recommends the number of buckets to ask for when creating a file hash map that is to hold a number of keys
+// at a target load factor, so that bucketsNeeded doesn't have to be guessed. Since the internal hash algorithms round
+// the table size, e.g. up to a prime or a power of two depending on CRT, the number of buckets actually available and
+// the resulting map file size are also returned. With Separate Chaining, Hybrid and Linear Hashing a load factor above
+// 1 is allowed, which plans for records in the overflow file, while the other CRTs require it to be at most 1.
+//   - keys is the number of unique keys expected to be stored
+//   - keyLength is the length of the key part in a record
+//   - valueLength is the length of the value part in a record
+//   - crtType is the Collision Resolution Technique to plan for
+//   - recordsPerBucket is the number of records in each bucket, below one means one
+//   - targetLoadFactor is the share of the map file to fill with records
+//
+// It returns:
+//   - plan is the recommended sizing
+//   - err is a standard error, if any of the parameters is not valid
+func Plan(keys int64, keyLength, valueLength, crtType, recordsPerBucket int, targetLoadFactor float64) (plan CapacityPlan, err error) {
+	err = checkCRTType(crtType)
+	if err != nil {
+		return
+	}
+	if keys <= 0 {
+		err = fmt.Errorf("keys must be a positive value higher than 0 (zero)")
+		return
+	}
+	overflow := crtType == crt.SeparateChaining || crtType == crt.Hybrid || crtType == crt.LinearHashing
+	if targetLoadFactor <= 0 || (!overflow && targetLoadFactor > 1) {
+		err = fmt.Errorf("targetLoadFactor must be above 0 (zero), and at most 1 unless the CRT uses an overflow file")
+		return
+	}
+	if recordsPerBucket < 1 {
+		recordsPerBucket = 1
+	}
+
+	bucketsNeeded := math.Ceil(float64(keys) / (targetLoadFactor * float64(recordsPerBucket)))
+	if bucketsNeeded > math.MaxInt32 {
+		err = fmt.Errorf("keys can not be held at the target load factor")
+		return
+	}
+
+	err = checkDimensions(int(bucketsNeeded), keyLength, valueLength)
+	if err != nil {
+		return
+	}
+
+	crtConf := model.CRTConf{
+		NumberOfBucketsNeeded:        int64(bucketsNeeded),
+		RecordsPerBucket:             int64(recordsPerBucket),
+		KeyLength:                    int64(keyLength),
+		ValueLength:                  int64(valueLength),
+		CollisionResolutionTechnique: crtType,
+	}
+
+	sp, err := estimateFileManagement(crtConf)
+	if err != nil {
+		err = fmt.Errorf("error while planning files: %s", err)
+		return
+	}
+
+	plan = CapacityPlan{
+		BucketsNeeded:            int(bucketsNeeded),
+		NumberOfBucketsAvailable: int(sp.NumberOfBucketsAvailable),
+		RecordsPerBucket:         recordsPerBucket,
+		FileSize:                 int(sp.MapFileSize),
+		LoadFactor:               float64(keys) / float64(sp.NumberOfBucketsAvailable*sp.RecordsPerBucket),
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		crtName string
+		crt     int
+	}{
+		{crtName: "SeparateChaining", crt: crt.SeparateChaining},
+		{crtName: "LinearProbing", crt: crt.LinearProbing},
+		{crtName: "QuadraticProbing", crt: crt.QuadraticProbing},
+		{crtName: "DoubleHashing", crt: crt.DoubleHashing},
+		{crtName: "RobinHood", crt: crt.RobinHood},
+		{crtName: "CuckooHashing", crt: crt.CuckooHashing},
+		{crtName: "Hopscotch", crt: crt.Hopscotch},
+		{crtName: "LinearHashing", crt: crt.LinearHashing},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("plan matches created files for %s", test.crtName), func(t *testing.T) {
+			// Execute
+			plan, err := Plan(1000, 16, 10, test.crt, 4, 0.75)
+
+			// Check
+			assert.NoError(t, err, "plan capacity")
+			assert.Equal(t, 334, plan.BucketsNeeded, "buckets needed for target load factor")
+			assert.LessOrEqual(t, plan.LoadFactor, 0.75, "load factor at most target")
+
+			fhm, info, err := NewFileHashMap(testHashMap, test.crt, plan.BucketsNeeded, plan.RecordsPerBucket, 16, 10, nil)
+			assert.NoError(t, err, "create file hash map")
+			assert.Equal(t, info.NumberOfBucketsAvailable, plan.NumberOfBucketsAvailable, "number of buckets available")
+			assert.Equal(t, info.FileSize, plan.FileSize, "map file size")
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "files can be removed")
+		})
+	}
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		// Execute
+		_, errKeys := Plan(0, 16, 10, crt.LinearProbing, 1, 0.5)
+		_, errCrt := Plan(100, 16, 10, 0, 1, 0.5)
+		_, errLoad := Plan(100, 16, 10, crt.LinearProbing, 1, 1.5)
+		_, errLength := Plan(100, 0, 10, crt.LinearProbing, 1, 0.5)
+		overflow, errOverflow := Plan(100, 16, 10, crt.SeparateChaining, 1, 1.5)
+
+		// Check
+		assert.Error(t, errKeys, "no keys")
+		assert.Error(t, errCrt, "unknown crt")
+		assert.Error(t, errLoad, "load factor above 1 without overflow")
+		assert.Error(t, errLength, "invalid key length")
+		assert.NoError(t, errOverflow, "load factor above 1 with overflow")
+		assert.Equal(t, 67, overflow.BucketsNeeded, "buckets needed with overflow")
+	})
+}
@@ -0,0 +1,42 @@
+package filehashmap
+
+import "fmt"
+
+// probeCapper - Implemented by file management that probes through buckets using the hash algorithm
+type probeCapper interface {
+	SetProbeCap(probeCap int64)
+}
+
+// SetProbeCap - Sets the number of probe iterations to attempt for a key before Get, Set and the other operations give
+// up with an error of type crt.ProbingAlgorithm. The default of ten times the number of buckets is generous, since a
+// sound probing algorithm reaches all buckets in as many iterations as there are buckets, but a custom hash algorithm
+// that mostly returns probes outside the table, or cycles over a part of it, may make each operation take very long
+// before failing. The error tells the hash values of the key, the number of iterations attempted and the number of
+// distinct buckets they reached, to help finding the fault in the hash algorithm. It is only supported for the open
+// addressing CRTs (LinearProbing, QuadraticProbing and DoubleHashing), other CRTs return an error. The cap is not
+// persisted, so it has to be set each time the FileHashMap is opened, but it is kept when the map grows automatically
+// (see EnableAutoGrow).
+//   - probeCap is the number of probe iterations, zero restores the default
+//
+// It returns:
+//   - err is a standard error, if the CRT doesn't probe using the hash algorithm or probeCap is negative
+func (F *FileHashMap) SetProbeCap(probeCap int) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if probeCap < 0 {
+		err = fmt.Errorf("probe cap can not be negative")
+		return
+	}
+
+	capper, ok := F.fileManagement.(probeCapper)
+	if !ok {
+		err = fmt.Errorf("probe cap is only supported by linear probing, quadratic probing and double hashing")
+		return
+	}
+
+	capper.SetProbeCap(int64(probeCap))
+	F.probeCap = int64(probeCap)
+
+	return
+}
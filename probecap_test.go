@@ -0,0 +1,83 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"errors"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// cyclingHashAlgorithm - A faulty linear probing hash algorithm whose probe sequence cycles over two buckets only
+type cyclingHashAlgorithm struct {
+	*LinearProbingHashAlgorithm
+}
+
+// ProbeIteration - Alternates between the home bucket and the next one
+func (C cyclingHashAlgorithm) ProbeIteration(hf1Value, hf2Value, iteration int64) int64 {
+	return (hf1Value + iteration%2) % C.GetTableSize()
+}
+
+func TestSetProbeCap(t *testing.T) {
+	t.Run("gives up after probe cap with diagnostics", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 1, 4, 4, cyclingHashAlgorithm{NewLinearProbingHashAlgorithm(100)})
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.SetProbeCap(20)
+		assert.NoError(t, err, "set probe cap")
+
+		// Execute
+		var key []byte
+		for i := 0; i < 100 && err == nil; i++ {
+			key = []byte{0, 0, 0, byte(i)}
+			err = fhm.Set(key, []byte{1, 1, 1, byte(i)})
+		}
+		_, getErr := fhm.Get(key)
+
+		// Check
+		var probingErr crt.ProbingAlgorithm
+		assert.True(t, errors.As(err, &probingErr), "set fails with probing algorithm error")
+		assert.Equal(t, int64(20), probingErr.Probes, "probes attempted")
+		assert.Equal(t, int64(2), probingErr.Visited, "buckets visited")
+		assert.Equal(t, int64(100), probingErr.Buckets, "buckets in table")
+		assert.Contains(t, err.Error(), "visiting 2 of 100 buckets", "error message")
+		assert.ErrorIs(t, getErr, crt.ProbingAlgorithm{}, "get fails with probing algorithm error")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for negative probe cap", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.DoubleHashing, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		// Execute
+		err = fhm.SetProbeCap(-1)
+
+		// Check
+		assert.Error(t, err, "negative probe cap")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails for collision resolution technique without probing", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		// Execute
+		err = fhm.SetProbeCap(20)
+
+		// Check
+		assert.Error(t, err, "separate chaining has no probing")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
@@ -0,0 +1,233 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+	"sort"
+	"time"
+)
+
+// Profile - A named bundle of settings suited for a kind of deployment, see OpenWithProfile and ApplyProfile
+//   - Name is the name of the profile
+//   - MaxLoadFactor is the load factor above which to grow automatically (see EnableAutoGrow), zero turns it off
+//   - SyncPolicy is when to sync files (see SetSyncPolicy)
+//   - SyncInterval is the time between syncs when SyncPolicy is SyncInterval
+//   - GroupCommitMutations is the max number of mutations between header checkpoints (see EnableGroupCommit), zero turns it off
+//   - AccessHints is whether to stop the kernel from reading ahead on random access (see EnableAccessHints)
+//   - MaxKeyLength is the max key length accepted, zero means no limit
+//   - MaxValueLength is the max value length accepted, zero means no limit
+//   - BucketCacheBytes is the budget of bytes for caching buckets (see EnableBucketCache), zero turns it off
+type Profile struct {
+	Name                 string
+	MaxLoadFactor        float64
+	SyncPolicy           SyncPolicy
+	SyncInterval         time.Duration
+	GroupCommitMutations int
+	AccessHints          bool
+	MaxKeyLength         int
+	MaxValueLength       int
+	BucketCacheBytes     int
+}
+
+// profiles - The named profiles available through GetProfile
+var profiles = map[string]Profile{
+	// Small devices with flash storage, where writes are expensive and memory is scarce
+	"small-embedded": {
+		Name:           "small-embedded",
+		MaxLoadFactor:  0.75,
+		SyncPolicy:     SyncOnClose,
+		MaxKeyLength:   64,
+		MaxValueLength: 1024,
+	},
+	// Servers with NVMe drives, where random reads are cheap and read-ahead only pollutes the page cache
+	"server-nvme": {
+		Name:                 "server-nvme",
+		MaxLoadFactor:        0.8,
+		SyncPolicy:           SyncInterval,
+		SyncInterval:         100 * time.Millisecond,
+		GroupCommitMutations: 1000,
+		AccessHints:          true,
+		MaxKeyLength:         1024,
+		MaxValueLength:       64 * 1024,
+		BucketCacheBytes:     64 * 1024 * 1024,
+	},
+	// Rotating disks holding large, mostly read, data sets, where every probe costs a seek
+	"archival-hdd": {
+		Name:                 "archival-hdd",
+		MaxLoadFactor:        0.5,
+		SyncPolicy:           SyncInterval,
+		SyncInterval:         time.Second,
+		GroupCommitMutations: 10000,
+		MaxKeyLength:         4096,
+		MaxValueLength:       1024 * 1024,
+		BucketCacheBytes:     256 * 1024 * 1024,
+	},
+}
+
+// ProfileNames - Returns the names of all available profiles in alphabetical order
+func ProfileNames() (names []string) {
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return
+}
+
+// GetProfile - Returns the named profile, which may be adjusted before it is given to ApplyProfile
+//   - name is the name of the profile, one of those returned by ProfileNames
+//
+// It returns:
+//   - profile is the Profile struct with the settings of the profile
+//   - err is a standard error, if there is no profile with the given name
+func GetProfile(name string) (profile Profile, err error) {
+	profile, ok := profiles[name]
+	if !ok {
+		err = fmt.Errorf("unknown profile: %s", name)
+	}
+
+	return
+}
+
+// OpenWithProfile - Works as NewFromExistingFiles and then applies the named profile, see ApplyProfile. If the profile
+// can not be applied the files are closed again.
+//   - name is the name of an existing hash map.
+//   - hashAlgorithm is an optional entry to provide a custom hash algorithm following the hashfunc.HashAlgorithm interface.
+//   - profileName is the name of the profile to apply
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - fileHashMap is a pointer to a FileHashMap struct
+//   - hashMapInfo is a HashMapInfo struct containing some data regarding the hash map opened.
+//   - err is a standard error, if the files could not be opened or the profile could not be applied
+func OpenWithProfile(name string, hashAlgorithm hashfunc.HashAlgorithm, profileName string, options ...Option) (fileHashMap *FileHashMap, hashMapInfo HashMapInfo, err error) {
+	profile, err := GetProfile(profileName)
+	if err != nil {
+		return
+	}
+
+	fileHashMap, hashMapInfo, err = NewFromExistingFiles(name, hashAlgorithm, options...)
+	if err != nil {
+		return
+	}
+
+	err = fileHashMap.ApplyProfile(profile)
+	if err != nil {
+		fileHashMap.CloseFiles()
+		fileHashMap = nil
+	}
+
+	return
+}
+
+// ApplyProfile - Checks the hash map against the length guardrails of a profile and then applies its settings, which
+// is the same as calling EnableAutoGrow, SetSyncPolicy, EnableGroupCommit, EnableAccessHints and EnableBucketCache with
+// the settings of the profile. Settings that are turned off in the profile are left as they are. Automatic growing is
+// left out for Linear Hashing, which grows by itself, and the bucket cache for collision resolution techniques that
+// don't support it. For variable length keys (see FeatureVariableKeys) the max key length is instead checked by every
+// Set. Everything is checked before anything is applied, and the settings are applied while holding the lock, so
+// other calls see either none or all of them.
+//   - profile is the profile to apply, see GetProfile
+//
+// It returns:
+//   - err is a standard error, if the key or value length of the hash map exceeds the guardrails or a setting could
+//     not be applied
+func (F *FileHashMap) ApplyProfile(profile Profile) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.checkProfile(profile)
+	if err != nil {
+		return
+	}
+
+	F.applyGuardrails(profile)
+
+	if F.growsWith(profile) {
+		err = F.enableAutoGrow(profile.MaxLoadFactor)
+		if err != nil {
+			return
+		}
+	}
+
+	F.setSyncPolicy(profile.SyncPolicy, profile.SyncInterval)
+
+	if profile.GroupCommitMutations > 0 {
+		err = F.enableGroupCommit(profile.GroupCommitMutations, 0)
+		if err != nil {
+			return
+		}
+	}
+
+	if profile.AccessHints {
+		err = F.enableAccessHints()
+		if err != nil {
+			return
+		}
+	}
+
+	if F.cachesWith(profile) {
+		err = F.enableBucketCache(profile.BucketCacheBytes)
+	}
+
+	return
+}
+
+// checkProfile - Checks the key and value lengths of the hash map against the max lengths of profile, and that all
+// settings of profile can be applied, to be called with the lock held
+func (F *FileHashMap) checkProfile(profile Profile) (err error) {
+	sp := F.fileManagement.GetStorageParameters()
+	valueLength := F.userValueLength()
+
+	if profile.MaxValueLength > 0 && valueLength > profile.MaxValueLength {
+		err = fmt.Errorf("value length %d exceeds max value length %d of profile %s", valueLength, profile.MaxValueLength, profile.Name)
+		return
+	}
+
+	if F.keyFile == nil && profile.MaxKeyLength > 0 && int(sp.KeyLength) > profile.MaxKeyLength {
+		err = fmt.Errorf("key length %d exceeds max key length %d of profile %s", sp.KeyLength, profile.MaxKeyLength, profile.Name)
+		return
+	}
+
+	if F.growsWith(profile) {
+		err = F.checkAutoGrow(profile.MaxLoadFactor)
+		if err != nil {
+			return
+		}
+	}
+
+	err = checkSyncPolicy(profile.SyncPolicy, profile.SyncInterval)
+	if err != nil {
+		return
+	}
+
+	if profile.BucketCacheBytes < 0 {
+		err = fmt.Errorf("bucket cache size of profile %s must not be negative", profile.Name)
+		return
+	}
+
+	if F.cachesWith(profile) {
+		err = F.checkBucketCache(profile.BucketCacheBytes)
+	}
+
+	return
+}
+
+// applyGuardrails - Sets the max key length of profile to check on Set for variable length keys, unless the profile
+// has no max key length, to be called with the lock held
+func (F *FileHashMap) applyGuardrails(profile Profile) {
+	if F.keyFile != nil && profile.MaxKeyLength > 0 {
+		F.maxKeyLength = profile.MaxKeyLength
+	}
+}
+
+// growsWith - Returns true if automatic growing is to be enabled by profile, to be called with the lock held
+func (F *FileHashMap) growsWith(profile Profile) bool {
+	return profile.MaxLoadFactor > 0 && F.fileManagement.GetStorageParameters().CollisionResolutionTechnique != crt.LinearHashing
+}
+
+// cachesWith - Returns true if the bucket cache is to be enabled by profile, to be called with the lock held
+func (F *FileHashMap) cachesWith(profile Profile) bool {
+	return profile.BucketCacheBytes > 0 && F.supportsBucketCache()
+}
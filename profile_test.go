@@ -0,0 +1,150 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Run("opens files with each profile", func(t *testing.T) {
+		for _, profileName := range ProfileNames() {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+			assert.NoError(t, err, "create new file hash map struct")
+			fhm.CloseFiles()
+
+			// Execute
+			fhm, _, err = OpenWithProfile(testHashMap, nil, profileName)
+
+			// Check
+			assert.NoErrorf(t, err, "opens files with profile %s", profileName)
+			profile, err := GetProfile(profileName)
+			assert.NoError(t, err, "gets profile")
+			assert.Equal(t, profile.SyncPolicy, fhm.syncPolicy, "sync policy applied")
+			assert.Equal(t, profile.AccessHints, fhm.accessHints, "access hints applied")
+			assert.Equal(t, profile.MaxLoadFactor > 0, fhm.autoGrow != nil, "auto grow applied")
+			assert.Equal(t, profile.GroupCommitMutations > 0, fhm.groupCommit != nil, "group commit applied")
+			assert.Equal(t, int64(profile.BucketCacheBytes), fhm.bucketCacheBudget, "bucket cache applied")
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		}
+	})
+
+	t.Run("rejects unknown profile", func(t *testing.T) {
+		// Execute
+		_, err := GetProfile("no-such-profile")
+
+		// Check
+		assert.Error(t, err, "unknown profile")
+	})
+
+	t.Run("enforces length guardrails", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 2000, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = OpenWithProfile(testHashMap, nil, "small-embedded")
+
+		// Check
+		assert.Error(t, err, "value length exceeds guardrail")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("enforces max key length on set for variable length keys", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 0, 10, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+		profile, err := GetProfile("small-embedded")
+		assert.NoError(t, err, "gets profile")
+
+		// Execute
+		err = fhm.ApplyProfile(profile)
+		assert.NoError(t, err, "applies profile")
+		errShort := fhm.Set(make([]byte, 64), make([]byte, 10))
+		errLong := fhm.Set(make([]byte, 65), make([]byte, 10))
+
+		// Check
+		assert.NoError(t, errShort, "accepts key within guardrail")
+		assert.Error(t, errLong, "rejects key exceeding guardrail")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("keeps max key length if the profile has none", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 0, 10, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+		profile, err := GetProfile("small-embedded")
+		assert.NoError(t, err, "gets profile")
+		err = fhm.ApplyProfile(profile)
+		assert.NoError(t, err, "applies profile")
+
+		// Execute
+		profile.MaxKeyLength = 0
+		err = fhm.ApplyProfile(profile)
+
+		// Check
+		assert.NoError(t, err, "applies profile without max key length")
+		assert.Equal(t, 64, fhm.maxKeyLength, "max key length kept")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("applies nothing if a setting is not valid", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		profile, err := GetProfile("server-nvme")
+		assert.NoError(t, err, "gets profile")
+		profile.SyncInterval = 0
+
+		// Execute
+		err = fhm.ApplyProfile(profile)
+
+		// Check
+		assert.Error(t, err, "sync interval not valid")
+		assert.Nil(t, fhm.autoGrow, "auto grow not applied")
+		assert.Nil(t, fhm.groupCommit, "group commit not applied")
+		assert.False(t, fhm.accessHints, "access hints not applied")
+		assert.Equal(t, int64(0), fhm.bucketCacheBudget, "bucket cache not applied")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("leaves out the bucket cache for techniques without one", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+		profile, err := GetProfile("server-nvme")
+		assert.NoError(t, err, "gets profile")
+
+		// Execute
+		err = fhm.ApplyProfile(profile)
+
+		// Check
+		assert.NoError(t, err, "applies profile")
+		assert.Equal(t, int64(0), fhm.bucketCacheBudget, "no bucket cache")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,195 @@
+package filehashmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamFormat - Is the format of a record stream, see ExportRecords and ImportRecords
+type StreamFormat int
+
+const (
+	// StreamBinary - Records are written as the length of the key as an unsigned varint, the key, the length of the
+	// value as an unsigned varint and the value, after a header telling the format
+	StreamBinary StreamFormat = iota
+	// StreamNDJSON - Records are written one per line as a JSON object with the key and value as hex strings, e.g.
+	// {"key":"0a0b","value":"0c0d0e"}
+	StreamNDJSON
+)
+
+// streamMagic - Is written first in a record stream of format StreamBinary, telling the format and its version
+var streamMagic = [8]byte{'F', 'H', 'M', 'R', 'E', 'C', 0, 1}
+
+// maxStreamFieldLength - Is the max length of a key or value read from a record stream, guarding against damaged streams
+const maxStreamFieldLength = 1 << 30
+
+// streamRecord - Is a record of a record stream of format StreamNDJSON
+type streamRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ExportRecords - Writes all records stored in the file hash map to w as a portable record stream, independent of
+// bucket layout, CRT and architecture, so that the file hash map can be rebuilt with other parameters or moved
+// elsewhere using ImportRecords. Records are written in the order of Iterator, with the key and value as given to Set.
+// Expiry times and timestamps are not included.
+//   - w is the writer to write the stream to
+//   - format is StreamBinary or StreamNDJSON
+//
+// It returns:
+//   - records is the number of records written
+//   - err is a standard error, if the format is unknown or something went wrong
+func (F *FileHashMap) ExportRecords(w io.Writer, format StreamFormat) (records int64, err error) {
+	if format != StreamBinary && format != StreamNDJSON {
+		err = fmt.Errorf("format has to be one of StreamBinary or StreamNDJSON")
+		return
+	}
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	lengths := make([]byte, binary.MaxVarintLen64)
+
+	if format == StreamBinary {
+		_, err = bw.Write(streamMagic[:])
+		if err != nil {
+			err = fmt.Errorf("error while writing record stream: %s", err)
+			return
+		}
+	}
+
+	err = F.ForEach(func(key, value []byte) (stop bool, err error) {
+		if format == StreamNDJSON {
+			err = encoder.Encode(streamRecord{Key: hex.EncodeToString(key), Value: hex.EncodeToString(value)})
+		} else {
+			for _, field := range [][]byte{key, value} {
+				n := binary.PutUvarint(lengths, uint64(len(field)))
+				_, err = bw.Write(lengths[:n])
+				if err == nil {
+					_, err = bw.Write(field)
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("error while writing record stream: %s", err)
+			return
+		}
+		records++
+
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		err = fmt.Errorf("error while writing record stream: %s", err)
+	}
+
+	return
+}
+
+// ImportRecords - Reads a record stream written by ExportRecords from r and sets each record in the file hash map,
+// which may have been created with other parameters than the one exported, as long as keys and values are of its
+// lengths. Records already in the file hash map with the same key are overwritten. Records imported before an error
+// are kept.
+//   - r is the reader to read the stream from
+//   - format is the format the stream was written with, StreamBinary or StreamNDJSON
+//
+// It returns:
+//   - records is the number of records imported
+//   - err is a standard error, if the format is unknown, the stream is damaged or a record could not be set
+func (F *FileHashMap) ImportRecords(r io.Reader, format StreamFormat) (records int64, err error) {
+	var next func() (key, value []byte, err error)
+	br := bufio.NewReader(r)
+
+	switch format {
+	case StreamBinary:
+		var magic [8]byte
+		_, err = io.ReadFull(br, magic[:])
+		if err == nil && magic != streamMagic {
+			err = fmt.Errorf("not a binary record stream")
+		}
+		if err != nil {
+			err = fmt.Errorf("error while reading record stream: %s", err)
+			return
+		}
+		next = func() (key, value []byte, err error) {
+			key, err = readStreamField(br)
+			if err != nil {
+				return
+			}
+			value, err = readStreamField(br)
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+	case StreamNDJSON:
+		decoder := json.NewDecoder(br)
+		next = func() (key, value []byte, err error) {
+			var record streamRecord
+			err = decoder.Decode(&record)
+			if err != nil {
+				return
+			}
+			key, err = hex.DecodeString(record.Key)
+			if err == nil {
+				value, err = hex.DecodeString(record.Value)
+			}
+			return
+		}
+	default:
+		err = fmt.Errorf("format has to be one of StreamBinary or StreamNDJSON")
+		return
+	}
+
+	var key, value []byte
+	for {
+		key, value, err = next()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("error while reading record #%d of record stream: %s", records+1, err)
+			return
+		}
+
+		err = F.Set(key, value)
+		if err != nil {
+			err = fmt.Errorf("error while importing record #%d: %s", records+1, err)
+			return
+		}
+		records++
+	}
+}
+
+// readStreamField - Reads a key or value of a record stream of format StreamBinary, returning io.EOF only if the
+// stream ended before the field
+func readStreamField(br *bufio.Reader) (field []byte, err error) {
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return
+	}
+	if length > maxStreamFieldLength {
+		err = fmt.Errorf("field length %d is too long", length)
+		return
+	}
+
+	field = make([]byte, length)
+	_, err = io.ReadFull(br, field)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return
+}
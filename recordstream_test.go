@@ -0,0 +1,87 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportImportRecords(t *testing.T) {
+	tests := []struct {
+		formatName string
+		format     StreamFormat
+	}{
+		{formatName: "StreamBinary", format: StreamBinary},
+		{formatName: "StreamNDJSON", format: StreamNDJSON},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("exports and imports records as %s", test.formatName), func(t *testing.T) {
+			// Prepare
+			importName := fmt.Sprintf("%s-import", testHashMap)
+			fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+			assert.NoError(t, err, "create file hash map")
+			for i := 0; i < 20; i++ {
+				err = fhm.Set([]byte{0, 0, 0, byte(i)}, []byte{1, 1, 1, byte(i)})
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			imp, _, err := NewFileHashMap(importName, crt.LinearProbing, 30, 1, 4, 4, nil)
+			assert.NoError(t, err, "create file hash map to import to")
+			buf := &bytes.Buffer{}
+
+			// Execute
+			exported, err := fhm.ExportRecords(buf, test.format)
+			assert.NoError(t, err, "export records")
+			imported, err := imp.ImportRecords(buf, test.format)
+
+			// Check
+			assert.NoError(t, err, "import records")
+			assert.Equal(t, int64(20), exported, "records exported")
+			assert.Equal(t, int64(20), imported, "records imported")
+			for i := 0; i < 20; i++ {
+				value, err := imp.Get([]byte{0, 0, 0, byte(i)})
+				assert.NoErrorf(t, err, "get record #%d", i)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of record #%d", i)
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "remove files")
+			err = imp.RemoveFiles()
+			assert.NoError(t, err, "remove import files")
+		})
+	}
+
+	t.Run("fails for damaged stream", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.Set([]byte{0, 0, 0, 1}, []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "set record")
+		buf := &bytes.Buffer{}
+		_, err = fhm.ExportRecords(buf, StreamBinary)
+		assert.NoError(t, err, "export records")
+		truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-2])
+
+		// Execute
+		imported, err := fhm.ImportRecords(truncated, StreamBinary)
+
+		// Check
+		assert.Error(t, err, "truncated stream")
+		assert.Equal(t, int64(0), imported, "no records imported")
+
+		_, err = fhm.ImportRecords(bytes.NewReader([]byte("not a stream")), StreamBinary)
+		assert.Error(t, err, "missing magic")
+
+		_, err = fhm.ExportRecords(buf, StreamFormat(5))
+		assert.Error(t, err, "unknown format")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}
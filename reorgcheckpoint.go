@@ -0,0 +1,85 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/hashfunc"
+)
+
+// reorgCheckpointSystemValueID - Is the id of the system value in the header of files being created by ReorgFiles
+// that holds the number of the next bucket of the original files to reorganize. The value is emptied once the
+// reorganization is complete.
+const reorgCheckpointSystemValueID uint8 = 6
+
+// defaultReorgCheckpointInterval - Is the number of buckets between checkpoints if not given in ReorgConf
+const defaultReorgCheckpointInterval int64 = 1000
+
+// resumeReorg - Opens new files left by an earlier reorganization that did not complete, if they were created with
+// the same settings and flags as would be used now. Any failure to open them, or a mismatch, means starting over.
+//
+// It returns:
+//   - toFhm is the opened file hash map, nil if there was nothing to resume
+//   - toHashMapInfo is the information about the opened file hash map
+//   - nextBucketNo is the next bucket of the original files to reorganize
+func resumeReorg(
+	newName string,
+	from *FileHashMap,
+	crtType, numberOfBucketsNeeded, recordsPerBucket, keyLength, valueLength int,
+	bucketAlgorithm hashfunc.HashAlgorithm,
+) (
+	toFhm *FileHashMap,
+	toHashMapInfo HashMapInfo,
+	nextBucketNo int64,
+) {
+	fhm, info, err := openExistingFiles(newName, bucketAlgorithm, false, from.options)
+	if err != nil {
+		return
+	}
+
+	checkpoint, err := fhm.fileManagement.GetSystemValue(reorgCheckpointSystemValueID)
+	sp := fhm.fileManagement.GetStorageParameters()
+	matches := err == nil && len(checkpoint) == 8 &&
+		sp.CollisionResolutionTechnique == crtType &&
+		int(sp.NumberOfBucketsNeeded) == numberOfBucketsNeeded &&
+		int(sp.RecordsPerBucket) == recordsPerBucket &&
+		sp.InternalAlgorithm == (customHashAlgorithm(bucketAlgorithm) == nil) &&
+		sameHashFunction(sp, bucketAlgorithm) &&
+		fhm.userValueLength() == valueLength &&
+		(fhm.keyFile != nil) == (from.keyFile != nil) &&
+		(fhm.keyFile != nil || int(sp.KeyLength) == keyLength) &&
+		(fhm.ttl != nil) == (from.ttl != nil) &&
+		fhm.checksums == from.checksums &&
+		fhm.timestamps == from.timestamps
+	if !matches {
+		fhm.CloseFiles()
+		return
+	}
+
+	toFhm, toHashMapInfo = fhm, info
+	nextBucketNo = int64(binary.LittleEndian.Uint64(checkpoint))
+
+	return
+}
+
+// saveReorgCheckpoint - Records in the header that the buckets of the original files before nextBucketNo have been
+// reorganized into F. The files are synced first, so that the checkpoint never claims records that are not on disk.
+// A negative nextBucketNo marks the reorganization as complete.
+func (F *FileHashMap) saveReorgCheckpoint(nextBucketNo int64) (err error) {
+	err = F.fileManagement.Sync()
+	if err != nil {
+		err = fmt.Errorf("error while syncing files before reorganization checkpoint: %s", err)
+		return
+	}
+
+	var checkpoint []byte
+	if nextBucketNo >= 0 {
+		checkpoint = binary.LittleEndian.AppendUint64(nil, uint64(nextBucketNo))
+	}
+
+	err = F.fileManagement.SetSystemValue(reorgCheckpointSystemValueID, checkpoint)
+	if err != nil {
+		err = fmt.Errorf("error while saving reorganization checkpoint: %s", err)
+	}
+
+	return
+}
@@ -0,0 +1,169 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"math/rand"
+)
+
+// ReorgEstimate - Is the projected outcome of a reorganization, see EstimateReorg
+//   - HasChanges is true if ReorgFiles would reorganize the files without the force flag
+//   - CollisionResolutionTechnique is the CRT of the new files
+//   - NumberOfBucketsAvailable is the number of buckets the new map file would get
+//   - RecordsPerBucket is the number of records per bucket in the new map file
+//   - KeyLength is the key length of the new files
+//   - ValueLength is the value length of the new files
+//   - FileSize is the size the new map file would be created with, not counting any overflow or key file
+//   - Records is the number of records expected to be moved, projected from the sampled buckets if any
+//   - LoadFactor is Records divided by the number of records the new map file can hold
+//   - SampledBuckets is the number of buckets of the original files that were read, zero if only headers were read
+type ReorgEstimate struct {
+	HasChanges                   bool
+	CollisionResolutionTechnique int
+	NumberOfBucketsAvailable     int
+	RecordsPerBucket             int
+	KeyLength                    int
+	ValueLength                  int
+	FileSize                     int
+	Records                      int64
+	LoadFactor                   float64
+	SampledBuckets               int
+}
+
+// EstimateReorg - Projects the outcome of a call to ReorgFiles with the same reorgConf, without writing anything, so
+// that the configuration can be validated before committing to the IO of a full reorganization. The original files are
+// opened read-only, and unless sampleBuckets is above zero only their headers are read, giving the number of records
+// stored. With sampleBuckets above zero, that many randomly chosen buckets are read and passed through the same
+// filtering, truncation and transforms as in ReorgFiles, and the number of records to move is projected from them.
+// This also calls ReorgConf.Filter, ReorgConf.TransformKey and ReorgConf.TransformValue for the sampled records, and
+// reports records that truncation would lose data of as an error.
+//   - name is the name of an existing file hash map (including correct path)
+//   - reorgConf is an instance of the ReorgConf struct, as it would be given to ReorgFiles
+//   - sampleBuckets is the number of buckets to read from the original files, zero reads headers only
+//   - options is any optional settings, such as WithFileSystem
+//
+// It returns:
+//   - estimate is the projected outcome of the reorganization
+//   - err is a standard error, if the original files could not be read or reorgConf is not valid for them
+func EstimateReorg(name string, reorgConf ReorgConf, sampleBuckets int, options ...Option) (estimate ReorgEstimate, err error) {
+	from, _, err := NewFromExistingFilesReadOnly(name, reorgConf.OldHashAlgorithm, options...)
+	if err != nil {
+		return
+	}
+	defer from.CloseFiles()
+
+	settings, hasChanges, err := newReorgSettings(from, reorgConf)
+	if err != nil {
+		return
+	}
+
+	// The new files get the same kind of extra data stored with each value as in reorgFiles
+	storedValueLength := settings.valueLength
+	switch {
+	case from.keyFile != nil:
+		storedValueLength += keyAddressLength
+	case from.ttl != nil:
+		storedValueLength += ttlLength
+	case from.checksums:
+		storedValueLength += checksumLength
+	case from.timestamps:
+		storedValueLength += timestampsLength
+	}
+
+	// A custom hash algorithm gets its table size set as if new files were created, which is undone afterwards
+	bucketAlgorithm := customHashAlgorithm(settings.bucketAlgorithm)
+	if bucketAlgorithm != nil {
+		defer bucketAlgorithm.SetTableSize(bucketAlgorithm.GetTableSize())
+	}
+
+	crtConf := model.CRTConf{
+		Name:                         name,
+		NumberOfBucketsNeeded:        int64(settings.numberOfBucketsNeeded),
+		RecordsPerBucket:             int64(settings.recordsPerBucket),
+		KeyLength:                    int64(settings.keyLength),
+		ValueLength:                  int64(storedValueLength),
+		CollisionResolutionTechnique: settings.crtType,
+		HashAlgorithm:                bucketAlgorithm,
+	}
+
+	sp, err := estimateFileManagement(crtConf)
+	if err != nil {
+		err = fmt.Errorf("error while estimating new files: %s", err)
+		return
+	}
+
+	estimate = ReorgEstimate{
+		HasChanges:                   hasChanges,
+		CollisionResolutionTechnique: sp.CollisionResolutionTechnique,
+		NumberOfBucketsAvailable:     int(sp.NumberOfBucketsAvailable),
+		RecordsPerBucket:             int(sp.RecordsPerBucket),
+		KeyLength:                    settings.keyLength,
+		ValueLength:                  settings.valueLength,
+		FileSize:                     int(sp.MapFileSize),
+	}
+
+	estimate.Records, estimate.SampledBuckets, err = estimateReorgRecords(from, reorgConf, sampleBuckets)
+	if err != nil {
+		return
+	}
+
+	capacity := sp.NumberOfBucketsAvailable * sp.RecordsPerBucket
+	if capacity > 0 {
+		estimate.LoadFactor = float64(estimate.Records) / float64(capacity)
+	}
+
+	return
+}
+
+// estimateReorgRecords - Returns the number of records a reorganization is expected to move from the original files,
+// counted from their headers or projected from sampleBuckets randomly chosen buckets
+//   - from is the original file hash map
+//   - reorgConf is the configuration of the reorganization
+//   - sampleBuckets is the number of buckets to read, zero reads headers only
+//
+// It returns:
+//   - records is the number of records expected to be moved
+//   - sampled is the number of buckets that were read
+//   - err is a standard error, if something went wrong
+func estimateReorgRecords(from *FileHashMap, reorgConf ReorgConf, sampleBuckets int) (records int64, sampled int, err error) {
+	nBuckets := from.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+	if sampleBuckets <= 0 || nBuckets == 0 {
+		records, err = from.Count()
+		return
+	}
+
+	// Buckets are drawn without replacement, all of them if there are not more than asked for
+	var bucketNos []int64
+	if int64(sampleBuckets) >= nBuckets {
+		bucketNos = make([]int64, nBuckets)
+		for i := range bucketNos {
+			bucketNos[i] = int64(i)
+		}
+	} else {
+		drawn := make(map[int64]bool, sampleBuckets)
+		for len(bucketNos) < sampleBuckets {
+			bucketNo := rand.Int63n(nBuckets)
+			if !drawn[bucketNo] {
+				drawn[bucketNo] = true
+				bucketNos = append(bucketNos, bucketNo)
+			}
+		}
+	}
+
+	var moved int64
+	var items []reorgItem
+	for _, bucketNo := range bucketNos {
+		items, err = readReorgBucket(from, reorgConf, bucketNo, func(bucketNo int64) ([]model.Record, error) {
+			return from.readBucketLocked(from.fileManagement.GetBucket, bucketNo)
+		})
+		if err != nil {
+			return
+		}
+		moved += int64(len(items))
+	}
+
+	sampled = len(bucketNos)
+	records = moved * nBuckets / int64(sampled)
+
+	return
+}
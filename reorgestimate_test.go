@@ -0,0 +1,110 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestEstimateReorg(t *testing.T) {
+	tests := []struct {
+		crtName string
+		fromCrt int
+		toCrt   int
+	}{
+		{crtName: "SeparateChaining", fromCrt: crt.SeparateChaining, toCrt: crt.SeparateChaining},
+		{crtName: "LinearProbing", fromCrt: crt.SeparateChaining, toCrt: crt.LinearProbing},
+		{crtName: "RobinHood", fromCrt: crt.LinearProbing, toCrt: crt.RobinHood},
+		{crtName: "Hopscotch", fromCrt: crt.LinearProbing, toCrt: crt.Hopscotch},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("estimate matches reorganization to %s", test.crtName), func(t *testing.T) {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, test.fromCrt, 50, 2, 8, 10, nil, FeatureTTL)
+			assert.NoError(t, err, "create file hash map")
+			for i := 0; i < 60; i++ {
+				key := make([]byte, 8)
+				rand.Read(key)
+				err = fhm.Set(key, make([]byte, 10))
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+			fhm.CloseFiles()
+
+			reorgConf := ReorgConf{
+				CollisionResolutionTechnique: test.toCrt,
+				NumberOfBucketsNeeded:        100,
+				RecordsPerBucket:             2,
+				ValueExtension:               6,
+			}
+
+			// Execute
+			estimate, err := EstimateReorg(testHashMap, reorgConf, 0)
+
+			// Check
+			assert.NoError(t, err, "estimate reorg")
+			_, err = os.Stat(fmt.Sprintf("%s-reorg-map.bin", testHashMap))
+			assert.True(t, os.IsNotExist(err), "nothing written")
+
+			_, toInfo, err := ReorgFiles(testHashMap, reorgConf, false)
+			assert.NoError(t, err, "run reorg files")
+			assert.True(t, estimate.HasChanges, "changes detected")
+			assert.Equal(t, toInfo.NumberOfBucketsAvailable, estimate.NumberOfBucketsAvailable, "number of buckets")
+			assert.Equal(t, toInfo.FileSize, estimate.FileSize, "map file size")
+			assert.Equal(t, 16, estimate.ValueLength, "value length extended")
+			assert.Equal(t, int64(60), estimate.Records, "records from header")
+			assert.InDelta(t, 60.0/float64(toInfo.TotalRecords), estimate.LoadFactor, 0.0001, "load factor")
+			assert.Equal(t, 0, estimate.SampledBuckets, "no buckets sampled")
+
+			// Clean up
+			fhm, _, err = NewFromExistingFiles(fmt.Sprintf("%s-reorg", testHashMap), nil)
+			assert.NoError(t, err, "open reorged files")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "new files can be removed")
+
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoError(t, err, "open original files")
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "original files can be removed")
+		})
+	}
+
+	t.Run("projects records from sampled buckets", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 8, 10, nil)
+		assert.NoError(t, err, "create file hash map")
+		for i := 0; i < 80; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			key[0] = byte(i % 2)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		nBuckets := fhm.fileManagement.GetStorageParameters().NumberOfBucketsAvailable
+		fhm.CloseFiles()
+
+		reorgConf := ReorgConf{Filter: func(key, value []byte) bool { return key[0] == 0 }}
+
+		// Execute
+		all, err := EstimateReorg(testHashMap, reorgConf, int(nBuckets))
+		some, errSome := EstimateReorg(testHashMap, reorgConf, 10)
+
+		// Check
+		assert.NoError(t, err, "estimate reorg sampling all buckets")
+		assert.Equal(t, int(nBuckets), all.SampledBuckets, "all buckets sampled")
+		assert.Equal(t, int64(40), all.Records, "filtered records counted")
+		assert.NoError(t, errSome, "estimate reorg sampling some buckets")
+		assert.Equal(t, 10, some.SampledBuckets, "some buckets sampled")
+
+		// Clean up
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "open original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "original files can be removed")
+	})
+}
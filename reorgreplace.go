@@ -0,0 +1,128 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"os"
+)
+
+// hashMapFileNames - Are the file names that a file hash map may consist of, besides the lock file
+var hashMapFileNames = []func(string) string{
+	storage.GetMapFileName,
+	storage.GetOvflFileName,
+	storage.GetKeyFileName,
+	storage.GetBloomFileName,
+	storage.GetWALFileName,
+}
+
+// replaceWithReorged - Swaps the files of a reorganization into the name of the original file hash map. The original
+// files are first renamed with a -original inserted in the name(s), then the new files are renamed into the original
+// name(s). If any rename fails, the files renamed so far are moved back. The backup of the original files is removed
+// at the end unless keepBackup is set. Both file hash maps are expected to be closed.
+//   - name is the name of the original file hash map
+//   - newName is the name of the reorganized file hash map
+//   - keepBackup set to true keeps the original files under the backup name
+//   - o is the settings to reach the files with
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func replaceWithReorged(name, newName string, keepBackup bool, o options) (err error) {
+	backupName := fmt.Sprintf("%s-original", name)
+	fs := o.fileSystem()
+
+	// A backup left by an earlier reorganization would otherwise get mixed up with this one
+	err = removeNamedFiles(backupName, fs)
+	if err != nil {
+		return
+	}
+
+	backedUp, err := renameNamedFiles(name, backupName, fs)
+	if err != nil {
+		_, _ = renameFileNames(backedUp, backupName, name, fs)
+		err = fmt.Errorf("error while backing up original files: %s", err)
+		return
+	}
+
+	replaced, err := renameNamedFiles(newName, name, fs)
+	if err != nil {
+		_, _ = renameFileNames(replaced, name, newName, fs)
+		_, _ = renameFileNames(backedUp, backupName, name, fs)
+		err = fmt.Errorf("error while replacing original files with reorganized files: %s", err)
+		return
+	}
+
+	_ = removeLockFile(newName, o)
+	err = syncDirOf(storage.GetMapFileName(name), o)
+	if err != nil {
+		return
+	}
+
+	if !keepBackup {
+		err = removeNamedFiles(backupName, fs)
+	}
+
+	return
+}
+
+// renameNamedFiles - Renames the existing files of the file hash map from to the file hash map to
+//   - from is the name of the file hash map to rename
+//   - to is the new name of the file hash map
+//   - fs is the file system holding the files
+//
+// It returns:
+//   - renamed is the file name functions of the files that were renamed, also when an error is returned
+//   - err is a standard error, if a rename failed
+func renameNamedFiles(from, to string, fs vfs.FileSystem) (renamed []func(string) string, err error) {
+	var existing []func(string) string
+	for _, fileName := range hashMapFileNames {
+		if _, statErr := fs.Stat(fileName(from)); statErr == nil {
+			existing = append(existing, fileName)
+		}
+	}
+
+	renamed, err = renameFileNames(existing, from, to, fs)
+
+	return
+}
+
+// renameFileNames - Renames the given files from one file hash map name to another, stopping at the first failure
+//   - fileNames is the file name functions of the files to rename
+//   - from is the name of the file hash map to rename
+//   - to is the new name of the file hash map
+//   - fs is the file system holding the files
+//
+// It returns:
+//   - renamed is the file name functions of the files that were renamed
+//   - err is a standard error, if a rename failed
+func renameFileNames(fileNames []func(string) string, from, to string, fs vfs.FileSystem) (renamed []func(string) string, err error) {
+	for _, fileName := range fileNames {
+		err = fs.Rename(fileName(from), fileName(to))
+		if err != nil {
+			return
+		}
+		renamed = append(renamed, fileName)
+	}
+
+	return
+}
+
+// removeNamedFiles - Removes any existing files of the file hash map
+//   - name is the name of the file hash map
+//   - fs is the file system holding the files
+//
+// It returns:
+//   - err is a standard error, if a file exists but could not be removed
+func removeNamedFiles(name string, fs vfs.FileSystem) (err error) {
+	for _, fileName := range hashMapFileNames {
+		err = fs.Remove(fileName(name))
+		if err != nil && !os.IsNotExist(err) {
+			err = fmt.Errorf("error while removing file %s: %s", fileName(name), err)
+			return
+		}
+	}
+
+	err = nil
+
+	return
+}
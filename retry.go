@@ -0,0 +1,78 @@
+package filehashmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy - Describes how file operations failing with transient errors are retried, see WithRetryPolicy
+//   - Attempts is the total number of attempts made for each file operation, including the first. Zero or one turns
+//     off retries.
+//   - Backoff is the wait before the first retry, which is doubled for each following retry
+//   - MaxBackoff caps the wait between retries, zero means no cap
+//   - Retryable classifies whether an error is transient and worth retrying, nil means IsTransientError
+//   - OnRetry is called, if not nil, before each retry with the name of the file operation (e.g. "read" or "write"),
+//     the attempt that failed and its error, which can be used to feed logs. Retries of the map file and overflow file
+//     are also counted by Metrics.AddRetries of file hash maps with metrics set (see SetMetrics).
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Retryable  func(err error) bool
+	OnRetry    func(op string, attempt int, err error)
+}
+
+// WithRetryPolicy - Returns an Option that sets the policy for retrying file operations of the file hash map that fail
+// with transient errors, e.g. EIO or EAGAIN from network file systems. Retries are made for each single read, write,
+// sync etc. of a file, so an operation in the middle of probing buckets is completed rather than left half done.
+// Retries are made on top of the file system given by WithFileSystem. No retries are made unless given, and the policy
+// is kept by automatic growing, the operation log and the new files of ReorgFiles.
+//   - policy is the retry policy, a zero RetryPolicy turns off retries
+//
+// It returns:
+//   - option is the Option to give to NewFileHashMap, NewFromExistingFiles or any function taking options, which
+//     fails them with a standard error if the policy is not valid
+func WithRetryPolicy(policy RetryPolicy) (option Option) {
+	option = optionFunc(func(o *options) (err error) {
+		if policy.Attempts < 0 || policy.Backoff < 0 || policy.MaxBackoff < 0 {
+			err = fmt.Errorf("attempts, backoff and max backoff can not be negative")
+			return
+		}
+
+		o.retrier = nil
+		if policy.Attempts <= 1 {
+			return
+		}
+
+		o.retrier = &storage.Retrier{
+			Attempts:   policy.Attempts,
+			Backoff:    policy.Backoff,
+			MaxBackoff: policy.MaxBackoff,
+			Retryable:  policy.Retryable,
+			OnRetry:    policy.OnRetry,
+		}
+		if o.retrier.Retryable == nil {
+			o.retrier.Retryable = IsTransientError
+		}
+
+		return
+	})
+
+	return
+}
+
+// IsTransientError - Is the default classifier of RetryPolicy, it reports whether err is an I/O error, a resource
+// temporarily unavailable error or an interrupted system call, which are the errors network file systems typically
+// return for conditions that pass.
+//   - err is the error to classify
+//
+// It returns:
+//   - transient is true if err is worth retrying
+func IsTransientError(err error) (transient bool) {
+	transient = errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR)
+
+	return
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/vfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyFileSystem - Wraps the operating system's file system with files failing every other write with EIO
+type flakyFileSystem struct {
+	vfs.OS
+}
+
+type flakyFile struct {
+	vfs.File
+	writes atomic.Int64
+}
+
+func (F flakyFileSystem) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	file, err := F.OS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file}, nil
+}
+
+func (F *flakyFile) WriteAt(p []byte, off int64) (int, error) {
+	if F.writes.Add(1)%2 == 0 {
+		return 0, &os.PathError{Op: "write", Path: "flaky", Err: syscall.EIO}
+	}
+	return F.File.WriteAt(p, off)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	testCases := []TestCaseOperations{
+		{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining, hFunc: nil},
+		{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing, hFunc: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run("retries transient write errors for "+tc.crtName, func(t *testing.T) {
+			// Prepare
+			var retries atomic.Int64
+			retry := WithRetryPolicy(RetryPolicy{Attempts: 3, Backoff: time.Microsecond, OnRetry: func(op string, attempt int, err error) {
+				retries.Add(1)
+			}})
+
+			fhm, _, err := NewFileHashMap(testHashMap, tc.crt, tc.buckets, tc.rpb, tc.keyLength, tc.valueLength, tc.hFunc, WithFileSystem(flakyFileSystem{}), retry)
+			assert.NoError(t, err, "create new file hash map struct")
+			metrics := &metricsRecorder{}
+			fhm.SetMetrics(metrics)
+
+			// Execute
+			for i := 0; i < 10; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				err = fhm.Set(key, []byte("0123456789"))
+				assert.NoErrorf(t, err, "sets record #%d", i)
+			}
+
+			// Check
+			assert.Greater(t, retries.Load(), int64(0), "writes were retried")
+			assert.Greater(t, metrics.retries.Load(), int64(0), "retries counted in metrics")
+			for i := 0; i < 10; i++ {
+				key := make([]byte, tc.keyLength)
+				key[0] = byte(i)
+				value, err := fhm.Get(key)
+				assert.NoErrorf(t, err, "gets record #%d", i)
+				assert.Equal(t, []byte("0123456789"), value, "value is read back")
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		})
+	}
+
+	t.Run("fails without retry policy", func(t *testing.T) {
+		// Execute
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithFileSystem(flakyFileSystem{}))
+		if err == nil {
+			for i := 0; i < 10 && err == nil; i++ {
+				key := make([]byte, 16)
+				key[0] = byte(i)
+				err = fhm.Set(key, make([]byte, 10))
+			}
+		}
+
+		// Check
+		assert.ErrorContains(t, err, syscall.EIO.Error(), "transient error surfaces")
+
+		// Clean up
+		_ = os.Remove(storage.GetMapFileName(testHashMap))
+	})
+
+	t.Run("classifies transient errors", func(t *testing.T) {
+		// Check
+		assert.True(t, IsTransientError(&os.PathError{Op: "read", Path: "file", Err: syscall.EIO}), "EIO is transient")
+		assert.True(t, IsTransientError(syscall.EAGAIN), "EAGAIN is transient")
+		assert.False(t, IsTransientError(os.ErrNotExist), "not exist is not transient")
+	})
+
+	t.Run("rejects negative values", func(t *testing.T) {
+		// Execute
+		_, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 1, 16, 10, nil, WithRetryPolicy(RetryPolicy{Attempts: -1}))
+
+		// Check
+		assert.Error(t, err, "negative attempts rejected")
+	})
+}
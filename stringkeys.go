@@ -0,0 +1,105 @@
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"strings"
+)
+
+// SetString - Works as Set but takes a string key that may be shorter than the key length given when the file hash map
+// was created, e.g. IDs of varying short lengths. The key is padded with zero bytes at the end, so the same string
+// always gives the same key, see KeyString for getting the string back from a key returned by e.g. Iterator.
+// With variable length keys (see FeatureVariableKeys) the key is used as it is.
+//   - key is the identifier of a record, it must not be longer than the key length and must not end with a zero byte
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//
+// It returns:
+//   - err is of type crt.WrongLength if key is too long, or a standard error if something went wrong
+func (F *FileHashMap) SetString(key string, value []byte) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	paddedKey, err := F.padStringKey(key, "SetString")
+	if err != nil {
+		return
+	}
+
+	err = F.set(paddedKey, value, 0)
+
+	return
+}
+
+// GetString - Works as Get but takes a string key, which is padded as by SetString
+//   - key is the identifier of a record, it must not be longer than the key length and must not end with a zero byte
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound, crt.WrongLength if key is too long, or a standard error if something went wrong
+func (F *FileHashMap) GetString(key string) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	paddedKey, err := F.padStringKey(key, "GetString")
+	if err != nil {
+		return
+	}
+
+	value, err = F.get(paddedKey)
+
+	return
+}
+
+// PopString - Works as Pop but takes a string key, which is padded as by SetString
+//   - key is the identifier of a record, it must not be longer than the key length and must not end with a zero byte
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound, crt.WrongLength if key is too long, or a standard error if something went wrong
+func (F *FileHashMap) PopString(key string) (value []byte, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	paddedKey, err := F.padStringKey(key, "PopString")
+	if err != nil {
+		return
+	}
+
+	value, err = F.pop(paddedKey)
+
+	return
+}
+
+// KeyString - Returns the string that a key was padded from by SetString, i.e. the key with the padding zero bytes at
+// the end stripped, e.g. for keys returned by Iterator or ForEach
+//   - key is a key as stored in the file hash map
+//
+// It returns:
+//   - s is the key as a string without padding
+func KeyString(key []byte) (s string) {
+	s = strings.TrimRight(string(key), "\x00")
+
+	return
+}
+
+// padStringKey - Returns key padded with zero bytes at the end to the key length, to be called with the lock held.
+// Keys ending with a zero byte are rejected, since they could not be told apart from a shorter key once padded.
+func (F *FileHashMap) padStringKey(key string, operation string) (paddedKey []byte, err error) {
+	if F.keyFile != nil {
+		paddedKey = []byte(key)
+		return
+	}
+
+	keyLength := int(F.fileManagement.GetStorageParameters().KeyLength)
+	if len(key) > keyLength {
+		err = crt.WrongLength{Field: "key", Operation: operation, Expected: keyLength, Actual: len(key)}
+		return
+	}
+	if strings.HasSuffix(key, "\x00") {
+		err = fmt.Errorf("string key must not end with a zero byte since keys are padded with zero bytes")
+		return
+	}
+
+	paddedKey = PadOrTrim([]byte(key), keyLength, KeepStart)
+
+	return
+}
@@ -0,0 +1,77 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetGetPopString(t *testing.T) {
+	t.Run("pads string keys and strips padding", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 8, 4, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		err = fhm.SetString("id-1", []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "sets short key")
+		err = fhm.SetString("id-12345", []byte{2, 2, 2, 2})
+		assert.NoError(t, err, "sets key of full length")
+		errLong := fhm.SetString("id-123456", []byte{3, 3, 3, 3})
+		errZero := fhm.SetString("id-1\x00", []byte{4, 4, 4, 4})
+
+		valueShort, errShort := fhm.GetString("id-1")
+		valueBytes, errBytes := fhm.Get([]byte{'i', 'd', '-', '1', 0, 0, 0, 0})
+		valueFull, errFull := fhm.GetString("id-12345")
+		_, errPrefix := fhm.GetString("id-")
+		valuePopped, errPop := fhm.PopString("id-1")
+		_, errPopped := fhm.GetString("id-1")
+
+		var keys []string
+		err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+			keys = append(keys, KeyString(key))
+			return
+		})
+		assert.NoError(t, err, "iterates records")
+
+		// Check
+		assert.ErrorIs(t, errLong, crt.WrongLength{}, "too long key rejected")
+		assert.Error(t, errZero, "key ending with zero byte rejected")
+		assert.NoError(t, errShort, "gets short key")
+		assert.Equal(t, []byte{1, 1, 1, 1}, valueShort, "value of short key")
+		assert.NoError(t, errBytes, "gets short key as padded bytes")
+		assert.Equal(t, []byte{1, 1, 1, 1}, valueBytes, "value of padded key")
+		assert.NoError(t, errFull, "gets key of full length")
+		assert.Equal(t, []byte{2, 2, 2, 2}, valueFull, "value of key of full length")
+		assert.ErrorIs(t, errPrefix, crt.NoRecordFound{}, "prefix of key is another key")
+		assert.NoError(t, errPop, "pops short key")
+		assert.Equal(t, []byte{1, 1, 1, 1}, valuePopped, "popped value")
+		assert.ErrorIs(t, errPopped, crt.NoRecordFound{}, "popped key is gone")
+		assert.Equal(t, []string{"id-12345"}, keys, "keys without padding")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("uses string keys as they are with variable length keys", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 0, 4, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		err = fhm.SetString("a-rather-long-identifier", []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "sets long key")
+		value, errGet := fhm.Get([]byte("a-rather-long-identifier"))
+
+		// Check
+		assert.NoError(t, errGet, "gets key as bytes")
+		assert.Equal(t, []byte{1, 1, 1, 1}, value, "value of key")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,129 @@
+package filehashmap
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyncPolicy - Controls when the hash map files are committed to stable storage, see SetSyncPolicy
+type SyncPolicy int
+
+const (
+	// SyncOnClose - Files are only synced when closed, which gives the highest throughput. This is the default.
+	SyncOnClose SyncPolicy = iota
+	// SyncAlways - Files are synced after every Set, SetBatch and Pop that changes the hash map
+	SyncAlways
+	// SyncInterval - Files are synced by a background goroutine at a fixed interval, if changed since the last sync
+	SyncInterval
+)
+
+// periodicSync - Holds the state of the background goroutine used by SyncInterval
+//   - stop is closed to make the goroutine return
+//   - dirty is true if the hash map has changed since the last sync
+type periodicSync struct {
+	stop  chan struct{}
+	dirty bool
+}
+
+// SetSyncPolicy - Sets when the hash map files are committed to stable storage, which trades throughput against how
+// many mutations may be lost if the machine crashes. Mutations are written to the files directly, so a crash of the
+// application alone loses nothing, unless the write buffer is enabled (see EnableWriteBuffer). The policy is not
+// persisted, so it has to be set each time the FileHashMap is opened. Use the WAL (see EnableWAL) if mutations must
+// never be lost.
+//   - policy is one of SyncOnClose, SyncAlways or SyncInterval
+//   - interval is the time between syncs for SyncInterval, it is ignored for other policies
+//
+// It returns:
+//   - err is a standard error, if the policy is unknown or the interval is not positive for SyncInterval
+func (F *FileHashMap) SetSyncPolicy(policy SyncPolicy, interval time.Duration) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = checkSyncPolicy(policy, interval)
+	if err != nil {
+		return
+	}
+
+	F.setSyncPolicy(policy, interval)
+
+	return
+}
+
+// checkSyncPolicy - Checks that policy is known and that interval is positive if needed by policy
+func checkSyncPolicy(policy SyncPolicy, interval time.Duration) (err error) {
+	switch policy {
+	case SyncOnClose, SyncAlways:
+	case SyncInterval:
+		if interval <= 0 {
+			err = fmt.Errorf("sync interval must be a positive duration")
+		}
+	default:
+		err = fmt.Errorf("unknown sync policy: %d", policy)
+	}
+
+	return
+}
+
+// setSyncPolicy - Is the implementation of SetSyncPolicy once checked by checkSyncPolicy, to be called with the lock
+// held
+func (F *FileHashMap) setSyncPolicy(policy SyncPolicy, interval time.Duration) {
+	F.stopPeriodicSync()
+	F.syncPolicy = policy
+
+	if policy == SyncInterval {
+		F.periodicSync = &periodicSync{stop: make(chan struct{})}
+		go F.runPeriodicSync(F.periodicSync, interval)
+	}
+}
+
+// syncAfterMutation - Syncs files or marks them as changed according to the sync policy, to be called with the lock
+// held after a mutation has been applied
+func (F *FileHashMap) syncAfterMutation() (err error) {
+	switch F.syncPolicy {
+	case SyncAlways:
+		err = F.fileManagement.Sync()
+	case SyncInterval:
+		F.periodicSync.dirty = true
+	}
+
+	return
+}
+
+// runPeriodicSync - Syncs the files every interval if they have changed, until ps.stop is closed
+func (F *FileHashMap) runPeriodicSync(ps *periodicSync, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ps.stop:
+			return
+		case <-ticker.C:
+		}
+
+		F.mu.Lock()
+		select {
+		case <-ps.stop:
+			// Stopped while waiting for the lock, the files may already be closed
+			F.mu.Unlock()
+			return
+		default:
+		}
+		if ps.dirty {
+			// On error the files are still marked as changed, so the sync is retried at the next tick
+			if F.fileManagement.Sync() == nil {
+				ps.dirty = false
+			}
+		}
+		F.mu.Unlock()
+	}
+}
+
+// stopPeriodicSync - Stops any background goroutine used by SyncInterval, to be called with the lock held. The
+// goroutine is not waited for, since it may be waiting for the lock.
+func (F *FileHashMap) stopPeriodicSync() {
+	if F.periodicSync != nil {
+		close(F.periodicSync.stop)
+		F.periodicSync = nil
+	}
+}
@@ -0,0 +1,87 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncCounter - Is a FileManagement that counts calls to Sync
+type syncCounter struct {
+	FileManagement
+	syncs int64
+}
+
+func (s *syncCounter) Sync() (err error) {
+	atomic.AddInt64(&s.syncs, 1)
+	return s.FileManagement.Sync()
+}
+
+func TestSetSyncPolicy(t *testing.T) {
+	t.Run("syncs according to policy", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		counter := &syncCounter{FileManagement: fhm.fileManagement}
+		fhm.fileManagement = counter
+		key := make([]byte, 16)
+		value := make([]byte, 10)
+
+		// Execute
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record with default policy")
+		onClose := atomic.LoadInt64(&counter.syncs)
+
+		err = fhm.SetSyncPolicy(SyncAlways, 0)
+		assert.NoError(t, err, "sets always policy")
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record")
+		_, err = fhm.Pop(key)
+		assert.NoError(t, err, "pops record")
+		always := atomic.LoadInt64(&counter.syncs)
+
+		err = fhm.SetSyncPolicy(SyncInterval, 10*time.Millisecond)
+		assert.NoError(t, err, "sets interval policy")
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record")
+		afterSet := atomic.LoadInt64(&counter.syncs)
+		time.Sleep(50 * time.Millisecond)
+		afterInterval := atomic.LoadInt64(&counter.syncs)
+		time.Sleep(50 * time.Millisecond)
+		afterIdle := atomic.LoadInt64(&counter.syncs)
+
+		// Check
+		assert.Equal(t, int64(0), onClose, "no sync with default policy")
+		assert.Equal(t, int64(2), always, "sync after every mutation")
+		assert.Equal(t, always, afterSet, "no sync directly after set with interval policy")
+		assert.Equal(t, afterSet+1, afterInterval, "one sync in background")
+		assert.Equal(t, afterInterval, afterIdle, "no sync while unchanged")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errInterval := fhm.SetSyncPolicy(SyncInterval, 0)
+		errPolicy := fhm.SetSyncPolicy(SyncPolicy(42), time.Second)
+
+		// Check
+		assert.Error(t, errInterval, "interval must be positive")
+		assert.Error(t, errPolicy, "unknown policy")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,151 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"time"
+)
+
+// timestampsSystemValueID - Is the id of the system value in the header that marks a file hash map as storing
+// created and modified timestamps with each record
+const timestampsSystemValueID uint8 = 5
+
+// timestampsLength - Is the number of bytes after each stored value holding the created and modified timestamps
+const timestampsLength int = 16
+
+// RecordMeta - Metadata of a record stored by a file hash map created with FeatureTimestamps
+//   - Created is the time when the record was first set
+//   - Modified is the time when the value of the record was last written
+type RecordMeta struct {
+	Created  time.Time
+	Modified time.Time
+}
+
+// GetWithMeta - Works as Get but also returns the created and modified timestamps of the record. The file hash map
+// must have been created with FeatureTimestamps.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found an error of type crt.NoRecordFound is also returned.
+//   - meta is the timestamps of the record
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong
+func (F *FileHashMap) GetWithMeta(key []byte) (value []byte, meta RecordMeta, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("GetWithMeta")()
+
+	if !F.timestamps {
+		err = fmt.Errorf("file hash map does not store timestamps, it has to be created with FeatureTimestamps")
+		return
+	}
+
+	F.opStats.addGets(1)
+	record, err := F.lookup(key)
+	if err != nil {
+		if errors.Is(err, crt.NoRecordFound{}) {
+			F.opStats.addMisses(1)
+		}
+		F.opStats.countError(err)
+		return
+	}
+
+	value = F.fromStoredValue(record.Value)
+	created, modified := F.storedTimestamps(record.Value)
+	meta = RecordMeta{Created: time.Unix(0, created), Modified: time.Unix(0, modified)}
+
+	err = F.countAccess(record)
+	F.opStats.countError(err)
+
+	return
+}
+
+// withTimestamps - Returns the stored value with the created and modified timestamps appended
+func withTimestamps(stored []byte, created, modified int64) (stamped []byte) {
+	stamped = make([]byte, len(stored), len(stored)+timestampsLength)
+	copy(stamped, stored)
+	stamped = binary.LittleEndian.AppendUint64(stamped, uint64(created))
+	stamped = binary.LittleEndian.AppendUint64(stamped, uint64(modified))
+
+	return
+}
+
+// withoutTimestamps - Returns a value, without any checksum, in the form it is stored in files without the timestamps
+// at the end of it
+func (F *FileHashMap) withoutTimestamps(stored []byte) (value []byte) {
+	if !F.timestamps || len(stored) < timestampsLength {
+		return stored
+	}
+
+	return stored[:len(stored)-timestampsLength]
+}
+
+// storedTimestamps - Returns the created and modified timestamps, in unix nanoseconds, of a value in the form it is
+// stored in files
+func (F *FileHashMap) storedTimestamps(stored []byte) (created, modified int64) {
+	stamped := F.withoutChecksum(stored)
+	if !F.timestamps || len(stamped) < timestampsLength {
+		return
+	}
+
+	n := len(stamped) - timestampsLength
+	created = int64(binary.LittleEndian.Uint64(stamped[n:]))
+	modified = int64(binary.LittleEndian.Uint64(stamped[n+8:]))
+
+	return
+}
+
+// restamp - Returns a value in the form it is stored in files with its timestamps replaced, and its checksum updated
+// if checksums are stored
+//   - key is the key in the form it is stored in files
+func (F *FileHashMap) restamp(key, stored []byte, created, modified int64) (restamped []byte) {
+	restamped = withTimestamps(F.withoutTimestamps(F.withoutChecksum(stored)), created, modified)
+	restamped = F.withChecksum(key, restamped)
+
+	return
+}
+
+// keepCreated - Returns a value in the form it is stored in files with the created timestamp of the existing record
+// with the same key, if there is one, so that setting a record again doesn't change when it was created
+//   - key is the key in the form it is stored in files
+func (F *FileHashMap) keepCreated(key, stored []byte) (kept []byte, err error) {
+	kept = stored
+	if !F.timestamps {
+		return
+	}
+
+	existing, err := F.fileManagement.Get(model.Record{Key: key})
+	if errors.Is(err, crt.NoRecordFound{}) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	created, _ := F.storedTimestamps(existing.Value)
+	_, modified := F.storedTimestamps(stored)
+	kept = F.restamp(key, stored, created, modified)
+
+	return
+}
+
+// setTimestamps - Sets the timestamps of an existing record, in place at its address, which is used to carry the
+// timestamps over when records are copied to new files by ReorgFiles
+func (F *FileHashMap) setTimestamps(key []byte, created, modified int64) (err error) {
+	record, err := F.lookup(key)
+	if err != nil {
+		return
+	}
+
+	err = setRecordValue(F.fileManagement, model.Record{
+		Key:           record.Key,
+		IsOverflow:    record.IsOverflow,
+		RecordAddress: record.RecordAddress,
+		Value:         F.restamp(record.Key, record.Value, created, modified),
+	})
+
+	return
+}
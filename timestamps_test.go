@@ -0,0 +1,120 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTimestamps(t *testing.T) {
+	t.Run("keeps created and updates modified timestamps for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 5, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 10, rpb: 2, keyLength: 5, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "RobinHood", buckets: 10, rpb: 2, keyLength: 5, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 10, rpb: 2, keyLength: 5, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 10, rpb: 2, keyLength: 5, valueLength: 10, crt: crt.Hopscotch},
+		}
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("keeps timestamps for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc, FeatureTimestamps)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				key := []byte{1, 2, 3, 4, 5}
+				value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+				before := time.Now()
+				err = fhm.Set(key, value)
+				assert.NoError(t, err, "sets record")
+				_, first, err := fhm.GetWithMeta(key)
+				assert.NoError(t, err, "gets first meta")
+				time.Sleep(time.Millisecond)
+
+				// Execute
+				err = fhm.Set(key, value)
+				assert.NoError(t, err, "sets record again")
+				_, second, err := fhm.GetWithMeta(key)
+				assert.NoError(t, err, "gets second meta")
+				time.Sleep(time.Millisecond)
+				err = fhm.Update(key, func(value []byte) ([]byte, error) { return value, nil })
+				assert.NoError(t, err, "updates record")
+				got, third, err := fhm.GetWithMeta(key)
+				assert.NoError(t, err, "gets third meta")
+
+				// Check
+				assert.Equal(t, value, got, "value without timestamps")
+				assert.False(t, first.Created.Before(before), "created after set")
+				assert.Equal(t, first.Created, first.Modified, "modified equals created for new record")
+				assert.Equal(t, first.Created, second.Created, "created kept by Set")
+				assert.True(t, second.Modified.After(first.Modified), "modified updated by Set")
+				assert.Equal(t, first.Created, third.Created, "created kept by Update")
+				assert.True(t, third.Modified.After(second.Modified), "modified updated by Update")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("keeps timestamps when reopening and reorganizing", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 5, 10, nil, FeatureTimestamps)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := []byte{1, 2, 3, 4, 5}
+		value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		err = fhm.Set(key, value)
+		assert.NoError(t, err, "sets record")
+		_, meta, err := fhm.GetWithMeta(key)
+		assert.NoError(t, err, "gets meta")
+		fhm.CloseFiles()
+
+		// Execute
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens existing files")
+		_, reopened, errReopened := fhm.GetWithMeta(key)
+		fhm.CloseFiles()
+		_, _, errReorg := ReorgFiles(testHashMap, ReorgConf{NumberOfBucketsNeeded: 100, RecordsPerBucket: 2}, false)
+
+		// Check
+		assert.NoError(t, errReopened, "gets meta from reopened files")
+		assert.Equal(t, meta, reopened, "timestamps kept when reopening")
+		assert.NoError(t, errReorg, "reorganizes files")
+
+		fhm, _, err = NewFromExistingFiles(fmt.Sprintf("%s-reorg", testHashMap), nil)
+		assert.NoError(t, err, "opens reorganized files")
+		got, reorganized, err := fhm.GetWithMeta(key)
+		assert.NoError(t, err, "gets meta from reorganized files")
+		assert.Equal(t, value, got, "value kept")
+		assert.Equal(t, meta, reorganized, "timestamps kept when reorganizing")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes reorganized files")
+		err = os.Remove(fmt.Sprintf("%s-map.bin", testHashMap))
+		assert.NoError(t, err, "removes original map file")
+	})
+
+	t.Run("rejects GetWithMeta without timestamps", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 5, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		_, _, err = fhm.GetWithMeta([]byte{1, 2, 3, 4, 5})
+
+		// Check
+		assert.Error(t, err, "timestamps not stored")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
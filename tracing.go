@@ -0,0 +1,141 @@
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/internal/model"
+	"hash/crc32"
+	"time"
+)
+
+// OperationEvent - Describes a completed operation, see SetOnOperation
+//   - Operation is the name of the operation, i.e. "Get", "Set" or "Pop"
+//   - KeyHash is a CRC-32 (IEEE) checksum of the key as given to the operation, which identifies the key without exposing it
+//   - BucketNo is the home bucket of the key, see HomeBucket
+//   - Probes is the number of buckets read while looking for the key, which with Separate Chaining, Hybrid and Linear
+//     Hashing also includes the overflow records walked, see Metrics.AddProbeIterations
+//   - Duration is the time the operation took
+//   - Err is the error returned by the operation, if any
+type OperationEvent struct {
+	Operation string
+	KeyHash   uint32
+	BucketNo  int64
+	Probes    int64
+	Duration  time.Duration
+	Err       error
+}
+
+// operationTracer - Holds the callback set by SetOnOperation and the state of the current operation, which is
+// protected by the lock of the FileHashMap
+//   - callback is the function to report completed operations to
+//   - depth is the number of nested operations in progress, only the outermost one is reported
+//   - probes is the number of probe iterations counted for the current operation
+type operationTracer struct {
+	callback func(event OperationEvent)
+	depth    int
+	probes   int64
+}
+
+// SetOnOperation - Sets a callback that is called each time a Get, Set or Pop has completed, e.g. to feed spans into
+// OpenTelemetry and to find slow probe sequences in production. Set includes SetWithTTL, SetWithSeq, SetIfAbsent,
+// GetOrSet and SetIdempotent, while Pop, which is the way records are deleted, includes PopWithSeq and PopAll. The callback is
+// called with the lock held, so it must not call the FileHashMap, and it has to be quick.
+// The callback is not persisted, so it has to be set each time the FileHashMap is opened.
+//   - callback is the function to call, nil turns off the callback
+func (F *FileHashMap) SetOnOperation(callback func(event OperationEvent)) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if callback == nil {
+		F.tracer = nil
+	} else {
+		F.tracer = &operationTracer{callback: callback}
+	}
+	setStorageMetrics(F.fileManagement, F.storageMetrics())
+}
+
+// trace - Marks the start of an operation on key to be reported to any callback set by SetOnOperation, to be called
+// with the lock held. The returned function marks the end of the operation, given a pointer to the error it returns,
+// and is typically deferred.
+func (F *FileHashMap) trace(operation string, key []byte) (done func(err *error)) {
+	tracer := F.tracer
+	if tracer == nil {
+		return func(*error) {}
+	}
+
+	tracer.depth++
+	if tracer.depth > 1 {
+		return func(*error) { tracer.depth-- }
+	}
+
+	tracer.probes = 0
+	start := time.Now()
+
+	return func(err *error) {
+		tracer.depth--
+		tracer.callback(OperationEvent{
+			Operation: operation,
+			KeyHash:   crc32.ChecksumIEEE(key),
+			BucketNo:  homeBucketOf(F.fileManagement, F.toStoredKey(key)),
+			Probes:    tracer.probes,
+			Duration:  time.Since(start),
+			Err:       *err,
+		})
+	}
+}
+
+// storageMetrics - Returns what to give to the file management as receiver of metrics, see SetMetrics and
+// SetOnOperation, which is nil if neither is set
+func (F *FileHashMap) storageMetrics() (metrics model.Metrics) {
+	receiver := F.opStats.metrics()
+	if receiver == nil && F.tracer == nil {
+		return
+	}
+
+	metrics = &storageMetrics{receiver: receiver, tracer: F.tracer}
+
+	return
+}
+
+// storageMetrics - Forwards metrics from the file management to any receiver of metrics while counting probe
+// iterations for any operation tracer
+type storageMetrics struct {
+	receiver Metrics
+	tracer   *operationTracer
+}
+
+// AddProbeIterations - Forwards probe iterations and counts them for the current operation
+func (S *storageMetrics) AddProbeIterations(n int64) {
+	if S.tracer != nil {
+		S.tracer.probes += n
+	}
+	if S.receiver != nil {
+		S.receiver.AddProbeIterations(n)
+	}
+}
+
+// AddOverflowAppends - Forwards overflow appends
+func (S *storageMetrics) AddOverflowAppends(n int64) {
+	if S.receiver != nil {
+		S.receiver.AddOverflowAppends(n)
+	}
+}
+
+// AddBytesRead - Forwards bytes read
+func (S *storageMetrics) AddBytesRead(n int64) {
+	if S.receiver != nil {
+		S.receiver.AddBytesRead(n)
+	}
+}
+
+// AddBytesWritten - Forwards bytes written
+func (S *storageMetrics) AddBytesWritten(n int64) {
+	if S.receiver != nil {
+		S.receiver.AddBytesWritten(n)
+	}
+}
+
+// AddRetries - Forwards retries
+func (S *storageMetrics) AddRetries(n int64) {
+	if S.receiver != nil {
+		S.receiver.AddRetries(n)
+	}
+}
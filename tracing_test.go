@@ -0,0 +1,115 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"testing"
+)
+
+func TestSetOnOperation(t *testing.T) {
+	t.Run("operation tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 1, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("reports operations for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				key := make([]byte, test.keyLength)
+				key[0] = 1
+				missing := make([]byte, test.keyLength)
+				missing[0] = 2
+
+				var events []OperationEvent
+				fhm.SetOnOperation(func(event OperationEvent) { events = append(events, event) })
+
+				// Execute
+				err = fhm.Set(key, make([]byte, test.valueLength))
+				assert.NoError(t, err, "sets record")
+				_, err = fhm.Get(key)
+				assert.NoError(t, err, "gets record")
+				_, err = fhm.Get(missing)
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "misses record")
+				_, err = fhm.Pop(key)
+				assert.NoError(t, err, "pops record")
+
+				// Check
+				assert.Len(t, events, 4, "one event per operation")
+				for i, operation := range []string{"Set", "Get", "Get", "Pop"} {
+					assert.Equalf(t, operation, events[i].Operation, "operation of event #%d", i)
+					assert.Positivef(t, events[i].Probes, "probes of event #%d", i)
+					assert.Positivef(t, events[i].Duration, "duration of event #%d", i)
+				}
+				assert.Equal(t, crc32.ChecksumIEEE(key), events[0].KeyHash, "key hash")
+				assert.Equal(t, fhm.HomeBucket(key), events[0].BucketNo, "home bucket")
+				assert.Equal(t, crc32.ChecksumIEEE(missing), events[2].KeyHash, "key hash of missing key")
+				assert.Equal(t, fhm.HomeBucket(missing), events[2].BucketNo, "home bucket of missing key")
+				assert.NoError(t, events[1].Err, "no error from get")
+				assert.ErrorIs(t, events[2].Err, crt.NoRecordFound{}, "error from missing get")
+
+				fhm.SetOnOperation(nil)
+				_, err = fhm.Get(key)
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "misses popped record")
+				assert.Len(t, events, 4, "no more events")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("reports GetOrSet as set whether or not the record exists", func(t *testing.T) {
+		for _, autoGrow := range []bool{false, true} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+			assert.NoError(t, err, "create new file hash map struct")
+			if autoGrow {
+				err = fhm.EnableAutoGrow(0.9)
+				assert.NoError(t, err, "enables auto grow, which looks up the record before setting it")
+			}
+
+			key := make([]byte, 16)
+			key[0] = 1
+
+			var events []OperationEvent
+			fhm.SetOnOperation(func(event OperationEvent) { events = append(events, event) })
+
+			// Execute
+			_, loaded, err := fhm.GetOrSet(key, make([]byte, 10))
+			assert.NoError(t, err, "sets record")
+			assert.False(t, loaded, "record set")
+			_, loaded, err = fhm.GetOrSet(key, make([]byte, 10))
+			assert.NoError(t, err, "gets record")
+			assert.True(t, loaded, "record loaded")
+
+			// Check
+			assert.Lenf(t, events, 2, "one event per operation with auto grow %t", autoGrow)
+			for i, event := range events {
+				assert.Equalf(t, "Set", event.Operation, "operation of event #%d", i)
+				assert.Equalf(t, crc32.ChecksumIEEE(key), event.KeyHash, "key hash of event #%d", i)
+				assert.Positivef(t, event.Probes, "probes of event #%d", i)
+			}
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "removes files")
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package filehashmap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+)
+
+// RecordDescription - Description of a record found at a given address, see WhatIsAt
+//   - Address is the address of the record in the file
+//   - IsOverflow is true if the record is stored in the overflow file
+//   - BucketNo is the bucket that the record is stored in, or for a record in the overflow file, the bucket whose
+//     overflow chain it is part of
+//   - State is the state of the record, either StateOccupied, StateDeleted or StateEmpty
+//   - Key is the key of the record
+//   - KeyHex is the key of the record in hexadecimal form
+//   - HomeBucketNo is the bucket that the key is addressed to by the hash algorithm, where lookups start
+//   - Reachable is true if the record is occupied and a lookup of its key ends at this very record
+type RecordDescription struct {
+	Address      int64
+	IsOverflow   bool
+	BucketNo     int64
+	State        uint8
+	Key          []byte
+	KeyHex       string
+	HomeBucketNo int64
+	Reachable    bool
+}
+
+// WhatIsAt - Decodes the record that lies at a given address in the map file or the overflow file, which is helpful
+// when investigating a specific address reported as corrupt. Besides the record itself it reports the bucket that
+// the key is addressed to and whether a lookup of the key actually finds the record, a record that is occupied but
+// not reachable is lost to Get. Finding a record in the overflow file requires a walk through all overflow chains.
+// A lookup may remove expired records, as any Get does.
+//   - address is the address of the start of the record in the file
+//   - isOverflow is true if address is in the overflow file, false if it is in the map file
+//
+// It returns:
+//   - description is a RecordDescription struct describing the record
+//   - err is a standard error, if no record starts at address or something went wrong
+func (F *FileHashMap) WhatIsAt(address int64, isOverflow bool) (description RecordDescription, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	var record model.Record
+	var bucketNo int64
+	var found bool
+	if isOverflow {
+		record, bucketNo, found, err = F.findOverflowRecord(address)
+	} else {
+		record, bucketNo, found, err = F.findMapFileRecord(address)
+	}
+	if err != nil {
+		return
+	}
+	if !found {
+		err = fmt.Errorf("no record starts at address %d", address)
+		return
+	}
+
+	key := record.Key
+	if record.State != model.RecordEmpty {
+		key, err = F.userKey(record)
+		if err != nil {
+			return
+		}
+	}
+
+	description = RecordDescription{
+		Address:      address,
+		IsOverflow:   isOverflow,
+		BucketNo:     bucketNo,
+		State:        record.State,
+		Key:          key,
+		KeyHex:       hex.EncodeToString(key),
+		HomeBucketNo: homeBucketOf(F.fileManagement, record.Key),
+	}
+
+	if record.State == model.RecordOccupied {
+		var got model.Record
+		got, err = F.fileManagement.Get(model.Record{Key: record.Key})
+		if err != nil && !errors.Is(err, crt.NoRecordFound{}) {
+			return
+		}
+		err = nil
+		description.Reachable = got.RecordAddress == address && got.IsOverflow == isOverflow
+	}
+
+	return
+}
+
+// findMapFileRecord - Finds the record that starts at address in the map file, to be called with the lock held
+func (F *FileHashMap) findMapFileRecord(address int64) (record model.Record, bucketNo int64, found bool, err error) {
+	var bucket model.Bucket
+
+	sp := F.fileManagement.GetStorageParameters()
+	if address < storage.MapFileHeaderLength || address >= sp.MapFileSize {
+		return
+	}
+
+	bucketLength := (sp.MapFileSize - storage.MapFileHeaderLength) / sp.NumberOfBucketsAvailable
+	bucketNo = (address - storage.MapFileHeaderLength) / bucketLength
+
+	bucket, _, err = F.fileManagement.GetBucket(bucketNo)
+	if err != nil {
+		return
+	}
+
+	for _, record = range bucket.Records {
+		if record.RecordAddress == address {
+			found = true
+			return
+		}
+	}
+
+	return
+}
+
+// findOverflowRecord - Finds the record that starts at address in the overflow file by walking through all overflow
+// chains, to be called with the lock held
+func (F *FileHashMap) findOverflowRecord(address int64) (record model.Record, bucketNo int64, found bool, err error) {
+	var iter *overflow.Records
+
+	sp := F.fileManagement.GetStorageParameters()
+	for bucketNo = 0; bucketNo < sp.NumberOfBucketsAvailable; bucketNo++ {
+		_, iter, err = F.fileManagement.GetBucket(bucketNo)
+		if err != nil {
+			return
+		}
+
+		for iter != nil && iter.HasNext() {
+			record, err = iter.Next()
+			if err != nil {
+				return
+			}
+			if record.RecordAddress == address {
+				found = true
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// HomeBucket - Returns the bucket that key is addressed to by the hash algorithm, which is where lookups of key start.
+// Keys with the same home bucket collide, which is useful when building test data with long collision chains.
+//   - key is the identifier of a record, for variable length keys the home bucket of its digest is returned
+//
+// It returns:
+//   - bucketNo is the home bucket of key
+func (F *FileHashMap) HomeBucket(key []byte) (bucketNo int64) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	bucketNo = homeBucketOf(F.fileManagement, F.toStoredKey(key))
+
+	return
+}
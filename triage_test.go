@@ -0,0 +1,71 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestWhatIsAt(t *testing.T) {
+	t.Run("describes records at addresses for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("describes records for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 100)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				err = fhm.SetBatch(records)
+				assert.NoError(t, err, "sets records")
+
+				// Execute & Check
+				for _, r := range records {
+					stored, err := fhm.fileManagement.Get(model.Record{Key: r.Key})
+					assert.NoError(t, err, "gets stored record")
+
+					description, err := fhm.WhatIsAt(stored.RecordAddress, stored.IsOverflow)
+					assert.NoError(t, err, "describes record")
+					assert.Equal(t, StateOccupied, description.State, "correct state")
+					assert.Equal(t, r.Key, description.Key, "correct key")
+					assert.Equal(t, hex.EncodeToString(r.Key), description.KeyHex, "correct key in hex")
+					assert.Equal(t, stored.IsOverflow, description.IsOverflow, "correct file")
+					assert.True(t, description.Reachable, "record is reachable")
+					if test.crt == crt.SeparateChaining || test.crt == crt.LinearHashing {
+						assert.Equal(t, description.HomeBucketNo, description.BucketNo, "stored in home bucket")
+					}
+				}
+
+				_, errMisaligned := fhm.WhatIsAt(1025, false)
+				_, errOutside := fhm.WhatIsAt(1<<40, false)
+				assert.Error(t, errMisaligned, "no record starts at misaligned address")
+				assert.Error(t, errOutside, "no record starts outside of file")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+}
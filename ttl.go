@@ -0,0 +1,171 @@
+package filehashmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"math/rand"
+	"time"
+)
+
+// ttlSystemValueID - Is the id of the system value in the header that marks a file hash map as supporting TTL
+const ttlSystemValueID uint8 = 1
+
+// ttlLength - Is the number of bytes in front of each stored value holding the expiry time
+const ttlLength int = 8
+
+// ttlSettings - Holds the TTL settings of a file hash map that supports TTL
+type ttlSettings struct {
+	jitter time.Duration
+}
+
+// expiryChecker - Implemented by file management that can delete expired records lazily while probing or scanning
+type expiryChecker interface {
+	SetExpiryCheck(isExpired func(value []byte) bool)
+}
+
+// enableTTL - Turns on TTL handling, both in the file hash map and in the file management
+func (F *FileHashMap) enableTTL() {
+	F.ttl = &ttlSettings{}
+	if checker, ok := F.fileManagement.(expiryChecker); ok {
+		checker.SetExpiryCheck(isExpiredValue)
+	}
+}
+
+// SetWithTTL - Works as Set but the record expires after ttl, plus a random jitter if set by SetTTLJitter. An expired
+// record is treated as if it was popped, but without a sequence number or WAL entry since it is removed lazily.
+// The file hash map must have been created with FeatureTTL.
+//   - key is the identifier of a record, it has to be of same length as given in call to NewFileHashMap
+//   - value is the bytes to be written to the bucket along with its key, length must be as was given in call to NewFileHashMap
+//   - ttl is the time to live for the record, a ttl of zero or below means that the record never expires
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) SetWithTTL(key []byte, value []byte, ttl time.Duration) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.ttl == nil {
+		err = fmt.Errorf("file hash map does not support TTL, it has to be created with FeatureTTL")
+		return
+	}
+
+	var expiry int64
+	if ttl > 0 {
+		if F.ttl.jitter > 0 {
+			ttl += time.Duration(rand.Int63n(int64(F.ttl.jitter) + 1))
+		}
+		expiry = time.Now().Add(ttl).UnixNano()
+	}
+
+	err = F.set(key, value, expiry)
+
+	return
+}
+
+// SetTTLJitter - Sets the max jitter to add to the ttl given in calls to SetWithTTL. Each record gets a random jitter
+// between zero and jitter, which spreads out the expiry of records written at the same time with the same ttl.
+// The jitter is not persisted and has to be set each time the file hash map is opened.
+//   - jitter is the max jitter to add, zero turns jitter off
+//
+// It returns:
+//   - err is a standard error, if something went wrong
+func (F *FileHashMap) SetTTLJitter(jitter time.Duration) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.ttl == nil {
+		err = fmt.Errorf("file hash map does not support TTL, it has to be created with FeatureTTL")
+		return
+	}
+	if jitter < 0 {
+		err = fmt.Errorf("jitter can not be negative")
+		return
+	}
+
+	F.ttl.jitter = jitter
+
+	return
+}
+
+// toStoredValue - Returns value in the form it is stored in files, which if TTL is supported has the expiry time in
+// front of it, if timestamps are stored has the current time as both created and modified timestamps after it, and
+// if checksums are stored has the checksum of stored key and value after that
+//   - key is the key in the form it is stored in files
+func (F *FileHashMap) toStoredValue(key, value []byte, expiry int64) (stored []byte, err error) {
+	if F.ttl == nil && !F.checksums && !F.timestamps {
+		stored = value
+		return
+	}
+
+	valueLength := int(F.fileManagement.GetStorageParameters().ValueLength)
+	if F.ttl != nil {
+		valueLength -= ttlLength
+	}
+	if F.checksums {
+		valueLength -= checksumLength
+	}
+	if F.timestamps {
+		valueLength -= timestampsLength
+	}
+	if len(value) != valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "Set", Expected: valueLength, Actual: len(value)}
+		return
+	}
+
+	stored = value
+	if F.ttl != nil {
+		stored = make([]byte, ttlLength, ttlLength+valueLength)
+		binary.LittleEndian.PutUint64(stored, uint64(expiry))
+		stored = append(stored, value...)
+	}
+	if F.timestamps {
+		now := time.Now().UnixNano()
+		stored = withTimestamps(stored, now, now)
+	}
+	stored = F.withChecksum(key, stored)
+
+	return
+}
+
+// fromStoredValue - Returns the value part of a value in the form it is stored in files
+func (F *FileHashMap) fromStoredValue(stored []byte) (value []byte) {
+	value = F.withoutExpiry(F.withoutTimestamps(F.withoutChecksum(stored)))
+	if F.keyFile != nil && len(value) >= keyAddressLength {
+		value = value[keyAddressLength:]
+	}
+
+	return
+}
+
+// withoutExpiry - Returns a value in the form it is stored in files without any expiry time in front of it
+func (F *FileHashMap) withoutExpiry(stored []byte) (value []byte) {
+	if F.ttl == nil || len(stored) < ttlLength {
+		return stored
+	}
+
+	return stored[ttlLength:]
+}
+
+// hasExpired - Returns true if TTL is supported and the record has expired
+func (F *FileHashMap) hasExpired(record model.Record) bool {
+	return F.ttl != nil && isExpiredValue(record.Value)
+}
+
+// isExpiredValue - Returns true if the expiry time in front of a stored value has passed, an expiry time of zero
+// means that the value never expires
+func isExpiredValue(stored []byte) bool {
+	expiry := storedExpiry(stored)
+
+	return expiry != 0 && expiry <= time.Now().UnixNano()
+}
+
+// storedExpiry - Returns the expiry time in front of a stored value
+func storedExpiry(stored []byte) (expiry int64) {
+	if len(stored) < ttlLength {
+		return
+	}
+
+	return int64(binary.LittleEndian.Uint64(stored))
+}
@@ -0,0 +1,174 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	t.Run("ttl tests for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "Hybrid", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hybrid},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("expires records lazily for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc, FeatureTTL)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				records := make([]Record, 250)
+				for i := range records {
+					records[i] = Record{Key: make([]byte, test.keyLength), Value: make([]byte, test.valueLength)}
+					rand.Read(records[i].Key)
+					rand.Read(records[i].Value)
+				}
+				for _, record := range records[:200] {
+					err = fhm.SetWithTTL(record.Key, record.Value, 250*time.Millisecond)
+					assert.NoError(t, err, "sets record with ttl")
+				}
+				err = fhm.SetBatch(records[200:])
+				assert.NoError(t, err, "sets records without ttl")
+
+				// Execute
+				valueBefore, errBefore := fhm.Get(records[0].Key)
+				time.Sleep(300 * time.Millisecond)
+				_, errAfter := fhm.Get(records[0].Key)
+				stat, errStat := fhm.Stat(false)
+
+				// Check
+				assert.NoError(t, errBefore, "gets record before expiry")
+				assert.Equal(t, records[0].Value, valueBefore, "value without expiry time")
+				assert.ErrorIs(t, errAfter, crt.NoRecordFound{}, "expired record not found")
+				assert.NoError(t, errStat, "gets stat")
+				assert.Equal(t, 50, stat.Records, "only records without ttl counted")
+
+				for _, record := range records[200:] {
+					value, err := fhm.Get(record.Key)
+					assert.NoError(t, err, "gets record without ttl")
+					assert.Equal(t, record.Value, value, "correct value")
+				}
+
+				n := 0
+				err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					n++
+					assert.Len(t, value, test.valueLength, "value without expiry time")
+					return
+				})
+				assert.NoError(t, err, "iterates records")
+				assert.Equal(t, 50, n, "expired records not iterated")
+
+				// Expired slots are reused
+				for i := 0; i < 200; i++ {
+					key := make([]byte, test.keyLength)
+					rand.Read(key)
+					err = fhm.SetWithTTL(key, records[0].Value, time.Hour)
+					assert.NoError(t, err, "sets record in expired slot")
+				}
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("ttl support is persisted", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil, FeatureTTL)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		value := make([]byte, 10)
+		rand.Read(key)
+		rand.Read(value)
+		err = fhm.SetWithTTL(key, value, time.Hour)
+		assert.NoError(t, err, "sets record with ttl")
+		fhm.CloseFiles()
+
+		// Execute
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens existing file hash map")
+		gotValue, errGet := fhm.Get(key)
+		errSet := fhm.Set(key, make([]byte, 18))
+
+		// Check
+		assert.NoError(t, errGet, "gets record")
+		assert.Equal(t, value, gotValue, "value without expiry time")
+		assert.Error(t, errSet, "value including room for expiry time is rejected")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("jitter is applied at write time", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil, FeatureTTL)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		err = fhm.SetTTLJitter(-time.Second)
+		assert.Error(t, err, "negative jitter is rejected")
+		err = fhm.SetTTLJitter(time.Hour)
+		assert.NoError(t, err, "sets jitter")
+
+		keys := make([][]byte, 20)
+		for i := range keys {
+			keys[i] = make([]byte, 16)
+			rand.Read(keys[i])
+		}
+
+		// Execute
+		for _, key := range keys {
+			err = fhm.SetWithTTL(key, make([]byte, 10), time.Millisecond)
+			assert.NoError(t, err, "sets record with ttl")
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		// Check
+		n := 0
+		err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+			n++
+			return
+		})
+		assert.NoError(t, err, "iterates records")
+		assert.Greater(t, n, 0, "jitter postpones expiry")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("ttl requires ttl support", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		errSet := fhm.SetWithTTL(make([]byte, 16), make([]byte, 10), time.Second)
+		errJitter := fhm.SetTTLJitter(time.Second)
+
+		// Check
+		assert.Error(t, errSet, "ttl not supported")
+		assert.Error(t, errJitter, "jitter not supported")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
@@ -0,0 +1,95 @@
+package filehashmap
+
+import (
+	"errors"
+	"github.com/gostonefire/filehashmap/crt"
+)
+
+// UnionView - A read-only view of several file hash maps that are queried in priority order, as if they were one
+// logical dataset, e.g. a stack of daily snapshot maps with the most recent first. A key found in a map shadows the
+// same key in all maps after it. The view holds no locks or files of its own, so the maps can still be used directly,
+// and closing them is up to the caller.
+type UnionView struct {
+	maps []*FileHashMap
+}
+
+// Union - Returns a read-only view of maps where a key is looked up in each map in the given order until found
+//   - maps is the file hash maps to query, in priority order with the highest priority first
+//
+// It returns:
+//   - view is a pointer to a UnionView struct
+func Union(maps ...*FileHashMap) (view *UnionView) {
+	view = &UnionView{maps: append([]*FileHashMap(nil), maps...)}
+
+	return
+}
+
+// Get - Gets the value of key from the first map in priority order that has a record with key
+//   - key is the identifier of a record, it has to be of the key length of the maps
+//
+// It returns:
+//   - value is the value of the matching record if found, if not found in any map an error of type crt.NoRecordFound is also returned.
+//   - err is either of type crt.NoRecordFound or a standard error, if something went wrong in any of the maps
+//     consulted. Errors other than crt.NoRecordFound stop the lookup, so a damaged record is never shadowed silently.
+func (U *UnionView) Get(key []byte) (value []byte, err error) {
+	for _, fhm := range U.maps {
+		value, err = fhm.Get(key)
+		if !errors.Is(err, crt.NoRecordFound{}) {
+			return
+		}
+	}
+
+	value, err = nil, crt.NoRecordFound{}
+
+	return
+}
+
+// GetBatch - Gets values for many keys in one call, using GetBatch on each map in priority order for the keys that
+// were not found in the maps before it
+//   - keys is the identifiers of records, they have to be of the key length of the maps
+//
+// It returns:
+//   - values is the values in the same order as keys, with nil for keys that were not found in any map
+//   - errs is per key errors in the same order as keys, nil if found or an error of type crt.NoRecordFound if not
+//   - err is a standard error, if something went wrong
+func (U *UnionView) GetBatch(keys [][]byte) (values [][]byte, errs []error, err error) {
+	values = make([][]byte, len(keys))
+	errs = make([]error, len(keys))
+
+	pending := make([]int, len(keys))
+	for i := range keys {
+		pending[i] = i
+		errs[i] = crt.NoRecordFound{}
+	}
+
+	var mapValues [][]byte
+	var mapErrs []error
+	for _, fhm := range U.maps {
+		if len(pending) == 0 {
+			break
+		}
+
+		mapKeys := make([][]byte, len(pending))
+		for j, i := range pending {
+			mapKeys[j] = keys[i]
+		}
+
+		mapValues, mapErrs, err = fhm.GetBatch(mapKeys)
+		if err != nil {
+			values, errs = nil, nil
+			return
+		}
+
+		stillPending := pending[:0]
+		for j, i := range pending {
+			if errors.Is(mapErrs[j], crt.NoRecordFound{}) {
+				stillPending = append(stillPending, i)
+				continue
+			}
+			values[i], errs[i] = mapValues[j], mapErrs[j]
+		}
+		pending = stillPending
+	}
+
+	return
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	t.Run("gets values in priority order", func(t *testing.T) {
+		// Prepare
+		today, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 2, 16, 10, nil)
+		assert.NoError(t, err, "create today's file hash map")
+		yesterday, _, err := NewFileHashMap(testHashMap+"-yesterday", crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create yesterday's file hash map")
+
+		keys := make([][]byte, 4)
+		for i := range keys {
+			keys[i] = make([]byte, 16)
+			keys[i][0] = byte(i)
+		}
+		todayValue := []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+		yesterdayValue := []byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+		err = today.Set(keys[0], todayValue)
+		assert.NoError(t, err, "sets record only today")
+		err = today.Set(keys[1], todayValue)
+		assert.NoError(t, err, "sets record today")
+		err = yesterday.Set(keys[1], yesterdayValue)
+		assert.NoError(t, err, "sets shadowed record yesterday")
+		err = yesterday.Set(keys[2], yesterdayValue)
+		assert.NoError(t, err, "sets record only yesterday")
+
+		view := Union(today, yesterday)
+
+		// Execute
+		values := make([][]byte, len(keys))
+		errs := make([]error, len(keys))
+		for i, key := range keys {
+			values[i], errs[i] = view.Get(key)
+		}
+		batchValues, batchErrs, err := view.GetBatch(keys)
+
+		// Check
+		expected := [][]byte{todayValue, todayValue, yesterdayValue, nil}
+		for i := range keys {
+			if expected[i] == nil {
+				assert.ErrorIsf(t, errs[i], crt.NoRecordFound{}, "record #%d not found", i)
+				assert.ErrorIsf(t, batchErrs[i], crt.NoRecordFound{}, "record #%d not found in batch", i)
+				continue
+			}
+			assert.NoErrorf(t, errs[i], "gets record #%d", i)
+			assert.Truef(t, utils.IsEqual(expected[i], values[i]), "value of record #%d", i)
+			assert.NoErrorf(t, batchErrs[i], "gets record #%d in batch", i)
+			assert.Truef(t, utils.IsEqual(expected[i], batchValues[i]), "value of record #%d in batch", i)
+		}
+		assert.NoError(t, err, "gets batch")
+
+		// Clean up
+		err = today.RemoveFiles()
+		assert.NoError(t, err, "removes today's files")
+		err = yesterday.RemoveFiles()
+		assert.NoError(t, err, "removes yesterday's files")
+	})
+
+	t.Run("finds nothing in an empty view", func(t *testing.T) {
+		// Execute
+		_, err := Union().Get(make([]byte, 16))
+
+		// Check
+		assert.ErrorIs(t, err, crt.NoRecordFound{}, "no record found")
+	})
+}
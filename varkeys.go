@@ -0,0 +1,169 @@
+package filehashmap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/keyfile"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/gostonefire/filehashmap/internal/utils"
+)
+
+// varKeysSystemValueID - Is the id of the system value in the header that marks a file hash map as supporting
+// variable length keys
+const varKeysSystemValueID uint8 = 2
+
+// keyDigestLength - Is the number of bytes of the key digest stored as key in the map file
+const keyDigestLength int = 16
+
+// storedKeyLength - Is the length of keys stored in the map file, which is the key digest followed by the key length
+const storedKeyLength int = keyDigestLength + 4
+
+// keyAddressLength - Is the number of bytes in front of each stored value holding the address of the key in the key file
+const keyAddressLength int = 8
+
+// enableVariableKeys - Turns on variable key handling by opening, or creating, the key file
+func (F *FileHashMap) enableVariableKeys() (err error) {
+	keyFile, err := keyfile.Open(F.fileSystem(), storage.GetKeyFileName(F.name))
+	if err != nil {
+		return
+	}
+
+	err = syncDirOf(storage.GetKeyFileName(F.name), F.options)
+	if err != nil {
+		keyFile.Close()
+		return
+	}
+
+	F.keyFile = keyFile
+
+	return
+}
+
+// toStoredKey - Returns the key in the form it is stored in the map file
+func (F *FileHashMap) toStoredKey(key []byte) (storedKey []byte) {
+	if F.keyFile == nil {
+		storedKey = key
+		return
+	}
+
+	digest := sha256.Sum256(key)
+	storedKey = make([]byte, storedKeyLength)
+	copy(storedKey, digest[:keyDigestLength])
+	binary.LittleEndian.PutUint32(storedKey[keyDigestLength:], uint32(len(key)))
+
+	return
+}
+
+// toStoredKeyValue - Returns the key and value in the form they are stored in the map file, before any expiry time
+// is added. For variable length keys the key is appended to the key file unless it is already stored.
+func (F *FileHashMap) toStoredKeyValue(key, value []byte) (storedKey, storedValue []byte, err error) {
+	if F.keyFile == nil {
+		storedKey, storedValue = key, value
+		return
+	}
+
+	valueLength := F.userValueLength()
+	if len(value) != valueLength {
+		err = crt.WrongLength{Field: "value", Operation: "Set", Expected: valueLength, Actual: len(value)}
+		return
+	}
+	if F.maxKeyLength > 0 && len(key) > F.maxKeyLength {
+		err = fmt.Errorf("key length %d exceeds max key length %d", len(key), F.maxKeyLength)
+		return
+	}
+
+	storedKey = F.toStoredKey(key)
+
+	var address int64
+	existing, err := F.fileManagement.Get(model.Record{Key: storedKey})
+	switch {
+	case err == nil:
+		var existingKey []byte
+		existingKey, err = F.userKey(existing)
+		if err != nil {
+			return
+		}
+		if !utils.IsEqual(key, existingKey) {
+			err = fmt.Errorf("key digest collides with the digest of an existing key")
+			return
+		}
+		address = keyAddressOf(F.withoutExpiry(existing.Value))
+
+	case errors.Is(err, crt.NoRecordFound{}):
+		address, err = F.keyFile.Append(key)
+		if err != nil {
+			err = fmt.Errorf("error while appending key to key file: %s", err)
+			return
+		}
+
+	default:
+		return
+	}
+
+	storedValue = make([]byte, keyAddressLength, keyAddressLength+len(value))
+	binary.LittleEndian.PutUint64(storedValue, uint64(address))
+	storedValue = append(storedValue, value...)
+
+	return
+}
+
+// lookup - Gets the record for key from the files, validating its checksum if checksums are stored and verifying the
+// full key if keys are of variable length
+func (F *FileHashMap) lookup(key []byte) (record model.Record, err error) {
+	storedKey := F.toStoredKey(key)
+	if F.definitelyMissing(storedKey) {
+		err = crt.NoRecordFound{}
+		return
+	}
+
+	record, err = F.fileManagement.Get(model.Record{Key: storedKey})
+	if err != nil {
+		return
+	}
+
+	err = F.checkRecord(record)
+	if err != nil || F.keyFile == nil {
+		return
+	}
+
+	err = F.verifyKey(record, key)
+
+	return
+}
+
+// verifyKey - Returns crt.NoRecordFound if the record found by digest doesn't have key as its full key
+func (F *FileHashMap) verifyKey(record model.Record, key []byte) (err error) {
+	storedKey, err := F.userKey(record)
+	if err != nil {
+		return
+	}
+	if !utils.IsEqual(key, storedKey) {
+		err = crt.NoRecordFound{}
+	}
+
+	return
+}
+
+// userKey - Returns the key of a record as given by the caller, which for variable length keys is read from the key file
+func (F *FileHashMap) userKey(record model.Record) (key []byte, err error) {
+	if F.keyFile == nil {
+		key = record.Key
+		return
+	}
+
+	key, err = F.keyFile.Read(keyAddressOf(F.withoutExpiry(record.Value)))
+	if err != nil {
+		err = fmt.Errorf("error while reading key from key file: %s", err)
+	}
+
+	return
+}
+
+// keyAddressOf - Returns the key address from a value with a key address in front of it
+func keyAddressOf(value []byte) int64 {
+	return int64(binary.LittleEndian.Uint64(value))
+}
@@ -0,0 +1,149 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func randomVariableKeys(n int) (keys [][]byte) {
+	keys = make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("https://example.com/%d/%s", i, make([]byte, rand.Intn(50))))
+	}
+
+	return
+}
+
+func TestVariableKeys(t *testing.T) {
+	t.Run("sets, gets and pops variable length keys for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "LinearHashing", buckets: 10, rpb: 2, valueLength: 10, crt: crt.LinearHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 3, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 3, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 3, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("variable keys for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, 0, test.valueLength, test.hFunc, FeatureVariableKeys)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				keys := randomVariableKeys(100)
+				values := make([][]byte, len(keys))
+				for i := range values {
+					values[i] = make([]byte, test.valueLength)
+					rand.Read(values[i])
+				}
+
+				// Execute
+				for i, key := range keys[:50] {
+					err = fhm.Set(key, values[i])
+					assert.NoError(t, err, "sets record")
+				}
+				batch := make([]Record, 50)
+				for i := range batch {
+					batch[i] = Record{Key: keys[50+i], Value: values[50+i]}
+				}
+				errBatch := fhm.SetBatch(batch)
+				errUpdate := fhm.Set(keys[1], values[0])
+				popped, errPop := fhm.Pop(keys[0])
+				errLength := fhm.Set([]byte("short"), make([]byte, test.valueLength+1))
+
+				// Check
+				assert.NoError(t, errBatch, "sets records in batch")
+				assert.NoError(t, errUpdate, "updates record")
+				assert.NoError(t, errPop, "pops record")
+				assert.Equal(t, values[0], popped, "popped value")
+				assert.ErrorIs(t, errLength, crt.WrongLength{}, "wrong value length rejected")
+
+				_, err = fhm.Get(keys[0])
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "popped record not found")
+				value, err := fhm.Get(keys[1])
+				assert.NoError(t, err, "gets updated record")
+				assert.Equal(t, values[0], value, "updated value")
+				got, errs, err := fhm.GetBatch(keys[2:])
+				assert.NoError(t, err, "gets records in batch")
+				for i := range got {
+					assert.NoError(t, errs[i], "gets record in batch")
+					assert.Equal(t, values[2+i], got[i], "correct value")
+				}
+
+				iterated := make(map[string][]byte)
+				err = fhm.ForEach(func(key, value []byte) (stop bool, err error) {
+					iterated[string(key)] = value
+					return
+				})
+				assert.NoError(t, err, "iterates records")
+				assert.Len(t, iterated, len(keys)-1, "all records iterated")
+				for i, key := range keys[2:] {
+					assert.Equal(t, values[2+i], iterated[string(key)], "iterated with full key")
+				}
+
+				fhm.CloseFiles()
+				fhm, _, err = NewFromExistingFiles(testHashMap, test.hFunc)
+				assert.NoError(t, err, "opens existing files")
+				value, err = fhm.Get(keys[99])
+				assert.NoError(t, err, "gets record from existing files")
+				assert.Equal(t, values[99], value, "correct value")
+
+				err = fhm.Clear()
+				assert.NoError(t, err, "clears records")
+				_, err = fhm.Get(keys[99])
+				assert.ErrorIs(t, err, crt.NoRecordFound{}, "cleared record not found")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+				_, err = os.Stat(storage.GetKeyFileName(testHashMap))
+				assert.True(t, os.IsNotExist(err), "key file removed")
+			})
+		}
+	})
+
+	t.Run("variable keys survive reorg and auto grow", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 0, 10, nil, FeatureVariableKeys)
+		assert.NoError(t, err, "create new file hash map struct")
+		err = fhm.EnableAutoGrow(0.8)
+		assert.NoError(t, err, "enables auto grow")
+
+		keys := randomVariableKeys(100)
+		for _, key := range keys {
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoError(t, err, "sets record")
+		}
+		fhm.CloseFiles()
+
+		// Execute
+		_, _, err = ReorgFiles(testHashMap, ReorgConf{ValueExtension: 2}, false)
+		assert.NoError(t, err, "reorganizes files")
+		fhm, _, err = NewFromExistingFiles(testHashMap+"-reorg", nil)
+		assert.NoError(t, err, "opens reorganized files")
+
+		// Check
+		for _, key := range keys {
+			value, err := fhm.Get(key)
+			assert.NoError(t, err, "gets record")
+			assert.Len(t, value, 12, "value extended")
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes reorganized files")
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "opens original files")
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes original files")
+	})
+}
@@ -0,0 +1,222 @@
+package filehashmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/overflow"
+	"github.com/gostonefire/filehashmap/internal/storage"
+)
+
+// VerifyProblem - A problem found by Verify
+//   - Address is the address of the record with the problem, or the bucket address for problems in a bucket header
+//   - IsOverflow is true if Address is in the overflow file
+//   - BucketNo is the bucket where the problem was found, for records in the overflow file the bucket whose overflow
+//     chain they are part of, or -1 for records in no chain
+//   - Description is a human-readable description of the problem
+type VerifyProblem struct {
+	Address     int64
+	IsOverflow  bool
+	BucketNo    int64
+	Description string
+}
+
+// VerifyReport - The result of Verify
+//   - Buckets is the number of buckets checked
+//   - Records is the number of occupied records checked, in the map file as well as in the overflow file
+//   - OverflowRecords is the number of records checked in the overflow file, occupied or not
+//   - Problems is the problems found, empty if the files are intact
+type VerifyReport struct {
+	Buckets         int64
+	Records         int64
+	OverflowRecords int64
+	Problems        []VerifyProblem
+}
+
+// OK - Returns true if Verify found no problems
+func (V VerifyReport) OK() bool {
+	return len(V.Problems) == 0
+}
+
+// Verify - Walks through every bucket and every overflow chain and checks that record states are valid, that overflow
+// pointers point within the overflow file and never loop, that keys have the right length (for variable length keys,
+// that they can be read from the key file), and that checksums match if the file hash map stores checksums (see
+// FeatureChecksums). If all overflow chains are intact, it also checks that every record in the overflow
+// file is either in a chain or in the list of free overflow records, and reports leaked records, which ScavengeOverflow
+// reclaims. Rather than failing at the first problem, all problems found are collected in the report, so it can be run
+// on files suspected to be damaged to see the extent of it. An overflow chain is not followed beyond a broken pointer.
+// Nothing is changed in the files.
+// As Stat, this can take a considerable amount of time for big files.
+//
+// It returns:
+//   - report is a VerifyReport struct with the problems found
+//   - err is a standard error, if something went wrong other than finding problems, e.g. the files could not be read
+func (F *FileHashMap) Verify() (report VerifyReport, err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+	defer F.watch("Verify")()
+
+	sp := F.fileManagement.GetStorageParameters()
+	ovflFileSize := int64(-1)
+	chainsIntact := true
+
+	var bucket model.Bucket
+	var iter *overflow.Records
+	var intact bool
+	for bucketNo := int64(0); bucketNo < sp.NumberOfBucketsAvailable; bucketNo++ {
+		bucket, iter, err = F.fileManagement.GetBucket(bucketNo)
+		if err != nil {
+			err = fmt.Errorf("error while reading bucket %d: %s", bucketNo, err)
+			return
+		}
+		report.Buckets++
+
+		for _, record := range bucket.Records {
+			F.verifyRecord(&report, record, bucketNo, sp)
+		}
+
+		if !bucket.HasOverflow || iter == nil {
+			continue
+		}
+
+		if ovflFileSize < 0 {
+			ovflFileSize, err = F.overflowFileSize()
+			if err != nil {
+				return
+			}
+		}
+
+		intact, err = F.verifyOverflowChain(&report, bucket, iter, bucketNo, ovflFileSize, sp)
+		if err != nil {
+			return
+		}
+		chainsIntact = chainsIntact && intact
+	}
+
+	if chainsIntact {
+		F.verifyOverflowReachable(&report)
+	}
+
+	return
+}
+
+// verifyOverflowReachable - Adds a problem to report for every overflow record that is neither in an overflow chain nor
+// in the list of free overflow records, to be called with the lock held and only if all overflow chains are intact, since
+// records beyond a broken pointer are unreachable as well
+func (F *FileHashMap) verifyOverflowReachable(report *VerifyReport) {
+	scavenger, ok := F.fileManagement.(overflowScavenger)
+	if !ok {
+		return
+	}
+	addresses, err := scavenger.UnreachableOverflow()
+	if err != nil {
+		report.Problems = append(report.Problems, VerifyProblem{IsOverflow: true, BucketNo: -1, Description: fmt.Sprintf("overflow file not walkable: %s", err)})
+		return
+	}
+
+	for _, address := range addresses {
+		report.Problems = append(report.Problems, VerifyProblem{Address: address, IsOverflow: true, BucketNo: -1, Description: "overflow record unreachable from any overflow chain or the list of free records"})
+	}
+}
+
+// verifyOverflowChain - Follows the overflow chain of bucket using iter and adds any problems found to report, each
+// pointer is checked before iter follows it. To be called with the lock held
+//
+// It returns:
+//   - intact is false if the chain was not followed to its end because of a broken pointer or an unreadable record
+//   - err is a standard error, if something went wrong other than finding problems
+func (F *FileHashMap) verifyOverflowChain(report *VerifyReport, bucket model.Bucket, iter *overflow.Records, bucketNo, ovflFileSize int64, sp model.StorageParameters) (intact bool, err error) {
+	var record model.Record
+
+	visited := make(map[int64]bool)
+	address := bucket.OverflowAddress
+	from, fromOverflow := bucket.BucketAddress, false
+	for iter.HasNext() {
+		problem := ""
+		switch {
+		case address < storage.OvflFileHeaderLength || address >= ovflFileSize:
+			problem = fmt.Sprintf("overflow pointer %d outside overflow file of size %d", address, ovflFileSize)
+		case visited[address]:
+			problem = fmt.Sprintf("overflow pointer %d loops back into the chain", address)
+		}
+		if problem != "" {
+			report.Problems = append(report.Problems, VerifyProblem{Address: from, IsOverflow: fromOverflow, BucketNo: bucketNo, Description: problem})
+			return
+		}
+		visited[address] = true
+
+		record, err = iter.Next()
+		if err != nil {
+			report.Problems = append(report.Problems, VerifyProblem{Address: address, IsOverflow: true, BucketNo: bucketNo, Description: fmt.Sprintf("unreadable overflow record: %s", err)})
+			err = nil
+			return
+		}
+		report.OverflowRecords++
+
+		F.verifyRecord(report, record, bucketNo, sp)
+
+		from, fromOverflow = address, true
+		address = record.NextOverflow
+	}
+
+	intact = true
+
+	return
+}
+
+// verifyRecord - Checks a single record and adds any problems found to report, to be called with the lock held
+func (F *FileHashMap) verifyRecord(report *VerifyReport, record model.Record, bucketNo int64, sp model.StorageParameters) {
+	addProblem := func(description string) {
+		report.Problems = append(report.Problems, VerifyProblem{
+			Address:     record.RecordAddress,
+			IsOverflow:  record.IsOverflow,
+			BucketNo:    bucketNo,
+			Description: description,
+		})
+	}
+
+	if record.State > model.RecordDeleted {
+		addProblem(fmt.Sprintf("invalid record state %d", record.State))
+		return
+	}
+	if record.State != model.RecordOccupied {
+		return
+	}
+	report.Records++
+
+	if int64(len(record.Key)) != sp.KeyLength {
+		addProblem(fmt.Sprintf("key length %d differs from %d", len(record.Key), sp.KeyLength))
+		return
+	}
+
+	if F.keyFile != nil {
+		key, err := F.userKey(record)
+		if err != nil {
+			addProblem(err.Error())
+			return
+		}
+		if len(key) == 0 {
+			addProblem("empty key in key file")
+			return
+		}
+	}
+
+	err := F.checkRecord(record)
+	if errors.Is(err, crt.CorruptRecord{}) {
+		addProblem("checksum mismatch")
+	}
+}
+
+// overflowFileSize - Returns the size of the overflow file of the file hash map
+func (F *FileHashMap) overflowFileSize() (size int64, err error) {
+	info, err := F.fileSystem().Stat(storage.GetOvflFileName(F.name))
+	if err != nil {
+		err = fmt.Errorf("error while getting size of overflow file: %s", err)
+		return
+	}
+
+	size = info.Size()
+
+	return
+}
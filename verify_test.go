@@ -0,0 +1,170 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/gostonefire/filehashmap/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("reports intact files as ok for all CRTs", func(t *testing.T) {
+		// Prepare
+		tests := []TestCaseOperations{
+			{crtName: "SeparateChaining", buckets: 10, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.SeparateChaining},
+			{crtName: "LinearProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.LinearProbing},
+			{crtName: "QuadraticProbing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.QuadraticProbing},
+			{crtName: "DoubleHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.DoubleHashing},
+			{crtName: "RobinHood", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.RobinHood},
+			{crtName: "CuckooHashing", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.CuckooHashing},
+			{crtName: "Hopscotch", buckets: 100, rpb: 2, keyLength: 16, valueLength: 10, crt: crt.Hopscotch},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("reports intact files as ok for %s", test.crtName), func(t *testing.T) {
+				// Prepare
+				fhm, _, err := NewFileHashMap(testHashMap, test.crt, test.buckets, test.rpb, test.keyLength, test.valueLength, test.hFunc, FeatureChecksums)
+				assert.NoError(t, err, "create new file hash map struct")
+
+				for i := 0; i < 50; i++ {
+					key := make([]byte, test.keyLength)
+					rand.Read(key)
+					err = fhm.Set(key, make([]byte, test.valueLength))
+					assert.NoErrorf(t, err, "sets record #%d", i)
+				}
+
+				// Execute
+				report, err := fhm.Verify()
+
+				// Check
+				assert.NoError(t, err, "verifies files")
+				assert.True(t, report.OK(), "no problems found")
+				assert.Equal(t, int64(50), report.Records, "all records checked")
+
+				// Clean up
+				err = fhm.RemoveFiles()
+				assert.NoError(t, err, "removes files")
+			})
+		}
+	})
+
+	t.Run("reports corrupted records and overflow pointers", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 16, 10, nil, FeatureChecksums)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		for i := 0; i < 50; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+
+		// Find three buckets that each have an occupied record in the map file and an overflow chain
+		var buckets []model.Bucket
+		for bucketNo := int64(0); bucketNo < 10 && len(buckets) < 3; bucketNo++ {
+			bucket, _, err := fhm.fileManagement.GetBucket(bucketNo)
+			assert.NoError(t, err, "gets bucket")
+			if bucket.HasOverflow && bucket.Records[0].State == model.RecordOccupied {
+				buckets = append(buckets, bucket)
+			}
+		}
+		assert.Len(t, buckets, 3, "finds buckets to corrupt")
+		fhm.CloseFiles()
+
+		file, err := os.OpenFile(storage.GetMapFileName(testHashMap), os.O_RDWR, 0644)
+		assert.NoError(t, err, "opens map file")
+		_, err = file.WriteAt([]byte{0xff}, buckets[0].Records[0].RecordAddress+1+16)
+		assert.NoError(t, err, "corrupts value")
+		_, err = file.WriteAt([]byte{0x03}, buckets[1].Records[0].RecordAddress)
+		assert.NoError(t, err, "corrupts record state")
+		pointer := make([]byte, 8)
+		binary.LittleEndian.PutUint64(pointer, 1<<40)
+		_, err = file.WriteAt(pointer, buckets[2].BucketAddress)
+		assert.NoError(t, err, "corrupts overflow pointer")
+		err = file.Close()
+		assert.NoError(t, err, "closes map file")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+
+		// Execute
+		report, err := fhm.Verify()
+
+		// Check
+		assert.NoError(t, err, "verifies files")
+		assert.False(t, report.OK(), "problems found")
+		assert.Len(t, report.Problems, 3, "one problem per corruption")
+
+		descriptions := make(map[int64]string)
+		for _, problem := range report.Problems {
+			descriptions[problem.Address] = problem.Description
+		}
+		assert.Equal(t, "checksum mismatch", descriptions[buckets[0].Records[0].RecordAddress], "detects corrupted value")
+		assert.Equal(t, "invalid record state 3", descriptions[buckets[1].Records[0].RecordAddress], "detects corrupted state")
+		assert.True(t, strings.HasPrefix(descriptions[buckets[2].BucketAddress], "overflow pointer"), "detects corrupted overflow pointer")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("reports leaked overflow records until scavenged", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 1, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		for i := 0; i < 50; i++ {
+			key := make([]byte, 16)
+			rand.Read(key)
+			err = fhm.Set(key, make([]byte, 10))
+			assert.NoErrorf(t, err, "sets record #%d", i)
+		}
+		fhm.CloseFiles()
+
+		// Simulate a crash after an overflow record was appended but before it was linked into a chain
+		file, err := os.OpenFile(storage.GetOvflFileName(testHashMap), os.O_RDWR, 0644)
+		assert.NoError(t, err, "opens overflow file")
+		stat, err := file.Stat()
+		assert.NoError(t, err, "gets overflow file size")
+		_, err = file.WriteAt(make([]byte, 8+1+16+10), stat.Size())
+		assert.NoError(t, err, "appends unlinked record")
+		err = file.Close()
+		assert.NoError(t, err, "closes overflow file")
+
+		fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, err, "reopens file hash map")
+
+		// Execute
+		report, err := fhm.Verify()
+		fhm.CloseFiles()
+		leaked, errScavenge := ScavengeOverflow(testHashMap)
+		fhm, _, errReopen := NewFromExistingFiles(testHashMap, nil)
+		assert.NoError(t, errReopen, "reopens file hash map after scavenging")
+		reportAfter, errAfter := fhm.Verify()
+
+		// Check
+		assert.NoError(t, err, "verifies files")
+		if assert.Len(t, report.Problems, 1, "one leaked record") {
+			assert.Equal(t, stat.Size(), report.Problems[0].Address, "address of leaked record")
+			assert.True(t, report.Problems[0].IsOverflow, "leaked record in overflow file")
+			assert.Equal(t, int64(-1), report.Problems[0].BucketNo, "leaked record in no chain")
+		}
+		assert.NoError(t, errScavenge, "scavenges overflow file")
+		assert.Equal(t, int64(1), leaked, "leaked record reclaimed")
+		assert.NoError(t, errAfter, "verifies files after scavenging")
+		assert.True(t, reportAfter.OK(), "no problems after scavenging")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
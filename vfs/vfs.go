@@ -0,0 +1,82 @@
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// File - Interface for an open file that a file hash map is stored in. It is satisfied by os.File, and a custom
+// FileSystem can return anything implementing it, e.g. a file in an encrypted file system or a gateway to object storage.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+
+	// Truncate - Changes the size of the file, extending it with zeros if it grows
+	Truncate(size int64) error
+
+	// Sync - Commits the current contents of the file to stable storage
+	Sync() error
+
+	// Close - Closes the file
+	Close() error
+
+	// Stat - Returns information about the file, of which only Size is used
+	Stat() (os.FileInfo, error)
+}
+
+// FileSystem - Interface that permits an implementation using the FileHashMap to supply the file system in which
+// files are created, opened, renamed and removed. The method signatures are those of the os package, so most file
+// system abstractions (e.g. afero) can be adapted with a few lines of code.
+type FileSystem interface {
+	// OpenFile - Opens the named file with the given flags (os.O_RDWR, os.O_CREATE, os.O_TRUNC etc.) and permissions,
+	// as os.OpenFile does
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+
+	// Stat - Returns information about the named file, as os.Stat does. An error satisfying errors.Is(err,
+	// os.ErrNotExist) must be returned if the file doesn't exist.
+	Stat(name string) (os.FileInfo, error)
+
+	// Remove - Removes the named file, as os.Remove does
+	Remove(name string) error
+
+	// Rename - Renames a file, replacing any existing file with the new name, as os.Rename does
+	Rename(oldName, newName string) error
+}
+
+// DirSyncer - Optional interface for a FileSystem that can make directory entries durable, which is used when strict
+// directory sync is turned on (see filehashmap.WithStrictDirSync). A FileSystem that doesn't implement it is assumed to
+// make directory entries durable on its own.
+type DirSyncer interface {
+	// SyncDir - Commits the directory entry of the named file to stable storage
+	SyncDir(name string) error
+}
+
+// OS - Is the FileSystem of the operating system, which is used unless another is given
+type OS struct{}
+
+// OpenFile - Opens the named file using os.OpenFile
+func (O OS) OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
+	osFile, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return
+	}
+
+	file = osFile
+
+	return
+}
+
+// Stat - Returns information about the named file using os.Stat
+func (O OS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Remove - Removes the named file using os.Remove
+func (O OS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Rename - Renames a file using os.Rename
+func (O OS) Rename(oldName, newName string) error {
+	return os.Rename(oldName, newName)
+}
@@ -0,0 +1,179 @@
+package filehashmap
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// WatchdogEvent - Describes an operation that has been running longer than the watchdog threshold, see EnableWatchdog
+//   - Operation is the name of the operation, e.g. "Get" or "Stat"
+//   - Elapsed is the time the operation had been running when the event was raised
+//   - BucketNo is the bucket last read from the map file by the operation, -1 if no bucket has been read yet
+//   - Probe is the number of buckets read by the operation before BucketNo, which for a lookup is the probe index
+type WatchdogEvent struct {
+	Operation string
+	Elapsed   time.Duration
+	BucketNo  int64
+	Probe     int64
+}
+
+// watchdog - Holds the state of the background goroutine that looks for operations running too long
+//   - threshold is the duration after which an operation is reported
+//   - callback is the function to report to
+//   - stop is closed to make the goroutine return
+//   - mu protects the state of the current operation below, which is shared with the goroutine
+//   - depth is the number of nested operations in progress, only the outermost one is reported
+//   - operation is the name of the current operation
+//   - start is when the current operation started
+//   - bucketNo is the bucket last read by the current operation
+//   - reads is the number of buckets read by the current operation
+//   - reported is true if the current operation has already been reported
+type watchdog struct {
+	threshold time.Duration
+	callback  func(event WatchdogEvent)
+	stop      chan struct{}
+	mu        sync.Mutex
+	depth     int
+	operation string
+	start     time.Time
+	bucketNo  int64
+	reads     int64
+	reported  bool
+}
+
+// progressReporter - Implemented by file management that can report the bucket it reads or writes
+type progressReporter interface {
+	SetProgress(progress func(bucketNo int64))
+}
+
+// setProgress - Sets the function fm reports the bucket it reads or writes to, nil turns reporting off. It is a no-op
+// if fm doesn't report progress.
+func setProgress(fm FileManagement, progress func(bucketNo int64)) {
+	if reporter, ok := fm.(progressReporter); ok {
+		reporter.SetProgress(progress)
+	}
+}
+
+// EnableWatchdog - Starts a background watchdog that reports any single operation running longer than threshold, which
+// helps diagnosing hangs on e.g. degraded disks in production. Watched operations are Get, GetBatch, GetOrSet, Set,
+// SetBatch, Swap, Pop, Stat, StatWithSink, Verify and Clear (including any automatic growing they trigger). Each
+// operation is reported at most once, while it is still running, together with the bucket it last read and how many
+// buckets it has read, i.e. how far it got in its probe loop or scan. The callback is called from the watchdog
+// goroutine and must not call the FileHashMap, since the operation reported may hold its lock. Calling EnableWatchdog
+// again replaces the settings.
+// The watchdog is not persisted, so it has to be enabled each time the FileHashMap is opened.
+//   - threshold is the duration after which a running operation is reported
+//   - callback is the function to report to, nil logs the event using the standard log package
+//
+// It returns:
+//   - err is a standard error, if threshold is not positive
+func (F *FileHashMap) EnableWatchdog(threshold time.Duration, callback func(event WatchdogEvent)) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if threshold <= 0 {
+		err = fmt.Errorf("watchdog threshold must be a positive duration")
+		return
+	}
+
+	if callback == nil {
+		callback = logWatchdogEvent
+	}
+
+	F.stopWatchdog()
+	F.watchdog = &watchdog{threshold: threshold, callback: callback, stop: make(chan struct{}), bucketNo: -1}
+	setProgress(F.fileManagement, F.watchdog.progress)
+	go F.watchdog.run()
+
+	return
+}
+
+// DisableWatchdog - Stops the watchdog started by EnableWatchdog
+func (F *FileHashMap) DisableWatchdog() {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	F.stopWatchdog()
+}
+
+// stopWatchdog - Stops any watchdog goroutine and progress reporting, to be called with the lock held
+func (F *FileHashMap) stopWatchdog() {
+	if F.watchdog != nil {
+		close(F.watchdog.stop)
+		F.watchdog = nil
+		setProgress(F.fileManagement, nil)
+	}
+}
+
+// watch - Marks the start of an operation to be watched by any watchdog, to be called with the lock held. The
+// returned function marks the end of the operation, and is typically deferred.
+func (F *FileHashMap) watch(operation string) (done func()) {
+	wd := F.watchdog
+	if wd == nil {
+		return func() {}
+	}
+
+	wd.mu.Lock()
+	if wd.depth == 0 {
+		wd.operation = operation
+		wd.start = time.Now()
+		wd.bucketNo = -1
+		wd.reads = 0
+		wd.reported = false
+	}
+	wd.depth++
+	wd.mu.Unlock()
+
+	return func() {
+		wd.mu.Lock()
+		wd.depth--
+		wd.mu.Unlock()
+	}
+}
+
+// progress - Records that the current operation reads bucketNo, it is given to the file management, see SetProgress
+func (W *watchdog) progress(bucketNo int64) {
+	W.mu.Lock()
+	W.bucketNo = bucketNo
+	W.reads++
+	W.mu.Unlock()
+}
+
+// run - Looks for an operation running longer than the threshold four times per threshold, until stop is closed
+func (W *watchdog) run() {
+	interval := W.threshold / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-W.stop:
+			return
+		case <-ticker.C:
+		}
+
+		W.mu.Lock()
+		elapsed := time.Since(W.start)
+		report := W.depth > 0 && !W.reported && elapsed > W.threshold
+		event := WatchdogEvent{Operation: W.operation, Elapsed: elapsed, BucketNo: W.bucketNo, Probe: W.reads - 1}
+		if report {
+			W.reported = true
+		}
+		W.mu.Unlock()
+
+		if report {
+			W.callback(event)
+		}
+	}
+}
+
+// logWatchdogEvent - Is the default watchdog callback, which logs the event
+func logWatchdogEvent(event WatchdogEvent) {
+	log.Printf("filehashmap: %s has been running for %s, last read bucket %d at probe %d", event.Operation, event.Elapsed, event.BucketNo, event.Probe)
+}
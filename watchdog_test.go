@@ -0,0 +1,77 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/gostonefire/filehashmap/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// slowGet - Is a FileManagement that takes delay to get a record
+type slowGet struct {
+	FileManagement
+	delay time.Duration
+}
+
+func (s *slowGet) Get(keyRecord model.Record) (record model.Record, err error) {
+	record, err = s.FileManagement.Get(keyRecord)
+	time.Sleep(s.delay)
+	return
+}
+
+func TestEnableWatchdog(t *testing.T) {
+	t.Run("reports slow operation once", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		key := make([]byte, 16)
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record")
+		homeBucketNo := fhm.HomeBucket(key)
+
+		events := make(chan WatchdogEvent, 10)
+		err = fhm.EnableWatchdog(10*time.Millisecond, func(event WatchdogEvent) { events <- event })
+		assert.NoError(t, err, "enables watchdog")
+
+		// Execute
+		err = fhm.Set(key, make([]byte, 10))
+		assert.NoError(t, err, "sets record fast")
+		fhm.fileManagement = &slowGet{FileManagement: fhm.fileManagement, delay: 50 * time.Millisecond}
+		_, err = fhm.Get(key)
+		assert.NoError(t, err, "gets record slowly")
+		time.Sleep(20 * time.Millisecond)
+
+		// Check
+		assert.Len(t, events, 1, "one event")
+		event := <-events
+		assert.Equal(t, "Get", event.Operation, "operation reported")
+		assert.GreaterOrEqual(t, event.Elapsed, 10*time.Millisecond, "elapsed time reported")
+		assert.Equal(t, homeBucketNo, event.BucketNo, "bucket reported")
+		assert.Equal(t, int64(0), event.Probe, "probe reported")
+
+		// Clean up
+		fhm.DisableWatchdog()
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+
+	t.Run("rejects invalid threshold", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.SeparateChaining, 10, 2, 16, 10, nil)
+		assert.NoError(t, err, "create new file hash map struct")
+
+		// Execute
+		err = fhm.EnableWatchdog(0, nil)
+
+		// Check
+		assert.Error(t, err, "threshold must be positive")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "removes files")
+	})
+}
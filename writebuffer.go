@@ -0,0 +1,101 @@
+package filehashmap
+
+import (
+	"fmt"
+	"time"
+)
+
+// writeBufferer - Implemented by file management that can hold writes in memory before they are made to the files
+type writeBufferer interface {
+	SetWriteBuffer(maxBytes int64, interval time.Duration) (err error)
+}
+
+// writeBuffer - Holds the settings of the write buffer, see EnableWriteBuffer
+//   - maxBytes is the max number of bytes to hold before flushing
+//   - interval is the max time to hold writes, zero means no time limit
+type writeBuffer struct {
+	maxBytes int64
+	interval time.Duration
+}
+
+// EnableWriteBuffer - Turns on a write-back buffer for the files. Instead of making a small write to the file for every
+// record written by Set and the other mutations, writes are held in memory, coalesced where they overlap or lie next to
+// each other, and made in order of offset with one write per contiguous run of bytes. The buffer is flushed when it
+// holds maxBytes, by a timer when interval has passed since the oldest pending write, also if no more mutations are
+// made, and when the files are synced (see SetSyncPolicy and EnableGroupCommit) or closed. Get, the iterators and the
+// other reads through the FileHashMap see pending writes, but other processes and functions opening the files by name,
+// such as InspectFiles, see them only after they are flushed, so close the FileHashMap, or have the files synced,
+// before handing them over.
+//
+// Pending writes are lost if the process crashes before they are flushed, which may leave records half written, the
+// same way an unsynced write may be lost in a system crash. Use it together with the WAL (see EnableWAL) to have such
+// mutations applied again when the files are next opened. The buffer can not be combined with memory mapping (see
+// EnableMemoryMapping), and is not supported for hash maps held in memory or opened read-only. It is not persisted, so
+// it has to be enabled each time the FileHashMap is opened, but it is kept when the map grows automatically (see
+// EnableAutoGrow).
+//   - maxBytes is the max number of bytes to hold per file, rounded up to whole pages of 4096 bytes, before flushing
+//   - interval is the max time to hold writes, zero means no time limit
+//
+// It returns:
+//   - err is a standard error, if the CRT doesn't support the buffer, maxBytes is not positive, the map file is memory
+//     mapped or pending writes of an already enabled buffer could not be flushed
+func (F *FileHashMap) EnableWriteBuffer(maxBytes int, interval time.Duration) (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	err = F.requireFiles("EnableWriteBuffer")
+	if err != nil {
+		return
+	}
+
+	if maxBytes <= 0 {
+		err = fmt.Errorf("maxBytes has to be greater than zero")
+		return
+	}
+	if F.memoryMapped {
+		err = fmt.Errorf("write buffer can not be used while memory mapping is enabled")
+		return
+	}
+
+	bufferer, ok := F.fileManagement.(writeBufferer)
+	if !ok {
+		err = fmt.Errorf("write buffer is not supported by the collision resolution technique")
+		return
+	}
+
+	err = bufferer.SetWriteBuffer(int64(maxBytes), interval)
+	if err != nil {
+		err = fmt.Errorf("error while enabling write buffer: %s", err)
+		return
+	}
+
+	F.writeBuffer = &writeBuffer{maxBytes: int64(maxBytes), interval: interval}
+
+	return
+}
+
+// DisableWriteBuffer - Flushes any pending writes and turns off the buffer turned on by EnableWriteBuffer, after which
+// every write is again made to the files directly.
+//
+// It returns:
+//   - err is a standard error, if pending writes could not be flushed
+func (F *FileHashMap) DisableWriteBuffer() (err error) {
+	F.mu.Lock()
+	defer F.mu.Unlock()
+
+	if F.writeBuffer == nil {
+		return
+	}
+
+	if bufferer, ok := F.fileManagement.(writeBufferer); ok {
+		err = bufferer.SetWriteBuffer(0, 0)
+		if err != nil {
+			err = fmt.Errorf("error while disabling write buffer: %s", err)
+			return
+		}
+	}
+
+	F.writeBuffer = nil
+
+	return
+}
@@ -0,0 +1,148 @@
+//go:build integration
+
+package filehashmap
+
+import (
+	"github.com/gostonefire/filehashmap/crt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEnableWriteBuffer(t *testing.T) {
+	t.Run("gets pending writes and keeps them when files are closed", func(t *testing.T) {
+		for _, crtType := range []int{crt.SeparateChaining, crt.LinearProbing, crt.DoubleHashing, crt.RobinHood, crt.CuckooHashing, crt.Hopscotch} {
+			// Prepare
+			fhm, _, err := NewFileHashMap(testHashMap, crtType, 100, 1, 4, 4, nil)
+			assert.NoErrorf(t, err, "create file hash map with crt %d", crtType)
+			err = fhm.EnableWriteBuffer(1<<20, time.Minute)
+			assert.NoErrorf(t, err, "enable write buffer with crt %d", crtType)
+
+			// Execute
+			for i := 0; i < 50; i++ {
+				err = fhm.Set([]byte{0, 0, byte(i), byte(i)}, []byte{1, 1, 1, byte(i)})
+				assert.NoErrorf(t, err, "sets record #%d with crt %d", i, crtType)
+			}
+			_, err = fhm.Pop([]byte{0, 0, 5, 5})
+			assert.NoErrorf(t, err, "pops record with crt %d", crtType)
+
+			// Check
+			for i := 0; i < 50; i++ {
+				value, err := fhm.Get([]byte{0, 0, byte(i), byte(i)})
+				if i == 5 {
+					assert.ErrorIsf(t, err, crt.NoRecordFound{}, "popped record is gone with crt %d", crtType)
+					continue
+				}
+				assert.NoErrorf(t, err, "gets pending record #%d with crt %d", i, crtType)
+				assert.Equalf(t, []byte{1, 1, 1, byte(i)}, value, "value of pending record #%d with crt %d", i, crtType)
+			}
+
+			fhm.CloseFiles()
+			fhm, _, err = NewFromExistingFiles(testHashMap, nil)
+			assert.NoErrorf(t, err, "reopen file hash map with crt %d", crtType)
+			count, err := fhm.Count()
+			assert.NoErrorf(t, err, "count records with crt %d", crtType)
+			assert.Equalf(t, int64(49), count, "records kept with crt %d", crtType)
+			value, err := fhm.Get([]byte{0, 0, 42, 42})
+			assert.NoErrorf(t, err, "gets record after reopen with crt %d", crtType)
+			assert.Equalf(t, []byte{1, 1, 1, 42}, value, "value after reopen with crt %d", crtType)
+
+			// Clean up
+			err = fhm.RemoveFiles()
+			assert.NoError(t, err, "remove files")
+		}
+	})
+
+	t.Run("flushes on sync and when disabled", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.EnableWriteBuffer(1<<20, 0)
+		assert.NoError(t, err, "enable write buffer")
+
+		// Execute
+		err = fhm.Set([]byte{0, 0, 1, 1}, []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "sets first record")
+		err = fhm.SetSyncPolicy(SyncAlways, 0)
+		assert.NoError(t, err, "sync always")
+		err = fhm.Set([]byte{0, 0, 3, 3}, []byte{3, 3, 3, 3})
+		assert.NoError(t, err, "sets record synced")
+		err = fhm.SetSyncPolicy(SyncOnClose, 0)
+		assert.NoError(t, err, "sync on close")
+		other, _, errOther := NewFromExistingFilesReadOnly(testHashMap, nil)
+		assert.NoError(t, errOther, "open files read-only")
+		valueAfterSync, errAfterSync := other.Get([]byte{0, 0, 1, 1})
+		other.CloseFiles()
+
+		err = fhm.Set([]byte{0, 0, 2, 2}, []byte{2, 2, 2, 2})
+		assert.NoError(t, err, "sets second record")
+		err = fhm.DisableWriteBuffer()
+		assert.NoError(t, err, "disable write buffer")
+		other, _, errOther = NewFromExistingFilesReadOnly(testHashMap, nil)
+		assert.NoError(t, errOther, "open files read-only again")
+		valueAfterDisable, errAfterDisable := other.Get([]byte{0, 0, 2, 2})
+		other.CloseFiles()
+
+		// Check
+		assert.NoError(t, errAfterSync, "record flushed by sync")
+		assert.Equal(t, []byte{1, 1, 1, 1}, valueAfterSync, "value flushed by sync")
+		assert.NoError(t, errAfterDisable, "record flushed when disabled")
+		assert.Equal(t, []byte{2, 2, 2, 2}, valueAfterDisable, "value flushed when disabled")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("flushes when interval has passed without further mutations", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+		err = fhm.EnableWriteBuffer(1<<20, 20*time.Millisecond)
+		assert.NoError(t, err, "enable write buffer")
+
+		// Execute
+		err = fhm.Set([]byte{0, 0, 1, 1}, []byte{1, 1, 1, 1})
+		assert.NoError(t, err, "sets record")
+		time.Sleep(200 * time.Millisecond)
+		other, _, errOther := NewFromExistingFilesReadOnly(testHashMap, nil)
+		assert.NoError(t, errOther, "open files read-only")
+		value, errGet := other.Get([]byte{0, 0, 1, 1})
+		other.CloseFiles()
+
+		// Check
+		assert.NoError(t, errGet, "record flushed by timer")
+		assert.Equal(t, []byte{1, 1, 1, 1}, value, "value flushed by timer")
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+
+	t.Run("fails together with memory mapping and for invalid max bytes", func(t *testing.T) {
+		// Prepare
+		fhm, _, err := NewFileHashMap(testHashMap, crt.LinearProbing, 100, 1, 4, 4, nil)
+		assert.NoError(t, err, "create file hash map")
+
+		// Execute
+		errMaxBytes := fhm.EnableWriteBuffer(0, 0)
+		err = fhm.EnableWriteBuffer(1<<20, 0)
+		assert.NoError(t, err, "enable write buffer")
+		errMapping := fhm.EnableMemoryMapping()
+		err = fhm.DisableWriteBuffer()
+		assert.NoError(t, err, "disable write buffer")
+		errMapped := fhm.EnableMemoryMapping()
+		errBuffer := fhm.EnableWriteBuffer(1<<20, 0)
+
+		// Check
+		assert.Error(t, errMaxBytes, "max bytes has to be positive")
+		assert.Error(t, errMapping, "memory mapping fails while buffering")
+		if errMapped == nil {
+			assert.Error(t, errBuffer, "write buffer fails while memory mapped")
+		}
+
+		// Clean up
+		err = fhm.RemoveFiles()
+		assert.NoError(t, err, "remove files")
+	})
+}